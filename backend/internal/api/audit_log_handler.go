@@ -0,0 +1,262 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAuditLogPageSize 审计日志查询默认每页条数
+	defaultAuditLogPageSize = 50
+	// maxAuditLogPageSize 审计日志查询最大每页条数
+	maxAuditLogPageSize = 500
+)
+
+// auditLogCSVHeader 审计日志导出的 CSV 表头
+var auditLogCSVHeader = []string{
+	"ID", "时间", "操作者类型", "操作者ID", "操作者", "API令牌ID", "操作", "资源类型", "资源ID",
+	"方法", "路由", "状态码", "结果", "变更", "请求参数", "详情", "来源地址", "请求ID",
+}
+
+// AuditLogHandler 审计日志处理器
+type AuditLogHandler struct {
+	auditRepo repository.AuditLogRepository
+}
+
+// NewAuditLogHandler 创建审计日志处理器
+func NewAuditLogHandler(auditRepo repository.AuditLogRepository) *AuditLogHandler {
+	return &AuditLogHandler{auditRepo: auditRepo}
+}
+
+// auditLogs 返回限定到当前组织的审计日志仓库
+func (h *AuditLogHandler) auditLogs(c *gin.Context) repository.AuditLogRepository {
+	return tenant.Bind(c.Request.Context(), h.auditRepo)
+}
+
+// ListAuditLogs 查询审计日志
+// @Summary 查询审计日志
+// @Description 按操作者、操作、资源、结果、来源地址和时间查询审计日志，按时间倒序
+// @Tags 审计日志
+// @Produce json
+// @Param actor_id query int false "操作者ID"
+// @Param actor query string false "操作者名称（模糊匹配）"
+// @Param actor_type query string false "操作者类型：user/api_token/system/anonymous"
+// @Param action query string false "操作，如 devices 匹配 devices.create 等全部设备操作"
+// @Param resource_type query string false "资源类型，如 devices"
+// @Param resource_id query string false "资源ID"
+// @Param status query string false "结果：success/failure"
+// @Param request_id query string false "请求ID"
+// @Param source_ip query string false "来源地址"
+// @Param search query string false "详情或路由关键字"
+// @Param start_time query string false "开始时间 (RFC3339)"
+// @Param end_time query string false "结束时间 (RFC3339)"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /audit-logs [get]
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	filter, err := parseAuditLogFilter(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultAuditLogPageSize)))
+	if pageSize < 1 || pageSize > maxAuditLogPageSize {
+		BadRequest(c, fmt.Sprintf("page_size 必须在 1 到 %d 之间", maxAuditLogPageSize))
+		return
+	}
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	entries, total, err := h.auditLogs(c).Query(filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询审计日志失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, entries, total, page, pageSize)
+}
+
+// GetAuditLog 获取审计日志详情
+// @Summary 获取审计日志详情
+// @Tags 审计日志
+// @Produce json
+// @Param id path int true "审计日志ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /audit-logs/{id} [get]
+func (h *AuditLogHandler) GetAuditLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的审计日志ID")
+		return
+	}
+
+	entry, err := h.auditLogs(c).GetByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "审计日志不存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取审计日志失败", err.Error())
+		return
+	}
+
+	Success(c, entry)
+}
+
+// ExportAuditLogs 导出审计日志为 CSV
+// @Summary 导出审计日志
+// @Description 按与查询接口相同的条件导出全部匹配的审计日志（CSV），按时间倒序
+// @Tags 审计日志
+// @Produce text/csv
+// @Param actor_id query int false "操作者ID"
+// @Param actor query string false "操作者名称（模糊匹配）"
+// @Param actor_type query string false "操作者类型"
+// @Param action query string false "操作"
+// @Param resource_type query string false "资源类型"
+// @Param resource_id query string false "资源ID"
+// @Param status query string false "结果：success/failure"
+// @Param request_id query string false "请求ID"
+// @Param source_ip query string false "来源地址"
+// @Param search query string false "详情或路由关键字"
+// @Param start_time query string false "开始时间 (RFC3339)"
+// @Param end_time query string false "结束时间 (RFC3339)"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Router /audit-logs/export [get]
+func (h *AuditLogHandler) ExportAuditLogs(c *gin.Context) {
+	filter, err := parseAuditLogFilter(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+
+	fileName := fmt.Sprintf("audit-logs-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	// 分批写出，避免一次加载全部记录；响应头已发送，中途失败只能截断输出
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(auditLogCSVHeader)
+	err = h.auditLogs(c).Each(filter, func(entries []*models.AuditLog) error {
+		for _, entry := range entries {
+			writer.Write(auditLogCSVRow(entry))
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		c.Error(err)
+	}
+	writer.Flush()
+}
+
+// auditLogCSVRow 将审计日志转换为 CSV 行
+func auditLogCSVRow(entry *models.AuditLog) []string {
+	apiTokenID := ""
+	if entry.APITokenID != 0 {
+		apiTokenID = strconv.FormatUint(uint64(entry.APITokenID), 10)
+	}
+	statusCode := ""
+	if entry.StatusCode != 0 {
+		statusCode = strconv.Itoa(entry.StatusCode)
+	}
+	return []string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		entry.CreatedAt.Format(time.RFC3339),
+		entry.ActorType,
+		strconv.FormatUint(uint64(entry.ActorID), 10),
+		entry.ActorName,
+		apiTokenID,
+		entry.Action,
+		entry.ResourceType,
+		entry.ResourceID,
+		entry.Method,
+		entry.Route,
+		statusCode,
+		entry.Status,
+		entry.Changes,
+		entry.Request,
+		entry.Detail,
+		entry.SourceIP,
+		entry.RequestID,
+	}
+}
+
+// parseAuditLogFilter 解析审计日志查询条件（不含分页）
+func parseAuditLogFilter(c *gin.Context) (repository.AuditLogFilter, error) {
+	filter := repository.AuditLogFilter{
+		ActorName:    c.Query("actor"),
+		ActorType:    c.Query("actor_type"),
+		Action:       c.Query("action"),
+		ResourceType: c.Query("resource_type"),
+		ResourceID:   c.Query("resource_id"),
+		Status:       c.Query("status"),
+		RequestID:    c.Query("request_id"),
+		SourceIP:     c.Query("source_ip"),
+		Search:       c.Query("search"),
+	}
+
+	if actorIDStr := c.Query("actor_id"); actorIDStr != "" {
+		actorID, err := strconv.ParseUint(actorIDStr, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid actor_id")
+		}
+		id := uint(actorID)
+		filter.ActorID = &id
+	}
+	if filter.Status != "" && filter.Status != models.AuditStatusSuccess && filter.Status != models.AuditStatusFailure {
+		return filter, fmt.Errorf("status must be success or failure")
+	}
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid start_time format, use RFC3339")
+		}
+		filter.StartTime = t
+	}
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return filter, fmt.Errorf("invalid end_time format, use RFC3339")
+		}
+		filter.EndTime = t
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && filter.StartTime.After(filter.EndTime) {
+		return filter, fmt.Errorf("start_time must be before end_time")
+	}
+
+	return filter, nil
+}
+
+// RegisterRoutesWithPermission 注册审计日志相关路由（带权限检查）
+// 审计日志只提供查询和导出接口
+func (h *AuditLogHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware gin.HandlerFunc) {
+	auditLogs := router.Group("/audit-logs", readMiddleware)
+	{
+		auditLogs.GET("", h.ListAuditLogs)
+		auditLogs.GET("/export", h.ExportAuditLogs)
+		auditLogs.GET("/:id", h.GetAuditLog)
+	}
+}
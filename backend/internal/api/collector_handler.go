@@ -55,6 +55,30 @@ type UpdateCollectorConfigRequest struct {
 	IntervalMs    int  `json:"interval_ms"`
 	PushBatchSize int  `json:"push_batch_size"`
 	Enabled       *bool `json:"enabled"`
+
+	// 可选服务指标模块，修改后需重新部署脚本
+	CollectPPP         *bool  `json:"collect_ppp"`
+	CollectDHCP        *bool  `json:"collect_dhcp"`
+	CollectWireless    *bool  `json:"collect_wireless"`
+	WirelessMenu       string `json:"wireless_menu"` // wireless 或 wifi
+	CollectQueues      *bool  `json:"collect_queues"`
+	MaxQueues          int    `json:"max_queues"`
+	CollectHealth      *bool  `json:"collect_health"`
+	ServiceIntervalSec int    `json:"service_interval_sec"`
+}
+
+// newCollectorModules 根据采集器配置构建脚本的可选服务指标模块
+func newCollectorModules(config *models.CollectorScript) collector.CollectorModules {
+	return collector.CollectorModules{
+		PPP:          config.CollectPPP,
+		DHCP:         config.CollectDHCP,
+		Wireless:     config.CollectWireless,
+		WirelessMenu: config.WirelessMenu,
+		Queues:       config.CollectQueues,
+		MaxQueues:    config.MaxQueues,
+		Health:       config.CollectHealth,
+		IntervalSec:  config.ServiceIntervalSec,
+	}
 }
 
 // GetCollectorConfig 获取设备的采集器配置
@@ -128,6 +152,61 @@ func (h *CollectorHandler) UpdateCollectorConfig(c *gin.Context) {
 	if req.Enabled != nil {
 		config.Enabled = *req.Enabled
 	}
+	if req.WirelessMenu != "" && req.WirelessMenu != "wireless" && req.WirelessMenu != "wifi" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "wireless_menu 必须为 wireless 或 wifi",
+		})
+		return
+	}
+	if req.MaxQueues < 0 || req.MaxQueues > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "max_queues 必须在 1 到 200 之间",
+		})
+		return
+	}
+	if req.CollectPPP != nil {
+		config.CollectPPP = *req.CollectPPP
+	}
+	if req.CollectDHCP != nil {
+		config.CollectDHCP = *req.CollectDHCP
+	}
+	if req.CollectWireless != nil {
+		config.CollectWireless = *req.CollectWireless
+	}
+	if req.WirelessMenu != "" {
+		config.WirelessMenu = req.WirelessMenu
+	}
+	if req.CollectQueues != nil {
+		config.CollectQueues = *req.CollectQueues
+	}
+	if req.MaxQueues > 0 {
+		config.MaxQueues = req.MaxQueues
+	}
+	if req.CollectHealth != nil {
+		config.CollectHealth = *req.CollectHealth
+	}
+	if req.ServiceIntervalSec > 0 {
+		config.ServiceIntervalSec = req.ServiceIntervalSec
+	}
+
+	// 缩短采集间隔时，已启用的 Ping 目标仍需能在一个采集周期内完成探测
+	if req.IntervalMs > 0 && h.pingTargetRepo != nil {
+		pingTargets, err := h.pingTargetRepo.GetEnabledByDeviceID(uint(deviceID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		for _, target := range pingTargets {
+			if err := target.ValidateBurstDuration(config.IntervalMs); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Ping 目标 " + target.TargetName + ": " + err.Error(),
+				})
+				return
+			}
+		}
+	}
 
 	if err := h.collectorRepo.Update(config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -199,11 +278,7 @@ func (h *CollectorHandler) DeployCollector(c *gin.Context) {
 	// 构建 Ping 目标配置
 	var pingTargetConfigs []collector.PingTargetConfig
 	for _, pt := range pingTargets {
-		pingTargetConfigs = append(pingTargetConfigs, collector.PingTargetConfig{
-			TargetAddress:   pt.TargetAddress,
-			TargetName:      pt.TargetName,
-			SourceInterface: pt.SourceInterface,
-		})
+		pingTargetConfigs = append(pingTargetConfigs, newPingTargetConfig(pt))
 	}
 
 	// 构建脚本配置
@@ -217,6 +292,7 @@ func (h *CollectorHandler) DeployCollector(c *gin.Context) {
 		SchedulerName: config.SchedulerName,
 		Interfaces:    interfaceNames,
 		PingTargets:   pingTargetConfigs,
+		Modules:       newCollectorModules(config),
 	}
 
 	// 部署脚本
@@ -501,11 +577,7 @@ func (h *CollectorHandler) PreviewScript(c *gin.Context) {
 	// 构建 Ping 目标配置
 	var pingTargetConfigs []collector.PingTargetConfig
 	for _, pt := range pingTargets {
-		pingTargetConfigs = append(pingTargetConfigs, collector.PingTargetConfig{
-			TargetAddress:   pt.TargetAddress,
-			TargetName:      pt.TargetName,
-			SourceInterface: pt.SourceInterface,
-		})
+		pingTargetConfigs = append(pingTargetConfigs, newPingTargetConfig(pt))
 	}
 
 	// 构建脚本配置
@@ -519,6 +591,7 @@ func (h *CollectorHandler) PreviewScript(c *gin.Context) {
 		SchedulerName: config.SchedulerName,
 		Interfaces:    interfaceNames,
 		PingTargets:   pingTargetConfigs,
+		Modules:       newCollectorModules(config),
 	}
 
 	// 生成脚本
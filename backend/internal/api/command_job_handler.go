@@ -0,0 +1,314 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultCommandJobPageSize 批量命令任务默认每页条数
+	defaultCommandJobPageSize = 20
+	// maxCommandJobPageSize 批量命令任务最大每页条数
+	maxCommandJobPageSize = 100
+)
+
+// DevicePermissionChecker 设备权限检查（由 auth.DevicePermissionChecker 实现）
+type DevicePermissionChecker interface {
+	CheckDevicePermission(userID, deviceID uint, action string) (bool, error)
+	IsSuperAdmin(userID uint) (bool, error)
+}
+
+// CommandJobHandler 批量命令任务处理器
+type CommandJobHandler struct {
+	jobService  *service.CommandJobService
+	permChecker DevicePermissionChecker
+}
+
+// NewCommandJobHandler 创建批量命令任务处理器
+func NewCommandJobHandler(jobService *service.CommandJobService, permChecker DevicePermissionChecker) *CommandJobHandler {
+	return &CommandJobHandler{
+		jobService:  jobService,
+		permChecker: permChecker,
+	}
+}
+
+// PreviewCommandJob 预览批量命令（dry-run）
+// @Summary 预览批量命令
+// @Description 解析目标设备并渲染每台设备将要执行的命令，不连接设备；需要对每台目标设备有 update 权限
+// @Tags 批量命令
+// @Accept json
+// @Produce json
+// @Param request body service.CommandJobRequest true "任务配置"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /command-jobs/preview [post]
+func (h *CommandJobHandler) PreviewCommandJob(c *gin.Context) {
+	req, devices, ok := h.bindAndAuthorize(c)
+	if !ok {
+		return
+	}
+
+	preview, err := h.jobService.Preview(req, devices)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的任务配置", err.Error())
+		return
+	}
+
+	Success(c, preview)
+}
+
+// CreateCommandJob 创建并执行批量命令任务
+// @Summary 执行批量命令
+// @Description 在目标设备上通过 SSH（经设备代理）并发执行命令模板，任务在后台运行；需要对每台目标设备有 update 权限
+// @Tags 批量命令
+// @Accept json
+// @Produce json
+// @Param request body service.CommandJobRequest true "任务配置"
+// @Success 202 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /command-jobs [post]
+func (h *CommandJobHandler) CreateCommandJob(c *gin.Context) {
+	req, devices, ok := h.bindAndAuthorize(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	uid, _ := userID.(uint)
+	name, _ := username.(string)
+
+	job, preview, err := h.jobService.Submit(c.Request.Context(), req, devices, uid, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrCommandJobNoTargets):
+			BadRequest(c, "没有可执行的设备")
+		case errors.Is(err, service.ErrCommandJobInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "无效的任务配置",
+				"details": err.Error(),
+				"preview": preview,
+			})
+		default:
+			ErrorWithDetails(c, http.StatusInternalServerError, "创建任务失败", err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// ListCommandJobs 查询批量命令任务
+// @Summary 查询批量命令任务
+// @Description 按时间倒序返回任务列表（不含设备结果），非管理员只能看到自己创建的任务
+// @Tags 批量命令
+// @Produce json
+// @Param status query string false "任务状态"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /command-jobs [get]
+func (h *CommandJobHandler) ListCommandJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultCommandJobPageSize)))
+	if pageSize < 1 || pageSize > maxCommandJobPageSize {
+		BadRequest(c, fmt.Sprintf("page_size 必须在 1 到 %d 之间", maxCommandJobPageSize))
+		return
+	}
+
+	filter := repository.CommandJobFilter{
+		Status: models.CommandJobStatus(c.Query("status")),
+		Offset: (page - 1) * pageSize,
+		Limit:  pageSize,
+	}
+
+	userID, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !isAdmin {
+		filter.CreatedBy = &userID
+	}
+
+	jobs, total, err := h.jobService.List(c.Request.Context(), filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询任务失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, jobs, total, page, pageSize)
+}
+
+// GetCommandJob 获取批量命令任务详情
+// @Summary 获取批量命令任务
+// @Description 返回任务及每台设备的命令、stdout、stderr 和退出码
+// @Tags 批量命令
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /command-jobs/{id} [get]
+func (h *CommandJobHandler) GetCommandJob(c *gin.Context) {
+	job, ok := h.getAccessibleJob(c)
+	if !ok {
+		return
+	}
+
+	Success(c, job)
+}
+
+// CancelCommandJob 取消批量命令任务
+// @Summary 取消批量命令任务
+// @Description 停止派发剩余设备，已在执行的命令会继续完成
+// @Tags 批量命令
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /command-jobs/{id}/cancel [post]
+func (h *CommandJobHandler) CancelCommandJob(c *gin.Context) {
+	job, ok := h.getAccessibleJob(c)
+	if !ok {
+		return
+	}
+
+	if err := h.jobService.Cancel(job.ID); err != nil {
+		if errors.Is(err, service.ErrCommandJobNotRunning) {
+			Error(c, http.StatusConflict, "任务未在执行")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "取消任务失败", err.Error())
+		return
+	}
+
+	Success(c, gin.H{"id": job.ID})
+}
+
+// bindAndAuthorize 解析请求、解析目标设备并检查每台设备的 update 权限，失败时直接写入错误响应
+func (h *CommandJobHandler) bindAndAuthorize(c *gin.Context) (*service.CommandJobRequest, []*models.Device, bool) {
+	var req service.CommandJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的请求格式", err.Error())
+		return nil, nil, false
+	}
+
+	devices, err := h.jobService.ResolveTargets(c.Request.Context(), req.Targets)
+	if err != nil {
+		if errors.Is(err, service.ErrCommandJobNoTargets) {
+			BadRequest(c, "未选择任何设备")
+			return nil, nil, false
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ErrorWithDetails(c, http.StatusNotFound, "设备不存在", err.Error())
+			return nil, nil, false
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "解析目标设备失败", err.Error())
+		return nil, nil, false
+	}
+
+	userID, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if isAdmin {
+		return &req, devices, true
+	}
+
+	var denied []string
+	for _, device := range devices {
+		allowed, err := h.permChecker.CheckDevicePermission(userID, device.ID, models.DeviceActionUpdate)
+		if err != nil {
+			InternalError(c, "权限检查失败")
+			return nil, nil, false
+		}
+		if !allowed {
+			denied = append(denied, fmt.Sprintf("%s (%d)", device.Name, device.ID))
+		}
+	}
+	if len(denied) > 0 {
+		ErrorWithDetails(c, http.StatusForbidden, "无权限操作部分目标设备", strings.Join(denied, ", "))
+		return nil, nil, false
+	}
+
+	return &req, devices, true
+}
+
+// currentUser 获取当前用户及是否为管理员，失败时直接写入错误响应
+func (h *CommandJobHandler) currentUser(c *gin.Context) (uint, bool, bool) {
+	value, exists := c.Get("user_id")
+	userID, ok := value.(uint)
+	if !exists || !ok {
+		Unauthorized(c, "用户未认证")
+		return 0, false, false
+	}
+
+	isAdmin, err := h.permChecker.IsSuperAdmin(userID)
+	if err != nil {
+		InternalError(c, "权限检查失败")
+		return 0, false, false
+	}
+	return userID, isAdmin, true
+}
+
+// getAccessibleJob 获取路径中的任务，非管理员只能访问自己创建的任务
+func (h *CommandJobHandler) getAccessibleJob(c *gin.Context) (*models.CommandJob, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的任务ID")
+		return nil, false
+	}
+
+	userID, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.jobService.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "任务不存在")
+			return nil, false
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取任务失败", err.Error())
+		return nil, false
+	}
+	if !isAdmin && job.CreatedBy != userID {
+		NotFound(c, "任务不存在")
+		return nil, false
+	}
+
+	return job, true
+}
+
+// RegisterRoutes 注册批量命令任务路由（设备级权限在处理器内逐台检查）
+func (h *CommandJobHandler) RegisterRoutes(router *gin.RouterGroup) {
+	jobs := router.Group("/command-jobs")
+	{
+		jobs.POST("/preview", h.PreviewCommandJob)
+		jobs.POST("", h.CreateCommandJob)
+		jobs.GET("", h.ListCommandJobs)
+		jobs.GET("/:id", h.GetCommandJob)
+		jobs.POST("/:id/cancel", h.CancelCommandJob)
+	}
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultConfigBackupPageSize 配置版本查询默认每页条数
+	defaultConfigBackupPageSize = 50
+	// maxConfigBackupPageSize 配置版本查询最大每页条数
+	maxConfigBackupPageSize = 500
+)
+
+// ConfigBackupHandler 设备配置备份处理器
+type ConfigBackupHandler struct {
+	backupService *service.ConfigBackupService
+	deviceRepo    repository.DeviceRepository
+}
+
+// NewConfigBackupHandler 创建设备配置备份处理器
+func NewConfigBackupHandler(backupService *service.ConfigBackupService, deviceRepo repository.DeviceRepository) *ConfigBackupHandler {
+	return &ConfigBackupHandler{
+		backupService: backupService,
+		deviceRepo:    deviceRepo,
+	}
+}
+
+// ConfigDiffResponse 配置版本差异响应
+type ConfigDiffResponse struct {
+	DeviceID     uint   `json:"device_id"`
+	FromVersion  int    `json:"from_version"`
+	ToVersion    int    `json:"to_version"`
+	Diff         string `json:"diff"` // 统一差异格式，为空表示两个版本相同
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+}
+
+// ListConfigChanges 查询配置变更事件
+// @Summary 查询配置变更
+// @Description 查询所有设备的配置变更（每台设备首个版本之后的新版本），按时间倒序
+// @Tags 配置备份
+// @Produce json
+// @Param device_id query int false "设备ID"
+// @Param group_id query int false "设备分组ID"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /config-changes [get]
+func (h *ConfigBackupHandler) ListConfigChanges(c *gin.Context) {
+	filter, page, pageSize, err := parseConfigBackupPage(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+
+	startTime, endTime, err := parseEventTimeRange(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+	filter.ChangesOnly = true
+	filter.DeviceIDs = scopedDeviceIDs(c)
+
+	for param, target := range map[string]**uint{
+		"device_id": &filter.DeviceID,
+		"group_id":  &filter.GroupID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", fmt.Sprintf("invalid %s: %v", param, err))
+			return
+		}
+		uid := uint(id)
+		*target = &uid
+	}
+
+	h.respondList(c, filter, page, pageSize)
+}
+
+// ListDeviceConfigBackups 查询设备配置版本列表
+// @Summary 查询设备配置版本
+// @Tags 配置备份
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/config-backups [get]
+func (h *ConfigBackupHandler) ListDeviceConfigBackups(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	filter, page, pageSize, err := parseConfigBackupPage(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+	id := uint(deviceID)
+	filter.DeviceID = &id
+
+	h.respondList(c, filter, page, pageSize)
+}
+
+// BackupDeviceConfig 立即备份设备配置
+// @Summary 立即备份设备配置
+// @Description 通过 SSH 导出 RouterOS 配置或读取 Linux 配置文件，配置有变化时保存新版本
+// @Tags 配置备份
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/config-backups [post]
+func (h *ConfigBackupHandler) BackupDeviceConfig(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	device, err := h.deviceRepo.GetByID(uint(deviceID))
+	if err != nil {
+		NotFound(c, "设备不存在")
+		return
+	}
+
+	result, err := h.backupService.BackupDevice(c.Request.Context(), device, models.ConfigBackupTriggerManual)
+	if err != nil {
+		if errors.Is(err, service.ErrConfigBackupUnsupported) {
+			BadRequest(c, "该设备类型不支持配置备份")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "备份设备配置失败", err.Error())
+		return
+	}
+
+	// 响应中不返回完整配置内容
+	backup := *result.Backup
+	backup.Content = ""
+	result.Backup = &backup
+	Success(c, result)
+}
+
+// GetDeviceConfigBackup 查看设备配置版本
+// @Summary 查看设备配置版本
+// @Description 返回指定版本的完整配置内容及相对上一版本的差异，version 为 latest 时返回最新版本
+// @Tags 配置备份
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param version path string true "版本号或 latest"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/config-backups/{version} [get]
+func (h *ConfigBackupHandler) GetDeviceConfigBackup(c *gin.Context) {
+	backup, ok := h.getVersion(c)
+	if !ok {
+		return
+	}
+	Success(c, backup)
+}
+
+// DownloadDeviceConfigBackup 下载设备配置版本
+// @Summary 下载设备配置版本
+// @Tags 配置备份
+// @Produce plain
+// @Param id path int true "设备ID"
+// @Param version path string true "版本号或 latest"
+// @Success 200 {string} string "配置内容"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/config-backups/{version}/download [get]
+func (h *ConfigBackupHandler) DownloadDeviceConfigBackup(c *gin.Context) {
+	backup, ok := h.getVersion(c)
+	if !ok {
+		return
+	}
+
+	extension := "txt"
+	if device, err := h.deviceRepo.GetByID(backup.DeviceID); err == nil && device.OSType == models.DeviceOSTypeMikroTik {
+		extension = "rsc"
+	}
+	fileName := fmt.Sprintf("device-%d-config-v%d-%s.%s", backup.DeviceID, backup.Version, backup.CreatedAt.Format("20060102-150405"), extension)
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(backup.Content))
+}
+
+// DiffDeviceConfigBackup 比较设备的两个配置版本
+// @Summary 比较设备配置版本
+// @Description 返回 from 版本到路径中版本的统一差异，from 默认为上一版本
+// @Tags 配置备份
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param version path string true "版本号或 latest"
+// @Param from query int false "对比的起始版本，默认为上一版本"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/config-backups/{version}/diff [get]
+func (h *ConfigBackupHandler) DiffDeviceConfigBackup(c *gin.Context) {
+	to, ok := h.getVersion(c)
+	if !ok {
+		return
+	}
+
+	fromVersion := to.Version - 1
+	if fromStr := c.Query("from"); fromStr != "" {
+		version, err := strconv.Atoi(fromStr)
+		if err != nil || version < 1 {
+			BadRequest(c, "无效的起始版本")
+			return
+		}
+		fromVersion = version
+	}
+	if fromVersion < 1 {
+		BadRequest(c, "首个版本没有可对比的上一版本")
+		return
+	}
+
+	diff, err := h.backupService.DiffVersions(c.Request.Context(), to.DeviceID, fromVersion, to.Version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "配置版本不存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "比较配置版本失败", err.Error())
+		return
+	}
+
+	response := ConfigDiffResponse{
+		DeviceID:    to.DeviceID,
+		FromVersion: fromVersion,
+		ToVersion:   to.Version,
+		Diff:        diff,
+	}
+	response.LinesAdded, response.LinesRemoved = service.CountDiffLines(diff)
+	Success(c, response)
+}
+
+// getVersion 获取路径中的设备配置版本，失败时直接写入错误响应
+func (h *ConfigBackupHandler) getVersion(c *gin.Context) (*models.ConfigBackup, bool) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return nil, false
+	}
+
+	version := 0
+	if versionStr := c.Param("version"); versionStr != "latest" {
+		version, err = strconv.Atoi(versionStr)
+		if err != nil || version < 1 {
+			BadRequest(c, "无效的版本号")
+			return nil, false
+		}
+	}
+
+	backup, err := h.backupService.GetVersion(c.Request.Context(), uint(deviceID), version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "配置版本不存在")
+			return nil, false
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取配置版本失败", err.Error())
+		return nil, false
+	}
+
+	return backup, true
+}
+
+// parseConfigBackupPage 解析分页参数
+func parseConfigBackupPage(c *gin.Context) (repository.ConfigBackupFilter, int, int, error) {
+	var filter repository.ConfigBackupFilter
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultConfigBackupPageSize)))
+	if pageSize < 1 || pageSize > maxConfigBackupPageSize {
+		return filter, 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxConfigBackupPageSize)
+	}
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	return filter, page, pageSize, nil
+}
+
+// respondList 执行查询并返回分页结果
+func (h *ConfigBackupHandler) respondList(c *gin.Context, filter repository.ConfigBackupFilter, page, pageSize int) {
+	backups, total, err := h.backupService.ListVersions(c.Request.Context(), filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询配置版本失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, backups, total, page, pageSize)
+}
+
+// RegisterRoutesWithPermission 注册配置备份相关路由（带权限检查）
+func (h *ConfigBackupHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware, updateMiddleware gin.HandlerFunc) {
+	router.GET("/config-changes", h.ListConfigChanges)
+
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/config-backups", readMiddleware, h.ListDeviceConfigBackups)
+		devices.POST("/:id/config-backups", updateMiddleware, h.BackupDeviceConfig)
+		devices.GET("/:id/config-backups/:version", readMiddleware, h.GetDeviceConfigBackup)
+		devices.GET("/:id/config-backups/:version/download", readMiddleware, h.DownloadDeviceConfigBackup)
+		devices.GET("/:id/config-backups/:version/diff", readMiddleware, h.DiffDeviceConfigBackup)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"nmp-platform/internal/collector"
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/service"
 	"strconv"
@@ -53,6 +54,9 @@ func (h *DataQueryHandler) QueryRealTimeData(c *gin.Context) {
 	for i, id := range deviceIDs {
 		deviceIDs[i] = strings.TrimSpace(id)
 	}
+	if !authorizeQueryDevices(c, deviceIDs...) {
+		return
+	}
 
 	var metrics []string
 	metricsStr := c.Query("metrics")
@@ -103,6 +107,10 @@ func (h *DataQueryHandler) QueryHistoricalData(c *gin.Context) {
 		return
 	}
 
+	if !authorizeQueryDevices(c, req.DeviceID) {
+		return
+	}
+
 	response, err := h.queryService.QueryHistoricalData(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -237,6 +245,10 @@ func (h *DataQueryHandler) QueryAggregatedData(c *gin.Context) {
 		return
 	}
 
+	if !authorizeQueryDevices(c, req.DeviceID) {
+		return
+	}
+
 	response, err := h.queryService.QueryAggregatedData(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -272,6 +284,10 @@ func (h *DataQueryHandler) QueryTimeSeriesData(c *gin.Context) {
 		return
 	}
 
+	if !authorizeQueryDevices(c, req.DeviceID) {
+		return
+	}
+
 	response, err := h.queryService.QueryTimeSeriesData(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
@@ -380,6 +396,9 @@ func (h *DataQueryHandler) QueryDeviceStatus(c *gin.Context) {
 	for i, id := range deviceIDs {
 		deviceIDs[i] = strings.TrimSpace(id)
 	}
+	if !authorizeQueryDevices(c, deviceIDs...) {
+		return
+	}
 
 	statuses, err := h.queryService.QueryDeviceList(c.Request.Context(), deviceIDs)
 	if err != nil {
@@ -461,22 +480,23 @@ func (h *DataQueryHandler) QueryLatestMetric(c *gin.Context) {
 }
 
 // RegisterRoutes 注册数据查询相关路由
+// 查询的设备必须在当前请求可访问的设备范围内（API 令牌的设备范围和用户的设备授权）
 func (h *DataQueryHandler) RegisterRoutes(router *gin.RouterGroup) {
 	queryGroup := router.Group("/query")
 	{
 		// 实时数据查询
 		queryGroup.GET("/realtime", h.QueryRealTimeData)
-		queryGroup.GET("/latest/:device_id/:metric", h.QueryLatestMetric)
+		queryGroup.GET("/latest/:device_id/:metric", requireDeviceInScope, h.QueryLatestMetric)
 		queryGroup.GET("/device-status", h.QueryDeviceStatus)
 
 		// 历史数据查询
 		queryGroup.POST("/historical", h.QueryHistoricalData)
-		queryGroup.GET("/historical/:device_id", h.QueryHistoricalDataByParams)
+		queryGroup.GET("/historical/:device_id", requireDeviceInScope, h.QueryHistoricalDataByParams)
 		queryGroup.POST("/aggregated", h.QueryAggregatedData)
 		queryGroup.POST("/timeseries", h.QueryTimeSeriesData)
 
 		// 统计和摘要
-		queryGroup.GET("/summary/:device_id/:metric", h.QueryMetricSummary)
+		queryGroup.GET("/summary/:device_id/:metric", requireDeviceInScope, h.QueryMetricSummary)
 	}
 
 	// 监控指标查询路由
@@ -485,10 +505,46 @@ func (h *DataQueryHandler) RegisterRoutes(router *gin.RouterGroup) {
 		// 总流量查询（聚合所有设备）- 必须放在参数路由之前
 		metricsGroup.GET("/traffic/total", h.QueryTotalTraffic)
 		// 带宽数据查询
-		metricsGroup.GET("/bandwidth/:device_id", h.QueryBandwidthData)
+		metricsGroup.GET("/bandwidth/:device_id", requireDeviceInScope, h.QueryBandwidthData)
 		// Ping 数据查询
-		metricsGroup.GET("/ping/:device_id", h.QueryPingData)
+		metricsGroup.GET("/ping/:device_id", requireDeviceInScope, h.QueryPingData)
+		// 服务指标查询（PPP/DHCP/无线/队列）
+		metricsGroup.GET("/ppp/:device_id", requireDeviceInScope, h.QueryPPPData)
+		metricsGroup.GET("/dhcp/:device_id", requireDeviceInScope, h.QueryDHCPData)
+		metricsGroup.GET("/wireless/:device_id", requireDeviceInScope, h.QueryWirelessData)
+		metricsGroup.GET("/queues/:device_id", requireDeviceInScope, h.QueryQueueData)
+		metricsGroup.GET("/health/:device_id", requireDeviceInScope, h.QueryHealthData)
+		// 服务端探测数据查询
+		metricsGroup.GET("/probes/summary", h.QueryProbeSummary)
+		metricsGroup.GET("/probe/:probe_id", h.QueryProbeData)
+	}
+}
+
+// authorizeQueryDevices 检查查询的设备是否都在当前请求可访问的设备范围内，否则返回 403
+func authorizeQueryDevices(c *gin.Context, deviceIDs ...string) bool {
+	if scopedDeviceIDs(c) == nil {
+		return true
+	}
+	for _, deviceIDStr := range deviceIDs {
+		deviceID, err := strconv.ParseUint(deviceIDStr, 10, 32)
+		if err != nil || !deviceInScope(c, uint(deviceID)) {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("no permission to access device %s", deviceIDStr),
+			})
+			return false
+		}
+	}
+	return true
+}
+
+// requireDeviceInScope 路由参数 device_id 指定的设备不在可访问范围内时中止请求
+func requireDeviceInScope(c *gin.Context) {
+	if !authorizeQueryDevices(c, c.Param("device_id")) {
+		c.Abort()
+		return
 	}
+	c.Next()
 }
 
 // BandwidthQueryResponse 带宽查询响应
@@ -502,8 +558,14 @@ type BandwidthQueryResponse struct {
 // BandwidthPoint 带宽数据点
 type BandwidthPoint struct {
 	Timestamp time.Time `json:"timestamp"`
-	RxRate    float64   `json:"rx_rate"` // 接收速率 bps
-	TxRate    float64   `json:"tx_rate"` // 发送速率 bps
+	RxRate    float64   `json:"rx_rate"`    // 接收速率 bps
+	TxRate    float64   `json:"tx_rate"`    // 发送速率 bps
+	RxPackets float64   `json:"rx_packets"` // 接收包速率 pps
+	TxPackets float64   `json:"tx_packets"` // 发送包速率 pps
+	RxErrors  float64   `json:"rx_errors"`  // 接收错误速率 个/秒
+	TxErrors  float64   `json:"tx_errors"`  // 发送错误速率 个/秒
+	RxDrops   float64   `json:"rx_drops"`   // 接收丢包速率 个/秒
+	TxDrops   float64   `json:"tx_drops"`   // 发送丢包速率 个/秒
 }
 
 // PingQueryResponse Ping 查询响应
@@ -516,10 +578,15 @@ type PingQueryResponse struct {
 
 // PingPoint Ping 数据点
 type PingPoint struct {
-	Timestamp time.Time `json:"timestamp"`
-	Latency   float64   `json:"latency"`   // 延迟 ms
-	Status    string    `json:"status"`    // up/down
-	IsLoss    bool      `json:"is_loss"`   // 是否丢包
+	Timestamp  time.Time `json:"timestamp"`
+	Latency    float64   `json:"latency"`               // 平均延迟 us
+	Status     string    `json:"status"`                // up/down
+	IsLoss     bool      `json:"is_loss"`               // 是否整组丢包
+	MinLatency float64   `json:"min_latency,omitempty"` // 最小延迟 us
+	MaxLatency float64   `json:"max_latency,omitempty"` // 最大延迟 us
+	Jitter     float64   `json:"jitter"`                // 抖动 us
+	Loss       float64   `json:"loss"`                  // 丢包率 (%)
+	MOS        float64   `json:"mos,omitempty"`         // MOS 值（仅 VoIP 目标）
 }
 
 // QueryBandwidthData 查询带宽数据
@@ -646,6 +713,188 @@ func (h *DataQueryHandler) QueryPingData(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// QueryPPPData 查询 PPP 会话数据
+// @Summary 查询 PPP 会话数
+// @Description 查询设备 PPP 活动会话数（active）和 PPPoE 会话数（pppoe）历史数据，需在采集器配置中启用 PPP 模块
+// @Tags 监控指标
+// @Produce json
+// @Param device_id path string true "设备ID"
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (1h, 6h, 12h, 24h)"
+// @Success 200 {object} service.ServiceMetricsQueryResponse "PPP 会话数据"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/ppp/{device_id} [get]
+func (h *DataQueryHandler) QueryPPPData(c *gin.Context) {
+	h.queryServiceMetrics(c, "ppp", "")
+}
+
+// QueryDHCPData 查询 DHCP 租约数据
+// @Summary 查询 DHCP 租约数
+// @Description 按 DHCP 服务器查询已绑定租约数（bound）和全部租约数（total）历史数据，需在采集器配置中启用 DHCP 模块
+// @Tags 监控指标
+// @Produce json
+// @Param device_id path string true "设备ID"
+// @Param server query string false "DHCP 服务器名称，为空则查询所有服务器"
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (1h, 6h, 12h, 24h)"
+// @Success 200 {object} service.ServiceMetricsQueryResponse "DHCP 租约数据"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/dhcp/{device_id} [get]
+func (h *DataQueryHandler) QueryDHCPData(c *gin.Context) {
+	h.queryServiceMetrics(c, "dhcp", c.Query("server"))
+}
+
+// QueryWirelessData 查询无线客户端数据
+// @Summary 查询无线客户端
+// @Description 按无线接口查询注册客户端数（clients）、平均信号（avg_signal）和最弱信号（min_signal，dBm）历史数据，需在采集器配置中启用无线模块
+// @Tags 监控指标
+// @Produce json
+// @Param device_id path string true "设备ID"
+// @Param interface query string false "无线接口名称，为空则查询所有接口"
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (1h, 6h, 12h, 24h)"
+// @Success 200 {object} service.ServiceMetricsQueryResponse "无线客户端数据"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/wireless/{device_id} [get]
+func (h *DataQueryHandler) QueryWirelessData(c *gin.Context) {
+	h.queryServiceMetrics(c, "wireless", c.Query("interface"))
+}
+
+// QueryQueueData 查询队列速率数据
+// @Summary 查询简单队列速率
+// @Description 按简单队列查询上传速率（upload_rate）和下载速率（download_rate，bps）历史数据，需在采集器配置中启用队列模块
+// @Tags 监控指标
+// @Produce json
+// @Param device_id path string true "设备ID"
+// @Param queue query string false "队列名称，为空则查询所有队列"
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (1h, 6h, 12h, 24h)"
+// @Success 200 {object} service.ServiceMetricsQueryResponse "队列速率数据"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/queues/{device_id} [get]
+func (h *DataQueryHandler) QueryQueueData(c *gin.Context) {
+	h.queryServiceMetrics(c, "queue", c.Query("queue"))
+}
+
+// QueryHealthData 查询硬件健康传感器数据
+// @Summary 查询硬件健康传感器
+// @Description 查询温度（C）、电压（V）、风扇转速（RPM）、功率（W）、电流（A）和电源/风扇状态（1 正常，0 故障）历史数据。
+// @Description 传感器名称已跨厂商归一化：CPU 温度为 cpu，主板温度为 board，电源为 psu1、psu2，风扇为 fan1、fan2。
+// @Description RouterOS 需在采集器配置中启用健康模块，Linux 设备通过 SSH 读取 lm-sensors 或 /sys/class/thermal
+// @Tags 监控指标
+// @Produce json
+// @Param device_id path string true "设备ID"
+// @Param sensor query string false "传感器名称，为空则查询所有传感器"
+// @Param type query string false "传感器类型：temperature/voltage/fan/power/current/state"
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (1h, 6h, 12h, 24h)"
+// @Success 200 {object} service.HealthQueryResponse "硬件健康数据"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/health/{device_id} [get]
+func (h *DataQueryHandler) QueryHealthData(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	if deviceID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "device_id is required",
+		})
+		return
+	}
+
+	sensorType := c.Query("type")
+	if sensorType != "" && collector.HealthSensorUnit(sensorType) == "" && sensorType != collector.HealthSensorState {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("invalid sensor type: %s", sensorType),
+		})
+		return
+	}
+
+	// 解析时间范围
+	startTime, endTime, err := h.parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 验证时间范围
+	if err := h.validateTimeRange(startTime, endTime); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response, err := h.queryService.QueryHealthMetrics(c.Request.Context(), deviceID, c.Query("sensor"), sensorType, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to query health data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// queryServiceMetrics 查询服务指标的公共逻辑
+func (h *DataQueryHandler) queryServiceMetrics(c *gin.Context, measurement, name string) {
+	deviceID := c.Param("device_id")
+	if deviceID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "device_id is required",
+		})
+		return
+	}
+
+	// 解析时间范围
+	startTime, endTime, err := h.parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 验证时间范围
+	if err := h.validateTimeRange(startTime, endTime); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response, err := h.queryService.QueryServiceMetrics(c.Request.Context(), deviceID, measurement, name, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: fmt.Sprintf("Failed to query %s data", measurement),
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // parseTimeRange 解析时间范围参数
 func (h *DataQueryHandler) parseTimeRange(c *gin.Context) (time.Time, time.Time, error) {
 	var startTime, endTime time.Time
@@ -782,4 +1031,109 @@ func (h *DataQueryHandler) QueryTotalTraffic(c *gin.Context) {
 		"success": true,
 		"data":    response,
 	})
-}
\ No newline at end of file
+}
+
+// QueryProbeData 查询服务端探测数据
+// @Summary 查询服务端探测数据
+// @Description 查询单个服务端探测的历史耗时和可用率，失败点位会被标记并保留失败原因
+// @Tags 监控指标
+// @Accept json
+// @Produce json
+// @Param probe_id path string true "探测ID"
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (10m, 30m, 1h, 3h, 6h, 12h, 24h)"
+// @Success 200 {object} service.ProbeQueryResponse "探测数据"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/probe/{probe_id} [get]
+func (h *DataQueryHandler) QueryProbeData(c *gin.Context) {
+	probeID := c.Param("probe_id")
+	if _, err := strconv.ParseUint(probeID, 10, 32); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "Invalid probe_id",
+		})
+		return
+	}
+
+	// 解析时间范围
+	startTime, endTime, err := h.parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 验证时间范围不超过24小时
+	if err := h.validateTimeRange(startTime, endTime); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response, err := h.queryService.QueryProbeData(c.Request.Context(), probeID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to query probe data",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// QueryProbeSummary 查询服务端探测汇总
+// @Summary 查询服务端探测汇总
+// @Description 查询所有服务端探测在时间范围内的可用率、耗时和证书剩余天数
+// @Tags 监控指标
+// @Accept json
+// @Produce json
+// @Param start_time query string false "开始时间 (RFC3339格式)"
+// @Param end_time query string false "结束时间 (RFC3339格式)"
+// @Param range query string false "时间范围 (10m, 30m, 1h, 3h, 6h, 12h, 24h)"
+// @Success 200 {object} service.ProbeSummaryResponse "探测汇总"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 500 {object} models.ErrorResponse "服务器内部错误"
+// @Router /api/v1/metrics/probes/summary [get]
+func (h *DataQueryHandler) QueryProbeSummary(c *gin.Context) {
+	// 解析时间范围
+	startTime, endTime, err := h.parseTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// 验证时间范围不超过24小时
+	if err := h.validateTimeRange(startTime, endTime); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	response, err := h.queryService.QueryProbeSummary(c.Request.Context(), startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to query probe summary",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+	})
+}
@@ -369,29 +369,13 @@ func (h *DataReceiverHandler) PushRouterOSMetrics(c *gin.Context) {
 		return
 	}
 	
-	// 获取采集器配置，用于计算时间间隔
-	// 默认采集间隔为 1000ms
-	intervalMs := int64(1000)
-	
-	// 处理每个指标点
-	// 如果时间戳为0，需要根据数据点位置倒推时间
-	// 假设数据点是按采集顺序排列的，最后一个点是最新的
+	// 计算每个数据点的时间戳：优先使用设备时钟（按测得的时钟偏差校正），
+	// 旧版脚本未提供时间戳时按设备配置的采集间隔倒推
+	timestamps := h.dataReceiverService.ResolvePointTimestamps(c.Request.Context(), device.ID, req.SentAt, req.Metrics)
 	processedCount := 0
-	now := time.Now().UnixMilli()
-	totalPoints := len(req.Metrics)
 	
 	for i, point := range req.Metrics {
-		// 计算时间戳
-		var timestamp int64
-		if point.Timestamp > 0 {
-			// 使用数据点自带的时间戳
-			timestamp = point.Timestamp
-		} else {
-			// 根据位置倒推时间：最后一个点是当前时间，往前每个点减去一个采集间隔
-			// 例如：30个点，间隔1秒，第0个点是29秒前，第29个点是当前时间
-			offsetMs := int64(totalPoints-1-i) * intervalMs
-			timestamp = now - offsetMs
-		}
+		timestamp := timestamps[i]
 		
 		// 处理带宽数据
 		if len(point.Interfaces) > 0 {
@@ -400,6 +384,10 @@ func (h *DataReceiverHandler) PushRouterOSMetrics(c *gin.Context) {
 			} else {
 				processedCount++
 			}
+			// 记录接口链路状态变化（与带宽数据分开存储）
+			if err := h.dataReceiverService.ProcessLinkStates(c.Request.Context(), device.ID, timestamp, point.Interfaces, models.InterfaceEventSourcePush); err != nil {
+				log.Printf("Failed to process link states for device %d: %v", device.ID, err)
+			}
 		}
 		
 		// 处理 Ping 数据
@@ -410,6 +398,15 @@ func (h *DataReceiverHandler) PushRouterOSMetrics(c *gin.Context) {
 				processedCount++
 			}
 		}
+		
+		// 处理服务指标（PPP/DHCP/无线/队列，可选模块）
+		if point.Services != nil {
+			if err := h.dataReceiverService.ProcessServiceMetrics(c.Request.Context(), device.ID, timestamp, point.Services); err != nil {
+				log.Printf("Failed to process service metrics for device %d: %v", device.ID, err)
+			} else {
+				processedCount++
+			}
+		}
 	}
 	
 	// 更新设备状态和最后推送时间
@@ -646,4 +643,46 @@ func (h *DataReceiverHandler) GetAllDevicesStatus(c *gin.Context) {
 		"devices":   statuses,
 		"timestamp": time.Now().Format(time.RFC3339),
 	})
-}
\ No newline at end of file
+}
+
+// RegisterRoutesWithPermission 注册需要登录的数据接收相关路由（带设备权限检查）
+// readMiddleware 按路由参数 device_id 检查设备读取权限
+func (h *DataReceiverHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware gin.HandlerFunc) {
+	router.GET("/data/clock-skew/:device_id", readMiddleware, h.GetClockSkew)
+}
+
+// GetClockSkew 获取设备时钟偏差
+// @Summary 获取设备时钟偏差
+// @Description 获取根据推送数据测得的设备时钟偏差（服务器时间减去设备时间）
+// @Tags 数据查询
+// @Produce json
+// @Param device_id path string true "设备ID"
+// @Success 200 {object} models.ClockSkew "时钟偏差"
+// @Failure 400 {object} models.ErrorResponse "请求参数错误"
+// @Failure 404 {object} models.ErrorResponse "尚未测得时钟偏差"
+// @Router /api/v1/data/clock-skew/{device_id} [get]
+func (h *DataReceiverHandler) GetClockSkew(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("device_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的设备ID",
+		})
+		return
+	}
+	
+	skew, err := h.dataReceiverService.GetClockSkew(c.Request.Context(), uint(deviceID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{
+			Code:    http.StatusNotFound,
+			Message: "尚未测得时钟偏差",
+			Details: err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    skew,
+	})
+}
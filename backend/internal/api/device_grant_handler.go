@@ -0,0 +1,282 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceAccessResolver 用户有效设备权限的解析和缓存（由 auth.DevicePermissionChecker 实现）
+type DeviceAccessResolver interface {
+	EffectiveDevices(userID uint, action string) (allDevices bool, deviceIDs []uint, err error)
+	InvalidateDeviceAccess()
+}
+
+// DeviceGrantHandler 设备授权处理器
+type DeviceGrantHandler struct {
+	grantRepo repository.DeviceGrantRepository
+	resolver  DeviceAccessResolver
+}
+
+// NewDeviceGrantHandler 创建设备授权处理器
+func NewDeviceGrantHandler(grantRepo repository.DeviceGrantRepository, resolver DeviceAccessResolver) *DeviceGrantHandler {
+	return &DeviceGrantHandler{
+		grantRepo: grantRepo,
+		resolver:  resolver,
+	}
+}
+
+// DeviceGrantRequest 创建或修改设备授权请求
+// user_id 和 role_id 二选一；device_group_id、tag_id、device_id 至多指定一个，都不指定时授权全部设备
+type DeviceGrantRequest struct {
+	UserID        *uint    `json:"user_id"`
+	RoleID        *uint    `json:"role_id"`
+	DeviceGroupID *uint    `json:"device_group_id"`
+	TagID         *uint    `json:"tag_id"`
+	DeviceID      *uint    `json:"device_id"`
+	Actions       []string `json:"actions" binding:"required,min=1"` // read/update/delete/deploy
+}
+
+// toGrant 校验请求并写入设备授权
+func (r *DeviceGrantRequest) toGrant(grant *models.DeviceGrant) error {
+	if (r.UserID == nil) == (r.RoleID == nil) {
+		return fmt.Errorf("exactly one of user_id and role_id is required")
+	}
+	targets := 0
+	for _, id := range []*uint{r.DeviceGroupID, r.TagID, r.DeviceID} {
+		if id != nil {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return fmt.Errorf("at most one of device_group_id, tag_id and device_id can be set")
+	}
+
+	grant.UserID = r.UserID
+	grant.RoleID = r.RoleID
+	grant.DeviceGroupID = r.DeviceGroupID
+	grant.TagID = r.TagID
+	grant.DeviceID = r.DeviceID
+	grant.CanRead, grant.CanUpdate, grant.CanDelete, grant.CanDeploy = false, false, false, false
+	for _, action := range r.Actions {
+		switch action {
+		case models.DeviceActionRead:
+			grant.CanRead = true
+		case models.DeviceActionUpdate:
+			grant.CanUpdate = true
+		case models.DeviceActionDelete:
+			grant.CanDelete = true
+		case models.DeviceActionDeploy:
+			grant.CanDeploy = true
+		default:
+			return fmt.Errorf("invalid action %q, must be one of %s", action, strings.Join(models.DeviceActions, ", "))
+		}
+	}
+	return nil
+}
+
+// ListDeviceGrants 查询设备授权
+// @Summary 查询设备授权
+// @Description 按授权对象（用户、角色）或授权范围（分组、标签、设备）查询设备授权
+// @Tags 设备授权
+// @Produce json
+// @Param user_id query int false "用户ID"
+// @Param role_id query int false "角色ID"
+// @Param device_group_id query int false "设备分组ID"
+// @Param tag_id query int false "标签ID"
+// @Param device_id query int false "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /device-grants [get]
+func (h *DeviceGrantHandler) ListDeviceGrants(c *gin.Context) {
+	var filter repository.DeviceGrantFilter
+	params := map[string]**uint{
+		"user_id":         &filter.UserID,
+		"role_id":         &filter.RoleID,
+		"device_group_id": &filter.DeviceGroupID,
+		"tag_id":          &filter.TagID,
+		"device_id":       &filter.DeviceID,
+	}
+	for name, target := range params {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			BadRequest(c, "无效的 "+name)
+			return
+		}
+		parsed := uint(id)
+		*target = &parsed
+	}
+
+	grants, err := h.grantRepo.List(filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询设备授权失败", err.Error())
+		return
+	}
+
+	Success(c, grants)
+}
+
+// CreateDeviceGrant 创建设备授权
+// @Summary 创建设备授权
+// @Description 将设备分组（含全部下级分组）、标签、单台设备或全部设备上的操作授权给用户或角色，立即生效
+// @Tags 设备授权
+// @Accept json
+// @Produce json
+// @Param request body DeviceGrantRequest true "设备授权"
+// @Success 201 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /device-grants [post]
+func (h *DeviceGrantHandler) CreateDeviceGrant(c *gin.Context) {
+	var req DeviceGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的请求格式", err.Error())
+		return
+	}
+
+	grant := &models.DeviceGrant{CreatedBy: c.GetUint("user_id")}
+	if err := req.toGrant(grant); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的设备授权", err.Error())
+		return
+	}
+	if err := h.grantRepo.Create(grant); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "创建设备授权失败", err.Error())
+		return
+	}
+	h.resolver.InvalidateDeviceAccess()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    grant,
+	})
+}
+
+// UpdateDeviceGrant 修改设备授权
+// @Summary 修改设备授权
+// @Tags 设备授权
+// @Accept json
+// @Produce json
+// @Param id path int true "设备授权ID"
+// @Param request body DeviceGrantRequest true "设备授权"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /device-grants/{id} [put]
+func (h *DeviceGrantHandler) UpdateDeviceGrant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备授权ID")
+		return
+	}
+
+	var req DeviceGrantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的请求格式", err.Error())
+		return
+	}
+
+	grant, err := h.grantRepo.GetByID(uint(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			NotFound(c, "设备授权不存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取设备授权失败", err.Error())
+		return
+	}
+	if err := req.toGrant(grant); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的设备授权", err.Error())
+		return
+	}
+	if err := h.grantRepo.Update(grant); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "修改设备授权失败", err.Error())
+		return
+	}
+	h.resolver.InvalidateDeviceAccess()
+
+	Success(c, grant)
+}
+
+// DeleteDeviceGrant 删除设备授权
+// @Summary 删除设备授权
+// @Tags 设备授权
+// @Produce json
+// @Param id path int true "设备授权ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /device-grants/{id} [delete]
+func (h *DeviceGrantHandler) DeleteDeviceGrant(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备授权ID")
+		return
+	}
+
+	if err := h.grantRepo.Delete(uint(id)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			NotFound(c, "设备授权不存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "删除设备授权失败", err.Error())
+		return
+	}
+	h.resolver.InvalidateDeviceAccess()
+
+	SuccessWithMessage(c, nil, "设备授权已删除")
+}
+
+// GetEffectiveDevices 查询用户的有效设备权限
+// @Summary 查询用户的有效设备权限
+// @Description 汇总授权给用户本人和其角色的设备授权，列出用户可执行各操作的设备；all_devices 为 true 时可对全部设备执行
+// @Tags 设备授权
+// @Produce json
+// @Param user_id query int true "用户ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /device-grants/effective [get]
+func (h *DeviceGrantHandler) GetEffectiveDevices(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	result := make(map[string]gin.H, len(models.DeviceActions))
+	for _, action := range models.DeviceActions {
+		allDevices, deviceIDs, err := h.resolver.EffectiveDevices(uint(userID), action)
+		if err != nil {
+			ErrorWithDetails(c, http.StatusInternalServerError, "查询有效设备权限失败", err.Error())
+			return
+		}
+		result[action] = gin.H{
+			"all_devices": allDevices,
+			"device_ids":  deviceIDs,
+		}
+	}
+
+	Success(c, result)
+}
+
+// RegisterRoutesWithPermission 注册设备授权相关路由（带权限检查）
+func (h *DeviceGrantHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, adminMiddleware gin.HandlerFunc) {
+	grants := router.Group("/device-grants", adminMiddleware)
+	{
+		grants.GET("", h.ListDeviceGrants)
+		grants.POST("", h.CreateDeviceGrant)
+		grants.GET("/effective", h.GetEffectiveDevices)
+		grants.PUT("/:id", h.UpdateDeviceGrant)
+		grants.DELETE("/:id", h.DeleteDeviceGrant)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -48,6 +49,16 @@ type ListDeviceGroupsRequest struct {
 	ParentID *uint `form:"parent_id"`
 }
 
+// groups 返回限定到当前组织的设备分组服务
+func (h *DeviceGroupHandler) groups(c *gin.Context) service.DeviceGroupService {
+	return tenant.Bind(c.Request.Context(), h.deviceGroupService)
+}
+
+// devices 返回限定到当前组织的设备服务
+func (h *DeviceGroupHandler) devices(c *gin.Context) service.DeviceService {
+	return tenant.Bind(c.Request.Context(), h.deviceService)
+}
+
 // CreateDeviceGroup 创建设备分组
 func (h *DeviceGroupHandler) CreateDeviceGroup(c *gin.Context) {
 	var req CreateDeviceGroupRequest
@@ -66,7 +77,7 @@ func (h *DeviceGroupHandler) CreateDeviceGroup(c *gin.Context) {
 		ParentID:    req.ParentID,
 	}
 
-	err := h.deviceGroupService.CreateGroup(group)
+	err := h.groups(c).CreateGroup(group)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -91,7 +102,7 @@ func (h *DeviceGroupHandler) GetDeviceGroup(c *gin.Context) {
 		return
 	}
 
-	group, err := h.deviceGroupService.GetGroup(uint(id))
+	group, err := h.groups(c).GetGroup(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -126,7 +137,7 @@ func (h *DeviceGroupHandler) UpdateDeviceGroup(c *gin.Context) {
 	}
 
 	// 获取现有分组
-	group, err := h.deviceGroupService.GetGroup(uint(id))
+	group, err := h.groups(c).GetGroup(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -139,7 +150,7 @@ func (h *DeviceGroupHandler) UpdateDeviceGroup(c *gin.Context) {
 	group.Description = req.Description
 	group.ParentID = req.ParentID
 
-	err = h.deviceGroupService.UpdateGroup(group)
+	err = h.groups(c).UpdateGroup(group)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -164,7 +175,7 @@ func (h *DeviceGroupHandler) DeleteDeviceGroup(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceGroupService.DeleteGroup(uint(id))
+	err = h.groups(c).DeleteGroup(uint(id))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -195,7 +206,7 @@ func (h *DeviceGroupHandler) ListDeviceGroups(c *gin.Context) {
 		offset = 0
 	}
 
-	groups, total, err := h.deviceGroupService.ListGroups(offset, req.PageSize)
+	groups, total, err := h.groups(c).ListGroups(offset, req.PageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -220,7 +231,7 @@ func (h *DeviceGroupHandler) ListDeviceGroups(c *gin.Context) {
 
 // GetAllDeviceGroups 获取所有设备分组（不分页）
 func (h *DeviceGroupHandler) GetAllDeviceGroups(c *gin.Context) {
-	groups, err := h.deviceGroupService.GetAllGroups()
+	groups, err := h.groups(c).GetAllGroups()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -236,7 +247,7 @@ func (h *DeviceGroupHandler) GetAllDeviceGroups(c *gin.Context) {
 
 // GetRootDeviceGroups 获取根分组
 func (h *DeviceGroupHandler) GetRootDeviceGroups(c *gin.Context) {
-	groups, err := h.deviceGroupService.GetRootGroups()
+	groups, err := h.groups(c).GetRootGroups()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -261,7 +272,7 @@ func (h *DeviceGroupHandler) GetChildDeviceGroups(c *gin.Context) {
 		return
 	}
 
-	groups, err := h.deviceGroupService.GetChildGroups(uint(parentID))
+	groups, err := h.groups(c).GetChildGroups(uint(parentID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -286,7 +297,7 @@ func (h *DeviceGroupHandler) GetDeviceGroupDevices(c *gin.Context) {
 		return
 	}
 
-	devices, err := h.deviceService.GetDevicesByGroup(uint(groupID))
+	devices, err := h.devices(c).GetDevicesByGroup(uint(groupID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -295,7 +306,7 @@ func (h *DeviceGroupHandler) GetDeviceGroupDevices(c *gin.Context) {
 	}
 
 	// 获取设备数量
-	deviceCount, err := h.deviceGroupService.GetGroupDeviceCount(uint(groupID))
+	deviceCount, err := h.groups(c).GetGroupDeviceCount(uint(groupID))
 	if err != nil {
 		deviceCount = int64(len(devices))
 	}
@@ -3,12 +3,14 @@ package api
 import (
 	"context"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"nmp-platform/internal/collector"
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,6 +28,10 @@ type DeviceHandler struct {
 	pingTargetRepo repository.PingTargetRepository
 	deployer       *collector.Deployer
 	serverURL      string
+	// 用于检查组织的设备数量上限
+	organizationRepo repository.OrganizationRepository
+	// 设备增删后清除有效设备权限缓存
+	deviceAccess DeviceAccessResolver
 }
 
 // NewDeviceHandler 创建新的设备处理器
@@ -82,6 +88,49 @@ func NewDeviceHandlerFull(
 	}
 }
 
+// SetOrganizationRepository 设置组织仓库，设置后创建设备时检查组织的设备数量上限
+func (h *DeviceHandler) SetOrganizationRepository(organizationRepo repository.OrganizationRepository) {
+	h.organizationRepo = organizationRepo
+}
+
+// SetDeviceAccessResolver 设置有效设备权限解析器
+// 有效设备权限限定在组织的设备内，设置后创建和删除设备时清除缓存，新设备立即可以访问
+func (h *DeviceHandler) SetDeviceAccessResolver(resolver DeviceAccessResolver) {
+	h.deviceAccess = resolver
+}
+
+// invalidateDeviceAccess 组织的设备变化后清除有效设备权限缓存
+func (h *DeviceHandler) invalidateDeviceAccess() {
+	if h.deviceAccess != nil {
+		h.deviceAccess.InvalidateDeviceAccess()
+	}
+}
+
+// devices 返回限定到当前组织的设备服务
+func (h *DeviceHandler) devices(c *gin.Context) service.DeviceService {
+	return tenant.Bind(c.Request.Context(), h.deviceService)
+}
+
+// deviceQuotaExceeded 当前组织的设备数量是否已达到上限
+func (h *DeviceHandler) deviceQuotaExceeded(c *gin.Context) (bool, error) {
+	organizationID, ok := tenant.FromContext(c.Request.Context())
+	if h.organizationRepo == nil || !ok {
+		return false, nil
+	}
+	organization, err := h.organizationRepo.GetByID(organizationID)
+	if err != nil {
+		return false, err
+	}
+	if organization.MaxDevices <= 0 {
+		return false, nil
+	}
+	usage, err := h.organizationRepo.Usage(organizationID)
+	if err != nil {
+		return false, err
+	}
+	return usage.Devices >= int64(organization.MaxDevices), nil
+}
+
 // redeployCollectorScript 自动重新部署采集器脚本
 func (h *DeviceHandler) redeployCollectorScript(deviceID uint) error {
 	if h.deployer == nil || h.collectorRepo == nil || h.interfaceRepo == nil {
@@ -119,11 +168,7 @@ func (h *DeviceHandler) redeployCollectorScript(deviceID uint) error {
 
 	var pingTargetConfigs []collector.PingTargetConfig
 	for _, pt := range pingTargets {
-		pingTargetConfigs = append(pingTargetConfigs, collector.PingTargetConfig{
-			TargetAddress:   pt.TargetAddress,
-			TargetName:      pt.TargetName,
-			SourceInterface: pt.SourceInterface,
-		})
+		pingTargetConfigs = append(pingTargetConfigs, newPingTargetConfig(pt))
 	}
 
 	scriptConfig := &collector.ScriptConfig{
@@ -136,6 +181,7 @@ func (h *DeviceHandler) redeployCollectorScript(deviceID uint) error {
 		SchedulerName: config.SchedulerName,
 		Interfaces:    interfaceNames,
 		PingTargets:   pingTargetConfigs,
+		Modules:       newCollectorModules(config),
 	}
 
 	h.deployer.DeployToMikroTik(scriptConfig, device.Host, device.APIPort, device.Port, device.Username, device.Password)
@@ -260,8 +306,19 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 		device.Protocol = "ssh"
 	}
 
+	// 检查组织的设备数量上限
+	exceeded, err := h.deviceQuotaExceeded(c)
+	if err != nil {
+		InternalError(c, "检查设备数量上限失败")
+		return
+	}
+	if exceeded {
+		Forbidden(c, "设备数量已达到组织上限")
+		return
+	}
+
 	// 创建设备
-	err := h.deviceService.CreateDevice(device)
+	err = h.devices(c).CreateDevice(device)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -269,7 +326,7 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 
 	// 添加到分组
 	for _, groupID := range req.GroupIDs {
-		if err := h.deviceService.AddDeviceToGroup(device.ID, groupID); err != nil {
+		if err := h.devices(c).AddDeviceToGroup(device.ID, groupID); err != nil {
 			// 记录错误但不中断流程
 			continue
 		}
@@ -277,14 +334,15 @@ func (h *DeviceHandler) CreateDevice(c *gin.Context) {
 
 	// 添加标签
 	for _, tagID := range req.TagIDs {
-		if err := h.deviceService.AddDeviceTag(device.ID, tagID); err != nil {
+		if err := h.devices(c).AddDeviceTag(device.ID, tagID); err != nil {
 			// 记录错误但不中断流程
 			continue
 		}
 	}
+	h.invalidateDeviceAccess()
 
 	// 重新获取设备信息（包含关联数据）
-	createdDevice, err := h.deviceService.GetDevice(device.ID)
+	createdDevice, err := h.devices(c).GetDevice(device.ID)
 	if err != nil {
 		InternalError(c, "获取创建的设备失败")
 		return
@@ -305,7 +363,7 @@ func (h *DeviceHandler) GetDevice(c *gin.Context) {
 		return
 	}
 
-	device, err := h.deviceService.GetDevice(uint(id))
+	device, err := h.devices(c).GetDevice(uint(id))
 	if err != nil {
 		NotFound(c, err.Error())
 		return
@@ -330,7 +388,7 @@ func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 	}
 
 	// 获取现有设备
-	device, err := h.deviceService.GetDevice(uint(id))
+	device, err := h.devices(c).GetDevice(uint(id))
 	if err != nil {
 		NotFound(c, err.Error())
 		return
@@ -360,7 +418,7 @@ func (h *DeviceHandler) UpdateDevice(c *gin.Context) {
 	device.Description = req.Description
 	device.ProxyID = req.ProxyID
 
-	err = h.deviceService.UpdateDevice(device)
+	err = h.devices(c).UpdateDevice(device)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -386,11 +444,12 @@ func (h *DeviceHandler) DeleteDevice(c *gin.Context) {
 		}
 	}
 
-	err = h.deviceService.DeleteDevice(uint(id))
+	err = h.devices(c).DeleteDevice(uint(id))
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
 	}
+	h.invalidateDeviceAccess()
 
 	SuccessWithMessage(c, nil, "设备及其所有监控数据已删除")
 }
@@ -426,8 +485,12 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 	if req.Search != "" {
 		filters["search"] = req.Search
 	}
+	// API 令牌或设备授权限定了设备范围时只返回范围内的设备
+	if deviceIDs := scopedDeviceIDs(c); deviceIDs != nil {
+		filters["device_ids"] = deviceIDs
+	}
 
-	devices, total, err := h.deviceService.ListDevices(offset, req.PageSize, filters)
+	devices, total, err := h.devices(c).ListDevices(offset, req.PageSize, filters)
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -445,6 +508,25 @@ func (h *DeviceHandler) ListDevices(c *gin.Context) {
 	})
 }
 
+// scopedDeviceIDs 当前请求可访问的设备ID列表（按 ID 排序），由 API 令牌的设备范围和
+// 用户的设备授权决定（见 auth.DeviceListFilterMiddleware），为 nil 时不限定
+func scopedDeviceIDs(c *gin.Context) []uint {
+	if deviceScope, exists := c.Get("device_scope"); exists {
+		return deviceScope.([]uint)
+	}
+	return nil
+}
+
+// deviceInScope 设备是否在当前请求可访问的设备范围内
+func deviceInScope(c *gin.Context, deviceID uint) bool {
+	deviceIDs := scopedDeviceIDs(c)
+	if deviceIDs == nil {
+		return true
+	}
+	i := sort.Search(len(deviceIDs), func(i int) bool { return deviceIDs[i] >= deviceID })
+	return i < len(deviceIDs) && deviceIDs[i] == deviceID
+}
+
 // UpdateDeviceStatus 更新设备状态
 func (h *DeviceHandler) UpdateDeviceStatus(c *gin.Context) {
 	idStr := c.Param("id")
@@ -463,7 +545,7 @@ func (h *DeviceHandler) UpdateDeviceStatus(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceService.UpdateDeviceStatus(uint(id), req.Status)
+	err = h.devices(c).UpdateDeviceStatus(uint(id), req.Status)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -490,7 +572,7 @@ func (h *DeviceHandler) AddDeviceToGroup(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceService.AddDeviceToGroup(uint(deviceID), req.GroupID)
+	err = h.devices(c).AddDeviceToGroup(uint(deviceID), req.GroupID)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -515,7 +597,7 @@ func (h *DeviceHandler) RemoveDeviceFromGroup(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceService.RemoveDeviceFromGroup(uint(deviceID), uint(groupID))
+	err = h.devices(c).RemoveDeviceFromGroup(uint(deviceID), uint(groupID))
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -542,7 +624,7 @@ func (h *DeviceHandler) AddDeviceTag(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceService.AddDeviceTag(uint(deviceID), req.TagID)
+	err = h.devices(c).AddDeviceTag(uint(deviceID), req.TagID)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -567,7 +649,7 @@ func (h *DeviceHandler) RemoveDeviceTag(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceService.RemoveDeviceTag(uint(deviceID), uint(tagID))
+	err = h.devices(c).RemoveDeviceTag(uint(deviceID), uint(tagID))
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -585,7 +667,7 @@ func (h *DeviceHandler) GetDeviceInterfaces(c *gin.Context) {
 		return
 	}
 
-	interfaces, err := h.deviceService.GetDeviceInterfaces(uint(deviceID))
+	interfaces, err := h.devices(c).GetDeviceInterfaces(uint(deviceID))
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -612,7 +694,7 @@ func (h *DeviceHandler) UpdateInterfaceMonitorStatus(c *gin.Context) {
 		return
 	}
 
-	err = h.deviceService.UpdateInterfaceMonitorStatus(uint(interfaceID), req.Monitor)
+	err = h.devices(c).UpdateInterfaceMonitorStatus(uint(interfaceID), req.Monitor)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -651,7 +733,7 @@ func (h *DeviceHandler) TestConnection(c *gin.Context) {
 		OSType:   req.OSType,
 	}
 
-	result, err := h.deviceService.TestConnection(device, req.ConnectionType)
+	result, err := h.devices(c).TestConnection(device, req.ConnectionType)
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -672,13 +754,13 @@ func (h *DeviceHandler) TestConnectionByID(c *gin.Context) {
 	// 获取连接类型参数
 	connectionType := c.DefaultQuery("type", "all")
 
-	device, err := h.deviceService.GetDevice(uint(id))
+	device, err := h.devices(c).GetDevice(uint(id))
 	if err != nil {
 		NotFound(c, err.Error())
 		return
 	}
 
-	result, err := h.deviceService.TestConnection(device, connectionType)
+	result, err := h.devices(c).TestConnection(device, connectionType)
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -696,7 +778,7 @@ func (h *DeviceHandler) GetSystemInfo(c *gin.Context) {
 		return
 	}
 
-	info, err := h.deviceService.GetSystemInfo(uint(id))
+	info, err := h.devices(c).GetSystemInfo(uint(id))
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -714,7 +796,7 @@ func (h *DeviceHandler) SyncInterfaces(c *gin.Context) {
 		return
 	}
 
-	interfaces, err := h.deviceService.SyncInterfacesFromDevice(uint(id))
+	interfaces, err := h.devices(c).SyncInterfacesFromDevice(uint(id))
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -742,7 +824,7 @@ func (h *DeviceHandler) SetMonitoredInterfaces(c *gin.Context) {
 	}
 
 	// 获取当前监控的接口列表（用于检测被移除的接口）
-	currentInterfaces, err := h.deviceService.GetMonitoredInterfaces(uint(id))
+	currentInterfaces, err := h.devices(c).GetMonitoredInterfaces(uint(id))
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -762,7 +844,7 @@ func (h *DeviceHandler) SetMonitoredInterfaces(c *gin.Context) {
 		}
 	}
 
-	err = h.deviceService.SetMonitoredInterfaces(uint(id), req.InterfaceNames)
+	err = h.devices(c).SetMonitoredInterfaces(uint(id), req.InterfaceNames)
 	if err != nil {
 		BadRequest(c, err.Error())
 		return
@@ -779,7 +861,7 @@ func (h *DeviceHandler) SetMonitoredInterfaces(c *gin.Context) {
 	}
 
 	// 返回更新后的接口列表
-	interfaces, err := h.deviceService.GetDeviceInterfaces(uint(id))
+	interfaces, err := h.devices(c).GetDeviceInterfaces(uint(id))
 	if err != nil {
 		InternalError(c, err.Error())
 		return
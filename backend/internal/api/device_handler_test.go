@@ -460,7 +460,7 @@ func TestDeviceHandler_UpdateDeviceStatus(t *testing.T) {
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 	assert.True(t, response["success"].(bool))
-	assert.Equal(t, "Device status updated successfully", response["message"])
+	assert.Equal(t, "设备状态更新成功", response["message"])
 
 	// 验证模拟调用
 	mockDeviceService.AssertExpectations(t)
@@ -639,6 +639,9 @@ func TestDeviceHandler_SetMonitoredInterfaces(t *testing.T) {
 	router.PUT("/devices/:id/interfaces/monitored", handler.SetMonitoredInterfaces)
 
 	// 设置模拟期望
+	mockDeviceService.On("GetMonitoredInterfaces", uint(1)).Return([]*models.Interface{
+		{ID: 1, DeviceID: 1, Name: "ether1", Status: models.InterfaceStatusUp, Monitored: true},
+	}, nil)
 	mockDeviceService.On("SetMonitoredInterfaces", uint(1), []string{"ether1", "ether2"}).Return(nil)
 	mockDeviceService.On("GetDeviceInterfaces", uint(1)).Return([]*models.Interface{
 		{ID: 1, DeviceID: 1, Name: "ether1", Status: models.InterfaceStatusUp, Monitored: true},
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultTopTalkersLimit 流量 Top N 默认条目数
+	defaultTopTalkersLimit = 10
+	// maxTopTalkersLimit 流量 Top N 最大条目数
+	maxTopTalkersLimit = 100
+)
+
+// FlowHandler 流量分析处理器
+type FlowHandler struct {
+	dataQueryService *service.DataQueryService
+	deviceRepo       repository.DeviceRepository
+	deployer         *collector.Deployer
+	targetHost       string // 设备导出流量的目标地址（平台地址）
+	targetPort       int
+}
+
+// NewFlowHandler 创建流量分析处理器
+func NewFlowHandler(
+	dataQueryService *service.DataQueryService,
+	deviceRepo repository.DeviceRepository,
+	serverURL string,
+	targetHost string,
+	targetPort int,
+) *FlowHandler {
+	return &FlowHandler{
+		dataQueryService: dataQueryService,
+		deviceRepo:       deviceRepo,
+		deployer:         collector.NewDeployer(serverURL),
+		targetHost:       targetHost,
+		targetPort:       targetPort,
+	}
+}
+
+// ConfigureTrafficFlowRequest 配置设备流量导出请求
+type ConfigureTrafficFlowRequest struct {
+	Target     string `json:"target"`     // 为空使用平台地址
+	Port       int    `json:"port"`       // 为空使用平台流量采集端口
+	Version    string `json:"version"`    // 5、9 或 ipfix，默认 9
+	Interfaces string `json:"interfaces"` // 导出的接口，默认 all
+}
+
+// GetTopTalkers 查询设备或接口的流量 Top N
+// @Summary 查询流量 Top N
+// @Description 按源地址、目的地址、端口或协议统计设备（或指定接口入/出方向）在时间范围内的流量排名
+// @Tags 流量分析
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param by query string false "统计维度：src/dst/port/protocol，默认 src"
+// @Param interface query string false "接口名称"
+// @Param if_index query int false "接口 ifIndex"
+// @Param direction query string false "接口方向：in/out，默认 in（未指定接口时为设备整体）"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param limit query int false "返回条目数，默认10，最大100"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/flows/top [get]
+func (h *FlowHandler) GetTopTalkers(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+	if _, err := h.deviceRepo.GetByID(uint(deviceID)); err != nil {
+		NotFound(c, "设备不存在")
+		return
+	}
+
+	startTime, endTime, err := parseEventTimeRange(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+
+	req := &service.TopTalkersRequest{
+		DeviceID:  strconv.FormatUint(deviceID, 10),
+		Interface: c.Query("interface"),
+		Dimension: c.DefaultQuery("by", service.FlowDimensionSrc),
+		Direction: service.FlowDirectionAll,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	validDimension := false
+	for _, dimension := range service.FlowDimensions {
+		if req.Dimension == dimension {
+			validDimension = true
+			break
+		}
+	}
+	if !validDimension {
+		BadRequest(c, "无效的统计维度，可选值: src, dst, port, protocol")
+		return
+	}
+
+	if ifIndexStr := c.Query("if_index"); ifIndexStr != "" {
+		req.IfIndex, err = strconv.Atoi(ifIndexStr)
+		if err != nil || req.IfIndex < 0 {
+			BadRequest(c, "无效的 if_index")
+			return
+		}
+	}
+
+	if req.Interface != "" || req.IfIndex > 0 {
+		req.Direction = c.DefaultQuery("direction", service.FlowDirectionIn)
+		if req.Direction != service.FlowDirectionIn && req.Direction != service.FlowDirectionOut {
+			BadRequest(c, "无效的方向，可选值: in, out")
+			return
+		}
+	}
+
+	req.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultTopTalkersLimit)))
+	if err != nil || req.Limit < 1 || req.Limit > maxTopTalkersLimit {
+		BadRequest(c, "limit 必须在 1 到 100 之间")
+		return
+	}
+
+	response, err := h.dataQueryService.QueryTopTalkers(c.Request.Context(), req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "查询流量 Top N 失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	Success(c, response)
+}
+
+// ConfigureDeviceTrafficFlow 配置 RouterOS 设备将流量导出到平台
+// @Summary 配置设备流量导出
+// @Description 在 RouterOS 设备上启用 /ip traffic-flow 并添加指向平台流量采集器的导出目标
+// @Tags 流量分析
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param request body ConfigureTrafficFlowRequest false "导出配置"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/traffic-flow [post]
+func (h *FlowHandler) ConfigureDeviceTrafficFlow(c *gin.Context) {
+	device, ok := h.getMikroTikDevice(c)
+	if !ok {
+		return
+	}
+
+	var req ConfigureTrafficFlowRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "无效的请求格式")
+			return
+		}
+	}
+
+	cfg := &collector.TrafficFlowConfig{
+		Target:     req.Target,
+		Port:       req.Port,
+		Version:    req.Version,
+		Interfaces: req.Interfaces,
+	}
+	if cfg.Target == "" {
+		cfg.Target = h.targetHost
+	}
+	if cfg.Port == 0 {
+		cfg.Port = h.targetPort
+	}
+
+	result := h.deployer.ConfigureTrafficFlow(cfg, device.Host, device.APIPort, device.Port, device.Username, device.Password)
+	if !result.Success {
+		ErrorWithDetails(c, http.StatusInternalServerError, "配置流量导出失败", result.ErrorMessage)
+		return
+	}
+
+	Success(c, result)
+}
+
+// RemoveDeviceTrafficFlow 移除 RouterOS 设备上指向平台的流量导出
+// @Summary 移除设备流量导出
+// @Description 移除指向平台的导出目标，设备上没有其他导出目标时关闭 traffic-flow
+// @Tags 流量分析
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/traffic-flow [delete]
+func (h *FlowHandler) RemoveDeviceTrafficFlow(c *gin.Context) {
+	device, ok := h.getMikroTikDevice(c)
+	if !ok {
+		return
+	}
+
+	result := h.deployer.RemoveTrafficFlow(h.targetHost, h.targetPort, device.Host, device.APIPort, device.Port, device.Username, device.Password)
+	if !result.Success {
+		ErrorWithDetails(c, http.StatusInternalServerError, "移除流量导出失败", result.ErrorMessage)
+		return
+	}
+
+	Success(c, result)
+}
+
+// getMikroTikDevice 获取路径中的 MikroTik 设备，失败时直接写入错误响应
+func (h *FlowHandler) getMikroTikDevice(c *gin.Context) (*models.Device, bool) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return nil, false
+	}
+
+	device, err := h.deviceRepo.GetByID(uint(deviceID))
+	if err != nil {
+		NotFound(c, "设备不存在")
+		return nil, false
+	}
+	if device.OSType != models.DeviceOSTypeMikroTik {
+		BadRequest(c, "仅支持 MikroTik RouterOS 设备")
+		return nil, false
+	}
+
+	return device, true
+}
+
+// RegisterRoutesWithPermission 注册流量分析相关路由（带权限检查）
+func (h *FlowHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware, updateMiddleware gin.HandlerFunc) {
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/flows/top", readMiddleware, h.GetTopTalkers)
+		devices.POST("/:id/traffic-flow", updateMiddleware, h.ConfigureDeviceTrafficFlow)
+		devices.DELETE("/:id/traffic-flow", updateMiddleware, h.RemoveDeviceTrafficFlow)
+	}
+}
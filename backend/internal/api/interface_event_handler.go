@@ -0,0 +1,200 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultEventRange 事件查询默认时间范围
+	defaultEventRange = 24 * time.Hour
+	// maxEventRange 事件查询最大时间范围
+	maxEventRange = 31 * 24 * time.Hour
+	// defaultEventLimit 事件查询默认返回条数
+	defaultEventLimit = 500
+	// maxEventLimit 事件查询最大返回条数
+	maxEventLimit = 5000
+)
+
+// InterfaceEventHandler 接口链路状态事件处理器
+type InterfaceEventHandler struct {
+	linkStateService *service.LinkStateService
+}
+
+// NewInterfaceEventHandler 创建接口链路状态事件处理器
+func NewInterfaceEventHandler(linkStateService *service.LinkStateService) *InterfaceEventHandler {
+	return &InterfaceEventHandler{
+		linkStateService: linkStateService,
+	}
+}
+
+// GetInterfaceEvents 获取接口状态变化时间线
+// @Summary 获取接口状态变化时间线
+// @Description 按时间倒序返回接口链路状态转换事件，可按接口名称过滤
+// @Tags 接口管理
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param interface query string false "接口名称，为空返回所有接口"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param limit query int false "返回条数，默认500，最大5000"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/interfaces/events [get]
+func (h *InterfaceEventHandler) GetInterfaceEvents(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的设备ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	startTime, endTime, err := parseEventTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的时间范围",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	limit := defaultEventLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxEventLimit {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "无效的 limit 参数",
+				Details: fmt.Sprintf("limit must be between 1 and %d", maxEventLimit),
+			})
+			return
+		}
+	}
+
+	interfaceName := c.Query("interface")
+	events, err := h.linkStateService.GetTimeline(c.Request.Context(), uint(deviceID), interfaceName, startTime, endTime, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取接口事件失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"device_id":  uint(deviceID),
+			"interface":  interfaceName,
+			"start_time": startTime,
+			"end_time":   endTime,
+			"events":     events,
+		},
+	})
+}
+
+// GetInterfaceFlaps 获取接口抖动次数
+// @Summary 获取接口抖动次数
+// @Description 统计时间范围内各接口 up->down 的次数及状态变化总次数，按抖动次数降序
+// @Tags 接口管理
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/interfaces/flaps [get]
+func (h *InterfaceEventHandler) GetInterfaceFlaps(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的设备ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	startTime, endTime, err := parseEventTimeRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的时间范围",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	counts, err := h.linkStateService.GetFlapCounts(c.Request.Context(), uint(deviceID), startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取接口抖动统计失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"device_id":  uint(deviceID),
+			"start_time": startTime,
+			"end_time":   endTime,
+			"interfaces": counts,
+		},
+	})
+}
+
+// parseEventTimeRange 解析事件查询时间范围
+func parseEventTimeRange(c *gin.Context) (time.Time, time.Time, error) {
+	endTime := time.Now()
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end_time format, use RFC3339")
+		}
+		endTime = t
+	}
+
+	startTime := endTime.Add(-defaultEventRange)
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		t, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start_time format, use RFC3339")
+		}
+		startTime = t
+	}
+
+	if startTime.After(endTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start_time must be before end_time")
+	}
+	if endTime.Sub(startTime) > maxEventRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("time range cannot exceed %v", maxEventRange)
+	}
+
+	return startTime, endTime, nil
+}
+
+// RegisterRoutesWithPermission 注册接口事件相关路由（带权限检查）
+func (h *InterfaceEventHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware gin.HandlerFunc) {
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/interfaces/events", readMiddleware, h.GetInterfaceEvents)
+		devices.GET("/:id/interfaces/flaps", readMiddleware, h.GetInterfaceFlaps)
+	}
+}
@@ -101,11 +101,7 @@ func (h *InterfaceHandler) redeployCollectorScript(deviceID uint) error {
 
 	var pingTargetConfigs []collector.PingTargetConfig
 	for _, pt := range pingTargets {
-		pingTargetConfigs = append(pingTargetConfigs, collector.PingTargetConfig{
-			TargetAddress:   pt.TargetAddress,
-			TargetName:      pt.TargetName,
-			SourceInterface: pt.SourceInterface,
-		})
+		pingTargetConfigs = append(pingTargetConfigs, newPingTargetConfig(pt))
 	}
 
 	scriptConfig := &collector.ScriptConfig{
@@ -118,6 +114,7 @@ func (h *InterfaceHandler) redeployCollectorScript(deviceID uint) error {
 		SchedulerName: config.SchedulerName,
 		Interfaces:    interfaceNames,
 		PingTargets:   pingTargetConfigs,
+		Modules:       newCollectorModules(config),
 	}
 
 	h.deployer.DeployToMikroTik(scriptConfig, device.Host, device.APIPort, device.Port, device.Username, device.Password)
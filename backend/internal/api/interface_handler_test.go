@@ -131,6 +131,7 @@ func TestInterfaceHandler_SetMonitoredInterfaces(t *testing.T) {
 	handler.RegisterRoutes(router.Group("/api"))
 
 	// 设置模拟期望
+	mockDeviceService.On("GetMonitoredInterfaces", uint(1)).Return([]*models.Interface{}, nil)
 	mockDeviceService.On("SetMonitoredInterfaces", uint(1), []string{"ether1", "ether2"}).Return(nil)
 	mockDeviceService.On("GetDeviceInterfaces", uint(1)).Return([]*models.Interface{
 		{ID: 1, DeviceID: 1, Name: "ether1", Status: models.InterfaceStatusUp, Monitored: true},
@@ -318,6 +319,7 @@ func TestInterfaceDataConsistency_Property(t *testing.T) {
 		// 模拟设置监控接口
 		selectedInterfaces := []string{"eth0"}
 		
+		mockDeviceService.On("GetMonitoredInterfaces", uint(3)).Return([]*models.Interface{}, nil)
 		mockDeviceService.On("SetMonitoredInterfaces", uint(3), selectedInterfaces).Return(nil)
 		mockDeviceService.On("GetDeviceInterfaces", uint(3)).Return([]*models.Interface{
 			{ID: 1, DeviceID: 3, Name: "eth0", Status: models.InterfaceStatusUp, Monitored: true},
@@ -358,6 +360,7 @@ func TestInterfaceDataConsistency_Property(t *testing.T) {
 		// 模拟清空所有监控接口
 		emptyInterfaces := []string{}
 		
+		mockDeviceService.On("GetMonitoredInterfaces", uint(4)).Return([]*models.Interface{}, nil)
 		mockDeviceService.On("SetMonitoredInterfaces", uint(4), emptyInterfaces).Return(nil)
 		mockDeviceService.On("GetDeviceInterfaces", uint(4)).Return([]*models.Interface{
 			{ID: 1, DeviceID: 4, Name: "eth0", Status: models.InterfaceStatusUp, Monitored: false},
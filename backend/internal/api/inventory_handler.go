@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultInventoryPageSize 设备清单默认每页条数
+	defaultInventoryPageSize = 50
+	// maxInventoryPageSize 设备清单最大每页条数
+	maxInventoryPageSize = 500
+)
+
+// inventoryCSVHeader 清单 CSV 导出的列
+var inventoryCSVHeader = []string{
+	"device_id", "device_name", "host", "os_type", "vendor", "model", "board_name", "serial_number",
+	"architecture", "cpu_model", "cpu_count", "memory_total", "os_version", "firmware_version",
+	"upgrade_firmware", "kernel", "distro", "disk_total", "packages", "collected_at",
+}
+
+// InventoryHandler 设备清单处理器
+type InventoryHandler struct {
+	inventoryService *service.InventoryService
+	deviceRepo       repository.DeviceRepository
+}
+
+// NewInventoryHandler 创建设备清单处理器
+func NewInventoryHandler(inventoryService *service.InventoryService, deviceRepo repository.DeviceRepository) *InventoryHandler {
+	return &InventoryHandler{
+		inventoryService: inventoryService,
+		deviceRepo:       deviceRepo,
+	}
+}
+
+// ListInventory 查询设备清单
+// @Summary 查询设备清单
+// @Description 按系统类型、分组、型号、架构、软件包和版本范围查询所有设备的硬件/软件清单，如 os_type=mikrotik&version_lt=7.12
+// @Tags 设备清单
+// @Produce json
+// @Param os_type query string false "系统类型：mikrotik/linux"
+// @Param group_id query int false "设备分组ID"
+// @Param model query string false "型号（模糊匹配）"
+// @Param architecture query string false "架构"
+// @Param package query string false "已安装的软件包名"
+// @Param version_lt query string false "系统版本低于"
+// @Param version_gte query string false "系统版本不低于"
+// @Param firmware_outdated query bool false "只返回 RouterBOOT 固件可升级的设备"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /inventory [get]
+func (h *InventoryHandler) ListInventory(c *gin.Context) {
+	filter, err := parseInventoryFilter(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultInventoryPageSize)))
+	if pageSize < 1 || pageSize > maxInventoryPageSize {
+		BadRequest(c, fmt.Sprintf("page_size 必须在 1 到 %d 之间", maxInventoryPageSize))
+		return
+	}
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	records, total, err := h.inventoryService.ListInventory(c.Request.Context(), filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询设备清单失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, records, total, page, pageSize)
+}
+
+// ExportInventory 导出设备清单为 CSV
+// @Summary 导出设备清单
+// @Description 按与查询接口相同的条件导出全部匹配设备的清单（CSV）
+// @Tags 设备清单
+// @Produce text/csv
+// @Param os_type query string false "系统类型：mikrotik/linux"
+// @Param group_id query int false "设备分组ID"
+// @Param model query string false "型号（模糊匹配）"
+// @Param architecture query string false "架构"
+// @Param package query string false "已安装的软件包名"
+// @Param version_lt query string false "系统版本低于"
+// @Param version_gte query string false "系统版本不低于"
+// @Param firmware_outdated query bool false "只返回 RouterBOOT 固件可升级的设备"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /inventory/export [get]
+func (h *InventoryHandler) ExportInventory(c *gin.Context) {
+	filter, err := parseInventoryFilter(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+
+	records, _, err := h.inventoryService.ListInventory(c.Request.Context(), filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "导出设备清单失败", err.Error())
+		return
+	}
+
+	fileName := fmt.Sprintf("inventory-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", fileName))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(inventoryCSVHeader)
+	for _, record := range records {
+		writer.Write(inventoryCSVRow(record))
+	}
+	writer.Flush()
+}
+
+// GetDeviceInventory 获取设备清单
+// @Summary 获取设备清单
+// @Tags 设备清单
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/inventory [get]
+func (h *InventoryHandler) GetDeviceInventory(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	inventory, err := h.inventoryService.GetInventory(c.Request.Context(), uint(deviceID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "尚未采集设备清单")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取设备清单失败", err.Error())
+		return
+	}
+
+	Success(c, inventory)
+}
+
+// RefreshDeviceInventory 立即刷新设备清单
+// @Summary 刷新设备清单
+// @Description 立即通过采集器获取设备的硬件/软件清单，字段变化时记录变更历史
+// @Tags 设备清单
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/inventory/refresh [post]
+func (h *InventoryHandler) RefreshDeviceInventory(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	device, err := h.deviceRepo.GetByID(uint(deviceID))
+	if err != nil {
+		NotFound(c, "设备不存在")
+		return
+	}
+
+	inventory, err := h.inventoryService.RefreshDevice(device)
+	if err != nil {
+		if errors.Is(err, service.ErrInventoryUnsupported) {
+			BadRequest(c, "该设备类型不支持清单采集")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "刷新设备清单失败", err.Error())
+		return
+	}
+
+	Success(c, inventory)
+}
+
+// ListDeviceInventoryChanges 查询设备清单变更历史
+// @Summary 查询设备清单变更历史
+// @Description 返回型号、序列号、版本、固件、软件包等字段的变化记录，按时间倒序
+// @Tags 设备清单
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/inventory/changes [get]
+func (h *InventoryHandler) ListDeviceInventoryChanges(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultInventoryPageSize)))
+	if pageSize < 1 || pageSize > maxInventoryPageSize {
+		BadRequest(c, fmt.Sprintf("page_size 必须在 1 到 %d 之间", maxInventoryPageSize))
+		return
+	}
+
+	changes, total, err := h.inventoryService.ListChanges(c.Request.Context(), uint(deviceID), (page-1)*pageSize, pageSize)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询清单变更历史失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, changes, total, page, pageSize)
+}
+
+// parseInventoryFilter 解析清单查询条件
+func parseInventoryFilter(c *gin.Context) (repository.DeviceInventoryFilter, error) {
+	filter := repository.DeviceInventoryFilter{
+		OSType:       models.DeviceOSType(c.Query("os_type")),
+		Model:        c.Query("model"),
+		Architecture: c.Query("architecture"),
+		Package:      c.Query("package"),
+		DeviceIDs:    scopedDeviceIDs(c),
+	}
+
+	if value := c.Query("group_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid group_id: %v", err)
+		}
+		groupID := uint(id)
+		filter.GroupID = &groupID
+	}
+
+	for param, target := range map[string]*string{
+		"version_lt":  &filter.VersionBelow,
+		"version_gte": &filter.VersionAtLeast,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		key := models.VersionSortKey(value)
+		if key == "" {
+			return filter, fmt.Errorf("invalid %s: %s", param, value)
+		}
+		*target = key
+	}
+
+	if value := c.Query("firmware_outdated"); value != "" {
+		outdated, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid firmware_outdated: %v", err)
+		}
+		filter.FirmwareOutdated = outdated
+	}
+
+	return filter, nil
+}
+
+// inventoryCSVRow 将清单记录转换为 CSV 行，软件包格式为 "名称 版本" 以分号分隔
+func inventoryCSVRow(record *repository.DeviceInventoryRecord) []string {
+	var packages []string
+	for name, version := range service.InventoryPackageVersions(record.Packages) {
+		packages = append(packages, strings.TrimSpace(name+" "+version))
+	}
+	sort.Strings(packages)
+
+	return []string{
+		strconv.FormatUint(uint64(record.DeviceID), 10),
+		record.DeviceName,
+		record.DeviceHost,
+		string(record.DeviceOSType),
+		record.Vendor,
+		record.Model,
+		record.BoardName,
+		record.SerialNumber,
+		record.Architecture,
+		record.CPUModel,
+		strconv.Itoa(record.CPUCount),
+		strconv.FormatInt(record.MemoryTotal, 10),
+		record.OSVersion,
+		record.FirmwareVersion,
+		record.UpgradeFirmware,
+		record.Kernel,
+		record.Distro,
+		strconv.FormatInt(record.DiskTotal, 10),
+		strings.Join(packages, "; "),
+		record.CollectedAt.Format(time.RFC3339),
+	}
+}
+
+// RegisterRoutesWithPermission 注册设备清单相关路由（带权限检查）
+func (h *InventoryHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware, updateMiddleware gin.HandlerFunc) {
+	router.GET("/inventory", h.ListInventory)
+	router.GET("/inventory/export", h.ExportInventory)
+
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/inventory", readMiddleware, h.GetDeviceInventory)
+		devices.POST("/:id/inventory/refresh", updateMiddleware, h.RefreshDeviceInventory)
+		devices.GET("/:id/inventory/changes", readMiddleware, h.ListDeviceInventoryChanges)
+	}
+}
@@ -0,0 +1,259 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHandler 组织（租户）管理处理器，只对全局超级管理员开放
+type OrganizationHandler struct {
+	organizationRepo repository.OrganizationRepository
+	deviceAccess     DeviceAccessResolver
+}
+
+// NewOrganizationHandler 创建组织管理处理器
+func NewOrganizationHandler(organizationRepo repository.OrganizationRepository) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationRepo: organizationRepo,
+	}
+}
+
+// SetDeviceAccessResolver 设置有效设备权限解析器，组织修改或删除后清除缓存
+func (h *OrganizationHandler) SetDeviceAccessResolver(resolver DeviceAccessResolver) {
+	h.deviceAccess = resolver
+}
+
+// invalidateDeviceAccess 组织变化后清除有效设备权限缓存
+func (h *OrganizationHandler) invalidateDeviceAccess() {
+	if h.deviceAccess != nil {
+		h.deviceAccess.InvalidateDeviceAccess()
+	}
+}
+
+// OrganizationRequest 创建或修改组织请求
+type OrganizationRequest struct {
+	Name          string `json:"name" binding:"required,max=50"`
+	DisplayName   string `json:"display_name" binding:"max=100"`
+	Description   string `json:"description" binding:"max=255"`
+	MaxDevices    int    `json:"max_devices" binding:"min=0"`    // 设备数量上限，0 表示不限制
+	RetentionDays int    `json:"retention_days" binding:"min=0"` // 时序数据保留天数，0 使用系统设置
+	Enabled       *bool  `json:"enabled"`
+}
+
+// apply 将请求写入组织
+func (r *OrganizationRequest) apply(organization *models.Organization) {
+	organization.Name = r.Name
+	organization.DisplayName = r.DisplayName
+	organization.Description = r.Description
+	organization.MaxDevices = r.MaxDevices
+	organization.RetentionDays = r.RetentionDays
+	if r.Enabled != nil {
+		organization.Enabled = *r.Enabled
+	}
+}
+
+// OrganizationWithUsage 带资源用量的组织
+type OrganizationWithUsage struct {
+	*models.Organization
+	Usage *repository.OrganizationUsage `json:"usage"`
+}
+
+// ListOrganizations 获取组织列表
+// @Summary 获取组织列表
+// @Description 列出全部组织及其设备数、用户数
+// @Tags 组织管理
+// @Produce json
+// @Success 200 {object} SuccessResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /organizations [get]
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	organizations, err := h.organizationRepo.List()
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取组织列表失败", err.Error())
+		return
+	}
+
+	result := make([]OrganizationWithUsage, 0, len(organizations))
+	for _, organization := range organizations {
+		usage, err := h.organizationRepo.Usage(organization.ID)
+		if err != nil {
+			ErrorWithDetails(c, http.StatusInternalServerError, "获取组织用量失败", err.Error())
+			return
+		}
+		result = append(result, OrganizationWithUsage{Organization: organization, Usage: usage})
+	}
+
+	Success(c, result)
+}
+
+// GetOrganization 获取组织详情
+// @Summary 获取组织详情
+// @Tags 组织管理
+// @Produce json
+// @Param id path int true "组织ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id} [get]
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	organization, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.organizationRepo.Usage(organization.ID)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取组织用量失败", err.Error())
+		return
+	}
+
+	Success(c, OrganizationWithUsage{Organization: organization, Usage: usage})
+}
+
+// CreateOrganization 创建组织
+// @Summary 创建组织
+// @Description 创建新的组织，组织之间的设备、分组、标签、代理、Ping 目标、设置和用户互相隔离
+// @Tags 组织管理
+// @Accept json
+// @Produce json
+// @Param request body OrganizationRequest true "组织"
+// @Success 201 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /organizations [post]
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的请求格式", err.Error())
+		return
+	}
+
+	organization := &models.Organization{Enabled: true}
+	req.apply(organization)
+	if err := h.organizationRepo.Create(organization); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			Conflict(c, "组织名称已存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "创建组织失败", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    organization,
+	})
+}
+
+// UpdateOrganization 修改组织
+// @Summary 修改组织
+// @Description 修改组织信息、设备数量上限和数据保留天数；停用后组织内用户无法访问
+// @Tags 组织管理
+// @Accept json
+// @Produce json
+// @Param id path int true "组织ID"
+// @Param request body OrganizationRequest true "组织"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id} [put]
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	organization, ok := h.loadOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的请求格式", err.Error())
+		return
+	}
+	if organization.ID == models.DefaultOrganizationID && req.Enabled != nil && !*req.Enabled {
+		BadRequest(c, "默认组织不能停用")
+		return
+	}
+
+	req.apply(organization)
+	if err := h.organizationRepo.Update(organization); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "修改组织失败", err.Error())
+		return
+	}
+	h.invalidateDeviceAccess()
+
+	Success(c, organization)
+}
+
+// DeleteOrganization 删除组织
+// @Summary 删除组织
+// @Description 删除组织，默认组织和仍有设备或用户的组织不能删除
+// @Tags 组织管理
+// @Produce json
+// @Param id path int true "组织ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id} [delete]
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return
+	}
+	if uint(id) == models.DefaultOrganizationID {
+		BadRequest(c, "默认组织不能删除")
+		return
+	}
+
+	if err := h.organizationRepo.Delete(uint(id)); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			NotFound(c, "组织不存在")
+		case strings.Contains(err.Error(), "still has"):
+			BadRequest(c, "组织内仍有设备或用户，不能删除")
+		default:
+			ErrorWithDetails(c, http.StatusInternalServerError, "删除组织失败", err.Error())
+		}
+		return
+	}
+	h.invalidateDeviceAccess()
+
+	SuccessWithMessage(c, nil, "组织已删除")
+}
+
+// loadOrganization 根据路径参数加载组织，失败时写入响应
+func (h *OrganizationHandler) loadOrganization(c *gin.Context) (*models.Organization, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的组织ID")
+		return nil, false
+	}
+
+	organization, err := h.organizationRepo.GetByID(uint(id))
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			NotFound(c, "组织不存在")
+			return nil, false
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取组织失败", err.Error())
+		return nil, false
+	}
+	return organization, true
+}
+
+// RegisterRoutesWithPermission 注册组织管理相关路由（带权限检查）
+func (h *OrganizationHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, adminMiddleware gin.HandlerFunc) {
+	organizations := router.Group("/organizations", adminMiddleware)
+	{
+		organizations.GET("", h.ListOrganizations)
+		organizations.POST("", h.CreateOrganization)
+		organizations.GET("/:id", h.GetOrganization)
+		organizations.PUT("/:id", h.UpdateOrganization)
+		organizations.DELETE("/:id", h.DeleteOrganization)
+	}
+}
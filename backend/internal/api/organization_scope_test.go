@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+	"nmp-platform/internal/snmptrap"
+	"nmp-platform/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupMonitoringScopeTest 创建带租户隔离的 syslog、trap 和设备清单路由，组织由请求头 X-Org 指定
+// 不设置设备范围，模拟组织管理员拥有全部设备权限的情况
+func setupMonitoringScopeTest(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, tenant.Register(db))
+	require.NoError(t, db.AutoMigrate(&models.Device{}, &models.DeviceGroupMember{},
+		&models.SyslogMessage{}, &models.SNMPTrap{}, &models.DeviceInventory{}))
+
+	deviceRepo := repository.NewDeviceRepository(db)
+	syslogService := service.NewSyslogService(repository.NewSyslogRepository(db), nil, "", "", 0)
+	trapService, err := service.NewSNMPTrapService(repository.NewSNMPTrapRepository(db), nil, nil, nil, snmptrap.Config{}, 0)
+	require.NoError(t, err)
+	inventoryService := service.NewInventoryService(repository.NewDeviceInventoryRepository(db), deviceRepo, 0)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var orgID uint
+		fmt.Sscan(c.GetHeader("X-Org"), &orgID)
+		c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), orgID))
+		c.Next()
+	})
+	allow := func(c *gin.Context) { c.Next() }
+	group := router.Group("/api")
+	NewSyslogHandler(syslogService, deviceRepo, "", "", 0).RegisterRoutesWithPermission(group, allow, allow)
+	NewSNMPTrapHandler(trapService).RegisterRoutesWithPermission(group, allow)
+	NewInventoryHandler(inventoryService, deviceRepo).RegisterRoutesWithPermission(group, allow, allow)
+	return router, db
+}
+
+func TestMonitoringRecords_OrganizationScope(t *testing.T) {
+	router, db := setupMonitoringScopeTest(t)
+
+	// 默认组织和 acme（组织 2）各有一台设备及其日志、trap 和清单
+	now := time.Now()
+	for _, org := range []struct {
+		id   uint
+		name string
+		host string
+	}{
+		{models.DefaultOrganizationID, "default-r1", "10.0.1.1"},
+		{2, "acme-r1", "10.0.2.1"},
+	} {
+		device := &models.Device{OrganizationID: org.id, Name: org.name, Host: org.host, OSType: models.DeviceOSTypeMikroTik}
+		require.NoError(t, db.Create(device).Error)
+		require.NoError(t, db.Create(&models.SyslogMessage{
+			OrganizationID: org.id, DeviceID: &device.ID, SourceIP: org.host,
+			Message: "log from " + org.name, Timestamp: now, ReceivedAt: now,
+		}).Error)
+		require.NoError(t, db.Create(&models.SNMPTrap{
+			OrganizationID: org.id, DeviceID: &device.ID, SourceIP: org.host,
+			TrapName: "linkDown-" + org.name, ReceivedAt: now,
+		}).Error)
+		require.NoError(t, db.Create(&models.DeviceInventory{
+			OrganizationID: org.id, DeviceID: device.ID, Model: "model-" + org.name, CollectedAt: now,
+		}).Error)
+	}
+
+	for _, path := range []string{"/api/syslog", "/api/snmp-traps", "/api/inventory", "/api/inventory/export"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Org", "2")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Body.String(), "acme-r1", path)
+		assert.NotContains(t, w.Body.String(), "default-r1", path)
+	}
+}
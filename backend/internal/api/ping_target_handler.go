@@ -9,6 +9,7 @@ import (
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -69,6 +70,11 @@ type CreatePingTargetRequest struct {
 	TargetName      string `json:"target_name" binding:"required"`
 	SourceInterface string `json:"source_interface"`
 	Enabled         *bool  `json:"enabled"`
+	Count           int    `json:"count"`       // 每组发送包数，默认 1
+	PacketSize      int    `json:"packet_size"` // 包大小（字节），0 使用设备默认值
+	IntervalMs      int    `json:"interval_ms"` // 组内发包间隔（毫秒），默认 100
+	DSCP            int    `json:"dscp"`        // DSCP 标记 0-63
+	VoIP            bool   `json:"voip"`        // 是否为 VoIP 目标
 }
 
 // UpdatePingTargetRequest 更新 Ping 目标请求
@@ -77,6 +83,11 @@ type UpdatePingTargetRequest struct {
 	TargetName      string `json:"target_name"`
 	SourceInterface string `json:"source_interface"`
 	Enabled         *bool  `json:"enabled"`
+	Count           *int   `json:"count"`
+	PacketSize      *int   `json:"packet_size"`
+	IntervalMs      *int   `json:"interval_ms"`
+	DSCP            *int   `json:"dscp"`
+	VoIP            *bool  `json:"voip"`
 }
 
 // PingTargetResponse Ping 目标响应
@@ -87,6 +98,42 @@ type PingTargetResponse struct {
 	TargetName      string `json:"target_name"`
 	SourceInterface string `json:"source_interface"`
 	Enabled         bool   `json:"enabled"`
+	Count           int    `json:"count"`
+	PacketSize      int    `json:"packet_size"`
+	IntervalMs      int    `json:"interval_ms"`
+	DSCP            int    `json:"dscp"`
+	VoIP            bool   `json:"voip"`
+}
+
+// newPingTargetResponse 转换为 Ping 目标响应
+func newPingTargetResponse(target *models.PingTarget) PingTargetResponse {
+	return PingTargetResponse{
+		ID:              target.ID,
+		DeviceID:        target.DeviceID,
+		TargetAddress:   target.TargetAddress,
+		TargetName:      target.TargetName,
+		SourceInterface: target.SourceInterface,
+		Enabled:         target.Enabled,
+		Count:           target.Count,
+		PacketSize:      target.PacketSize,
+		IntervalMs:      target.IntervalMs,
+		DSCP:            target.DSCP,
+		VoIP:            target.VoIP,
+	}
+}
+
+// newPingTargetConfig 转换为采集脚本的 Ping 目标配置
+func newPingTargetConfig(target *models.PingTarget) collector.PingTargetConfig {
+	return collector.PingTargetConfig{
+		TargetAddress:   target.TargetAddress,
+		TargetName:      target.TargetName,
+		SourceInterface: target.SourceInterface,
+		Count:           target.Count,
+		PacketSize:      target.PacketSize,
+		IntervalMs:      target.IntervalMs,
+		DSCP:            target.DSCP,
+		VoIP:            target.VoIP,
+	}
 }
 
 // redeployCollectorScript 自动重新部署采集器脚本
@@ -133,11 +180,7 @@ func (h *PingTargetHandler) redeployCollectorScript(deviceID uint) error {
 
 	var pingTargetConfigs []collector.PingTargetConfig
 	for _, pt := range pingTargets {
-		pingTargetConfigs = append(pingTargetConfigs, collector.PingTargetConfig{
-			TargetAddress:   pt.TargetAddress,
-			TargetName:      pt.TargetName,
-			SourceInterface: pt.SourceInterface,
-		})
+		pingTargetConfigs = append(pingTargetConfigs, newPingTargetConfig(pt))
 	}
 
 	// 构建脚本配置
@@ -151,6 +194,7 @@ func (h *PingTargetHandler) redeployCollectorScript(deviceID uint) error {
 		SchedulerName: config.SchedulerName,
 		Interfaces:    interfaceNames,
 		PingTargets:   pingTargetConfigs,
+		Modules:       newCollectorModules(config),
 	}
 
 	// 重新部署脚本
@@ -162,6 +206,36 @@ func (h *PingTargetHandler) redeployCollectorScript(deviceID uint) error {
 	return nil
 }
 
+// validateProbeOptions 校验 Ping 探测参数，并确保一组探测能在设备的采集间隔内完成
+func (h *PingTargetHandler) validateProbeOptions(target *models.PingTarget) error {
+	if err := target.ValidateProbeOptions(); err != nil {
+		return err
+	}
+	return target.ValidateBurstDuration(h.collectIntervalMs(target.DeviceID))
+}
+
+// collectIntervalMs 获取设备采集器的采集间隔（毫秒），未配置采集器时返回默认值
+func (h *PingTargetHandler) collectIntervalMs(deviceID uint) int {
+	if h.collectorRepo == nil {
+		return models.CollectorDefaultIntervalMs
+	}
+	config, err := h.collectorRepo.GetByDeviceID(deviceID)
+	if err != nil || config == nil || config.IntervalMs <= 0 {
+		return models.CollectorDefaultIntervalMs
+	}
+	return config.IntervalMs
+}
+
+// pingTargets 返回限定到当前组织的 Ping 目标仓库
+func (h *PingTargetHandler) pingTargets(c *gin.Context) repository.PingTargetRepository {
+	return tenant.Bind(c.Request.Context(), h.pingTargetRepo)
+}
+
+// devices 返回限定到当前组织的设备仓库
+func (h *PingTargetHandler) devices(c *gin.Context) repository.DeviceRepository {
+	return tenant.Bind(c.Request.Context(), h.deviceRepo)
+}
+
 // GetPingTargets 获取设备的 Ping 目标列表
 // GET /api/devices/:id/ping-targets
 func (h *PingTargetHandler) GetPingTargets(c *gin.Context) {
@@ -175,7 +249,7 @@ func (h *PingTargetHandler) GetPingTargets(c *gin.Context) {
 	}
 
 	// 检查设备是否存在
-	_, err = h.deviceRepo.GetByID(uint(deviceID))
+	_, err = h.devices(c).GetByID(uint(deviceID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "设备不存在",
@@ -183,7 +257,7 @@ func (h *PingTargetHandler) GetPingTargets(c *gin.Context) {
 		return
 	}
 
-	targets, err := h.pingTargetRepo.GetByDeviceID(uint(deviceID))
+	targets, err := h.pingTargets(c).GetByDeviceID(uint(deviceID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -194,14 +268,7 @@ func (h *PingTargetHandler) GetPingTargets(c *gin.Context) {
 	// 转换为响应格式
 	response := make([]PingTargetResponse, 0, len(targets))
 	for _, target := range targets {
-		response = append(response, PingTargetResponse{
-			ID:              target.ID,
-			DeviceID:        target.DeviceID,
-			TargetAddress:   target.TargetAddress,
-			TargetName:      target.TargetName,
-			SourceInterface: target.SourceInterface,
-			Enabled:         target.Enabled,
-		})
+		response = append(response, newPingTargetResponse(target))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -223,7 +290,7 @@ func (h *PingTargetHandler) CreatePingTarget(c *gin.Context) {
 	}
 
 	// 检查设备是否存在
-	_, err = h.deviceRepo.GetByID(uint(deviceID))
+	_, err = h.devices(c).GetByID(uint(deviceID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "设备不存在",
@@ -242,7 +309,7 @@ func (h *PingTargetHandler) CreatePingTarget(c *gin.Context) {
 
 	// 检查是否已存在相同目标地址和源接口的组合
 	// 重复判断条件：同设备 + 同目标IP + 同源接口
-	exists, err := h.pingTargetRepo.Exists(uint(deviceID), req.TargetAddress, req.SourceInterface)
+	exists, err := h.pingTargets(c).Exists(uint(deviceID), req.TargetAddress, req.SourceInterface)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -268,9 +335,20 @@ func (h *PingTargetHandler) CreatePingTarget(c *gin.Context) {
 		TargetName:      req.TargetName,
 		SourceInterface: req.SourceInterface,
 		Enabled:         enabled,
+		Count:           req.Count,
+		PacketSize:      req.PacketSize,
+		IntervalMs:      req.IntervalMs,
+		DSCP:            req.DSCP,
+		VoIP:            req.VoIP,
+	}
+	if err := h.validateProbeOptions(target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
 	}
 
-	if err := h.pingTargetRepo.Create(target); err != nil {
+	if err := h.pingTargets(c).Create(target); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -282,14 +360,7 @@ func (h *PingTargetHandler) CreatePingTarget(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
-		"data": PingTargetResponse{
-			ID:              target.ID,
-			DeviceID:        target.DeviceID,
-			TargetAddress:   target.TargetAddress,
-			TargetName:      target.TargetName,
-			SourceInterface: target.SourceInterface,
-			Enabled:         target.Enabled,
-		},
+		"data": newPingTargetResponse(target),
 		"message": "Ping 目标创建成功",
 	})
 }
@@ -316,7 +387,7 @@ func (h *PingTargetHandler) UpdatePingTarget(c *gin.Context) {
 	}
 
 	// 获取现有目标
-	target, err := h.pingTargetRepo.GetByID(uint(targetID))
+	target, err := h.pingTargets(c).GetByID(uint(targetID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Ping 目标不存在",
@@ -354,7 +425,7 @@ func (h *PingTargetHandler) UpdatePingTarget(c *gin.Context) {
 	
 	// 只有当目标地址或源接口发生变化时才检查重复
 	if newTargetAddress != target.TargetAddress || newSourceInterface != target.SourceInterface {
-		exists, err := h.pingTargetRepo.Exists(uint(deviceID), newTargetAddress, newSourceInterface)
+		exists, err := h.pingTargets(c).Exists(uint(deviceID), newTargetAddress, newSourceInterface)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": err.Error(),
@@ -379,8 +450,29 @@ func (h *PingTargetHandler) UpdatePingTarget(c *gin.Context) {
 	if req.Enabled != nil {
 		target.Enabled = *req.Enabled
 	}
+	if req.Count != nil {
+		target.Count = *req.Count
+	}
+	if req.PacketSize != nil {
+		target.PacketSize = *req.PacketSize
+	}
+	if req.IntervalMs != nil {
+		target.IntervalMs = *req.IntervalMs
+	}
+	if req.DSCP != nil {
+		target.DSCP = *req.DSCP
+	}
+	if req.VoIP != nil {
+		target.VoIP = *req.VoIP
+	}
+	if err := h.validateProbeOptions(target); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	if err := h.pingTargetRepo.Update(target); err != nil {
+	if err := h.pingTargets(c).Update(target); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -392,14 +484,7 @@ func (h *PingTargetHandler) UpdatePingTarget(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": PingTargetResponse{
-			ID:              target.ID,
-			DeviceID:        target.DeviceID,
-			TargetAddress:   target.TargetAddress,
-			TargetName:      target.TargetName,
-			SourceInterface: target.SourceInterface,
-			Enabled:         target.Enabled,
-		},
+		"data": newPingTargetResponse(target),
 		"message": "Ping 目标更新成功",
 	})
 }
@@ -426,7 +511,7 @@ func (h *PingTargetHandler) DeletePingTarget(c *gin.Context) {
 	}
 
 	// 获取现有目标
-	target, err := h.pingTargetRepo.GetByID(uint(targetID))
+	target, err := h.pingTargets(c).GetByID(uint(targetID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Ping 目标不存在",
@@ -450,7 +535,7 @@ func (h *PingTargetHandler) DeletePingTarget(c *gin.Context) {
 		}
 	}
 
-	if err := h.pingTargetRepo.Delete(uint(targetID)); err != nil {
+	if err := h.pingTargets(c).Delete(uint(targetID)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -488,7 +573,7 @@ func (h *PingTargetHandler) TogglePingTarget(c *gin.Context) {
 	}
 
 	// 获取现有目标
-	target, err := h.pingTargetRepo.GetByID(uint(targetID))
+	target, err := h.pingTargets(c).GetByID(uint(targetID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Ping 目标不存在",
@@ -506,7 +591,7 @@ func (h *PingTargetHandler) TogglePingTarget(c *gin.Context) {
 
 	// 切换启用状态
 	newEnabled := !target.Enabled
-	if err := h.pingTargetRepo.UpdateEnabled(uint(targetID), newEnabled); err != nil {
+	if err := h.pingTargets(c).UpdateEnabled(uint(targetID), newEnabled); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
 		})
@@ -73,8 +73,8 @@ func (m *MockPingTargetRepository) UpdateEnabled(id uint, enabled bool) error {
 	return args.Error(0)
 }
 
-func (m *MockPingTargetRepository) Exists(deviceID uint, targetAddress string) (bool, error) {
-	args := m.Called(deviceID, targetAddress)
+func (m *MockPingTargetRepository) Exists(deviceID uint, targetAddress string, sourceInterface string) (bool, error) {
+	args := m.Called(deviceID, targetAddress, sourceInterface)
 	return args.Bool(0), args.Error(1)
 }
 
@@ -229,7 +229,7 @@ func TestPingTargetHandler_CreatePingTarget(t *testing.T) {
 
 	// 设置模拟期望
 	mockDeviceRepo.On("GetByID", uint(1)).Return(&models.Device{ID: 1, Name: "TestDevice"}, nil)
-	mockPingTargetRepo.On("Exists", uint(1), "8.8.8.8").Return(false, nil)
+	mockPingTargetRepo.On("Exists", uint(1), "8.8.8.8", "ether1").Return(false, nil)
 	mockPingTargetRepo.On("Create", mock.AnythingOfType("*models.PingTarget")).Return(nil)
 
 	createReq := CreatePingTargetRequest{
@@ -403,7 +403,7 @@ func TestPingTargetHandler_DuplicateTarget(t *testing.T) {
 
 	// 设置模拟期望 - 目标地址已存在
 	mockDeviceRepo.On("GetByID", uint(1)).Return(&models.Device{ID: 1, Name: "TestDevice"}, nil)
-	mockPingTargetRepo.On("Exists", uint(1), "8.8.8.8").Return(true, nil)
+	mockPingTargetRepo.On("Exists", uint(1), "8.8.8.8", "").Return(true, nil)
 
 	createReq := CreatePingTargetRequest{
 		TargetAddress: "8.8.8.8",
@@ -427,6 +427,40 @@ func TestPingTargetHandler_DuplicateTarget(t *testing.T) {
 	mockDeviceRepo.AssertExpectations(t)
 }
 
+// TestPingTargetHandler_BurstExceedsCollectorInterval 测试一组探测耗时超过采集间隔时拒绝创建
+func TestPingTargetHandler_BurstExceedsCollectorInterval(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockPingTargetRepo := new(MockPingTargetRepository)
+	mockDeviceRepo := new(MockDeviceRepository)
+
+	handler := NewPingTargetHandler(mockPingTargetRepo, mockDeviceRepo)
+
+	router := gin.New()
+	handler.RegisterRoutes(router.Group("/api"))
+
+	mockDeviceRepo.On("GetByID", uint(1)).Return(&models.Device{ID: 1, Name: "TestDevice"}, nil)
+	mockPingTargetRepo.On("Exists", uint(1), "8.8.8.8", "").Return(false, nil)
+
+	// 未配置采集器时采集间隔为 1000ms，20 × 100ms 超出
+	createReq := CreatePingTargetRequest{
+		TargetAddress: "8.8.8.8",
+		TargetName:    "Google DNS",
+		Count:         20,
+		IntervalMs:    100,
+	}
+	reqBody, _ := json.Marshal(createReq)
+	req, _ := http.NewRequest("POST", "/api/devices/1/ping-targets", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "collector interval")
+	mockPingTargetRepo.AssertNotCalled(t, "Create", mock.Anything)
+}
+
 // TestPingTargetHandler_InvalidDeviceID 测试无效设备 ID
 func TestPingTargetHandler_InvalidDeviceID(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -586,7 +620,7 @@ func TestPingTargetDataIntegrity_Property(t *testing.T) {
 	t.Run("支持指定源接口", func(t *testing.T) {
 		// 设置模拟期望
 		mockDeviceRepo.On("GetByID", uint(14)).Return(&models.Device{ID: 14, Name: "TestDevice"}, nil)
-		mockPingTargetRepo.On("Exists", uint(14), "8.8.8.8").Return(false, nil)
+		mockPingTargetRepo.On("Exists", uint(14), "8.8.8.8", "ether1").Return(false, nil)
 		mockPingTargetRepo.On("Create", mock.AnythingOfType("*models.PingTarget")).Return(nil)
 
 		createReq := CreatePingTargetRequest{
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProbeHandler 服务端探测处理器
+type ProbeHandler struct {
+	probeService *service.ProbeService
+	proxyRepo    repository.ProxyRepository
+}
+
+// NewProbeHandler 创建新的服务端探测处理器
+func NewProbeHandler(probeService *service.ProbeService) *ProbeHandler {
+	return &ProbeHandler{
+		probeService: probeService,
+	}
+}
+
+// SetProxyRepository 设置代理仓库，用于检查探测使用的代理属于当前组织
+func (h *ProbeHandler) SetProxyRepository(proxyRepo repository.ProxyRepository) {
+	h.proxyRepo = proxyRepo
+}
+
+// probes 返回限定到当前组织的探测服务
+func (h *ProbeHandler) probes(c *gin.Context) *service.ProbeService {
+	return tenant.Bind(c.Request.Context(), h.probeService)
+}
+
+// checkProxy 检查探测使用的代理属于当前组织，不存在时写入错误响应
+func (h *ProbeHandler) checkProxy(c *gin.Context, proxyID *uint) bool {
+	if proxyID == nil || *proxyID == 0 || h.proxyRepo == nil {
+		return true
+	}
+	if _, err := tenant.Bind(c.Request.Context(), h.proxyRepo).GetByID(*proxyID); err != nil {
+		BadRequest(c, "代理不存在")
+		return false
+	}
+	return true
+}
+
+// ProbeRequest 创建/更新服务端探测请求
+type ProbeRequest struct {
+	Name           string           `json:"name" binding:"required"`
+	Type           models.ProbeType `json:"type" binding:"required"`
+	Target         string           `json:"target"`
+	Port           int              `json:"port"`
+	DNSQuery       string           `json:"dns_query"`
+	HTTPMethod     string           `json:"http_method"`
+	ExpectedStatus int              `json:"expected_status"`
+	TLSSkipVerify  bool             `json:"tls_skip_verify"`
+	IntervalSec    int              `json:"interval_sec"`
+	TimeoutMs      int              `json:"timeout_ms"`
+	ProxyID        *uint            `json:"proxy_id"`
+	Enabled        *bool            `json:"enabled"`
+}
+
+// apply 将请求内容写入探测模型
+func (req *ProbeRequest) apply(p *models.Probe) {
+	p.Name = req.Name
+	p.Type = req.Type
+	p.Target = req.Target
+	p.Port = req.Port
+	p.DNSQuery = req.DNSQuery
+	p.HTTPMethod = req.HTTPMethod
+	p.ExpectedStatus = req.ExpectedStatus
+	p.TLSSkipVerify = req.TLSSkipVerify
+	p.IntervalSec = req.IntervalSec
+	p.TimeoutMs = req.TimeoutMs
+	p.ProxyID = req.ProxyID
+	if req.Enabled != nil {
+		p.Enabled = *req.Enabled
+	}
+}
+
+// respondProbeError 根据错误类型返回探测相关错误
+func respondProbeError(c *gin.Context, err error) {
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		BadRequest(c, validationErr.Error())
+		return
+	}
+	InternalError(c, err.Error())
+}
+
+// ListProbes 获取服务端探测列表
+// @Summary 获取服务端探测列表
+// @Tags 服务端探测
+// @Produce json
+// @Success 200 {object} SuccessResponse "探测列表"
+// @Router /api/v1/probes [get]
+func (h *ProbeHandler) ListProbes(c *gin.Context) {
+	probes, err := h.probes(c).ListProbes()
+	if err != nil {
+		InternalError(c, err.Error())
+		return
+	}
+
+	Success(c, probes)
+}
+
+// GetProbe 获取服务端探测详情
+// @Summary 获取服务端探测详情
+// @Tags 服务端探测
+// @Produce json
+// @Param id path int true "探测ID"
+// @Success 200 {object} SuccessResponse "探测详情"
+// @Failure 404 {object} ErrorResponse "探测不存在"
+// @Router /api/v1/probes/{id} [get]
+func (h *ProbeHandler) GetProbe(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的探测 ID")
+		return
+	}
+
+	probe, err := h.probes(c).GetProbe(uint(id))
+	if err != nil {
+		NotFound(c, err.Error())
+		return
+	}
+
+	Success(c, probe)
+}
+
+// CreateProbe 创建服务端探测
+// @Summary 创建服务端探测
+// @Tags 服务端探测
+// @Accept json
+// @Produce json
+// @Param request body ProbeRequest true "探测配置"
+// @Success 201 {object} SuccessResponse "创建成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Router /api/v1/probes [post]
+func (h *ProbeHandler) CreateProbe(c *gin.Context) {
+	var req ProbeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求格式")
+		return
+	}
+	if !h.checkProxy(c, req.ProxyID) {
+		return
+	}
+
+	probe := &models.Probe{Enabled: true, LastStatus: "unknown"}
+	req.apply(probe)
+	if err := h.probes(c).CreateProbe(probe); err != nil {
+		respondProbeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    probe,
+	})
+}
+
+// UpdateProbe 更新服务端探测
+// @Summary 更新服务端探测
+// @Tags 服务端探测
+// @Accept json
+// @Produce json
+// @Param id path int true "探测ID"
+// @Param request body ProbeRequest true "探测配置"
+// @Success 200 {object} SuccessResponse "更新成功"
+// @Failure 400 {object} ErrorResponse "请求参数错误"
+// @Failure 404 {object} ErrorResponse "探测不存在"
+// @Router /api/v1/probes/{id} [put]
+func (h *ProbeHandler) UpdateProbe(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的探测 ID")
+		return
+	}
+
+	var req ProbeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "无效的请求格式")
+		return
+	}
+	if !h.checkProxy(c, req.ProxyID) {
+		return
+	}
+
+	probe, err := h.probes(c).GetProbe(uint(id))
+	if err != nil {
+		NotFound(c, err.Error())
+		return
+	}
+
+	req.apply(probe)
+	if err := h.probes(c).UpdateProbe(probe); err != nil {
+		respondProbeError(c, err)
+		return
+	}
+
+	Success(c, probe)
+}
+
+// DeleteProbe 删除服务端探测
+// @Summary 删除服务端探测
+// @Tags 服务端探测
+// @Produce json
+// @Param id path int true "探测ID"
+// @Success 200 {object} SuccessResponse "删除成功"
+// @Failure 404 {object} ErrorResponse "探测不存在"
+// @Router /api/v1/probes/{id} [delete]
+func (h *ProbeHandler) DeleteProbe(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的探测 ID")
+		return
+	}
+
+	if _, err := h.probes(c).GetProbe(uint(id)); err != nil {
+		NotFound(c, err.Error())
+		return
+	}
+
+	if err := h.probes(c).DeleteProbe(uint(id)); err != nil {
+		InternalError(c, err.Error())
+		return
+	}
+
+	SuccessWithMessage(c, nil, "探测删除成功")
+}
+
+// RunProbe 立即执行一次服务端探测
+// @Summary 立即执行服务端探测
+// @Description 立即执行一次探测，结果同时写入历史数据
+// @Tags 服务端探测
+// @Produce json
+// @Param id path int true "探测ID"
+// @Success 200 {object} SuccessResponse "探测结果"
+// @Failure 404 {object} ErrorResponse "探测不存在"
+// @Router /api/v1/probes/{id}/run [post]
+func (h *ProbeHandler) RunProbe(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的探测 ID")
+		return
+	}
+
+	probe, err := h.probes(c).GetProbe(uint(id))
+	if err != nil {
+		NotFound(c, err.Error())
+		return
+	}
+
+	result, err := h.probes(c).RunProbe(c.Request.Context(), probe)
+	if err != nil {
+		InternalError(c, err.Error())
+		return
+	}
+
+	Success(c, result)
+}
+
+// RegisterRoutes 注册服务端探测相关路由
+func (h *ProbeHandler) RegisterRoutes(router *gin.RouterGroup) {
+	probes := router.Group("/probes")
+	{
+		probes.GET("", h.ListProbes)
+		probes.POST("", h.CreateProbe)
+		probes.GET("/:id", h.GetProbe)
+		probes.PUT("/:id", h.UpdateProbe)
+		probes.DELETE("/:id", h.DeleteProbe)
+		probes.POST("/:id/run", h.RunProbe)
+	}
+}
+
+// RegisterRoutesWithPermission 注册服务端探测相关路由（带权限检查）
+// 探测会从平台服务器或客户网络内的代理向任意目标发起连接，创建、修改、删除和立即执行需要设备更新权限
+func (h *ProbeHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware, updateMiddleware gin.HandlerFunc) {
+	probes := router.Group("/probes")
+	{
+		probes.GET("", readMiddleware, h.ListProbes)
+		probes.POST("", updateMiddleware, h.CreateProbe)
+		probes.GET("/:id", readMiddleware, h.GetProbe)
+		probes.PUT("/:id", updateMiddleware, h.UpdateProbe)
+		probes.DELETE("/:id", updateMiddleware, h.DeleteProbe)
+		probes.POST("/:id/run", updateMiddleware, h.RunProbe)
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupProbeHandlerTest 创建带租户隔离的探测处理器，组织由请求头 X-Org 指定
+func setupProbeHandlerTest(t *testing.T, updateMiddleware gin.HandlerFunc) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, tenant.Register(db))
+	require.NoError(t, db.AutoMigrate(&models.Probe{}, &models.Proxy{}))
+
+	probeService := service.NewProbeService(repository.NewProbeRepository(db), nil, nil)
+	handler := NewProbeHandler(probeService)
+	handler.SetProxyRepository(repository.NewProxyRepository(db))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		var orgID uint
+		fmt.Sscan(c.GetHeader("X-Org"), &orgID)
+		c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), orgID))
+		c.Next()
+	})
+	allow := func(c *gin.Context) { c.Next() }
+	if updateMiddleware == nil {
+		updateMiddleware = allow
+	}
+	handler.RegisterRoutesWithPermission(router.Group("/api"), allow, updateMiddleware)
+	return router, db
+}
+
+func doProbeRequest(router *gin.Engine, method, path string, orgID uint, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Org", fmt.Sprint(orgID))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestProbeHandler_OrganizationScope(t *testing.T) {
+	router, db := setupProbeHandlerTest(t, nil)
+
+	acme := tenant.WithOrganization(t.Context(), 2)
+	probe := &models.Probe{Name: "web", Type: models.ProbeTypeHTTP, Target: "http://10.0.0.1", Enabled: true}
+	require.NoError(t, db.WithContext(acme).Create(probe).Error)
+	path := fmt.Sprintf("/api/probes/%d", probe.ID)
+	update := ProbeRequest{Name: "web", Type: models.ProbeTypeHTTP, Target: "http://10.0.0.2"}
+
+	// 本组织可以查看
+	w := doProbeRequest(router, http.MethodGet, path, 2, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 其他组织看不到、改不了、删不掉
+	w = doProbeRequest(router, http.MethodGet, "/api/probes", models.DefaultOrganizationID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "10.0.0.1")
+
+	w = doProbeRequest(router, http.MethodGet, path, models.DefaultOrganizationID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doProbeRequest(router, http.MethodPut, path, models.DefaultOrganizationID, update)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doProbeRequest(router, http.MethodDelete, path, models.DefaultOrganizationID, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var stored models.Probe
+	require.NoError(t, db.First(&stored, probe.ID).Error)
+	assert.Equal(t, "http://10.0.0.1", stored.Target)
+}
+
+func TestProbeHandler_RejectsOtherOrganizationProxy(t *testing.T) {
+	router, db := setupProbeHandlerTest(t, nil)
+
+	proxy := &models.Proxy{Name: "jump", Type: models.ProxyTypeSOCKS5}
+	require.NoError(t, db.WithContext(tenant.WithOrganization(t.Context(), 2)).Create(proxy).Error)
+	req := ProbeRequest{Name: "ssh", Type: models.ProbeTypeTCP, Target: "10.0.0.1", Port: 22, ProxyID: &proxy.ID}
+
+	w := doProbeRequest(router, http.MethodPost, "/api/probes", models.DefaultOrganizationID, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "代理不存在")
+
+	w = doProbeRequest(router, http.MethodPost, "/api/probes", 2, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var stored models.Probe
+	require.NoError(t, db.First(&stored).Error)
+	assert.Equal(t, uint(2), stored.OrganizationID)
+}
+
+func TestProbeHandler_UpdateRoutesRequirePermission(t *testing.T) {
+	deny := func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+	}
+	router, db := setupProbeHandlerTest(t, deny)
+
+	probe := &models.Probe{Name: "web", Type: models.ProbeTypeHTTP, Target: "http://10.0.0.1", Enabled: true}
+	require.NoError(t, db.Create(probe).Error)
+	path := fmt.Sprintf("/api/probes/%d", probe.ID)
+	req := ProbeRequest{Name: "web", Type: models.ProbeTypeHTTP, Target: "http://10.0.0.2"}
+
+	// 只读路由不受 update 权限影响
+	w := doProbeRequest(router, http.MethodGet, path, models.DefaultOrganizationID, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	for _, tc := range []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodPost, "/api/probes", req},
+		{http.MethodPut, path, req},
+		{http.MethodDelete, path, nil},
+		{http.MethodPost, path + "/run", nil},
+	} {
+		w := doProbeRequest(router, tc.method, tc.path, models.DefaultOrganizationID, tc.body)
+		assert.Equal(t, http.StatusForbidden, w.Code, "%s %s", tc.method, tc.path)
+	}
+
+	var count int64
+	require.NoError(t, db.Model(&models.Probe{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
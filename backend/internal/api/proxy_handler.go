@@ -7,6 +7,7 @@ import (
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/proxy"
 	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -101,6 +102,23 @@ type TestProxyRequest struct {
 	ParentProxyID  *uint  `json:"parent_proxy_id"`
 }
 
+// proxies 返回限定到当前组织的代理仓库
+func (h *ProxyHandler) proxies(c *gin.Context) repository.ProxyRepository {
+	return tenant.Bind(c.Request.Context(), h.proxyRepo)
+}
+
+// checkParentProxy 检查父代理属于当前组织，不存在时写入错误响应
+func (h *ProxyHandler) checkParentProxy(c *gin.Context, parentProxyID *uint) bool {
+	if parentProxyID == nil || *parentProxyID == 0 {
+		return true
+	}
+	if _, err := h.proxies(c).GetByID(*parentProxyID); err != nil {
+		BadRequest(c, "父代理不存在")
+		return false
+	}
+	return true
+}
+
 // CreateProxy 创建代理
 func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 	var req CreateProxyRequest
@@ -115,6 +133,9 @@ func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 		BadRequest(c, err.Error())
 		return
 	}
+	if !h.checkParentProxy(c, req.ParentProxyID) {
+		return
+	}
 
 	// 创建代理对象
 	proxyModel := &models.Proxy{
@@ -142,7 +163,7 @@ func (h *ProxyHandler) CreateProxy(c *gin.Context) {
 	}
 
 	// 创建代理
-	if err := h.proxyRepo.Create(proxyModel); err != nil {
+	if err := h.proxies(c).Create(proxyModel); err != nil {
 		BadRequest(c, err.Error())
 		return
 	}
@@ -162,7 +183,7 @@ func (h *ProxyHandler) GetProxy(c *gin.Context) {
 		return
 	}
 
-	proxyModel, err := h.proxyRepo.GetByID(uint(id))
+	proxyModel, err := h.proxies(c).GetByID(uint(id))
 	if err != nil {
 		NotFound(c, err.Error())
 		return
@@ -194,11 +215,14 @@ func (h *ProxyHandler) UpdateProxy(c *gin.Context) {
 	}
 
 	// 获取现有代理
-	proxyModel, err := h.proxyRepo.GetByID(uint(id))
+	proxyModel, err := h.proxies(c).GetByID(uint(id))
 	if err != nil {
 		NotFound(c, err.Error())
 		return
 	}
+	if !h.checkParentProxy(c, req.ParentProxyID) {
+		return
+	}
 
 	// 关闭现有连接
 	h.proxyManager.CloseProxy(uint(id))
@@ -229,7 +253,7 @@ func (h *ProxyHandler) UpdateProxy(c *gin.Context) {
 		proxyModel.SOCKS5Port = 1080
 	}
 
-	if err := h.proxyRepo.Update(proxyModel); err != nil {
+	if err := h.proxies(c).Update(proxyModel); err != nil {
 		BadRequest(c, err.Error())
 		return
 	}
@@ -245,12 +269,16 @@ func (h *ProxyHandler) DeleteProxy(c *gin.Context) {
 		BadRequest(c, "无效的代理 ID")
 		return
 	}
+	if _, err := h.proxies(c).GetByID(uint(id)); err != nil {
+		NotFound(c, err.Error())
+		return
+	}
 
 	// 关闭连接
 	h.proxyManager.CloseProxy(uint(id))
 
 	// 删除代理
-	if err := h.proxyRepo.Delete(uint(id)); err != nil {
+	if err := h.proxies(c).Delete(uint(id)); err != nil {
 		BadRequest(c, err.Error())
 		return
 	}
@@ -287,7 +315,7 @@ func (h *ProxyHandler) ListProxies(c *gin.Context) {
 		filters["search"] = req.Search
 	}
 
-	proxies, total, err := h.proxyRepo.List(offset, req.PageSize, filters)
+	proxies, total, err := h.proxies(c).List(offset, req.PageSize, filters)
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -321,6 +349,9 @@ func (h *ProxyHandler) TestProxy(c *gin.Context) {
 	}
 
 	// 获取父代理的 Dialer（如果有）
+	if !h.checkParentProxy(c, req.ParentProxyID) {
+		return
+	}
 	var parentDialer proxy.Dialer
 	if req.ParentProxyID != nil && *req.ParentProxyID > 0 {
 		var err error
@@ -382,13 +413,17 @@ func (h *ProxyHandler) TestProxyByID(c *gin.Context) {
 		BadRequest(c, "无效的代理 ID")
 		return
 	}
+	if _, err := h.proxies(c).GetByID(uint(id)); err != nil {
+		NotFound(c, err.Error())
+		return
+	}
 
 	// 测试代理连接
 	testErr := h.proxyManager.TestProxy(uint(id))
 
 	// 更新代理状态
 	if testErr != nil {
-		h.proxyRepo.UpdateStatus(uint(id), models.ProxyStatusError, testErr.Error())
+		h.proxies(c).UpdateStatus(uint(id), models.ProxyStatusError, testErr.Error())
 		Success(c, gin.H{
 			"connected": false,
 			"error":     testErr.Error(),
@@ -396,7 +431,7 @@ func (h *ProxyHandler) TestProxyByID(c *gin.Context) {
 		return
 	}
 
-	h.proxyRepo.UpdateStatus(uint(id), models.ProxyStatusConnected, "")
+	h.proxies(c).UpdateStatus(uint(id), models.ProxyStatusConnected, "")
 	Success(c, gin.H{
 		"connected": true,
 	})
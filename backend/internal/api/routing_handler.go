@@ -0,0 +1,291 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultRoutingEventPageSize 路由事件默认每页条数
+	defaultRoutingEventPageSize = 50
+	// maxRoutingEventPageSize 路由事件最大每页条数
+	maxRoutingEventPageSize = 500
+)
+
+// RoutingHandler 路由邻居处理器
+type RoutingHandler struct {
+	routingService *service.RoutingService
+	deviceRepo     repository.DeviceRepository
+}
+
+// NewRoutingHandler 创建路由邻居处理器
+func NewRoutingHandler(routingService *service.RoutingService, deviceRepo repository.DeviceRepository) *RoutingHandler {
+	return &RoutingHandler{
+		routingService: routingService,
+		deviceRepo:     deviceRepo,
+	}
+}
+
+// ListRoutingNeighbors 获取设备路由邻居表
+// @Summary 获取设备路由邻居表
+// @Description 返回设备最近一次采集的 BGP 对等体和 OSPF 邻居状态，包括运行时间和前缀数
+// @Tags 路由监控
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param protocol query string false "协议：bgp/ospf"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/routing-neighbors [get]
+func (h *RoutingHandler) ListRoutingNeighbors(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	protocol, err := parseRoutingProtocol(c.Query("protocol"))
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	neighbors, err := h.routingService.ListNeighbors(c.Request.Context(), uint(deviceID), protocol)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取路由邻居失败", err.Error())
+		return
+	}
+
+	Success(c, neighbors)
+}
+
+// RefreshRoutingNeighbors 立即采集设备路由邻居
+// @Summary 刷新设备路由邻居
+// @Description 立即从设备采集 BGP/OSPF 邻居，状态变化时记录事件
+// @Tags 路由监控
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/routing-neighbors/refresh [post]
+func (h *RoutingHandler) RefreshRoutingNeighbors(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	device, err := h.deviceRepo.GetByID(uint(deviceID))
+	if err != nil {
+		NotFound(c, "设备不存在")
+		return
+	}
+
+	neighbors, err := h.routingService.RefreshDevice(device)
+	if err != nil {
+		if errors.Is(err, service.ErrRoutingUnsupported) {
+			BadRequest(c, "该设备类型不支持路由邻居采集")
+			return
+		}
+		if errors.Is(err, collector.ErrFRRUnavailable) {
+			BadRequest(c, "设备未安装 FRR 或当前用户无权执行 vtysh")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "刷新路由邻居失败", err.Error())
+		return
+	}
+
+	Success(c, neighbors)
+}
+
+// DeleteRoutingNeighbor 删除路由邻居
+// @Summary 删除路由邻居
+// @Description 删除已从设备配置中移除的邻居，避免一直显示为 down；历史事件保留
+// @Tags 路由监控
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param neighbor_id path int true "邻居ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/routing-neighbors/{neighbor_id} [delete]
+func (h *RoutingHandler) DeleteRoutingNeighbor(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+	neighborID, err := strconv.ParseUint(c.Param("neighbor_id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的邻居ID")
+		return
+	}
+
+	neighbor, err := h.routingService.GetNeighbor(c.Request.Context(), uint(neighborID))
+	if err != nil || neighbor.DeviceID != uint(deviceID) {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "路由邻居不存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取路由邻居失败", err.Error())
+		return
+	}
+
+	if err := h.routingService.DeleteNeighbor(c.Request.Context(), neighbor.ID); err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "删除路由邻居失败", err.Error())
+		return
+	}
+
+	Success(c, gin.H{"message": "路由邻居已删除"})
+}
+
+// ListDeviceRoutingEvents 查询设备路由邻居事件
+// @Summary 查询设备路由邻居事件
+// @Description 返回设备 BGP/OSPF 邻居的状态变化记录，按时间倒序
+// @Tags 路由监控
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param protocol query string false "协议：bgp/ospf"
+// @Param neighbor query string false "邻居地址或 router-id"
+// @Param lost_only query bool false "只返回邻接丢失事件"
+// @Param start query string false "开始时间（RFC3339）"
+// @Param end query string false "结束时间（RFC3339）"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/routing-events [get]
+func (h *RoutingHandler) ListDeviceRoutingEvents(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return
+	}
+
+	id := uint(deviceID)
+	h.listRoutingEvents(c, &id)
+}
+
+// ListRoutingEvents 查询所有设备的路由邻居事件
+// @Summary 查询路由邻居事件
+// @Description 返回所有设备 BGP/OSPF 邻居的状态变化记录，lost_only=true 只返回邻接丢失事件
+// @Tags 路由监控
+// @Produce json
+// @Param protocol query string false "协议：bgp/ospf"
+// @Param neighbor query string false "邻居地址或 router-id"
+// @Param lost_only query bool false "只返回邻接丢失事件"
+// @Param start query string false "开始时间（RFC3339）"
+// @Param end query string false "结束时间（RFC3339）"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最大500"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /routing-events [get]
+func (h *RoutingHandler) ListRoutingEvents(c *gin.Context) {
+	h.listRoutingEvents(c, nil)
+}
+
+// listRoutingEvents 解析查询条件并返回分页的路由邻居事件
+func (h *RoutingHandler) listRoutingEvents(c *gin.Context, deviceID *uint) {
+	filter, err := parseRoutingEventFilter(c)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的查询参数", err.Error())
+		return
+	}
+	filter.DeviceID = deviceID
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultRoutingEventPageSize)))
+	if pageSize < 1 || pageSize > maxRoutingEventPageSize {
+		BadRequest(c, fmt.Sprintf("page_size 必须在 1 到 %d 之间", maxRoutingEventPageSize))
+		return
+	}
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	events, total, err := h.routingService.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询路由邻居事件失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, events, total, page, pageSize)
+}
+
+// parseRoutingProtocol 解析路由协议参数，空值表示所有协议
+func parseRoutingProtocol(value string) (models.RoutingProtocol, error) {
+	switch protocol := models.RoutingProtocol(value); protocol {
+	case "", models.RoutingProtocolBGP, models.RoutingProtocolOSPF:
+		return protocol, nil
+	default:
+		return "", fmt.Errorf("无效的协议: %s", value)
+	}
+}
+
+// parseRoutingEventFilter 解析路由邻居事件查询条件
+func parseRoutingEventFilter(c *gin.Context) (repository.RoutingEventFilter, error) {
+	filter := repository.RoutingEventFilter{Neighbor: c.Query("neighbor"), DeviceIDs: scopedDeviceIDs(c)}
+
+	protocol, err := parseRoutingProtocol(c.Query("protocol"))
+	if err != nil {
+		return filter, err
+	}
+	filter.Protocol = protocol
+
+	if value := c.Query("lost_only"); value != "" {
+		lostOnly, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid lost_only: %v", err)
+		}
+		filter.AdjacencyLost = lostOnly
+	}
+
+	for param, target := range map[string]**time.Time{
+		"start": &filter.Start,
+		"end":   &filter.End,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %v", param, err)
+		}
+		*target = &t
+	}
+
+	return filter, nil
+}
+
+// RegisterRoutesWithPermission 注册路由监控相关路由（带权限检查）
+func (h *RoutingHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware, updateMiddleware gin.HandlerFunc) {
+	router.GET("/routing-events", h.ListRoutingEvents)
+
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/routing-neighbors", readMiddleware, h.ListRoutingNeighbors)
+		devices.POST("/:id/routing-neighbors/refresh", updateMiddleware, h.RefreshRoutingNeighbors)
+		devices.DELETE("/:id/routing-neighbors/:neighbor_id", updateMiddleware, h.DeleteRoutingNeighbor)
+		devices.GET("/:id/routing-events", readMiddleware, h.ListDeviceRoutingEvents)
+	}
+}
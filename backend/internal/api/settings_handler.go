@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,14 +39,19 @@ type CollectionSettingsResponse struct {
 	FollowPushInterval      bool `json:"follow_push_interval"`         // 前端刷新是否跟随推送间隔
 }
 
+// settings 返回当前组织的设置仓库，非默认组织读写组织级设置
+func (h *SettingsHandler) settings(c *gin.Context) repository.SettingsRepository {
+	return tenant.Bind(c.Request.Context(), h.settingsRepo)
+}
+
 // GetCollectionSettings 获取采集设置
 func (h *SettingsHandler) GetCollectionSettings(c *gin.Context) {
 	settings := CollectionSettingsResponse{
-		DefaultPushInterval:     h.settingsRepo.GetDefaultPushInterval(),
-		DataRetentionDays:       h.settingsRepo.GetDataRetentionDays(),
-		FrontendRefreshInterval: h.settingsRepo.GetFrontendRefreshInterval(),
-		DeviceOfflineTimeout:    h.settingsRepo.GetDeviceOfflineTimeout(),
-		FollowPushInterval:      h.settingsRepo.GetFollowPushInterval(),
+		DefaultPushInterval:     h.settings(c).GetDefaultPushInterval(),
+		DataRetentionDays:       h.settings(c).GetDataRetentionDays(),
+		FrontendRefreshInterval: h.settings(c).GetFrontendRefreshInterval(),
+		DeviceOfflineTimeout:    h.settings(c).GetDeviceOfflineTimeout(),
+		FollowPushInterval:      h.settings(c).GetFollowPushInterval(),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -68,7 +74,7 @@ func (h *SettingsHandler) UpdateCollectionSettings(c *gin.Context) {
 			BadRequest(c, "推送间隔不能小于 100 毫秒")
 			return
 		}
-		if err := h.settingsRepo.SetDefaultPushInterval(*req.DefaultPushInterval); err != nil {
+		if err := h.settings(c).SetDefaultPushInterval(*req.DefaultPushInterval); err != nil {
 			InternalError(c, "更新推送间隔失败: "+err.Error())
 			return
 		}
@@ -79,7 +85,7 @@ func (h *SettingsHandler) UpdateCollectionSettings(c *gin.Context) {
 			BadRequest(c, "数据保留天数不能小于 1 天")
 			return
 		}
-		if err := h.settingsRepo.SetDataRetentionDays(*req.DataRetentionDays); err != nil {
+		if err := h.settings(c).SetDataRetentionDays(*req.DataRetentionDays); err != nil {
 			InternalError(c, "更新数据保留天数失败: "+err.Error())
 			return
 		}
@@ -90,7 +96,7 @@ func (h *SettingsHandler) UpdateCollectionSettings(c *gin.Context) {
 			BadRequest(c, "前端刷新间隔必须在 3-60 秒之间")
 			return
 		}
-		if err := h.settingsRepo.SetFrontendRefreshInterval(*req.FrontendRefreshInterval); err != nil {
+		if err := h.settings(c).SetFrontendRefreshInterval(*req.FrontendRefreshInterval); err != nil {
 			InternalError(c, "更新前端刷新间隔失败: "+err.Error())
 			return
 		}
@@ -101,14 +107,14 @@ func (h *SettingsHandler) UpdateCollectionSettings(c *gin.Context) {
 			BadRequest(c, "设备离线超时不能小于 10 秒")
 			return
 		}
-		if err := h.settingsRepo.SetDeviceOfflineTimeout(*req.DeviceOfflineTimeout); err != nil {
+		if err := h.settings(c).SetDeviceOfflineTimeout(*req.DeviceOfflineTimeout); err != nil {
 			InternalError(c, "更新设备离线超时失败: "+err.Error())
 			return
 		}
 	}
 
 	if req.FollowPushInterval != nil {
-		if err := h.settingsRepo.SetFollowPushInterval(*req.FollowPushInterval); err != nil {
+		if err := h.settings(c).SetFollowPushInterval(*req.FollowPushInterval); err != nil {
 			InternalError(c, "更新跟随推送间隔设置失败: "+err.Error())
 			return
 		}
@@ -116,11 +122,11 @@ func (h *SettingsHandler) UpdateCollectionSettings(c *gin.Context) {
 
 	// 返回更新后的设置
 	settings := CollectionSettingsResponse{
-		DefaultPushInterval:     h.settingsRepo.GetDefaultPushInterval(),
-		DataRetentionDays:       h.settingsRepo.GetDataRetentionDays(),
-		FrontendRefreshInterval: h.settingsRepo.GetFrontendRefreshInterval(),
-		DeviceOfflineTimeout:    h.settingsRepo.GetDeviceOfflineTimeout(),
-		FollowPushInterval:      h.settingsRepo.GetFollowPushInterval(),
+		DefaultPushInterval:     h.settings(c).GetDefaultPushInterval(),
+		DataRetentionDays:       h.settings(c).GetDataRetentionDays(),
+		FrontendRefreshInterval: h.settings(c).GetFrontendRefreshInterval(),
+		DeviceOfflineTimeout:    h.settings(c).GetDeviceOfflineTimeout(),
+		FollowPushInterval:      h.settings(c).GetFollowPushInterval(),
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -132,7 +138,7 @@ func (h *SettingsHandler) UpdateCollectionSettings(c *gin.Context) {
 
 // GetAllSettings 获取所有系统设置
 func (h *SettingsHandler) GetAllSettings(c *gin.Context) {
-	settings, err := h.settingsRepo.GetAll()
+	settings, err := h.settings(c).GetAll()
 	if err != nil {
 		InternalError(c, err.Error())
 		return
@@ -143,7 +149,7 @@ func (h *SettingsHandler) GetAllSettings(c *gin.Context) {
 
 // InitDefaults 初始化默认设置
 func (h *SettingsHandler) InitDefaults(c *gin.Context) {
-	if err := h.settingsRepo.InitDefaults(); err != nil {
+	if err := h.settings(c).InitDefaults(); err != nil {
 		InternalError(c, err.Error())
 		return
 	}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultSNMPTrapPageSize trap 查询默认每页条数
+	defaultSNMPTrapPageSize = 100
+	// maxSNMPTrapPageSize trap 查询最大每页条数
+	maxSNMPTrapPageSize = 1000
+)
+
+// SNMPTrapHandler SNMP trap 处理器
+type SNMPTrapHandler struct {
+	trapService *service.SNMPTrapService
+}
+
+// NewSNMPTrapHandler 创建 SNMP trap 处理器
+func NewSNMPTrapHandler(trapService *service.SNMPTrapService) *SNMPTrapHandler {
+	return &SNMPTrapHandler{trapService: trapService}
+}
+
+// QueryTraps 查询 SNMP trap
+// @Summary 查询 SNMP trap
+// @Description 按设备、分组、接口、trap OID 和关键字查询接收到的 SNMP trap/inform，按接收时间倒序
+// @Tags 日志
+// @Produce json
+// @Param device_id query int false "设备ID"
+// @Param group_id query int false "设备分组ID"
+// @Param interface_id query int false "接口ID"
+// @Param trap_oid query string false "trap OID 前缀"
+// @Param search query string false "关键字（trap 名称、接口名称、变量绑定）"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认100，最大1000"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /snmp-traps [get]
+func (h *SNMPTrapHandler) QueryTraps(c *gin.Context) {
+	filter, page, pageSize, err := h.parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的查询参数",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for param, target := range map[string]**uint{
+		"device_id": &filter.DeviceID,
+		"group_id":  &filter.GroupID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "无效的查询参数",
+				Details: fmt.Sprintf("invalid %s: %v", param, err),
+			})
+			return
+		}
+		uid := uint(id)
+		*target = &uid
+	}
+
+	h.respondQuery(c, filter, page, pageSize)
+}
+
+// QueryDeviceTraps 查询单个设备的 SNMP trap
+// @Summary 查询设备 SNMP trap
+// @Tags 日志
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param interface_id query int false "接口ID"
+// @Param trap_oid query string false "trap OID 前缀"
+// @Param search query string false "关键字"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认100，最大1000"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/snmp-traps [get]
+func (h *SNMPTrapHandler) QueryDeviceTraps(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的设备ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	filter, page, pageSize, err := h.parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的查询参数",
+			Details: err.Error(),
+		})
+		return
+	}
+	id := uint(deviceID)
+	filter.DeviceID = &id
+
+	h.respondQuery(c, filter, page, pageSize)
+}
+
+// parseFilter 解析通用查询参数
+func (h *SNMPTrapHandler) parseFilter(c *gin.Context) (repository.SNMPTrapFilter, int, int, error) {
+	filter := repository.SNMPTrapFilter{DeviceIDs: scopedDeviceIDs(c)}
+
+	startTime, endTime, err := parseEventTimeRange(c)
+	if err != nil {
+		return filter, 0, 0, err
+	}
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+	filter.TrapOID = c.Query("trap_oid")
+	filter.Search = c.Query("search")
+
+	if interfaceIDStr := c.Query("interface_id"); interfaceIDStr != "" {
+		interfaceID, err := strconv.ParseUint(interfaceIDStr, 10, 32)
+		if err != nil {
+			return filter, 0, 0, fmt.Errorf("invalid interface_id: %w", err)
+		}
+		id := uint(interfaceID)
+		filter.InterfaceID = &id
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultSNMPTrapPageSize)))
+	if pageSize < 1 || pageSize > maxSNMPTrapPageSize {
+		return filter, 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxSNMPTrapPageSize)
+	}
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	return filter, page, pageSize, nil
+}
+
+// respondQuery 执行查询并返回分页结果
+func (h *SNMPTrapHandler) respondQuery(c *gin.Context, filter repository.SNMPTrapFilter, page, pageSize int) {
+	traps, total, err := h.trapService.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "查询 SNMP trap 失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	SuccessPaginated(c, traps, total, page, pageSize)
+}
+
+// RegisterRoutesWithPermission 注册 SNMP trap 相关路由（带权限检查）
+func (h *SNMPTrapHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware gin.HandlerFunc) {
+	router.GET("/snmp-traps", h.QueryTraps)
+
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/snmp-traps", readMiddleware, h.QueryDeviceTraps)
+	}
+}
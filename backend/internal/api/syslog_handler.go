@@ -0,0 +1,305 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultSyslogPageSize syslog 查询默认每页条数
+	defaultSyslogPageSize = 100
+	// maxSyslogPageSize syslog 查询最大每页条数
+	maxSyslogPageSize = 1000
+)
+
+// SyslogHandler syslog 日志处理器
+type SyslogHandler struct {
+	syslogService *service.SyslogService
+	deviceRepo    repository.DeviceRepository
+	deployer      *collector.Deployer
+	remoteHost    string // 设备转发日志的目标地址（平台地址）
+	remotePort    int
+}
+
+// NewSyslogHandler 创建 syslog 日志处理器
+func NewSyslogHandler(
+	syslogService *service.SyslogService,
+	deviceRepo repository.DeviceRepository,
+	serverURL string,
+	remoteHost string,
+	remotePort int,
+) *SyslogHandler {
+	return &SyslogHandler{
+		syslogService: syslogService,
+		deviceRepo:    deviceRepo,
+		deployer:      collector.NewDeployer(serverURL),
+		remoteHost:    remoteHost,
+		remotePort:    remotePort,
+	}
+}
+
+// ConfigureSyslogRequest 配置设备日志转发请求
+type ConfigureSyslogRequest struct {
+	Remote string   `json:"remote"` // 为空使用平台地址
+	Port   int      `json:"port"`   // 为空使用平台 syslog 端口
+	Topics []string `json:"topics"` // 为空转发 info,warning,error,critical
+}
+
+// QuerySyslog 查询 syslog 日志
+// @Summary 查询 syslog 日志
+// @Description 按设备、分组、严重级别和关键字查询设备 syslog 日志，按接收时间倒序
+// @Tags 日志
+// @Produce json
+// @Param device_id query int false "设备ID"
+// @Param group_id query int false "设备分组ID"
+// @Param severity query string false "最低严重级别（emergency/alert/critical/error/warning/notice/info/debug 或 0-7）"
+// @Param search query string false "关键字"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认100，最大1000"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /syslog [get]
+func (h *SyslogHandler) QuerySyslog(c *gin.Context) {
+	filter, page, pageSize, err := h.parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的查询参数",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if deviceIDStr := c.Query("device_id"); deviceIDStr != "" {
+		deviceID, err := strconv.ParseUint(deviceIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "无效的设备ID",
+				Details: err.Error(),
+			})
+			return
+		}
+		id := uint(deviceID)
+		filter.DeviceID = &id
+	}
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "无效的分组ID",
+				Details: err.Error(),
+			})
+			return
+		}
+		id := uint(groupID)
+		filter.GroupID = &id
+	}
+
+	h.respondQuery(c, filter, page, pageSize)
+}
+
+// QueryDeviceSyslog 查询单个设备的 syslog 日志
+// @Summary 查询设备 syslog 日志
+// @Tags 日志
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param severity query string false "最低严重级别"
+// @Param search query string false "关键字"
+// @Param start_time query string false "开始时间 (RFC3339)，默认24小时前"
+// @Param end_time query string false "结束时间 (RFC3339)，默认当前时间"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认100，最大1000"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /devices/{id}/syslog [get]
+func (h *SyslogHandler) QueryDeviceSyslog(c *gin.Context) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的设备ID",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	filter, page, pageSize, err := h.parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "无效的查询参数",
+			Details: err.Error(),
+		})
+		return
+	}
+	id := uint(deviceID)
+	filter.DeviceID = &id
+
+	h.respondQuery(c, filter, page, pageSize)
+}
+
+// ConfigureDeviceSyslog 配置 RouterOS 设备将日志转发到平台
+// @Summary 配置设备日志转发
+// @Description 在 RouterOS 设备上创建 /system logging action，将日志转发到平台内置 syslog 接收器
+// @Tags 日志
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param request body ConfigureSyslogRequest false "转发配置"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/syslog/forward [post]
+func (h *SyslogHandler) ConfigureDeviceSyslog(c *gin.Context) {
+	device, ok := h.getMikroTikDevice(c)
+	if !ok {
+		return
+	}
+
+	var req ConfigureSyslogRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "无效的请求格式")
+			return
+		}
+	}
+
+	cfg := &collector.SyslogForwardConfig{
+		Remote:     req.Remote,
+		RemotePort: req.Port,
+		Topics:     req.Topics,
+	}
+	if cfg.Remote == "" {
+		cfg.Remote = h.remoteHost
+	}
+	if cfg.RemotePort == 0 {
+		cfg.RemotePort = h.remotePort
+	}
+
+	result := h.deployer.ConfigureSyslog(cfg, device.Host, device.APIPort, device.Port, device.Username, device.Password)
+	if !result.Success {
+		ErrorWithDetails(c, http.StatusInternalServerError, "配置日志转发失败", result.ErrorMessage)
+		return
+	}
+
+	Success(c, result)
+}
+
+// RemoveDeviceSyslog 移除 RouterOS 设备上的日志转发配置
+// @Summary 移除设备日志转发
+// @Tags 日志
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /devices/{id}/syslog/forward [delete]
+func (h *SyslogHandler) RemoveDeviceSyslog(c *gin.Context) {
+	device, ok := h.getMikroTikDevice(c)
+	if !ok {
+		return
+	}
+
+	result := h.deployer.RemoveSyslog("", device.Host, device.APIPort, device.Port, device.Username, device.Password)
+	if !result.Success {
+		ErrorWithDetails(c, http.StatusInternalServerError, "移除日志转发失败", result.ErrorMessage)
+		return
+	}
+
+	Success(c, result)
+}
+
+// getMikroTikDevice 获取路径中的 MikroTik 设备，失败时直接写入错误响应
+func (h *SyslogHandler) getMikroTikDevice(c *gin.Context) (*models.Device, bool) {
+	deviceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的设备ID")
+		return nil, false
+	}
+
+	device, err := h.deviceRepo.GetByID(uint(deviceID))
+	if err != nil {
+		NotFound(c, "设备不存在")
+		return nil, false
+	}
+	if device.OSType != models.DeviceOSTypeMikroTik {
+		BadRequest(c, "仅支持 MikroTik RouterOS 设备")
+		return nil, false
+	}
+
+	return device, true
+}
+
+// parseFilter 解析通用查询参数
+func (h *SyslogHandler) parseFilter(c *gin.Context) (repository.SyslogFilter, int, int, error) {
+	filter := repository.SyslogFilter{DeviceIDs: scopedDeviceIDs(c)}
+
+	startTime, endTime, err := parseEventTimeRange(c)
+	if err != nil {
+		return filter, 0, 0, err
+	}
+	filter.StartTime = startTime
+	filter.EndTime = endTime
+	filter.Search = c.Query("search")
+
+	if severityStr := c.Query("severity"); severityStr != "" {
+		severity, err := models.ParseSyslogSeverity(severityStr)
+		if err != nil {
+			return filter, 0, 0, err
+		}
+		filter.MaxSeverity = &severity
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultSyslogPageSize)))
+	if pageSize < 1 || pageSize > maxSyslogPageSize {
+		return filter, 0, 0, fmt.Errorf("page_size must be between 1 and %d", maxSyslogPageSize)
+	}
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	return filter, page, pageSize, nil
+}
+
+// respondQuery 执行查询并返回分页结果
+func (h *SyslogHandler) respondQuery(c *gin.Context, filter repository.SyslogFilter, page, pageSize int) {
+	messages, total, err := h.syslogService.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "查询 syslog 日志失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	SuccessPaginated(c, messages, total, page, pageSize)
+}
+
+// RegisterRoutesWithPermission 注册 syslog 相关路由（带权限检查）
+func (h *SyslogHandler) RegisterRoutesWithPermission(router *gin.RouterGroup, readMiddleware, updateMiddleware gin.HandlerFunc) {
+	router.GET("/syslog", h.QuerySyslog)
+
+	devices := router.Group("/devices")
+	{
+		devices.GET("/:id/syslog", readMiddleware, h.QueryDeviceSyslog)
+		devices.POST("/:id/syslog/forward", updateMiddleware, h.ConfigureDeviceSyslog)
+		devices.DELETE("/:id/syslog/forward", updateMiddleware, h.RemoveDeviceSyslog)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -42,6 +43,11 @@ type ListTagsRequest struct {
 	PageSize int `form:"page_size,default=20"`
 }
 
+// tags 返回限定到当前组织的标签服务
+func (h *TagHandler) tags(c *gin.Context) service.TagService {
+	return tenant.Bind(c.Request.Context(), h.tagService)
+}
+
 // CreateTag 创建标签
 func (h *TagHandler) CreateTag(c *gin.Context) {
 	var req CreateTagRequest
@@ -65,7 +71,7 @@ func (h *TagHandler) CreateTag(c *gin.Context) {
 		tag.Color = "#007bff"
 	}
 
-	err := h.tagService.CreateTag(tag)
+	err := h.tags(c).CreateTag(tag)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -90,7 +96,7 @@ func (h *TagHandler) GetTag(c *gin.Context) {
 		return
 	}
 
-	tag, err := h.tagService.GetTag(uint(id))
+	tag, err := h.tags(c).GetTag(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -125,7 +131,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 	}
 
 	// 获取现有标签
-	tag, err := h.tagService.GetTag(uint(id))
+	tag, err := h.tags(c).GetTag(uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": err.Error(),
@@ -143,7 +149,7 @@ func (h *TagHandler) UpdateTag(c *gin.Context) {
 		tag.Color = "#007bff"
 	}
 
-	err = h.tagService.UpdateTag(tag)
+	err = h.tags(c).UpdateTag(tag)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -168,7 +174,7 @@ func (h *TagHandler) DeleteTag(c *gin.Context) {
 		return
 	}
 
-	err = h.tagService.DeleteTag(uint(id))
+	err = h.tags(c).DeleteTag(uint(id))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
@@ -199,7 +205,7 @@ func (h *TagHandler) ListTags(c *gin.Context) {
 		offset = 0
 	}
 
-	tags, total, err := h.tagService.ListTags(offset, req.PageSize)
+	tags, total, err := h.tags(c).ListTags(offset, req.PageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
@@ -224,7 +230,7 @@ func (h *TagHandler) ListTags(c *gin.Context) {
 
 // GetAllTags 获取所有标签（不分页）
 func (h *TagHandler) GetAllTags(c *gin.Context) {
-	tags, err := h.tagService.GetAllTags()
+	tags, err := h.tags(c).GetAllTags()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": err.Error(),
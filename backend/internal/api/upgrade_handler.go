@@ -0,0 +1,463 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultUpgradeJobPageSize 升级任务默认每页条数
+	defaultUpgradeJobPageSize = 20
+	// maxUpgradeJobPageSize 升级任务最大每页条数
+	maxUpgradeJobPageSize = 100
+	// maxUpgradePackageSize 升级包最大大小
+	maxUpgradePackageSize = 512 << 20
+)
+
+// UpgradeHandler RouterOS 升级处理器
+type UpgradeHandler struct {
+	upgradeService *service.UpgradeService
+	permChecker    DevicePermissionChecker
+}
+
+// NewUpgradeHandler 创建 RouterOS 升级处理器
+func NewUpgradeHandler(upgradeService *service.UpgradeService, permChecker DevicePermissionChecker) *UpgradeHandler {
+	return &UpgradeHandler{
+		upgradeService: upgradeService,
+		permChecker:    permChecker,
+	}
+}
+
+// UploadPackage 上传升级包
+// @Summary 上传 RouterOS 升级包
+// @Description 上传 .npk 升级包保存到平台本地，文件名需保持官方命名（如 routeros-7.14.2-arm64.npk）以识别名称、版本和架构；仅超级管理员可用
+// @Tags RouterOS升级
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "升级包文件"
+// @Success 201 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /upgrade-packages [post]
+func (h *UpgradeHandler) UploadPackage(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUpgradePackageSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "请上传升级包文件", err.Error())
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "读取升级包失败", err.Error())
+		return
+	}
+	defer file.Close()
+
+	username, _ := c.Get("username")
+	name, _ := username.(string)
+
+	pkg, err := h.upgradeService.SavePackage(fileHeader.Filename, file, name)
+	if err != nil {
+		if errors.Is(err, service.ErrUpgradePackageExists) {
+			Error(c, http.StatusConflict, "同名升级包已存在")
+			return
+		}
+		ErrorWithDetails(c, http.StatusBadRequest, "保存升级包失败", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    pkg,
+	})
+}
+
+// ListPackages 查询升级包
+// @Summary 查询 RouterOS 升级包
+// @Tags RouterOS升级
+// @Produce json
+// @Success 200 {object} SuccessResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /upgrade-packages [get]
+func (h *UpgradeHandler) ListPackages(c *gin.Context) {
+	packages, err := h.upgradeService.ListPackages()
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询升级包失败", err.Error())
+		return
+	}
+
+	Success(c, packages)
+}
+
+// DeletePackage 删除升级包
+// @Summary 删除 RouterOS 升级包
+// @Description 删除升级包记录和文件，执行中任务使用的升级包不能删除；仅超级管理员可用
+// @Tags RouterOS升级
+// @Produce json
+// @Param id path int true "升级包ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /upgrade-packages/{id} [delete]
+func (h *UpgradeHandler) DeletePackage(c *gin.Context) {
+	if !h.requireSuperAdmin(c) {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的升级包ID")
+		return
+	}
+
+	if err := h.upgradeService.DeletePackage(uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			NotFound(c, "升级包不存在")
+		case errors.Is(err, service.ErrUpgradePackageInUse):
+			Error(c, http.StatusConflict, "升级包正在被执行中的任务使用")
+		default:
+			ErrorWithDetails(c, http.StatusInternalServerError, "删除升级包失败", err.Error())
+		}
+		return
+	}
+
+	Success(c, gin.H{"id": id})
+}
+
+// FetchPackage 设备下载升级包
+// @Summary 下载 RouterOS 升级包（设备使用）
+// @Description 无法通过 SSH 上传时，平台通过 API 让设备以 /tool fetch 下载升级包；地址带有限时签名，无需登录
+// @Tags RouterOS升级
+// @Produce application/octet-stream
+// @Param file path string true "升级包文件名"
+// @Param expires query int true "过期时间（Unix 时间戳）"
+// @Param signature query string true "签名"
+// @Success 200 {file} file
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /upgrade-packages/fetch/{file} [get]
+func (h *UpgradeHandler) FetchPackage(c *gin.Context) {
+	fileName := c.Param("file")
+	if !h.upgradeService.VerifyFetchSignature(fileName, c.Query("expires"), c.Query("signature")) {
+		Forbidden(c, "下载地址无效或已过期")
+		return
+	}
+
+	pkg, err := h.upgradeService.GetPackageByFileName(fileName)
+	if err != nil {
+		NotFound(c, "升级包不存在")
+		return
+	}
+	file, err := h.upgradeService.OpenPackage(pkg)
+	if err != nil {
+		NotFound(c, "升级包文件不存在")
+		return
+	}
+	defer file.Close()
+
+	c.DataFromReader(http.StatusOK, pkg.Size, "application/octet-stream", file, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%s", pkg.FileName),
+	})
+}
+
+// PreviewUpgrade 预检升级计划
+// @Summary 预检 RouterOS 升级
+// @Description 校验升级包文件完整性，并根据设备清单中的架构和版本为每台设备选择升级包，列出无法升级的原因；需要对每台设备有 update 权限
+// @Tags RouterOS升级
+// @Accept json
+// @Produce json
+// @Param request body service.UpgradeRequest true "升级配置"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /upgrade-jobs/preview [post]
+func (h *UpgradeHandler) PreviewUpgrade(c *gin.Context) {
+	req, ok := h.bindAndAuthorize(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.upgradeService.Preview(c.Request.Context(), req)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的升级配置", err.Error())
+		return
+	}
+
+	Success(c, plan)
+}
+
+// CreateUpgradeJob 创建并执行升级任务
+// @Summary 执行 RouterOS 升级
+// @Description 先将升级包上传到全部设备，全部成功后按 device_ids 顺序分批重启；每批设备恢复在线并重新推送数据、版本校验通过后才继续下一批，任一设备失败立即停止；需要对每台设备有 update 权限
+// @Tags RouterOS升级
+// @Accept json
+// @Produce json
+// @Param request body service.UpgradeRequest true "升级配置"
+// @Success 202 {object} SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /upgrade-jobs [post]
+func (h *UpgradeHandler) CreateUpgradeJob(c *gin.Context) {
+	req, ok := h.bindAndAuthorize(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	uid, _ := userID.(uint)
+	name, _ := username.(string)
+
+	job, plan, err := h.upgradeService.Submit(c.Request.Context(), req, uid, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUpgradePlanInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "升级预检未通过",
+				"details": err.Error(),
+				"plan":    plan,
+			})
+		case errors.Is(err, service.ErrUpgradeDeviceBusy):
+			ErrorWithDetails(c, http.StatusConflict, "设备正在升级中", err.Error())
+		case plan == nil:
+			ErrorWithDetails(c, http.StatusBadRequest, "无效的升级配置", err.Error())
+		default:
+			ErrorWithDetails(c, http.StatusInternalServerError, "创建升级任务失败", err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// ListUpgradeJobs 查询升级任务
+// @Summary 查询 RouterOS 升级任务
+// @Description 按时间倒序返回任务列表（不含设备进度），非管理员只能看到自己创建的任务
+// @Tags RouterOS升级
+// @Produce json
+// @Param status query string false "任务状态"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Success 200 {object} PaginatedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /upgrade-jobs [get]
+func (h *UpgradeHandler) ListUpgradeJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultUpgradeJobPageSize)))
+	if pageSize < 1 || pageSize > maxUpgradeJobPageSize {
+		BadRequest(c, fmt.Sprintf("page_size 必须在 1 到 %d 之间", maxUpgradeJobPageSize))
+		return
+	}
+
+	filter := repository.UpgradeJobFilter{
+		Status: models.UpgradeJobStatus(c.Query("status")),
+		Offset: (page - 1) * pageSize,
+		Limit:  pageSize,
+	}
+
+	userID, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if !isAdmin {
+		filter.CreatedBy = &userID
+	}
+
+	jobs, total, err := h.upgradeService.ListJobs(c.Request.Context(), filter)
+	if err != nil {
+		ErrorWithDetails(c, http.StatusInternalServerError, "查询升级任务失败", err.Error())
+		return
+	}
+
+	SuccessPaginated(c, jobs, total, page, pageSize)
+}
+
+// GetUpgradeJob 获取升级任务详情
+// @Summary 获取 RouterOS 升级任务
+// @Description 返回任务及每台设备的状态、升级前后版本和完整升级日志
+// @Tags RouterOS升级
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /upgrade-jobs/{id} [get]
+func (h *UpgradeHandler) GetUpgradeJob(c *gin.Context) {
+	job, ok := h.getAccessibleJob(c)
+	if !ok {
+		return
+	}
+
+	Success(c, job)
+}
+
+// CancelUpgradeJob 取消升级任务
+// @Summary 取消 RouterOS 升级任务
+// @Description 不再重启后续设备，已上传但未重启设备上的升级包会被删除
+// @Tags RouterOS升级
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /upgrade-jobs/{id}/cancel [post]
+func (h *UpgradeHandler) CancelUpgradeJob(c *gin.Context) {
+	job, ok := h.getAccessibleJob(c)
+	if !ok {
+		return
+	}
+
+	if err := h.upgradeService.Cancel(job.ID); err != nil {
+		if errors.Is(err, service.ErrUpgradeJobNotRunning) {
+			Error(c, http.StatusConflict, "任务未在执行")
+			return
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "取消任务失败", err.Error())
+		return
+	}
+
+	Success(c, gin.H{"id": job.ID})
+}
+
+// bindAndAuthorize 解析请求并检查每台设备的 update 权限，失败时直接写入错误响应
+func (h *UpgradeHandler) bindAndAuthorize(c *gin.Context) (*service.UpgradeRequest, bool) {
+	var req service.UpgradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorWithDetails(c, http.StatusBadRequest, "无效的请求格式", err.Error())
+		return nil, false
+	}
+
+	userID, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+	if isAdmin {
+		return &req, true
+	}
+
+	var denied []string
+	for _, deviceID := range req.DeviceIDs {
+		allowed, err := h.permChecker.CheckDevicePermission(userID, deviceID, models.DeviceActionUpdate)
+		if err != nil {
+			InternalError(c, "权限检查失败")
+			return nil, false
+		}
+		if !allowed {
+			denied = append(denied, strconv.FormatUint(uint64(deviceID), 10))
+		}
+	}
+	if len(denied) > 0 {
+		ErrorWithDetails(c, http.StatusForbidden, "无权限操作部分目标设备", strings.Join(denied, ", "))
+		return nil, false
+	}
+
+	return &req, true
+}
+
+// requireSuperAdmin 检查当前用户为超级管理员，失败时直接写入错误响应
+func (h *UpgradeHandler) requireSuperAdmin(c *gin.Context) bool {
+	_, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return false
+	}
+	if !isAdmin {
+		Forbidden(c, "仅超级管理员可以管理升级包")
+		return false
+	}
+	return true
+}
+
+// currentUser 获取当前用户及是否为管理员，失败时直接写入错误响应
+func (h *UpgradeHandler) currentUser(c *gin.Context) (uint, bool, bool) {
+	value, exists := c.Get("user_id")
+	userID, ok := value.(uint)
+	if !exists || !ok {
+		Unauthorized(c, "用户未认证")
+		return 0, false, false
+	}
+
+	isAdmin, err := h.permChecker.IsSuperAdmin(userID)
+	if err != nil {
+		InternalError(c, "权限检查失败")
+		return 0, false, false
+	}
+	return userID, isAdmin, true
+}
+
+// getAccessibleJob 获取路径中的任务，非管理员只能访问自己创建的任务
+func (h *UpgradeHandler) getAccessibleJob(c *gin.Context) (*models.UpgradeJob, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的任务ID")
+		return nil, false
+	}
+
+	userID, isAdmin, ok := h.currentUser(c)
+	if !ok {
+		return nil, false
+	}
+
+	job, err := h.upgradeService.GetJob(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			NotFound(c, "任务不存在")
+			return nil, false
+		}
+		ErrorWithDetails(c, http.StatusInternalServerError, "获取任务失败", err.Error())
+		return nil, false
+	}
+	if !isAdmin && job.CreatedBy != userID {
+		NotFound(c, "任务不存在")
+		return nil, false
+	}
+
+	return job, true
+}
+
+// RegisterRoutes 注册 RouterOS 升级路由（设备级权限在处理器内逐台检查）
+func (h *UpgradeHandler) RegisterRoutes(router *gin.RouterGroup) {
+	packages := router.Group("/upgrade-packages")
+	{
+		packages.POST("", h.UploadPackage)
+		packages.GET("", h.ListPackages)
+		packages.DELETE("/:id", h.DeletePackage)
+	}
+
+	jobs := router.Group("/upgrade-jobs")
+	{
+		jobs.POST("/preview", h.PreviewUpgrade)
+		jobs.POST("", h.CreateUpgradeJob)
+		jobs.GET("", h.ListUpgradeJobs)
+		jobs.GET("/:id", h.GetUpgradeJob)
+		jobs.POST("/:id/cancel", h.CancelUpgradeJob)
+	}
+}
+
+// RegisterPublicRoutes 注册设备下载升级包的路由（签名校验，无需登录）
+func (h *UpgradeHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/upgrade-packages/fetch/:file", h.FetchPackage)
+}
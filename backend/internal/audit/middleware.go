@@ -0,0 +1,292 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nmp-platform/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxRequestBody 记录请求参数的最大请求体长度，超过时不记录请求参数
+	maxRequestBody = 1 << 20
+	// maxResponseBody 解析响应时最多缓存的响应体长度
+	maxResponseBody = 64 << 10
+)
+
+// route 从路由模板解析出的资源信息
+type route struct {
+	resourceType string
+	resourceID   string
+	words        []string // 资源ID之后的非参数路径段，如 ping-targets
+}
+
+// action 操作名称
+// 资源本身的增删改为 devices.create / devices.update / devices.delete，
+// 资源上的动作为 devices.ping（POST）或 devices.ping-targets.update（其他方法）
+func (r route) action(method string) string {
+	if len(r.words) == 0 {
+		return r.resourceType + "." + methodVerb(method)
+	}
+	action := r.resourceType + "." + strings.Join(r.words, ".")
+	if method == http.MethodPost {
+		return action
+	}
+	return action + "." + methodVerb(method)
+}
+
+// isCollectionCreate 是否为在资源集合上创建资源（如 POST /devices）
+func (r route) isCollectionCreate(method string) bool {
+	return method == http.MethodPost && r.resourceID == "" && len(r.words) == 0
+}
+
+// methodVerb HTTP 方法对应的操作
+func methodVerb(method string) string {
+	switch method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "update"
+	}
+}
+
+// isMutating 是否为变更操作
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// routeGroups 不作为资源类型的路由组前缀
+var routeGroups = map[string]bool{"admin": true, "rbac": true}
+
+// parseRoute 解析路由模板，忽略 /api/v1 前缀和 admin、rbac 路由组
+// 如 /api/v1/admin/users/:id/lockout 解析为资源 users、资源ID为 :id 的值、动作 lockout
+func parseRoute(fullPath string, params gin.Params) route {
+	var segments []string
+	for _, segment := range strings.Split(fullPath, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) >= 2 && segments[0] == "api" && strings.HasPrefix(segments[1], "v") {
+		segments = segments[2:]
+	}
+	if len(segments) > 1 && routeGroups[segments[0]] {
+		segments = segments[1:]
+	}
+	if len(segments) == 0 {
+		return route{}
+	}
+
+	r := route{resourceType: segments[0]}
+	rest := segments[1:]
+	if len(rest) > 0 && isParam(rest[0]) {
+		r.resourceID = params.ByName(rest[0][1:])
+		rest = rest[1:]
+	}
+	for _, segment := range rest {
+		if !isParam(segment) {
+			r.words = append(r.words, segment)
+		}
+	}
+	return r
+}
+
+// hasPrefix 路由是否以任一前缀开头
+func hasPrefix(fullPath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(fullPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isParam 路径段是否为路由参数
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*")
+}
+
+// responseCapture 缓存响应体的前 maxResponseBody 字节，用于读取创建结果和错误信息
+type responseCapture struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseCapture) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseCapture) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *responseCapture) capture(data []byte) {
+	if remaining := maxResponseBody - w.body.Len(); remaining > 0 {
+		if len(data) > remaining {
+			data = data[:remaining]
+		}
+		w.body.Write(data)
+	}
+}
+
+// response 解析 JSON 响应，无法解析时返回 nil
+func (w *responseCapture) response() map[string]interface{} {
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.body.Bytes(), &body); err != nil {
+		return nil
+	}
+	return body
+}
+
+// readRequest 读取并还原 JSON 请求体，返回解析后的请求参数
+func readRequest(c *gin.Context) interface{} {
+	if c.Request.Body == nil || !strings.Contains(c.GetHeader("Content-Type"), "application/json") {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRequestBody+1))
+	// 未读完的部分原样保留给后续处理函数
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), c.Request.Body), c.Request.Body}
+	if err != nil || len(data) == 0 || len(data) > maxRequestBody {
+		return nil
+	}
+
+	var request interface{}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil
+	}
+	return request
+}
+
+// snapshot 通过注册的加载函数获取资源快照
+func (r *Recorder) snapshot(resourceType, resourceID string) interface{} {
+	loader := r.loader(resourceType)
+	if loader == nil || resourceID == "" {
+		return nil
+	}
+	id, err := strconv.ParseUint(resourceID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	value, err := loader(uint(id))
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// Middleware 审计中间件，记录所有变更请求（POST/PUT/PATCH/DELETE）
+// 操作者来自认证中间件设置的上下文，变更前后快照通过注册的加载函数获取，
+// 敏感字段只记录“已修改”而不记录值。skipPrefixes 为不记录的路由前缀（如设备数据推送）
+func (r *Recorder) Middleware(skipPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fullPath := c.FullPath()
+		if r == nil || fullPath == "" || !isMutating(c.Request.Method) || hasPrefix(fullPath, skipPrefixes) {
+			c.Next()
+			return
+		}
+
+		method := c.Request.Method
+		rt := parseRoute(fullPath, c.Params)
+		request := readRequest(c)
+		before := r.snapshot(rt.resourceType, rt.resourceID)
+
+		writer := &responseCapture{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		entry := Entry{
+			OrganizationID: c.GetUint("organization_id"),
+			Action:         rt.action(method),
+			ResourceType:   rt.resourceType,
+			ResourceID:     rt.resourceID,
+			Method:         method,
+			Route:          fullPath,
+			StatusCode:     writer.Status(),
+			Status:         models.AuditStatusSuccess,
+			Request:        request,
+			SourceIP:       c.ClientIP(),
+			RequestID:      c.GetString("request_id"),
+		}
+		if userID := c.GetUint("user_id"); userID != 0 {
+			entry.ActorType = models.AuditActorUser
+			entry.ActorID = userID
+			entry.ActorName = c.GetString("username")
+			if tokenID := c.GetUint("api_token_id"); tokenID != 0 {
+				entry.ActorType = models.AuditActorAPIToken
+				entry.APITokenID = tokenID
+			}
+		} else {
+			// 未认证的请求（登录、刷新令牌等）记录请求中的用户名
+			entry.ActorType = models.AuditActorAnonymous
+			if fields, ok := request.(map[string]interface{}); ok {
+				entry.ActorName, _ = fields["username"].(string)
+			}
+		}
+
+		response := writer.response()
+		if entry.StatusCode >= http.StatusBadRequest {
+			entry.Status = models.AuditStatusFailure
+			entry.Detail = responseMessage(response)
+			entry.Changes = map[string]Change{}
+			r.Record(entry)
+			return
+		}
+
+		var after interface{}
+		switch {
+		case method == http.MethodDelete && len(rt.words) == 0:
+			// 资源已删除，after 为空
+		case rt.isCollectionCreate(method):
+			if response != nil {
+				after = response["data"]
+				if data, ok := after.(map[string]interface{}); ok {
+					if id, ok := data["id"].(float64); ok {
+						entry.ResourceID = strconv.FormatUint(uint64(id), 10)
+					}
+				}
+			}
+		default:
+			after = r.snapshot(rt.resourceType, rt.resourceID)
+		}
+
+		entry.Changes = map[string]Change{}
+		if before != nil || after != nil {
+			entry.Changes = Diff(before, after)
+			// 密码等敏感字段不会出现在快照中，只记录被修改
+			for _, field := range secretFields(request) {
+				if _, ok := entry.Changes[field]; !ok {
+					entry.Changes[field] = Change{After: Redacted}
+				}
+			}
+		}
+		r.Record(entry)
+	}
+}
+
+// responseMessage 从错误响应中读取错误信息
+func responseMessage(response map[string]interface{}) string {
+	for _, key := range []string{"error", "message"} {
+		if message, ok := response[key].(string); ok && message != "" {
+			return message
+		}
+	}
+	return ""
+}
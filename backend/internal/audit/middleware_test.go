@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testDevice struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func setupAuditTest(t *testing.T) (*gin.Engine, repository.AuditLogRepository, map[uint]*testDevice) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.AuditLog{}))
+	repo := repository.NewAuditLogRepository(db)
+
+	devices := map[uint]*testDevice{1: {ID: 1, Name: "r1", Password: "old"}}
+	recorder := NewRecorder(repo, 0)
+	recorder.RegisterLoader("devices", func(id uint) (interface{}, error) {
+		device, ok := devices[id]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		copied := *device
+		return &copied, nil
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("request_id", "req-"+c.Request.Method)
+		c.Next()
+	})
+	router.Use(recorder.Middleware("/api/v1/push"))
+
+	authenticated := router.Group("/api/v1", func(c *gin.Context) {
+		c.Set("user_id", uint(3))
+		c.Set("username", "operator")
+		c.Set("organization_id", uint(2))
+		if c.GetHeader("X-API-Token") != "" {
+			c.Set("api_token_id", uint(9))
+		}
+		c.Next()
+	})
+	authenticated.POST("/devices", func(c *gin.Context) {
+		var device testDevice
+		c.ShouldBindJSON(&device)
+		device.ID = 2
+		devices[device.ID] = &device
+		c.JSON(http.StatusCreated, gin.H{"success": true, "data": device})
+	})
+	authenticated.PUT("/devices/:id", func(c *gin.Context) {
+		var device testDevice
+		c.ShouldBindJSON(&device)
+		if device.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+		devices[1].Name = device.Name
+		if device.Password != "" {
+			devices[1].Password = device.Password
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	authenticated.DELETE("/devices/:id", func(c *gin.Context) {
+		delete(devices, 1)
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	authenticated.POST("/devices/:id/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	authenticated.GET("/devices", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	router.POST("/api/v1/push/metrics", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"success": true})
+	})
+	router.POST("/api/v1/auth/login", func(c *gin.Context) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid username or password"})
+	})
+	return router, repo, devices
+}
+
+func send(router *gin.Engine, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.10:5000"
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func latestEntry(t *testing.T, repo repository.AuditLogRepository) (*models.AuditLog, map[string]Change) {
+	entries, _, err := repo.Query(repository.AuditLogFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	changes := map[string]Change{}
+	if entries[0].Changes != "" {
+		require.NoError(t, json.Unmarshal([]byte(entries[0].Changes), &changes))
+	}
+	return entries[0], changes
+}
+
+func TestMiddleware(t *testing.T) {
+	router, repo, _ := setupAuditTest(t)
+
+	// 创建：资源ID取自响应，记录全部字段，敏感字段脱敏
+	require.Equal(t, http.StatusCreated, send(router, http.MethodPost, "/api/v1/devices", `{"name":"r2","password":"p@ss"}`).Code)
+	entry, changes := latestEntry(t, repo)
+	assert.Equal(t, "devices.create", entry.Action)
+	assert.Equal(t, "devices", entry.ResourceType)
+	assert.Equal(t, "2", entry.ResourceID)
+	assert.Equal(t, uint(2), entry.OrganizationID)
+	assert.Equal(t, models.AuditActorUser, entry.ActorType)
+	assert.Equal(t, uint(3), entry.ActorID)
+	assert.Equal(t, "operator", entry.ActorName)
+	assert.Equal(t, "192.0.2.10", entry.SourceIP)
+	assert.Equal(t, "req-POST", entry.RequestID)
+	assert.Equal(t, http.StatusCreated, entry.StatusCode)
+	assert.Equal(t, Change{After: "r2"}, changes["name"])
+	assert.Equal(t, Change{After: Redacted}, changes["password"])
+	assert.Equal(t, `{"name":"r2","password":"[REDACTED]"}`, entry.Request)
+	assert.NotContains(t, entry.Changes+entry.Request, "p@ss")
+
+	// 修改：通过加载函数比较前后快照
+	send(router, http.MethodPut, "/api/v1/devices/1", `{"name":"r1-core","password":"new"}`, "X-API-Token", "1")
+	entry, changes = latestEntry(t, repo)
+	assert.Equal(t, "devices.update", entry.Action)
+	assert.Equal(t, "1", entry.ResourceID)
+	assert.Equal(t, "/api/v1/devices/:id", entry.Route)
+	assert.Equal(t, models.AuditActorAPIToken, entry.ActorType)
+	assert.Equal(t, uint(9), entry.APITokenID)
+	assert.Equal(t, map[string]Change{
+		"name":     {Before: "r1", After: "r1-core"},
+		"password": {Before: Redacted, After: Redacted},
+	}, changes)
+
+	// 失败的操作记录错误信息，不记录变更
+	send(router, http.MethodPut, "/api/v1/devices/1", `{}`)
+	entry, changes = latestEntry(t, repo)
+	assert.Equal(t, models.AuditStatusFailure, entry.Status)
+	assert.Equal(t, "name is required", entry.Detail)
+	assert.Empty(t, changes)
+
+	// 资源上的动作
+	send(router, http.MethodPost, "/api/v1/devices/1/ping", ``)
+	entry, changes = latestEntry(t, repo)
+	assert.Equal(t, "devices.ping", entry.Action)
+	assert.Empty(t, changes)
+
+	// 删除：记录删除前的快照
+	send(router, http.MethodDelete, "/api/v1/devices/1", ``)
+	entry, changes = latestEntry(t, repo)
+	assert.Equal(t, "devices.delete", entry.Action)
+	assert.Equal(t, Change{Before: "r1-core"}, changes["name"])
+	assert.Equal(t, Change{Before: Redacted}, changes["password"])
+
+	// 未认证的请求记录请求中的用户名，不记录密码
+	send(router, http.MethodPost, "/api/v1/auth/login", `{"username":"admin","password":"guess"}`)
+	entry, _ = latestEntry(t, repo)
+	assert.Equal(t, "auth.login", entry.Action)
+	assert.Equal(t, models.AuditActorAnonymous, entry.ActorType)
+	assert.Equal(t, "admin", entry.ActorName)
+	assert.Equal(t, models.AuditStatusFailure, entry.Status)
+	assert.NotContains(t, entry.Request, "guess")
+
+	// 查询操作和未匹配的路由不记录
+	send(router, http.MethodGet, "/api/v1/devices", ``)
+	send(router, http.MethodPost, "/api/v1/unknown", `{}`)
+	send(router, http.MethodPost, "/api/v1/push/metrics", `{}`)
+	_, total, err := repo.Query(repository.AuditLogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), total)
+}
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		params   gin.Params
+		resource string
+		id       string
+		action   string
+	}{
+		{http.MethodPost, "/api/v1/devices", nil, "devices", "", "devices.create"},
+		{http.MethodPatch, "/api/v1/devices/:id", gin.Params{{Key: "id", Value: "5"}}, "devices", "5", "devices.update"},
+		{http.MethodPost, "/api/v1/devices/batch-delete", nil, "devices", "", "devices.batch-delete"},
+		{http.MethodPut, "/api/v1/devices/:id/ping-targets/:tid", gin.Params{{Key: "id", Value: "5"}, {Key: "tid", Value: "8"}}, "devices", "5", "devices.ping-targets.update"},
+		{http.MethodDelete, "/api/v1/admin/users/:id/lockout", gin.Params{{Key: "id", Value: "2"}}, "users", "2", "users.lockout.delete"},
+		{http.MethodPost, "/api/v1/auth/login/2fa", nil, "auth", "", "auth.login.2fa"},
+		{http.MethodPut, "/api/v1/rbac/users/:id/roles", gin.Params{{Key: "id", Value: "4"}}, "users", "4", "users.roles.update"},
+	}
+	for _, tt := range tests {
+		rt := parseRoute(tt.path, tt.params)
+		assert.Equal(t, tt.resource, rt.resourceType, tt.path)
+		assert.Equal(t, tt.id, rt.resourceID, tt.path)
+		assert.Equal(t, tt.action, rt.action(tt.method), tt.path)
+	}
+}
+
+func TestRecordJob(t *testing.T) {
+	_, repo, _ := setupAuditTest(t)
+	recorder := NewRecorder(repo, 0)
+
+	recorder.RecordJob(2, "command-jobs.run", "command-jobs", "4", "succeeded=2 | failed=1", errors.New("1 device failed"))
+	entry, _ := latestEntry(t, repo)
+	assert.Equal(t, models.AuditActorSystem, entry.ActorType)
+	assert.Equal(t, uint(2), entry.OrganizationID)
+	assert.Equal(t, models.AuditStatusFailure, entry.Status)
+	assert.Equal(t, "succeeded=2 | failed=1: 1 device failed", entry.Detail)
+
+	// 未启用审计时调用是安全的
+	var disabled *Recorder
+	disabled.RecordJob(2, "command-jobs.run", "command-jobs", "4", "", nil)
+	disabled.Stop()
+}
@@ -0,0 +1,221 @@
+// Package audit 审计日志：记录所有变更操作和后台任务的操作者、资源、前后差异、来源地址和请求ID
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+)
+
+// retentionCheckInterval 检查过期审计日志的间隔
+const retentionCheckInterval = time.Hour
+
+// Loader 按资源ID加载资源快照，用于记录变更前后的差异
+type Loader func(id uint) (interface{}, error)
+
+// Entry 一条待写入的审计记录
+// Changes 为空时根据 Before 和 After 计算，Request 写入前会脱敏
+type Entry struct {
+	OrganizationID uint // 所属组织，为 0 时归属默认组织
+	ActorType      string
+	ActorID        uint
+	ActorName      string
+	APITokenID     uint
+	Action         string
+	ResourceType   string
+	ResourceID     string
+	Method         string
+	Route          string
+	StatusCode     int
+	Status         string
+	Before         interface{}
+	After          interface{}
+	Changes        map[string]Change
+	Request        interface{}
+	Detail         string
+	SourceIP       string
+	RequestID      string
+}
+
+// Recorder 审计记录器
+// 所有方法对 nil 接收者安全，未启用审计时调用方无需判断
+type Recorder struct {
+	repo          repository.AuditLogRepository
+	retentionDays int
+
+	mu      sync.RWMutex
+	loaders map[string]Loader
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewRecorder 创建审计记录器，retentionDays 为 0 时不清理过期记录
+func NewRecorder(repo repository.AuditLogRepository, retentionDays int) *Recorder {
+	return &Recorder{
+		repo:          repo,
+		retentionDays: retentionDays,
+		loaders:       make(map[string]Loader),
+	}
+}
+
+// RegisterLoader 注册资源快照加载函数，resourceType 与路由的第一段一致（如 devices）
+func (r *Recorder) RegisterLoader(resourceType string, loader Loader) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loaders[resourceType] = loader
+}
+
+// loader 获取资源快照加载函数
+func (r *Recorder) loader(resourceType string) Loader {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.loaders[resourceType]
+}
+
+// Record 写入一条审计记录，失败时只记录日志，不影响业务操作
+func (r *Recorder) Record(entry Entry) {
+	if r == nil {
+		return
+	}
+	if err := r.repo.Create(entry.model()); err != nil {
+		log.Printf("Failed to write audit log %s %s/%s: %v", entry.Action, entry.ResourceType, entry.ResourceID, err)
+	}
+}
+
+// RecordJob 记录后台任务的执行结果，记录归属任务所属组织，err 不为空时记为失败
+func (r *Recorder) RecordJob(organizationID uint, action, resourceType, resourceID, detail string, err error) {
+	if r == nil {
+		return
+	}
+	entry := Entry{
+		OrganizationID: organizationID,
+		ActorType:      models.AuditActorSystem,
+		ActorName:      "system",
+		Action:         action,
+		ResourceType:   resourceType,
+		ResourceID:     resourceID,
+		Status:         models.AuditStatusSuccess,
+		Detail:         detail,
+	}
+	if err != nil {
+		entry.Status = models.AuditStatusFailure
+		if detail != "" {
+			entry.Detail = detail + ": " + err.Error()
+		} else {
+			entry.Detail = err.Error()
+		}
+	}
+	r.Record(entry)
+}
+
+// model 转换为数据库模型
+func (e Entry) model() *models.AuditLog {
+	changes := e.Changes
+	if changes == nil {
+		changes = Diff(e.Before, e.After)
+	}
+	status := e.Status
+	if status == "" {
+		status = models.AuditStatusSuccess
+	}
+
+	entry := &models.AuditLog{
+		OrganizationID: e.OrganizationID,
+		ActorType:      e.ActorType,
+		ActorID:        e.ActorID,
+		ActorName:      e.ActorName,
+		APITokenID:     e.APITokenID,
+		Action:         e.Action,
+		ResourceType:   e.ResourceType,
+		ResourceID:     e.ResourceID,
+		Method:         e.Method,
+		Route:          e.Route,
+		StatusCode:     e.StatusCode,
+		Status:         status,
+		Detail:         e.Detail,
+		SourceIP:       e.SourceIP,
+		RequestID:      e.RequestID,
+	}
+	if len(changes) > 0 {
+		entry.Changes = encode(changes)
+	}
+	if e.Request != nil {
+		entry.Request = encode(Redact(e.Request))
+	}
+	return entry
+}
+
+// encode 序列化为 JSON 字符串
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Start 启动过期审计日志清理
+func (r *Recorder) Start(ctx context.Context) {
+	if r == nil || r.retentionDays <= 0 || r.stopCh != nil {
+		return
+	}
+	r.stopCh = make(chan struct{})
+	r.wg.Add(1)
+	go r.cleanupLoop(ctx)
+}
+
+// Stop 停止过期审计日志清理
+func (r *Recorder) Stop() {
+	if r == nil || r.stopCh == nil {
+		return
+	}
+	close(r.stopCh)
+	r.wg.Wait()
+	r.stopCh = nil
+}
+
+// cleanupLoop 定期删除超过保留期的审计日志
+func (r *Recorder) cleanupLoop(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+
+	r.cleanup()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.stopCh:
+			return
+		case <-ticker.C:
+			r.cleanup()
+		}
+	}
+}
+
+// cleanup 删除超过保留期的审计日志，清理本身也记录在审计日志中
+func (r *Recorder) cleanup() {
+	cutoff := time.Now().AddDate(0, 0, -r.retentionDays)
+	deleted, err := r.repo.DeleteBefore(cutoff)
+	if err != nil {
+		log.Printf("Failed to purge audit logs: %v", err)
+		r.RecordJob(models.DefaultOrganizationID, "audit.purge", "audit-logs", "", fmt.Sprintf("retention_days=%d", r.retentionDays), err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Purged %d audit logs older than %d days", deleted, r.retentionDays)
+		r.RecordJob(models.DefaultOrganizationID, "audit.purge", "audit-logs", "",
+			fmt.Sprintf("deleted=%d | before=%s", deleted, cutoff.Format(time.RFC3339)), nil)
+	}
+}
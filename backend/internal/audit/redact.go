@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Redacted 脱敏后的占位值
+const Redacted = "[REDACTED]"
+
+// sensitiveKeyParts 字段名包含这些片段（不区分大小写）时视为敏感字段
+var sensitiveKeyParts = []string{
+	"password", "passwd", "passphrase", "secret", "token", "community", "credential",
+	"private_key", "privatekey", "api_key", "apikey", "auth_key", "priv_key", "ssh_key",
+	"provisioning_uri", "recovery_code", "signature", "cookie", "authorization",
+}
+
+// sensitiveKeys 字段名等于这些值时视为敏感字段（一次性验证码等）
+var sensitiveKeys = map[string]bool{
+	"code": true, "otp": true, "totp": true,
+}
+
+// ignoredChangeFields 不计入字段变更的字段
+var ignoredChangeFields = map[string]bool{
+	"updated_at": true,
+}
+
+// Change 字段变更
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// isSensitiveKey 判断字段是否敏感，ID 和时间字段（如 api_token_id、token_expires_at）不视为敏感
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "_id") || strings.HasSuffix(key, "_ids") || strings.HasSuffix(key, "_at") {
+		return false
+	}
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize 将任意值转换为 JSON 通用结构（map、slice 和基本类型），无法序列化时返回 nil
+func normalize(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil
+	}
+	return normalized
+}
+
+// Redact 返回脱敏后的通用结构：任意层级的敏感字段非空时替换为 [REDACTED]
+func Redact(value interface{}) interface{} {
+	return redactValue(normalize(value))
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactField(key, item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(item)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// redactField 脱敏单个字段的值
+func redactField(key string, value interface{}) interface{} {
+	if isSensitiveKey(key) && value != nil && value != "" {
+		return Redacted
+	}
+	return redactValue(value)
+}
+
+// asObject 将脱敏后的值视为对象，非对象值放在 value 字段下
+func asObject(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case nil:
+		return map[string]interface{}{}
+	case map[string]interface{}:
+		return v
+	default:
+		return map[string]interface{}{"value": v}
+	}
+}
+
+// Diff 比较操作前后的顶层字段，返回脱敏后的字段变更
+// 比较使用原始值，敏感字段被修改时记录为 [REDACTED] -> [REDACTED]。
+// before 为空时（创建）列出 after 的全部字段，after 为空时（删除）列出 before 的全部字段
+func Diff(before, after interface{}) map[string]Change {
+	if before == nil && after == nil {
+		return nil
+	}
+	beforeFields := asObject(normalize(before))
+	afterFields := asObject(normalize(after))
+
+	changes := make(map[string]Change)
+	for key, afterValue := range afterFields {
+		if ignoredChangeFields[key] {
+			continue
+		}
+		beforeValue, ok := beforeFields[key]
+		if !ok || !reflect.DeepEqual(beforeValue, afterValue) {
+			changes[key] = Change{Before: redactField(key, beforeValue), After: redactField(key, afterValue)}
+		}
+	}
+	for key, beforeValue := range beforeFields {
+		if ignoredChangeFields[key] {
+			continue
+		}
+		if _, ok := afterFields[key]; !ok {
+			changes[key] = Change{Before: redactField(key, beforeValue)}
+		}
+	}
+	return changes
+}
+
+// secretFields 返回对象中非空敏感字段的名称，用于记录“已修改密码”这类不会出现在快照中的变更
+func secretFields(value interface{}) []string {
+	object, ok := normalize(value).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var fields []string
+	for key, item := range object {
+		if isSensitiveKey(key) && item != nil && item != "" {
+			fields = append(fields, key)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	value := map[string]interface{}{
+		"name":             "core-router",
+		"password":         "Secret@123",
+		"snmp_community":   "public",
+		"api_token_id":     7,
+		"token_expires_at": "2024-01-01T00:00:00Z",
+		"empty_secret":     "",
+		"code":             "123456",
+		"credentials": []interface{}{
+			map[string]interface{}{"username": "admin", "ssh_private_key": "-----BEGIN"},
+		},
+		"nested": map[string]interface{}{"Auth_Key": "k", "port": 22},
+	}
+
+	redacted := Redact(value).(map[string]interface{})
+	assert.Equal(t, "core-router", redacted["name"])
+	assert.Equal(t, Redacted, redacted["password"])
+	assert.Equal(t, Redacted, redacted["snmp_community"])
+	assert.Equal(t, float64(7), redacted["api_token_id"])
+	assert.Equal(t, "2024-01-01T00:00:00Z", redacted["token_expires_at"])
+	assert.Equal(t, "", redacted["empty_secret"])
+	assert.Equal(t, Redacted, redacted["code"])
+	// credentials 整体视为敏感字段
+	assert.Equal(t, Redacted, redacted["credentials"])
+	nested := redacted["nested"].(map[string]interface{})
+	assert.Equal(t, Redacted, nested["Auth_Key"])
+	assert.Equal(t, float64(22), nested["port"])
+
+	// 原值不受影响
+	assert.Equal(t, "Secret@123", value["password"])
+}
+
+func TestDiff(t *testing.T) {
+	type device struct {
+		ID        uint   `json:"id"`
+		Name      string `json:"name"`
+		Password  string `json:"password"`
+		Port      int    `json:"port"`
+		UpdatedAt string `json:"updated_at"`
+	}
+	before := device{ID: 1, Name: "r1", Password: "old", Port: 22, UpdatedAt: "t1"}
+	after := device{ID: 1, Name: "r1-core", Password: "new", Port: 22, UpdatedAt: "t2"}
+
+	// 修改：只列出变化的字段，敏感字段的值被脱敏，updated_at 不计入
+	changes := Diff(before, after)
+	assert.Equal(t, map[string]Change{
+		"name":     {Before: "r1", After: "r1-core"},
+		"password": {Before: Redacted, After: Redacted},
+	}, changes)
+	after.Password = "old"
+	assert.NotContains(t, Diff(before, after), "password")
+
+	// 创建和删除列出全部字段
+	created := Diff(nil, after)
+	assert.Equal(t, Change{After: "r1-core"}, created["name"])
+	assert.Equal(t, Change{After: Redacted}, created["password"])
+	deleted := Diff(&before, nil)
+	assert.Equal(t, Change{Before: float64(22)}, deleted["port"])
+	assert.Len(t, deleted, 4)
+
+	assert.Nil(t, Diff(nil, nil))
+	assert.Equal(t, map[string]Change{"value": {Before: "a", After: "b"}}, Diff("a", "b"))
+}
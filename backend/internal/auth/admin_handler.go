@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"errors"
+	"net"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -8,8 +11,10 @@ import (
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AdminHandler 管理员API处理器
@@ -61,6 +66,11 @@ func (h *AdminHandler) SetRepositories(
 	h.permissionRepo = permissionRepo
 }
 
+// users 返回限定到当前组织的用户仓库
+func (h *AdminHandler) users(c *gin.Context) repository.UserRepository {
+	return tenant.Bind(c.Request.Context(), h.userRepo)
+}
+
 // ========== 用户管理 ==========
 
 // CreateUserRequest 创建用户请求
@@ -102,7 +112,7 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 	}
 
 	// 从数据库获取用户列表
-	users, total, err := h.userRepo.List(page, size, search)
+	users, total, err := h.users(c).List(page, size, search)
 	if err != nil {
 		api.InternalError(c, "获取用户列表失败")
 		return
@@ -116,15 +126,16 @@ func (h *AdminHandler) ListUsers(c *gin.Context) {
 			roleNames[j] = role.Name
 		}
 		items[i] = gin.H{
-			"id":         user.ID,
-			"username":   user.Username,
-			"email":      user.Email,
-			"full_name":  user.FullName,
-			"status":     user.Status,
-			"roles":      roleNames,
-			"last_login": user.LastLogin,
-			"created_at": user.CreatedAt,
-			"updated_at": user.UpdatedAt,
+			"id":          user.ID,
+			"username":    user.Username,
+			"email":       user.Email,
+			"full_name":   user.FullName,
+			"status":      user.Status,
+			"auth_source": user.AuthSource,
+			"roles":       roleNames,
+			"last_login":  user.LastLogin,
+			"created_at":  user.CreatedAt,
+			"updated_at":  user.UpdatedAt,
 		}
 	}
 
@@ -161,7 +172,7 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 		Status:   models.UserStatusActive,
 	}
 
-	if err := h.userRepo.Create(user); err != nil {
+	if err := h.users(c).Create(user); err != nil {
 		if strings.Contains(err.Error(), "already exists") {
 			api.Conflict(c, err.Error())
 			return
@@ -172,14 +183,14 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 
 	// 分配角色
 	if len(req.RoleIDs) > 0 {
-		if err := h.userRepo.AssignRoles(user.ID, req.RoleIDs); err != nil {
+		if err := h.users(c).AssignRoles(user.ID, req.RoleIDs); err != nil {
 			// 用户已创建，角色分配失败只记录警告
 			c.Set("warning", "用户创建成功，但角色分配失败: "+err.Error())
 		}
 	}
 
 	// 重新获取用户信息（包含角色）
-	user, _ = h.userRepo.GetByID(user.ID)
+	user, _ = h.users(c).GetByID(user.ID)
 
 	api.SuccessWithMessage(c, gin.H{
 		"id":         user.ID,
@@ -205,7 +216,7 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userRepo.GetByID(uint(id))
+	user, err := h.users(c).GetByID(uint(id))
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			api.NotFound(c, "用户不存在")
@@ -221,15 +232,16 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 	}
 
 	api.Success(c, gin.H{
-		"id":         user.ID,
-		"username":   user.Username,
-		"email":      user.Email,
-		"full_name":  user.FullName,
-		"status":     user.Status,
-		"roles":      roleNames,
-		"last_login": user.LastLogin,
-		"created_at": user.CreatedAt,
-		"updated_at": user.UpdatedAt,
+		"id":          user.ID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"full_name":   user.FullName,
+		"status":      user.Status,
+		"auth_source": user.AuthSource,
+		"roles":       roleNames,
+		"last_login":  user.LastLogin,
+		"created_at":  user.CreatedAt,
+		"updated_at":  user.UpdatedAt,
 	})
 }
 
@@ -254,7 +266,7 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// 获取现有用户
-	user, err := h.userRepo.GetByID(uint(id))
+	user, err := h.users(c).GetByID(uint(id))
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			api.NotFound(c, "用户不存在")
@@ -264,6 +276,8 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	previousStatus := user.Status
+
 	// 更新字段
 	if req.Email != "" {
 		user.Email = req.Email
@@ -284,14 +298,19 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 	}
 
 	// 保存更新
-	if err := h.userRepo.Update(user); err != nil {
+	if err := h.users(c).Update(user); err != nil {
 		api.InternalError(c, "更新用户失败: "+err.Error())
 		return
 	}
 
+	// 用户被停用或禁用时撤销其所有会话
+	if previousStatus == models.UserStatusActive && user.Status != models.UserStatusActive {
+		h.authService.RevokeUserSessions(user.ID, models.SessionRevokeUserDisabled)
+	}
+
 	// 更新角色
 	if req.RoleIDs != nil {
-		if err := h.userRepo.AssignRoles(user.ID, req.RoleIDs); err != nil {
+		if err := h.users(c).AssignRoles(user.ID, req.RoleIDs); err != nil {
 			c.Set("warning", "用户更新成功，但角色分配失败: "+err.Error())
 		}
 	}
@@ -321,7 +340,7 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	}
 
 	// 检查用户是否存在
-	user, err := h.userRepo.GetByID(uint(id))
+	user, err := h.users(c).GetByID(uint(id))
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			api.NotFound(c, "用户不存在")
@@ -338,30 +357,292 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	}
 
 	// 删除用户
-	if err := h.userRepo.Delete(uint(id)); err != nil {
+	if err := h.users(c).Delete(uint(id)); err != nil {
 		api.InternalError(c, "删除用户失败: "+err.Error())
 		return
 	}
+	h.authService.RevokeUserSessions(user.ID, models.SessionRevokeUserDisabled)
 
 	api.SuccessWithMessage(c, nil, "用户删除成功")
 }
 
 
+// ========== 会话管理 ==========
+
+// ListUserSessions 获取用户的会话列表
+func (h *AdminHandler) ListUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	// 默认只返回有效会话，all=true 时包含已撤销和已过期的会话
+	activeOnly := c.Query("all") != "true"
+	sessions, err := h.authService.ListSessions(uint(id), activeOnly)
+	if err != nil {
+		api.InternalError(c, "获取会话列表失败: "+err.Error())
+		return
+	}
+
+	api.Success(c, sessions)
+}
+
+// RevokeUserSessions 撤销用户的所有会话（强制下线）
+func (h *AdminHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	count := h.authService.RevokeUserSessions(uint(id), models.SessionRevokeAdmin)
+	api.SuccessWithMessage(c, gin.H{"revoked": count}, "用户会话已全部撤销")
+}
+
+// RevokeUserSession 撤销用户的指定会话
+func (h *AdminHandler) RevokeUserSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.authService.RevokeSession(uint(id), c.Param("session_id"), models.SessionRevokeAdmin); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.NotFound(c, "会话不存在")
+			return
+		}
+		api.InternalError(c, "撤销会话失败: "+err.Error())
+		return
+	}
+
+	api.SuccessWithMessage(c, nil, "会话已撤销")
+}
+
+// ResetUserTwoFactor 重置用户的两步验证（如用户丢失认证设备）
+func (h *AdminHandler) ResetUserTwoFactor(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.authService.ResetTwoFactor(uint(id)); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			api.NotFound(c, "用户不存在")
+			return
+		}
+		api.InternalError(c, "重置两步验证失败: "+err.Error())
+		return
+	}
+
+	api.SuccessWithMessage(c, nil, "两步验证已重置")
+}
+
+// ========== 服务账号与 API 令牌 ==========
+
+// CreateServiceAccountRequest 创建服务账号请求
+type CreateServiceAccountRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	FullName string `json:"full_name" binding:"max=100"`
+	RoleIDs  []uint `json:"role_ids"`
+}
+
+// CreateServiceAccount 创建服务账号
+// 服务账号没有密码，不能交互式登录，只能使用管理员为其创建的 API 令牌
+func (h *AdminHandler) CreateServiceAccount(c *gin.Context) {
+	var req CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	if h.userRepo == nil {
+		api.InternalError(c, "用户仓库未初始化")
+		return
+	}
+
+	user := &models.User{
+		Username:   req.Username,
+		FullName:   req.FullName,
+		Status:     models.UserStatusActive,
+		AuthSource: models.UserAuthSourceService,
+	}
+	if err := h.users(c).Create(user); err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			api.Conflict(c, err.Error())
+			return
+		}
+		api.InternalError(c, "创建服务账号失败: "+err.Error())
+		return
+	}
+
+	if len(req.RoleIDs) > 0 {
+		if err := h.users(c).AssignRoles(user.ID, req.RoleIDs); err != nil {
+			c.Set("warning", "服务账号创建成功，但角色分配失败: "+err.Error())
+		}
+	}
+
+	api.SuccessWithMessage(c, gin.H{
+		"id":          user.ID,
+		"username":    user.Username,
+		"full_name":   user.FullName,
+		"status":      user.Status,
+		"auth_source": user.AuthSource,
+		"created_at":  user.CreatedAt,
+	}, "服务账号创建成功")
+}
+
+// ListUserAPITokens 获取用户的 API 令牌列表（包括已撤销和已过期的令牌）
+func (h *AdminHandler) ListUserAPITokens(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(uint(id))
+	if err != nil {
+		api.Error(c, apiTokenErrorStatus(err), "获取令牌列表失败: "+err.Error())
+		return
+	}
+
+	api.Success(c, tokens)
+}
+
+// CreateUserAPIToken 为用户（通常是服务账号）创建 API 令牌，令牌权限不能超过该用户的权限
+func (h *AdminHandler) CreateUserAPIToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.BadRequest(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	token, err := h.authService.CreateAPIToken(uint(id), c.GetUint("user_id"), &req)
+	if err != nil {
+		if strings.Contains(err.Error(), "user not found") {
+			api.NotFound(c, "用户不存在")
+			return
+		}
+		api.Error(c, apiTokenErrorStatus(err), "创建令牌失败: "+err.Error())
+		return
+	}
+
+	api.SuccessWithMessage(c, token, "令牌创建成功，请妥善保存，令牌只显示一次")
+}
+
+// RevokeUserAPIToken 撤销用户的 API 令牌
+func (h *AdminHandler) RevokeUserAPIToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+	tokenID, err := strconv.ParseUint(c.Param("token_id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的令牌ID")
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(uint(id), uint(tokenID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.NotFound(c, "令牌不存在或已撤销")
+			return
+		}
+		api.Error(c, apiTokenErrorStatus(err), "撤销令牌失败: "+err.Error())
+		return
+	}
+
+	api.SuccessWithMessage(c, nil, "令牌已撤销")
+}
+
+// ========== 登录锁定 ==========
+
+// GetUserLoginLockout 获取用户的登录失败次数和锁定状态
+func (h *AdminHandler) GetUserLoginLockout(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	lockout, err := h.authService.GetLoginLockout(uint(id))
+	if err != nil {
+		respondLoginLockoutError(c, err, "获取登录锁定状态失败")
+		return
+	}
+
+	api.Success(c, lockout)
+}
+
+// UnlockUserLogin 解除用户的登录锁定并清除失败次数
+func (h *AdminHandler) UnlockUserLogin(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		api.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	if err := h.authService.UnlockUserLogin(uint(id), c.GetUint("user_id")); err != nil {
+		respondLoginLockoutError(c, err, "解除登录锁定失败")
+		return
+	}
+
+	api.SuccessWithMessage(c, nil, "登录锁定已解除")
+}
+
+// UnlockIPLogin 解除来源地址的登录锁定并清除失败次数
+func (h *AdminHandler) UnlockIPLogin(c *gin.Context) {
+	ip := net.ParseIP(c.Param("ip"))
+	if ip == nil {
+		api.BadRequest(c, "无效的IP地址")
+		return
+	}
+
+	if err := h.authService.UnlockIPLogin(ip.String(), c.GetUint("user_id")); err != nil {
+		respondLoginLockoutError(c, err, "解除登录锁定失败")
+		return
+	}
+
+	api.SuccessWithMessage(c, nil, "登录锁定已解除")
+}
+
+// respondLoginLockoutError 登录锁定管理的错误响应
+func respondLoginLockoutError(c *gin.Context, err error, message string) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		api.NotFound(c, "用户不存在")
+	case errors.Is(err, ErrLoginProtectionDisabled):
+		api.Error(c, http.StatusNotImplemented, "未启用登录保护")
+	default:
+		api.InternalError(c, message+": "+err.Error())
+	}
+}
+
 // ========== 角色管理 ==========
 
 // CreateRoleRequest 创建角色请求
 type CreateRoleRequest struct {
-	Name          string `json:"name" binding:"required,min=2,max=50"`
-	DisplayName   string `json:"display_name" binding:"max=100"`
-	Description   string `json:"description" binding:"max=255"`
-	PermissionIDs []uint `json:"permission_ids"`
+	Name             string `json:"name" binding:"required,min=2,max=50"`
+	DisplayName      string `json:"display_name" binding:"max=100"`
+	Description      string `json:"description" binding:"max=255"`
+	PermissionIDs    []uint `json:"permission_ids"`
+	RequireTwoFactor bool   `json:"require_two_factor"`
 }
 
 // UpdateRoleRequest 更新角色请求
 type UpdateRoleRequest struct {
-	DisplayName   string `json:"display_name" binding:"max=100"`
-	Description   string `json:"description" binding:"max=255"`
-	PermissionIDs []uint `json:"permission_ids"`
+	DisplayName      string `json:"display_name" binding:"max=100"`
+	Description      string `json:"description" binding:"max=255"`
+	PermissionIDs    []uint `json:"permission_ids"`
+	RequireTwoFactor *bool  `json:"require_two_factor"`
 }
 
 // ListRoles 获取角色列表
@@ -396,22 +677,23 @@ func (h *AdminHandler) ListRoles(c *gin.Context) {
 	for i, role := range roles {
 		// 获取使用该角色的用户数量
 		userCount, _ := h.roleRepo.GetUserCount(role.ID)
-		
+
 		permissionIDs := make([]uint, len(role.Permissions))
 		for j, perm := range role.Permissions {
 			permissionIDs[j] = perm.ID
 		}
-		
+
 		items[i] = gin.H{
-			"id":             role.ID,
-			"name":           role.Name,
-			"display_name":   role.DisplayName,
-			"description":    role.Description,
-			"is_system":      role.IsSystem,
-			"user_count":     userCount,
-			"permission_ids": permissionIDs,
-			"created_at":     role.CreatedAt,
-			"updated_at":     role.UpdatedAt,
+			"id":                 role.ID,
+			"name":               role.Name,
+			"display_name":       role.DisplayName,
+			"description":        role.Description,
+			"is_system":          role.IsSystem,
+			"user_count":         userCount,
+			"permission_ids":     permissionIDs,
+			"require_two_factor": role.RequireTwoFactor,
+			"created_at":         role.CreatedAt,
+			"updated_at":         role.UpdatedAt,
 		}
 	}
 
@@ -433,10 +715,11 @@ func (h *AdminHandler) CreateRole(c *gin.Context) {
 
 	// 创建角色
 	role := &models.Role{
-		Name:        req.Name,
-		DisplayName: req.DisplayName,
-		Description: req.Description,
-		IsSystem:    false,
+		Name:             req.Name,
+		DisplayName:      req.DisplayName,
+		Description:      req.Description,
+		IsSystem:         false,
+		RequireTwoFactor: req.RequireTwoFactor,
 	}
 
 	if err := h.roleRepo.Create(role); err != nil {
@@ -456,11 +739,12 @@ func (h *AdminHandler) CreateRole(c *gin.Context) {
 	}
 
 	api.SuccessWithMessage(c, gin.H{
-		"id":           role.ID,
-		"name":         role.Name,
-		"display_name": role.DisplayName,
-		"description":  role.Description,
-		"created_at":   role.CreatedAt,
+		"id":                 role.ID,
+		"name":               role.Name,
+		"display_name":       role.DisplayName,
+		"description":        role.Description,
+		"require_two_factor": role.RequireTwoFactor,
+		"created_at":         role.CreatedAt,
 	}, "角色创建成功")
 }
 
@@ -495,16 +779,17 @@ func (h *AdminHandler) GetRole(c *gin.Context) {
 	}
 
 	api.Success(c, gin.H{
-		"id":             role.ID,
-		"name":           role.Name,
-		"display_name":   role.DisplayName,
-		"description":    role.Description,
-		"is_system":      role.IsSystem,
-		"user_count":     userCount,
-		"permission_ids": permissionIDs,
-		"permissions":    role.Permissions,
-		"created_at":     role.CreatedAt,
-		"updated_at":     role.UpdatedAt,
+		"id":                 role.ID,
+		"name":               role.Name,
+		"display_name":       role.DisplayName,
+		"description":        role.Description,
+		"is_system":          role.IsSystem,
+		"user_count":         userCount,
+		"permission_ids":     permissionIDs,
+		"permissions":        role.Permissions,
+		"require_two_factor": role.RequireTwoFactor,
+		"created_at":         role.CreatedAt,
+		"updated_at":         role.UpdatedAt,
 	})
 }
 
@@ -551,6 +836,9 @@ func (h *AdminHandler) UpdateRole(c *gin.Context) {
 	if req.Description != "" {
 		role.Description = req.Description
 	}
+	if req.RequireTwoFactor != nil {
+		role.RequireTwoFactor = *req.RequireTwoFactor
+	}
 
 	// 保存更新
 	if err := h.roleRepo.Update(role); err != nil {
@@ -566,11 +854,12 @@ func (h *AdminHandler) UpdateRole(c *gin.Context) {
 	}
 
 	api.SuccessWithMessage(c, gin.H{
-		"id":           role.ID,
-		"name":         role.Name,
-		"display_name": role.DisplayName,
-		"description":  role.Description,
-		"updated_at":   role.UpdatedAt,
+		"id":                 role.ID,
+		"name":               role.Name,
+		"display_name":       role.DisplayName,
+		"description":        role.Description,
+		"require_two_factor": role.RequireTwoFactor,
+		"updated_at":         role.UpdatedAt,
 	}, "角色更新成功")
 }
 
@@ -710,20 +999,38 @@ func (h *AdminHandler) GetPermission(c *gin.Context) {
 func (h *AdminHandler) RegisterRoutes(router *gin.RouterGroup, authService *AuthService) {
 	admin := router.Group("/admin")
 	admin.Use(AuthMiddleware(authService))
+	organizationUser := RequireOrganizationUser(h.userRepo, "id")
+	// 管理其他用户的令牌、会话等需要 global 或 group 范围的用户管理权限
+	manageUsers := RequireManagePermission(h.rbacService, "user", "update")
 	{
-		// 用户管理
+		// 用户管理（限定在当前组织内）
 		admin.GET("/users", h.ListUsers)
 		admin.POST("/users", h.CreateUser)
 		admin.GET("/users/:id", h.GetUser)
 		admin.PUT("/users/:id", h.UpdateUser)
 		admin.DELETE("/users/:id", h.DeleteUser)
+		admin.GET("/users/:id/sessions", manageUsers, organizationUser, h.ListUserSessions)
+		admin.DELETE("/users/:id/sessions", manageUsers, organizationUser, h.RevokeUserSessions)
+		admin.DELETE("/users/:id/sessions/:session_id", manageUsers, organizationUser, h.RevokeUserSession)
+		admin.DELETE("/users/:id/2fa", manageUsers, organizationUser, h.ResetUserTwoFactor)
+		admin.GET("/users/:id/tokens", manageUsers, organizationUser, h.ListUserAPITokens)
+		admin.POST("/users/:id/tokens", manageUsers, organizationUser, h.CreateUserAPIToken)
+		admin.DELETE("/users/:id/tokens/:token_id", manageUsers, organizationUser, h.RevokeUserAPIToken)
+		admin.GET("/users/:id/lockout", manageUsers, organizationUser, h.GetUserLoginLockout)
+		admin.DELETE("/users/:id/lockout", manageUsers, organizationUser, h.UnlockUserLogin)
+
+		// 来源地址登录锁定（全局）
+		admin.DELETE("/login-lockouts/:ip", RequireGlobalAdmin(), h.UnlockIPLogin)
+
+		// 服务账号管理
+		admin.POST("/service-accounts", manageUsers, h.CreateServiceAccount)
 		
-		// 角色管理
+		// 角色管理（角色为全部组织共用，只有全局超级管理员可以修改）
 		admin.GET("/roles", h.ListRoles)
-		admin.POST("/roles", h.CreateRole)
+		admin.POST("/roles", RequireGlobalAdmin(), h.CreateRole)
 		admin.GET("/roles/:id", h.GetRole)
-		admin.PUT("/roles/:id", h.UpdateRole)
-		admin.DELETE("/roles/:id", h.DeleteRole)
+		admin.PUT("/roles/:id", RequireGlobalAdmin(), h.UpdateRole)
+		admin.DELETE("/roles/:id", RequireGlobalAdmin(), h.DeleteRole)
 		
 		// 权限管理
 		admin.GET("/permissions", h.ListPermissions)
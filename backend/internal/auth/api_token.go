@@ -0,0 +1,488 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// API 令牌相关常量
+const (
+	// APITokenPrefix API 令牌明文前缀，用于区分 API 令牌和登录签发的 JWT
+	APITokenPrefix = "nmp_"
+	// apiTokenPrefixLength 列表中展示的令牌明文长度（含前缀）
+	apiTokenPrefixLength = len(APITokenPrefix) + 6
+	// apiTokenTouchInterval 最近使用时间的最小更新间隔，避免每次请求都写数据库
+	apiTokenTouchInterval = time.Minute
+	// apiTokenPathPrefix API 令牌可访问的接口路径前缀
+	apiTokenPathPrefix = "/api/v1/"
+)
+
+// API 令牌错误
+var (
+	ErrInvalidAPIToken           = errors.New("invalid or expired API token")
+	ErrAPITokenNoPermissions     = errors.New("API token requires at least one permission")
+	ErrAPITokenInvalidPermission = errors.New("invalid API token permission, expected resource:action")
+	ErrAPITokenPermission        = errors.New("API token permission exceeds user permissions")
+	ErrAPITokenExpiry            = errors.New("API token expiry must be in the future")
+	ErrAPITokenServiceDisabled   = errors.New("API tokens are not configured")
+)
+
+// PermissionChecker 用户权限检查接口（由 RBACService 实现）
+type PermissionChecker interface {
+	CheckPermission(userID uint, resource, action string) (bool, error)
+}
+
+// apiTokenRoute API 令牌可访问的接口分组
+type apiTokenRoute struct {
+	resource string // 对应的权限资源
+	readOnly bool   // 查询接口，POST 查询也按 read 处理
+}
+
+// apiTokenRoutes 按 /api/v1/ 之后的第一段路径匹配
+// 未列出的接口（认证、令牌管理、用户和系统管理等）不接受 API 令牌
+var apiTokenRoutes = map[string]apiTokenRoute{
+	"devices":       {resource: "device"},
+	"device-groups": {resource: "device"},
+	"tags":          {resource: "device"},
+	"interfaces":    {resource: "device"},
+	"command-jobs":  {resource: "device"},
+	"query":         {resource: "monitoring", readOnly: true},
+	"metrics":       {resource: "monitoring", readOnly: true},
+}
+
+// CreateAPITokenRequest 创建 API 令牌请求
+type CreateAPITokenRequest struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Permissions []string   `json:"permissions" binding:"required,min=1"` // resource:action，如 device:read
+	DeviceIDs   []uint     `json:"device_ids"`                           // 限定的设备，与分组均为空时不限定
+	GroupIDs    []uint     `json:"group_ids"`                            // 限定的设备分组
+	ExpiresAt   *time.Time `json:"expires_at"`                           // 为空时永不过期
+}
+
+// APITokenInfo API 令牌信息（不含令牌明文）
+type APITokenInfo struct {
+	ID          uint       `json:"id"`
+	UserID      uint       `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Permissions []string   `json:"permissions"`
+	DeviceIDs   []uint     `json:"device_ids"`
+	GroupIDs    []uint     `json:"group_ids"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	Active      bool       `json:"active"`
+	CreatedBy   uint       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreatedAPIToken 新创建的 API 令牌，令牌明文只在创建时返回一次
+type CreatedAPIToken struct {
+	*APITokenInfo
+	Token string `json:"token"`
+}
+
+// APITokenScope 请求所用 API 令牌的授权范围
+type APITokenScope struct {
+	TokenID     uint
+	Permissions map[string]bool
+	Devices     map[uint]bool // 令牌限定的设备（含限定分组下的设备），为 nil 时不限定
+}
+
+// Allows 令牌是否包含指定权限
+func (s *APITokenScope) Allows(resource, action string) bool {
+	return s.Permissions[resource+":"+action]
+}
+
+// AllowsDevice 令牌是否可以访问指定设备
+func (s *APITokenScope) AllowsDevice(deviceID uint) bool {
+	return s.Devices == nil || s.Devices[deviceID]
+}
+
+// DeviceList 令牌限定的设备ID列表（按 ID 排序）
+func (s *APITokenScope) DeviceList() []uint {
+	ids := make([]uint, 0, len(s.Devices))
+	for id := range s.Devices {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// APITokenService API 令牌服务
+// 令牌权限在创建时必须是所属用户权限的子集，使用时再次检查用户当前是否仍拥有该权限
+type APITokenService struct {
+	repo       repository.APITokenRepository
+	userRepo   repository.UserRepository
+	deviceRepo repository.DeviceRepository
+	checker    PermissionChecker
+}
+
+// NewAPITokenService 创建 API 令牌服务
+func NewAPITokenService(
+	repo repository.APITokenRepository,
+	userRepo repository.UserRepository,
+	deviceRepo repository.DeviceRepository,
+	checker PermissionChecker,
+) *APITokenService {
+	return &APITokenService{
+		repo:       repo,
+		userRepo:   userRepo,
+		deviceRepo: deviceRepo,
+		checker:    checker,
+	}
+}
+
+// Create 为用户创建 API 令牌，createdBy 为操作人
+// 令牌权限必须同时是所属用户和操作人权限的子集，操作人不能借他人的令牌获得自己没有的权限
+func (s *APITokenService) Create(userID, createdBy uint, req *CreateAPITokenRequest) (*CreatedAPIToken, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, errors.New("user account is not active")
+	}
+
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, ErrAPITokenExpiry
+	}
+
+	subjects := []uint{user.ID}
+	if createdBy != user.ID {
+		subjects = append(subjects, createdBy)
+	}
+	permissions, err := s.normalizePermissions(subjects, req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := APITokenPrefix + secret
+
+	permissionsJSON, _ := json.Marshal(permissions)
+	deviceIDs, _ := json.Marshal(uniqueIDs(req.DeviceIDs))
+	groupIDs, _ := json.Marshal(uniqueIDs(req.GroupIDs))
+	token := &models.APIToken{
+		UserID:      user.ID,
+		Name:        req.Name,
+		TokenHash:   hashAPIToken(plaintext),
+		TokenPrefix: plaintext[:apiTokenPrefixLength],
+		Permissions: string(permissionsJSON),
+		DeviceIDs:   string(deviceIDs),
+		GroupIDs:    string(groupIDs),
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   createdBy,
+	}
+	if err := s.repo.Create(token); err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	return &CreatedAPIToken{APITokenInfo: apiTokenInfo(token, now), Token: plaintext}, nil
+}
+
+// normalizePermissions 校验权限格式并确认每个用户都拥有这些权限，返回去重排序后的权限列表
+func (s *APITokenService) normalizePermissions(userIDs []uint, permissions []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		resource, action, ok := strings.Cut(permission, ":")
+		if !ok || resource == "" || action == "" {
+			return nil, fmt.Errorf("%w: %q", ErrAPITokenInvalidPermission, permission)
+		}
+		if seen[permission] {
+			continue
+		}
+
+		for _, userID := range userIDs {
+			allowed, err := s.checker.CheckPermission(userID, resource, action)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check permission %s: %w", permission, err)
+			}
+			if !allowed {
+				return nil, fmt.Errorf("%w: %s", ErrAPITokenPermission, permission)
+			}
+		}
+		seen[permission] = true
+		result = append(result, permission)
+	}
+	if len(result) == 0 {
+		return nil, ErrAPITokenNoPermissions
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// List 获取用户的令牌列表
+func (s *APITokenService) List(userID uint) ([]*APITokenInfo, error) {
+	tokens, err := s.repo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]*APITokenInfo, len(tokens))
+	for i, token := range tokens {
+		result[i] = apiTokenInfo(token, now)
+	}
+	return result, nil
+}
+
+// Revoke 撤销用户的令牌，令牌不属于该用户时返回 gorm.ErrRecordNotFound
+func (s *APITokenService) Revoke(userID, tokenID uint) error {
+	token, err := s.repo.GetByID(tokenID)
+	if err != nil {
+		return err
+	}
+	if token.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	return s.repo.Revoke(token.ID, time.Now())
+}
+
+// Authenticate 校验令牌明文，返回令牌所属用户和授权范围
+func (s *APITokenService) Authenticate(plaintext, ipAddress string) (*models.User, *APITokenScope, error) {
+	if !strings.HasPrefix(plaintext, APITokenPrefix) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	token, err := s.repo.GetByHash(hashAPIToken(plaintext))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+
+	now := time.Now()
+	if !token.IsActive(now) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	// 所属用户被删除或停用后令牌立即失效
+	user, err := s.userRepo.GetByID(token.UserID)
+	if err != nil || user.Status != models.UserStatusActive {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	scope, err := s.scope(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.repo.TouchLastUsed(token.ID, ipAddress, now); err != nil {
+			log.Printf("Failed to record API token usage: %v", err)
+		}
+	}
+	return user, scope, nil
+}
+
+// Authorize 检查令牌和所属用户是否都拥有指定权限
+func (s *APITokenService) Authorize(user *models.User, scope *APITokenScope, resource, action string) (bool, error) {
+	if !scope.Allows(resource, action) {
+		return false, nil
+	}
+	return s.checker.CheckPermission(user.ID, resource, action)
+}
+
+// scope 解析令牌的授权范围，限定的分组展开为分组下的设备
+func (s *APITokenService) scope(token *models.APIToken) (*APITokenScope, error) {
+	var permissions []string
+	var deviceIDs, groupIDs []uint
+	if err := json.Unmarshal([]byte(token.Permissions), &permissions); err != nil {
+		return nil, fmt.Errorf("invalid permissions of API token %d: %w", token.ID, err)
+	}
+	if token.DeviceIDs != "" {
+		if err := json.Unmarshal([]byte(token.DeviceIDs), &deviceIDs); err != nil {
+			return nil, fmt.Errorf("invalid devices of API token %d: %w", token.ID, err)
+		}
+	}
+	if token.GroupIDs != "" {
+		if err := json.Unmarshal([]byte(token.GroupIDs), &groupIDs); err != nil {
+			return nil, fmt.Errorf("invalid groups of API token %d: %w", token.ID, err)
+		}
+	}
+
+	scope := &APITokenScope{
+		TokenID:     token.ID,
+		Permissions: make(map[string]bool, len(permissions)),
+	}
+	for _, permission := range permissions {
+		scope.Permissions[permission] = true
+	}
+
+	if len(deviceIDs) == 0 && len(groupIDs) == 0 {
+		return scope, nil
+	}
+	scope.Devices = make(map[uint]bool)
+	for _, id := range deviceIDs {
+		scope.Devices[id] = true
+	}
+	for _, groupID := range groupIDs {
+		devices, err := s.deviceRepo.GetByGroupID(groupID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get devices of group %d: %w", groupID, err)
+		}
+		for _, device := range devices {
+			scope.Devices[device.ID] = true
+		}
+	}
+	return scope, nil
+}
+
+// apiTokenInfo 转换为令牌信息
+func apiTokenInfo(token *models.APIToken, now time.Time) *APITokenInfo {
+	info := &APITokenInfo{
+		ID:          token.ID,
+		UserID:      token.UserID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Permissions: []string{},
+		DeviceIDs:   []uint{},
+		GroupIDs:    []uint{},
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		LastUsedIP:  token.LastUsedIP,
+		RevokedAt:   token.RevokedAt,
+		Active:      token.IsActive(now),
+		CreatedBy:   token.CreatedBy,
+		CreatedAt:   token.CreatedAt,
+	}
+	json.Unmarshal([]byte(token.Permissions), &info.Permissions)
+	json.Unmarshal([]byte(token.DeviceIDs), &info.DeviceIDs)
+	json.Unmarshal([]byte(token.GroupIDs), &info.GroupIDs)
+	return info
+}
+
+// apiTokenPermission 根据请求方法和路由计算访问接口所需的权限，以及路由中标识设备的参数名（没有时为空）
+// ok 为 false 表示该接口不接受 API 令牌
+func apiTokenPermission(method, fullPath string) (resource, action, deviceParam string, ok bool) {
+	if !strings.HasPrefix(fullPath, apiTokenPathPrefix) {
+		return "", "", "", false
+	}
+	segments := strings.Split(strings.TrimPrefix(fullPath, apiTokenPathPrefix), "/")
+	route, ok := apiTokenRoutes[segments[0]]
+	if !ok {
+		return "", "", "", false
+	}
+
+	switch {
+	case method == http.MethodGet || method == http.MethodHead || route.readOnly:
+		action = "read"
+	case method == http.MethodPost && len(segments) == 1:
+		action = "create"
+	case method == http.MethodDelete && len(segments) == 2:
+		action = "delete"
+	default:
+		// 对已有资源的其他写操作（如 POST /devices/:id/tags）视为更新
+		action = "update"
+	}
+
+	param := "device_id"
+	if segments[0] == "devices" {
+		param = "id"
+	}
+	for _, segment := range segments[1:] {
+		if segment == ":"+param {
+			deviceParam = param
+			break
+		}
+	}
+	return route.resource, action, deviceParam, true
+}
+
+// hashAPIToken 计算 API 令牌的 SHA-256 哈希，数据库中不保存令牌明文
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// uniqueIDs 去重并排序
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// SetAPITokenService 设置 API 令牌服务
+func (s *AuthService) SetAPITokenService(apiTokens *APITokenService) {
+	s.apiTokens = apiTokens
+}
+
+// CreateAPIToken 为用户创建 API 令牌
+func (s *AuthService) CreateAPIToken(userID, createdBy uint, req *CreateAPITokenRequest) (*CreatedAPIToken, error) {
+	if s.apiTokens == nil {
+		return nil, ErrAPITokenServiceDisabled
+	}
+	token, err := s.apiTokens.Create(userID, createdBy, req)
+	if err != nil {
+		return nil, err
+	}
+	s.logLoginEvent(userID, "", "api_token_created", fmt.Sprintf("token %d (%s) by user %d", token.ID, token.Name, createdBy))
+	return token, nil
+}
+
+// ListAPITokens 获取用户的 API 令牌列表
+func (s *AuthService) ListAPITokens(userID uint) ([]*APITokenInfo, error) {
+	if s.apiTokens == nil {
+		return nil, ErrAPITokenServiceDisabled
+	}
+	return s.apiTokens.List(userID)
+}
+
+// RevokeAPIToken 撤销用户的 API 令牌
+func (s *AuthService) RevokeAPIToken(userID, tokenID uint) error {
+	if s.apiTokens == nil {
+		return ErrAPITokenServiceDisabled
+	}
+	if err := s.apiTokens.Revoke(userID, tokenID); err != nil {
+		return err
+	}
+	s.logLoginEvent(userID, "", "api_token_revoked", fmt.Sprintf("token %d", tokenID))
+	return nil
+}
+
+// AuthenticateAPIToken 校验 API 令牌，返回令牌所属用户和授权范围
+func (s *AuthService) AuthenticateAPIToken(token, ipAddress string) (*models.User, *APITokenScope, error) {
+	if s.apiTokens == nil {
+		return nil, nil, ErrInvalidAPIToken
+	}
+	return s.apiTokens.Authenticate(token, ipAddress)
+}
+
+// AuthorizeAPIToken 检查 API 令牌和所属用户是否都拥有指定权限
+func (s *AuthService) AuthorizeAPIToken(user *models.User, scope *APITokenScope, resource, action string) (bool, error) {
+	if s.apiTokens == nil {
+		return false, ErrAPITokenServiceDisabled
+	}
+	return s.apiTokens.Authorize(user, scope, resource, action)
+}
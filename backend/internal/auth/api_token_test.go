@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakePermissionChecker 按角色名授予权限的权限检查器
+type fakePermissionChecker struct {
+	userRepo    repository.UserRepository
+	permissions map[string][]string // 角色名 -> resource:action
+}
+
+func (f *fakePermissionChecker) CheckPermission(userID uint, resource, action string) (bool, error) {
+	user, err := f.userRepo.GetByID(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range user.Roles {
+		for _, permission := range f.permissions[role.Name] {
+			if permission == resource+":"+action {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func setupAPITokenTest(t *testing.T) (*AuthService, *fakePermissionChecker, *gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+	db := setupExternalAuthDB(t)
+	require.NoError(t, db.AutoMigrate(&models.APIToken{}, &models.Device{}, &models.Interface{}, &models.Tag{},
+		&models.DeviceGroup{}, &models.DeviceTag{}, &models.DeviceGroupMember{}))
+
+	// 设备 1、2、3，分组 1 包含设备 2
+	for _, name := range []string{"r1", "r2", "r3"} {
+		require.NoError(t, db.Create(&models.Device{Name: name, Type: models.DeviceTypeRouter, Host: name}).Error)
+	}
+	require.NoError(t, db.Create(&models.DeviceGroup{Name: "core"}).Error)
+	require.NoError(t, db.Create(&models.DeviceGroupMember{DeviceID: 2, DeviceGroupID: 1}).Error)
+
+	// 操作员 alice
+	var operator models.Role
+	require.NoError(t, db.Where("name = ?", "operator").First(&operator).Error)
+	userRepo := repository.NewUserRepository(db)
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Status: models.UserStatusActive}
+	require.NoError(t, userRepo.Create(alice))
+	require.NoError(t, userRepo.AssignRoles(alice.ID, []uint{operator.ID}))
+
+	checker := &fakePermissionChecker{userRepo: userRepo, permissions: map[string][]string{
+		"admin":    {"device:create", "device:read", "device:update", "device:delete"},
+		"operator": {"device:read", "device:update", "monitoring:read"},
+	}}
+	service := NewAuthService(userRepo, "api-token-test-secret-key-with-32-chars", time.Hour)
+	service.SetLogger(nil)
+	service.SetAPITokenService(NewAPITokenService(repository.NewAPITokenRepository(db), userRepo,
+		repository.NewDeviceRepository(db), checker))
+
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(AuthMiddleware(service))
+	respond := func(c *gin.Context) {
+		scope, _ := c.Get("device_scope")
+		c.JSON(http.StatusOK, gin.H{"user_id": c.GetUint("user_id"), "device_scope": scope})
+	}
+	api.GET("/devices", respond)
+	api.GET("/devices/:id", respond)
+	api.PUT("/devices/:id", respond)
+	api.GET("/query/latest/:device_id/:metric", respond)
+	api.GET("/admin/users", respond)
+	return service, checker, router, db
+}
+
+func TestAPIToken_ScopeEnforcement(t *testing.T) {
+	service, checker, router, db := setupAPITokenTest(t)
+	alice, err := repository.NewUserRepository(db).GetByUsername("alice")
+	require.NoError(t, err)
+
+	call := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 令牌权限不能超过用户权限
+	_, err = service.CreateAPIToken(alice.ID, alice.ID, &CreateAPITokenRequest{Name: "x", Permissions: []string{"device:delete"}})
+	assert.ErrorIs(t, err, ErrAPITokenPermission)
+	_, err = service.CreateAPIToken(alice.ID, alice.ID, &CreateAPITokenRequest{Name: "x", Permissions: []string{"device"}})
+	assert.ErrorIs(t, err, ErrAPITokenInvalidPermission)
+	past := time.Now().Add(-time.Hour)
+	_, err = service.CreateAPIToken(alice.ID, alice.ID, &CreateAPITokenRequest{Name: "x", Permissions: []string{"device:read"}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrAPITokenExpiry)
+
+	readToken, err := service.CreateAPIToken(alice.ID, alice.ID, &CreateAPITokenRequest{
+		Name: "ci", Permissions: []string{"device:read", "device:read"},
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^nmp_`, readToken.Token)
+	assert.Equal(t, []string{"device:read"}, readToken.Permissions)
+
+	// 数据库只保存哈希
+	var stored models.APIToken
+	require.NoError(t, db.First(&stored, readToken.ID).Error)
+	assert.NotContains(t, stored.TokenHash, readToken.Token)
+	assert.Equal(t, hashAPIToken(readToken.Token), stored.TokenHash)
+
+	// 只读令牌：可以读取，不能更新；不允许访问令牌未覆盖的接口
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/devices", readToken.Token).Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/devices/3", readToken.Token).Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPut, "/api/v1/devices/3", readToken.Token).Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/v1/admin/users", readToken.Token).Code)
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/devices", readToken.Token+"x").Code)
+
+	// 记录最近使用时间和地址
+	require.NoError(t, db.First(&stored, readToken.ID).Error)
+	require.NotNil(t, stored.LastUsedAt)
+	assert.NotEmpty(t, stored.LastUsedIP)
+
+	// 限定设备和分组的令牌
+	scopedToken, err := service.CreateAPIToken(alice.ID, alice.ID, &CreateAPITokenRequest{
+		Name: "scoped", Permissions: []string{"device:read", "device:update", "monitoring:read"}, DeviceIDs: []uint{1}, GroupIDs: []uint{1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/devices/1", scopedToken.Token).Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodPut, "/api/v1/devices/2", scopedToken.Token).Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/v1/devices/3", scopedToken.Token).Code)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/v1/query/latest/2/cpu", scopedToken.Token).Code)
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/api/v1/query/latest/3/cpu", scopedToken.Token).Code)
+
+	w := call(http.MethodGet, "/api/v1/devices", scopedToken.Token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		UserID      uint   `json:"user_id"`
+		DeviceScope []uint `json:"device_scope"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, alice.ID, body.UserID)
+	assert.Equal(t, []uint{1, 2}, body.DeviceScope)
+
+	// 用户失去权限后令牌中的对应权限立即失效
+	checker.permissions["operator"] = []string{"device:read", "monitoring:read"}
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPut, "/api/v1/devices/2", scopedToken.Token).Code)
+
+	// 撤销、过期和用户停用后令牌失效
+	require.NoError(t, service.RevokeAPIToken(alice.ID, scopedToken.ID))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/devices/1", scopedToken.Token).Code)
+	assert.ErrorIs(t, service.RevokeAPIToken(alice.ID+1, readToken.ID), gorm.ErrRecordNotFound)
+
+	require.NoError(t, db.Model(&models.APIToken{}).Where("id = ?", readToken.ID).Update("expires_at", past).Error)
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/api/v1/devices", readToken.Token).Code)
+
+	tokens, err := service.ListAPITokens(alice.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	for _, token := range tokens {
+		assert.False(t, token.Active)
+	}
+}
+
+func TestAPIToken_ServiceAccount(t *testing.T) {
+	service, _, router, db := setupAPITokenTest(t)
+
+	var admin models.Role
+	require.NoError(t, db.Where("name = ?", "admin").First(&admin).Error)
+	userRepo := repository.NewUserRepository(db)
+	hashed, err := NewPasswordManager().HashPassword("Robot@Pass1")
+	require.NoError(t, err)
+	robot := &models.User{Username: "ci-bot", Password: hashed, Status: models.UserStatusActive, AuthSource: models.UserAuthSourceService}
+	require.NoError(t, userRepo.Create(robot))
+	require.NoError(t, userRepo.AssignRoles(robot.ID, []uint{admin.ID}))
+	require.NoError(t, userRepo.AssignRoles(1, []uint{admin.ID}))
+
+	// 服务账号不能交互式登录，即使设置了密码
+	_, err = service.Login(&LoginRequest{Username: "ci-bot", Password: "Robot@Pass1"})
+	assert.EqualError(t, err, "invalid username or password")
+
+	// 操作人不能为他人创建超出自己权限的令牌
+	alice, err := userRepo.GetByUsername("alice")
+	require.NoError(t, err)
+	_, err = service.CreateAPIToken(robot.ID, alice.ID, &CreateAPITokenRequest{Name: "escalate", Permissions: []string{"device:delete"}})
+	assert.ErrorIs(t, err, ErrAPITokenPermission)
+
+	// 管理员为服务账号创建的令牌可以访问接口
+	token, err := service.CreateAPIToken(robot.ID, 1, &CreateAPITokenRequest{Name: "deploy", Permissions: []string{"device:update"}})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), token.CreatedBy)
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/devices/1", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// 服务账号停用后令牌失效
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", robot.ID).Update("status", models.UserStatusInactive).Error)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPITokenPermission(t *testing.T) {
+	tests := []struct {
+		method, path                  string
+		resource, action, deviceParam string
+		ok                            bool
+	}{
+		{"GET", "/api/v1/devices", "device", "read", "", true},
+		{"POST", "/api/v1/devices", "device", "create", "", true},
+		{"PUT", "/api/v1/devices/:id", "device", "update", "id", true},
+		{"DELETE", "/api/v1/devices/:id", "device", "delete", "id", true},
+		{"DELETE", "/api/v1/devices/:id/tags/:tag_id", "device", "update", "id", true},
+		{"POST", "/api/v1/devices/:id/interfaces/sync", "device", "update", "id", true},
+		{"GET", "/api/v1/device-groups/:id", "device", "read", "", true},
+		{"POST", "/api/v1/query/historical", "monitoring", "read", "", true},
+		{"GET", "/api/v1/metrics/bandwidth/:device_id", "monitoring", "read", "device_id", true},
+		{"POST", "/api/v1/auth/tokens", "", "", "", false},
+		{"GET", "/api/v1/admin/users", "", "", "", false},
+		{"GET", "/health", "", "", "", false},
+	}
+	for _, tt := range tests {
+		resource, action, deviceParam, ok := apiTokenPermission(tt.method, tt.path)
+		assert.Equal(t, tt.ok, ok, tt.path)
+		assert.Equal(t, tt.resource, resource, tt.path)
+		assert.Equal(t, tt.action, action, tt.method+" "+tt.path)
+		assert.Equal(t, tt.deviceParam, deviceParam, tt.path)
+	}
+}
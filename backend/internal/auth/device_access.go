@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"nmp-platform/internal/models"
+)
+
+// deviceAccessCacheTTL 用户有效设备权限的缓存时间
+// 授权变更、设备增删和组织变更时立即失效，分组成员、标签和用户角色的变更最迟在缓存过期后生效
+const deviceAccessCacheTTL = 30 * time.Second
+
+// DeviceAccess 用户对设备的有效权限
+type DeviceAccess struct {
+	superAdmin bool                     // 超级管理员可对全部设备执行任意操作
+	all        map[string]bool          // 可对全部设备执行的操作
+	devices    map[string]map[uint]bool // 各操作可执行的设备
+	allGroups  bool                     // 被授权全部设备，视为属于全部设备分组
+	groups     map[uint]bool            // 授权涉及的设备分组（含下级分组）
+	orgDevices map[uint]bool            // 用户所属组织的设备，权限不超出本组织；超级管理员为 nil
+}
+
+// cachedDeviceAccess 缓存的有效设备权限
+type cachedDeviceAccess struct {
+	access    *DeviceAccess
+	expiresAt time.Time
+}
+
+func newDeviceAccess() *DeviceAccess {
+	return &DeviceAccess{
+		all:     make(map[string]bool),
+		devices: make(map[string]map[uint]bool),
+		groups:  make(map[uint]bool),
+	}
+}
+
+// grant 授予设备上的操作
+func (a *DeviceAccess) grant(action string, deviceIDs []uint) {
+	devices := a.devices[action]
+	if devices == nil {
+		devices = make(map[uint]bool, len(deviceIDs))
+		a.devices[action] = devices
+	}
+	for _, id := range deviceIDs {
+		devices[id] = true
+	}
+}
+
+// IsSuperAdmin 是否为超级管理员
+func (a *DeviceAccess) IsSuperAdmin() bool {
+	return a.superAdmin
+}
+
+// restrict 将权限限定在组织的设备内
+func (a *DeviceAccess) restrict(deviceIDs []uint) {
+	a.orgDevices = make(map[uint]bool, len(deviceIDs))
+	for _, id := range deviceIDs {
+		a.orgDevices[id] = true
+	}
+	for _, devices := range a.devices {
+		for id := range devices {
+			if !a.orgDevices[id] {
+				delete(devices, id)
+			}
+		}
+	}
+}
+
+// Allows 是否可对设备执行操作
+func (a *DeviceAccess) Allows(deviceID uint, action string) bool {
+	if a.orgDevices != nil && !a.orgDevices[deviceID] {
+		return false
+	}
+	return a.AllDevices(action) || a.devices[action][deviceID]
+}
+
+// AllDevices 是否可对全部设备执行操作，非超级管理员的全部设备指本组织的全部设备
+func (a *DeviceAccess) AllDevices(action string) bool {
+	return a.superAdmin || a.all[action]
+}
+
+// DeviceIDs 可执行操作的设备ID列表（按 ID 排序），超级管理员返回 nil 表示不限定；
+// 其他用户可对全部设备执行时返回本组织的全部设备，保证列表查询不会越出本组织
+func (a *DeviceAccess) DeviceIDs(action string) []uint {
+	if a.superAdmin {
+		return nil
+	}
+	devices := a.devices[action]
+	if a.all[action] {
+		devices = a.orgDevices
+	}
+	ids := make([]uint, 0, len(devices))
+	for id := range devices {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// GroupIDs 用户所属的设备分组ID列表（按 ID 排序），属于全部分组时 allGroups 为 true
+func (a *DeviceAccess) GroupIDs() (allGroups bool, groupIDs []uint) {
+	if a.superAdmin || a.allGroups {
+		return true, nil
+	}
+	groupIDs = make([]uint, 0, len(a.groups))
+	for id := range a.groups {
+		groupIDs = append(groupIDs, id)
+	}
+	sort.Slice(groupIDs, func(i, j int) bool { return groupIDs[i] < groupIDs[j] })
+	return false, groupIDs
+}
+
+// DeviceAccess 获取用户的有效设备权限，结果缓存 deviceAccessCacheTTL
+func (c *DevicePermissionChecker) DeviceAccess(userID uint) (*DeviceAccess, error) {
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.cache[userID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.access, nil
+	}
+
+	access, err := c.resolveDeviceAccess(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[userID] = cachedDeviceAccess{access: access, expiresAt: now.Add(deviceAccessCacheTTL)}
+	c.mu.Unlock()
+	return access, nil
+}
+
+// EffectiveDevices 用户可执行操作的设备：可对全部设备执行时 allDevices 为 true，
+// 设备ID列表为 nil 表示不限定（超级管理员），否则为可执行操作的本组织设备
+func (c *DevicePermissionChecker) EffectiveDevices(userID uint, action string) (bool, []uint, error) {
+	access, err := c.DeviceAccess(userID)
+	if err != nil {
+		return false, nil, err
+	}
+	return access.AllDevices(action), access.DeviceIDs(action), nil
+}
+
+// UserDeviceGroups 用户所属的设备分组，即授权给用户本人或其角色的分组（含下级分组）
+// 超级管理员或被授权全部设备时 allGroups 为 true
+func (c *DevicePermissionChecker) UserDeviceGroups(userID uint) (bool, []uint, error) {
+	access, err := c.DeviceAccess(userID)
+	if err != nil {
+		return false, nil, err
+	}
+	allGroups, groupIDs := access.GroupIDs()
+	return allGroups, groupIDs, nil
+}
+
+// OrganizationDevices 组织内全部设备的ID（按 ID 排序），全局超级管理员切换组织时用于限定设备范围
+func (c *DevicePermissionChecker) OrganizationDevices(organizationID uint) ([]uint, error) {
+	deviceIDs, err := c.grantRepo.OrganizationDevices(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization devices: %w", err)
+	}
+	if deviceIDs == nil {
+		deviceIDs = []uint{}
+	}
+	return deviceIDs, nil
+}
+
+// InvalidateDeviceAccess 清除全部用户的有效设备权限缓存，授权变更后调用
+func (c *DevicePermissionChecker) InvalidateDeviceAccess() {
+	c.mu.Lock()
+	c.cache = make(map[uint]cachedDeviceAccess)
+	c.mu.Unlock()
+}
+
+// resolveDeviceAccess 汇总授权给用户本人和其角色的设备授权
+// 分组授权包含全部下级分组中的设备，逐台设备授权（user_device_permissions）视为允许全部操作；
+// 默认组织的管理员是超级管理员，其他组织的管理员可对本组织全部设备执行任意操作，
+// 除超级管理员外所有用户的权限都限定在所属组织的设备内
+func (c *DevicePermissionChecker) resolveDeviceAccess(userID uint) (*DeviceAccess, error) {
+	user, err := c.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := newDeviceAccess()
+	organizationID := userOrganization(user)
+	roleIDs := make([]uint, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		if role.Name == "admin" {
+			if organizationID == models.DefaultOrganizationID {
+				access.superAdmin = true
+				return access, nil
+			}
+			access.allGroups = true
+			for _, action := range models.DeviceActions {
+				access.all[action] = true
+			}
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	grants, err := c.grantRepo.ListForSubject(userID, roleIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device grants: %w", err)
+	}
+
+	// 收集授权涉及的分组（含下级分组）和标签，批量查询成员
+	var groupIDs, tagIDs []uint
+	subtrees := make(map[uint][]uint)
+	var children map[uint][]uint
+	for _, grant := range grants {
+		switch {
+		case grant.DeviceGroupID != nil:
+			if _, ok := subtrees[*grant.DeviceGroupID]; ok {
+				continue
+			}
+			if children == nil {
+				if children, err = c.grantRepo.GroupChildren(); err != nil {
+					return nil, fmt.Errorf("failed to get device group tree: %w", err)
+				}
+			}
+			subtree := groupSubtree(*grant.DeviceGroupID, children)
+			subtrees[*grant.DeviceGroupID] = subtree
+			groupIDs = append(groupIDs, subtree...)
+		case grant.TagID != nil:
+			tagIDs = append(tagIDs, *grant.TagID)
+		}
+	}
+	groupMembers, err := c.grantRepo.GroupMembers(groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device group members: %w", err)
+	}
+	tagMembers, err := c.grantRepo.TagMembers(tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tagged devices: %w", err)
+	}
+
+	for _, grant := range grants {
+		var deviceIDs []uint
+		allDevices := false
+		switch {
+		case grant.DeviceGroupID != nil:
+			for _, groupID := range subtrees[*grant.DeviceGroupID] {
+				deviceIDs = append(deviceIDs, groupMembers[groupID]...)
+				access.groups[groupID] = true
+			}
+		case grant.TagID != nil:
+			deviceIDs = tagMembers[*grant.TagID]
+		case grant.DeviceID != nil:
+			deviceIDs = []uint{*grant.DeviceID}
+		default:
+			allDevices = true
+			access.allGroups = true
+		}
+
+		for _, action := range models.DeviceActions {
+			if !grant.Allows(action) {
+				continue
+			}
+			if allDevices {
+				access.all[action] = true
+			} else {
+				access.grant(action, deviceIDs)
+			}
+		}
+	}
+
+	devices, err := c.permRepo.GetUserDevices(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user devices: %w", err)
+	}
+	if len(devices) > 0 {
+		deviceIDs := make([]uint, len(devices))
+		for i, device := range devices {
+			deviceIDs[i] = device.ID
+		}
+		for _, action := range models.DeviceActions {
+			access.grant(action, deviceIDs)
+		}
+	}
+
+	orgDevices, err := c.grantRepo.OrganizationDevices(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization devices: %w", err)
+	}
+	access.restrict(orgDevices)
+
+	return access, nil
+}
+
+// groupSubtree 分组及其全部下级分组的ID
+func groupSubtree(groupID uint, children map[uint][]uint) []uint {
+	visited := map[uint]bool{groupID: true}
+	subtree := []uint{groupID}
+	for i := 0; i < len(subtree); i++ {
+		for _, child := range children[subtree[i]] {
+			if !visited[child] {
+				visited[child] = true
+				subtree = append(subtree, child)
+			}
+		}
+	}
+	return subtree
+}
+
+// intersectDeviceIDs 两个有序设备ID列表的交集
+func intersectDeviceIDs(a, b []uint) []uint {
+	result := make([]uint, 0)
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			result = append(result, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return result
+}
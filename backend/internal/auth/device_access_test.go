@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupDeviceAccessTest 创建设备授权测试环境
+// 设备 1-5；分组 core(1) 下有 core-east(2)，设备 1 属于 core，设备 2 属于 core-east，设备 3 属于 edge(3)；
+// 标签 pop(1) 标记设备 4。用户 alice 为操作员，bob 为查看者，root 为管理员
+func setupDeviceAccessTest(t *testing.T) (*DevicePermissionChecker, *gorm.DB, map[string]uint) {
+	db := setupExternalAuthDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Device{}, &models.Interface{}, &models.Tag{}, &models.DeviceGroup{},
+		&models.DeviceTag{}, &models.DeviceGroupMember{}, &models.UserDevicePermission{}, &models.DeviceGrant{}))
+
+	for _, name := range []string{"r1", "r2", "r3", "r4", "r5"} {
+		require.NoError(t, db.Create(&models.Device{Name: name, Type: models.DeviceTypeRouter, Host: name}).Error)
+	}
+	core := &models.DeviceGroup{Name: "core"}
+	require.NoError(t, db.Create(core).Error)
+	require.NoError(t, db.Create(&models.DeviceGroup{Name: "core-east", ParentID: &core.ID}).Error)
+	require.NoError(t, db.Create(&models.DeviceGroup{Name: "edge"}).Error)
+	require.NoError(t, db.Create(&[]models.DeviceGroupMember{
+		{DeviceID: 1, DeviceGroupID: 1}, {DeviceID: 2, DeviceGroupID: 2}, {DeviceID: 3, DeviceGroupID: 3},
+	}).Error)
+	require.NoError(t, db.Create(&models.Tag{Name: "pop"}).Error)
+	require.NoError(t, db.Create(&models.DeviceTag{DeviceID: 4, TagID: 1}).Error)
+
+	roles := map[string]uint{}
+	for _, name := range []string{"admin", "operator", "viewer"} {
+		var role models.Role
+		require.NoError(t, db.Where("name = ?", name).First(&role).Error)
+		roles[name] = role.ID
+	}
+	userRepo := repository.NewUserRepository(db)
+	users := map[string]uint{}
+	for username, role := range map[string]string{"alice": "operator", "bob": "viewer", "root": "admin"} {
+		user := &models.User{Username: username, Email: username + "@example.com", Status: models.UserStatusActive}
+		require.NoError(t, userRepo.Create(user))
+		require.NoError(t, userRepo.AssignRoles(user.ID, []uint{roles[role]}))
+		users[username] = user.ID
+	}
+
+	// 操作员角色可查看和修改 core 分组（含下级分组），alice 本人可向 pop 标签的设备下发，
+	// 查看者角色可查看全部设备，bob 另有设备 5 的逐台授权
+	alice, operator, viewer, groupID, tagID := users["alice"], roles["operator"], roles["viewer"], core.ID, uint(1)
+	require.NoError(t, db.Create(&[]models.DeviceGrant{
+		{RoleID: &operator, DeviceGroupID: &groupID, CanRead: true, CanUpdate: true},
+		{UserID: &alice, TagID: &tagID, CanDeploy: true},
+		{RoleID: &viewer, CanRead: true},
+	}).Error)
+	require.NoError(t, db.Create(&models.UserDevicePermission{UserID: users["bob"], DeviceID: 5}).Error)
+
+	checker := NewDevicePermissionChecker(nil, repository.NewPermissionRepository(db), userRepo,
+		repository.NewDeviceGrantRepository(db))
+	return checker, db, users
+}
+
+func TestDevicePermissionChecker_Grants(t *testing.T) {
+	checker, _, users := setupDeviceAccessTest(t)
+
+	tests := []struct {
+		user     string
+		deviceID uint
+		action   string
+		want     bool
+	}{
+		// 分组授权包含下级分组中的设备
+		{"alice", 1, models.DeviceActionRead, true},
+		{"alice", 2, models.DeviceActionUpdate, true},
+		{"alice", 3, models.DeviceActionRead, false},
+		{"alice", 1, models.DeviceActionDelete, false},
+		// 标签授权只包含授予的操作
+		{"alice", 4, models.DeviceActionDeploy, true},
+		{"alice", 4, models.DeviceActionRead, false},
+		// 全部设备授权和逐台设备授权
+		{"bob", 3, models.DeviceActionRead, true},
+		{"bob", 3, models.DeviceActionUpdate, false},
+		{"bob", 5, models.DeviceActionDelete, true},
+		{"root", 3, models.DeviceActionDeploy, true},
+		{"root", 3, "create", true},
+	}
+	for _, tt := range tests {
+		allowed, err := checker.CheckDevicePermission(users[tt.user], tt.deviceID, tt.action)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, allowed, "%s %s device %d", tt.user, tt.action, tt.deviceID)
+	}
+
+	allDevices, deviceIDs, err := checker.EffectiveDevices(users["alice"], models.DeviceActionRead)
+	require.NoError(t, err)
+	assert.False(t, allDevices)
+	assert.Equal(t, []uint{1, 2}, deviceIDs)
+	// 被授权全部设备时设备列表为本组织的全部设备，超级管理员不限定
+	allDevices, deviceIDs, err = checker.EffectiveDevices(users["bob"], models.DeviceActionRead)
+	require.NoError(t, err)
+	assert.True(t, allDevices)
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, deviceIDs)
+	allDevices, deviceIDs, err = checker.EffectiveDevices(users["root"], models.DeviceActionRead)
+	require.NoError(t, err)
+	assert.True(t, allDevices)
+	assert.Nil(t, deviceIDs)
+}
+
+func TestDevicePermissionChecker_Cache(t *testing.T) {
+	checker, db, users := setupDeviceAccessTest(t)
+	alice := users["alice"]
+
+	allowed, err := checker.CheckDevicePermission(alice, 3, models.DeviceActionRead)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// 新授权在缓存失效后生效
+	groupID := uint(3)
+	require.NoError(t, db.Create(&models.DeviceGrant{UserID: &alice, DeviceGroupID: &groupID, CanRead: true}).Error)
+	allowed, err = checker.CheckDevicePermission(alice, 3, models.DeviceActionRead)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	checker.InvalidateDeviceAccess()
+	allowed, err = checker.CheckDevicePermission(alice, 3, models.DeviceActionRead)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestDeviceListFilterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	checker, db, users := setupDeviceAccessTest(t)
+
+	// acme(2) 组织的设备 6 和管理员 carol
+	require.NoError(t, db.Create(&models.Device{Name: "r6", Type: models.DeviceTypeRouter, Host: "r6", OrganizationID: 2}).Error)
+	var adminRole models.Role
+	require.NoError(t, db.Where("name = ?", "admin").First(&adminRole).Error)
+	userRepo := repository.NewUserRepository(db)
+	carol := &models.User{Username: "carol", Email: "carol@example.com", Status: models.UserStatusActive, OrganizationID: 2}
+	require.NoError(t, userRepo.Create(carol))
+	require.NoError(t, userRepo.AssignRoles(carol.ID, []uint{adminRole.ID}))
+
+	organization := models.DefaultOrganizationID
+	serve := func(userID uint, tokenScope []uint) (map[string]interface{}, bool) {
+		router := gin.New()
+		router.GET("/devices", func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Set("organization_id", organization)
+			if tokenScope != nil {
+				c.Set("device_scope", tokenScope)
+			}
+			c.Next()
+		}, DeviceListFilterMiddleware(checker), func(c *gin.Context) {
+			scope, exists := c.Get("device_scope")
+			c.JSON(http.StatusOK, gin.H{"scope": scope, "filter": c.GetBool("filter_by_permission"), "exists": exists})
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/devices", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return body, body["exists"].(bool)
+	}
+
+	// 只能查看部分设备时设置可查看的设备范围
+	body, exists := serve(users["alice"], nil)
+	assert.True(t, exists)
+	assert.Equal(t, true, body["filter"])
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, body["scope"])
+
+	// 与 API 令牌限定的设备范围取交集
+	body, _ = serve(users["alice"], []uint{2, 3})
+	assert.Equal(t, []interface{}{float64(2)}, body["scope"])
+	body, _ = serve(users["alice"], []uint{3})
+	assert.Equal(t, []interface{}{}, body["scope"])
+
+	// 被授权全部设备时限定为本组织的全部设备
+	body, exists = serve(users["bob"], nil)
+	assert.True(t, exists)
+	assert.Equal(t, true, body["filter"])
+	assert.Equal(t, []interface{}{float64(1), float64(2), float64(3), float64(4), float64(5)}, body["scope"])
+
+	// 全局超级管理员不限定
+	body, exists = serve(users["root"], nil)
+	assert.False(t, exists)
+	assert.Equal(t, false, body["filter"])
+
+	// 其他组织的管理员只能看到本组织的设备
+	organization = 2
+	body, exists = serve(carol.ID, nil)
+	assert.True(t, exists)
+	assert.Equal(t, []interface{}{float64(6)}, body["scope"])
+
+	// 全局超级管理员切换组织后只看到该组织的设备
+	body, exists = serve(users["root"], nil)
+	assert.True(t, exists)
+	assert.Equal(t, []interface{}{float64(6)}, body["scope"])
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"nmp-platform/internal/audit"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+)
+
+// externalIdentity 外部身份源（LDAP、OIDC）校验通过的用户信息
+type externalIdentity struct {
+	Source     string // 认证来源，models.UserAuthSource*
+	ExternalID string // 外部身份标识，如 LDAP DN 或 OIDC issuer|sub
+	Username   string
+	Email      string
+	FullName   string
+}
+
+// provisionExternalUser 按外部身份创建或同步本地用户，并将角色替换为映射结果
+// existing 为按用户名找到的本地用户，不存在时为 nil；本地账号与外部账号同名时拒绝登录
+func (s *AuthService) provisionExternalUser(identity *externalIdentity, existing *models.User, roles []*models.Role, autoCreate bool) (*models.User, error) {
+	user := existing
+	var before *models.User
+	if user != nil && user.AuthSource != identity.Source {
+		s.logLoginEvent(user.ID, user.Username, "login_failed", identity.Source+": username belongs to another account source")
+		return nil, ErrInvalidCredentials
+	}
+
+	if user == nil {
+		if !autoCreate {
+			s.logLoginEvent(0, identity.Username, "login_failed", identity.Source+": user not provisioned")
+			return nil, ErrInvalidCredentials
+		}
+		user = &models.User{
+			Username:   identity.Username,
+			Email:      identity.Email,
+			FullName:   identity.FullName,
+			Status:     models.UserStatusActive,
+			AuthSource: identity.Source,
+			ExternalID: identity.ExternalID,
+		}
+		if err := s.userRepo.Create(user); err != nil {
+			s.logLoginEvent(0, identity.Username, "login_failed", identity.Source+": failed to create user: "+err.Error())
+			return nil, fmt.Errorf("failed to provision %s user", identity.Source)
+		}
+		s.logLoginEvent(user.ID, user.Username, identity.Source+"_user_created", identity.ExternalID)
+	} else {
+		// 本地停用的外部用户不允许登录
+		if user.Status != models.UserStatusActive {
+			s.logLoginEvent(user.ID, user.Username, "login_failed", "account not active")
+			return nil, errors.New("user account is not active")
+		}
+		snapshot := *user
+		before = &snapshot
+		if syncExternalAttributes(user, identity) {
+			user.Roles = nil
+			if err := s.userRepo.Update(user); err != nil {
+				s.logLoginEvent(user.ID, user.Username, identity.Source+"_sync_failed", err.Error())
+			}
+		}
+	}
+
+	roleIDs := make([]uint, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+	}
+	if err := s.userRepo.AssignRoles(user.ID, roleIDs); err != nil {
+		s.logLoginEvent(user.ID, user.Username, "login_failed", identity.Source+": failed to assign roles: "+err.Error())
+		return nil, fmt.Errorf("failed to sync %s roles", identity.Source)
+	}
+
+	// 重新加载用户以获取同步后的角色和权限
+	user, err := s.userRepo.GetByID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	s.auditExternalUser(identity, before, user)
+	return user, nil
+}
+
+// auditExternalUser 在审计日志中记录外部用户的自动创建，以及属性和角色的同步变化
+func (s *AuthService) auditExternalUser(identity *externalIdentity, before, after *models.User) {
+	if s.audit == nil {
+		return
+	}
+	action := "auth." + identity.Source + "_user_created"
+	if before != nil {
+		action = "auth." + identity.Source + "_user_synced"
+	}
+	changes := audit.Diff(before, after)
+	// 每次登录都会更新最后登录时间等字段，只有实际变化时记录同步
+	delete(changes, "last_login")
+	if before != nil && len(changes) == 0 {
+		return
+	}
+	s.audit.Record(audit.Entry{
+		OrganizationID: after.OrganizationID,
+		ActorType:      models.AuditActorSystem,
+		ActorName:      "system",
+		Action:         action,
+		ResourceType:   "users",
+		ResourceID:     fmt.Sprint(after.ID),
+		Changes:        changes,
+		Detail:         identity.ExternalID,
+	})
+}
+
+// syncExternalAttributes 将外部身份的邮箱、姓名和标识同步到本地用户，返回是否有变化
+func syncExternalAttributes(user *models.User, identity *externalIdentity) bool {
+	changed := false
+	if identity.Email != "" && user.Email != identity.Email {
+		user.Email = identity.Email
+		changed = true
+	}
+	if identity.FullName != "" && user.FullName != identity.FullName {
+		user.FullName = identity.FullName
+		changed = true
+	}
+	if user.ExternalID != identity.ExternalID {
+		user.ExternalID = identity.ExternalID
+		changed = true
+	}
+	return changed
+}
+
+// rolesByName 按名称查询角色映射结果
+func rolesByName(roleRepo repository.RoleRepository, names []string) ([]*models.Role, error) {
+	roles := make([]*models.Role, 0, len(names))
+	for _, name := range names {
+		role, err := roleRepo.GetByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role %s: %w", name, err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
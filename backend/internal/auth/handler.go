@@ -1,9 +1,24 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 认证 Cookie 名称
+const (
+	authTokenCookie    = "auth_token"
+	refreshTokenCookie = "refresh_token"
+	oidcStateCookie    = "oidc_state"
 )
 
 // AuthHandler 认证处理器
@@ -29,27 +44,20 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 	response, err := h.authService.Login(&req)
 	if err != nil {
+		if respondLoginThrottled(c, err) {
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	// 设置 HttpOnly Cookie 存储 JWT Token
-	// 生产环境应设置 Secure: true
-	isSecure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
-	c.SetSameSite(http.SameSiteStrictMode)
-	c.SetCookie(
-		"auth_token",           // Cookie 名称
-		response.Token,         // Token 值
-		60*60*24*7,             // 过期时间：7天（秒）
-		"/",                    // 路径
-		"",                     // 域名（空表示当前域名）
-		isSecure,               // Secure（仅 HTTPS）
-		true,                   // HttpOnly（禁止 JS 访问）
-	)
+	setAuthCookies(c, response)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -83,33 +91,40 @@ func (h *AuthHandler) Register(c *gin.Context) {
 }
 
 // RefreshToken 刷新令牌处理
+// 刷新令牌优先从 HttpOnly Cookie 获取，其次从请求体 refresh_token 获取；每次刷新返回新的刷新令牌，旧令牌立即失效
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	tokenString := c.GetHeader("Authorization")
-	if tokenString == "" {
+	refreshToken, _ := c.Cookie(refreshTokenCookie)
+	if refreshToken == "" {
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		refreshToken = req.RefreshToken
+	}
+	if refreshToken == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Authorization header is required",
+			"error": "Refresh token is required",
 		})
 		return
 	}
 
-	// 移除 "Bearer " 前缀
-	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-		tokenString = tokenString[7:]
-	}
-
-	newToken, err := h.authService.RefreshToken(tokenString)
+	response, err := h.authService.RefreshToken(refreshToken, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
+		// 并发刷新时旧令牌已被轮换，保留 Cookie 以便使用已下发的新令牌
+		if !errors.Is(err, ErrRefreshTokenReused) {
+			clearAuthCookies(c)
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	setAuthCookies(c, response)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"token": newToken,
-		},
+		"data":    response,
 	})
 }
 
@@ -229,19 +244,17 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 }
 
 // Logout 登出处理
+// 撤销当前会话并清除 Cookie，已签发的访问令牌和刷新令牌立即失效
 func (h *AuthHandler) Logout(c *gin.Context) {
-	// 清除 HttpOnly Cookie
-	isSecure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
-	c.SetSameSite(http.SameSiteStrictMode)
-	c.SetCookie(
-		"auth_token",
-		"",
-		-1,        // 立即过期
-		"/",
-		"",
-		isSecure,
-		true,
-	)
+	refreshToken, _ := c.Cookie(refreshTokenCookie)
+	if err := h.authService.Logout(requestToken(c), refreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	clearAuthCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -249,6 +262,584 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
+// LogoutAll 登出当前用户的所有会话
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	count := h.authService.RevokeUserSessions(userID.(uint), models.SessionRevokeLogoutAll)
+	clearAuthCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "All sessions logged out",
+		"data": gin.H{
+			"revoked": count,
+		},
+	})
+}
+
+// SessionInfo 会话信息
+type SessionInfo struct {
+	*models.UserSession
+	Current bool `json:"current"`
+}
+
+// ListSessions 获取当前用户的有效会话
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userID.(uint), true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	currentID := c.GetString("session_id")
+	result := make([]SessionInfo, len(sessions))
+	for i, session := range sessions {
+		result[i] = SessionInfo{UserSession: session, Current: session.SessionID == currentID}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// RevokeSession 撤销当前用户的指定会话（如在其他设备上登出）
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	if err := h.authService.RevokeSession(userID.(uint), sessionID, models.SessionRevokeLogout); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Session not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if sessionID == c.GetString("session_id") {
+		clearAuthCookies(c)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session revoked",
+	})
+}
+
+// LoginTwoFactor 登录第二步：提交 TOTP 验证码或恢复码
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	response, err := h.authService.LoginTwoFactor(&req)
+	if err != nil {
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		c.JSON(twoFactorErrorStatus(err, http.StatusUnauthorized), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	setAuthCookies(c, response)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+	})
+}
+
+// LoginTwoFactorSetup 登录时按角色策略开始绑定两步验证，返回密钥和二维码 URI
+func (h *AuthHandler) LoginTwoFactorSetup(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	setup, err := h.authService.LoginTwoFactorSetup(req.ChallengeToken)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err, http.StatusUnauthorized), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    setup,
+	})
+}
+
+// LoginTwoFactorEnable 登录时提交首个验证码完成绑定并登录，响应包含恢复码
+func (h *AuthHandler) LoginTwoFactorEnable(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+	response, err := h.authService.LoginTwoFactorEnable(&req)
+	if err != nil {
+		if respondLoginThrottled(c, err) {
+			return
+		}
+		c.JSON(twoFactorErrorStatus(err, http.StatusUnauthorized), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	setAuthCookies(c, response)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    response,
+	})
+}
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GetTwoFactorStatus 获取当前用户的两步验证状态
+func (h *AuthHandler) GetTwoFactorStatus(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	status, err := h.authService.GetTwoFactorStatus(userID.(uint))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err, http.StatusBadRequest), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// SetupTwoFactor 开始绑定两步验证，返回密钥和二维码 URI
+func (h *AuthHandler) SetupTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	setup, err := h.authService.BeginTwoFactorSetup(userID.(uint))
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err, http.StatusBadRequest), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    setup,
+	})
+}
+
+// EnableTwoFactor 提交首个验证码启用两步验证，返回恢复码（只显示一次）
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	h.handleTwoFactorCodes(c, h.authService.EnableTwoFactor)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，原有恢复码失效
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	h.handleTwoFactorCodes(c, h.authService.RegenerateRecoveryCodes)
+}
+
+// handleTwoFactorCodes 校验验证码后返回新生成的恢复码
+func (h *AuthHandler) handleTwoFactorCodes(c *gin.Context, action func(userID uint, code string) ([]string, error)) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	codes, err := action(userID.(uint), req.Code)
+	if err != nil {
+		c.JSON(twoFactorErrorStatus(err, http.StatusBadRequest), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor 关闭两步验证，需要提交验证码或恢复码
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(userID.(uint), req.Code); err != nil {
+		c.JSON(twoFactorErrorStatus(err, http.StatusBadRequest), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// ListOIDCProviders 获取已配置的 OIDC 身份提供者，供登录页显示单点登录入口
+func (h *AuthHandler) ListOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.authService.OIDCProviders(),
+	})
+}
+
+// OIDCLogin 发起 OIDC 登录，状态令牌写入 Cookie 后跳转到身份提供者
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	authURL, stateToken, err := h.authService.BeginOIDCLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		if errors.Is(err, ErrOIDCProviderNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		h.oidcFailure(c, err)
+		return
+	}
+
+	// 回调是身份提供者发起的跨站跳转，状态 Cookie 需使用 Lax 才会随回调请求发送
+	isSecure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, stateToken, int(oidcStateTTL.Seconds()), oidcCookiePath(c), "", isSecure, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 处理身份提供者回调，签发令牌 Cookie 后跳转回前端登录页
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	stateToken, _ := c.Cookie(oidcStateCookie)
+	isSecure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath(c), "", isSecure, true)
+
+	if errCode := c.Query("error"); errCode != "" {
+		description := c.Query("error_description")
+		if description == "" {
+			description = errCode
+		}
+		h.oidcFailure(c, errors.New(description))
+		return
+	}
+
+	response, err := h.authService.CompleteOIDCLogin(c.Request.Context(), c.Param("provider"),
+		c.Query("code"), c.Query("state"), stateToken, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.oidcFailure(c, err)
+		return
+	}
+
+	setAuthCookies(c, response)
+	c.Redirect(http.StatusFound, h.authService.OIDCFrontendURL("/login?sso=success"))
+}
+
+// oidcFailure 单点登录失败时跳转回前端登录页并携带错误信息
+func (h *AuthHandler) oidcFailure(c *gin.Context, err error) {
+	c.Redirect(http.StatusFound, h.authService.OIDCFrontendURL("/login?sso_error="+url.QueryEscape(err.Error())))
+}
+
+// oidcCookiePath OIDC 状态 Cookie 的路径，只发送给 OIDC 路由（如 /api/v1/auth/oidc）
+func oidcCookiePath(c *gin.Context) string {
+	return strings.TrimSuffix(authCookiePath(c), "/") + "/oidc"
+}
+
+// ListAPITokens 获取当前用户的 API 令牌列表
+func (h *AuthHandler) ListAPITokens(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(userID.(uint))
+	if err != nil {
+		c.JSON(apiTokenErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// CreateAPIToken 为当前用户创建 API 令牌，令牌明文只在响应中返回一次
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	token, err := h.authService.CreateAPIToken(userID.(uint), userID.(uint), &req)
+	if err != nil {
+		c.JSON(apiTokenErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    token,
+	})
+}
+
+// RevokeAPIToken 撤销当前用户的 API 令牌
+func (h *AuthHandler) RevokeAPIToken(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid token ID",
+		})
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(userID.(uint), uint(tokenID)); err != nil {
+		c.JSON(apiTokenErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "API token revoked",
+	})
+}
+
+// apiTokenErrorStatus API 令牌错误对应的 HTTP 状态码
+func apiTokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrAPITokenNoPermissions), errors.Is(err, ErrAPITokenInvalidPermission),
+		errors.Is(err, ErrAPITokenExpiry):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrAPITokenPermission):
+		return http.StatusForbidden
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAPITokenServiceDisabled):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondLoginThrottled 登录被限制时返回 429 和 Retry-After，返回值表示是否已响应
+func respondLoginThrottled(c *gin.Context, err error) bool {
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int64((throttled.RetryAfter + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       err.Error(),
+		"retry_after": seconds,
+	})
+	return true
+}
+
+// twoFactorErrorStatus 两步验证错误对应的 HTTP 状态码，codeStatus 为验证码错误时使用的状态码
+func twoFactorErrorStatus(err error, codeStatus int) int {
+	switch {
+	case errors.Is(err, ErrInvalidTwoFactorCode):
+		return codeStatus
+	case errors.Is(err, ErrInvalidChallenge), errors.Is(err, ErrTooManyTwoFactorAttempts):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrTwoFactorRequired):
+		return http.StatusForbidden
+	case errors.Is(err, ErrTwoFactorAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, ErrTwoFactorNotSetup), errors.Is(err, ErrTwoFactorNotEnabled):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrTwoFactorServiceDisabled):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// setAuthCookies 设置访问令牌和刷新令牌 Cookie，等待两步验证的响应不设置
+// 生产环境应通过 HTTPS 访问，Cookie 才会带 Secure 标记
+func setAuthCookies(c *gin.Context, response *LoginResponse) {
+	if response.Token == "" {
+		return
+	}
+	isSecure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(
+		authTokenCookie,                               // Cookie 名称
+		response.Token,                                // Token 值
+		int(time.Until(response.ExpiresAt).Seconds()), // 与访问令牌同时过期
+		"/",                                           // 路径
+		"",                                            // 域名（空表示当前域名）
+		isSecure,                                      // Secure（仅 HTTPS）
+		true,                                          // HttpOnly（禁止 JS 访问）
+	)
+	if response.RefreshToken != "" && response.RefreshExpiresAt != nil {
+		// 刷新令牌只发送给认证接口
+		c.SetCookie(
+			refreshTokenCookie,
+			response.RefreshToken,
+			int(time.Until(*response.RefreshExpiresAt).Seconds()),
+			authCookiePath(c),
+			"",
+			isSecure,
+			true,
+		)
+	}
+}
+
+// clearAuthCookies 清除访问令牌和刷新令牌 Cookie
+func clearAuthCookies(c *gin.Context) {
+	isSecure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(authTokenCookie, "", -1, "/", "", isSecure, true)
+	c.SetCookie(refreshTokenCookie, "", -1, authCookiePath(c), "", isSecure, true)
+}
+
+// authCookiePath 刷新令牌 Cookie 的路径，即认证路由组路径（如 /api/v1/auth）
+func authCookiePath(c *gin.Context) string {
+	fullPath := c.FullPath()
+	if i := strings.Index(fullPath, "/auth/"); i >= 0 {
+		return fullPath[:i+len("/auth")]
+	}
+	return "/"
+}
+
+// requestToken 从 Cookie 或 Authorization Header 获取访问令牌
+func requestToken(c *gin.Context) string {
+	if cookie, err := c.Cookie(authTokenCookie); err == nil && cookie != "" {
+		return cookie
+	}
+	if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return authHeader[7:]
+	}
+	return ""
+}
+
 // RegisterRoutes 注册认证相关路由
 func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
 	auth := router.Group("/auth")
@@ -263,6 +854,12 @@ func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
 		auth.POST("/refresh", h.RefreshToken)
 		auth.POST("/refresh-token", h.RefreshToken) // 兼容前端路由别名
 		auth.POST("/logout", h.Logout)
+		auth.POST("/login/2fa", h.LoginTwoFactor)
+		auth.POST("/login/2fa/setup", h.LoginTwoFactorSetup)
+		auth.POST("/login/2fa/enable", h.LoginTwoFactorEnable)
+		auth.GET("/oidc/providers", h.ListOIDCProviders)
+		auth.GET("/oidc/:provider/login", h.OIDCLogin)
+		auth.GET("/oidc/:provider/callback", h.OIDCCallback)
 		
 		// 需要认证的路由
 		authenticated := auth.Group("")
@@ -273,6 +870,17 @@ func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
 			authenticated.PUT("/profile", h.UpdateProfile)
 			authenticated.POST("/change-password", h.ChangePassword)
 			authenticated.PUT("/password", h.ChangePassword)
+			authenticated.POST("/logout-all", h.LogoutAll)
+			authenticated.GET("/sessions", h.ListSessions)
+			authenticated.DELETE("/sessions/:session_id", h.RevokeSession)
+			authenticated.GET("/2fa", h.GetTwoFactorStatus)
+			authenticated.POST("/2fa/setup", h.SetupTwoFactor)
+			authenticated.POST("/2fa/enable", h.EnableTwoFactor)
+			authenticated.POST("/2fa/disable", h.DisableTwoFactor)
+			authenticated.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+			authenticated.GET("/tokens", h.ListAPITokens)
+			authenticated.POST("/tokens", h.CreateAPIToken)
+			authenticated.DELETE("/tokens/:id", h.RevokeAPIToken)
 		}
 	}
 }
\ No newline at end of file
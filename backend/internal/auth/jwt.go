@@ -9,9 +9,10 @@ import (
 
 // TokenClaims JWT令牌声明
 type TokenClaims struct {
-	UserID   uint     `json:"user_id"`
-	Username string   `json:"username"`
-	Roles    []string `json:"roles"`
+	UserID         uint     `json:"user_id"`
+	OrganizationID uint     `json:"organization_id,omitempty"` // 用户所属组织，旧令牌未携带时为默认组织
+	Username       string   `json:"username"`
+	Roles          []string `json:"roles"`
 	jwt.RegisteredClaims
 }
 
@@ -29,19 +30,21 @@ func NewJWTManager(secretKey string, tokenExpiry time.Duration) *JWTManager {
 	}
 }
 
-// GenerateToken 生成JWT令牌
-func (j *JWTManager) GenerateToken(userID uint, username string, roles []string) (string, error) {
+// GenerateToken 生成JWT访问令牌，sessionID 写入 jti 用于关联服务端会话
+func (j *JWTManager) GenerateToken(userID, organizationID uint, username string, roles []string, sessionID string) (string, error) {
 	now := time.Now()
 	claims := TokenClaims{
-		UserID:   userID,
-		Username: username,
-		Roles:    roles,
+		UserID:         userID,
+		OrganizationID: organizationID,
+		Username:       username,
+		Roles:          roles,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(j.tokenExpiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "nmp-platform",
 			Subject:   username,
+			ID:        sessionID,
 		},
 	}
 
@@ -70,19 +73,113 @@ func (j *JWTManager) ValidateToken(tokenString string) (*TokenClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// RefreshToken 刷新JWT令牌
-func (j *JWTManager) RefreshToken(tokenString string) (string, error) {
-	claims, err := j.ValidateToken(tokenString)
+// 两步验证挑战令牌用途
+const (
+	ChallengePurposeVerify = "2fa_verify" // 已启用两步验证，提交验证码完成登录
+	ChallengePurposeEnroll = "2fa_enroll" // 角色要求两步验证但尚未绑定，完成绑定后登录
+)
+
+// ChallengeClaims 两步验证挑战令牌声明
+// 密码验证通过后签发，只能用于完成两步验证，不能作为访问令牌使用
+type ChallengeClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateChallengeToken 生成两步验证挑战令牌，返回令牌和令牌ID
+func (j *JWTManager) GenerateChallengeToken(userID uint, purpose string, ttl time.Duration) (string, string, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	claims := ChallengeClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "nmp-platform",
+			ID:        tokenID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(j.challengeKey())
+	return signed, tokenID, err
+}
+
+// ValidateChallengeToken 验证两步验证挑战令牌及其用途
+func (j *JWTManager) ValidateChallengeToken(tokenString, purpose string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return j.challengeKey(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ChallengeClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose {
+		return nil, errors.New("invalid challenge token")
+	}
+	return claims, nil
+}
+
+// challengeKey 挑战令牌使用独立的签名密钥，避免与访问令牌互相冒用
+func (j *JWTManager) challengeKey() []byte {
+	return []byte(j.secretKey + ":2fa-challenge")
+}
+
+// OIDCStateClaims OIDC 登录状态令牌声明
+// 发起登录时写入 Cookie，回调时校验 state、nonce 并取回 PKCE 校验码
+type OIDCStateClaims struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// GenerateOIDCStateToken 生成 OIDC 登录状态令牌
+func (j *JWTManager) GenerateOIDCStateToken(claims OIDCStateClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    "nmp-platform",
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(j.oidcStateKey())
+}
+
+// ValidateOIDCStateToken 验证 OIDC 登录状态令牌
+func (j *JWTManager) ValidateOIDCStateToken(tokenString string) (*OIDCStateClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &OIDCStateClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return j.oidcStateKey(), nil
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// 检查令牌是否即将过期（在过期前30分钟内可以刷新）
-	// 如果剩余时间超过30分钟，不需要刷新
-	if time.Until(claims.ExpiresAt.Time) > 30*time.Minute {
-		return "", errors.New("token is not eligible for refresh yet")
+	claims, ok := token.Claims.(*OIDCStateClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid state token")
 	}
+	return claims, nil
+}
 
-	// 生成新令牌
-	return j.GenerateToken(claims.UserID, claims.Username, claims.Roles)
-}
\ No newline at end of file
+// oidcStateKey 状态令牌使用独立的签名密钥
+func (j *JWTManager) oidcStateKey() []byte {
+	return []byte(j.secretKey + ":oidc-state")
+}
@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAP 认证错误
+var (
+	ErrLDAPInvalidCredentials = errors.New("invalid LDAP credentials")
+	ErrLDAPUserNotFound       = errors.New("LDAP user not found")
+	ErrLDAPAmbiguousUser      = errors.New("LDAP user filter matched multiple entries")
+	ErrLDAPNoMappedRole       = errors.New("LDAP user is not a member of any mapped group")
+	ErrExternalPassword       = errors.New("password is managed by the directory service")
+)
+
+// defaultLDAPTimeout LDAP 连接和操作的默认超时时间
+const defaultLDAPTimeout = 10 * time.Second
+
+// LDAPConfig LDAP / Active Directory 认证配置
+type LDAPConfig struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // %s 替换为转义后的用户名
+
+	UsernameAttribute string
+	EmailAttribute    string
+	NameAttribute     string
+	GroupAttribute    string
+	GroupBaseDN       string
+	GroupFilter       string // %s 替换为转义后的用户 DN，为空时读取 GroupAttribute
+
+	AutoCreateUsers bool
+	DefaultRole     string
+	GroupMappings   []LDAPGroupMapping
+}
+
+// LDAPGroupMapping LDAP 组到角色的映射
+type LDAPGroupMapping struct {
+	Group string // 组 DN 或 CN，不区分大小写
+	Role  string // 角色名称
+}
+
+// LDAPEntry LDAP 用户条目
+type LDAPEntry struct {
+	DN       string
+	Username string
+	Email    string
+	FullName string
+	Groups   []string
+}
+
+// ldapConn LDAP 连接接口（由 *ldap.Conn 实现，测试时替换为进程内目录）
+type ldapConn interface {
+	Bind(username, password string) error
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// LDAPProvider LDAP 认证提供者
+// 使用服务账号搜索用户条目，再以用户 DN 和密码绑定校验密码
+type LDAPProvider struct {
+	config   LDAPConfig
+	roleRepo repository.RoleRepository
+	dial     func() (ldapConn, error)
+}
+
+// NewLDAPProvider 创建 LDAP 认证提供者
+func NewLDAPProvider(config LDAPConfig, roleRepo repository.RoleRepository) *LDAPProvider {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultLDAPTimeout
+	}
+	if config.UserFilter == "" {
+		config.UserFilter = "(sAMAccountName=%s)"
+	}
+	if config.UsernameAttribute == "" {
+		config.UsernameAttribute = "sAMAccountName"
+	}
+	if config.GroupBaseDN == "" {
+		config.GroupBaseDN = config.BaseDN
+	}
+
+	provider := &LDAPProvider{
+		config:   config,
+		roleRepo: roleRepo,
+	}
+	provider.dial = provider.dialDirectory
+	return provider
+}
+
+// dialDirectory 连接 LDAP 服务器
+func (p *LDAPProvider) dialDirectory() (ldapConn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.config.InsecureSkipVerify}
+	if u, err := url.Parse(p.config.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(p.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	conn.SetTimeout(p.config.Timeout)
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// AutoCreateUsers 是否在首次登录时自动创建本地用户
+func (p *LDAPProvider) AutoCreateUsers() bool {
+	return p.config.AutoCreateUsers
+}
+
+// Authenticate 校验用户名和密码，返回用户条目及所属组
+func (p *LDAPProvider) Authenticate(username, password string) (*LDAPEntry, error) {
+	// 空密码会被服务器视为匿名绑定而返回成功，必须拒绝
+	if username == "" || password == "" {
+		return nil, ErrLDAPInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.searchUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrLDAPInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind as user: %w", err)
+	}
+
+	if p.config.GroupFilter != "" {
+		// 用户本身可能没有搜索组的权限，使用服务账号重新绑定
+		if err := p.bindService(conn); err != nil {
+			return nil, err
+		}
+		groups, err := p.searchGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		entry.Groups = groups
+	}
+	return entry, nil
+}
+
+// bindService 使用服务账号绑定，未配置服务账号时匿名搜索
+func (p *LDAPProvider) bindService(conn ldapConn) error {
+	if p.config.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+		return fmt.Errorf("failed to bind LDAP service account: %w", err)
+	}
+	return nil
+}
+
+// searchUser 按用户过滤器搜索用户条目
+func (p *LDAPProvider) searchUser(conn ldapConn, username string) (*LDAPEntry, error) {
+	attributes := []string{p.config.UsernameAttribute}
+	for _, attribute := range []string{p.config.EmailAttribute, p.config.NameAttribute, p.config.GroupAttribute} {
+		if attribute != "" {
+			attributes = append(attributes, attribute)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.config.Timeout.Seconds()), false,
+		strings.ReplaceAll(p.config.UserFilter, "%s", ldap.EscapeFilter(username)),
+		attributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search LDAP user: %w", err)
+	}
+	if result == nil || len(result.Entries) == 0 {
+		return nil, ErrLDAPUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, ErrLDAPAmbiguousUser
+	}
+
+	found := result.Entries[0]
+	entry := &LDAPEntry{
+		DN:       found.DN,
+		Username: found.GetAttributeValue(p.config.UsernameAttribute),
+		Groups:   []string{},
+	}
+	if entry.Username == "" {
+		entry.Username = username
+	}
+	if p.config.EmailAttribute != "" {
+		entry.Email = found.GetAttributeValue(p.config.EmailAttribute)
+	}
+	if p.config.NameAttribute != "" {
+		entry.FullName = found.GetAttributeValue(p.config.NameAttribute)
+	}
+	if p.config.GroupFilter == "" && p.config.GroupAttribute != "" {
+		entry.Groups = found.GetAttributeValues(p.config.GroupAttribute)
+	}
+	return entry, nil
+}
+
+// searchGroups 按组过滤器搜索用户所属组
+func (p *LDAPProvider) searchGroups(conn ldapConn, userDN string) ([]string, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		0, int(p.config.Timeout.Seconds()), false,
+		strings.ReplaceAll(p.config.GroupFilter, "%s", ldap.EscapeFilter(userDN)),
+		[]string{"cn"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search LDAP groups: %w", err)
+	}
+
+	groups := make([]string, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// MapRoles 将 LDAP 组映射为本地角色
+// 组映射按组 DN 或组 CN 匹配（不区分大小写）；未匹配任何映射时使用默认角色
+func (p *LDAPProvider) MapRoles(groups []string) ([]*models.Role, error) {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, mapping := range p.config.GroupMappings {
+		if seen[mapping.Role] || !ldapGroupMatches(groups, mapping.Group) {
+			continue
+		}
+		seen[mapping.Role] = true
+		names = append(names, mapping.Role)
+	}
+	if len(names) == 0 && p.config.DefaultRole != "" {
+		names = append(names, p.config.DefaultRole)
+	}
+
+	return rolesByName(p.roleRepo, names)
+}
+
+// ldapGroupMatches 检查用户所属组中是否包含指定组（DN 或 CN）
+func ldapGroupMatches(groups []string, group string) bool {
+	for _, dn := range groups {
+		if strings.EqualFold(dn, group) || strings.EqualFold(ldapCommonName(dn), group) {
+			return true
+		}
+	}
+	return false
+}
+
+// ldapCommonName 提取 DN 第一个 RDN 的值，如 CN=NMP Admins,OU=Groups,... 返回 NMP Admins
+func ldapCommonName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+// SetLDAPProvider 设置 LDAP 认证提供者
+func (s *AuthService) SetLDAPProvider(provider *LDAPProvider) {
+	s.ldap = provider
+}
+
+// loginLDAP 通过 LDAP 校验用户，按需创建本地用户并按组映射同步角色
+// existing 为按登录名找到的 LDAP 来源用户，不存在时为 nil
+func (s *AuthService) loginLDAP(username, password string, existing *models.User) (*models.User, error) {
+	entry, err := s.ldap.Authenticate(username, password)
+	if err != nil {
+		s.logLoginEvent(0, username, "login_failed", "ldap: "+err.Error())
+		if errors.Is(err, ErrLDAPInvalidCredentials) || errors.Is(err, ErrLDAPUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, errors.New("directory service unavailable")
+	}
+
+	if existing == nil && entry.Username != username {
+		// 登录名与目录中的用户名大小写不同
+		if found, err := s.userRepo.GetByUsername(entry.Username); err == nil {
+			existing = found
+		}
+	}
+
+	roles, err := s.ldap.MapRoles(entry.Groups)
+	if err != nil {
+		s.logLoginEvent(0, entry.Username, "login_failed", "ldap: "+err.Error())
+		return nil, errors.New("failed to map LDAP groups to roles")
+	}
+	if len(roles) == 0 {
+		s.logLoginEvent(0, entry.Username, "login_failed", "ldap: "+ErrLDAPNoMappedRole.Error())
+		return nil, ErrLDAPNoMappedRole
+	}
+
+	return s.provisionExternalUser(&externalIdentity{
+		Source:     models.UserAuthSourceLDAP,
+		ExternalID: entry.DN,
+		Username:   entry.Username,
+		Email:      entry.Email,
+		FullName:   entry.FullName,
+	}, existing, roles, s.ldap.AutoCreateUsers())
+}
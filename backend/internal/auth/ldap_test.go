@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeDirectory 进程内 LDAP 目录，只支持 (attr=value) 形式的等值过滤器
+type fakeDirectory struct {
+	entries   map[string]map[string][]string // DN -> 属性
+	passwords map[string]string              // DN -> 密码
+	binds     []string
+}
+
+func (d *fakeDirectory) dial() (ldapConn, error) {
+	return &fakeConn{directory: d}, nil
+}
+
+type fakeConn struct {
+	directory *fakeDirectory
+}
+
+func (c *fakeConn) Bind(username, password string) error {
+	c.directory.binds = append(c.directory.binds, username)
+	if expected, ok := c.directory.passwords[username]; ok && expected == password {
+		return nil
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (c *fakeConn) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	filter := strings.TrimSuffix(strings.TrimPrefix(request.Filter, "("), ")")
+	parts := strings.SplitN(filter, "=", 2)
+	if len(parts) != 2 {
+		return nil, ldap.NewError(ldap.LDAPResultFilterError, errors.New("unsupported filter"))
+	}
+
+	result := &ldap.SearchResult{}
+	for dn, attributes := range c.directory.entries {
+		if !strings.HasSuffix(strings.ToLower(dn), strings.ToLower(request.BaseDN)) {
+			continue
+		}
+		for name, values := range attributes {
+			if !strings.EqualFold(name, parts[0]) {
+				continue
+			}
+			for _, value := range values {
+				if strings.EqualFold(value, parts[1]) {
+					result.Entries = append(result.Entries, ldap.NewEntry(dn, attributes))
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+const (
+	ldapAdminsDN    = "CN=NMP Admins,OU=Groups,DC=example,DC=com"
+	ldapOperatorsDN = "CN=NMP Operators,OU=Groups,DC=example,DC=com"
+	ldapServiceDN   = "CN=svc-nmp,OU=Service,DC=example,DC=com"
+	ldapAliceDN     = "CN=Alice Wang,OU=Staff,DC=example,DC=com"
+	ldapBobDN       = "CN=Bob Li,OU=Staff,DC=example,DC=com"
+)
+
+// setupExternalAuthDB 创建外部认证测试使用的内存数据库，包含 admin/operator/viewer 角色和本地 admin 账号
+func setupExternalAuthDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Role{}, &models.Permission{}, &models.UserRole{}, &models.RolePermission{}))
+	for _, name := range []string{"admin", "operator", "viewer"} {
+		require.NoError(t, db.Create(&models.Role{Name: name, DisplayName: name}).Error)
+	}
+
+	// 本地管理员账号
+	hashed, err := NewPasswordManager().HashPassword("Local@Pass1")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&models.User{Username: "admin", Password: hashed, Email: "admin@local", Status: models.UserStatusActive}).Error)
+	return db
+}
+
+func setupLDAPTest(t *testing.T, config LDAPConfig) (*AuthService, *fakeDirectory, *gorm.DB) {
+	db := setupExternalAuthDB(t)
+	directory := &fakeDirectory{
+		entries: map[string]map[string][]string{
+			ldapAliceDN: {
+				"sAMAccountName": {"alice"},
+				"mail":           {"alice@example.com"},
+				"displayName":    {"Alice Wang"},
+				"memberOf":       {ldapAdminsDN},
+			},
+			ldapBobDN: {
+				"sAMAccountName": {"bob"},
+				"mail":           {"bob@example.com"},
+			},
+			ldapAdminsDN:    {"member": {ldapAliceDN}},
+			ldapOperatorsDN: {"member": {}},
+		},
+		passwords: map[string]string{
+			ldapServiceDN: "service-secret",
+			ldapAliceDN:   "alice-secret",
+			ldapBobDN:     "bob-secret",
+		},
+	}
+
+	config.BindDN = ldapServiceDN
+	config.BindPassword = "service-secret"
+	config.BaseDN = "DC=example,DC=com"
+	config.EmailAttribute = "mail"
+	config.NameAttribute = "displayName"
+	config.GroupAttribute = "memberOf"
+	config.GroupMappings = []LDAPGroupMapping{
+		{Group: ldapAdminsDN, Role: "admin"},
+		{Group: "nmp operators", Role: "operator"},
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	provider := NewLDAPProvider(config, repository.NewRoleRepository(db))
+	provider.dial = directory.dial
+
+	service := NewAuthService(userRepo, "ldap-test-secret-key-with-32-characters", time.Hour)
+	service.SetLogger(nil)
+	service.SetLDAPProvider(provider)
+	return service, directory, db
+}
+
+func TestLDAPLogin_ProvisionAndSyncRoles(t *testing.T) {
+	service, directory, db := setupLDAPTest(t, LDAPConfig{AutoCreateUsers: true})
+
+	// 首次登录自动创建本地用户并按组映射分配角色
+	response, err := service.Login(&LoginRequest{Username: "alice", Password: "alice-secret"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.Equal(t, []string{"admin"}, response.User.Roles)
+	assert.Equal(t, "alice@example.com", response.User.Email)
+	assert.Equal(t, "Alice Wang", response.User.FullName)
+
+	var user models.User
+	require.NoError(t, db.Where("username = ?", "alice").First(&user).Error)
+	assert.Equal(t, models.UserAuthSourceLDAP, user.AuthSource)
+	assert.Equal(t, ldapAliceDN, user.ExternalID)
+
+	// 组变化后再次登录同步角色
+	directory.entries[ldapAliceDN]["memberOf"] = []string{ldapOperatorsDN}
+	directory.entries[ldapAliceDN]["mail"] = []string{"alice.wang@example.com"}
+	response, err = service.Login(&LoginRequest{Username: "alice", Password: "alice-secret"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"operator"}, response.User.Roles)
+	assert.Equal(t, "alice.wang@example.com", response.User.Email)
+
+	var count int64
+	require.NoError(t, db.Model(&models.User{}).Where("username = ?", "alice").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// LDAP 用户不能修改本地密码
+	assert.ErrorIs(t, service.ChangePassword(user.ID, "alice-secret", "New@Pass123"), ErrExternalPassword)
+
+	// 密码错误和空密码均被拒绝，空密码不发起绑定
+	_, err = service.Login(&LoginRequest{Username: "alice", Password: "wrong"})
+	assert.EqualError(t, err, "invalid username or password")
+	binds := len(directory.binds)
+	_, err = service.Login(&LoginRequest{Username: "alice", Password: ""})
+	assert.Error(t, err)
+	assert.Len(t, directory.binds, binds)
+
+	// 未匹配任何组映射且未配置默认角色时拒绝登录
+	_, err = service.Login(&LoginRequest{Username: "bob", Password: "bob-secret"})
+	assert.ErrorIs(t, err, ErrLDAPNoMappedRole)
+	assert.Error(t, db.Where("username = ?", "bob").First(&models.User{}).Error)
+
+	// 本地停用的 LDAP 用户不能登录
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("status", models.UserStatusBlocked).Error)
+	_, err = service.Login(&LoginRequest{Username: "alice", Password: "alice-secret"})
+	assert.EqualError(t, err, "user account is not active")
+}
+
+func TestLDAPLogin_LocalFallbackAndDefaultRole(t *testing.T) {
+	service, _, db := setupLDAPTest(t, LDAPConfig{DefaultRole: "viewer"})
+
+	// 未开启自动创建时，目录中存在但本地不存在的用户不能登录
+	_, err := service.Login(&LoginRequest{Username: "bob", Password: "bob-secret"})
+	assert.EqualError(t, err, "invalid username or password")
+
+	require.NoError(t, db.Create(&models.User{Username: "bob", Status: models.UserStatusActive, AuthSource: models.UserAuthSourceLDAP}).Error)
+	response, err := service.Login(&LoginRequest{Username: "bob", Password: "bob-secret"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"viewer"}, response.User.Roles)
+
+	// 目录服务不可用时本地账号仍可登录，LDAP 账号登录失败
+	service.ldap.dial = func() (ldapConn, error) {
+		return nil, errors.New("connection refused")
+	}
+	response, err = service.Login(&LoginRequest{Username: "admin", Password: "Local@Pass1"})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", response.User.Username)
+
+	_, err = service.Login(&LoginRequest{Username: "bob", Password: "bob-secret"})
+	assert.EqualError(t, err, "directory service unavailable")
+}
+
+func TestLDAPProvider_GroupFilter(t *testing.T) {
+	service, directory, _ := setupLDAPTest(t, LDAPConfig{
+		GroupBaseDN: "OU=Groups,DC=example,DC=com",
+		GroupFilter: "(member=%s)",
+	})
+	directory.entries[ldapOperatorsDN]["member"] = []string{ldapAliceDN}
+
+	entry, err := service.ldap.Authenticate("ALICE", "alice-secret")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", entry.Username)
+	assert.ElementsMatch(t, []string{ldapAdminsDN, ldapOperatorsDN}, entry.Groups)
+	// 用户绑定后使用服务账号重新绑定再搜索组
+	assert.Equal(t, []string{ldapServiceDN, ldapAliceDN, ldapServiceDN}, directory.binds)
+
+	roles, err := service.ldap.MapRoles(entry.Groups)
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	assert.Equal(t, "admin", roles[0].Name)
+	assert.Equal(t, "operator", roles[1].Name)
+}
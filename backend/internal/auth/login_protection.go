@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/audit"
+	"nmp-platform/internal/models"
+)
+
+const (
+	loginFailureKeyPrefix = "auth:login:failures:"
+	loginDelayKeyPrefix   = "auth:login:delay:"
+	loginLockKeyPrefix    = "auth:login:locked:"
+
+	// loginCacheTimeout 访问 Redis 的超时时间，超时后放行登录请求
+	loginCacheTimeout = 2 * time.Second
+)
+
+var (
+	// ErrLoginThrottled 登录失败次数过多，需要等待或已被锁定
+	// 用户名不存在时同样计数和锁定，响应不会暴露用户名是否存在
+	ErrLoginThrottled          = errors.New("too many failed login attempts, please try again later")
+	ErrLoginProtectionDisabled = errors.New("login protection is not enabled")
+)
+
+// LoginThrottledError 登录被限制的错误，携带可以重试的时间
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrLoginThrottled.Error()
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// LoginAttemptCache 登录失败计数缓存接口（由 Redis 客户端实现）
+type LoginAttemptCache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// LoginProtectionConfig 登录防暴力破解配置
+type LoginProtectionConfig struct {
+	MaxAttempts     int           // 同一用户名失败多少次后锁定
+	IPMaxAttempts   int           // 同一来源地址失败多少次后锁定
+	FreeAttempts    int           // 不延迟的失败次数，超过后每次失败延迟翻倍
+	BaseDelay       time.Duration // 首次延迟
+	MaxDelay        time.Duration // 延迟上限
+	LockoutDuration time.Duration // 锁定时长
+	Window          time.Duration // 失败计数在最后一次失败后保留的时间
+}
+
+// LoginLockout 用户名或来源地址的锁定状态
+type LoginLockout struct {
+	Subject           string `json:"subject"`
+	Locked            bool   `json:"locked"`
+	FailedAttempts    int64  `json:"failed_attempts"`
+	RetryAfterSeconds int64  `json:"retry_after_seconds"`
+}
+
+// loginLockEvent 失败计数达到阈值时产生的锁定事件
+type loginLockEvent struct {
+	Subject  string
+	Attempts int64
+	Duration time.Duration
+}
+
+// LoginProtector 登录防暴力破解
+// 按用户名和来源地址分别统计失败次数：超过免延迟次数后指数退避，达到阈值后临时锁定。
+// Redis 不可用时记录日志并放行，不影响正常登录
+type LoginProtector struct {
+	cache  LoginAttemptCache
+	config LoginProtectionConfig
+}
+
+// NewLoginProtector 创建登录防暴力破解组件
+func NewLoginProtector(cache LoginAttemptCache, config LoginProtectionConfig) *LoginProtector {
+	return &LoginProtector{cache: cache, config: config}
+}
+
+// userSubject 用户名对应的计数主体，忽略大小写和首尾空白
+func userSubject(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+// ipSubject 来源地址对应的计数主体
+func ipSubject(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// subjects 本次登录需要检查和计数的主体
+func (p *LoginProtector) subjects(username, ipAddress string) []string {
+	subjects := []string{userSubject(username)}
+	if ipAddress != "" {
+		subjects = append(subjects, ipSubject(ipAddress))
+	}
+	return subjects
+}
+
+// Check 检查是否允许登录，被锁定或仍在退避期内时返回 LoginThrottledError
+func (p *LoginProtector) Check(username, ipAddress string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), loginCacheTimeout)
+	defer cancel()
+
+	var retryAfter time.Duration
+	for _, subject := range p.subjects(username, ipAddress) {
+		for _, key := range []string{loginLockKeyPrefix + subject, loginDelayKeyPrefix + subject} {
+			ttl, err := p.cache.TTL(ctx, key)
+			if err != nil {
+				log.Printf("Failed to check login throttle for %s: %v", subject, err)
+				continue
+			}
+			if ttl > retryAfter {
+				retryAfter = ttl
+			}
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginThrottledError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，返回本次失败触发的锁定事件
+func (p *LoginProtector) RecordFailure(username, ipAddress string) []loginLockEvent {
+	ctx, cancel := context.WithTimeout(context.Background(), loginCacheTimeout)
+	defer cancel()
+
+	var events []loginLockEvent
+	if event := p.recordSubjectFailure(ctx, userSubject(username), p.config.MaxAttempts); event != nil {
+		events = append(events, *event)
+	}
+	if ipAddress != "" {
+		if event := p.recordSubjectFailure(ctx, ipSubject(ipAddress), p.config.IPMaxAttempts); event != nil {
+			events = append(events, *event)
+		}
+	}
+	return events
+}
+
+// recordSubjectFailure 增加主体的失败计数，设置退避时间或锁定
+func (p *LoginProtector) recordSubjectFailure(ctx context.Context, subject string, maxAttempts int) *loginLockEvent {
+	failureKey := loginFailureKeyPrefix + subject
+	attempts, err := p.cache.Incr(ctx, failureKey)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v", subject, err)
+		return nil
+	}
+	if err := p.cache.Expire(ctx, failureKey, p.config.Window); err != nil {
+		log.Printf("Failed to set login failure window for %s: %v", subject, err)
+	}
+
+	// 达到阈值：锁定并清零计数，锁定到期后重新计数
+	if maxAttempts > 0 && attempts >= int64(maxAttempts) {
+		if err := p.cache.Set(ctx, loginLockKeyPrefix+subject, attempts, p.config.LockoutDuration); err != nil {
+			log.Printf("Failed to lock %s: %v", subject, err)
+			return nil
+		}
+		if err := p.cache.Delete(ctx, failureKey, loginDelayKeyPrefix+subject); err != nil {
+			log.Printf("Failed to clear login failures for %s: %v", subject, err)
+		}
+		return &loginLockEvent{Subject: subject, Attempts: attempts, Duration: p.config.LockoutDuration}
+	}
+
+	if delay := p.delay(attempts); delay > 0 {
+		if err := p.cache.Set(ctx, loginDelayKeyPrefix+subject, attempts, delay); err != nil {
+			log.Printf("Failed to set login delay for %s: %v", subject, err)
+		}
+	}
+	return nil
+}
+
+// delay 第 attempts 次失败后的退避时间
+func (p *LoginProtector) delay(attempts int64) time.Duration {
+	exceeded := attempts - int64(p.config.FreeAttempts)
+	if exceeded <= 0 || p.config.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.config.BaseDelay
+	for i := int64(1); i < exceeded && (p.config.MaxDelay <= 0 || delay < p.config.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.config.MaxDelay > 0 && delay > p.config.MaxDelay {
+		return p.config.MaxDelay
+	}
+	return delay
+}
+
+// Status 获取主体的锁定状态
+func (p *LoginProtector) Status(subject string) (*LoginLockout, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), loginCacheTimeout)
+	defer cancel()
+
+	status := &LoginLockout{Subject: subject}
+	retryAfter, err := p.cache.TTL(ctx, loginLockKeyPrefix+subject)
+	if err != nil {
+		return nil, err
+	}
+	status.Locked = retryAfter > 0
+	if delay, err := p.cache.TTL(ctx, loginDelayKeyPrefix+subject); err == nil && delay > retryAfter {
+		retryAfter = delay
+	}
+	if retryAfter > 0 {
+		status.RetryAfterSeconds = int64((retryAfter + time.Second - 1) / time.Second)
+	}
+	// 计数不存在时 Get 返回错误，视为没有失败记录
+	if value, err := p.cache.Get(ctx, loginFailureKeyPrefix+subject); err == nil {
+		status.FailedAttempts, _ = strconv.ParseInt(value, 10, 64)
+	}
+	return status, nil
+}
+
+// Reset 清除主体的失败计数、退避和锁定
+func (p *LoginProtector) Reset(subject string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), loginCacheTimeout)
+	defer cancel()
+	return p.cache.Delete(ctx, loginFailureKeyPrefix+subject, loginDelayKeyPrefix+subject, loginLockKeyPrefix+subject)
+}
+
+// ========== AuthService 集成 ==========
+
+// SetLoginProtector 设置登录防暴力破解组件，未设置时只受全局限流保护
+func (s *AuthService) SetLoginProtector(protector *LoginProtector) {
+	s.loginProtector = protector
+}
+
+// checkLoginThrottle 检查用户名和来源地址是否允许登录
+func (s *AuthService) checkLoginThrottle(username, ipAddress string) error {
+	if s.loginProtector == nil {
+		return nil
+	}
+	if err := s.loginProtector.Check(username, ipAddress); err != nil {
+		s.logLoginEvent(0, username, "login_throttled", "ip="+ipAddress)
+		return err
+	}
+	return nil
+}
+
+// recordLoginFailure 记录登录失败，达到阈值时在审计日志中记录锁定事件
+func (s *AuthService) recordLoginFailure(username, ipAddress string) {
+	if s.loginProtector == nil {
+		return
+	}
+	for _, event := range s.loginProtector.RecordFailure(username, ipAddress) {
+		name := "account_locked"
+		if strings.HasPrefix(event.Subject, "ip:") {
+			name = "ip_locked"
+		}
+		detail := fmt.Sprintf("%s | attempts=%d | duration=%s", event.Subject, event.Attempts, event.Duration)
+		s.logLoginEvent(0, username, name, detail+" | ip="+ipAddress)
+		s.audit.Record(audit.Entry{
+			ActorType:    models.AuditActorSystem,
+			ActorName:    "system",
+			Action:       "auth." + name,
+			ResourceType: "login-lockouts",
+			ResourceID:   event.Subject,
+			Detail:       detail,
+			SourceIP:     ipAddress,
+		})
+	}
+}
+
+// resetLoginFailures 登录成功后清除用户名的失败计数（来源地址的计数保留到过期）
+func (s *AuthService) resetLoginFailures(username string) {
+	if s.loginProtector == nil {
+		return
+	}
+	if err := s.loginProtector.Reset(userSubject(username)); err != nil {
+		log.Printf("Failed to reset login failures for %s: %v", username, err)
+	}
+}
+
+// GetLoginLockout 获取用户的登录锁定状态
+func (s *AuthService) GetLoginLockout(userID uint) (*LoginLockout, error) {
+	if s.loginProtector == nil {
+		return nil, ErrLoginProtectionDisabled
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.loginProtector.Status(userSubject(user.Username))
+}
+
+// UnlockUserLogin 管理员解除用户的登录锁定并清除失败计数
+func (s *AuthService) UnlockUserLogin(userID, operatorID uint) error {
+	if s.loginProtector == nil {
+		return ErrLoginProtectionDisabled
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.loginProtector.Reset(userSubject(user.Username)); err != nil {
+		return fmt.Errorf("failed to unlock user: %w", err)
+	}
+	s.logLoginEvent(user.ID, user.Username, "account_unlocked", fmt.Sprintf("operator_id=%d", operatorID))
+	return nil
+}
+
+// UnlockIPLogin 管理员解除来源地址的登录锁定并清除失败计数
+func (s *AuthService) UnlockIPLogin(ipAddress string, operatorID uint) error {
+	if s.loginProtector == nil {
+		return ErrLoginProtectionDisabled
+	}
+	if err := s.loginProtector.Reset(ipSubject(ipAddress)); err != nil {
+		return fmt.Errorf("failed to unlock ip: %w", err)
+	}
+	s.logLoginEvent(0, "", "ip_unlocked", fmt.Sprintf("ip=%s | operator_id=%d", ipAddress, operatorID))
+	return nil
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAttemptCache 内存中的登录失败计数缓存，过期时间按 now 计算
+type fakeAttemptCache struct {
+	mu      sync.Mutex
+	now     time.Time
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeAttemptCache() *fakeAttemptCache {
+	return &fakeAttemptCache{
+		now:     time.Now(),
+		values:  make(map[string]string),
+		expires: make(map[string]time.Time),
+	}
+}
+
+func (f *fakeAttemptCache) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// live 删除已过期的键并返回键是否存在，调用方持有锁
+func (f *fakeAttemptCache) live(key string) bool {
+	if expires, ok := f.expires[key]; ok && !f.now.Before(expires) {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	_, ok := f.values[key]
+	return ok
+}
+
+func (f *fakeAttemptCache) Get(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.live(key) {
+		return "", errors.New("redis: nil")
+	}
+	return f.values[key], nil
+}
+
+func (f *fakeAttemptCache) Incr(ctx context.Context, key string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.live(key)
+	value, _ := strconv.ParseInt(f.values[key], 10, 64)
+	value++
+	f.values[key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func (f *fakeAttemptCache) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.live(key) {
+		f.expires[key] = f.now.Add(expiration)
+	}
+	return nil
+}
+
+func (f *fakeAttemptCache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.live(key) {
+		return -2, nil
+	}
+	expires, ok := f.expires[key]
+	if !ok {
+		return -1, nil
+	}
+	return expires.Sub(f.now), nil
+}
+
+func (f *fakeAttemptCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[key] = strconv.FormatInt(value.(int64), 10)
+	delete(f.expires, key)
+	if expiration > 0 {
+		f.expires[key] = f.now.Add(expiration)
+	}
+	return nil
+}
+
+func (f *fakeAttemptCache) Delete(ctx context.Context, keys ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	return nil
+}
+
+var testLoginProtection = LoginProtectionConfig{
+	MaxAttempts:     5,
+	IPMaxAttempts:   8,
+	FreeAttempts:    2,
+	BaseDelay:       time.Second,
+	MaxDelay:        4 * time.Second,
+	LockoutDuration: 15 * time.Minute,
+	Window:          15 * time.Minute,
+}
+
+func setupLoginProtectionTest(t *testing.T) (*AuthService, *fakeAttemptCache) {
+	db := setupExternalAuthDB(t)
+	cache := newFakeAttemptCache()
+	service := NewAuthService(repository.NewUserRepository(db), "login-protection-test-secret-with-32-chars", time.Hour)
+	service.SetLogger(nil)
+	service.SetLoginProtector(NewLoginProtector(cache, testLoginProtection))
+	return service, cache
+}
+
+func TestLoginProtection_DelayAndLockout(t *testing.T) {
+	service, cache := setupLoginProtectionTest(t)
+	login := func(username, password, ip string) error {
+		_, err := service.Login(&LoginRequest{Username: username, Password: password, IPAddress: ip})
+		return err
+	}
+
+	// 免延迟次数内：不存在的用户和密码错误返回相同的错误
+	assert.EqualError(t, login("nobody", "wrong", "10.0.0.1"), "invalid username or password")
+	assert.EqualError(t, login("admin", "wrong", "10.0.0.2"), "invalid username or password")
+	assert.EqualError(t, login("admin", "wrong", "10.0.0.3"), "invalid username or password")
+	assert.EqualError(t, login("admin", "wrong", "10.0.0.3"), "invalid username or password")
+
+	// 超过免延迟次数后需要等待，等待时间指数增长
+	err := login("admin", "Local@Pass1", "10.0.0.3")
+	var throttled *LoginThrottledError
+	require.ErrorAs(t, err, &throttled)
+	assert.Equal(t, time.Second, throttled.RetryAfter)
+
+	cache.advance(time.Second)
+	assert.ErrorIs(t, login("admin", "wrong", "10.0.0.4"), ErrInvalidCredentials)
+	require.ErrorAs(t, login("admin", "wrong", "10.0.0.4"), &throttled)
+	assert.Equal(t, 2*time.Second, throttled.RetryAfter)
+
+	// 达到阈值后锁定，锁定期间正确的密码也被拒绝
+	cache.advance(2 * time.Second)
+	assert.ErrorIs(t, login("ADMIN", "wrong", "10.0.0.5"), ErrInvalidCredentials)
+	require.ErrorAs(t, login("admin", "Local@Pass1", "10.0.0.6"), &throttled)
+	assert.Equal(t, 15*time.Minute, throttled.RetryAfter)
+
+	lockout, err := service.GetLoginLockout(1)
+	require.NoError(t, err)
+	assert.True(t, lockout.Locked)
+	assert.Equal(t, int64(900), lockout.RetryAfterSeconds)
+
+	// 管理员解锁后可以登录，登录成功清除失败计数
+	require.NoError(t, service.UnlockUserLogin(1, 1))
+	require.NoError(t, login("admin", "Local@Pass1", "10.0.0.6"))
+	lockout, err = service.GetLoginLockout(1)
+	require.NoError(t, err)
+	assert.False(t, lockout.Locked)
+	assert.Zero(t, lockout.FailedAttempts)
+
+	// 锁定到期后自动解锁
+	for i := 0; i < testLoginProtection.MaxAttempts; i++ {
+		cache.advance(testLoginProtection.MaxDelay)
+		login("admin", "wrong", "10.0.1."+strconv.Itoa(i))
+	}
+	require.ErrorAs(t, login("admin", "Local@Pass1", "10.0.2.1"), &throttled)
+	cache.advance(testLoginProtection.LockoutDuration)
+	assert.NoError(t, login("admin", "Local@Pass1", "10.0.2.1"))
+}
+
+func TestLoginProtection_UnknownUserAndIP(t *testing.T) {
+	service, cache := setupLoginProtectionTest(t)
+
+	// 不存在的用户名与存在的用户名同样被锁定，响应无法区分
+	for i := 0; i < testLoginProtection.MaxAttempts; i++ {
+		cache.advance(testLoginProtection.MaxDelay)
+		_, err := service.Login(&LoginRequest{Username: "ghost", Password: "x", IPAddress: "10.1.0." + strconv.Itoa(i)})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, ghostErr := service.Login(&LoginRequest{Username: "ghost", Password: "x", IPAddress: "10.1.1.1"})
+	assert.ErrorIs(t, ghostErr, ErrLoginThrottled)
+
+	// 同一来源地址尝试不同用户名，达到阈值后锁定该地址
+	for i := 0; i < testLoginProtection.IPMaxAttempts; i++ {
+		cache.advance(testLoginProtection.MaxDelay)
+		service.Login(&LoginRequest{Username: "user" + strconv.Itoa(i), Password: "x", IPAddress: "10.9.9.9"})
+	}
+	_, err := service.Login(&LoginRequest{Username: "admin", Password: "Local@Pass1", IPAddress: "10.9.9.9"})
+	assert.ErrorIs(t, err, ErrLoginThrottled)
+	assert.Equal(t, ghostErr.Error(), err.Error())
+
+	// 其他地址不受影响；解锁来源地址后恢复
+	_, err = service.Login(&LoginRequest{Username: "admin", Password: "Local@Pass1", IPAddress: "10.9.9.10"})
+	assert.NoError(t, err)
+	require.NoError(t, service.UnlockIPLogin("10.9.9.9", 1))
+	_, err = service.Login(&LoginRequest{Username: "admin", Password: "Local@Pass1", IPAddress: "10.9.9.9"})
+	assert.NoError(t, err)
+}
+
+func TestLoginProtection_HandlerResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, _ := setupLoginProtectionTest(t)
+	router := gin.New()
+	NewAuthHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	post := func(username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+			strings.NewReader(`{"username":"`+username+`","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < testLoginProtection.FreeAttempts+1; i++ {
+		assert.Equal(t, http.StatusUnauthorized, post("admin").Code)
+	}
+	w := post("admin")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func TestLoginProtection_IgnoresUntrustedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, cache := setupLoginProtectionTest(t)
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	NewAuthHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	// 未配置可信代理时伪造的 X-Forwarded-For 不影响按来源地址计数
+	for i, forwarded := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+			strings.NewReader(`{"username":"user`+strconv.Itoa(i)+`","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", forwarded)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		_, err := cache.Get(context.Background(), loginFailureKeyPrefix+ipSubject(forwarded))
+		assert.Error(t, err, forwarded)
+	}
+	remote, _, err := net.SplitHostPort(httptest.NewRequest(http.MethodGet, "/", nil).RemoteAddr)
+	require.NoError(t, err)
+	value, err := cache.Get(context.Background(), loginFailureKeyPrefix+ipSubject(remote))
+	require.NoError(t, err)
+	assert.Equal(t, "3", value)
+}
+
+func TestLoginProtectorDelay(t *testing.T) {
+	protector := NewLoginProtector(newFakeAttemptCache(), testLoginProtection)
+	tests := []struct {
+		attempts int64
+		delay    time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{10, 4 * time.Second},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.delay, protector.delay(tt.attempts), "attempts=%d", tt.attempts)
+	}
+}
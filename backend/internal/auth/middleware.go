@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -13,7 +14,7 @@ func AuthMiddleware(authService *AuthService) gin.HandlerFunc {
 		var tokenString string
 
 		// 优先从 HttpOnly Cookie 获取 Token
-		if cookie, err := c.Cookie("auth_token"); err == nil && cookie != "" {
+		if cookie, err := c.Cookie(authTokenCookie); err == nil && cookie != "" {
 			tokenString = cookie
 		} else {
 			// 回退到 Authorization Header（兼容旧客户端）
@@ -38,7 +39,15 @@ func AuthMiddleware(authService *AuthService) gin.HandlerFunc {
 			tokenString = authHeader[7:] // 移除 "Bearer " 前缀
 		}
 
-		// 验证令牌
+		// nmp_ 前缀为 API 令牌，按令牌权限范围校验
+		if strings.HasPrefix(tokenString, APITokenPrefix) {
+			if authorizeAPIToken(c, authService, tokenString) {
+				c.Next()
+			}
+			return
+		}
+
+		// 验证令牌及其关联的会话（已登出、被撤销或用户停用的会话立即失效）
 		claims, err := authService.ValidateToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -48,22 +57,97 @@ func AuthMiddleware(authService *AuthService) gin.HandlerFunc {
 			return
 		}
 
+		// 确定当前组织，请求上下文携带组织后数据查询自动限定到该组织
+		if !bindOrganization(c, authService, claims.OrganizationID, claims.Roles) {
+			return
+		}
+
 		// 将用户信息存储到上下文中
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("roles", claims.Roles)
+		c.Set("session_id", claims.ID)
 
 		c.Next()
 	}
 }
 
+// authorizeAPIToken 校验 API 令牌并检查令牌和所属用户是否有权访问当前接口，失败时写入响应并中止请求
+func authorizeAPIToken(c *gin.Context, authService *AuthService, tokenString string) bool {
+	user, scope, err := authService.AuthenticateAPIToken(tokenString, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid or expired token",
+		})
+		c.Abort()
+		return false
+	}
+
+	resource, action, deviceParam, ok := apiTokenPermission(c.Request.Method, c.FullPath())
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "API tokens are not allowed for this endpoint",
+		})
+		c.Abort()
+		return false
+	}
+
+	// 限定设备范围的令牌只能访问指定设备的接口和设备列表（列表按设备范围过滤）
+	if scope.Devices != nil {
+		allowed := false
+		if deviceParam != "" {
+			deviceID, err := strconv.ParseUint(c.Param(deviceParam), 10, 32)
+			allowed = err == nil && scope.AllowsDevice(uint(deviceID))
+		} else {
+			allowed = c.Request.Method == http.MethodGet && c.FullPath() == apiTokenPathPrefix+"devices"
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API token is not allowed to access this device",
+			})
+			c.Abort()
+			return false
+		}
+		c.Set("device_scope", scope.DeviceList())
+	}
+
+	allowed, err := authService.AuthorizeAPIToken(user, scope, resource, action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check permission",
+		})
+		c.Abort()
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Insufficient permissions",
+		})
+		c.Abort()
+		return false
+	}
+
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
+	}
+	if !bindOrganization(c, authService, userOrganization(user), roles) {
+		return false
+	}
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("roles", roles)
+	c.Set("api_token_id", scope.TokenID)
+	return true
+}
+
 // OptionalAuthMiddleware 可选认证中间件（不强制要求认证）
 func OptionalAuthMiddleware(authService *AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var tokenString string
 
 		// 优先从 HttpOnly Cookie 获取 Token
-		if cookie, err := c.Cookie("auth_token"); err == nil && cookie != "" {
+		if cookie, err := c.Cookie(authTokenCookie); err == nil && cookie != "" {
 			tokenString = cookie
 		} else {
 			// 回退到 Authorization Header
@@ -75,9 +159,11 @@ func OptionalAuthMiddleware(authService *AuthService) gin.HandlerFunc {
 
 		if tokenString != "" {
 			if claims, err := authService.ValidateToken(tokenString); err == nil {
+				setOrganization(c, claims.OrganizationID, isGlobalAdmin(claims.OrganizationID, claims.Roles))
 				c.Set("user_id", claims.UserID)
 				c.Set("username", claims.Username)
 				c.Set("roles", claims.Roles)
+				c.Set("session_id", claims.ID)
 			}
 		}
 		c.Next()
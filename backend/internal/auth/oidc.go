@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDC 登录相关常量
+const (
+	// oidcStateTTL 发起登录到回调之间允许的最长时间
+	oidcStateTTL = 10 * time.Minute
+	// oidcRequestTimeout 发现、换取令牌和获取密钥的超时时间
+	oidcRequestTimeout = 15 * time.Second
+)
+
+// OIDC 错误
+var (
+	ErrOIDCProviderNotFound = errors.New("OIDC provider not found")
+	ErrOIDCInvalidState     = errors.New("invalid or expired OIDC login state")
+	ErrOIDCNoMappedRole     = errors.New("OIDC user is not assigned any mapped role")
+)
+
+// OIDCProviderConfig OIDC 身份提供者配置
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	UsernameClaim string
+	EmailClaim    string
+	NameClaim     string
+	RolesClaim    string // 支持点号分隔的嵌套声明，如 realm_access.roles
+
+	AutoCreateUsers bool
+	DefaultRole     string
+	RoleMappings    []OIDCRoleMapping
+}
+
+// OIDCRoleMapping OIDC 声明值到角色的映射
+type OIDCRoleMapping struct {
+	Value string // 声明值，不区分大小写
+	Role  string // 角色名称
+}
+
+// OIDCProviderInfo 登录页展示的提供者信息
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCProvider OIDC 身份提供者
+// 使用授权码 + PKCE 流程登录，提供者元数据和签名密钥通过发现文档获取
+type OIDCProvider struct {
+	config   OIDCProviderConfig
+	roleRepo repository.RoleRepository
+	client   *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider 创建 OIDC 身份提供者，首次登录时才请求发现文档
+func NewOIDCProvider(config OIDCProviderConfig, roleRepo repository.RoleRepository) *OIDCProvider {
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.EmailClaim == "" {
+		config.EmailClaim = "email"
+	}
+	if config.NameClaim == "" {
+		config.NameClaim = "name"
+	}
+
+	return &OIDCProvider{
+		config:   config,
+		roleRepo: roleRepo,
+		client:   &http.Client{Timeout: oidcRequestTimeout},
+	}
+}
+
+// Info 获取提供者展示信息
+func (p *OIDCProvider) Info() OIDCProviderInfo {
+	return OIDCProviderInfo{Name: p.config.Name, DisplayName: p.config.DisplayName}
+}
+
+// discover 获取提供者元数据，成功后缓存；失败时下次登录重试
+func (p *OIDCProvider) discover(ctx context.Context) (*oidc.Provider, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.provider != nil {
+		return p.provider, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, p.client), p.config.IssuerURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover OIDC provider %s: %w", p.config.Name, err)
+	}
+	p.provider = provider
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return p.provider, p.verifier, nil
+}
+
+// oauth2Config 生成 OAuth2 客户端配置
+func (p *OIDCProvider) oauth2Config(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.config.Scopes,
+	}
+}
+
+// AuthCodeURL 生成授权地址，携带 state、nonce 和 PKCE 挑战码
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	provider, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauth2Config(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// Exchange 使用授权码换取 ID 令牌，校验签名、签发者、受众、有效期和 nonce 后返回声明
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (map[string]interface{}, error) {
+	provider, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, p.client)
+	token, err := p.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response does not contain an id_token")
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	claims := make(map[string]interface{})
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse id_token claims: %w", err)
+	}
+	claims["iss"] = idToken.Issuer
+	claims["sub"] = idToken.Subject
+	return claims, nil
+}
+
+// Identity 从 ID 令牌声明中提取用户信息和用于角色映射的声明值
+func (p *OIDCProvider) Identity(claims map[string]interface{}) (*externalIdentity, []string, error) {
+	issuer, _ := claims["iss"].(string)
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, nil, errors.New("id_token does not contain a subject")
+	}
+
+	identity := &externalIdentity{
+		Source:     models.UserAuthSourceOIDC,
+		ExternalID: issuer + "|" + subject,
+		Username:   claimString(claims, p.config.UsernameClaim),
+		Email:      claimString(claims, p.config.EmailClaim),
+		FullName:   claimString(claims, p.config.NameClaim),
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		return nil, nil, fmt.Errorf("id_token does not contain the %s claim", p.config.UsernameClaim)
+	}
+
+	var values []string
+	if p.config.RolesClaim != "" {
+		values = claimStrings(claims, p.config.RolesClaim)
+	}
+	return identity, values, nil
+}
+
+// MapRoles 将声明值映射为本地角色，未匹配任何映射时使用默认角色
+func (p *OIDCProvider) MapRoles(values []string) ([]*models.Role, error) {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, mapping := range p.config.RoleMappings {
+		if seen[mapping.Role] {
+			continue
+		}
+		for _, value := range values {
+			if strings.EqualFold(value, mapping.Value) {
+				seen[mapping.Role] = true
+				names = append(names, mapping.Role)
+				break
+			}
+		}
+	}
+	if len(names) == 0 && p.config.DefaultRole != "" {
+		names = append(names, p.config.DefaultRole)
+	}
+	return rolesByName(p.roleRepo, names)
+}
+
+// claimValue 按点号分隔的路径读取声明
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	var current interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+	return current
+}
+
+// claimString 读取字符串声明
+func claimString(claims map[string]interface{}, path string) string {
+	value, _ := claimValue(claims, path).(string)
+	return value
+}
+
+// claimStrings 读取字符串或字符串数组声明
+func claimStrings(claims map[string]interface{}, path string) []string {
+	switch value := claimValue(claims, path).(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// SetOIDCProviders 设置 OIDC 身份提供者，frontendURL 为登录完成后跳转的前端地址
+func (s *AuthService) SetOIDCProviders(providers []*OIDCProvider, frontendURL string) {
+	s.oidcProviders = providers
+	s.oidcFrontendURL = strings.TrimRight(frontendURL, "/")
+}
+
+// OIDCProviders 获取已配置的 OIDC 身份提供者
+func (s *AuthService) OIDCProviders() []OIDCProviderInfo {
+	infos := make([]OIDCProviderInfo, len(s.oidcProviders))
+	for i, provider := range s.oidcProviders {
+		infos[i] = provider.Info()
+	}
+	return infos
+}
+
+// OIDCFrontendURL 生成登录完成后跳转的前端地址
+func (s *AuthService) OIDCFrontendURL(path string) string {
+	return s.oidcFrontendURL + path
+}
+
+// oidcProvider 按名称查找身份提供者
+func (s *AuthService) oidcProvider(name string) (*OIDCProvider, error) {
+	for _, provider := range s.oidcProviders {
+		if provider.config.Name == name {
+			return provider, nil
+		}
+	}
+	return nil, ErrOIDCProviderNotFound
+}
+
+// BeginOIDCLogin 发起 OIDC 登录，返回授权地址和需要写入 Cookie 的状态令牌
+func (s *AuthService) BeginOIDCLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, err := s.oidcProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier := oauth2.GenerateVerifier()
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		return "", "", err
+	}
+	stateToken, err := s.jwtManager.GenerateOIDCStateToken(OIDCStateClaims{
+		Provider: providerName,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: codeVerifier,
+	}, oidcStateTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state token: %w", err)
+	}
+	return authURL, stateToken, nil
+}
+
+// CompleteOIDCLogin 处理 OIDC 回调：校验状态、换取并验证 ID 令牌，按声明映射角色后签发与密码登录相同的令牌
+// 多因素认证由身份提供者负责，OIDC 登录不再进行本地两步验证
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, providerName, code, state, stateToken, ipAddress, userAgent string) (*LoginResponse, error) {
+	provider, err := s.oidcProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := s.jwtManager.ValidateOIDCStateToken(stateToken)
+	if err != nil || claims.Provider != providerName ||
+		subtle.ConstantTimeCompare([]byte(claims.State), []byte(state)) != 1 {
+		s.logLoginEvent(0, "", "login_failed", "oidc: "+ErrOIDCInvalidState.Error())
+		return nil, ErrOIDCInvalidState
+	}
+	if code == "" {
+		return nil, errors.New("missing authorization code")
+	}
+
+	idClaims, err := provider.Exchange(ctx, code, claims.Verifier, claims.Nonce)
+	if err != nil {
+		s.logLoginEvent(0, "", "login_failed", "oidc: "+err.Error())
+		return nil, errors.New("failed to verify identity provider response")
+	}
+	identity, values, err := provider.Identity(idClaims)
+	if err != nil {
+		s.logLoginEvent(0, "", "login_failed", "oidc: "+err.Error())
+		return nil, err
+	}
+
+	roles, err := provider.MapRoles(values)
+	if err != nil {
+		s.logLoginEvent(0, identity.Username, "login_failed", "oidc: "+err.Error())
+		return nil, errors.New("failed to map OIDC claims to roles")
+	}
+	if len(roles) == 0 {
+		s.logLoginEvent(0, identity.Username, "login_failed", "oidc: "+ErrOIDCNoMappedRole.Error())
+		return nil, ErrOIDCNoMappedRole
+	}
+
+	existing, err := s.userRepo.GetByUsername(identity.Username)
+	if err != nil {
+		existing = nil
+	}
+	// 同名用户已绑定其他身份（如另一个提供者的同名账号）时拒绝登录
+	if existing != nil && existing.AuthSource == models.UserAuthSourceOIDC &&
+		existing.ExternalID != "" && existing.ExternalID != identity.ExternalID {
+		s.logLoginEvent(existing.ID, existing.Username, "login_failed", "oidc: username is bound to another identity")
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.provisionExternalUser(identity, existing, roles, provider.config.AutoCreateUsers)
+	if err != nil {
+		return nil, err
+	}
+	return s.completeLogin(user, ipAddress, userAgent)
+}
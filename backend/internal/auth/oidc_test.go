@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIssuer 本地 OIDC 身份提供者，提供发现文档、JWKS 和令牌端点
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+// mockAuthorization 授权码对应的授权请求和用户声明
+type mockAuthorization struct {
+	nonce     string
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := &mockIssuer{key: key, codes: make(map[string]mockAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.server.URL,
+			"authorization_endpoint":                issuer.server.URL + "/authorize",
+			"token_endpoint":                        issuer.server.URL + "/token",
+			"jwks_uri":                              issuer.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// authorize 模拟用户在身份提供者完成登录，返回携带授权码的回调参数
+func (m *mockIssuer) authorize(t *testing.T, authURL, code string, claims jwt.MapClaims) url.Values {
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	query := parsed.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{
+		nonce:     query.Get("nonce"),
+		challenge: query.Get("code_challenge"),
+		claims:    claims,
+	}
+	m.mu.Unlock()
+	return url.Values{"code": {code}, "state": {query.Get("state")}}
+}
+
+// token 令牌端点：授权码只能使用一次，并校验 PKCE 校验码
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	m.mu.Lock()
+	authorization, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != authorization.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   "nmp",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": authorization.nonce,
+	}
+	for k, v := range authorization.claims {
+		claims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, _ := token.SignedString(m.key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	issuer := newMockIssuer(t)
+	db := setupExternalAuthDB(t)
+
+	service := NewAuthService(repository.NewUserRepository(db), "oidc-test-secret-key-with-32-characters", time.Hour)
+	service.SetLogger(nil)
+	service.SetOIDCProviders([]*OIDCProvider{NewOIDCProvider(OIDCProviderConfig{
+		Name:            "mock",
+		DisplayName:     "Mock SSO",
+		IssuerURL:       issuer.server.URL,
+		ClientID:        "nmp",
+		RedirectURL:     "http://nmp.test/api/v1/auth/oidc/mock/callback",
+		RolesClaim:      "realm_access.roles",
+		AutoCreateUsers: true,
+		RoleMappings: []OIDCRoleMapping{
+			{Value: "NMP-Operators", Role: "operator"},
+		},
+	}, repository.NewRoleRepository(db))}, "https://nmp.test")
+
+	router := gin.New()
+	NewAuthHandler(service).RegisterRoutes(router.Group("/api/v1"))
+
+	// login 发起登录，返回授权地址和状态 Cookie
+	login := func() (string, *http.Cookie) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/mock/login", nil))
+		require.Equal(t, http.StatusFound, w.Code)
+		var stateCookie *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == oidcStateCookie {
+				stateCookie = cookie
+			}
+		}
+		require.NotNil(t, stateCookie)
+		assert.Equal(t, "/api/v1/auth/oidc", stateCookie.Path)
+		assert.Equal(t, http.SameSiteLaxMode, stateCookie.SameSite)
+		return w.Header().Get("Location"), stateCookie
+	}
+	// callback 携带状态 Cookie 请求回调地址
+	callback := func(params url.Values, stateCookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/mock/callback?"+params.Encode(), nil)
+		if stateCookie != nil {
+			req.AddCookie(stateCookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusFound, w.Code)
+		return w
+	}
+	aliceClaims := jwt.MapClaims{
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"name":               "Alice Wang",
+		"realm_access":       map[string]interface{}{"roles": []string{"nmp-operators", "offline_access"}},
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/providers", nil))
+	assert.Contains(t, w.Body.String(), `"display_name":"Mock SSO"`)
+
+	// 完整登录流程：签发与密码登录相同的令牌 Cookie 并跳转回前端
+	authURL, stateCookie := login()
+	assert.True(t, strings.HasPrefix(authURL, issuer.server.URL+"/authorize?"))
+	params := issuer.authorize(t, authURL, "code-1", aliceClaims)
+	w = callback(params, stateCookie)
+	assert.Equal(t, "https://nmp.test/login?sso=success", w.Header().Get("Location"))
+
+	var accessToken string
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == authTokenCookie {
+			accessToken = cookie.Value
+		}
+	}
+	require.NotEmpty(t, accessToken)
+	claims, err := service.ValidateToken(accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, []string{"operator"}, claims.Roles)
+
+	user, err := repository.NewUserRepository(db).GetByUsername("alice")
+	require.NoError(t, err)
+	assert.Equal(t, "oidc", user.AuthSource)
+	assert.Equal(t, issuer.server.URL+"|user-1", user.ExternalID)
+
+	// 授权码不能重复使用
+	w = callback(params, stateCookie)
+	assert.Contains(t, w.Header().Get("Location"), "/login?sso_error=")
+
+	// 缺少状态 Cookie 或 state 不匹配时拒绝
+	authURL, stateCookie = login()
+	params = issuer.authorize(t, authURL, "code-2", aliceClaims)
+	w = callback(params, nil)
+	assert.Contains(t, w.Header().Get("Location"), url.QueryEscape(ErrOIDCInvalidState.Error()))
+	params.Set("state", "forged")
+	w = callback(params, stateCookie)
+	assert.Contains(t, w.Header().Get("Location"), url.QueryEscape(ErrOIDCInvalidState.Error()))
+
+	// 身份提供者返回的用户名与本地账号同名时拒绝登录
+	authURL, stateCookie = login()
+	params = issuer.authorize(t, authURL, "code-3", jwt.MapClaims{
+		"sub":                "user-2",
+		"preferred_username": "admin",
+		"realm_access":       map[string]interface{}{"roles": []string{"nmp-operators"}},
+	})
+	w = callback(params, stateCookie)
+	assert.Contains(t, w.Header().Get("Location"), "/login?sso_error=")
+
+	// 未匹配任何角色映射时拒绝登录
+	authURL, stateCookie = login()
+	params = issuer.authorize(t, authURL, "code-4", jwt.MapClaims{"sub": "user-3", "preferred_username": "carol"})
+	w = callback(params, stateCookie)
+	assert.Contains(t, w.Header().Get("Location"), url.QueryEscape(ErrOIDCNoMappedRole.Error()))
+}
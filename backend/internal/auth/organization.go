@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHeader 全局超级管理员切换组织使用的请求头
+const OrganizationHeader = "X-Organization-ID"
+
+// SetOrganizationRepository 设置组织仓库
+// 设置后校验用户所属组织是否启用，并允许全局超级管理员通过 X-Organization-ID 请求头切换组织
+func (s *AuthService) SetOrganizationRepository(organizations repository.OrganizationRepository) {
+	s.organizations = organizations
+}
+
+// userOrganization 用户所属组织，未设置时为默认组织
+func userOrganization(user *models.User) uint {
+	if user.OrganizationID == 0 {
+		return models.DefaultOrganizationID
+	}
+	return user.OrganizationID
+}
+
+// isGlobalAdmin 是否为全局超级管理员，即默认组织中拥有 admin 角色的用户
+func isGlobalAdmin(organizationID uint, roles []string) bool {
+	if organizationID != 0 && organizationID != models.DefaultOrganizationID {
+		return false
+	}
+	for _, role := range roles {
+		if role == "admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// bindOrganization 确定请求的当前组织并写入上下文，失败时写入响应并中止请求
+// 当前组织为用户所属组织，全局超级管理员可通过 X-Organization-ID 请求头切换到其他组织
+func bindOrganization(c *gin.Context, authService *AuthService, organizationID uint, roles []string) bool {
+	if organizationID == 0 {
+		organizationID = models.DefaultOrganizationID
+	}
+	globalAdmin := isGlobalAdmin(organizationID, roles)
+
+	current := organizationID
+	if header := c.GetHeader(OrganizationHeader); header != "" {
+		id, err := strconv.ParseUint(header, 10, 32)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid organization ID",
+			})
+			c.Abort()
+			return false
+		}
+		if uint(id) != organizationID && !globalAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Only global administrators can switch organization",
+			})
+			c.Abort()
+			return false
+		}
+		current = uint(id)
+	}
+
+	if authService.organizations != nil && current != models.DefaultOrganizationID {
+		organization, err := authService.organizations.GetByID(current)
+		if err != nil {
+			status := http.StatusInternalServerError
+			message := "Failed to load organization"
+			if strings.Contains(err.Error(), "not found") {
+				status, message = http.StatusForbidden, "Organization not found"
+			}
+			c.JSON(status, gin.H{
+				"error": message,
+			})
+			c.Abort()
+			return false
+		}
+		// 全局超级管理员可以进入已停用的组织进行管理
+		if !organization.Enabled && !globalAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Organization is disabled",
+			})
+			c.Abort()
+			return false
+		}
+	}
+
+	setOrganization(c, current, globalAdmin)
+	return true
+}
+
+// setOrganization 将当前组织写入 gin 上下文和请求上下文
+func setOrganization(c *gin.Context, organizationID uint, globalAdmin bool) {
+	if organizationID == 0 {
+		organizationID = models.DefaultOrganizationID
+	}
+	c.Set("organization_id", organizationID)
+	c.Set("global_admin", globalAdmin)
+	c.Request = c.Request.WithContext(tenant.WithOrganization(c.Request.Context(), organizationID))
+}
+
+// RequireGlobalAdmin 要求全局超级管理员的中间件，用于跨组织的管理接口
+func RequireGlobalAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("global_admin") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Global administrator required",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireOrganizationUser 确认路径参数中的用户属于当前组织，其他组织的用户视为不存在
+func RequireOrganizationUser(userRepo repository.UserRepository, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid user ID",
+			})
+			c.Abort()
+			return
+		}
+		if _, err := tenant.Bind(c.Request.Context(), userRepo).GetByID(uint(userID)); err != nil {
+			status := http.StatusInternalServerError
+			message := "Failed to get user"
+			if strings.Contains(err.Error(), "not found") {
+				status, message = http.StatusNotFound, "User not found"
+			}
+			c.JSON(status, gin.H{
+				"error": message,
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setupOrganizationTest 创建组织切换测试环境
+// 组织 default(1)、acme(2)、已停用的 initech(3)；root 为默认组织管理员（全局超级管理员），
+// carol 为 acme 的管理员，dave 为 initech 的操作员
+func setupOrganizationTest(t *testing.T) (*AuthService, *gin.Engine, *gorm.DB, map[string]uint) {
+	gin.SetMode(gin.TestMode)
+	db := setupExternalAuthDB(t)
+	require.NoError(t, tenant.Register(db))
+	require.NoError(t, db.AutoMigrate(&models.Organization{}))
+	for _, name := range []string{"default", "acme", "initech"} {
+		require.NoError(t, db.Create(&models.Organization{Name: name, Enabled: true}).Error)
+	}
+	require.NoError(t, db.Model(&models.Organization{}).Where("id = ?", 3).Update("enabled", false).Error)
+
+	roles := map[string]uint{}
+	for _, name := range []string{"admin", "operator"} {
+		var role models.Role
+		require.NoError(t, db.Where("name = ?", name).First(&role).Error)
+		roles[name] = role.ID
+	}
+	userRepo := repository.NewUserRepository(db)
+	users := map[string]uint{}
+	for _, u := range []struct {
+		name         string
+		organization uint
+		role         string
+	}{{"root", 1, "admin"}, {"carol", 2, "admin"}, {"dave", 3, "operator"}} {
+		user := &models.User{Username: u.name, Email: u.name + "@example.com", Status: models.UserStatusActive, OrganizationID: u.organization}
+		require.NoError(t, userRepo.Create(user))
+		require.NoError(t, userRepo.AssignRoles(user.ID, []uint{roles[u.role]}))
+		users[u.name] = user.ID
+	}
+
+	service := NewAuthService(userRepo, "organization-test-secret-key-with-32-chars", time.Hour)
+	service.SetLogger(nil)
+	service.SetOrganizationRepository(repository.NewOrganizationRepository(db))
+
+	router := gin.New()
+	api := router.Group("/api/v1", AuthMiddleware(service))
+	api.GET("/whoami", func(c *gin.Context) {
+		organizationID, _ := tenant.FromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{
+			"organization_id": organizationID,
+			"global_admin":    c.GetBool("global_admin"),
+		})
+	})
+	api.GET("/organizations", RequireGlobalAdmin(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	api.GET("/users/:id", RequireOrganizationUser(userRepo, "id"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return service, router, db, users
+}
+
+func TestAuthMiddleware_OrganizationSwitching(t *testing.T) {
+	service, router, _, users := setupOrganizationTest(t)
+	tokens := map[string]string{}
+	for name, organization := range map[string]uint{"root": 1, "carol": 2, "dave": 3} {
+		role := "admin"
+		if name == "dave" {
+			role = "operator"
+		}
+		token, err := service.jwtManager.GenerateToken(users[name], organization, name, []string{role}, "")
+		require.NoError(t, err)
+		tokens[name] = token
+	}
+
+	tests := []struct {
+		name         string
+		user         string
+		header       string
+		wantStatus   int
+		organization uint
+		globalAdmin  bool
+	}{
+		{"全局超级管理员默认在默认组织", "root", "", http.StatusOK, 1, true},
+		{"全局超级管理员切换组织", "root", "2", http.StatusOK, 2, true},
+		{"全局超级管理员可以进入已停用的组织", "root", "3", http.StatusOK, 3, true},
+		{"切换到不存在的组织", "root", "99", http.StatusForbidden, 0, false},
+		{"无效的组织ID", "root", "acme", http.StatusBadRequest, 0, false},
+		{"组织管理员在本组织", "carol", "", http.StatusOK, 2, false},
+		{"组织管理员指定本组织", "carol", "2", http.StatusOK, 2, false},
+		{"组织管理员不能切换组织", "carol", "1", http.StatusForbidden, 0, false},
+		{"已停用组织的用户被拒绝", "dave", "", http.StatusForbidden, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/whoami", nil)
+			req.Header.Set("Authorization", "Bearer "+tokens[tt.user])
+			if tt.header != "" {
+				req.Header.Set(OrganizationHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				OrganizationID uint `json:"organization_id"`
+				GlobalAdmin    bool `json:"global_admin"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.organization, body.OrganizationID)
+			assert.Equal(t, tt.globalAdmin, body.GlobalAdmin)
+		})
+	}
+
+	// 只有全局超级管理员可以访问跨组织的管理接口
+	for user, want := range map[string]int{"root": http.StatusOK, "carol": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/organizations", nil)
+		req.Header.Set("Authorization", "Bearer "+tokens[user])
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, want, w.Code, user)
+	}
+}
+
+func TestRequireOrganizationUser(t *testing.T) {
+	service, router, _, users := setupOrganizationTest(t)
+	rootToken, err := service.jwtManager.GenerateToken(users["root"], 1, "root", []string{"admin"}, "")
+	require.NoError(t, err)
+	carolToken, err := service.jwtManager.GenerateToken(users["carol"], 2, "carol", []string{"admin"}, "")
+	require.NoError(t, err)
+
+	call := func(token string, userID uint, organization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/users/"+strconv.FormatUint(uint64(userID), 10), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if organization != "" {
+			req.Header.Set(OrganizationHeader, organization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 其他组织的用户视为不存在
+	assert.Equal(t, http.StatusOK, call(carolToken, users["carol"], ""))
+	assert.Equal(t, http.StatusNotFound, call(carolToken, users["root"], ""))
+	assert.Equal(t, http.StatusOK, call(rootToken, users["root"], ""))
+	assert.Equal(t, http.StatusNotFound, call(rootToken, users["carol"], ""))
+
+	// 全局超级管理员切换到组织后管理该组织的用户
+	assert.Equal(t, http.StatusOK, call(rootToken, users["carol"], "2"))
+	assert.Equal(t, http.StatusNotFound, call(rootToken, users["root"], "2"))
+}
+
+func TestDevicePermissionChecker_OrganizationAdmin(t *testing.T) {
+	checker, db, users := setupDeviceAccessTest(t)
+
+	// acme 组织的管理员和设备
+	var admin models.Role
+	require.NoError(t, db.Where("name = ?", "admin").First(&admin).Error)
+	userRepo := repository.NewUserRepository(db)
+	carol := &models.User{Username: "carol", Email: "carol@example.com", Status: models.UserStatusActive, OrganizationID: 2}
+	require.NoError(t, userRepo.Create(carol))
+	require.NoError(t, userRepo.AssignRoles(carol.ID, []uint{admin.ID}))
+	device := &models.Device{Name: "acme-r1", Type: models.DeviceTypeRouter, Host: "acme-r1", OrganizationID: 2}
+	require.NoError(t, db.Create(device).Error)
+
+	// 组织管理员可对本组织全部设备执行操作，但不是超级管理员
+	access, err := checker.DeviceAccess(carol.ID)
+	require.NoError(t, err)
+	assert.False(t, access.IsSuperAdmin())
+	for _, action := range models.DeviceActions {
+		assert.True(t, access.Allows(device.ID, action), action)
+		assert.False(t, access.Allows(1, action), action)
+	}
+
+	// 默认组织的管理员是超级管理员
+	access, err = checker.DeviceAccess(users["root"])
+	require.NoError(t, err)
+	assert.True(t, access.IsSuperAdmin())
+	assert.True(t, access.Allows(device.ID, models.DeviceActionDelete))
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -11,6 +12,20 @@ const (
 	DefaultCost = bcrypt.DefaultCost
 )
 
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// dummyPasswordHash 用户不存在时用于比较的密码哈希，使响应时间与密码错误时一致
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		hashed, _ := bcrypt.GenerateFromPassword([]byte("nmp-dummy-password"), DefaultCost)
+		dummyHash = string(hashed)
+	})
+	return dummyHash
+}
+
 // PasswordManager 密码管理器
 type PasswordManager struct {
 	cost int
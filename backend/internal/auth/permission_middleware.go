@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 
+	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +17,10 @@ type DevicePermissionChecker struct {
 	rbacService *RBACService
 	permRepo    repository.PermissionRepository
 	userRepo    repository.UserRepository
+	grantRepo   repository.DeviceGrantRepository
+
+	mu    sync.Mutex
+	cache map[uint]cachedDeviceAccess // 用户有效设备权限缓存
 }
 
 // NewDevicePermissionChecker 创建设备权限检查器
@@ -21,11 +28,14 @@ func NewDevicePermissionChecker(
 	rbacService *RBACService,
 	permRepo repository.PermissionRepository,
 	userRepo repository.UserRepository,
+	grantRepo repository.DeviceGrantRepository,
 ) *DevicePermissionChecker {
 	return &DevicePermissionChecker{
 		rbacService: rbacService,
 		permRepo:    permRepo,
 		userRepo:    userRepo,
+		grantRepo:   grantRepo,
+		cache:       make(map[uint]cachedDeviceAccess),
 	}
 }
 
@@ -33,73 +43,27 @@ func NewDevicePermissionChecker(
 // 返回值: (是否有权限, 错误)
 // 权限规则:
 // 1. admin 角色可以操作所有设备
-// 2. operator 角色只能操作分配给自己的设备
-// 3. viewer 角色只能查看，不能修改
+// 2. 其他用户按授权给本人或其角色的设备授权（全部设备、分组、标签或单台设备）逐项检查操作
 func (c *DevicePermissionChecker) CheckDevicePermission(userID, deviceID uint, action string) (bool, error) {
-	// 获取用户信息（包含角色）
-	user, err := c.userRepo.GetByID(userID)
+	access, err := c.DeviceAccess(userID)
 	if err != nil {
 		return false, err
 	}
-
-	// 检查用户角色
-	isAdmin := false
-	isOperator := false
-	isViewer := false
-
-	for _, role := range user.Roles {
-		switch role.Name {
-		case "admin":
-			isAdmin = true
-		case "operator":
-			isOperator = true
-		case "viewer":
-			isViewer = true
-		}
-	}
-
-	// admin 角色可以操作所有设备
-	if isAdmin {
-		return true, nil
-	}
-
-	// viewer 角色只能查看
-	if isViewer && !isOperator {
-		if action == "read" {
-			// viewer 可以查看所有设备
-			return true, nil
-		}
-		// viewer 不能进行其他操作
-		return false, nil
-	}
-
-	// operator 角色需要检查设备权限
-	if isOperator {
-		// 检查是否有该设备的权限
-		hasPermission, err := c.permRepo.HasDevicePermission(userID, deviceID)
-		if err != nil {
-			return false, err
-		}
-		return hasPermission, nil
-	}
-
-	// 没有任何角色，拒绝访问
-	return false, nil
+	return access.Allows(deviceID, action), nil
 }
 
-// IsSuperAdmin 检查用户是否是超级管理员
+// IsSuperAdmin 检查用户是否是超级管理员（默认组织中拥有 admin 角色的用户）
 func (c *DevicePermissionChecker) IsSuperAdmin(userID uint) (bool, error) {
 	user, err := c.userRepo.GetByID(userID)
 	if err != nil {
 		return false, err
 	}
 
-	for _, role := range user.Roles {
-		if role.Name == "admin" {
-			return true, nil
-		}
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
 	}
-	return false, nil
+	return isGlobalAdmin(userOrganization(user), roles), nil
 }
 
 // GetUserRole 获取用户的主要角色
@@ -130,8 +94,13 @@ func (c *DevicePermissionChecker) GetUserRole(userID uint) (string, error) {
 }
 
 // DevicePermissionMiddleware 设备权限检查中间件
-// action: read, create, update, delete
+// action: read, update, delete, deploy
 func DevicePermissionMiddleware(checker *DevicePermissionChecker, action string) gin.HandlerFunc {
+	return DeviceParamPermissionMiddleware(checker, "id", action)
+}
+
+// DeviceParamPermissionMiddleware 设备权限检查中间件，设备ID取自指定的路由参数
+func DeviceParamPermissionMiddleware(checker *DevicePermissionChecker, param, action string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户ID
 		userID, exists := c.Get("user_id")
@@ -144,7 +113,7 @@ func DevicePermissionMiddleware(checker *DevicePermissionChecker, action string)
 		}
 
 		// 获取设备ID（从URL参数）
-		deviceIDStr := c.Param("id")
+		deviceIDStr := c.Param(param)
 		if deviceIDStr == "" {
 			// 如果没有设备ID参数，可能是列表或创建操作，跳过设备级别权限检查
 			c.Next()
@@ -183,7 +152,10 @@ func DevicePermissionMiddleware(checker *DevicePermissionChecker, action string)
 }
 
 // DeviceListFilterMiddleware 设备列表过滤中间件
-// 用于过滤用户只能看到有权限的设备
+// 用于过滤用户只能看到有权限的设备：除未切换组织的全局超级管理员外，将可查看的设备ID列表
+// （与 API 令牌限定的设备范围取交集）设置到上下文 device_scope，供列表、查询和导出使用。
+// 被授权全部设备的用户（包括其他组织的管理员）的范围是本组织的全部设备，
+// 全局超级管理员切换组织后的范围是该组织的全部设备
 func DeviceListFilterMiddleware(checker *DevicePermissionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户ID
@@ -196,8 +168,7 @@ func DeviceListFilterMiddleware(checker *DevicePermissionChecker) gin.HandlerFun
 			return
 		}
 
-		// 检查是否是超级管理员
-		isAdmin, err := checker.IsSuperAdmin(userID.(uint))
+		access, err := checker.DeviceAccess(userID.(uint))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "权限检查失败",
@@ -206,16 +177,63 @@ func DeviceListFilterMiddleware(checker *DevicePermissionChecker) gin.HandlerFun
 			return
 		}
 
+		var deviceIDs []uint
+		filterByPermission := true
+		if !access.IsSuperAdmin() {
+			deviceIDs = access.DeviceIDs(models.DeviceActionRead)
+		} else if organizationID := c.GetUint("organization_id"); organizationID != 0 && organizationID != models.DefaultOrganizationID {
+			if deviceIDs, err = checker.OrganizationDevices(organizationID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "权限检查失败",
+				})
+				c.Abort()
+				return
+			}
+		} else {
+			filterByPermission = false
+		}
+
 		// 设置上下文变量，供后续处理使用
-		c.Set("is_admin", isAdmin)
-		c.Set("filter_by_permission", !isAdmin)
+		c.Set("is_admin", access.IsSuperAdmin())
+		c.Set("filter_by_permission", filterByPermission)
+		if filterByPermission {
+			if scope, ok := c.Get("device_scope"); ok {
+				deviceIDs = intersectDeviceIDs(scope.([]uint), deviceIDs)
+			}
+			c.Set("device_scope", deviceIDs)
+		}
 
 		c.Next()
 	}
 }
 
+// ResourceOwnerFunc 解析请求访问的资源归属，返回 nil 表示请求不针对单个资源（如列表或创建）
+type ResourceOwnerFunc func(c *gin.Context) (*ResourceOwner, error)
+
+// ResourceOwnerFromParam 资源所有者为路由参数指定的用户，如 /users/:user_id/reports
+func ResourceOwnerFromParam(param string) ResourceOwnerFunc {
+	return func(c *gin.Context) (*ResourceOwner, error) {
+		value := c.Param(param)
+		if value == "" {
+			return nil, nil
+		}
+		userID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", param, value)
+		}
+		return &ResourceOwner{UserID: uint(userID)}, nil
+	}
+}
+
 // PermissionMiddleware 权限检查中间件
 func PermissionMiddleware(rbacService *RBACService, resource, action string) gin.HandlerFunc {
+	return ScopedPermissionMiddleware(rbacService, resource, action, nil)
+}
+
+// ScopedPermissionMiddleware 按权限范围检查的中间件
+// owner 解析请求访问的资源归属，资源须在用户被授予的范围内；owner 为 nil 或解析结果为 nil 时只检查是否拥有权限。
+// 授予的最大范围（global/group/self）设置到上下文 permission_scope，供处理器过滤列表
+func ScopedPermissionMiddleware(rbacService *RBACService, resource, action string, owner ResourceOwnerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户ID
 		userID, exists := c.Get("user_id")
@@ -227,8 +245,22 @@ func PermissionMiddleware(rbacService *RBACService, resource, action string) gin
 			return
 		}
 
+		// 解析资源归属
+		var resourceOwner *ResourceOwner
+		if owner != nil {
+			var err error
+			if resourceOwner, err = owner(c); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid resource",
+					"details": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+		}
+
 		// 检查权限
-		allowed, err := rbacService.CheckPermission(userID.(uint), resource, action)
+		scope, allowed, err := rbacService.ResolvePermission(userID.(uint), resource, action, resourceOwner)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to check permission",
@@ -245,6 +277,7 @@ func PermissionMiddleware(rbacService *RBACService, resource, action string) gin
 			return
 		}
 
+		c.Set("permission_scope", scope)
 		c.Next()
 	}
 }
@@ -254,6 +287,41 @@ func RequirePermission(rbacService *RBACService, resource, action string) gin.Ha
 	return PermissionMiddleware(rbacService, resource, action)
 }
 
+// RequireManagePermission 要求以 global 或 group 范围拥有权限的中间件，用于管理其他用户的接口
+// self 范围只允许操作本人的资源，不能通过这类接口操作其他用户或为自己提权（如为自己分配角色）
+func RequireManagePermission(rbacService *RBACService, resource, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("user_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "User not authenticated",
+			})
+			c.Abort()
+			return
+		}
+
+		scope, allowed, err := rbacService.ResolvePermission(userID.(uint), resource, action, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to check permission",
+			})
+			c.Abort()
+			return
+		}
+
+		if !allowed || scope == models.PermissionScopeSelf {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Insufficient permissions",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("permission_scope", scope)
+		c.Next()
+	}
+}
+
 // RequireAnyPermission 要求任意权限之一的中间件
 func RequireAnyPermission(rbacService *RBACService, permissions []Permission) gin.HandlerFunc {
 	return func(c *gin.Context) {
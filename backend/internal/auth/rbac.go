@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"sort"
 
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
@@ -13,10 +14,22 @@ import (
 
 // RBACService RBAC权限服务
 type RBACService struct {
-	enforcer   *casbin.Enforcer
-	userRepo   repository.UserRepository
-	roleRepo   repository.RoleRepository
-	permRepo   repository.PermissionRepository
+	enforcer      *casbin.Enforcer
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	permRepo      repository.PermissionRepository
+	groupResolver DeviceGroupResolver // 解析 group 范围权限中用户所属的设备分组
+}
+
+// DeviceGroupResolver 用户所属设备分组的解析（由 DevicePermissionChecker 实现）
+type DeviceGroupResolver interface {
+	UserDeviceGroups(userID uint) (allGroups bool, groupIDs []uint, err error)
+}
+
+// ResourceOwner 资源的归属，用于检查 group 和 self 范围的权限
+type ResourceOwner struct {
+	UserID   uint   // 资源所有者的用户ID，0 表示不属于任何用户
+	GroupIDs []uint // 资源所属的设备分组
 }
 
 // NewRBACService 创建新的RBAC服务
@@ -51,24 +64,117 @@ func NewRBACService(db *gorm.DB, userRepo repository.UserRepository, roleRepo re
 	return service, nil
 }
 
-// CheckPermission 检查用户权限
+// SetDeviceGroupResolver 设置设备分组解析器，未设置时 group 范围的权限不匹配任何资源
+func (s *RBACService) SetDeviceGroupResolver(resolver DeviceGroupResolver) {
+	s.groupResolver = resolver
+}
+
+// CheckPermission 检查用户权限（任意范围），检查具体资源时使用 CheckResourcePermission
 func (s *RBACService) CheckPermission(userID uint, resource, action string) (bool, error) {
+	_, allowed, err := s.ResolvePermission(userID, resource, action, nil)
+	return allowed, err
+}
+
+// CheckResourcePermission 检查用户对指定资源的权限，按权限范围比较资源的归属
+func (s *RBACService) CheckResourcePermission(userID uint, resource, action string, owner ResourceOwner) (bool, error) {
+	_, allowed, err := s.ResolvePermission(userID, resource, action, &owner)
+	return allowed, err
+}
+
+// ResolvePermission 检查用户权限，返回用户各角色授予的最大权限范围（global > group > self），无权限时为空
+// owner 为 nil 时只要以任意范围拥有权限即允许；否则资源须在某个授予的范围内：
+// group 范围要求资源属于用户所属的设备分组，self 范围要求资源为用户本人所有
+func (s *RBACService) ResolvePermission(userID uint, resource, action string, owner *ResourceOwner) (string, bool, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
-		return false, err
+		return "", false, err
+	}
+
+	scopes, err := s.permissionScopes(user, resource, action)
+	if err != nil {
+		return "", false, err
+	}
+
+	var scope string
+	for _, candidate := range []string{models.PermissionScopeGlobal, models.PermissionScopeGroup, models.PermissionScopeSelf} {
+		if scopes[candidate] {
+			scope = candidate
+			break
+		}
+	}
+	if scope == "" {
+		return "", false, nil
+	}
+	if owner == nil || scopes[models.PermissionScopeGlobal] {
+		return scope, true, nil
+	}
+
+	if scopes[models.PermissionScopeSelf] && owner.UserID != 0 && owner.UserID == userID {
+		return scope, true, nil
+	}
+	if scopes[models.PermissionScopeGroup] {
+		inGroups, err := s.inUserGroups(userID, owner.GroupIDs)
+		if err != nil {
+			return "", false, err
+		}
+		if inGroups {
+			return scope, true, nil
+		}
 	}
 
-	// 检查用户的每个角色是否有权限
+	return scope, false, nil
+}
+
+// permissionScopes 用户各角色授予权限的范围
+// Casbin 策略决定角色是否拥有权限，范围取自角色关联的权限记录；没有对应权限记录的策略视为 global
+func (s *RBACService) permissionScopes(user *models.User, resource, action string) (map[string]bool, error) {
+	scopes := make(map[string]bool)
 	for _, role := range user.Roles {
 		allowed, err := s.enforcer.Enforce(role.Name, resource, action)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		if allowed {
-			return true, nil
+		if !allowed {
+			continue
+		}
+
+		found := false
+		for _, permission := range role.Permissions {
+			if permission.Resource != resource || permission.Action != action {
+				continue
+			}
+			found = true
+			// 无法识别的范围不授予权限
+			if scope, ok := models.NormalizePermissionScope(permission.Scope); ok {
+				scopes[scope] = true
+			}
+		}
+		if !found {
+			scopes[models.PermissionScopeGlobal] = true
 		}
 	}
+	return scopes, nil
+}
+
+// inUserGroups 资源是否属于用户所属的设备分组
+func (s *RBACService) inUserGroups(userID uint, groupIDs []uint) (bool, error) {
+	if s.groupResolver == nil || len(groupIDs) == 0 {
+		return false, nil
+	}
 
+	allGroups, userGroups, err := s.groupResolver.UserDeviceGroups(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user device groups: %w", err)
+	}
+	if allGroups {
+		return true, nil
+	}
+	for _, groupID := range groupIDs {
+		i := sort.Search(len(userGroups), func(i int) bool { return userGroups[i] >= groupID })
+		if i < len(userGroups) && userGroups[i] == groupID {
+			return true, nil
+		}
+	}
 	return false, nil
 }
 
@@ -202,12 +308,17 @@ func (s *RBACService) CreateRole(name, displayName, description string, isSystem
 	return role, nil
 }
 
-// CreatePermission 创建权限
+// CreatePermission 创建权限，范围为空时为 global
 func (s *RBACService) CreatePermission(resource, action, scope, description string) (*models.Permission, error) {
+	normalized, ok := models.NormalizePermissionScope(scope)
+	if !ok {
+		return nil, fmt.Errorf("invalid permission scope %q, must be one of global, group, self", scope)
+	}
+
 	permission := &models.Permission{
 		Resource:    resource,
 		Action:      action,
-		Scope:       scope,
+		Scope:       normalized,
 		Description: description,
 	}
 
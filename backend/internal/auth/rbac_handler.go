@@ -282,9 +282,12 @@ func (h *RBACHandler) CheckPermission(c *gin.Context) {
 		return
 	}
 
+	// owner_id 和 group_ids 描述具体资源的归属，都不指定时只检查是否拥有权限
 	var req struct {
 		Resource string `json:"resource" binding:"required"`
 		Action   string `json:"action" binding:"required"`
+		OwnerID  uint   `json:"owner_id"`
+		GroupIDs []uint `json:"group_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -295,7 +298,12 @@ func (h *RBACHandler) CheckPermission(c *gin.Context) {
 		return
 	}
 
-	allowed, err := h.rbacService.CheckPermission(userID.(uint), req.Resource, req.Action)
+	var owner *ResourceOwner
+	if req.OwnerID != 0 || len(req.GroupIDs) > 0 {
+		owner = &ResourceOwner{UserID: req.OwnerID, GroupIDs: req.GroupIDs}
+	}
+
+	scope, allowed, err := h.rbacService.ResolvePermission(userID.(uint), req.Resource, req.Action, owner)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to check permission",
@@ -307,6 +315,7 @@ func (h *RBACHandler) CheckPermission(c *gin.Context) {
 		"success": true,
 		"data": gin.H{
 			"allowed": allowed,
+			"scope":   scope,
 		},
 	})
 }
@@ -335,9 +344,11 @@ func (h *RBACHandler) RegisterRoutes(router *gin.RouterGroup, authService *AuthS
 			permissions.POST("", h.CreatePermission)
 		}
 
-		// 用户角色管理 - 需要用户管理权限
+		// 用户角色管理 - 需要 global 或 group 范围的用户管理权限（限定在当前组织内），
+		// self 范围不能为本人分配角色，否则普通用户可以为自己分配管理员角色
 		users := rbac.Group("/users")
-		users.Use(RequirePermission(h.rbacService, "user", "update"))
+		users.Use(RequireManagePermission(h.rbacService, "user", "update"),
+			RequireOrganizationUser(h.rbacService.userRepo, "user_id"))
 		{
 			users.POST("/:user_id/roles", h.AssignRoleToUser)
 			users.DELETE("/:user_id/roles/:role_id", h.RemoveRoleFromUser)
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rbacTestModel 与 configs/rbac_model.conf 相同的 Casbin 模型
+const rbacTestModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+// setupScopeTest 在设备授权测试环境上创建带范围的权限
+// 操作员可查看所属分组的报表、修改本人的报表和用户信息；查看者可查看所属分组的报表；
+// 查看者的 device:read 为旧写法 all，操作员的 device:update 仅有策略没有权限记录
+func setupScopeTest(t *testing.T) (*RBACService, map[string]uint) {
+	checker, db, users := setupDeviceAccessTest(t)
+
+	m, err := model.NewModelFromString(rbacTestModel)
+	require.NoError(t, err)
+	enforcer, err := casbin.NewEnforcer(m)
+	require.NoError(t, err)
+
+	service := &RBACService{
+		enforcer: enforcer,
+		userRepo: repository.NewUserRepository(db),
+		roleRepo: repository.NewRoleRepository(db),
+		permRepo: repository.NewPermissionRepository(db),
+	}
+	service.SetDeviceGroupResolver(checker)
+
+	grant := func(roleName, resource, action, scope string) {
+		permission, err := service.GetPermissionByResourceAction(resource, action)
+		if err != nil {
+			permission = &models.Permission{Resource: resource, Action: action, Scope: scope}
+			require.NoError(t, db.Create(permission).Error)
+		}
+		var role models.Role
+		require.NoError(t, db.Where("name = ?", roleName).First(&role).Error)
+		require.NoError(t, db.Create(&models.RolePermission{RoleID: role.ID, PermissionID: permission.ID}).Error)
+		_, err = enforcer.AddPolicy(roleName, resource, action)
+		require.NoError(t, err)
+	}
+	grant("operator", "report", "read", models.PermissionScopeGroup)
+	grant("operator", "report", "update", models.PermissionScopeSelf)
+	grant("viewer", "report", "read", models.PermissionScopeGroup)
+	grant("viewer", "device", "read", "all")
+	grant("operator", "user", "update", models.PermissionScopeSelf)
+	_, err = enforcer.AddPolicy("operator", "device", "update")
+	require.NoError(t, err)
+
+	return service, users
+}
+
+func TestRBACService_PermissionScope(t *testing.T) {
+	service, users := setupScopeTest(t)
+	alice, bob := users["alice"], users["bob"]
+
+	tests := []struct {
+		name     string
+		user     uint
+		resource string
+		action   string
+		owner    *ResourceOwner
+		scope    string
+		want     bool
+	}{
+		// 未指定资源时只检查是否拥有权限
+		{"alice read reports", alice, "report", "read", nil, models.PermissionScopeGroup, true},
+		{"bob update reports", bob, "report", "update", nil, "", false},
+		// group 范围包含授权分组的下级分组，不含未授权的分组和不属于任何分组的资源
+		{"alice read core", alice, "report", "read", &ResourceOwner{GroupIDs: []uint{1}}, models.PermissionScopeGroup, true},
+		{"alice read core-east", alice, "report", "read", &ResourceOwner{GroupIDs: []uint{3, 2}}, models.PermissionScopeGroup, true},
+		{"alice read edge", alice, "report", "read", &ResourceOwner{GroupIDs: []uint{3}}, models.PermissionScopeGroup, false},
+		{"alice read ungrouped", alice, "report", "read", &ResourceOwner{UserID: alice}, models.PermissionScopeGroup, false},
+		// 被授权全部设备的用户属于全部分组
+		{"bob read edge", bob, "report", "read", &ResourceOwner{GroupIDs: []uint{3}}, models.PermissionScopeGroup, true},
+		// self 范围只包含本人的资源
+		{"alice update own", alice, "report", "update", &ResourceOwner{UserID: alice}, models.PermissionScopeSelf, true},
+		{"alice update bob's", alice, "report", "update", &ResourceOwner{UserID: bob, GroupIDs: []uint{1}}, models.PermissionScopeSelf, false},
+		// 旧写法 all 和没有权限记录的策略视为 global
+		{"bob read device", bob, "device", "read", &ResourceOwner{UserID: alice}, models.PermissionScopeGlobal, true},
+		{"alice update device", alice, "device", "update", &ResourceOwner{GroupIDs: []uint{3}}, models.PermissionScopeGlobal, true},
+	}
+	for _, tt := range tests {
+		scope, allowed, err := service.ResolvePermission(tt.user, tt.resource, tt.action, tt.owner)
+		require.NoError(t, err, tt.name)
+		assert.Equal(t, tt.scope, scope, tt.name)
+		assert.Equal(t, tt.want, allowed, tt.name)
+	}
+
+	allowed, err := service.CheckPermission(alice, "report", "update")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = service.CheckResourcePermission(alice, "report", "update", ResourceOwner{UserID: bob})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// 未设置分组解析器时 group 范围不匹配任何资源
+	service.SetDeviceGroupResolver(nil)
+	allowed, err = service.CheckResourcePermission(bob, "report", "read", ResourceOwner{GroupIDs: []uint{3}})
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRBACService_CreatePermissionScope(t *testing.T) {
+	service, _ := setupScopeTest(t)
+
+	permission, err := service.CreatePermission("dashboard", "read", "own", "查看仪表盘")
+	require.NoError(t, err)
+	assert.Equal(t, models.PermissionScopeSelf, permission.Scope)
+	permission, err = service.CreatePermission("dashboard", "update", "", "修改仪表盘")
+	require.NoError(t, err)
+	assert.Equal(t, models.PermissionScopeGlobal, permission.Scope)
+
+	_, err = service.CreatePermission("dashboard", "delete", "tenant", "删除仪表盘")
+	assert.EqualError(t, err, `invalid permission scope "tenant", must be one of global, group, self`)
+}
+
+func TestScopedPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, users := setupScopeTest(t)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", users["alice"])
+		c.Next()
+	})
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("permission_scope"))
+	}
+	router.GET("/reports", ScopedPermissionMiddleware(service, "report", "update", ResourceOwnerFromParam("user_id")), handler)
+	router.GET("/users/:user_id/reports", ScopedPermissionMiddleware(service, "report", "update", ResourceOwnerFromParam("user_id")), handler)
+	router.GET("/roles", PermissionMiddleware(service, "role", "read"), handler)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// 列表请求不针对单个资源，只检查权限并设置范围
+	w := serve("/reports")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.PermissionScopeSelf, w.Body.String())
+
+	w = serve("/users/" + strconv.FormatUint(uint64(users["alice"]), 10) + "/reports")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusForbidden, serve("/users/"+strconv.FormatUint(uint64(users["bob"]), 10)+"/reports").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/users/abc/reports").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/roles").Code)
+}
+
+func TestRequireManagePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, users := setupScopeTest(t)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", users[c.Query("user")])
+		c.Next()
+	})
+	handler := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("permission_scope"))
+	}
+	router.GET("/reports/update", RequireManagePermission(service, "report", "update"), handler)
+	router.GET("/reports/read", RequireManagePermission(service, "report", "read"), handler)
+	router.GET("/devices", RequireManagePermission(service, "device", "read"), handler)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	// self 范围不能管理其他用户的资源
+	assert.Equal(t, http.StatusForbidden, serve("/reports/update?user=alice").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/reports/update?user=bob").Code)
+
+	w := serve("/reports/read?user=alice")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.PermissionScopeGroup, w.Body.String())
+
+	w = serve("/devices?user=bob")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, models.PermissionScopeGlobal, w.Body.String())
+}
+
+func TestRBACHandler_UserRolesRequireManageScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, users := setupScopeTest(t)
+	// 管理员的 user:update 仅有策略没有权限记录，视为 global
+	_, err := service.enforcer.AddPolicy("admin", "user", "update")
+	require.NoError(t, err)
+
+	authService := NewAuthService(service.userRepo, "rbac-handler-test-secret-with-32-chars", time.Hour)
+	authService.SetLogger(nil)
+	router := gin.New()
+	NewRBACHandler(service).RegisterRoutes(router.Group("/api/v1"), authService)
+
+	adminRole, err := service.roleRepo.GetByName("admin")
+	require.NoError(t, err)
+	assign := func(user string, target uint) int {
+		token, err := authService.jwtManager.GenerateToken(users[user], 0, user, nil, "")
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/rbac/users/"+strconv.FormatUint(uint64(target), 10)+"/roles",
+			strings.NewReader(`{"role_id":`+strconv.FormatUint(uint64(adminRole.ID), 10)+`}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// self 范围的用户管理权限不能为本人或他人分配角色
+	assert.Equal(t, http.StatusForbidden, assign("alice", users["alice"]))
+	assert.Equal(t, http.StatusForbidden, assign("alice", users["bob"]))
+	assert.NotEqual(t, http.StatusForbidden, assign("root", users["alice"]))
+}
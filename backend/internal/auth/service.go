@@ -2,19 +2,30 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"nmp-platform/internal/audit"
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
+
+	"gorm.io/gorm"
 )
 
 // LoginRequest 登录请求
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+
+	// 客户端信息，由处理器填充，记录在会话中
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
+// ErrInvalidCredentials 用户名或密码错误，用户不存在时返回同样的错误
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
 // RegisterRequest 注册请求
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -28,6 +39,17 @@ type LoginResponse struct {
 	Token     string      `json:"token"`
 	ExpiresAt time.Time   `json:"expires_at"`
 	User      *UserInfo   `json:"user"`
+
+	// 刷新令牌，启用会话存储时返回
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+	SessionID        string     `json:"-"`
+
+	// 两步验证：密码验证通过后返回挑战令牌，提交验证码（或完成绑定）后才签发访问令牌
+	TwoFactorRequired      bool     `json:"two_factor_required,omitempty"`
+	TwoFactorSetupRequired bool     `json:"two_factor_setup_required,omitempty"`
+	ChallengeToken         string   `json:"challenge_token,omitempty"`
+	RecoveryCodes          []string `json:"recovery_codes,omitempty"` // 登录时完成绑定返回，只显示一次
 }
 
 // UserInfo 用户信息
@@ -45,6 +67,15 @@ type AuthService struct {
 	userRepo        repository.UserRepository
 	jwtManager      *JWTManager
 	passwordManager *PasswordManager
+	sessions        *SessionStore
+	twoFactor       *TwoFactorService
+	ldap            *LDAPProvider
+	oidcProviders   []*OIDCProvider
+	oidcFrontendURL string
+	apiTokens       *APITokenService
+	loginProtector  *LoginProtector
+	audit           *audit.Recorder
+	organizations   repository.OrganizationRepository
 	logger          *log.Logger
 }
 
@@ -58,11 +89,24 @@ func NewAuthService(userRepo repository.UserRepository, jwtSecret string, tokenE
 	}
 }
 
+// SetSessionStore 设置会话存储
+// 设置后访问令牌关联服务端会话，登出、撤销或用户停用后立即失效，并启用刷新令牌
+func (s *AuthService) SetSessionStore(store *SessionStore) {
+	store.setAccessTokenTTL(s.jwtManager.tokenExpiry)
+	s.sessions = store
+}
+
 // SetLogger 设置日志记录器
 func (s *AuthService) SetLogger(logger *log.Logger) {
 	s.logger = logger
 }
 
+// SetAuditRecorder 设置审计记录器
+// 登录请求本身由审计中间件记录，这里只记录不经过变更接口的事件（登录锁定、外部用户自动创建和同步）
+func (s *AuthService) SetAuditRecorder(recorder *audit.Recorder) {
+	s.audit = recorder
+}
+
 // logLoginEvent 记录登录事件
 func (s *AuthService) logLoginEvent(userID uint, username, event, detail string) {
 	if s.logger == nil {
@@ -80,37 +124,96 @@ func (s *AuthService) logLoginEvent(userID uint, username, event, detail string)
 }
 
 // Login 用户登录
+// 本地账号使用本地密码校验；启用 LDAP 时，本地不存在的用户和 LDAP 来源的用户通过目录服务校验。
+// 启用登录保护时按用户名和来源地址统计失败次数，失败过多时延迟或锁定
 func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
+	if err := s.checkLoginThrottle(req.Username, req.IPAddress); err != nil {
+		return nil, err
+	}
+
+	user, err := s.authenticate(req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			s.recordLoginFailure(req.Username, req.IPAddress)
+		}
+		return nil, err
+	}
+
+	// 已启用两步验证或角色要求两步验证时，返回挑战令牌等待第二步
+	challenge, err := s.twoFactorChallenge(user)
+	if err != nil {
+		s.logLoginEvent(user.ID, user.Username, "login_failed", err.Error())
+		return nil, errors.New("failed to check two-factor status")
+	}
+	if challenge != nil {
+		return challenge, nil
+	}
+
+	return s.completeLogin(user, req.IPAddress, req.UserAgent)
+}
+
+// authenticate 校验用户名和密码
+// 用户不存在、密码错误和账号不可交互登录时返回相同的 ErrInvalidCredentials，
+// 用户不存在时同样执行一次密码哈希比较，避免通过响应时间判断用户名是否存在
+func (s *AuthService) authenticate(req *LoginRequest) (*models.User, error) {
 	// 获取用户
 	user, err := s.userRepo.GetByUsername(req.Username)
-	if err != nil {
-		s.logLoginEvent(0, req.Username, "login_failed", "user not found")
-		return nil, errors.New("invalid username or password")
+	if s.ldap != nil && (err != nil || user.AuthSource == models.UserAuthSourceLDAP) {
+		if err != nil {
+			user = nil
+		}
+		return s.loginLDAP(req.Username, req.Password, user)
 	}
 
-	// 检查用户状态
-	if user.Status != models.UserStatusActive {
-		s.logLoginEvent(user.ID, user.Username, "login_failed", "account not active")
-		return nil, errors.New("user account is not active")
+	if err != nil {
+		s.passwordManager.VerifyPassword(dummyPasswordHash(), req.Password)
+		s.logLoginEvent(0, req.Username, "login_failed", "user not found")
+		return nil, ErrInvalidCredentials
 	}
 
 	// 验证密码
 	if err := s.passwordManager.VerifyPassword(user.Password, req.Password); err != nil {
 		s.logLoginEvent(user.ID, user.Username, "login_failed", "invalid password")
-		return nil, errors.New("invalid username or password")
+		return nil, ErrInvalidCredentials
 	}
 
-	// 获取用户角色
-	roles := make([]string, len(user.Roles))
-	for i, role := range user.Roles {
-		roles[i] = role.Name
+	// 服务账号只能通过 API 令牌访问
+	if user.AuthSource == models.UserAuthSourceService {
+		s.logLoginEvent(user.ID, user.Username, "login_failed", "service account cannot log in interactively")
+		return nil, ErrInvalidCredentials
 	}
 
-	// 生成JWT令牌
-	token, err := s.jwtManager.GenerateToken(user.ID, user.Username, roles)
+	// 检查用户状态（密码正确后才提示，避免暴露账号状态）
+	if user.Status != models.UserStatusActive {
+		s.logLoginEvent(user.ID, user.Username, "login_failed", "account not active")
+		return nil, errors.New("user account is not active")
+	}
+	return user, nil
+}
+
+// completeLogin 完成登录：创建会话、签发令牌并更新最后登录时间
+func (s *AuthService) completeLogin(user *models.User, ipAddress, userAgent string) (*LoginResponse, error) {
+	var sessionID, refreshToken string
+	var refreshExpiresAt *time.Time
+	if s.sessions != nil {
+		session, token, err := s.sessions.Create(user.ID, ipAddress, userAgent)
+		if err != nil {
+			s.logLoginEvent(user.ID, user.Username, "session_create_failed", err.Error())
+			return nil, errors.New("failed to create session")
+		}
+		sessionID = session.SessionID
+		refreshToken = token
+		refreshExpiresAt = &session.ExpiresAt
+	}
+
+	response, err := s.issueToken(user, sessionID)
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, err
 	}
+	response.RefreshToken = refreshToken
+	response.RefreshExpiresAt = refreshExpiresAt
+
+	s.resetLoginFailures(user.Username)
 
 	// 更新最后登录时间
 	if err := s.userRepo.UpdateLastLogin(user.ID); err != nil {
@@ -120,12 +223,30 @@ func (s *AuthService) Login(req *LoginRequest) (*LoginResponse, error) {
 		s.logLoginEvent(user.ID, user.Username, "login_success", "")
 	}
 
+	return response, nil
+}
+
+// issueToken 为用户签发访问令牌
+func (s *AuthService) issueToken(user *models.User, sessionID string) (*LoginResponse, error) {
+	// 获取用户角色
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
+	}
+
+	// 生成JWT令牌
+	token, err := s.jwtManager.GenerateToken(user.ID, userOrganization(user), user.Username, roles, sessionID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
 	// 计算过期时间
 	expiresAt := time.Now().Add(s.jwtManager.tokenExpiry)
 
 	return &LoginResponse{
 		Token:     token,
 		ExpiresAt: expiresAt,
+		SessionID: sessionID,
 		User: &UserInfo{
 			ID:       user.ID,
 			Username: user.Username,
@@ -169,13 +290,120 @@ func (s *AuthService) Register(req *RegisterRequest) (*models.User, error) {
 }
 
 // ValidateToken 验证令牌
+// 启用会话存储时同时检查令牌关联的会话是否有效，未关联会话的令牌视为无效
 func (s *AuthService) ValidateToken(tokenString string) (*TokenClaims, error) {
-	return s.jwtManager.ValidateToken(tokenString)
+	claims, err := s.jwtManager.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if s.sessions == nil {
+		return claims, nil
+	}
+
+	active, err := s.sessions.IsActive(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSessionRevoked
+	}
+	return claims, nil
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌和刷新令牌
+// 刷新令牌每次使用后轮换；用户已停用时撤销其所有会话
+func (s *AuthService) RefreshToken(refreshToken, ipAddress, userAgent string) (*LoginResponse, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionStoreDisabled
+	}
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	session, newRefreshToken, err := s.sessions.Rotate(refreshToken, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status != models.UserStatusActive {
+		s.RevokeUserSessions(user.ID, models.SessionRevokeUserDisabled)
+		return nil, errors.New("user account is not active")
+	}
+
+	response, err := s.issueToken(user, session.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	response.RefreshToken = newRefreshToken
+	response.RefreshExpiresAt = &session.ExpiresAt
+	return response, nil
+}
+
+// Logout 登出，撤销访问令牌或刷新令牌关联的会话
+func (s *AuthService) Logout(accessToken, refreshToken string) error {
+	if s.sessions == nil {
+		return nil
+	}
+
+	if accessToken != "" {
+		if claims, err := s.jwtManager.ValidateToken(accessToken); err == nil && claims.ID != "" {
+			s.logLoginEvent(claims.UserID, claims.Username, "logout", "")
+			return s.sessions.Revoke(claims.ID, models.SessionRevokeLogout)
+		}
+	}
+	// 访问令牌已过期时通过刷新令牌定位会话
+	if refreshToken != "" {
+		if session, err := s.sessions.GetByRefreshToken(refreshToken); err == nil {
+			s.logLoginEvent(session.UserID, "", "logout", "")
+			return s.sessions.Revoke(session.SessionID, models.SessionRevokeLogout)
+		}
+	}
+	return nil
 }
 
-// RefreshToken 刷新令牌
-func (s *AuthService) RefreshToken(tokenString string) (string, error) {
-	return s.jwtManager.RefreshToken(tokenString)
+// ListSessions 获取用户的会话，activeOnly 为 true 时只返回有效会话
+func (s *AuthService) ListSessions(userID uint, activeOnly bool) ([]*models.UserSession, error) {
+	if s.sessions == nil {
+		return nil, ErrSessionStoreDisabled
+	}
+	return s.sessions.ListByUser(userID, activeOnly)
+}
+
+// RevokeSession 撤销用户的指定会话，会话不属于该用户时返回 gorm.ErrRecordNotFound
+func (s *AuthService) RevokeSession(userID uint, sessionID, reason string) error {
+	if s.sessions == nil {
+		return ErrSessionStoreDisabled
+	}
+	session, err := s.sessions.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+	s.logLoginEvent(userID, "", "session_revoked", reason)
+	return s.sessions.Revoke(sessionID, reason)
+}
+
+// RevokeUserSessions 撤销用户的所有会话，返回撤销的会话数
+// 失败时只记录日志，调用方（如停用用户）不因此中断
+func (s *AuthService) RevokeUserSessions(userID uint, reason string) int {
+	if s.sessions == nil {
+		return 0
+	}
+	count, err := s.sessions.RevokeUser(userID, reason)
+	if err != nil {
+		s.logLoginEvent(userID, "", "session_revoke_failed", err.Error())
+		return 0
+	}
+	if count > 0 {
+		s.logLoginEvent(userID, "", "sessions_revoked", fmt.Sprintf("reason=%s count=%d", reason, count))
+	}
+	return count
 }
 
 // GetUserByID 根据ID获取用户信息
@@ -208,6 +436,11 @@ func (s *AuthService) ChangePassword(userID uint, oldPassword, newPassword strin
 		return err
 	}
 
+	// 外部账号的密码由目录服务管理
+	if user.AuthSource == models.UserAuthSourceLDAP {
+		return ErrExternalPassword
+	}
+
 	// 验证旧密码
 	if err := s.passwordManager.VerifyPassword(user.Password, oldPassword); err != nil {
 		return errors.New("invalid old password")
@@ -0,0 +1,303 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// 会话相关常量
+const (
+	sessionRevokedKeyPrefix = "auth:session:revoked:"
+	sessionActiveKeyPrefix  = "auth:session:active:"
+	// sessionActiveCacheTTL 会话有效状态的缓存时间，Redis 撤销标记写入失败时撤销最多延迟该时间生效
+	sessionActiveCacheTTL = time.Minute
+	// refreshReuseGrace 刷新令牌轮换后的宽限期，期间重复使用旧令牌（多个标签页并发刷新）只拒绝不撤销会话
+	refreshReuseGrace = 10 * time.Second
+	// sessionPruneInterval 清理过期会话的最小间隔
+	sessionPruneInterval = time.Hour
+	// sessionRetention 过期会话保留时间，便于管理员查看近期会话
+	sessionRetention = 7 * 24 * time.Hour
+	// sessionCacheTimeout Redis 操作超时时间，超时后回退到数据库
+	sessionCacheTimeout = 2 * time.Second
+)
+
+// 会话错误
+var (
+	ErrSessionRevoked       = errors.New("session has been revoked")
+	ErrInvalidRefreshToken  = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused   = errors.New("refresh token has already been used")
+	ErrSessionStoreDisabled = errors.New("session store is not configured")
+)
+
+// SessionCache 会话缓存接口（由 Redis 客户端实现）
+type SessionCache interface {
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// SessionStore 会话存储
+// 会话持久化在数据库中，Redis 缓存撤销列表和有效状态以避免每次请求查询数据库；
+// Redis 不可用时回退到数据库查询
+type SessionStore struct {
+	repo           repository.SessionRepository
+	cache          SessionCache
+	refreshExpiry  time.Duration
+	accessTokenTTL time.Duration
+
+	lastPrune time.Time
+	pruneMu   sync.Mutex
+}
+
+// NewSessionStore 创建会话存储，cache 为 nil 时只使用数据库
+func NewSessionStore(repo repository.SessionRepository, cache SessionCache, refreshExpiry time.Duration) *SessionStore {
+	return &SessionStore{
+		repo:          repo,
+		cache:         cache,
+		refreshExpiry: refreshExpiry,
+	}
+}
+
+// setAccessTokenTTL 设置访问令牌有效期，撤销标记至少保留到已签发的访问令牌全部过期
+func (s *SessionStore) setAccessTokenTTL(ttl time.Duration) {
+	s.accessTokenTTL = ttl
+}
+
+// Create 创建会话，返回会话和刷新令牌明文
+func (s *SessionStore) Create(userID uint, ipAddress, userAgent string) (*models.UserSession, string, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		SessionID:        sessionID,
+		UserID:           userID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		IPAddress:        ipAddress,
+		UserAgent:        truncateUserAgent(userAgent),
+		ExpiresAt:        now.Add(s.refreshExpiry),
+		LastUsedAt:       now,
+	}
+	if err := s.repo.Create(session); err != nil {
+		return nil, "", fmt.Errorf("failed to create session: %w", err)
+	}
+
+	s.pruneExpired(now)
+	return session, refreshToken, nil
+}
+
+// Rotate 使用刷新令牌换取新的刷新令牌
+// 已轮换的旧令牌在宽限期后再次出现视为令牌泄露，撤销整个会话
+func (s *SessionStore) Rotate(refreshToken, ipAddress, userAgent string) (*models.UserSession, string, error) {
+	hash := hashRefreshToken(refreshToken)
+	now := time.Now()
+
+	session, err := s.repo.GetByRefreshTokenHash(hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", s.checkReuse(hash, now)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if !session.IsActive(now) {
+		return nil, "", ErrInvalidRefreshToken
+	}
+
+	newToken, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	userAgent = truncateUserAgent(userAgent)
+	if err := s.repo.Rotate(session.SessionID, hash, hashRefreshToken(newToken), ipAddress, userAgent, now); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// 并发请求已完成轮换
+			return nil, "", ErrRefreshTokenReused
+		}
+		return nil, "", fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	session.IPAddress = ipAddress
+	session.UserAgent = userAgent
+	session.LastUsedAt = now
+	return session, newToken, nil
+}
+
+// checkReuse 检查未匹配的刷新令牌是否为已轮换的旧令牌
+func (s *SessionStore) checkReuse(hash string, now time.Time) error {
+	session, err := s.repo.GetByPreviousRefreshHash(hash)
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	if session.RotatedAt != nil && now.Sub(*session.RotatedAt) < refreshReuseGrace {
+		return ErrRefreshTokenReused
+	}
+	if session.RevokedAt == nil {
+		log.Printf("Refresh token reuse detected for session %s of user %d, revoking session", session.SessionID, session.UserID)
+		if err := s.Revoke(session.SessionID, models.SessionRevokeTokenReuse); err != nil {
+			log.Printf("Failed to revoke session %s: %v", session.SessionID, err)
+		}
+	}
+	return ErrRefreshTokenReused
+}
+
+// IsActive 检查会话是否有效
+// 优先查询 Redis 撤销列表和有效状态缓存，未命中或 Redis 不可用时查询数据库
+func (s *SessionStore) IsActive(sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+
+	if s.cache != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionCacheTimeout)
+		defer cancel()
+		if revoked, err := s.cache.Exists(ctx, sessionRevokedKeyPrefix+sessionID); err == nil {
+			if revoked {
+				return false, nil
+			}
+			if active, err := s.cache.Exists(ctx, sessionActiveKeyPrefix+sessionID); err == nil && active {
+				return true, nil
+			}
+		}
+	}
+
+	session, err := s.repo.GetBySessionID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	active := session.IsActive(time.Now())
+	if s.cache != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), sessionCacheTimeout)
+		defer cancel()
+		if active {
+			_ = s.cache.Set(ctx, sessionActiveKeyPrefix+sessionID, 1, sessionActiveCacheTTL)
+		} else {
+			_ = s.cache.Set(ctx, sessionRevokedKeyPrefix+sessionID, 1, s.revokedTTL())
+		}
+	}
+	return active, nil
+}
+
+// Revoke 撤销单个会话
+func (s *SessionStore) Revoke(sessionID, reason string) error {
+	if err := s.repo.Revoke(sessionID, reason); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	s.markRevoked(sessionID)
+	return nil
+}
+
+// RevokeUser 撤销用户的所有会话，返回撤销的会话数
+func (s *SessionStore) RevokeUser(userID uint, reason string) (int, error) {
+	sessionIDs, err := s.repo.RevokeByUser(userID, reason)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	s.markRevoked(sessionIDs...)
+	return len(sessionIDs), nil
+}
+
+// Get 根据会话ID获取会话
+func (s *SessionStore) Get(sessionID string) (*models.UserSession, error) {
+	return s.repo.GetBySessionID(sessionID)
+}
+
+// GetByRefreshToken 根据刷新令牌获取会话
+func (s *SessionStore) GetByRefreshToken(refreshToken string) (*models.UserSession, error) {
+	return s.repo.GetByRefreshTokenHash(hashRefreshToken(refreshToken))
+}
+
+// ListByUser 获取用户的会话
+func (s *SessionStore) ListByUser(userID uint, activeOnly bool) ([]*models.UserSession, error) {
+	return s.repo.ListByUser(userID, activeOnly)
+}
+
+// markRevoked 将会话写入 Redis 撤销列表并清除有效状态缓存
+func (s *SessionStore) markRevoked(sessionIDs ...string) {
+	if s.cache == nil || len(sessionIDs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sessionCacheTimeout)
+	defer cancel()
+
+	activeKeys := make([]string, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		if err := s.cache.Set(ctx, sessionRevokedKeyPrefix+sessionID, 1, s.revokedTTL()); err != nil {
+			log.Printf("Failed to cache revoked session %s: %v", sessionID, err)
+		}
+		activeKeys = append(activeKeys, sessionActiveKeyPrefix+sessionID)
+	}
+	if err := s.cache.Delete(ctx, activeKeys...); err != nil {
+		log.Printf("Failed to clear active session cache: %v", err)
+	}
+}
+
+// revokedTTL 撤销标记的保留时间
+func (s *SessionStore) revokedTTL() time.Duration {
+	if s.accessTokenTTL > 0 {
+		return s.accessTokenTTL
+	}
+	return s.refreshExpiry
+}
+
+// pruneExpired 定期删除过期较久的会话
+func (s *SessionStore) pruneExpired(now time.Time) {
+	s.pruneMu.Lock()
+	if now.Sub(s.lastPrune) < sessionPruneInterval {
+		s.pruneMu.Unlock()
+		return
+	}
+	s.lastPrune = now
+	s.pruneMu.Unlock()
+
+	if deleted, err := s.repo.DeleteExpired(now.Add(-sessionRetention)); err != nil {
+		log.Printf("Failed to prune expired sessions: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Pruned %d expired sessions", deleted)
+	}
+}
+
+// randomToken 生成 URL 安全的随机令牌
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashRefreshToken 计算刷新令牌的 SHA-256 哈希，数据库中不保存令牌明文
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncateUserAgent 截断 User-Agent 以适应字段长度
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > 255 {
+		return userAgent[:255]
+	}
+	return userAgent
+}
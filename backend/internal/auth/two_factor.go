@@ -0,0 +1,514 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// 两步验证相关常量
+const (
+	twoFactorIssuer = "NMP"
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// twoFactorChallengeTTL 密码验证通过后完成两步验证的时限
+	twoFactorChallengeTTL = 5 * time.Minute
+	// twoFactorMaxAttempts 每个挑战令牌允许的验证码错误次数
+	twoFactorMaxAttempts = 5
+)
+
+// 两步验证错误
+var (
+	ErrTwoFactorNotSetup        = errors.New("two-factor setup has not been started")
+	ErrTwoFactorNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorRequired        = errors.New("two-factor authentication is required by role policy")
+	ErrInvalidTwoFactorCode     = errors.New("invalid two-factor code")
+	ErrInvalidChallenge         = errors.New("invalid or expired two-factor challenge")
+	ErrTooManyTwoFactorAttempts = errors.New("too many invalid two-factor codes, please log in again")
+	ErrTwoFactorServiceDisabled = errors.New("two-factor authentication is not configured")
+)
+
+// TwoFactorSetup 两步验证绑定信息，provisioning_uri 用于生成二维码
+type TwoFactorSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// TwoFactorStatus 用户两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	Required               bool       `json:"required"` // 角色策略要求启用
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorLoginRequest 两步验证登录请求
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"` // TOTP 验证码或恢复码
+
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// challengeAttempts 挑战令牌的验证失败次数
+type challengeAttempts struct {
+	count     int
+	expiresAt time.Time
+}
+
+// TwoFactorService 两步验证（TOTP）服务
+type TwoFactorService struct {
+	repo   repository.TwoFactorRepository
+	issuer string
+
+	attempts   map[string]*challengeAttempts
+	attemptsMu sync.Mutex
+}
+
+// NewTwoFactorService 创建两步验证服务
+func NewTwoFactorService(repo repository.TwoFactorRepository) *TwoFactorService {
+	return &TwoFactorService{
+		repo:     repo,
+		issuer:   twoFactorIssuer,
+		attempts: make(map[string]*challengeAttempts),
+	}
+}
+
+// RoleRequiresTwoFactor 判断用户的角色是否要求两步验证
+func RoleRequiresTwoFactor(user *models.User) bool {
+	for _, role := range user.Roles {
+		if role.RequireTwoFactor {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEnabled 判断用户是否已启用两步验证
+func (s *TwoFactorService) IsEnabled(userID uint) (bool, error) {
+	twoFactor, err := s.repo.Get(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get two-factor config: %w", err)
+	}
+	return twoFactor.Enabled, nil
+}
+
+// Status 获取用户两步验证状态
+func (s *TwoFactorService) Status(user *models.User) (*TwoFactorStatus, error) {
+	status := &TwoFactorStatus{Required: RoleRequiresTwoFactor(user)}
+
+	twoFactor, err := s.repo.Get(user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor config: %w", err)
+	}
+	if !twoFactor.Enabled {
+		return status, nil
+	}
+
+	status.Enabled = true
+	status.EnabledAt = twoFactor.EnabledAt
+	if status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return status, nil
+}
+
+// BeginSetup 开始绑定：生成新密钥，验证首个验证码后才启用
+func (s *TwoFactorService) BeginSetup(user *models.User) (*TwoFactorSetup, error) {
+	twoFactor, err := s.repo.Get(user.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get two-factor config: %w", err)
+	}
+	if twoFactor == nil {
+		twoFactor = &models.UserTwoFactor{UserID: user.ID}
+	} else if twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = 0
+	if err := s.repo.Save(twoFactor); err != nil {
+		return nil, fmt.Errorf("failed to save two-factor config: %w", err)
+	}
+
+	return &TwoFactorSetup{
+		Secret:          secret,
+		ProvisioningURI: TOTPProvisioningURI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+// Enable 验证首个验证码并启用两步验证，返回恢复码明文（只显示这一次）
+func (s *TwoFactorService) Enable(userID uint, code string) ([]string, error) {
+	twoFactor, err := s.repo.Get(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTwoFactorNotSetup
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor config: %w", err)
+	}
+	if twoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := ValidateTOTP(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(userID, step, hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor: %w", err)
+	}
+	return codes, nil
+}
+
+// Verify 校验 TOTP 验证码或恢复码
+// TOTP 验证码在同一时间步内只能使用一次，恢复码只能使用一次
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	twoFactor, err := s.repo.Get(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get two-factor config: %w", err)
+	}
+	if !twoFactor.Enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := ValidateTOTP(twoFactor.Secret, code, time.Now()); ok {
+		unused, err := s.repo.MarkStepUsed(userID, step)
+		if err != nil {
+			return fmt.Errorf("failed to record two-factor code: %w", err)
+		}
+		if !unused {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+	used, err := s.repo.UseRecoveryCode(userID, hashRecoveryCode(normalized))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// Disable 用户关闭两步验证，需要验证码确认；角色策略要求时不允许关闭
+func (s *TwoFactorService) Disable(user *models.User, code string) error {
+	if RoleRequiresTwoFactor(user) {
+		return ErrTwoFactorRequired
+	}
+	if err := s.Verify(user.ID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(user.ID)
+}
+
+// RegenerateRecoveryCodes 验证码确认后重新生成恢复码，原有恢复码失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// Reset 管理员重置用户的两步验证（如用户丢失设备），用户下次登录时按角色策略重新绑定
+func (s *TwoFactorService) Reset(userID uint) error {
+	return s.repo.Delete(userID)
+}
+
+// recordFailure 记录挑战令牌的验证失败，超过次数后返回 true
+func (s *TwoFactorService) recordFailure(challengeID string, expiresAt time.Time) bool {
+	s.attemptsMu.Lock()
+	defer s.attemptsMu.Unlock()
+
+	now := time.Now()
+	for id, attempts := range s.attempts {
+		if now.After(attempts.expiresAt) {
+			delete(s.attempts, id)
+		}
+	}
+
+	attempts, ok := s.attempts[challengeID]
+	if !ok {
+		attempts = &challengeAttempts{expiresAt: expiresAt}
+		s.attempts[challengeID] = attempts
+	}
+	attempts.count++
+	return attempts.count >= twoFactorMaxAttempts
+}
+
+// exhausted 判断挑战令牌是否已用完验证次数
+func (s *TwoFactorService) exhausted(challengeID string) bool {
+	s.attemptsMu.Lock()
+	defer s.attemptsMu.Unlock()
+	attempts, ok := s.attempts[challengeID]
+	return ok && attempts.count >= twoFactorMaxAttempts
+}
+
+// generateRecoveryCodes 生成恢复码明文（xxxxx-xxxxx 格式）及其哈希
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 去除恢复码中的分隔符和空格并转换为小写
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashRecoveryCode 计算恢复码的 SHA-256 哈希
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// ========== 登录流程 ==========
+
+// SetTwoFactorService 设置两步验证服务，设置后已启用两步验证或角色要求两步验证的用户登录时需要第二步验证
+func (s *AuthService) SetTwoFactorService(twoFactor *TwoFactorService) {
+	s.twoFactor = twoFactor
+}
+
+// twoFactorChallenge 检查用户是否需要两步验证，需要时返回挑战响应
+func (s *AuthService) twoFactorChallenge(user *models.User) (*LoginResponse, error) {
+	if s.twoFactor == nil {
+		return nil, nil
+	}
+
+	enabled, err := s.twoFactor.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	purpose := ChallengePurposeVerify
+	if !enabled {
+		if !RoleRequiresTwoFactor(user) {
+			return nil, nil
+		}
+		purpose = ChallengePurposeEnroll
+	}
+
+	token, _, err := s.jwtManager.GenerateChallengeToken(user.ID, purpose, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate challenge token")
+	}
+	s.logLoginEvent(user.ID, user.Username, "two_factor_challenge", purpose)
+
+	return &LoginResponse{
+		TwoFactorRequired:      enabled,
+		TwoFactorSetupRequired: !enabled,
+		ChallengeToken:         token,
+		ExpiresAt:              time.Now().Add(twoFactorChallengeTTL),
+	}, nil
+}
+
+// challengeUser 验证挑战令牌并获取有效用户
+func (s *AuthService) challengeUser(challengeToken, purpose string) (*models.User, *ChallengeClaims, error) {
+	if s.twoFactor == nil {
+		return nil, nil, ErrTwoFactorServiceDisabled
+	}
+	claims, err := s.jwtManager.ValidateChallengeToken(challengeToken, purpose)
+	if err != nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	if s.twoFactor.exhausted(claims.ID) {
+		return nil, nil, ErrTooManyTwoFactorAttempts
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidChallenge
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, nil, errors.New("user account is not active")
+	}
+	return user, claims, nil
+}
+
+// codeFailure 记录验证码错误，超过次数后挑战令牌失效；错误同时计入登录保护的失败次数
+func (s *AuthService) codeFailure(user *models.User, claims *ChallengeClaims, ipAddress string, err error) error {
+	if !errors.Is(err, ErrInvalidTwoFactorCode) {
+		return err
+	}
+	s.logLoginEvent(user.ID, user.Username, "login_failed", "invalid two-factor code")
+	s.recordLoginFailure(user.Username, ipAddress)
+	if s.twoFactor.recordFailure(claims.ID, claims.ExpiresAt.Time) {
+		return ErrTooManyTwoFactorAttempts
+	}
+	return err
+}
+
+// LoginTwoFactor 登录第二步：校验 TOTP 验证码或恢复码后完成登录
+func (s *AuthService) LoginTwoFactor(req *TwoFactorLoginRequest) (*LoginResponse, error) {
+	user, claims, err := s.challengeUser(req.ChallengeToken, ChallengePurposeVerify)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginThrottle(user.Username, req.IPAddress); err != nil {
+		return nil, err
+	}
+	if err := s.twoFactor.Verify(user.ID, req.Code); err != nil {
+		return nil, s.codeFailure(user, claims, req.IPAddress, err)
+	}
+	return s.completeLogin(user, req.IPAddress, req.UserAgent)
+}
+
+// LoginTwoFactorSetup 登录时按角色策略开始绑定两步验证
+func (s *AuthService) LoginTwoFactorSetup(challengeToken string) (*TwoFactorSetup, error) {
+	user, _, err := s.challengeUser(challengeToken, ChallengePurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactor.BeginSetup(user)
+}
+
+// LoginTwoFactorEnable 登录时完成两步验证绑定并登录，响应中包含恢复码
+func (s *AuthService) LoginTwoFactorEnable(req *TwoFactorLoginRequest) (*LoginResponse, error) {
+	user, claims, err := s.challengeUser(req.ChallengeToken, ChallengePurposeEnroll)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkLoginThrottle(user.Username, req.IPAddress); err != nil {
+		return nil, err
+	}
+	codes, err := s.twoFactor.Enable(user.ID, req.Code)
+	if err != nil {
+		return nil, s.codeFailure(user, claims, req.IPAddress, err)
+	}
+	s.logLoginEvent(user.ID, user.Username, "two_factor_enabled", "")
+
+	response, err := s.completeLogin(user, req.IPAddress, req.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	response.RecoveryCodes = codes
+	return response, nil
+}
+
+// ========== 用户自助管理 ==========
+
+// twoFactorUser 获取用户，用于两步验证管理
+func (s *AuthService) twoFactorUser(userID uint) (*models.User, error) {
+	if s.twoFactor == nil {
+		return nil, ErrTwoFactorServiceDisabled
+	}
+	return s.userRepo.GetByID(userID)
+}
+
+// GetTwoFactorStatus 获取用户两步验证状态
+func (s *AuthService) GetTwoFactorStatus(userID uint) (*TwoFactorStatus, error) {
+	user, err := s.twoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactor.Status(user)
+}
+
+// BeginTwoFactorSetup 开始绑定两步验证
+func (s *AuthService) BeginTwoFactorSetup(userID uint) (*TwoFactorSetup, error) {
+	user, err := s.twoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactor.BeginSetup(user)
+}
+
+// EnableTwoFactor 验证首个验证码并启用两步验证，返回恢复码
+func (s *AuthService) EnableTwoFactor(userID uint, code string) ([]string, error) {
+	user, err := s.twoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := s.twoFactor.Enable(user.ID, code)
+	if err == nil {
+		s.logLoginEvent(user.ID, user.Username, "two_factor_enabled", "")
+	}
+	return codes, err
+}
+
+// DisableTwoFactor 关闭两步验证
+func (s *AuthService) DisableTwoFactor(userID uint, code string) error {
+	user, err := s.twoFactorUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.twoFactor.Disable(user, code); err != nil {
+		return err
+	}
+	s.logLoginEvent(user.ID, user.Username, "two_factor_disabled", "")
+	return nil
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (s *AuthService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	if _, err := s.twoFactorUser(userID); err != nil {
+		return nil, err
+	}
+	return s.twoFactor.RegenerateRecoveryCodes(userID, code)
+}
+
+// ResetTwoFactor 管理员重置用户的两步验证
+func (s *AuthService) ResetTwoFactor(userID uint) error {
+	user, err := s.twoFactorUser(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.twoFactor.Reset(user.ID); err != nil {
+		return fmt.Errorf("failed to reset two-factor: %w", err)
+	}
+	s.logLoginEvent(user.ID, user.Username, "two_factor_reset", "by admin")
+	return nil
+}
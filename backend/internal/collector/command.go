@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/proxy"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxCommandOutputSize 单条命令 stdout/stderr 保留的最大字节数
+const maxCommandOutputSize = 64 * 1024
+
+// routerOSErrorPrefixes RouterOS 命令出错时的输出前缀（RouterOS SSH 出错时退出码仍为 0）
+var routerOSErrorPrefixes = []string{
+	"failure:",
+	"syntax error",
+	"bad command name",
+	"expected end of command",
+	"input does not match any value",
+	"no such item",
+	"invalid value",
+	"ambiguous value",
+}
+
+// CommandResult 远程命令执行结果
+type CommandResult struct {
+	Stdout     string
+	Stderr     string
+	ExitStatus int
+	Truncated  bool // 输出超过上限被截断
+}
+
+// ConnectWithDialer 通过指定的 Dialer（设备代理）建立 SSH 连接，dialer 为空时直接连接
+func (c *SSHCollector) ConnectWithDialer(dialer proxy.Dialer, ip string, port int, username, password string) (*ssh.Client, error) {
+	if dialer == nil {
+		return c.Connect(ip, port, username, password)
+	}
+
+	config := &ssh.ClientConfig{
+		User: username,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(password),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         c.Timeout,
+	}
+
+	address := fmt.Sprintf("%s:%d", ip, port)
+	conn, err := dialWithTimeout(dialer, address, c.Timeout)
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+
+	// 代理连接不受 ClientConfig.Timeout 约束，握手期间设置截止时间
+	conn.SetDeadline(time.Now().Add(c.Timeout))
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		return nil, c.wrapError(err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// dialWithTimeout 通过代理拨号，超时后放弃（迟到的连接会被关闭）
+func dialWithTimeout(dialer proxy.Dialer, address string, timeout time.Duration) (net.Conn, error) {
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan dialResult, 1)
+	go func() {
+		conn, err := dialer.Dial("tcp", address)
+		done <- dialResult{conn: conn, err: err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.conn, result.err
+	case <-timer.C:
+		go func() {
+			if result := <-done; result.conn != nil {
+				result.conn.Close()
+			}
+		}()
+		return nil, fmt.Errorf("dial %s: timeout", address)
+	}
+}
+
+// RunCommand 执行命令并分别采集 stdout、stderr 和退出码
+// 命令以非零退出码结束不视为错误；只有连接、会话失败或 ctx 取消时返回错误
+func (c *SSHCollector) RunCommand(ctx context.Context, client *ssh.Client, cmd string) (*CommandResult, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("创建会话失败: %w", err)
+	}
+	defer session.Close()
+
+	stdout := &limitedBuffer{limit: maxCommandOutputSize}
+	stderr := &limitedBuffer{limit: maxCommandOutputSize}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(cmd)
+	}()
+
+	var runErr error
+	select {
+	case runErr = <-done:
+	case <-ctx.Done():
+		session.Close()
+		<-done
+		return nil, ctx.Err()
+	}
+
+	result := &CommandResult{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+	if runErr != nil {
+		var exitErr *ssh.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return nil, fmt.Errorf("执行命令失败: %w", runErr)
+		}
+		result.ExitStatus = exitErr.ExitStatus()
+	}
+	return result, nil
+}
+
+// RouterOSCommandError 从 RouterOS 命令输出中识别错误信息，无错误返回空字符串
+func RouterOSCommandError(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		lower := strings.ToLower(line)
+		for _, prefix := range routerOSErrorPrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return line
+			}
+		}
+	}
+	return ""
+}
+
+// limitedBuffer 超过上限后丢弃后续输出的缓冲区
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write 写入数据，超出上限的部分被丢弃但不返回错误，以免中断远程命令
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// String 返回缓冲内容
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ExportMikroTikConfig 通过 SSH 执行 /export 获取 RouterOS 配置
+// RouterOS API 不支持 /export，只能通过 SSH 获取；导出头部带有导出时间，需去掉以免每次都产生差异
+func (c *SSHCollector) ExportMikroTikConfig(client *ssh.Client) (string, error) {
+	output, err := c.runCommand(client, "/export terse")
+	if err != nil {
+		return "", fmt.Errorf("导出配置失败: %w", err)
+	}
+	config := NormalizeMikroTikExport(output)
+	if config == "" {
+		return "", fmt.Errorf("导出配置为空")
+	}
+	return config, nil
+}
+
+// NormalizeMikroTikExport 规范化 /export 输出：统一换行符、去掉时间戳注释和首尾空行
+func NormalizeMikroTikExport(output string) string {
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+
+	lines := strings.Split(output, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \r")
+		// 形如 "# 2024-01-15 10:00:00 by RouterOS 7.13.2" 或 "# jan/15/2024 10:00:00 by RouterOS 6.49.7"
+		if strings.HasPrefix(line, "#") && strings.Contains(line, " by RouterOS ") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	config := strings.Trim(strings.Join(kept, "\n"), "\n")
+	if config == "" {
+		return ""
+	}
+	return config + "\n"
+}
+
+// CollectLinuxFiles 通过 SSH 读取 Linux 设备上的配置文件，按路径顺序拼接
+// 路径支持 * ? [] 通配符；每个文件以 "### 路径" 开头；不存在或无权限读取的文件只记录路径，全部读取失败时返回错误
+func (c *SSHCollector) CollectLinuxFiles(client *ssh.Client, paths []string) (string, error) {
+	if len(paths) == 0 {
+		return "", fmt.Errorf("未配置需要备份的文件")
+	}
+
+	var builder strings.Builder
+	collected := 0
+	for _, path := range c.expandLinuxPaths(client, paths) {
+		output, err := c.runCommand(client, "cat -- "+shellQuote(path))
+		if err != nil {
+			fmt.Fprintf(&builder, "### %s (unreadable)\n", path)
+			continue
+		}
+		collected++
+		fmt.Fprintf(&builder, "### %s\n", path)
+		builder.WriteString(output)
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			builder.WriteString("\n")
+		}
+	}
+
+	if collected == 0 {
+		return "", fmt.Errorf("所有配置文件均无法读取")
+	}
+	return builder.String(), nil
+}
+
+// globPathPattern 允许在远端 shell 中展开的通配符路径
+var globPathPattern = regexp.MustCompile(`^[A-Za-z0-9_./*?\[\]-]+$`)
+
+// expandLinuxPaths 在设备上展开通配符路径，无匹配的通配符路径被忽略
+func (c *SSHCollector) expandLinuxPaths(client *ssh.Client, paths []string) []string {
+	expanded := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if !strings.ContainsAny(path, "*?[") || !globPathPattern.MatchString(path) {
+			expanded = append(expanded, path)
+			continue
+		}
+
+		output, err := c.runCommand(client, fmt.Sprintf(`for f in %s; do [ -f "$f" ] && echo "$f"; done`, path))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				expanded = append(expanded, line)
+			}
+		}
+	}
+	return expanded
+}
+
+// shellQuote 使用单引号转义 shell 参数
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -25,14 +26,22 @@ var testDevice = struct {
 	Password: "927528",
 }
 
-// TestScriptGenerator_GenerateMikroTikSimpleScript 测试脚本生成
-func TestScriptGenerator_GenerateMikroTikSimpleScript(t *testing.T) {
+// requireTestDevice 需要真实设备的测试只在设置了 NMP_TEST_DEVICE 环境变量时运行
+func requireTestDevice(t *testing.T) {
+	t.Helper()
+	if os.Getenv("NMP_TEST_DEVICE") == "" {
+		t.Skipf("未设置 NMP_TEST_DEVICE，跳过需要测试设备 %s 的测试", testDevice.IP)
+	}
+}
+
+// TestScriptGenerator_GenerateMikroTikScript 测试脚本生成
+func TestScriptGenerator_GenerateMikroTikScript(t *testing.T) {
 	generator := NewScriptGenerator("http://localhost:8080")
 
 	config := &ScriptConfig{
 		DeviceID:      1,
 		DeviceIP:      "10.10.10.254",
-		ServerURL:     "http://localhost:8080/api/push/metrics",
+		ServerURL:     "http://localhost:8080",
 		IntervalMs:    1000,
 		PushBatchSize: 10,
 		ScriptName:    "nmp-collector",
@@ -44,11 +53,11 @@ func TestScriptGenerator_GenerateMikroTikSimpleScript(t *testing.T) {
 		},
 	}
 
-	script := generator.GenerateMikroTikSimpleScript(config)
+	script := generator.GenerateMikroTikScript(config)
 
 	assert.NotEmpty(t, script, "生成的脚本不应为空")
 	assert.Contains(t, script, "10.10.10.254", "脚本应包含设备IP")
-	assert.Contains(t, script, "http://localhost:8080/api/push/metrics", "脚本应包含服务器URL")
+	assert.Contains(t, script, "http://localhost:8080/api/push/metrics", "脚本应包含推送地址")
 	assert.Contains(t, script, "ether1", "脚本应包含接口名称")
 	assert.Contains(t, script, "ether2", "脚本应包含接口名称")
 	assert.Contains(t, script, "8.8.8.8", "脚本应包含Ping目标")
@@ -57,8 +66,8 @@ func TestScriptGenerator_GenerateMikroTikSimpleScript(t *testing.T) {
 	t.Logf("生成的脚本:\n%s", script)
 }
 
-// TestScriptGenerator_GenerateMikroTikScheduler 测试调度器命令生成
-func TestScriptGenerator_GenerateMikroTikScheduler(t *testing.T) {
+// TestScriptGenerator_GenerateDeployCommands 测试部署命令生成
+func TestScriptGenerator_GenerateDeployCommands(t *testing.T) {
 	generator := NewScriptGenerator("http://localhost:8080")
 
 	config := &ScriptConfig{
@@ -67,36 +76,35 @@ func TestScriptGenerator_GenerateMikroTikScheduler(t *testing.T) {
 		SchedulerName: "nmp-scheduler",
 	}
 
-	cmd := generator.GenerateMikroTikScheduler(config)
+	commands := generator.GenerateDeployCommands(config)
+	require.NotEmpty(t, commands, "部署命令不应为空")
 
-	assert.NotEmpty(t, cmd, "调度器命令不应为空")
-	assert.Contains(t, cmd, "nmp-scheduler", "命令应包含调度器名称")
-	assert.Contains(t, cmd, "nmp-collector", "命令应包含脚本名称")
-	assert.Contains(t, cmd, "00:00:01", "命令应包含间隔时间")
+	scheduler := commands[len(commands)-1]
+	assert.Contains(t, scheduler, "nmp-scheduler", "调度器命令应包含调度器名称")
+	assert.Contains(t, scheduler, "nmp-collector_launcher", "调度器应运行启动脚本")
+	assert.Contains(t, scheduler, "start-time=startup", "调度器应在设备启动时运行")
 
-	t.Logf("调度器命令: %s", cmd)
+	t.Logf("调度器命令: %s", scheduler)
 }
 
-// TestScriptGenerator_msToRouterOSInterval 测试时间间隔转换
-func TestScriptGenerator_msToRouterOSInterval(t *testing.T) {
+// TestScriptGenerator_Interval 测试采集间隔换算为秒
+func TestScriptGenerator_Interval(t *testing.T) {
 	generator := NewScriptGenerator("")
 
 	tests := []struct {
 		ms       int
 		expected string
 	}{
-		{500, "00:00:01"},    // 小于1秒，返回1秒
-		{1000, "00:00:01"},   // 1秒
-		{5000, "00:00:05"},   // 5秒
-		{60000, "00:01:00"},  // 1分钟
-		{3600000, "01:00:00"}, // 1小时
-		{3661000, "01:01:01"}, // 1小时1分1秒
+		{0, ":local intv 1;"},     // 未设置时默认 1 秒
+		{1000, ":local intv 1;"},  // 1秒
+		{5000, ":local intv 5;"},  // 5秒
+		{60000, ":local intv 60;"}, // 1分钟
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.expected, func(t *testing.T) {
-			result := generator.msToRouterOSInterval(tt.ms)
-			assert.Equal(t, tt.expected, result, "%d ms 应转换为 %s", tt.ms, tt.expected)
+			script := generator.GenerateMikroTikScript(&ScriptConfig{IntervalMs: tt.ms})
+			assert.Contains(t, script, tt.expected, "%d ms 应换算为 %s", tt.ms, tt.expected)
 		})
 	}
 }
@@ -105,6 +113,8 @@ func TestScriptGenerator_msToRouterOSInterval(t *testing.T) {
 // Feature: device-monitoring
 // Validates: Requirements 4.1, 4.2, 4.3
 func TestDeployer_DeployToMikroTik(t *testing.T) {
+	requireTestDevice(t)
+
 	deployer := NewDeployer("http://localhost:8080")
 
 	config := &ScriptConfig{
@@ -161,6 +171,8 @@ func TestDeployer_DeployToMikroTik(t *testing.T) {
 // Feature: device-monitoring
 // Validates: Requirements 4.4
 func TestDeployer_RemoveFromMikroTik(t *testing.T) {
+	requireTestDevice(t)
+
 	deployer := NewDeployer("http://localhost:8080")
 
 	// 先部署一个测试脚本
@@ -217,6 +229,8 @@ func TestDeployer_RemoveFromMikroTik(t *testing.T) {
 // Feature: device-monitoring
 // Validates: Requirements 4.6, 4.7
 func TestDeployer_EnableDisableScheduler(t *testing.T) {
+	requireTestDevice(t)
+
 	deployer := NewDeployer("http://localhost:8080")
 
 	// 先部署一个测试脚本
@@ -304,6 +318,8 @@ func TestDeployer_EnableDisableScheduler(t *testing.T) {
 
 // TestDeployer_GetScriptStatus 测试获取脚本状态
 func TestDeployer_GetScriptStatus(t *testing.T) {
+	requireTestDevice(t)
+
 	deployer := NewDeployer("http://localhost:8080")
 
 	// 测试不存在的脚本
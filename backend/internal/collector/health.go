@@ -0,0 +1,304 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 硬件健康传感器类型
+const (
+	HealthSensorTemperature = "temperature" // °C
+	HealthSensorVoltage     = "voltage"     // V
+	HealthSensorFan         = "fan"         // RPM
+	HealthSensorPower       = "power"       // W
+	HealthSensorCurrent     = "current"     // A
+	HealthSensorState       = "state"       // 1 正常，0 故障（电源、风扇状态）
+)
+
+// HealthSensor 归一化后的硬件健康传感器读数
+// 名称与厂商无关：CPU 温度统一为 cpu，主板温度为 board，电源为 psu1、psu2，风扇为 fan1、fan2
+type HealthSensor struct {
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	Value float64 `json:"value"`
+}
+
+// HealthSensorUnit 返回传感器类型的单位
+func HealthSensorUnit(sensorType string) string {
+	switch sensorType {
+	case HealthSensorTemperature:
+		return "C"
+	case HealthSensorVoltage:
+		return "V"
+	case HealthSensorFan:
+		return "RPM"
+	case HealthSensorPower:
+		return "W"
+	case HealthSensorCurrent:
+		return "A"
+	default:
+		return ""
+	}
+}
+
+// routerOSHealthSkipped RouterOS /system health 中的配置项（非传感器读数）
+var routerOSHealthSkipped = []string{"threshold", "overtemp", "target", "min-speed", "full-speed", "fan-mode", "fan-switch", "use-fan", "active-fan"}
+
+// NormalizeRouterOSHealth 归一化 RouterOS /system health 的一项读数
+// unit 为 RouterOS 7 的 type 字段（C、F、V、RPM、W、A），RouterOS 6 没有该字段时按名称判断；无法识别的项返回 false
+func NormalizeRouterOSHealth(name, value, unit string) (HealthSensor, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+	unit = strings.ToUpper(strings.TrimSpace(unit))
+	if name == "" || value == "" {
+		return HealthSensor{}, false
+	}
+	for _, keyword := range routerOSHealthSkipped {
+		if strings.Contains(name, keyword) {
+			return HealthSensor{}, false
+		}
+	}
+
+	sensor := HealthSensor{}
+	component := name
+	switch {
+	case strings.HasSuffix(name, "-state") || strings.HasSuffix(name, "-status"):
+		sensor.Type = HealthSensorState
+		component = name[:strings.LastIndex(name, "-")]
+	case unit == "C" || unit == "F" || strings.Contains(name, "temperature"):
+		sensor.Type = HealthSensorTemperature
+		component = strings.Replace(strings.Replace(name, "-temperature", "", 1), "temperature", "", 1)
+		// 不带前缀的 temperature 为主板温度
+		if strings.Trim(component, "0123456789") == "" {
+			component = "board" + component
+		}
+	case unit == "RPM" || strings.HasSuffix(name, "-speed"):
+		sensor.Type = HealthSensorFan
+		component = strings.TrimSuffix(name, "-speed")
+	case unit == "V" || strings.Contains(name, "voltage"):
+		sensor.Type = HealthSensorVoltage
+		component = strings.TrimSuffix(strings.TrimSuffix(name, "voltage"), "-")
+	case unit == "W" || strings.HasSuffix(name, "-consumption") || strings.HasSuffix(name, "power"):
+		sensor.Type = HealthSensorPower
+		component = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "-consumption"), "power"), "-")
+		if component == "" {
+			component = "system"
+		}
+	case unit == "A" || strings.HasSuffix(name, "current"):
+		sensor.Type = HealthSensorCurrent
+		component = strings.TrimSuffix(strings.TrimSuffix(name, "current"), "-")
+	default:
+		return HealthSensor{}, false
+	}
+	if component == "" {
+		// 不带前缀的电压/电流为输入电源
+		component = "input"
+	}
+	sensor.Name = normalizeSensorName(component)
+
+	if sensor.Type == HealthSensorState {
+		state, ok := parseHealthState(value)
+		if !ok {
+			return HealthSensor{}, false
+		}
+		sensor.Value = state
+		return sensor, true
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimRight(value, "CFVRPMWA "), 64)
+	if err != nil {
+		return HealthSensor{}, false
+	}
+	if unit == "F" {
+		number = (number - 32) * 5 / 9
+	}
+	sensor.Value = number
+	return sensor, true
+}
+
+// parseHealthState 将状态文本转换为 1（正常）或 0（故障）
+func parseHealthState(value string) (float64, bool) {
+	switch strings.ToLower(value) {
+	case "ok", "true", "yes", "on", "good", "normal":
+		return 1, true
+	case "fail", "failed", "false", "no", "off", "error", "bad", "fault":
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// normalizeSensorName 将传感器名称转换为小写下划线格式
+func normalizeSensorName(name string) string {
+	var sb strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			underscore = false
+		} else if !underscore && sb.Len() > 0 {
+			sb.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "_")
+}
+
+// linuxSensorTypes lm-sensors 子特性前缀对应的传感器类型
+var linuxSensorTypes = map[string]string{
+	"temp":  HealthSensorTemperature,
+	"in":    HealthSensorVoltage,
+	"fan":   HealthSensorFan,
+	"power": HealthSensorPower,
+	"curr":  HealthSensorCurrent,
+}
+
+// ParseLinuxSensors 解析 lm-sensors "sensors -j" 的输出
+// 名称为 芯片_特性（如 nct6775_fan2），CPU 封装温度（coretemp Package id 0、k10temp Tctl）统一为 cpu
+func ParseLinuxSensors(output string) ([]HealthSensor, error) {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return nil, nil
+	}
+	var chips map[string]map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &chips); err != nil {
+		return nil, fmt.Errorf("解析 sensors 输出失败: %w", err)
+	}
+
+	// 按名称顺序遍历，名称重复时保留第一个，保证结果稳定
+	seen := make(map[string]bool)
+	var sensors []HealthSensor
+	chipNames := make([]string, 0, len(chips))
+	for chip := range chips {
+		chipNames = append(chipNames, chip)
+	}
+	sort.Strings(chipNames)
+	for _, chip := range chipNames {
+		chipName := chip
+		if i := strings.Index(chip, "-"); i > 0 {
+			chipName = chip[:i]
+		}
+		features := chips[chip]
+		featureNames := make([]string, 0, len(features))
+		for feature := range features {
+			featureNames = append(featureNames, feature)
+		}
+		sort.Strings(featureNames)
+		for _, feature := range featureNames {
+			var subfeatures map[string]float64
+			if err := json.Unmarshal(features[feature], &subfeatures); err != nil {
+				continue // Adapter 等非读数字段
+			}
+			sensor, ok := linuxSensorReading(subfeatures)
+			if !ok {
+				continue
+			}
+			sensor.Name = linuxSensorName(chipName, feature)
+			if seen[sensor.Name+"|"+sensor.Type] {
+				continue
+			}
+			seen[sensor.Name+"|"+sensor.Type] = true
+			sensors = append(sensors, sensor)
+		}
+	}
+
+	sortHealthSensors(sensors)
+	return sensors, nil
+}
+
+// linuxSensorReading 从子特性中取当前读数（如 temp1_input、power1_average）
+func linuxSensorReading(subfeatures map[string]float64) (HealthSensor, bool) {
+	for key, value := range subfeatures {
+		if !strings.HasSuffix(key, "_input") && !strings.HasSuffix(key, "_average") {
+			continue
+		}
+		prefix := strings.TrimRightFunc(key[:strings.Index(key, "_")], unicode.IsDigit)
+		sensorType, ok := linuxSensorTypes[prefix]
+		if !ok {
+			continue
+		}
+		return HealthSensor{Type: sensorType, Value: value}, true
+	}
+	return HealthSensor{}, false
+}
+
+// linuxSensorName 生成 Linux 传感器的归一化名称
+func linuxSensorName(chip, feature string) string {
+	switch {
+	case chip == "coretemp" && strings.HasPrefix(feature, "Package id "):
+		if id := strings.TrimPrefix(feature, "Package id "); id != "0" {
+			return "cpu" + id
+		}
+		return "cpu"
+	case chip == "k10temp" && feature == "Tctl":
+		return "cpu"
+	case chip == "cpu_thermal" || chip == "cpu-thermal":
+		return "cpu"
+	}
+	return normalizeSensorName(chip + "_" + feature)
+}
+
+// ParseLinuxThermalZones 解析 /sys/class/thermal 的输出（每行 类型 毫摄氏度），用于未安装 lm-sensors 的设备
+func ParseLinuxThermalZones(output string) []HealthSensor {
+	seen := make(map[string]bool)
+	var sensors []HealthSensor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		milli, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		name := normalizeSensorName(fields[0])
+		switch name {
+		case "x86_pkg_temp", "cpu_thermal", "soc_thermal":
+			name = "cpu"
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		sensors = append(sensors, HealthSensor{Name: name, Type: HealthSensorTemperature, Value: milli / 1000})
+	}
+	sortHealthSensors(sensors)
+	return sensors
+}
+
+// sortHealthSensors 按类型和名称排序
+func sortHealthSensors(sensors []HealthSensor) {
+	sort.Slice(sensors, func(i, j int) bool {
+		if sensors[i].Type != sensors[j].Type {
+			return sensors[i].Type < sensors[j].Type
+		}
+		return sensors[i].Name < sensors[j].Name
+	})
+}
+
+// GetLinuxHealthSensors 通过 SSH 获取 Linux 硬件健康传感器
+// 优先使用 lm-sensors（sensors -j），未安装时读取 /sys/class/thermal 的温度
+func (c *SSHCollector) GetLinuxHealthSensors(client *ssh.Client) ([]HealthSensor, error) {
+	output, err := c.runCommand(client, "sensors -j 2>/dev/null")
+	if err == nil && strings.HasPrefix(strings.TrimSpace(output), "{") {
+		sensors, err := ParseLinuxSensors(output)
+		if err != nil {
+			return nil, err
+		}
+		if len(sensors) > 0 {
+			return sensors, nil
+		}
+	}
+
+	output, err = c.runCommand(client, `for z in /sys/class/thermal/thermal_zone*; do [ -r "$z/temp" ] && echo "$(cat "$z/type") $(cat "$z/temp")"; done 2>/dev/null; true`)
+	if err != nil {
+		return nil, fmt.Errorf("获取传感器信息失败: %w", err)
+	}
+	return ParseLinuxThermalZones(output), nil
+}
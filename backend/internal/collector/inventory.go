@@ -0,0 +1,278 @@
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-routeros/routeros/v3"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	terseNamePattern    = regexp.MustCompile(`(?:^|\s)name="?([^"\s]+)"?`)
+	terseVersionPattern = regexp.MustCompile(`(?:^|\s)version="?([^"\s]+)"?`)
+	terseFlagsPattern   = regexp.MustCompile(`^\s*\d+\s+([A-Z]+)\s`)
+)
+
+// InventoryInfo 设备硬件和软件清单
+type InventoryInfo struct {
+	Vendor          string        `json:"vendor"`
+	Model           string        `json:"model"`
+	BoardName       string        `json:"board_name"`
+	SerialNumber    string        `json:"serial_number"`
+	Architecture    string        `json:"architecture"`
+	CPUModel        string        `json:"cpu_model"`
+	CPUCount        int           `json:"cpu_count"`
+	MemoryTotal     int64         `json:"memory_total"` // 字节
+	OSVersion       string        `json:"os_version"`   // RouterOS 版本或 Linux 发行版版本
+	FirmwareVersion string        `json:"firmware_version"`
+	UpgradeFirmware string        `json:"upgrade_firmware"` // RouterBOOT 可升级到的版本
+	Kernel          string        `json:"kernel"`
+	Distro          string        `json:"distro"`
+	Packages        []PackageInfo `json:"packages"`
+	Disks           []DiskInfo    `json:"disks"`
+}
+
+// PackageInfo 已安装软件包
+type PackageInfo struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// DiskInfo 磁盘信息
+type DiskInfo struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"` // 字节
+}
+
+// GetInventory 通过 API 获取 RouterOS 设备清单
+// CHR 等非 RouterBOARD 设备没有序列号和固件版本
+func (c *RouterOSCollector) GetInventory(client *routeros.Client) (*InventoryInfo, error) {
+	inventory := &InventoryInfo{Vendor: "MikroTik"}
+
+	reply, err := client.Run("/system/resource/print")
+	if err != nil {
+		return nil, fmt.Errorf("获取系统资源失败: %w", err)
+	}
+	if len(reply.Re) > 0 {
+		applyMikroTikResource(inventory, reply.Re[0].Map)
+	}
+
+	if reply, err := client.Run("/system/routerboard/print"); err == nil && len(reply.Re) > 0 {
+		applyMikroTikRouterboard(inventory, reply.Re[0].Map)
+	}
+
+	if reply, err := client.Run("/system/package/print"); err == nil {
+		for _, re := range reply.Re {
+			if re.Map["name"] == "" {
+				continue
+			}
+			inventory.Packages = append(inventory.Packages, PackageInfo{
+				Name:     re.Map["name"],
+				Version:  re.Map["version"],
+				Disabled: re.Map["disabled"] == "true",
+			})
+		}
+	}
+
+	return inventory, nil
+}
+
+// GetMikroTikInventory 通过 SSH 获取 RouterOS 设备清单
+func (c *SSHCollector) GetMikroTikInventory(client *ssh.Client) (*InventoryInfo, error) {
+	inventory := &InventoryInfo{Vendor: "MikroTik"}
+
+	output, err := c.runCommand(client, "/system resource print")
+	if err != nil {
+		return nil, fmt.Errorf("获取系统资源失败: %w", err)
+	}
+	resource := parseMikroTikPrint(output)
+	// print 输出中内存和磁盘带单位（如 1024.0MiB）
+	for _, key := range []string{"total-memory", "total-hdd-space"} {
+		if value, ok := resource[key]; ok {
+			resource[key] = strconv.FormatInt(c.parseMemoryValue(value), 10)
+		}
+	}
+	applyMikroTikResource(inventory, resource)
+
+	if output, err := c.runCommand(client, "/system routerboard print"); err == nil {
+		applyMikroTikRouterboard(inventory, parseMikroTikPrint(output))
+	}
+
+	if output, err := c.runCommand(client, "/system package print terse"); err == nil {
+		inventory.Packages = ParseMikroTikPackages(output)
+	}
+
+	return inventory, nil
+}
+
+// GetLinuxInventory 通过 SSH 获取 Linux 设备清单
+// 序列号等 DMI 信息通常只有 root 可读，读取失败时留空
+func (c *SSHCollector) GetLinuxInventory(client *ssh.Client) (*InventoryInfo, error) {
+	inventory := &InventoryInfo{}
+
+	output, err := c.runCommand(client, "uname -r; uname -m")
+	if err != nil {
+		return nil, fmt.Errorf("获取内核信息失败: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	inventory.Kernel = strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		inventory.Architecture = strings.TrimSpace(lines[1])
+	}
+
+	if output, err := c.runCommand(client, "cat /etc/os-release 2>/dev/null"); err == nil {
+		release := parseOSRelease(output)
+		inventory.Distro = release["PRETTY_NAME"]
+		if inventory.Distro == "" {
+			inventory.Distro = strings.TrimSpace(release["NAME"] + " " + release["VERSION"])
+		}
+		inventory.OSVersion = release["VERSION_ID"]
+	}
+
+	dmi := map[string]*string{
+		"sys_vendor":     &inventory.Vendor,
+		"product_name":   &inventory.Model,
+		"board_name":     &inventory.BoardName,
+		"product_serial": &inventory.SerialNumber,
+		"bios_version":   &inventory.FirmwareVersion,
+	}
+	for name, target := range dmi {
+		if output, err := c.runCommand(client, "cat /sys/class/dmi/id/"+name+" 2>/dev/null"); err == nil {
+			*target = strings.TrimSpace(output)
+		}
+	}
+	// 树莓派等 ARM 设备没有 DMI，从设备树读取型号
+	if inventory.Model == "" {
+		if output, err := c.runCommand(client, "tr -d '\\0' < /proc/device-tree/model 2>/dev/null"); err == nil {
+			inventory.Model = strings.TrimSpace(output)
+		}
+	}
+
+	if output, err := c.runCommand(client, "nproc; grep -m1 'model name' /proc/cpuinfo; grep MemTotal /proc/meminfo"); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, "model name"):
+				if _, value, ok := strings.Cut(line, ":"); ok {
+					inventory.CPUModel = strings.TrimSpace(value)
+				}
+			case strings.HasPrefix(line, "MemTotal:"):
+				fields := strings.Fields(line)
+				if len(fields) >= 2 {
+					kb, _ := strconv.ParseInt(fields[1], 10, 64)
+					inventory.MemoryTotal = kb * 1024
+				}
+			default:
+				if n, err := strconv.Atoi(line); err == nil {
+					inventory.CPUCount = n
+				}
+			}
+		}
+	}
+
+	if output, err := c.runCommand(client, "lsblk -b -d -n -o NAME,SIZE,TYPE 2>/dev/null"); err == nil {
+		inventory.Disks = ParseLsblkDisks(output)
+	}
+
+	return inventory, nil
+}
+
+// applyMikroTikResource 从 /system resource 结果填充清单
+func applyMikroTikResource(inventory *InventoryInfo, resource map[string]string) {
+	inventory.OSVersion = resource["version"]
+	inventory.Architecture = resource["architecture-name"]
+	inventory.BoardName = resource["board-name"]
+	inventory.CPUModel = resource["cpu"]
+	inventory.CPUCount, _ = strconv.Atoi(resource["cpu-count"])
+	inventory.MemoryTotal, _ = strconv.ParseInt(resource["total-memory"], 10, 64)
+	if size, err := strconv.ParseInt(resource["total-hdd-space"], 10, 64); err == nil && size > 0 {
+		inventory.Disks = []DiskInfo{{Name: "system", Size: size}}
+	}
+}
+
+// applyMikroTikRouterboard 从 /system routerboard 结果填充清单
+func applyMikroTikRouterboard(inventory *InventoryInfo, board map[string]string) {
+	if board["routerboard"] != "yes" && board["routerboard"] != "true" {
+		return
+	}
+	if model := board["model"]; model != "" {
+		inventory.Model = model
+	}
+	if name := board["board-name"]; name != "" {
+		inventory.BoardName = name
+	}
+	inventory.SerialNumber = board["serial-number"]
+	inventory.FirmwareVersion = board["current-firmware"]
+	inventory.UpgradeFirmware = board["upgrade-firmware"]
+}
+
+// parseMikroTikPrint 解析 RouterOS "key: value" 格式的 print 输出
+func parseMikroTikPrint(output string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t") {
+			continue
+		}
+		values[key] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return values
+}
+
+// ParseMikroTikPackages 解析 "/system package print terse" 输出，标志 X 表示已禁用
+func ParseMikroTikPackages(output string) []PackageInfo {
+	var packages []PackageInfo
+	for _, line := range strings.Split(output, "\n") {
+		name := terseNamePattern.FindStringSubmatch(line)
+		if len(name) < 2 {
+			continue
+		}
+		pkg := PackageInfo{Name: name[1]}
+		if version := terseVersionPattern.FindStringSubmatch(line); len(version) > 1 {
+			pkg.Version = version[1]
+		}
+		if flags := terseFlagsPattern.FindStringSubmatch(line); len(flags) > 1 {
+			pkg.Disabled = strings.Contains(flags[1], "X")
+		}
+		packages = append(packages, pkg)
+	}
+	return packages
+}
+
+// ParseLsblkDisks 解析 "lsblk -b -d -n -o NAME,SIZE,TYPE" 输出，只保留物理磁盘
+func ParseLsblkDisks(output string) []DiskInfo {
+	var disks []DiskInfo
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] != "disk" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		disks = append(disks, DiskInfo{Name: fields[0], Size: size})
+	}
+	return disks
+}
+
+// parseOSRelease 解析 /etc/os-release
+func parseOSRelease(output string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		values[key] = strings.Trim(value, `"'`)
+	}
+	return values
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"nmp-platform/internal/proxy"
+
 	"github.com/go-routeros/routeros/v3"
 )
 
@@ -26,8 +28,21 @@ type SystemInfo struct {
 
 // InterfaceInfo 接口信息结构
 type InterfaceInfo struct {
-	Name   string `json:"name"`
-	Status string `json:"status"` // up, down
+	Name    string `json:"name"`
+	Status  string `json:"status"`             // up, down
+	IfIndex int    `json:"if_index,omitempty"` // SNMP ifIndex，未知时为 0
+}
+
+// InterfaceTraffic 接口流量、包速率及错误/丢包累计计数
+type InterfaceTraffic struct {
+	RxRate    int64 `json:"rx_rate"`    // 接收速率 bps
+	TxRate    int64 `json:"tx_rate"`    // 发送速率 bps
+	RxPackets int64 `json:"rx_packets"` // 接收包速率 pps
+	TxPackets int64 `json:"tx_packets"` // 发送包速率 pps
+	RxErrors  int64 `json:"rx_errors"`  // 接收错误累计数
+	TxErrors  int64 `json:"tx_errors"`  // 发送错误累计数
+	RxDrops   int64 `json:"rx_drops"`   // 接收丢包累计数
+	TxDrops   int64 `json:"tx_drops"`   // 发送丢包累计数
 }
 
 // RouterOSCollector MikroTik RouterOS API 采集器
@@ -57,6 +72,33 @@ func (c *RouterOSCollector) Connect(ip string, port int, username, password stri
 	return client, nil
 }
 
+// ConnectWithDialer 通过指定的 Dialer（设备代理）连接 RouterOS 设备，dialer 为空时直接连接
+func (c *RouterOSCollector) ConnectWithDialer(dialer proxy.Dialer, ip string, port int, username, password string) (*routeros.Client, error) {
+	if dialer == nil {
+		return c.Connect(ip, port, username, password)
+	}
+
+	conn, err := dialWithTimeout(dialer, fmt.Sprintf("%s:%d", ip, port), c.Timeout)
+	if err != nil {
+		return nil, c.wrapError(err)
+	}
+
+	// 代理连接没有登录超时，登录期间设置截止时间
+	conn.SetDeadline(time.Now().Add(c.Timeout))
+	client, err := routeros.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, c.wrapError(err)
+	}
+	if err := client.Login(username, password); err != nil {
+		client.Close()
+		return nil, c.wrapError(err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return client, nil
+}
+
 // TestConnection 测试连接
 func (c *RouterOSCollector) TestConnection(ip string, port int, username, password string) error {
 	client, err := c.Connect(ip, port, username, password)
@@ -154,6 +196,11 @@ func (c *RouterOSCollector) GetInterfaces(client *routeros.Client) ([]InterfaceI
 			Name:   re.Map["name"],
 			Status: "down",
 		}
+
+		// RouterOS 的内部编号（如 *1A）与 SNMP ifIndex 一致
+		if id, err := strconv.ParseInt(strings.TrimPrefix(re.Map[".id"], "*"), 16, 64); err == nil {
+			iface.IfIndex = int(id)
+		}
 		
 		// 检查接口状态
 		if running, ok := re.Map["running"]; ok && running == "true" {
@@ -27,6 +27,8 @@ var testMikroTikDevice = struct {
 
 // TestRouterOSCollector_Connect 测试 RouterOS API 连接
 func TestRouterOSCollector_Connect(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewRouterOSCollector(10 * time.Second)
 	
 	client, err := collector.Connect(
@@ -44,6 +46,8 @@ func TestRouterOSCollector_Connect(t *testing.T) {
 
 // TestRouterOSCollector_TestConnection 测试连接测试功能
 func TestRouterOSCollector_TestConnection(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewRouterOSCollector(10 * time.Second)
 	
 	err := collector.TestConnection(
@@ -58,6 +62,8 @@ func TestRouterOSCollector_TestConnection(t *testing.T) {
 
 // TestRouterOSCollector_TestConnection_WrongPassword 测试错误密码
 func TestRouterOSCollector_TestConnection_WrongPassword(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewRouterOSCollector(5 * time.Second)
 	
 	err := collector.TestConnection(
@@ -89,6 +95,8 @@ func TestRouterOSCollector_TestConnection_WrongPort(t *testing.T) {
 // Feature: device-monitoring, Property 6: 主动采集数据完整性
 // Validates: Requirements 7.3, 7.4
 func TestRouterOSCollector_GetSystemInfo(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewRouterOSCollector(10 * time.Second)
 	
 	client, err := collector.Connect(
@@ -131,6 +139,8 @@ func TestRouterOSCollector_GetSystemInfo(t *testing.T) {
 
 // TestRouterOSCollector_GetInterfaces 测试获取接口列表
 func TestRouterOSCollector_GetInterfaces(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewRouterOSCollector(10 * time.Second)
 	
 	client, err := collector.Connect(
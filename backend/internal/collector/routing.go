@@ -0,0 +1,352 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-routeros/routeros/v3"
+	"golang.org/x/crypto/ssh"
+)
+
+// ErrFRRUnavailable 设备未安装 FRR 或 vtysh 不可用
+var ErrFRRUnavailable = errors.New("FRR vtysh is not available")
+
+// RoutingNeighborInfo 路由协议邻居（BGP 对等体或 OSPF 邻居）
+type RoutingNeighborInfo struct {
+	Protocol    string `json:"protocol"` // bgp 或 ospf
+	Instance    string `json:"instance"` // RouterOS 实例/区域，FRR 地址族
+	Neighbor    string `json:"neighbor"` // BGP 对端地址，OSPF 邻居 router-id
+	Name        string `json:"name"`     // BGP 会话名或描述
+	Address     string `json:"address"`
+	Interface   string `json:"interface"`
+	RemoteAS    int64  `json:"remote_as"`
+	LocalAS     int64  `json:"local_as"`
+	State       string `json:"state"`        // 小写，如 established、active、full、2-way
+	Established bool   `json:"established"`  // BGP 为 Established，OSPF 为 Full
+	Uptime      int64  `json:"uptime"`       // 秒
+	PrefixCount int64  `json:"prefix_count"` // 收到的前缀数
+}
+
+// GetRoutingNeighbors 通过 API 获取 RouterOS 的 BGP 和 OSPF 邻居
+// RouterOS 7 使用 /routing/bgp/session，RouterOS 6 使用 /routing/bgp/peer
+// 设备不支持的命令（未安装路由功能）视为没有邻居，连接错误则返回错误，避免误判邻居全部断开
+func (c *RouterOSCollector) GetRoutingNeighbors(client *routeros.Client) ([]RoutingNeighborInfo, error) {
+	var neighbors []RoutingNeighborInfo
+
+	reply, err := client.Run("/routing/bgp/session/print")
+	if err == nil {
+		for _, re := range reply.Re {
+			neighbors = append(neighbors, c.routerOSBGPSession(re.Map))
+		}
+	} else if isRouterOSDeviceError(err) {
+		reply, err = client.Run("/routing/bgp/peer/print")
+		if err == nil {
+			for _, re := range reply.Re {
+				neighbors = append(neighbors, c.routerOSBGPPeer(re.Map))
+			}
+		}
+	}
+	if err != nil && !isRouterOSDeviceError(err) {
+		return nil, fmt.Errorf("获取 BGP 邻居失败: %w", err)
+	}
+
+	reply, err = client.Run("/routing/ospf/neighbor/print")
+	if err != nil && !isRouterOSDeviceError(err) {
+		return nil, fmt.Errorf("获取 OSPF 邻居失败: %w", err)
+	}
+	if err == nil {
+		for _, re := range reply.Re {
+			neighbors = append(neighbors, c.routerOSOSPFNeighbor(re.Map))
+		}
+	}
+
+	return neighbors, nil
+}
+
+// isRouterOSDeviceError 判断是否为设备返回的错误（如命令不存在），而非连接错误
+func isRouterOSDeviceError(err error) bool {
+	var deviceErr *routeros.DeviceError
+	return errors.As(err, &deviceErr)
+}
+
+// routerOSBGPSession 解析 RouterOS 7 BGP 会话
+func (c *RouterOSCollector) routerOSBGPSession(m map[string]string) RoutingNeighborInfo {
+	info := RoutingNeighborInfo{
+		Protocol:    "bgp",
+		Instance:    m["instance"],
+		Neighbor:    stripRouterOSPort(m["remote.address"]),
+		Name:        m["name"],
+		Address:     stripRouterOSPort(m["remote.address"]),
+		Established: m["established"] == "true",
+		Uptime:      c.parseRouterOSDuration(m["uptime"]),
+	}
+	info.RemoteAS, _ = strconv.ParseInt(m["remote.as"], 10, 64)
+	info.LocalAS, _ = strconv.ParseInt(m["local.as"], 10, 64)
+	info.PrefixCount, _ = strconv.ParseInt(m["prefix-count"], 10, 64)
+	info.State = strings.ToLower(m["state"])
+	if info.State == "" {
+		// 会话列表只包含已建立或正在建立的会话，没有 state 字段时按 established 标志判断
+		info.State = "idle"
+		if info.Established {
+			info.State = "established"
+		}
+	}
+	return info
+}
+
+// routerOSBGPPeer 解析 RouterOS 6 BGP 对等体
+func (c *RouterOSCollector) routerOSBGPPeer(m map[string]string) RoutingNeighborInfo {
+	info := RoutingNeighborInfo{
+		Protocol: "bgp",
+		Instance: m["instance"],
+		Neighbor: m["remote-address"],
+		Name:     m["name"],
+		Address:  m["remote-address"],
+		State:    strings.ToLower(m["state"]),
+		Uptime:   c.parseRouterOSDuration(m["uptime"]),
+	}
+	if m["disabled"] == "true" {
+		info.State = "disabled"
+	}
+	info.Established = info.State == "established"
+	info.RemoteAS, _ = strconv.ParseInt(m["remote-as"], 10, 64)
+	info.PrefixCount, _ = strconv.ParseInt(m["prefix-count"], 10, 64)
+	return info
+}
+
+// routerOSOSPFNeighbor 解析 RouterOS OSPF 邻居
+func (c *RouterOSCollector) routerOSOSPFNeighbor(m map[string]string) RoutingNeighborInfo {
+	instance := m["instance"]
+	if area := m["area"]; area != "" {
+		instance = strings.TrimPrefix(instance+"/"+area, "/")
+	}
+	info := RoutingNeighborInfo{
+		Protocol:  "ospf",
+		Instance:  instance,
+		Neighbor:  m["router-id"],
+		Address:   m["address"],
+		Interface: m["interface"],
+		State:     NormalizeOSPFState(m["state"]),
+		Uptime:    c.parseRouterOSDuration(m["adjacency"]),
+	}
+	if info.Neighbor == "" {
+		info.Neighbor = info.Address
+	}
+	info.Established = info.State == "full"
+	return info
+}
+
+// parseRouterOSDuration 解析 RouterOS 时长，支持 1w2d3h4m5s 和 1d02:03:04 两种格式
+func (c *RouterOSCollector) parseRouterOSDuration(value string) int64 {
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		start := strings.LastIndexAny(value[:i], "wd") + 1
+		parts := strings.Split(value[start:], ":")
+		var seconds int64
+		for _, part := range parts {
+			n, _ := strconv.ParseFloat(part, 64)
+			seconds = seconds*60 + int64(n)
+		}
+		return c.parseUptime(value[:start]) + seconds
+	}
+	return c.parseUptime(value)
+}
+
+// stripRouterOSPort 去掉 RouterOS 7 地址中的端口（如 10.0.0.1:179 或 [fe80::1]:179）
+func stripRouterOSPort(address string) string {
+	if strings.HasPrefix(address, "[") {
+		if end := strings.Index(address, "]"); end > 0 {
+			return address[1:end]
+		}
+	}
+	if strings.Count(address, ":") == 1 {
+		return address[:strings.Index(address, ":")]
+	}
+	return address
+}
+
+// NormalizeOSPFState 将 OSPF 状态转换为小写并去掉角色后缀（如 Full/DR -> full）
+func NormalizeOSPFState(state string) string {
+	state = strings.ToLower(strings.TrimSpace(state))
+	if i := strings.Index(state, "/"); i >= 0 {
+		state = state[:i]
+	}
+	return state
+}
+
+// GetFRRRoutingNeighbors 通过 SSH 使用 vtysh 获取 Linux FRR 的 BGP 和 OSPF 邻居
+// 未安装 FRR 或未运行对应守护进程时返回 ErrFRRUnavailable
+func (c *SSHCollector) GetFRRRoutingNeighbors(client *ssh.Client) ([]RoutingNeighborInfo, error) {
+	bgpOutput, bgpErr := c.runCommand(client, frrCommand("show bgp summary json"))
+	ospfOutput, ospfErr := c.runCommand(client, frrCommand("show ip ospf neighbor json"))
+	if bgpErr != nil && ospfErr != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFRRUnavailable, bgpErr)
+	}
+
+	// 对应守护进程未运行时 vtysh 输出提示文本而非 JSON，视为没有邻居
+	var neighbors []RoutingNeighborInfo
+	if bgpErr == nil && strings.HasPrefix(strings.TrimSpace(bgpOutput), "{") {
+		bgp, err := ParseFRRBGPSummary(bgpOutput)
+		if err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, bgp...)
+	}
+	if ospfErr == nil && strings.HasPrefix(strings.TrimSpace(ospfOutput), "{") {
+		ospf, err := ParseFRROSPFNeighbors(ospfOutput)
+		if err != nil {
+			return nil, err
+		}
+		neighbors = append(neighbors, ospf...)
+	}
+	return neighbors, nil
+}
+
+// frrCommand 构造 vtysh 命令，普通用户无权限时尝试免密 sudo
+func frrCommand(command string) string {
+	return fmt.Sprintf("vtysh -c '%[1]s' 2>/dev/null || sudo -n vtysh -c '%[1]s'", command)
+}
+
+// frrBGPPeer FRR "show bgp summary json" 中的对等体
+type frrBGPPeer struct {
+	RemoteAS       json.Number `json:"remoteAs"`
+	LocalAS        json.Number `json:"localAs"`
+	State          string      `json:"state"`
+	PeerUptimeMsec int64       `json:"peerUptimeMsec"`
+	PfxRcd         int64       `json:"pfxRcd"`
+	Description    string      `json:"desc"`
+	Hostname       string      `json:"hostname"`
+	IDType         string      `json:"idType"`
+}
+
+// frrBGPAddressFamily FRR "show bgp summary json" 中的地址族
+type frrBGPAddressFamily struct {
+	AS    json.Number           `json:"as"`
+	Peers map[string]frrBGPPeer `json:"peers"`
+}
+
+// ParseFRRBGPSummary 解析 "show bgp summary json"，同一对端在每个地址族各记录一条
+func ParseFRRBGPSummary(output string) ([]RoutingNeighborInfo, error) {
+	output = strings.TrimSpace(output)
+	if output == "" || output == "{}" {
+		return nil, nil
+	}
+	var families map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &families); err != nil {
+		return nil, fmt.Errorf("解析 BGP 摘要失败: %w", err)
+	}
+
+	afiNames := make([]string, 0, len(families))
+	for name := range families {
+		afiNames = append(afiNames, name)
+	}
+	sort.Strings(afiNames)
+
+	var neighbors []RoutingNeighborInfo
+	for _, afi := range afiNames {
+		var family frrBGPAddressFamily
+		if err := json.Unmarshal(families[afi], &family); err != nil || family.Peers == nil {
+			continue
+		}
+		localAS, _ := family.AS.Int64()
+
+		addresses := make([]string, 0, len(family.Peers))
+		for address := range family.Peers {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+		for _, address := range addresses {
+			peer := family.Peers[address]
+			info := RoutingNeighborInfo{
+				Protocol:    "bgp",
+				Instance:    afi,
+				Neighbor:    address,
+				Name:        peer.Description,
+				Address:     address,
+				LocalAS:     localAS,
+				State:       strings.ToLower(peer.State),
+				Uptime:      peer.PeerUptimeMsec / 1000,
+				PrefixCount: peer.PfxRcd,
+			}
+			if info.Name == "" {
+				info.Name = peer.Hostname
+			}
+			// 接口邻居（unnumbered）以接口名作为键
+			if peer.IDType == "interface" {
+				info.Interface = address
+			}
+			info.RemoteAS, _ = peer.RemoteAS.Int64()
+			if as, err := peer.LocalAS.Int64(); err == nil && as > 0 {
+				info.LocalAS = as
+			}
+			info.Established = info.State == "established"
+			neighbors = append(neighbors, info)
+		}
+	}
+	return neighbors, nil
+}
+
+// frrOSPFNeighbor FRR "show ip ospf neighbor json" 中的邻居，不同版本字段名不同
+type frrOSPFNeighbor struct {
+	NbrState     string `json:"nbrState"`
+	State        string `json:"state"`
+	IfaceAddress string `json:"ifaceAddress"`
+	Address      string `json:"address"`
+	IfaceName    string `json:"ifaceName"`
+	AreaID       string `json:"areaId"`
+	UpTimeInMsec int64  `json:"upTimeInMsec"`
+}
+
+// ParseFRROSPFNeighbors 解析 "show ip ospf neighbor json"
+func ParseFRROSPFNeighbors(output string) ([]RoutingNeighborInfo, error) {
+	output = strings.TrimSpace(output)
+	if output == "" || output == "{}" {
+		return nil, nil
+	}
+	var result struct {
+		Neighbors map[string][]frrOSPFNeighbor `json:"neighbors"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("解析 OSPF 邻居失败: %w", err)
+	}
+
+	routerIDs := make([]string, 0, len(result.Neighbors))
+	for routerID := range result.Neighbors {
+		routerIDs = append(routerIDs, routerID)
+	}
+	sort.Strings(routerIDs)
+
+	var neighbors []RoutingNeighborInfo
+	for _, routerID := range routerIDs {
+		for _, nbr := range result.Neighbors[routerID] {
+			state := nbr.NbrState
+			if state == "" {
+				state = nbr.State
+			}
+			address := nbr.IfaceAddress
+			if address == "" {
+				address = nbr.Address
+			}
+			// ifaceName 形如 eth0:10.0.0.1
+			iface := nbr.IfaceName
+			if i := strings.Index(iface, ":"); i >= 0 {
+				iface = iface[:i]
+			}
+			info := RoutingNeighborInfo{
+				Protocol:  "ospf",
+				Instance:  nbr.AreaID,
+				Neighbor:  routerID,
+				Address:   address,
+				Interface: iface,
+				State:     NormalizeOSPFState(state),
+				Uptime:    nbr.UpTimeInMsec / 1000,
+			}
+			info.Established = info.State == "full"
+			neighbors = append(neighbors, info)
+		}
+	}
+	return neighbors, nil
+}
@@ -26,12 +26,35 @@ LauncherName  string
 SchedulerName string
 Interfaces    []string
 PingTargets   []PingTargetConfig
+Modules       CollectorModules
+}
+
+// CollectorModules 可选的服务指标采集模块（按设备启用），结果以 services 字段随数据点推送
+type CollectorModules struct {
+PPP          bool   // PPP 活动会话数（总数和 PPPoE）
+DHCP         bool   // 每个 DHCP 服务器的租约数
+Wireless     bool   // 每个无线接口的注册客户端数和信号强度
+WirelessMenu string // wireless（旧驱动）或 wifi（RouterOS 7 wifi 包），为空按 wireless 处理
+Queues       bool   // 简单队列实时速率
+MaxQueues    int    // 每次最多采集的队列数，<=0 按 30 处理
+Health       bool   // /system health 温度、电压、风扇和电源状态
+IntervalSec  int    // 采集间隔（秒），<=0 按 30 处理；租约统计等命令开销较大，不随每个采集周期执行
+}
+
+// Enabled 是否启用了任一模块
+func (m CollectorModules) Enabled() bool {
+return m.PPP || m.DHCP || m.Wireless || m.Queues || m.Health
 }
 
 type PingTargetConfig struct {
 TargetAddress   string
 TargetName      string
 SourceInterface string
+Count           int  // 每组发送包数，<=0 按 1 处理
+PacketSize      int  // 包大小（字节），0 使用设备默认值
+IntervalMs      int  // 组内发包间隔（毫秒），<=0 按 100 处理
+DSCP            int  // DSCP 标记，0 不设置
+VoIP            bool // 是否为 VoIP 目标，服务端据此计算 MOS
 }
 
 type ScriptGenerator struct {
@@ -54,42 +77,39 @@ sb.WriteString(fmt.Sprintf(":local batch %d;\n", config.PushBatchSize))
 sb.WriteString(fmt.Sprintf(":local intv %d;\n", config.IntervalMs/1000))
 sb.WriteString(fmt.Sprintf(":local url \"%s/api/push/metrics\";\n", config.ServerURL))
 sb.WriteString(fmt.Sprintf(":local key \"%s\";\n", config.DeviceIP))
+g.writeClockFunc(&sb)
+g.writeLatencyFunc(&sb)
+// nmpNum 将可能缺失的计数器值（部分接口类型没有该字段）转换为数字
+sb.WriteString(":local nmpNum do={:if ([:typeof $1] = \"num\") do={:return $1};:return 0};\n")
+if config.Modules.Enabled() {
+sb.WriteString(":local svcWait 0;\n")
+}
 sb.WriteString(":while (true) do={\n")
+sb.WriteString(":local sts [$nmpClock];\n")
 sb.WriteString(":local ifData \"\";\n")
+sb.WriteString(":local ifStats \"\";\n")
 for _, iface := range config.Interfaces {
 varName := sanitizeVarName(iface)
-sb.WriteString(fmt.Sprintf(`:global nmpRx%s;:global nmpTx%s;:set nmpRx%s 0;:set nmpTx%s 0;`, varName, varName, varName, varName))
-sb.WriteString(fmt.Sprintf(`:do {/interface monitor-traffic "%s" once do={:global nmpRx%s;:set nmpRx%s $"rx-bits-per-second";:global nmpTx%s;:set nmpTx%s $"tx-bits-per-second"}} on-error={};`, iface, varName, varName, varName, varName))
-sb.WriteString(fmt.Sprintf(`:set ifData ($ifData . "\"%s\":{\"rx_rate\":" . $nmpRx%s . ",\"tx_rate\":" . $nmpTx%s . "},");`, iface, varName, varName))
+sb.WriteString(fmt.Sprintf(`:global nmpRx%s;:global nmpTx%s;:global nmpRxP%s;:global nmpTxP%s;:set nmpRx%s 0;:set nmpTx%s 0;:set nmpRxP%s 0;:set nmpTxP%s 0;`, varName, varName, varName, varName, varName, varName, varName, varName))
+sb.WriteString(fmt.Sprintf(`:do {/interface monitor-traffic "%s" once do={:global nmpRx%s;:set nmpRx%s $"rx-bits-per-second";:global nmpTx%s;:set nmpTx%s $"tx-bits-per-second";:global nmpRxP%s;:set nmpRxP%s $"rx-packets-per-second";:global nmpTxP%s;:set nmpTxP%s $"tx-packets-per-second"}} on-error={};`, iface, varName, varName, varName, varName, varName, varName, varName, varName))
+// 错误和丢包为 /interface print stats 中的累计计数，由服务端换算为速率；running 用于记录链路状态变化
+sb.WriteString(`:set ifStats "";`)
+sb.WriteString(fmt.Sprintf(`:do {:local st [/interface get [find name="%s"]];:set ifStats (",\"rx_errors\":" . [$nmpNum ($st->"rx-error")] . ",\"tx_errors\":" . [$nmpNum ($st->"tx-error")] . ",\"rx_drops\":" . [$nmpNum ($st->"rx-drop")] . ",\"tx_drops\":" . [$nmpNum ($st->"tx-drop")] . ",\"running\":" . ($st->"running"))} on-error={};`, iface))
+sb.WriteString(fmt.Sprintf(`:set ifData ($ifData . "\"%s\":{\"rx_rate\":" . $nmpRx%s . ",\"tx_rate\":" . $nmpTx%s . ",\"rx_packets\":" . $nmpRxP%s . ",\"tx_packets\":" . $nmpTxP%s . $ifStats . "},");`, iface, varName, varName, varName, varName))
 sb.WriteString("\n")
 }
 sb.WriteString(":if ([:len $ifData] > 0) do={:set ifData [:pick $ifData 0 ([:len $ifData]-1)]};\n")
 sb.WriteString(":local pingData \"\";\n")
 for _, target := range config.PingTargets {
-srcParam := ""
-srcJson := ""
-if target.SourceInterface != "" {
-srcParam = fmt.Sprintf(` interface="%s"`, target.SourceInterface)
-srcJson = target.SourceInterface
-}
-sb.WriteString(":do {\n")
-sb.WriteString(fmt.Sprintf(`:local r [/ping %s count=1%s as-value];`+"\n", target.TargetAddress, srcParam))
-sb.WriteString(`:local t ($r->"time");` + "\n")
-sb.WriteString(`:if ([:typeof $t]="time") do={` + "\n")
-sb.WriteString(":local ts [:tostr $t];\n")
-sb.WriteString(":local dotPos [:find $ts \".\" -1];\n")
-sb.WriteString(":local usStr [:pick $ts ($dotPos+1) [:len $ts]];\n")
-sb.WriteString(":local us [:tonum $usStr];\n")
-sb.WriteString(fmt.Sprintf(`:set pingData ($pingData . "{\"target\":\"%s\",\"src_iface\":\"%s\",\"latency\":" . $us . ",\"status\":\"up\"},");`+"\n", target.TargetAddress, srcJson))
-sb.WriteString("} else={\n")
-sb.WriteString(fmt.Sprintf(`:set pingData ($pingData . "{\"target\":\"%s\",\"src_iface\":\"%s\",\"latency\":0,\"status\":\"down\"},");`+"\n", target.TargetAddress, srcJson))
-sb.WriteString("};\n")
-sb.WriteString("} on-error={\n")
-sb.WriteString(fmt.Sprintf(`:set pingData ($pingData . "{\"target\":\"%s\",\"src_iface\":\"%s\",\"latency\":0,\"status\":\"down\"},");`+"\n", target.TargetAddress, srcJson))
-sb.WriteString("};\n")
+g.writePingBurst(&sb, target)
 }
 sb.WriteString(":if ([:len $pingData] > 0) do={:set pingData [:pick $pingData 0 ([:len $pingData]-1)]};\n")
-sb.WriteString(`:local pt ("{\"ts\":0,\"interfaces\":{" . $ifData . "},\"pings\":[" . $pingData . "]}");` + "\n")
+if config.Modules.Enabled() {
+g.writeServiceModules(&sb, config)
+sb.WriteString(`:local pt ("{\"ts\":" . $sts . ",\"interfaces\":{" . $ifData . "},\"pings\":[" . $pingData . "]" . $svc . "}");` + "\n")
+} else {
+sb.WriteString(`:local pt ("{\"ts\":" . $sts . ",\"interfaces\":{" . $ifData . "},\"pings\":[" . $pingData . "]}");` + "\n")
+}
 sb.WriteString(":if ([:len $queue] > 0) do={\n")
 sb.WriteString(`:set queue ($queue . "," . $pt);` + "\n")
 sb.WriteString("} else={\n")
@@ -97,7 +117,7 @@ sb.WriteString(":set queue $pt;\n")
 sb.WriteString("};\n")
 sb.WriteString(":set cnt ($cnt + 1);\n")
 sb.WriteString(":if ($cnt >= $batch) do={\n")
-sb.WriteString(`:local pl ("{\"device_key\":\"" . $key . "\",\"metrics\":[" . $queue . "]}");` + "\n")
+sb.WriteString(`:local pl ("{\"device_key\":\"" . $key . "\",\"sent_ts\":" . [$nmpClock] . ",\"metrics\":[" . $queue . "]}");` + "\n")
 sb.WriteString(":do {\n")
 sb.WriteString(`/tool fetch url=$url http-method=post http-data=$pl http-header-field="Content-Type:application/json" output=none;` + "\n")
 sb.WriteString(":set queue \"\";\n")
@@ -114,6 +134,151 @@ sb.WriteString("};\n")
 return sb.String()
 }
 
+// writeServiceModules 写入可选服务指标模块的采集代码，结果保存在 $svc（形如 ,"services":{...}）
+// 每 IntervalSec 秒采集一次，其余周期 $svc 为空；各模块独立容错，设备缺少对应功能包时跳过
+func (g *ScriptGenerator) writeServiceModules(sb *strings.Builder, config *ScriptConfig) {
+modules := config.Modules
+intervalSec := modules.IntervalSec
+if intervalSec <= 0 {
+intervalSec = 30
+}
+loopSec := config.IntervalMs / 1000
+if loopSec < 1 {
+loopSec = 1
+}
+rounds := intervalSec / loopSec
+if rounds < 1 {
+rounds = 1
+}
+sb.WriteString(":local svc \"\";\n")
+sb.WriteString(":set svcWait ($svcWait - 1);\n")
+sb.WriteString(":if ($svcWait <= 0) do={\n")
+sb.WriteString(fmt.Sprintf(":set svcWait %d;\n", rounds))
+if modules.PPP {
+sb.WriteString(`:do {:set svc ($svc . "\"ppp\":{\"active\":" . [/ppp active print count-only] . ",\"pppoe\":" . [/ppp active print count-only where service=pppoe] . "},")} on-error={};` + "\n")
+}
+if modules.DHCP {
+sb.WriteString(":local dh \"\";\n")
+sb.WriteString(`:do {:foreach s in=[/ip dhcp-server find] do={:local n [/ip dhcp-server get $s name];:set dh ($dh . "{\"server\":\"" . $n . "\",\"bound\":" . [/ip dhcp-server lease print count-only where server=$n status=bound] . ",\"total\":" . [/ip dhcp-server lease print count-only where server=$n] . "},")}} on-error={};` + "\n")
+sb.WriteString(`:if ([:len $dh] > 0) do={:set svc ($svc . "\"dhcp\":[" . [:pick $dh 0 ([:len $dh]-1)] . "],")};` + "\n")
+}
+if modules.Wireless {
+menu, signal := "wireless", "signal-strength"
+if modules.WirelessMenu == "wifi" {
+menu, signal = "wifi", "signal"
+}
+// signal-strength 形如 -65dBm@6Mbps，截取 dBm 之前的数值
+sb.WriteString(":local wl \"\";\n")
+sb.WriteString(fmt.Sprintf(`:do {:foreach w in=[/interface %[1]s find] do={:local wn [/interface %[1]s get $w name];:local c 0;:local sn 0;:local sum 0;:local mn 0;:foreach r in=[/interface %[1]s registration-table find where interface=$wn] do={:set c ($c+1);:local ss [:tostr [/interface %[1]s registration-table get $r %[2]s]];:local p [:find $ss "dBm" -1];:if ([:typeof $p]="num") do={:set ss [:pick $ss 0 $p]};:local sv [:tonum $ss];:if ([:typeof $sv]="num") do={:set sn ($sn+1);:set sum ($sum+$sv);:if ($sn=1 || $sv<$mn) do={:set mn $sv}}};:local avg 0;:if ($sn>0) do={:set avg ($sum/$sn)};:set wl ($wl . "{\"iface\":\"" . $wn . "\",\"clients\":" . $c . ",\"avg_signal\":" . $avg . ",\"min_signal\":" . $mn . "},")}} on-error={};`, menu, signal) + "\n")
+sb.WriteString(`:if ([:len $wl] > 0) do={:set svc ($svc . "\"wireless\":[" . [:pick $wl 0 ([:len $wl]-1)] . "],")};` + "\n")
+}
+if modules.Queues {
+maxQueues := modules.MaxQueues
+if maxQueues <= 0 {
+maxQueues = 30
+}
+// rate 形如 上传/下载（bps）
+sb.WriteString(":local qd \"\";:local qn 0;\n")
+sb.WriteString(fmt.Sprintf(`:do {:foreach q in=[/queue simple find where disabled=no] do={:if ($qn < %d) do={:local r [:tostr [/queue simple get $q rate]];:local sp [:find $r "/" -1];:if ([:typeof $sp]="num") do={:set qd ($qd . "{\"name\":\"" . [/queue simple get $q name] . "\",\"upload_rate\":" . [:tonum [:pick $r 0 $sp]] . ",\"download_rate\":" . [:tonum [:pick $r ($sp+1) [:len $r]]] . "},");:set qn ($qn+1)}}}} on-error={};`, maxQueues) + "\n")
+sb.WriteString(`:if ([:len $qd] > 0) do={:set svc ($svc . "\"queues\":[" . [:pick $qd 0 ([:len $qd]-1)] . "],")};` + "\n")
+}
+if modules.Health {
+// RouterOS 7 每个传感器一项（name/value/type），RouterOS 6 为单项菜单，get 返回 名称=值 数组；名称和单位由服务端归一化
+sb.WriteString(":local hs \"\";\n")
+sb.WriteString(`:do {:foreach h in=[/system health find] do={:set hs ($hs . "{\"name\":\"" . [/system health get $h name] . "\",\"value\":\"" . [/system health get $h value] . "\",\"type\":\"" . [/system health get $h type] . "\"},")}} on-error={:do {:foreach k,v in=[/system health get] do={:set hs ($hs . "{\"name\":\"" . $k . "\",\"value\":\"" . $v . "\"},")}} on-error={}};` + "\n")
+sb.WriteString(`:if ([:len $hs] > 0) do={:set svc ($svc . "\"health\":[" . [:pick $hs 0 ([:len $hs]-1)] . "],")};` + "\n")
+}
+sb.WriteString(`:if ([:len $svc] > 0) do={:set svc (",\"services\":{" . [:pick $svc 0 ([:len $svc]-1)] . "}")};` + "\n")
+sb.WriteString("};\n")
+}
+
+// writeLatencyFunc 写入将 ping 返回的 time 转换为微秒的函数 nmpUs
+// 优先使用 :tonsec（RouterOS 7），否则解析 "hh:mm:ss.ffffff" 字符串
+func (g *ScriptGenerator) writeLatencyFunc(sb *strings.Builder) {
+sb.WriteString(":local nmpUs do={\n")
+sb.WriteString(":local v -1;\n")
+sb.WriteString(":do {:set v ([:tonsec $1] / 1000)} on-error={};\n")
+sb.WriteString(":if ($v < 0) do={\n")
+sb.WriteString(":local s [:tostr $1];:local dp [:find $s \".\" -1];:set v 0;\n")
+// 小数部分补齐到 6 位，前补 1 再减 1000000，避免前导零问题
+sb.WriteString(`:if ([:typeof $dp] = "num") do={:local f [:pick $s ($dp+1) [:len $s]];:while ([:len $f] < 6) do={:set f ($f . "0")};:set v ([:tonum ("1" . [:pick $f 0 6])] - 1000000);:set s [:pick $s 0 $dp]};` + "\n")
+sb.WriteString(":set v ($v + [:tonum [:totime $s]] * 1000000);\n")
+sb.WriteString("};\n")
+sb.WriteString(":return $v;\n")
+sb.WriteString("};\n")
+}
+
+// writePingBurst 写入单个 Ping 目标的探测代码
+// 每个采集周期发送 Count 个包，统计 min/avg/max 延迟（微秒）、抖动和收发包数；
+// 抖动为相邻两个成功响应延迟差的绝对值的平均值
+func (g *ScriptGenerator) writePingBurst(sb *strings.Builder, target PingTargetConfig) {
+count := target.Count
+if count < 1 {
+count = 1
+}
+interval := target.IntervalMs
+if interval <= 0 {
+interval = 100
+}
+params := ""
+if target.PacketSize > 0 {
+params += fmt.Sprintf(" size=%d", target.PacketSize)
+}
+if target.DSCP > 0 {
+params += fmt.Sprintf(" dscp=%d", target.DSCP)
+}
+srcJson := ""
+if target.SourceInterface != "" {
+params += fmt.Sprintf(` interface="%s"`, target.SourceInterface)
+srcJson = target.SourceInterface
+}
+extra := ""
+if target.VoIP {
+extra = `,\"voip\":true`
+}
+sb.WriteString(":do {\n")
+sb.WriteString(":local sent 0;:local recv 0;:local sum 0;:local mn 0;:local mx 0;:local jsum 0;:local prev -1;\n")
+sb.WriteString(fmt.Sprintf(":for i from=1 to=%d do={\n", count))
+sb.WriteString(fmt.Sprintf(`:do {:local r [/ping %s count=1%s as-value];:local t ($r->"time");`, target.TargetAddress, params))
+sb.WriteString(`:if ([:typeof $t]="time") do={:local us [$nmpUs $t];:set recv ($recv+1);:set sum ($sum+$us);:if ($recv=1 || $us<$mn) do={:set mn $us};:if ($us>$mx) do={:set mx $us};:if ($prev>=0) do={:local d ($us-$prev);:if ($d<0) do={:set d (0-$d)};:set jsum ($jsum+$d)};:set prev $us}} on-error={};` + "\n")
+sb.WriteString(":set sent ($sent+1);\n")
+if count > 1 {
+sb.WriteString(fmt.Sprintf(":if ($i<%d) do={:delay %dms};\n", count, interval))
+}
+sb.WriteString("};\n")
+sb.WriteString(":local avg 0;:if ($recv>0) do={:set avg ($sum/$recv)};\n")
+sb.WriteString(":local jit 0;:if ($recv>1) do={:set jit ($jsum/($recv-1))};\n")
+sb.WriteString(`:local st "down";:if ($recv>0) do={:set st "up"};` + "\n")
+sb.WriteString(fmt.Sprintf(`:set pingData ($pingData . "{\"target\":\"%s\",\"src_iface\":\"%s\",\"latency\":" . $avg . ",\"min_latency\":" . $mn . ",\"max_latency\":" . $mx . ",\"jitter\":" . $jit . ",\"sent\":" . $sent . ",\"received\":" . $recv . ",\"status\":\"" . $st . "\"%s},");`+"\n", target.TargetAddress, srcJson, extra))
+sb.WriteString("} on-error={\n")
+sb.WriteString(fmt.Sprintf(`:set pingData ($pingData . "{\"target\":\"%s\",\"src_iface\":\"%s\",\"latency\":0,\"sent\":%d,\"received\":0,\"status\":\"down\"%s},");`+"\n", target.TargetAddress, srcJson, count, extra))
+sb.WriteString("};\n")
+}
+
+// writeClockFunc 写入读取设备时钟的函数 nmpClock，返回 Unix 毫秒时间戳
+// 优先使用 :timestamp（RouterOS 7.10+），否则根据 /system clock 的日期和时间换算；
+// 后者使用设备本地时区，偏差由服务端根据 sent_ts 测量并校正
+func (g *ScriptGenerator) writeClockFunc(sb *strings.Builder) {
+sb.WriteString(":local nmpClock do={\n")
+sb.WriteString(":local ms 0;\n")
+sb.WriteString(":do {:set ms ([:tonsec [:timestamp]] / 1000000)} on-error={};\n")
+sb.WriteString(":if ($ms = 0) do={\n")
+sb.WriteString(":local d [/system clock get date];\n")
+sb.WriteString(":local y 0;:local m 0;:local dd 0;\n")
+// 7.10+ 日期格式为 2024-01-15，旧版本为 jan/15/2024；两位数字前补 1 再减 100，避免前导零问题
+sb.WriteString(`:if ([:pick $d 4 5] = "-") do={:set y [:tonum [:pick $d 0 4]];:set m ([:tonum ("1" . [:pick $d 5 7])] - 100);:set dd ([:tonum ("1" . [:pick $d 8 10])] - 100)} else={:set m ([:find "janfebmaraprmayjunjulaugsepoctnovdec" [:pick $d 0 3] -1] / 3 + 1);:set dd ([:tonum ("1" . [:pick $d 4 6])] - 100);:set y [:tonum [:pick $d 7 11]]};` + "\n")
+// 公历日期换算为距 1970-01-01 的天数
+sb.WriteString(":if ($m <= 2) do={:set y ($y - 1);:set m ($m + 9)} else={:set m ($m - 3)};\n")
+sb.WriteString(":local era ($y / 400);\n")
+sb.WriteString(":local yoe ($y - $era * 400);\n")
+sb.WriteString(":local doe ($yoe * 365 + $yoe / 4 - $yoe / 100 + (153 * $m + 2) / 5 + $dd - 1);\n")
+sb.WriteString(":local days ($era * 146097 + $doe - 719468);\n")
+sb.WriteString(":set ms (($days * 86400 + [:tonum [:totime [/system clock get time]]]) * 1000);\n")
+sb.WriteString("};\n")
+sb.WriteString(":return $ms;\n")
+sb.WriteString("};\n")
+}
+
 func (g *ScriptGenerator) GenerateMikroTikLauncher(config *ScriptConfig) string {
 g.applyDefaults(config)
 var sb strings.Builder
@@ -269,6 +269,10 @@ func (c *SSHCollector) GetLinuxInterfaces(client *ssh.Client) ([]InterfaceInfo,
 			Name:   strings.TrimSpace(parts[1]),
 			Status: "down",
 		}
+		// 行首序号即内核 ifindex，与 SNMP ifIndex 一致
+		if index, err := strconv.Atoi(strings.TrimSpace(parts[0])); err == nil {
+			iface.IfIndex = index
+		}
 
 		// 检查接口状态
 		if strings.Contains(line, "state UP") || strings.Contains(line, ",UP,") {
@@ -283,6 +287,108 @@ func (c *SSHCollector) GetLinuxInterfaces(client *ssh.Client) ([]InterfaceInfo,
 	return interfaces, nil
 }
 
+// procNetDevCounters /proc/net/dev 中单个接口的累计计数
+type procNetDevCounters struct {
+	RxBytes, RxPackets, RxErrors, RxDrops int64
+	TxBytes, TxPackets, TxErrors, TxDrops int64
+}
+
+// GetLinuxInterfaceTraffic 获取 Linux 接口流量
+// 间隔 1 秒读取两次 /proc/net/dev，按实际经过时间计算速率
+func (c *SSHCollector) GetLinuxInterfaceTraffic(client *ssh.Client) (map[string]*InterfaceTraffic, error) {
+	output, err := c.runCommand(client, "date +%s%N; cat /proc/net/dev; echo ---; sleep 1; date +%s%N; cat /proc/net/dev")
+	if err != nil {
+		return nil, fmt.Errorf("获取接口流量失败: %w", err)
+	}
+
+	parts := strings.SplitN(output, "---", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("解析接口流量失败: 输出格式不正确")
+	}
+
+	t1, before := c.parseProcNetDev(parts[0])
+	t2, after := c.parseProcNetDev(parts[1])
+	elapsed := float64(t2-t1) / float64(time.Second)
+	if elapsed <= 0 {
+		return nil, fmt.Errorf("解析接口流量失败: 采样时间无效")
+	}
+
+	rate := func(cur, prev int64) int64 {
+		if cur < prev {
+			return 0
+		}
+		return int64(float64(cur-prev) / elapsed)
+	}
+
+	traffic := make(map[string]*InterfaceTraffic, len(after))
+	for name, cur := range after {
+		prev, ok := before[name]
+		if !ok {
+			continue
+		}
+		traffic[name] = &InterfaceTraffic{
+			RxRate:    rate(cur.RxBytes, prev.RxBytes) * 8,
+			TxRate:    rate(cur.TxBytes, prev.TxBytes) * 8,
+			RxPackets: rate(cur.RxPackets, prev.RxPackets),
+			TxPackets: rate(cur.TxPackets, prev.TxPackets),
+			RxErrors:  cur.RxErrors,
+			TxErrors:  cur.TxErrors,
+			RxDrops:   cur.RxDrops,
+			TxDrops:   cur.TxDrops,
+		}
+	}
+
+	return traffic, nil
+}
+
+// parseProcNetDev 解析 "date +%s%N" 与 /proc/net/dev 的输出
+// 返回采样时间（纳秒）与各接口计数
+func (c *SSHCollector) parseProcNetDev(output string) (int64, map[string]procNetDevCounters) {
+	var ts int64
+	counters := make(map[string]procNetDevCounters)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// 格式: eth0: 1234 10 0 0 0 0 0 0 5678 20 0 0 0 0 0 0
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			if ts == 0 {
+				if v, err := strconv.ParseInt(line, 10, 64); err == nil {
+					ts = v
+				}
+			}
+			continue
+		}
+
+		name := strings.TrimSpace(line[:idx])
+		fields := strings.Fields(line[idx+1:])
+		if len(fields) < 16 || name == "lo" {
+			continue
+		}
+
+		values := make([]int64, 16)
+		for i := 0; i < 16; i++ {
+			values[i], _ = strconv.ParseInt(fields[i], 10, 64)
+		}
+		counters[name] = procNetDevCounters{
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			RxErrors:  values[2],
+			RxDrops:   values[3],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+			TxErrors:  values[10],
+			TxDrops:   values[11],
+		}
+	}
+
+	return ts, counters
+}
+
 // parseMikroTikValue 解析 MikroTik 输出中的值
 func (c *SSHCollector) parseMikroTikValue(output, key string) string {
 	pattern := regexp.MustCompile(fmt.Sprintf(`%s:\s*(.+)`, key))
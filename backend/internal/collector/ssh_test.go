@@ -10,6 +10,8 @@ import (
 
 // TestSSHCollector_Connect 测试 SSH 连接
 func TestSSHCollector_Connect(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewSSHCollector(10 * time.Second)
 
 	client, err := collector.Connect(
@@ -27,6 +29,8 @@ func TestSSHCollector_Connect(t *testing.T) {
 
 // TestSSHCollector_TestConnection 测试连接测试功能
 func TestSSHCollector_TestConnection(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewSSHCollector(10 * time.Second)
 
 	err := collector.TestConnection(
@@ -41,6 +45,8 @@ func TestSSHCollector_TestConnection(t *testing.T) {
 
 // TestSSHCollector_TestConnection_WrongPassword 测试错误密码
 func TestSSHCollector_TestConnection_WrongPassword(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewSSHCollector(5 * time.Second)
 
 	err := collector.TestConnection(
@@ -72,6 +78,8 @@ func TestSSHCollector_TestConnection_WrongPort(t *testing.T) {
 // Feature: device-monitoring, Property 6: 主动采集数据完整性
 // Validates: Requirements 7.3, 7.4
 func TestSSHCollector_GetMikroTikSystemInfo(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewSSHCollector(10 * time.Second)
 
 	client, err := collector.Connect(
@@ -113,6 +121,8 @@ func TestSSHCollector_GetMikroTikSystemInfo(t *testing.T) {
 
 // TestSSHCollector_GetMikroTikInterfaces 测试获取 MikroTik 接口列表（通过 SSH）
 func TestSSHCollector_GetMikroTikInterfaces(t *testing.T) {
+	requireTestDevice(t)
+
 	collector := NewSSHCollector(10 * time.Second)
 
 	client, err := collector.Connect(
@@ -140,6 +150,8 @@ func TestSSHCollector_GetMikroTikInterfaces(t *testing.T) {
 
 // TestSSHCollector_CompareWithAPI 比较 SSH 和 API 采集结果
 func TestSSHCollector_CompareWithAPI(t *testing.T) {
+	requireTestDevice(t)
+
 	// 通过 API 获取系统信息
 	rosCollector := NewRouterOSCollector(10 * time.Second)
 	apiClient, err := rosCollector.Connect(
@@ -0,0 +1,210 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-routeros/routeros/v3"
+)
+
+// 默认的 RouterOS 日志动作名称
+const defaultSyslogActionName = "nmp-syslog"
+
+// SyslogForwardConfig RouterOS 远程日志转发配置
+type SyslogForwardConfig struct {
+	ActionName string   // /system logging action 名称，默认 nmp-syslog
+	Remote     string   // 平台 syslog 接收地址
+	RemotePort int      // 默认 514
+	Topics     []string // 转发的日志主题，默认 info,warning,error,critical
+}
+
+// applyDefaults 填充默认值
+func (c *SyslogForwardConfig) applyDefaults() {
+	if c.ActionName == "" {
+		c.ActionName = defaultSyslogActionName
+	}
+	if c.RemotePort == 0 {
+		c.RemotePort = 514
+	}
+	if len(c.Topics) == 0 {
+		c.Topics = []string{"info", "warning", "error", "critical"}
+	}
+}
+
+// GenerateSyslogCommands 生成配置远程日志转发的 RouterOS 命令
+// 先移除同名动作及其规则，保证重复执行结果一致
+func (g *ScriptGenerator) GenerateSyslogCommands(cfg *SyslogForwardConfig) []string {
+	cfg.applyDefaults()
+	commands := []string{
+		fmt.Sprintf(`/system logging remove [find action="%s"]`, cfg.ActionName),
+		fmt.Sprintf(`/system logging action remove [find name="%s"]`, cfg.ActionName),
+		fmt.Sprintf(`/system logging action add name="%s" target=remote remote=%s remote-port=%d bsd-syslog=yes`, cfg.ActionName, cfg.Remote, cfg.RemotePort),
+	}
+	for _, topic := range cfg.Topics {
+		commands = append(commands, fmt.Sprintf(`/system logging add topics=%s action="%s"`, topic, cfg.ActionName))
+	}
+	return commands
+}
+
+// GenerateSyslogRemoveCommands 生成移除远程日志转发的 RouterOS 命令
+func (g *ScriptGenerator) GenerateSyslogRemoveCommands(actionName string) []string {
+	if actionName == "" {
+		actionName = defaultSyslogActionName
+	}
+	return []string{
+		fmt.Sprintf(`/system logging remove [find action="%s"]`, actionName),
+		fmt.Sprintf(`/system logging action remove [find name="%s"]`, actionName),
+	}
+}
+
+// ConfigureSyslog 配置 MikroTik 设备将日志转发到平台
+// 优先使用 API，失败则尝试 SSH
+func (d *Deployer) ConfigureSyslog(cfg *SyslogForwardConfig, ip string, apiPort, sshPort int, username, password string) *DeployResult {
+	if cfg.Remote == "" {
+		return &DeployResult{
+			Success:      false,
+			Method:       "none",
+			ErrorMessage: "syslog 接收地址不能为空",
+		}
+	}
+	cfg.applyDefaults()
+
+	result := d.configureSyslogViaAPI(cfg, ip, apiPort, username, password)
+	if result.Success {
+		return result
+	}
+
+	sshResult := d.runCommandsViaSSH(d.generator.GenerateSyslogCommands(cfg), ip, sshPort, username, password)
+	if sshResult.Success {
+		sshResult.Message = "通过 SSH 配置日志转发成功"
+		return sshResult
+	}
+
+	return &DeployResult{
+		Success:      false,
+		Method:       "none",
+		Message:      "配置日志转发失败",
+		ErrorMessage: fmt.Sprintf("API 错误: %s; SSH 错误: %s", result.ErrorMessage, sshResult.ErrorMessage),
+	}
+}
+
+// RemoveSyslog 移除 MikroTik 设备上的平台日志转发配置
+func (d *Deployer) RemoveSyslog(actionName, ip string, apiPort, sshPort int, username, password string) *DeployResult {
+	if actionName == "" {
+		actionName = defaultSyslogActionName
+	}
+
+	client, err := d.rosCollector.Connect(ip, apiPort, username, password)
+	if err == nil {
+		defer client.Close()
+		d.removeSyslogViaAPI(client, actionName)
+		return &DeployResult{
+			Success: true,
+			Method:  "api",
+			Message: "通过 API 移除日志转发成功",
+		}
+	}
+
+	result := d.runCommandsViaSSH(d.generator.GenerateSyslogRemoveCommands(actionName), ip, sshPort, username, password)
+	if result.Success {
+		result.Message = "通过 SSH 移除日志转发成功"
+	}
+	return result
+}
+
+// configureSyslogViaAPI 通过 API 配置日志转发
+func (d *Deployer) configureSyslogViaAPI(cfg *SyslogForwardConfig, ip string, port int, username, password string) *DeployResult {
+	client, err := d.rosCollector.Connect(ip, port, username, password)
+	if err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "api",
+			ErrorMessage: err.Error(),
+		}
+	}
+	defer client.Close()
+
+	d.removeSyslogViaAPI(client, cfg.ActionName)
+
+	_, err = client.Run("/system/logging/action/add",
+		fmt.Sprintf("=name=%s", cfg.ActionName),
+		"=target=remote",
+		fmt.Sprintf("=remote=%s", cfg.Remote),
+		fmt.Sprintf("=remote-port=%d", cfg.RemotePort),
+		"=bsd-syslog=yes",
+	)
+	if err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "api",
+			ErrorMessage: fmt.Sprintf("添加日志动作失败: %s", err.Error()),
+		}
+	}
+
+	for _, topic := range cfg.Topics {
+		_, err = client.Run("/system/logging/add",
+			fmt.Sprintf("=topics=%s", topic),
+			fmt.Sprintf("=action=%s", cfg.ActionName),
+		)
+		if err != nil {
+			return &DeployResult{
+				Success:      false,
+				Method:       "api",
+				ErrorMessage: fmt.Sprintf("添加日志规则 %s 失败: %s", topic, err.Error()),
+			}
+		}
+	}
+
+	return &DeployResult{
+		Success: true,
+		Method:  "api",
+		Message: "通过 API 配置日志转发成功",
+	}
+}
+
+// removeSyslogViaAPI 通过 API 移除日志动作及其规则
+func (d *Deployer) removeSyslogViaAPI(client *routeros.Client, actionName string) {
+	reply, err := client.Run("/system/logging/print", fmt.Sprintf("?action=%s", actionName))
+	if err == nil {
+		for _, re := range reply.Re {
+			if id, ok := re.Map[".id"]; ok {
+				client.Run("/system/logging/remove", fmt.Sprintf("=.id=%s", id))
+			}
+		}
+	}
+
+	reply, err = client.Run("/system/logging/action/print", fmt.Sprintf("?name=%s", actionName))
+	if err == nil && len(reply.Re) > 0 {
+		if id, ok := reply.Re[0].Map[".id"]; ok {
+			client.Run("/system/logging/action/remove", fmt.Sprintf("=.id=%s", id))
+		}
+	}
+}
+
+// runCommandsViaSSH 通过 SSH 依次执行命令，忽略移除命令的错误
+func (d *Deployer) runCommandsViaSSH(commands []string, ip string, port int, username, password string) *DeployResult {
+	client, err := d.sshCollector.Connect(ip, port, username, password)
+	if err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "ssh",
+			ErrorMessage: err.Error(),
+		}
+	}
+	defer client.Close()
+
+	for _, cmd := range commands {
+		if _, err := d.runSSHCommand(client, cmd); err != nil && !strings.Contains(cmd, "remove") {
+			return &DeployResult{
+				Success:      false,
+				Method:       "ssh",
+				ErrorMessage: fmt.Sprintf("执行命令失败: %s", err.Error()),
+			}
+		}
+	}
+
+	return &DeployResult{
+		Success: true,
+		Method:  "ssh",
+	}
+}
@@ -0,0 +1,215 @@
+package collector
+
+import (
+	"fmt"
+
+	"github.com/go-routeros/routeros/v3"
+)
+
+// TrafficFlowConfig RouterOS 流量导出（/ip traffic-flow）配置
+type TrafficFlowConfig struct {
+	Target              string // 平台流量采集地址
+	Port                int    // 默认 2055
+	Version             string // 导出格式：5、9 或 ipfix，默认 9
+	Interfaces          string // 导出的接口，默认 all
+	ActiveFlowTimeout   string // 活动流导出周期，默认 1m
+	InactiveFlowTimeout string // 空闲流超时，默认 15s
+}
+
+// applyDefaults 填充默认值
+func (c *TrafficFlowConfig) applyDefaults() {
+	if c.Port == 0 {
+		c.Port = 2055
+	}
+	if c.Version == "" {
+		c.Version = "9"
+	}
+	if c.Interfaces == "" {
+		c.Interfaces = "all"
+	}
+	if c.ActiveFlowTimeout == "" {
+		c.ActiveFlowTimeout = "1m"
+	}
+	if c.InactiveFlowTimeout == "" {
+		c.InactiveFlowTimeout = "15s"
+	}
+}
+
+// validate 校验配置
+func (c *TrafficFlowConfig) validate() error {
+	if c.Target == "" {
+		return fmt.Errorf("流量采集地址不能为空")
+	}
+	switch c.Version {
+	case "5", "9", "ipfix":
+		return nil
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", c.Version)
+	}
+}
+
+// GenerateTrafficFlowCommands 生成启用流量导出的 RouterOS 命令
+// RouterOS v7 使用 dst-address/port，v6 使用 address=IP:端口，添加失败时回退到 v6 写法
+func (g *ScriptGenerator) GenerateTrafficFlowCommands(cfg *TrafficFlowConfig) []string {
+	cfg.applyDefaults()
+	return append(trafficFlowTargetRemoveCommands(cfg.Target, cfg.Port),
+		fmt.Sprintf(`:do { /ip traffic-flow target add dst-address=%s port=%d version=%s } on-error={ /ip traffic-flow target add address=%s:%d version=%s }`,
+			cfg.Target, cfg.Port, cfg.Version, cfg.Target, cfg.Port, cfg.Version),
+		fmt.Sprintf(`/ip traffic-flow set enabled=yes interfaces=%s active-flow-timeout=%s inactive-flow-timeout=%s`,
+			cfg.Interfaces, cfg.ActiveFlowTimeout, cfg.InactiveFlowTimeout),
+	)
+}
+
+// GenerateTrafficFlowRemoveCommands 生成移除流量导出目标的 RouterOS 命令
+// 没有其他导出目标时关闭 traffic-flow
+func (g *ScriptGenerator) GenerateTrafficFlowRemoveCommands(target string, port int) []string {
+	if port == 0 {
+		port = 2055
+	}
+	return append(trafficFlowTargetRemoveCommands(target, port),
+		`:if ([:len [/ip traffic-flow target find]] = 0) do={ /ip traffic-flow set enabled=no }`,
+	)
+}
+
+// trafficFlowTargetRemoveCommands 移除指向平台的导出目标（兼容 v6/v7 写法）
+func trafficFlowTargetRemoveCommands(target string, port int) []string {
+	return []string{
+		fmt.Sprintf(`:do { /ip traffic-flow target remove [find dst-address=%s port=%d] } on-error={}`, target, port),
+		fmt.Sprintf(`:do { /ip traffic-flow target remove [find address="%s:%d"] } on-error={}`, target, port),
+	}
+}
+
+// ConfigureTrafficFlow 配置 MikroTik 设备将流量导出到平台
+// 优先使用 API，失败则尝试 SSH
+func (d *Deployer) ConfigureTrafficFlow(cfg *TrafficFlowConfig, ip string, apiPort, sshPort int, username, password string) *DeployResult {
+	cfg.applyDefaults()
+	if err := cfg.validate(); err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "none",
+			ErrorMessage: err.Error(),
+		}
+	}
+
+	result := d.configureTrafficFlowViaAPI(cfg, ip, apiPort, username, password)
+	if result.Success {
+		return result
+	}
+
+	sshResult := d.runCommandsViaSSH(d.generator.GenerateTrafficFlowCommands(cfg), ip, sshPort, username, password)
+	if sshResult.Success {
+		sshResult.Message = "通过 SSH 配置流量导出成功"
+		return sshResult
+	}
+
+	return &DeployResult{
+		Success:      false,
+		Method:       "none",
+		Message:      "配置流量导出失败",
+		ErrorMessage: fmt.Sprintf("API 错误: %s; SSH 错误: %s", result.ErrorMessage, sshResult.ErrorMessage),
+	}
+}
+
+// RemoveTrafficFlow 移除 MikroTik 设备上指向平台的流量导出目标
+func (d *Deployer) RemoveTrafficFlow(target string, port int, ip string, apiPort, sshPort int, username, password string) *DeployResult {
+	if port == 0 {
+		port = 2055
+	}
+
+	client, err := d.rosCollector.Connect(ip, apiPort, username, password)
+	if err == nil {
+		defer client.Close()
+		d.removeTrafficFlowTargetViaAPI(client, target, port)
+
+		// 没有其他导出目标时关闭 traffic-flow
+		reply, err := client.Run("/ip/traffic-flow/target/print")
+		if err == nil && len(reply.Re) == 0 {
+			client.Run("/ip/traffic-flow/set", "=enabled=no")
+		}
+		return &DeployResult{
+			Success: true,
+			Method:  "api",
+			Message: "通过 API 移除流量导出成功",
+		}
+	}
+
+	result := d.runCommandsViaSSH(d.generator.GenerateTrafficFlowRemoveCommands(target, port), ip, sshPort, username, password)
+	if result.Success {
+		result.Message = "通过 SSH 移除流量导出成功"
+	}
+	return result
+}
+
+// configureTrafficFlowViaAPI 通过 API 配置流量导出
+func (d *Deployer) configureTrafficFlowViaAPI(cfg *TrafficFlowConfig, ip string, port int, username, password string) *DeployResult {
+	client, err := d.rosCollector.Connect(ip, port, username, password)
+	if err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "api",
+			ErrorMessage: err.Error(),
+		}
+	}
+	defer client.Close()
+
+	d.removeTrafficFlowTargetViaAPI(client, cfg.Target, cfg.Port)
+
+	_, err = client.Run("/ip/traffic-flow/target/add",
+		fmt.Sprintf("=dst-address=%s", cfg.Target),
+		fmt.Sprintf("=port=%d", cfg.Port),
+		fmt.Sprintf("=version=%s", cfg.Version),
+	)
+	if err != nil {
+		// RouterOS v6 不支持 dst-address/port
+		_, err = client.Run("/ip/traffic-flow/target/add",
+			fmt.Sprintf("=address=%s:%d", cfg.Target, cfg.Port),
+			fmt.Sprintf("=version=%s", cfg.Version),
+		)
+	}
+	if err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "api",
+			ErrorMessage: fmt.Sprintf("添加流量导出目标失败: %s", err.Error()),
+		}
+	}
+
+	_, err = client.Run("/ip/traffic-flow/set",
+		"=enabled=yes",
+		fmt.Sprintf("=interfaces=%s", cfg.Interfaces),
+		fmt.Sprintf("=active-flow-timeout=%s", cfg.ActiveFlowTimeout),
+		fmt.Sprintf("=inactive-flow-timeout=%s", cfg.InactiveFlowTimeout),
+	)
+	if err != nil {
+		return &DeployResult{
+			Success:      false,
+			Method:       "api",
+			ErrorMessage: fmt.Sprintf("启用流量导出失败: %s", err.Error()),
+		}
+	}
+
+	return &DeployResult{
+		Success: true,
+		Method:  "api",
+		Message: "通过 API 配置流量导出成功",
+	}
+}
+
+// removeTrafficFlowTargetViaAPI 通过 API 移除指向平台的导出目标（兼容 v6/v7 写法）
+func (d *Deployer) removeTrafficFlowTargetViaAPI(client *routeros.Client, target string, port int) {
+	queries := [][]string{
+		{fmt.Sprintf("?dst-address=%s", target), fmt.Sprintf("?port=%d", port)},
+		{fmt.Sprintf("?address=%s:%d", target, port)},
+	}
+	for _, query := range queries {
+		reply, err := client.Run(append([]string{"/ip/traffic-flow/target/print"}, query...)...)
+		if err != nil {
+			continue
+		}
+		for _, re := range reply.Re {
+			if id, ok := re.Map[".id"]; ok {
+				client.Run("/ip/traffic-flow/target/remove", fmt.Sprintf("=.id=%s", id))
+			}
+		}
+	}
+}
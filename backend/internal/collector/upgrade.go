@@ -0,0 +1,227 @@
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-routeros/routeros/v3"
+	"golang.org/x/crypto/ssh"
+)
+
+// routerOSArchitectures RouterOS 升级包文件名中的架构
+var routerOSArchitectures = []string{"arm64", "arm", "mipsbe", "mmips", "smips", "ppc", "tile", "x86"}
+
+var routerOSPackageVersionPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?((alpha|beta|rc)\d+)?$`)
+
+// ParseRouterOSPackageName 解析 RouterOS 升级包文件名
+// v7 格式为 名称-版本[-架构].npk（x86 不带架构），v6 格式为 名称-架构-版本.npk
+func ParseRouterOSPackageName(fileName string) (name, version, arch string, err error) {
+	base := path.Base(fileName)
+	if !strings.HasSuffix(base, ".npk") {
+		return "", "", "", fmt.Errorf("not a RouterOS package: %s", fileName)
+	}
+	parts := strings.Split(strings.TrimSuffix(base, ".npk"), "-")
+
+	// 从右向左找到版本号
+	versionIndex := -1
+	for i := len(parts) - 1; i > 0; i-- {
+		if routerOSPackageVersionPattern.MatchString(parts[i]) {
+			versionIndex = i
+			break
+		}
+	}
+	if versionIndex < 0 {
+		return "", "", "", fmt.Errorf("no version in package name: %s", fileName)
+	}
+	version = parts[versionIndex]
+	nameParts := parts[:versionIndex]
+
+	switch {
+	case versionIndex == len(parts)-2 && isRouterOSArchitecture(parts[len(parts)-1]):
+		arch = parts[len(parts)-1]
+	case versionIndex == len(parts)-1 && len(nameParts) > 1 && isRouterOSArchitecture(nameParts[len(nameParts)-1]):
+		arch = nameParts[len(nameParts)-1]
+		nameParts = nameParts[:len(nameParts)-1]
+	case versionIndex == len(parts)-1:
+		arch = "x86"
+	default:
+		return "", "", "", fmt.Errorf("unrecognized package name: %s", fileName)
+	}
+
+	return strings.Join(nameParts, "-"), version, arch, nil
+}
+
+// isRouterOSArchitecture 判断是否为 RouterOS 架构名
+func isRouterOSArchitecture(value string) bool {
+	for _, arch := range routerOSArchitectures {
+		if value == arch {
+			return true
+		}
+	}
+	return false
+}
+
+// RouterOSPackageArchitecture 将 /system resource 的 architecture-name 转换为升级包架构
+// CHR 和 x86 设备报告 x86_64 或 i386，使用不带架构后缀的 x86 包
+func RouterOSPackageArchitecture(architectureName string) string {
+	switch architectureName {
+	case "x86_64", "i386", "x86":
+		return "x86"
+	default:
+		return architectureName
+	}
+}
+
+// UploadFile 通过 SCP 将文件上传到设备（RouterOS 上传到文件根目录）
+func (c *SSHCollector) UploadFile(client *ssh.Client, remoteName string, content io.Reader, size int64) error {
+	if strings.ContainsAny(remoteName, "/'\"\n ") {
+		return fmt.Errorf("invalid remote file name: %s", remoteName)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建会话失败: %w", err)
+	}
+	if err := session.Start("scp -t " + remoteName); err != nil {
+		return fmt.Errorf("启动 SCP 失败: %w", err)
+	}
+
+	reader := bufio.NewReader(stdout)
+	if err := readSCPAck(reader); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(stdin, "C0644 %d %s\n", size, remoteName); err != nil {
+		return fmt.Errorf("SCP 写入失败: %w", err)
+	}
+	if err := readSCPAck(reader); err != nil {
+		return err
+	}
+	written, err := io.CopyN(stdin, content, size)
+	if err != nil {
+		return fmt.Errorf("SCP 传输失败（已传输 %d/%d 字节）: %w", written, size, err)
+	}
+	if _, err := stdin.Write([]byte{0}); err != nil {
+		return fmt.Errorf("SCP 写入失败: %w", err)
+	}
+	if err := readSCPAck(reader); err != nil {
+		return err
+	}
+	stdin.Close()
+
+	if err := session.Wait(); err != nil {
+		var exitErr *ssh.ExitError
+		var missingErr *ssh.ExitMissingError
+		// 部分设备传输完成后不返回退出码
+		if !errors.As(err, &missingErr) && (!errors.As(err, &exitErr) || exitErr.ExitStatus() != 0) {
+			return fmt.Errorf("SCP 结束失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// readSCPAck 读取 SCP 应答：0 表示成功，1/2 后跟错误信息
+func readSCPAck(reader *bufio.Reader) error {
+	code, err := reader.ReadByte()
+	if err != nil {
+		return fmt.Errorf("读取 SCP 应答失败: %w", err)
+	}
+	if code == 0 {
+		return nil
+	}
+	message, _ := reader.ReadString('\n')
+	return fmt.Errorf("SCP 错误: %s", strings.TrimSpace(message))
+}
+
+// RebootMikroTik 通过 SSH 重启 RouterOS 设备
+// 在脚本中执行以跳过交互确认；重启导致连接断开不视为错误
+func (c *SSHCollector) RebootMikroTik(client *ssh.Client) error {
+	_, err := c.runCommand(client, ":execute {/system reboot}")
+	if err != nil && !isConnectionClosedError(err) {
+		return fmt.Errorf("重启设备失败: %w", err)
+	}
+	return nil
+}
+
+// UpgradeMikroTikFirmware 通过 SSH 将 RouterBOOT 固件升级到当前 RouterOS 自带的版本，重启后生效
+func (c *SSHCollector) UpgradeMikroTikFirmware(client *ssh.Client) error {
+	output, err := c.runCommand(client, ":execute {/system routerboard upgrade}")
+	if err != nil {
+		return fmt.Errorf("升级固件失败: %w", err)
+	}
+	if message := RouterOSCommandError(output); message != "" {
+		return fmt.Errorf("升级固件失败: %s", message)
+	}
+	return nil
+}
+
+// FetchFile 通过 API 让设备从指定 URL 下载文件（/tool fetch），下载完成后返回
+func (c *RouterOSCollector) FetchFile(client *routeros.Client, url, dstPath string) error {
+	_, err := client.Run("/tool/fetch",
+		"=url="+url,
+		"=dst-path="+dstPath,
+		"=check-certificate=no",
+	)
+	if err != nil {
+		return fmt.Errorf("设备下载文件失败: %w", err)
+	}
+	return nil
+}
+
+// Reboot 通过 API 重启设备，重启导致连接断开不视为错误
+func (c *RouterOSCollector) Reboot(client *routeros.Client) error {
+	_, err := client.Run("/system/reboot")
+	if err != nil && !isConnectionClosedError(err) {
+		return fmt.Errorf("重启设备失败: %w", err)
+	}
+	return nil
+}
+
+// UpgradeRouterboardFirmware 通过 API 将 RouterBOOT 固件升级到当前 RouterOS 自带的版本，重启后生效
+// 非 RouterBOARD 设备（如 CHR）直接返回
+func (c *RouterOSCollector) UpgradeRouterboardFirmware(client *routeros.Client) (bool, error) {
+	reply, err := client.Run("/system/routerboard/print")
+	if err != nil {
+		return false, fmt.Errorf("获取固件信息失败: %w", err)
+	}
+	if len(reply.Re) == 0 || reply.Re[0].Map["routerboard"] != "true" {
+		return false, nil
+	}
+	board := reply.Re[0].Map
+	if board["current-firmware"] == board["upgrade-firmware"] {
+		return false, nil
+	}
+	if _, err := client.Run("/system/routerboard/upgrade"); err != nil {
+		return false, fmt.Errorf("升级固件失败: %w", err)
+	}
+	return true, nil
+}
+
+// isConnectionClosedError 判断是否为连接被关闭导致的错误（设备重启时常见）
+func isConnectionClosedError(err error) bool {
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	var missingErr *ssh.ExitMissingError
+	if errors.As(err, &missingErr) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "EOF") ||
+		strings.Contains(message, "connection reset") ||
+		strings.Contains(message, "use of closed network connection")
+}
@@ -18,6 +18,17 @@ type Config struct {
 	InfluxDB InfluxConfig   `mapstructure:"influxdb" validate:"required"`
 	Auth     AuthConfig     `mapstructure:"auth" validate:"required"`
 	Plugins  PluginConfigs  `mapstructure:"plugins" validate:"required"`
+	Syslog   SyslogConfig   `mapstructure:"syslog"`
+	SNMPTrap SNMPTrapConfig `mapstructure:"snmptrap"`
+	Flow     FlowConfig     `mapstructure:"flow"`
+
+	LinuxPoller  LinuxPollerConfig  `mapstructure:"linux_poller"`
+
+	ConfigBackup ConfigBackupConfig `mapstructure:"config_backup"`
+	Inventory    InventoryConfig    `mapstructure:"inventory"`
+	Upgrade      UpgradeConfig      `mapstructure:"upgrade"`
+	Routing      RoutingConfig      `mapstructure:"routing"`
+	Audit        AuditConfig        `mapstructure:"audit"`
 }
 
 // ServerConfig HTTP服务器配置
@@ -28,6 +39,9 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout" validate:"required"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout" validate:"required"`
 	PublicURL    string        `mapstructure:"public_url"` // 设备回调使用的公网/内网地址，如 http://10.10.10.231:8080
+	// TrustedProxies 可信反向代理的地址或网段，只采信这些地址转发的 X-Forwarded-For；
+	// 为空时客户端地址取连接来源地址，登录限流和锁定不受伪造请求头影响
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // DatabaseConfig PostgreSQL数据库配置
@@ -61,6 +75,84 @@ type AuthConfig struct {
 	JWTSecret     string        `mapstructure:"jwt_secret" validate:"required,min=32"`
 	TokenExpiry   time.Duration `mapstructure:"token_expiry" validate:"required"`
 	RefreshExpiry time.Duration `mapstructure:"refresh_expiry" validate:"required"`
+	LDAP          LDAPConfig    `mapstructure:"ldap"`
+	OIDC          OIDCConfig    `mapstructure:"oidc"`
+
+	LoginProtection LoginProtectionConfig `mapstructure:"login_protection"`
+}
+
+// LoginProtectionConfig 登录防暴力破解配置，失败次数保存在 Redis 中
+type LoginProtectionConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	MaxAttempts     int           `mapstructure:"max_attempts"`     // 同一用户名失败多少次后锁定（不区分用户名是否存在）
+	IPMaxAttempts   int           `mapstructure:"ip_max_attempts"`  // 同一来源地址失败多少次后锁定
+	FreeAttempts    int           `mapstructure:"free_attempts"`    // 不延迟的失败次数，超过后每次失败延迟翻倍
+	BaseDelay       time.Duration `mapstructure:"base_delay"`       // 首次延迟
+	MaxDelay        time.Duration `mapstructure:"max_delay"`        // 延迟上限
+	LockoutDuration time.Duration `mapstructure:"lockout_duration"` // 锁定时长，到期自动解锁，管理员可提前解锁
+	Window          time.Duration `mapstructure:"window"`           // 失败计数在最后一次失败后保留的时间
+}
+
+// LDAPConfig LDAP / Active Directory 认证配置
+type LDAPConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	URL                string        `mapstructure:"url"` // ldap://host:389 或 ldaps://host:636
+	StartTLS           bool          `mapstructure:"start_tls"`
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+	BindDN             string        `mapstructure:"bind_dn"` // 搜索用户使用的服务账号，为空时匿名搜索
+	BindPassword       string        `mapstructure:"bind_password"`
+	BaseDN             string        `mapstructure:"base_dn"`
+	UserFilter         string        `mapstructure:"user_filter"` // %s 替换为转义后的用户名
+
+	UsernameAttribute string `mapstructure:"username_attribute"`
+	EmailAttribute    string `mapstructure:"email_attribute"`
+	NameAttribute     string `mapstructure:"name_attribute"`
+	GroupAttribute    string `mapstructure:"group_attribute"` // 用户条目上的组属性，如 memberOf
+	GroupBaseDN       string `mapstructure:"group_base_dn"`   // 为空时使用 base_dn
+	GroupFilter       string `mapstructure:"group_filter"`    // 按组条目反查，%s 替换为用户 DN；为空时读取 group_attribute
+
+	AutoCreateUsers bool               `mapstructure:"auto_create_users"` // 首次登录时自动创建本地用户
+	DefaultRole     string             `mapstructure:"default_role"`      // 未匹配任何组映射时分配的角色，为空时拒绝登录
+	GroupMappings   []LDAPGroupMapping `mapstructure:"group_mappings"`
+}
+
+// LDAPGroupMapping LDAP 组到角色的映射
+type LDAPGroupMapping struct {
+	Group string `mapstructure:"group"` // 组 DN 或 CN，不区分大小写
+	Role  string `mapstructure:"role"`  // 角色名称
+}
+
+// OIDCConfig OpenID Connect 单点登录配置
+type OIDCConfig struct {
+	FrontendURL string               `mapstructure:"frontend_url"` // 登录完成后跳转的前端地址，为空时跳转到当前域名
+	Providers   []OIDCProviderConfig `mapstructure:"providers"`
+}
+
+// OIDCProviderConfig OIDC 身份提供者配置
+type OIDCProviderConfig struct {
+	Name         string   `mapstructure:"name"`         // 提供者标识，用于登录和回调路径
+	DisplayName  string   `mapstructure:"display_name"` // 登录页显示名称
+	IssuerURL    string   `mapstructure:"issuer_url"`   // 通过 {issuer_url}/.well-known/openid-configuration 自动发现端点
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"` // 公共客户端（仅 PKCE）可为空
+	RedirectURL  string   `mapstructure:"redirect_url"`  // 如 https://nmp.example.com/api/v1/auth/oidc/{name}/callback
+	Scopes       []string `mapstructure:"scopes"`        // 为空时使用 openid profile email
+
+	UsernameClaim string `mapstructure:"username_claim"` // 为空时使用 preferred_username
+	EmailClaim    string `mapstructure:"email_claim"`    // 为空时使用 email
+	NameClaim     string `mapstructure:"name_claim"`     // 为空时使用 name
+	RolesClaim    string `mapstructure:"roles_claim"`    // 用于角色映射的声明，如 groups、roles
+
+	AutoCreateUsers bool              `mapstructure:"auto_create_users"` // 首次登录时自动创建本地用户
+	DefaultRole     string            `mapstructure:"default_role"`      // 未匹配任何映射时分配的角色，为空时拒绝登录
+	RoleMappings    []OIDCRoleMapping `mapstructure:"role_mappings"`
+}
+
+// OIDCRoleMapping OIDC 声明值到角色的映射
+type OIDCRoleMapping struct {
+	Value string `mapstructure:"value"` // 声明值（如组名或组 ID），不区分大小写
+	Role  string `mapstructure:"role"`  // 角色名称
 }
 
 // PluginConfigs 插件配置
@@ -69,6 +161,75 @@ type PluginConfigs struct {
 	Configs   map[string]interface{} `mapstructure:"configs"`
 }
 
+// SyslogConfig 内置 syslog 接收配置
+type SyslogConfig struct {
+	Enabled       bool   `mapstructure:"enabled"`
+	UDPAddress    string `mapstructure:"udp_address"`                     // 为空则不监听 UDP
+	TCPAddress    string `mapstructure:"tcp_address"`                     // 为空则不监听 TCP
+	RetentionDays int    `mapstructure:"retention_days" validate:"min=0"` // 0 表示不清理
+}
+
+// SNMPTrapConfig 内置 SNMP trap/inform 接收配置
+type SNMPTrapConfig struct {
+	Enabled       bool             `mapstructure:"enabled"`
+	Address       string           `mapstructure:"address"`                         // UDP 监听地址
+	Communities   []string         `mapstructure:"communities"`                     // 允许的 v1/v2c 团体名，为空接受任意团体名
+	EngineID      string           `mapstructure:"engine_id"`                       // 本地 SNMPv3 引擎 ID（十六进制），为空按主机名生成
+	Users         []SNMPUserConfig `mapstructure:"users"`                           // SNMPv3 USM 用户
+	RetentionDays int              `mapstructure:"retention_days" validate:"min=0"` // 0 表示不清理
+}
+
+// FlowConfig 内置 NetFlow v5/v9、IPFIX 流量采集配置
+type FlowConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`                // UDP 监听地址
+	TopN    int    `mapstructure:"top_n" validate:"min=0"` // 每分钟每个维度保存的条目数
+}
+
+// LinuxPollerConfig Linux 设备接口指标轮询配置
+type LinuxPollerConfig struct {
+	Interval time.Duration `mapstructure:"interval"` // 通过 SSH 读取接口计数器的间隔
+}
+
+// ConfigBackupConfig 设备配置备份配置
+type ConfigBackupConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`                      // 定时备份间隔
+	MaxVersions int           `mapstructure:"max_versions" validate:"min=0"` // 每台设备保留的版本数，0 表示不清理
+	LinuxFiles  []string      `mapstructure:"linux_files"`                   // Linux 设备需要备份的配置文件
+}
+
+// InventoryConfig 设备硬件/软件清单配置
+type InventoryConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // 定时刷新间隔
+}
+
+// UpgradeConfig RouterOS 升级配置
+type UpgradeConfig struct {
+	PackageDir string `mapstructure:"package_dir"` // 升级包（.npk）保存目录
+}
+
+// RoutingConfig BGP/OSPF 邻居监控配置
+type RoutingConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // 邻居状态采集间隔
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	RetentionDays int `mapstructure:"retention_days" validate:"min=0"` // 0 表示不清理
+}
+
+// SNMPUserConfig SNMPv3 USM 用户配置
+type SNMPUserConfig struct {
+	Name         string `mapstructure:"name"`
+	AuthProtocol string `mapstructure:"auth_protocol"` // MD5、SHA、SHA224、SHA256、SHA384、SHA512，为空表示不认证
+	AuthPassword string `mapstructure:"auth_password"`
+	PrivProtocol string `mapstructure:"priv_protocol"` // DES、AES，为空表示不加密
+	PrivPassword string `mapstructure:"priv_password"`
+}
+
 // Load 加载配置文件
 func Load() (*Config, error) {
 	return LoadWithPath("./configs")
@@ -158,6 +319,48 @@ func setupEnvironmentVariables() {
 		"auth.token_expiry":     {"NMP_AUTH_TOKEN_EXPIRY"},
 		"auth.refresh_expiry":   {"NMP_AUTH_REFRESH_EXPIRY"},
 		"plugins.directory":     {"NMP_PLUGINS_DIRECTORY"},
+		"syslog.enabled":        {"NMP_SYSLOG_ENABLED"},
+		"syslog.udp_address":    {"NMP_SYSLOG_UDP_ADDRESS"},
+		"syslog.tcp_address":    {"NMP_SYSLOG_TCP_ADDRESS"},
+		"syslog.retention_days": {"NMP_SYSLOG_RETENTION_DAYS"},
+
+		// LDAP 认证
+		"auth.ldap.enabled":       {"NMP_AUTH_LDAP_ENABLED"},
+		"auth.ldap.url":           {"NMP_AUTH_LDAP_URL"},
+		"auth.ldap.bind_dn":       {"NMP_AUTH_LDAP_BIND_DN"},
+		"auth.ldap.bind_password": {"NMP_AUTH_LDAP_BIND_PASSWORD"},
+		"auth.ldap.base_dn":       {"NMP_AUTH_LDAP_BASE_DN"},
+		"auth.oidc.frontend_url":  {"NMP_AUTH_OIDC_FRONTEND_URL"},
+
+		// SNMP trap 接收
+		"snmptrap.enabled":        {"NMP_SNMPTRAP_ENABLED"},
+		"snmptrap.address":        {"NMP_SNMPTRAP_ADDRESS"},
+		"snmptrap.engine_id":      {"NMP_SNMPTRAP_ENGINE_ID"},
+		"snmptrap.retention_days": {"NMP_SNMPTRAP_RETENTION_DAYS"},
+
+		// 流量采集
+		"flow.enabled": {"NMP_FLOW_ENABLED"},
+		"flow.address": {"NMP_FLOW_ADDRESS"},
+		"flow.top_n":   {"NMP_FLOW_TOP_N"},
+
+		// 设备配置备份
+		"config_backup.enabled":      {"NMP_CONFIG_BACKUP_ENABLED"},
+		"config_backup.interval":     {"NMP_CONFIG_BACKUP_INTERVAL"},
+		"config_backup.max_versions": {"NMP_CONFIG_BACKUP_MAX_VERSIONS"},
+
+		// 设备清单
+		"inventory.enabled":  {"NMP_INVENTORY_ENABLED"},
+		"inventory.interval": {"NMP_INVENTORY_INTERVAL"},
+
+		// RouterOS 升级
+		"upgrade.package_dir": {"NMP_UPGRADE_PACKAGE_DIR"},
+
+		// 路由邻居监控
+		"routing.enabled":  {"NMP_ROUTING_ENABLED"},
+		"routing.interval": {"NMP_ROUTING_INTERVAL"},
+
+		// 审计日志
+		"audit.retention_days": {"NMP_AUDIT_RETENTION_DAYS"},
 	}
 	
 	for key, envVars := range envBindings {
@@ -209,6 +412,15 @@ func applyEnvironmentOverrides(config *Config) {
 	if influxURL := os.Getenv("INFLUXDB_URL"); influxURL != "" {
 		config.InfluxDB.URL = influxURL
 	}
+
+	// OIDC 客户端密钥，按提供者名称覆盖，如 NMP_AUTH_OIDC_AZURE_CLIENT_SECRET
+	for i := range config.Auth.OIDC.Providers {
+		provider := &config.Auth.OIDC.Providers[i]
+		envName := "NMP_AUTH_OIDC_" + strings.ToUpper(strings.ReplaceAll(provider.Name, "-", "_")) + "_CLIENT_SECRET"
+		if secret := os.Getenv(envName); secret != "" {
+			provider.ClientSecret = secret
+		}
+	}
 }
 
 // validateProductionConfig 生产环境配置验证
@@ -324,6 +536,48 @@ func validateCustomRules(config *Config) error {
 	if config.Auth.RefreshExpiry <= config.Auth.TokenExpiry {
 		return fmt.Errorf("refresh expiry must be greater than token expiry")
 	}
+
+	// 验证 LDAP 配置
+	if ldap := config.Auth.LDAP; ldap.Enabled {
+		if ldap.URL == "" || ldap.BaseDN == "" {
+			return fmt.Errorf("auth.ldap.url and auth.ldap.base_dn are required when LDAP is enabled")
+		}
+		if !strings.Contains(ldap.UserFilter, "%s") {
+			return fmt.Errorf("auth.ldap.user_filter must contain %%s placeholder for the username")
+		}
+		for _, mapping := range ldap.GroupMappings {
+			if mapping.Group == "" || mapping.Role == "" {
+				return fmt.Errorf("auth.ldap.group_mappings entries require both group and role")
+			}
+		}
+	}
+
+	// 验证登录保护配置
+	if protection := config.Auth.LoginProtection; protection.Enabled {
+		if protection.MaxAttempts <= 0 || protection.IPMaxAttempts <= 0 {
+			return fmt.Errorf("auth.login_protection max_attempts and ip_max_attempts must be positive")
+		}
+		if protection.LockoutDuration <= 0 || protection.Window <= 0 {
+			return fmt.Errorf("auth.login_protection lockout_duration and window must be positive")
+		}
+	}
+
+	// 验证 OIDC 提供者配置
+	providerNames := make(map[string]bool)
+	for _, provider := range config.Auth.OIDC.Providers {
+		if provider.Name == "" || provider.IssuerURL == "" || provider.ClientID == "" || provider.RedirectURL == "" {
+			return fmt.Errorf("auth.oidc.providers entries require name, issuer_url, client_id and redirect_url")
+		}
+		if providerNames[provider.Name] {
+			return fmt.Errorf("duplicate OIDC provider name: %s", provider.Name)
+		}
+		providerNames[provider.Name] = true
+		for _, mapping := range provider.RoleMappings {
+			if mapping.Value == "" || mapping.Role == "" {
+				return fmt.Errorf("auth.oidc provider %s role_mappings entries require both value and role", provider.Name)
+			}
+		}
+	}
 	
 	// 验证插件目录是否存在
 	if _, err := os.Stat(config.Plugins.Directory); os.IsNotExist(err) {
@@ -367,11 +621,72 @@ func setDefaults() {
 
 	// 认证默认配置
 	viper.SetDefault("auth.jwt_secret", "nmp-secret-key-change-in-production")
-	viper.SetDefault("auth.token_expiry", "24h")
+	viper.SetDefault("auth.token_expiry", "15m")
 	viper.SetDefault("auth.refresh_expiry", "168h")
+	viper.SetDefault("auth.ldap.enabled", false)
+	viper.SetDefault("auth.ldap.timeout", "10s")
+	viper.SetDefault("auth.ldap.user_filter", "(sAMAccountName=%s)")
+	viper.SetDefault("auth.ldap.username_attribute", "sAMAccountName")
+	viper.SetDefault("auth.ldap.email_attribute", "mail")
+	viper.SetDefault("auth.ldap.name_attribute", "displayName")
+	viper.SetDefault("auth.ldap.group_attribute", "memberOf")
+	viper.SetDefault("auth.ldap.auto_create_users", true)
+	viper.SetDefault("auth.login_protection.enabled", true)
+	viper.SetDefault("auth.login_protection.max_attempts", 10)
+	viper.SetDefault("auth.login_protection.ip_max_attempts", 50)
+	viper.SetDefault("auth.login_protection.free_attempts", 3)
+	viper.SetDefault("auth.login_protection.base_delay", "1s")
+	viper.SetDefault("auth.login_protection.max_delay", "1m")
+	viper.SetDefault("auth.login_protection.lockout_duration", "15m")
+	viper.SetDefault("auth.login_protection.window", "15m")
 
 	// 插件默认配置
 	viper.SetDefault("plugins.directory", "./plugins")
+
+	// syslog 默认配置
+	viper.SetDefault("syslog.enabled", true)
+	viper.SetDefault("syslog.udp_address", ":514")
+	viper.SetDefault("syslog.tcp_address", ":514")
+	viper.SetDefault("syslog.retention_days", 30)
+
+	// SNMP trap 默认配置
+	viper.SetDefault("snmptrap.enabled", true)
+	viper.SetDefault("snmptrap.address", ":162")
+	viper.SetDefault("snmptrap.retention_days", 30)
+
+	// 流量采集默认配置
+	viper.SetDefault("flow.enabled", true)
+	viper.SetDefault("flow.address", ":2055")
+	viper.SetDefault("flow.top_n", 20)
+
+	// Linux 设备接口指标轮询默认配置
+	viper.SetDefault("linux_poller.interval", "10s")
+
+	// 设备配置备份默认配置
+	viper.SetDefault("config_backup.enabled", true)
+	viper.SetDefault("config_backup.interval", "24h")
+	viper.SetDefault("config_backup.max_versions", 100)
+	viper.SetDefault("config_backup.linux_files", []string{
+		"/etc/network/interfaces",
+		"/etc/netplan/*.yaml",
+		"/etc/hosts",
+		"/etc/resolv.conf",
+		"/etc/sysctl.conf",
+	})
+
+	// 设备清单默认配置
+	viper.SetDefault("inventory.enabled", true)
+	viper.SetDefault("inventory.interval", "6h")
+
+	// RouterOS 升级默认配置
+	viper.SetDefault("upgrade.package_dir", "./data/upgrade-packages")
+
+	// 路由邻居监控默认配置
+	viper.SetDefault("routing.enabled", true)
+	viper.SetDefault("routing.interval", "60s")
+
+	// 审计日志默认配置
+	viper.SetDefault("audit.retention_days", 365)
 }
 
 // GetConfig 获取当前配置实例（单例模式）
@@ -6,6 +6,7 @@ import (
 	"nmp-platform/internal/config"
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/tenant"
 	"os"
 	"time"
 
@@ -40,6 +41,11 @@ func Connect(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// 注册租户隔离回调，请求上下文携带组织时自动限定查询范围
+	if err := tenant.Register(db); err != nil {
+		return nil, err
+	}
+
 	// 配置连接池
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -68,6 +74,16 @@ func Connect(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 func Migrate(db *gorm.DB) error {
 	log.Println("Starting database migration...")
 
+	// 标签和分组名称改为在组织内唯一，先删除旧版本创建的全局唯一约束
+	for _, stmt := range []string{
+		"ALTER TABLE IF EXISTS tags DROP CONSTRAINT IF EXISTS tags_name_key",
+		"ALTER TABLE IF EXISTS device_groups DROP CONSTRAINT IF EXISTS device_groups_name_key",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to drop legacy unique constraint: %w", err)
+		}
+	}
+
 	// 执行自动迁移
 	if err := models.AutoMigrate(db); err != nil {
 		return fmt.Errorf("failed to auto migrate: %w", err)
@@ -86,6 +102,11 @@ func Migrate(db *gorm.DB) error {
 func SeedData(db *gorm.DB) error {
 	log.Println("Starting database seeding...")
 
+	// 创建默认组织
+	if err := seedDefaultOrganization(db); err != nil {
+		return fmt.Errorf("failed to seed default organization: %w", err)
+	}
+
 	// 创建默认权限
 	if err := seedPermissions(db); err != nil {
 		return fmt.Errorf("failed to seed permissions: %w", err)
@@ -96,6 +117,11 @@ func SeedData(db *gorm.DB) error {
 		return fmt.Errorf("failed to seed roles: %w", err)
 	}
 
+	// 创建默认设备授权
+	if err := seedDeviceGrants(db); err != nil {
+		return fmt.Errorf("failed to seed device grants: %w", err)
+	}
+
 	// 创建默认管理员用户
 	if err := seedAdminUser(db); err != nil {
 		return fmt.Errorf("failed to seed admin user: %w", err)
@@ -110,6 +136,33 @@ func SeedData(db *gorm.DB) error {
 	return nil
 }
 
+// seedDefaultOrganization 创建默认组织，升级前的数据都归属默认组织
+func seedDefaultOrganization(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Organization{}).Where("id = ?", models.DefaultOrganizationID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	organization := models.Organization{
+		ID:          models.DefaultOrganizationID,
+		Name:        "default",
+		DisplayName: "默认组织",
+		Description: "系统默认组织",
+		Enabled:     true,
+	}
+	if err := db.Create(&organization).Error; err != nil {
+		return err
+	}
+	// PostgreSQL 显式指定主键后需要同步序列，避免后续创建的组织主键冲突
+	if db.Dialector.Name() == "postgres" {
+		return db.Exec("SELECT setval(pg_get_serial_sequence('organizations', 'id'), (SELECT MAX(id) FROM organizations))").Error
+	}
+	return nil
+}
+
 // seedPermissions 创建默认权限
 func seedPermissions(db *gorm.DB) error {
 	permissions := []models.Permission{
@@ -229,6 +282,32 @@ func seedRoles(db *gorm.DB) error {
 	return nil
 }
 
+// seedDeviceGrants 创建默认设备授权
+// 仅在还没有任何设备授权时执行：查看者角色可以查看全部设备，
+// 操作员角色需要管理员按分组、标签或设备授权
+func seedDeviceGrants(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.DeviceGrant{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var viewerRole models.Role
+	if err := db.Where("name = ?", "viewer").First(&viewerRole).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	return db.Create(&models.DeviceGrant{
+		RoleID:  &viewerRole.ID,
+		CanRead: true,
+	}).Error
+}
+
 // seedAdminUser 创建默认管理员用户
 func seedAdminUser(db *gorm.DB) error {
 	var existingUser models.User
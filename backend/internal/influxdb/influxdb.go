@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"nmp-platform/internal/config"
+	"nmp-platform/internal/tenant"
+	"strconv"
 	"sync"
 	"time"
 
@@ -74,6 +76,19 @@ type Client struct {
 	queryAPI        api.QueryAPI
 	config          *config.InfluxConfig
 	mu              sync.Mutex
+
+	organizations OrganizationResolver
+}
+
+// OrganizationResolver 根据设备 ID 解析设备所属组织
+type OrganizationResolver interface {
+	DeviceOrganization(deviceID uint) (uint, bool)
+}
+
+// SetOrganizationResolver 设置组织解析器
+// 设置后带 device_id 标签的数据点自动附加 organization_id 标签，用于按组织查询和清理数据
+func (c *Client) SetOrganizationResolver(resolver OrganizationResolver) {
+	c.organizations = resolver
 }
 
 // Connect 连接到InfluxDB
@@ -129,6 +144,9 @@ func (c *Client) WritePoint(measurement string, tags map[string]string, fields m
 	for k, v := range tags {
 		point = point.AddTag(k, v)
 	}
+	if organizationID, ok := c.deviceOrganization(tags); ok {
+		point = point.AddTag(tenant.InfluxTag, strconv.FormatUint(uint64(organizationID), 10))
+	}
 	
 	// 添加字段
 	for k, v := range fields {
@@ -151,6 +169,21 @@ func (c *Client) WritePoint(measurement string, tags map[string]string, fields m
 	return nil
 }
 
+// deviceOrganization 数据点所属组织，标签中已有组织或没有设备时不处理
+func (c *Client) deviceOrganization(tags map[string]string) (uint, bool) {
+	if c.organizations == nil {
+		return 0, false
+	}
+	if _, ok := tags[tenant.InfluxTag]; ok {
+		return 0, false
+	}
+	deviceID, err := strconv.ParseUint(tags["device_id"], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return c.organizations.DeviceOrganization(uint(deviceID))
+}
+
 // WriteBatch 批量写入数据点
 func (c *Client) WriteBatch(points []*write.Point) error {
 	for _, point := range points {
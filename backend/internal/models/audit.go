@@ -0,0 +1,65 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogImmutable 审计日志只允许追加，不能修改或逐条删除
+var ErrAuditLogImmutable = errors.New("audit log entries are append-only")
+
+// 审计操作者类型
+const (
+	AuditActorUser      = "user"      // 登录用户
+	AuditActorAPIToken  = "api_token" // 通过 API 令牌调用
+	AuditActorSystem    = "system"    // 后台任务
+	AuditActorAnonymous = "anonymous" // 未认证的请求（如登录）
+)
+
+// 审计结果
+const (
+	AuditStatusSuccess = "success"
+	AuditStatusFailure = "failure"
+)
+
+// AuditLog 审计日志
+// 记录谁在何时从哪里对什么资源做了什么操作，以及操作前后的差异（敏感字段已脱敏）。
+// 表只允许追加，过期记录由保留策略按时间批量清理
+type AuditLog struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，后台任务为任务所属组织
+	CreatedAt      time.Time `gorm:"not null;index" json:"created_at"`
+	ActorType      string    `gorm:"size:20;not null;index" json:"actor_type"`
+	ActorID        uint      `gorm:"index" json:"actor_id"` // 后台任务为 0
+	ActorName      string    `gorm:"size:100" json:"actor_name"`
+	APITokenID     uint      `json:"api_token_id,omitempty"`
+	Action         string    `gorm:"size:100;not null;index" json:"action"`
+	ResourceType   string    `gorm:"size:50;index:idx_audit_resource,priority:1" json:"resource_type"`
+	ResourceID     string    `gorm:"size:64;index:idx_audit_resource,priority:2" json:"resource_id,omitempty"`
+	Method         string    `gorm:"size:10" json:"method,omitempty"`
+	Route          string    `gorm:"size:255" json:"route,omitempty"` // 路由模板，如 /api/v1/devices/:id
+	StatusCode     int       `json:"status_code,omitempty"`
+	Status         string    `gorm:"size:20;not null;index" json:"status"`
+	Changes        string    `gorm:"type:text" json:"changes,omitempty"` // 字段变更（JSON，字段 -> {before, after}）
+	Request        string    `gorm:"type:text" json:"request,omitempty"` // 请求参数（JSON，敏感字段已脱敏）
+	Detail         string    `gorm:"type:text" json:"detail,omitempty"`
+	SourceIP       string    `gorm:"size:64" json:"source_ip,omitempty"`
+	RequestID      string    `gorm:"size:64;index" json:"request_id,omitempty"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// BeforeUpdate 禁止修改审计日志
+func (AuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete 禁止通过模型删除审计日志，保留策略使用按时间的批量删除
+func (AuditLog) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
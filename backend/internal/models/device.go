@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -37,27 +38,28 @@ const (
 
 // Device 设备模型
 type Device struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"not null;size:100" json:"name"`
-	Type        DeviceType     `gorm:"type:varchar(20);not null" json:"type"`
-	OSType      DeviceOSType   `gorm:"type:varchar(20);default:'mikrotik'" json:"os_type"` // mikrotik, linux
-	Host        string         `gorm:"not null;size:255" json:"host"`
-	Port        int            `gorm:"default:22" json:"port"`           // SSH 端口
-	APIPort     int            `gorm:"default:8728" json:"api_port"`     // MikroTik API 端口
-	Protocol    string         `gorm:"size:20;default:'ssh'" json:"protocol"` // ssh, snmp, telnet
-	Username    string         `gorm:"size:100" json:"username"`
-	Password    string         `gorm:"size:255" json:"-"` // 不在JSON中返回密码
-	Version     string         `gorm:"size:50" json:"version"` // 设备版本
-	Description string         `gorm:"size:500" json:"description"`
-	Status      DeviceStatus   `gorm:"type:varchar(20);default:'unknown'" json:"status"`
-	LastSeen    *time.Time     `json:"last_seen"`
-	ProxyID     *uint          `gorm:"index" json:"proxy_id,omitempty"` // 代理ID
-	Tags        []Tag          `gorm:"many2many:device_tags;" json:"tags"`
-	Interfaces  []Interface    `json:"interfaces"`
-	Groups      []DeviceGroup  `gorm:"many2many:device_group_members;" json:"groups"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织
+	Name           string         `gorm:"not null;size:100" json:"name"`
+	Type           DeviceType     `gorm:"type:varchar(20);not null" json:"type"`
+	OSType         DeviceOSType   `gorm:"type:varchar(20);default:'mikrotik'" json:"os_type"` // mikrotik, linux
+	Host           string         `gorm:"not null;size:255" json:"host"`
+	Port           int            `gorm:"default:22" json:"port"`                // SSH 端口
+	APIPort        int            `gorm:"default:8728" json:"api_port"`          // MikroTik API 端口
+	Protocol       string         `gorm:"size:20;default:'ssh'" json:"protocol"` // ssh, snmp, telnet
+	Username       string         `gorm:"size:100" json:"username"`
+	Password       string         `gorm:"size:255" json:"-"`      // 不在JSON中返回密码
+	Version        string         `gorm:"size:50" json:"version"` // 设备版本
+	Description    string         `gorm:"size:500" json:"description"`
+	Status         DeviceStatus   `gorm:"type:varchar(20);default:'unknown'" json:"status"`
+	LastSeen       *time.Time     `json:"last_seen"`
+	ProxyID        *uint          `gorm:"index" json:"proxy_id,omitempty"` // 代理ID
+	Tags           []Tag          `gorm:"many2many:device_tags;" json:"tags"`
+	Interfaces     []Interface    `json:"interfaces"`
+	Groups         []DeviceGroup  `gorm:"many2many:device_group_members;" json:"groups"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -71,6 +73,7 @@ type Interface struct {
 	DeviceID    uint             `gorm:"not null;index" json:"device_id"`
 	Name        string           `gorm:"not null;size:100" json:"name"`
 	Status      InterfaceStatus  `gorm:"type:varchar(20);default:'unknown'" json:"status"`
+	IfIndex     int              `gorm:"index" json:"if_index"` // SNMP ifIndex，用于关联 trap
 	Monitored   bool             `gorm:"default:false" json:"monitored"` // 是否监控此接口
 	Device      Device           `gorm:"foreignKey:DeviceID" json:"-"`
 	CreatedAt   time.Time        `json:"created_at"`
@@ -94,14 +97,15 @@ func (Interface) TableName() string {
 
 // Tag 标签模型
 type Tag struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"unique;not null;size:50" json:"name"`
-	Color       string         `gorm:"size:7;default:'#007bff'" json:"color"` // 十六进制颜色值
-	Description string         `gorm:"size:255" json:"description"`
-	Devices     []Device       `gorm:"many2many:device_tags;" json:"-"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;default:1;uniqueIndex:idx_tags_org_name,priority:1;<-:create" json:"organization_id"` // 所属组织，标签名在组织内唯一
+	Name           string         `gorm:"not null;size:50;uniqueIndex:idx_tags_org_name,priority:2" json:"name"`
+	Color          string         `gorm:"size:7;default:'#007bff'" json:"color"` // 十六进制颜色值
+	Description    string         `gorm:"size:255" json:"description"`
+	Devices        []Device       `gorm:"many2many:device_tags;" json:"-"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -111,16 +115,17 @@ func (Tag) TableName() string {
 
 // DeviceGroup 设备分组模型
 type DeviceGroup struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"unique;not null;size:100" json:"name"`
-	Description string         `gorm:"size:500" json:"description"`
-	ParentID    *uint          `gorm:"index" json:"parent_id"` // 支持分组嵌套
-	Parent      *DeviceGroup   `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
-	Children    []DeviceGroup  `gorm:"foreignKey:ParentID" json:"children,omitempty"`
-	Devices     []Device       `gorm:"many2many:device_group_members;" json:"devices,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;default:1;uniqueIndex:idx_device_groups_org_name,priority:1;<-:create" json:"organization_id"` // 所属组织，分组名在组织内唯一
+	Name           string         `gorm:"not null;size:100;uniqueIndex:idx_device_groups_org_name,priority:2" json:"name"`
+	Description    string         `gorm:"size:500" json:"description"`
+	ParentID       *uint          `gorm:"index" json:"parent_id"` // 支持分组嵌套
+	Parent         *DeviceGroup   `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	Children       []DeviceGroup  `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	Devices        []Device       `gorm:"many2many:device_group_members;" json:"devices,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -150,4 +155,302 @@ type DeviceGroupMember struct {
 // TableName 指定表名
 func (DeviceGroupMember) TableName() string {
 	return "device_group_members"
-}
\ No newline at end of file
+}
+// ConfigBackupTrigger 配置备份触发方式
+type ConfigBackupTrigger string
+
+const (
+	ConfigBackupTriggerSchedule ConfigBackupTrigger = "schedule" // 定时备份
+	ConfigBackupTriggerManual   ConfigBackupTrigger = "manual"   // 手动触发
+)
+
+// ConfigBackup 设备配置备份版本
+// 只有配置内容发生变化时才保存新版本，Diff 为相对上一版本的统一差异格式
+type ConfigBackup struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	OrganizationID uint                `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
+	DeviceID       uint                `gorm:"not null;uniqueIndex:idx_config_backups_device_version,priority:1" json:"device_id"`
+	Device         Device              `gorm:"foreignKey:DeviceID" json:"-"`
+	Version        int                 `gorm:"not null;uniqueIndex:idx_config_backups_device_version,priority:2" json:"version"` // 设备内递增版本号
+	Hash           string              `gorm:"size:64;not null" json:"hash"`                                                     // 内容 SHA-256
+	Size           int                 `json:"size"`
+	Content        string              `gorm:"type:text" json:"content,omitempty"`
+	Diff           string              `gorm:"type:text" json:"diff,omitempty"`
+	LinesAdded     int                 `json:"lines_added"`
+	LinesRemoved   int                 `json:"lines_removed"`
+	Method         string              `gorm:"size:20" json:"method"` // 采集方式：ssh
+	Trigger        ConfigBackupTrigger `gorm:"type:varchar(20)" json:"trigger"`
+	CreatedAt      time.Time           `gorm:"index" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ConfigBackup) TableName() string {
+	return "config_backups"
+}
+
+// CommandJobStatus 批量命令任务状态
+type CommandJobStatus string
+
+const (
+	CommandJobStatusRunning     CommandJobStatus = "running"     // 执行中
+	CommandJobStatusCompleted   CommandJobStatus = "completed"   // 全部成功
+	CommandJobStatusFailed      CommandJobStatus = "failed"      // 部分或全部设备失败
+	CommandJobStatusStopped     CommandJobStatus = "stopped"     // 遇到失败后停止
+	CommandJobStatusCancelled   CommandJobStatus = "cancelled"   // 被用户取消
+	CommandJobStatusInterrupted CommandJobStatus = "interrupted" // 服务重启导致中断
+)
+
+// CommandResultStatus 单台设备的命令执行状态
+type CommandResultStatus string
+
+const (
+	CommandResultStatusPending CommandResultStatus = "pending" // 等待执行
+	CommandResultStatusRunning CommandResultStatus = "running" // 执行中
+	CommandResultStatusSuccess CommandResultStatus = "success" // 执行成功
+	CommandResultStatusFailed  CommandResultStatus = "failed"  // 执行失败
+	CommandResultStatusSkipped CommandResultStatus = "skipped" // 未执行（系统类型不匹配、任务停止或取消）
+)
+
+// CommandJob 批量命令任务
+// 将命令模板渲染后通过 SSH 在选定的设备、分组或标签下的设备上执行，记录执行人和每台设备的输出
+type CommandJob struct {
+	ID             uint               `gorm:"primaryKey" json:"id"`
+	OrganizationID uint               `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织
+	Name           string             `gorm:"size:100" json:"name"`
+	Template       string             `gorm:"type:text;not null" json:"template"` // 命令模板（text/template 语法）
+	OSType         DeviceOSType       `gorm:"type:varchar(20);not null" json:"os_type"`
+	Targets        string             `gorm:"type:text" json:"targets"` // 目标选择（JSON）
+	Concurrency    int                `json:"concurrency"`
+	Timeout        int                `json:"timeout"` // 单台设备超时（秒）
+	StopOnFailure  bool               `json:"stop_on_failure"`
+	Status         CommandJobStatus   `gorm:"type:varchar(20);index" json:"status"`
+	Total          int                `json:"total"`
+	Succeeded      int                `json:"succeeded"`
+	Failed         int                `json:"failed"`
+	Skipped        int                `json:"skipped"`
+	CreatedBy      uint               `gorm:"index" json:"created_by"`
+	CreatedByName  string             `gorm:"size:100" json:"created_by_name"`
+	StartedAt      *time.Time         `json:"started_at"`
+	FinishedAt     *time.Time         `json:"finished_at"`
+	CreatedAt      time.Time          `gorm:"index" json:"created_at"`
+	Results        []CommandJobResult `gorm:"foreignKey:JobID" json:"results,omitempty"`
+}
+
+// TableName 指定表名
+func (CommandJob) TableName() string {
+	return "command_jobs"
+}
+
+// CommandJobResult 批量命令任务在单台设备上的执行结果
+type CommandJobResult struct {
+	ID         uint                `gorm:"primaryKey" json:"id"`
+	JobID      uint                `gorm:"not null;index" json:"job_id"`
+	DeviceID   uint                `gorm:"not null;index" json:"device_id"`
+	DeviceName string              `gorm:"size:100" json:"device_name"`
+	Host       string              `gorm:"size:255" json:"host"`
+	Command    string              `gorm:"type:text" json:"command"` // 渲染后的命令
+	Status     CommandResultStatus `gorm:"type:varchar(20)" json:"status"`
+	Stdout     string              `gorm:"type:text" json:"stdout"`
+	Stderr     string              `gorm:"type:text" json:"stderr"`
+	ExitStatus *int                `json:"exit_status"`
+	Error      string              `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  *time.Time          `json:"started_at"`
+	FinishedAt *time.Time          `json:"finished_at"`
+}
+
+// TableName 指定表名
+func (CommandJobResult) TableName() string {
+	return "command_job_results"
+}
+
+// DeviceInventory 设备硬件和软件清单（每台设备一条，定时刷新）
+type DeviceInventory struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID  uint      `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
+	DeviceID        uint      `gorm:"not null;uniqueIndex" json:"device_id"`
+	Device          Device    `gorm:"foreignKey:DeviceID" json:"-"`
+	Vendor          string    `gorm:"size:100" json:"vendor"`
+	Model           string    `gorm:"size:100;index" json:"model"`
+	BoardName       string    `gorm:"size:100" json:"board_name"`
+	SerialNumber    string    `gorm:"size:100;index" json:"serial_number"`
+	Architecture    string    `gorm:"size:50" json:"architecture"`
+	CPUModel        string    `gorm:"size:200" json:"cpu_model"`
+	CPUCount        int       `json:"cpu_count"`
+	MemoryTotal     int64     `json:"memory_total"` // 字节
+	OSVersion       string    `gorm:"size:100" json:"os_version"`
+	OSVersionKey    string    `gorm:"size:50;index" json:"-"` // 可按字典序比较的版本，见 VersionSortKey
+	FirmwareVersion string    `gorm:"size:100" json:"firmware_version"`
+	UpgradeFirmware string    `gorm:"size:100" json:"upgrade_firmware"`
+	Kernel          string    `gorm:"size:100" json:"kernel"`
+	Distro          string    `gorm:"size:200" json:"distro"`
+	Packages        string    `gorm:"type:text" json:"packages"` // 已安装软件包（JSON）
+	Disks           string    `gorm:"type:text" json:"disks"`    // 磁盘（JSON）
+	DiskTotal       int64     `json:"disk_total"`                // 字节
+	CollectedAt     time.Time `gorm:"index" json:"collected_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DeviceInventory) TableName() string {
+	return "device_inventories"
+}
+
+// BeforeSave 保存前计算版本排序键
+func (i *DeviceInventory) BeforeSave(tx *gorm.DB) error {
+	i.OSVersionKey = VersionSortKey(i.OSVersion)
+	return nil
+}
+
+// DeviceInventoryChange 设备清单字段变更历史
+type DeviceInventoryChange struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
+	DeviceID       uint      `gorm:"not null;index" json:"device_id"`
+	Field          string    `gorm:"size:50;not null" json:"field"`
+	OldValue       string    `gorm:"type:text" json:"old_value"`
+	NewValue       string    `gorm:"type:text" json:"new_value"`
+	ChangedAt      time.Time `gorm:"index" json:"changed_at"`
+}
+
+// TableName 指定表名
+func (DeviceInventoryChange) TableName() string {
+	return "device_inventory_changes"
+}
+
+// versionSortKeyParts 版本排序键的分段数
+const versionSortKeyParts = 4
+
+// VersionSortKey 将版本号转换为可按字典序比较的键
+// 取开头的数字分段（如 "7.12.1 (stable)" -> 7、12、1），每段补零到 5 位（超出按 99999）、不足 4 段补 0，无法解析时返回空字符串
+func VersionSortKey(version string) string {
+	parts := make([]string, 0, versionSortKeyParts)
+	current := ""
+	for _, r := range strings.TrimSpace(version) {
+		if r >= '0' && r <= '9' {
+			current += string(r)
+			continue
+		}
+		if current == "" || r != '.' {
+			break
+		}
+		parts = append(parts, current)
+		current = ""
+		if len(parts) == versionSortKeyParts {
+			break
+		}
+	}
+	if current != "" && len(parts) < versionSortKeyParts {
+		parts = append(parts, current)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	for len(parts) < versionSortKeyParts {
+		parts = append(parts, "0")
+	}
+	for i, part := range parts {
+		part = strings.TrimLeft(part, "0")
+		if len(part) > 5 {
+			part = "99999"
+		}
+		parts[i] = strings.Repeat("0", 5-len(part)) + part
+	}
+	return strings.Join(parts, ".")
+}
+
+// UpgradePackage 平台上保存的 RouterOS 升级包（.npk），用于离线升级
+type UpgradePackage struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	FileName     string    `gorm:"size:255;not null;uniqueIndex" json:"file_name"`
+	Name         string    `gorm:"size:100;not null" json:"name"` // 包名，如 routeros、wireless
+	Version      string    `gorm:"size:50;not null;index" json:"version"`
+	Architecture string    `gorm:"size:50" json:"architecture"` // arm64、arm、mipsbe 等，x86 包为 x86
+	Size         int64     `json:"size"`
+	SHA256       string    `gorm:"column:sha256;size:64" json:"sha256"`
+	UploadedBy   string    `gorm:"size:100" json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UpgradePackage) TableName() string {
+	return "upgrade_packages"
+}
+
+// UpgradeJobStatus 升级任务状态
+type UpgradeJobStatus string
+
+const (
+	UpgradeJobStatusUploading   UpgradeJobStatus = "uploading"   // 正在上传升级包
+	UpgradeJobStatusRebooting   UpgradeJobStatus = "rebooting"   // 正在滚动重启
+	UpgradeJobStatusCompleted   UpgradeJobStatus = "completed"   // 全部设备升级成功
+	UpgradeJobStatusFailed      UpgradeJobStatus = "failed"      // 遇到失败后停止
+	UpgradeJobStatusCancelled   UpgradeJobStatus = "cancelled"   // 被用户取消
+	UpgradeJobStatusInterrupted UpgradeJobStatus = "interrupted" // 服务重启导致中断
+)
+
+// UpgradeDeviceStatus 单台设备的升级状态
+type UpgradeDeviceStatus string
+
+const (
+	UpgradeDeviceStatusPending   UpgradeDeviceStatus = "pending"   // 等待上传
+	UpgradeDeviceStatusUploading UpgradeDeviceStatus = "uploading" // 上传中
+	UpgradeDeviceStatusUploaded  UpgradeDeviceStatus = "uploaded"  // 已上传，等待重启
+	UpgradeDeviceStatusRebooting UpgradeDeviceStatus = "rebooting" // 重启并等待恢复
+	UpgradeDeviceStatusSuccess   UpgradeDeviceStatus = "success"   // 升级成功
+	UpgradeDeviceStatusFailed    UpgradeDeviceStatus = "failed"    // 升级失败
+	UpgradeDeviceStatusSkipped   UpgradeDeviceStatus = "skipped"   // 任务停止，未执行
+)
+
+// UpgradeJob RouterOS 升级任务
+// 先将升级包上传到全部设备，再按顺序分批重启，每批设备恢复在线并重新推送数据后才继续下一批
+type UpgradeJob struct {
+	ID              uint               `gorm:"primaryKey" json:"id"`
+	OrganizationID  uint               `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织
+	Name            string             `gorm:"size:100" json:"name"`
+	PackageIDs      string             `gorm:"type:text" json:"package_ids"` // 升级包 ID 列表（JSON）
+	TargetVersion   string             `gorm:"size:50" json:"target_version"`
+	BatchSize       int                `json:"batch_size"`     // 每批同时重启的设备数
+	HealthTimeout   int                `json:"health_timeout"` // 重启后等待恢复的超时（秒）
+	RequirePush     bool               `json:"require_push"`   // 是否要求设备重启后重新推送数据
+	UpgradeFirmware bool               `json:"upgrade_firmware"`
+	Status          UpgradeJobStatus   `gorm:"type:varchar(20);index" json:"status"`
+	Total           int                `json:"total"`
+	Succeeded       int                `json:"succeeded"`
+	Failed          int                `json:"failed"`
+	Error           string             `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy       uint               `gorm:"index" json:"created_by"`
+	CreatedByName   string             `gorm:"size:100" json:"created_by_name"`
+	StartedAt       *time.Time         `json:"started_at"`
+	FinishedAt      *time.Time         `json:"finished_at"`
+	CreatedAt       time.Time          `gorm:"index" json:"created_at"`
+	Devices         []UpgradeJobDevice `gorm:"foreignKey:JobID" json:"devices,omitempty"`
+}
+
+// TableName 指定表名
+func (UpgradeJob) TableName() string {
+	return "upgrade_jobs"
+}
+
+// UpgradeJobDevice 升级任务中单台设备的进度和日志
+type UpgradeJobDevice struct {
+	ID          uint                `gorm:"primaryKey" json:"id"`
+	JobID       uint                `gorm:"not null;index" json:"job_id"`
+	DeviceID    uint                `gorm:"not null;index" json:"device_id"`
+	DeviceName  string              `gorm:"size:100" json:"device_name"`
+	Position    int                 `json:"position"` // 重启顺序
+	Packages    string              `gorm:"type:text" json:"packages"` // 上传到该设备的包文件名（JSON）
+	Status      UpgradeDeviceStatus `gorm:"type:varchar(20)" json:"status"`
+	FromVersion string              `gorm:"size:100" json:"from_version"`
+	ToVersion   string              `gorm:"size:100" json:"to_version"`
+	Log         string              `gorm:"type:text" json:"log"`
+	Error       string              `gorm:"type:text" json:"error,omitempty"`
+	StartedAt   *time.Time          `json:"started_at"`
+	FinishedAt  *time.Time          `json:"finished_at"`
+}
+
+// TableName 指定表名
+func (UpgradeJobDevice) TableName() string {
+	return "upgrade_job_devices"
+}
@@ -144,29 +144,91 @@ type TimeSeriesResponse struct {
 
 // PushMetricsRequest 推送数据请求结构（设计文档定义）
 type PushMetricsRequest struct {
-	DeviceKey string            `json:"device_key"` // 设备 IP 作为标识
+	DeviceKey string            `json:"device_key"`        // 设备 IP 作为标识
+	SentAt    int64             `json:"sent_ts,omitempty"` // 推送时刻的设备时钟（Unix 毫秒），用于测量时钟偏差
 	Metrics   []PushMetricPoint `json:"metrics"`
 }
 
 // PushMetricPoint 推送指标点
 type PushMetricPoint struct {
-	Timestamp  int64                       `json:"ts"`         // Unix 毫秒时间戳
+	Timestamp  int64                       `json:"ts"`         // 采样时刻的设备时钟（Unix 毫秒），0 表示设备未提供
 	Interfaces map[string]InterfaceMetrics `json:"interfaces"` // 接口带宽数据
 	Pings      []PingMetric                `json:"pings"`      // Ping 数据
+	Services   *ServiceMetrics             `json:"services,omitempty"` // 可选服务指标模块，未启用或本周期未采集时为空
+}
+
+// ServiceMetrics 服务指标（PPP 会话、DHCP 租约、无线客户端、队列速率、硬件健康），由采集脚本的可选模块上报
+type ServiceMetrics struct {
+	PPP      *PPPMetrics          `json:"ppp,omitempty"`
+	DHCP     []DHCPServerMetrics  `json:"dhcp,omitempty"`
+	Wireless []WirelessMetrics    `json:"wireless,omitempty"`
+	Queues   []QueueMetrics       `json:"queues,omitempty"`
+	Health   []HealthSensorMetric `json:"health,omitempty"`
+}
+
+// PPPMetrics PPP 活动会话数
+type PPPMetrics struct {
+	Active int64 `json:"active"` // 全部活动会话
+	PPPoE  int64 `json:"pppoe"`  // PPPoE 会话
+}
+
+// DHCPServerMetrics 单个 DHCP 服务器的租约数
+type DHCPServerMetrics struct {
+	Server string `json:"server"`
+	Bound  int64  `json:"bound"` // 已绑定租约
+	Total  int64  `json:"total"` // 全部租约（含静态和等待中）
+}
+
+// WirelessMetrics 单个无线接口的注册客户端
+type WirelessMetrics struct {
+	Interface string `json:"iface"`
+	Clients   int64  `json:"clients"`    // 注册表客户端数
+	AvgSignal int64  `json:"avg_signal"` // 平均信号强度 dBm
+	MinSignal int64  `json:"min_signal"` // 最弱信号强度 dBm
+}
+
+// QueueMetrics 单个简单队列的实时速率
+type QueueMetrics struct {
+	Name         string `json:"name"`
+	UploadRate   int64  `json:"upload_rate"`   // 上传速率 bps
+	DownloadRate int64  `json:"download_rate"` // 下载速率 bps
+}
+
+// HealthSensorMetric /system health 的一项原始读数，由服务端归一化名称和单位
+type HealthSensorMetric struct {
+	Name  string `json:"name"`           // 如 cpu-temperature、fan1-speed、psu1-state
+	Value string `json:"value"`          // 数值或状态文本（如 ok、fail）
+	Type  string `json:"type,omitempty"` // 单位（C、F、V、RPM、W、A），RouterOS 6 不提供
 }
 
 // InterfaceMetrics 接口带宽指标
 type InterfaceMetrics struct {
-	RxRate int64 `json:"rx_rate"` // 接收速率 bps
-	TxRate int64 `json:"tx_rate"` // 发送速率 bps
+	RxRate    int64 `json:"rx_rate"`              // 接收速率 bps
+	TxRate    int64 `json:"tx_rate"`              // 发送速率 bps
+	RxPackets *int64 `json:"rx_packets,omitempty"` // 接收包速率 pps，旧版脚本不提供
+	TxPackets *int64 `json:"tx_packets,omitempty"` // 发送包速率 pps，旧版脚本不提供
+	// 以下为接口累计计数，服务端根据相邻两次采样换算为每秒速率
+	RxErrors *int64 `json:"rx_errors,omitempty"` // 接收错误累计数
+	TxErrors *int64 `json:"tx_errors,omitempty"` // 发送错误累计数
+	RxDrops  *int64 `json:"rx_drops,omitempty"`  // 接收丢包累计数
+	TxDrops  *int64 `json:"tx_drops,omitempty"`  // 发送丢包累计数
+	// 接口运行状态（链路是否 up），用于记录状态变化事件
+	Running *bool `json:"running,omitempty"`
 }
 
 // PingMetric Ping 指标
 type PingMetric struct {
 	Target    string `json:"target"`     // 目标地址
 	SrcIface  string `json:"src_iface"`  // 源接口
-	Latency   int64  `json:"latency"`    // 延迟 ms，0 表示丢包
+	Latency   int64  `json:"latency"`    // 平均延迟 us，0 表示丢包
 	Status    string `json:"status"`     // up/down
+	// 以下字段由多包探测脚本上报，旧版单包脚本不提供
+	MinLatency int64 `json:"min_latency,omitempty"` // 最小延迟 us
+	MaxLatency int64 `json:"max_latency,omitempty"` // 最大延迟 us
+	Jitter     int64 `json:"jitter,omitempty"`      // 抖动 us
+	Sent       int   `json:"sent,omitempty"`        // 发送包数
+	Received   int   `json:"received,omitempty"`    // 接收包数
+	VoIP       bool  `json:"voip,omitempty"`        // 是否为 VoIP 目标
 }
 
 // BandwidthPushRequest 带宽数据推送请求
@@ -191,4 +253,16 @@ type DeviceOnlineStatus struct {
 	Status     string     `json:"status"`      // online/offline/unknown
 	LastSeen   *time.Time `json:"last_seen"`
 	LastPushAt *time.Time `json:"last_push_at"`
+	ClockSkew  *ClockSkew `json:"clock_skew,omitempty"` // 设备时钟偏差
+}
+
+// ClockSkew 设备时钟偏差
+// SkewMs 为服务器时钟减去设备时钟，正值表示设备时钟落后
+type ClockSkew struct {
+	DeviceID   uint      `json:"device_id"`
+	SkewMs     int64     `json:"skew_ms"`     // 平滑后的偏差（毫秒）
+	LastSample int64     `json:"last_sample"` // 最近一次测量值（毫秒）
+	Samples    int64     `json:"samples"`     // 累计测量次数
+	Corrected  bool      `json:"corrected"`   // 偏差是否超过阈值并被用于校正时间戳
+	MeasuredAt time.Time `json:"measured_at"`
 }
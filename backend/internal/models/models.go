@@ -7,12 +7,20 @@ import (
 // AllModels 返回所有需要迁移的模型
 func AllModels() []interface{} {
 	return []interface{}{
+		// 组织（租户）
+		&Organization{},
+		&OrganizationSetting{},
+
 		// 用户相关模型
 		&User{},
 		&Role{},
 		&Permission{},
 		&UserRole{},
 		&RolePermission{},
+		&UserSession{},
+		&UserTwoFactor{},
+		&UserRecoveryCode{},
+		&APIToken{},
 
 		// 设备相关模型
 		&Device{},
@@ -28,6 +36,24 @@ func AllModels() []interface{} {
 		&PingTarget{},
 		&SystemSetting{},
 		&UserDevicePermission{},
+		&DeviceGrant{},
+		&InterfaceStateEvent{},
+		&RoutingNeighbor{},
+		&RoutingNeighborEvent{},
+		&Probe{},
+		&SyslogMessage{},
+		&SNMPTrap{},
+		&ConfigBackup{},
+		&CommandJob{},
+		&CommandJobResult{},
+		&DeviceInventory{},
+		&DeviceInventoryChange{},
+		&UpgradePackage{},
+		&UpgradeJob{},
+		&UpgradeJobDevice{},
+
+		// 审计日志
+		&AuditLog{},
 
 		// 插件相关模型
 		&Plugin{},
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -26,6 +29,7 @@ const (
 // Proxy 代理模型
 type Proxy struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织
 	Name           string         `gorm:"not null;size:100" json:"name"`
 	Type           ProxyType      `gorm:"type:varchar(20);not null" json:"type"`
 	
@@ -82,6 +86,16 @@ type CollectorScript struct {
 	ScriptName     string          `gorm:"size:64;default:'nmp-collector'" json:"script_name"`
 	SchedulerName  string          `gorm:"size:64;default:'nmp-scheduler'" json:"scheduler_name"`
 	
+	// 可选服务指标模块（仅 RouterOS），修改后需重新部署脚本
+	CollectPPP         bool        `gorm:"default:false" json:"collect_ppp"`                   // PPP 活动会话数
+	CollectDHCP        bool        `gorm:"default:false" json:"collect_dhcp"`                  // 每个 DHCP 服务器的租约数
+	CollectWireless    bool        `gorm:"default:false" json:"collect_wireless"`              // 无线注册客户端数和信号
+	WirelessMenu       string      `gorm:"size:16;default:'wireless'" json:"wireless_menu"`    // wireless（旧驱动）或 wifi（RouterOS 7 wifi 包）
+	CollectQueues      bool        `gorm:"default:false" json:"collect_queues"`                // 简单队列速率
+	MaxQueues          int         `gorm:"default:30" json:"max_queues"`                       // 每次最多采集的队列数
+	CollectHealth      bool        `gorm:"default:false" json:"collect_health"`                // 温度、电压、风扇和电源状态
+	ServiceIntervalSec int         `gorm:"default:30" json:"service_interval_sec"`             // 服务指标采集间隔（秒）
+	
 	DeployedAt     *time.Time      `json:"deployed_at,omitempty"`
 	LastPushAt     *time.Time      `json:"last_push_at,omitempty"`
 	PushCount      int64           `gorm:"default:0" json:"push_count"`
@@ -101,6 +115,7 @@ func (CollectorScript) TableName() string {
 // PingTarget Ping 目标模型
 type PingTarget struct {
 	ID              uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID  uint           `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
 	DeviceID        uint           `gorm:"not null;index" json:"device_id"`
 	Device          Device         `gorm:"foreignKey:DeviceID" json:"-"`
 	
@@ -109,6 +124,13 @@ type PingTarget struct {
 	SourceInterface string         `gorm:"size:100;default:''" json:"source_interface"`
 	Enabled         bool           `gorm:"default:true" json:"enabled"`
 	
+	// 探测参数：每个采集周期发送一组（burst）ping，用于计算抖动和丢包率
+	Count           int            `gorm:"default:1" json:"count"`         // 每组发送包数
+	PacketSize      int            `gorm:"default:0" json:"packet_size"`   // 包大小（字节），0 使用设备默认值
+	IntervalMs      int            `gorm:"default:100" json:"interval_ms"` // 组内发包间隔（毫秒）
+	DSCP            int            `gorm:"default:0" json:"dscp"`          // DSCP 标记 0-63
+	VoIP            bool           `gorm:"default:false" json:"voip"`      // 是否为 VoIP 目标（计算 MOS）
+	
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
@@ -119,6 +141,55 @@ func (PingTarget) TableName() string {
 	return "ping_targets"
 }
 
+// Ping 探测参数范围
+const (
+	PingMaxCount          = 100
+	PingMinPacketSize     = 28
+	PingMaxPacketSize     = 65500
+	PingMinIntervalMs     = 10
+	PingMaxIntervalMs     = 5000
+	PingMaxDSCP           = 63
+	PingDefaultIntervalMs = 100
+)
+
+// ValidateProbeOptions 校验 Ping 探测参数，并为未设置的字段填充默认值
+func (p *PingTarget) ValidateProbeOptions() error {
+	if p.Count == 0 {
+		p.Count = 1
+	}
+	if p.IntervalMs == 0 {
+		p.IntervalMs = PingDefaultIntervalMs
+	}
+	if p.Count < 1 || p.Count > PingMaxCount {
+		return NewValidationError(fmt.Sprintf("count must be between 1 and %d", PingMaxCount))
+	}
+	if p.PacketSize != 0 && (p.PacketSize < PingMinPacketSize || p.PacketSize > PingMaxPacketSize) {
+		return NewValidationError(fmt.Sprintf("packet_size must be between %d and %d", PingMinPacketSize, PingMaxPacketSize))
+	}
+	if p.IntervalMs < PingMinIntervalMs || p.IntervalMs > PingMaxIntervalMs {
+		return NewValidationError(fmt.Sprintf("interval_ms must be between %d and %d", PingMinIntervalMs, PingMaxIntervalMs))
+	}
+	if p.DSCP < 0 || p.DSCP > PingMaxDSCP {
+		return NewValidationError(fmt.Sprintf("dscp must be between 0 and %d", PingMaxDSCP))
+	}
+	return nil
+}
+
+// CollectorDefaultIntervalMs 未配置采集器时使用的采集间隔（毫秒），与 CollectorScript.IntervalMs 的默认值一致
+const CollectorDefaultIntervalMs = 1000
+
+// ValidateBurstDuration 校验一组探测的耗时（count × interval_ms）不超过采集间隔
+// 探测在采集脚本的采样循环中同步执行，耗时超过采集间隔会拖慢接口采样
+func (p *PingTarget) ValidateBurstDuration(collectIntervalMs int) error {
+	if collectIntervalMs <= 0 {
+		collectIntervalMs = CollectorDefaultIntervalMs
+	}
+	if burst := p.Count * p.IntervalMs; burst > collectIntervalMs {
+		return NewValidationError(fmt.Sprintf("count × interval_ms (%d ms) must not exceed the collector interval (%d ms)", burst, collectIntervalMs))
+	}
+	return nil
+}
+
 // SystemSetting 系统设置模型
 type SystemSetting struct {
 	Key         string    `gorm:"primaryKey;size:100" json:"key"`
@@ -157,3 +228,347 @@ func (u *UserDevicePermission) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// 设备权限操作
+const (
+	DeviceActionRead   = "read"
+	DeviceActionUpdate = "update"
+	DeviceActionDelete = "delete"
+	DeviceActionDeploy = "deploy" // 部署操作级别；批量命令和升级沿用 update 权限
+)
+
+// DeviceActions 全部设备权限操作
+var DeviceActions = []string{DeviceActionRead, DeviceActionUpdate, DeviceActionDelete, DeviceActionDeploy}
+
+// DeviceGrant 设备授权
+// 授权给用户或角色（二选一），范围为设备分组（含全部下级分组）、标签或单台设备，
+// 三者都为空时授权全部设备。每条授权分别指定允许的操作
+type DeviceGrant struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        *uint     `gorm:"index" json:"user_id"`
+	RoleID        *uint     `gorm:"index" json:"role_id"`
+	DeviceGroupID *uint     `gorm:"index" json:"device_group_id"`
+	TagID         *uint     `gorm:"index" json:"tag_id"`
+	DeviceID      *uint     `gorm:"index" json:"device_id"`
+	CanRead       bool      `gorm:"not null;default:false" json:"can_read"`
+	CanUpdate     bool      `gorm:"not null;default:false" json:"can_update"`
+	CanDelete     bool      `gorm:"not null;default:false" json:"can_delete"`
+	CanDeploy     bool      `gorm:"not null;default:false" json:"can_deploy"`
+	CreatedBy     uint      `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DeviceGrant) TableName() string {
+	return "device_grants"
+}
+
+// Allows 授权是否允许指定操作
+func (g *DeviceGrant) Allows(action string) bool {
+	switch action {
+	case DeviceActionRead:
+		return g.CanRead
+	case DeviceActionUpdate:
+		return g.CanUpdate
+	case DeviceActionDelete:
+		return g.CanDelete
+	case DeviceActionDeploy:
+		return g.CanDeploy
+	}
+	return false
+}
+
+// InterfaceEventSource 接口状态事件来源
+type InterfaceEventSource string
+
+const (
+	InterfaceEventSourcePush InterfaceEventSource = "push" // 采集脚本推送
+	InterfaceEventSourcePoll InterfaceEventSource = "poll" // 服务端 SSH 轮询
+	InterfaceEventSourceTrap InterfaceEventSource = "trap" // SNMP linkDown/linkUp 通知
+)
+
+// InterfaceStateEvent 接口链路状态变化事件
+// 每条记录表示一次状态转换，与带宽时序数据分开存储
+type InterfaceStateEvent struct {
+	ID             uint                 `gorm:"primaryKey" json:"id"`
+	OrganizationID uint                 `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
+	DeviceID       uint                 `gorm:"not null;index:idx_iface_events_device_iface_time,priority:1" json:"device_id"`
+	Device         Device               `gorm:"foreignKey:DeviceID" json:"-"`
+	InterfaceName  string               `gorm:"not null;size:100;index:idx_iface_events_device_iface_time,priority:2" json:"interface_name"`
+	OldStatus      InterfaceStatus      `gorm:"type:varchar(20)" json:"old_status"`
+	NewStatus      InterfaceStatus      `gorm:"type:varchar(20);not null" json:"new_status"`
+	Source         InterfaceEventSource `gorm:"type:varchar(20)" json:"source"`
+	OccurredAt     time.Time            `gorm:"not null;index:idx_iface_events_device_iface_time,priority:3" json:"occurred_at"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+// TableName 指定表名
+func (InterfaceStateEvent) TableName() string {
+	return "interface_state_events"
+}
+
+// InterfaceFlapCount 接口抖动统计
+type InterfaceFlapCount struct {
+	InterfaceName string    `json:"interface_name"`
+	Flaps         int64     `json:"flaps"`       // up -> down 次数
+	Transitions   int64     `json:"transitions"` // 状态变化总次数
+	LastChange    time.Time `json:"last_change"` // 最近一次状态变化时间
+}
+
+// RoutingProtocol 动态路由协议
+type RoutingProtocol string
+
+const (
+	RoutingProtocolBGP  RoutingProtocol = "bgp"
+	RoutingProtocolOSPF RoutingProtocol = "ospf"
+)
+
+// RoutingNeighborStateDown 上次采集存在、本次采集消失的邻居状态
+const RoutingNeighborStateDown = "down"
+
+// RoutingNeighbor 设备的 BGP 对等体或 OSPF 邻居当前状态（每次采集更新）
+type RoutingNeighbor struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	OrganizationID uint            `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
+	DeviceID       uint            `gorm:"not null;uniqueIndex:idx_routing_neighbors_key,priority:1" json:"device_id"`
+	Device         Device          `gorm:"foreignKey:DeviceID" json:"-"`
+	Protocol       RoutingProtocol `gorm:"type:varchar(10);not null;uniqueIndex:idx_routing_neighbors_key,priority:2" json:"protocol"`
+	Instance       string          `gorm:"size:100;not null;default:'';uniqueIndex:idx_routing_neighbors_key,priority:3" json:"instance"` // RouterOS 实例/区域，FRR 地址族
+	Neighbor       string          `gorm:"size:100;not null;uniqueIndex:idx_routing_neighbors_key,priority:4" json:"neighbor"`            // BGP 对端地址，OSPF 邻居 router-id
+	Name           string          `gorm:"size:100" json:"name"`
+	Address        string          `gorm:"size:100" json:"address"`
+	Interface      string          `gorm:"size:100;not null;default:'';uniqueIndex:idx_routing_neighbors_key,priority:5" json:"interface"` // 同一 OSPF 邻居可经多个接口建立邻接
+	RemoteAS       int64           `json:"remote_as"`
+	LocalAS        int64           `json:"local_as"`
+	State          string          `gorm:"size:30;not null" json:"state"`
+	Established    bool            `gorm:"default:false;index" json:"established"` // BGP 为 Established，OSPF 为 Full
+	Uptime         int64           `json:"uptime"`                                 // 秒
+	PrefixCount    int64           `json:"prefix_count"`
+	LastChange     time.Time       `json:"last_change"` // 最近一次状态变化时间
+	CollectedAt    time.Time       `json:"collected_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (RoutingNeighbor) TableName() string {
+	return "routing_neighbors"
+}
+
+// RoutingNeighborEvent 路由邻居状态变化事件
+type RoutingNeighborEvent struct {
+	ID             uint            `gorm:"primaryKey" json:"id"`
+	OrganizationID uint            `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，与设备相同
+	DeviceID       uint            `gorm:"not null;index:idx_routing_events_device_time,priority:1" json:"device_id"`
+	Device         Device          `gorm:"foreignKey:DeviceID" json:"-"`
+	Protocol       RoutingProtocol `gorm:"type:varchar(10);not null" json:"protocol"`
+	Instance       string          `gorm:"size:100" json:"instance"`
+	Neighbor       string          `gorm:"size:100;not null" json:"neighbor"`
+	Name           string          `gorm:"size:100" json:"name"`
+	OldState       string          `gorm:"size:30" json:"old_state"`
+	NewState       string          `gorm:"size:30;not null" json:"new_state"`
+	AdjacencyLost  bool            `gorm:"default:false;index" json:"adjacency_lost"` // 离开 Established/Full 状态
+	OccurredAt     time.Time       `gorm:"not null;index;index:idx_routing_events_device_time,priority:2" json:"occurred_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RoutingNeighborEvent) TableName() string {
+	return "routing_neighbor_events"
+}
+
+// ProbeType 服务端探测类型
+type ProbeType string
+
+const (
+	ProbeTypeICMP ProbeType = "icmp"
+	ProbeTypeTCP  ProbeType = "tcp"
+	ProbeTypeHTTP ProbeType = "http" // 支持 http:// 和 https://
+	ProbeTypeDNS  ProbeType = "dns"
+)
+
+// Probe 服务端主动探测模型
+// 由平台服务器（或通过代理从客户网络）定期探测服务可用性
+type Probe struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织
+	Name           string     `gorm:"not null;size:100" json:"name"`
+	Type           ProbeType  `gorm:"type:varchar(20);not null" json:"type"`
+	Target         string     `gorm:"not null;size:500" json:"target"`          // ICMP/TCP 为主机，HTTP 为 URL，DNS 为 DNS 服务器
+	Port           int        `gorm:"default:0" json:"port"`                    // TCP 端口
+	DNSQuery       string     `gorm:"size:255" json:"dns_query"`                // DNS 探测解析的域名
+	HTTPMethod     string     `gorm:"size:10;default:'GET'" json:"http_method"` // HTTP 方法
+	ExpectedStatus int        `gorm:"default:0" json:"expected_status"`         // 期望的 HTTP 状态码，0 表示 2xx/3xx
+	TLSSkipVerify  bool       `gorm:"default:false" json:"tls_skip_verify"`     // 跳过证书校验
+	IntervalSec    int        `gorm:"default:60" json:"interval_sec"`           // 探测间隔（秒）
+	TimeoutMs      int        `gorm:"default:5000" json:"timeout_ms"`           // 超时时间（毫秒）
+	ProxyID        *uint      `gorm:"index" json:"proxy_id,omitempty"`          // 通过代理探测，从客户网络发起
+	Enabled        bool       `gorm:"default:true" json:"enabled"`
+	LastStatus     string     `gorm:"size:20;default:'unknown'" json:"last_status"` // up/down/unknown
+	LastError      string     `gorm:"size:500" json:"last_error,omitempty"`
+	LastCheckedAt  *time.Time `json:"last_checked_at,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 指定表名
+func (Probe) TableName() string {
+	return "probes"
+}
+
+// Validate 校验探测配置，并为未设置的字段填充默认值
+func (p *Probe) Validate() error {
+	if p.Name == "" {
+		return NewValidationError("name is required")
+	}
+	if p.Target == "" && p.Type != ProbeTypeDNS {
+		return NewValidationError("target is required")
+	}
+	switch p.Type {
+	case ProbeTypeICMP:
+		if p.ProxyID != nil && *p.ProxyID > 0 {
+			return NewValidationError("icmp probes cannot run through a proxy")
+		}
+	case ProbeTypeTCP:
+		if p.Port <= 0 || p.Port > 65535 {
+			return NewValidationError("port must be between 1 and 65535")
+		}
+	case ProbeTypeHTTP:
+		if !strings.HasPrefix(p.Target, "http://") && !strings.HasPrefix(p.Target, "https://") {
+			return NewValidationError("target must be an http:// or https:// URL")
+		}
+		if p.HTTPMethod == "" {
+			p.HTTPMethod = "GET"
+		}
+	case ProbeTypeDNS:
+		if p.DNSQuery == "" {
+			return NewValidationError("dns_query is required")
+		}
+		if p.Target == "" && p.ProxyID != nil && *p.ProxyID > 0 {
+			return NewValidationError("dns server is required when using a proxy")
+		}
+	default:
+		return NewValidationError(fmt.Sprintf("unsupported probe type: %s", p.Type))
+	}
+	if p.IntervalSec == 0 {
+		p.IntervalSec = 60
+	}
+	if p.TimeoutMs == 0 {
+		p.TimeoutMs = 5000
+	}
+	if p.IntervalSec < 10 || p.IntervalSec > 86400 {
+		return NewValidationError("interval_sec must be between 10 and 86400")
+	}
+	if p.TimeoutMs < 100 || p.TimeoutMs > 60000 {
+		return NewValidationError("timeout_ms must be between 100 and 60000")
+	}
+	if time.Duration(p.TimeoutMs)*time.Millisecond >= time.Duration(p.IntervalSec)*time.Second {
+		return NewValidationError("timeout_ms must be shorter than interval_sec")
+	}
+	return nil
+}
+
+// SyslogSeverity syslog 严重级别（数值越小越严重）
+type SyslogSeverity int
+
+const (
+	SyslogSeverityEmergency SyslogSeverity = iota
+	SyslogSeverityAlert
+	SyslogSeverityCritical
+	SyslogSeverityError
+	SyslogSeverityWarning
+	SyslogSeverityNotice
+	SyslogSeverityInfo
+	SyslogSeverityDebug
+)
+
+// syslogSeverityNames 严重级别名称
+var syslogSeverityNames = []string{"emergency", "alert", "critical", "error", "warning", "notice", "info", "debug"}
+
+// String 返回严重级别名称
+func (s SyslogSeverity) String() string {
+	if s < 0 || int(s) >= len(syslogSeverityNames) {
+		return "unknown"
+	}
+	return syslogSeverityNames[s]
+}
+
+// ParseSyslogSeverity 解析严重级别名称或数值
+func ParseSyslogSeverity(value string) (SyslogSeverity, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for i, name := range syslogSeverityNames {
+		if value == name || value == strconv.Itoa(i) {
+			return SyslogSeverity(i), nil
+		}
+	}
+	switch value {
+	case "emerg", "panic":
+		return SyslogSeverityEmergency, nil
+	case "crit":
+		return SyslogSeverityCritical, nil
+	case "err":
+		return SyslogSeverityError, nil
+	case "warn":
+		return SyslogSeverityWarning, nil
+	}
+	return 0, NewValidationError(fmt.Sprintf("invalid syslog severity: %s", value))
+}
+
+// SyslogMessage 设备 syslog 日志
+// 来源地址无法匹配设备时 DeviceID 为空，仍保留日志以便排查
+type SyslogMessage struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，来源无法匹配设备时为默认组织
+	DeviceID       *uint          `gorm:"index:idx_syslog_device_time,priority:1" json:"device_id,omitempty"`
+	SourceIP       string         `gorm:"size:45;not null;index" json:"source_ip"`
+	Facility       int            `json:"facility"`
+	Severity       SyslogSeverity `gorm:"not null;index" json:"severity"`
+	SeverityName   string         `gorm:"-" json:"severity_name"`
+	Hostname       string         `gorm:"size:255" json:"hostname,omitempty"`
+	AppName        string         `gorm:"size:100" json:"app_name,omitempty"`
+	ProcID         string         `gorm:"size:128" json:"proc_id,omitempty"`
+	MsgID          string         `gorm:"size:32" json:"msg_id,omitempty"`
+	StructuredData string         `gorm:"type:text" json:"structured_data,omitempty"`
+	Message        string         `gorm:"type:text" json:"message"`
+	Timestamp      time.Time      `json:"timestamp"` // 设备上报的时间
+	ReceivedAt     time.Time      `gorm:"not null;index;index:idx_syslog_device_time,priority:2" json:"received_at"`
+}
+
+// TableName 指定表名
+func (SyslogMessage) TableName() string {
+	return "syslog_messages"
+}
+
+// AfterFind 填充严重级别名称
+func (m *SyslogMessage) AfterFind(tx *gorm.DB) error {
+	m.SeverityName = m.Severity.String()
+	return nil
+}
+
+// SNMPTrap 接收到的 SNMP trap/inform 通知
+// 按来源 IP 归属到设备，接口相关的通知按 ifIndex 关联到 Interface
+type SNMPTrap struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织，来源无法匹配设备时为默认组织
+	DeviceID       *uint     `gorm:"index:idx_snmp_traps_device_time,priority:1" json:"device_id,omitempty"`
+	InterfaceID    *uint     `gorm:"index" json:"interface_id,omitempty"`
+	InterfaceName  string    `gorm:"size:100" json:"interface_name,omitempty"`
+	IfIndex        int       `json:"if_index,omitempty"`
+	SourceIP       string    `gorm:"size:45;not null;index" json:"source_ip"`
+	Version        string    `gorm:"size:8" json:"version"`                  // v1、v2c、v3
+	SecurityName   string    `gorm:"size:64" json:"security_name,omitempty"` // SNMPv3 用户名
+	TrapOID        string    `gorm:"column:trap_oid;size:255;not null;index" json:"trap_oid"`
+	TrapName       string    `gorm:"size:100" json:"trap_name"`
+	Inform         bool      `gorm:"default:false" json:"inform"`
+	Uptime         int64     `json:"uptime"`                                    // 设备 sysUpTime，单位 1/100 秒
+	VarBinds       string    `gorm:"column:varbinds;type:text" json:"varbinds"` // JSON 格式的变量绑定
+	ReceivedAt     time.Time `gorm:"not null;index;index:idx_snmp_traps_device_time,priority:2" json:"received_at"`
+}
+
+// TableName 指定表名
+func (SNMPTrap) TableName() string {
+	return "snmp_traps"
+}
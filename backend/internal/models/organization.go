@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationID 默认组织，升级前的数据和未指定组织的数据都归属默认组织
+// 默认组织中拥有 admin 角色的用户是全局超级管理员，可以切换到其他组织
+const DefaultOrganizationID uint = 1
+
+// Organization 组织（租户）
+// 设备、设备分组、标签、代理、Ping 目标、设置和用户都归属于一个组织，不同组织的数据互相隔离
+type Organization struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Name          string         `gorm:"uniqueIndex;not null;size:50" json:"name"`
+	DisplayName   string         `gorm:"size:100" json:"display_name"`
+	Description   string         `gorm:"size:255" json:"description"`
+	MaxDevices    int            `gorm:"not null;default:0" json:"max_devices"`    // 设备数量上限，0 表示不限制
+	RetentionDays int            `gorm:"not null;default:0" json:"retention_days"` // 时序数据保留天数，0 使用系统设置
+	Enabled       bool           `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName 指定表名
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// OrganizationSetting 组织级设置，覆盖同名的系统设置
+type OrganizationSetting struct {
+	OrganizationID uint      `gorm:"primaryKey" json:"organization_id"`
+	Key            string    `gorm:"primaryKey;size:100" json:"key"`
+	Value          string    `gorm:"type:text;not null" json:"value"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (OrganizationSetting) TableName() string {
+	return "organization_settings"
+}
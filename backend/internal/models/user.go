@@ -15,19 +15,30 @@ const (
 	UserStatusBlocked  UserStatus = "blocked"
 )
 
+// 用户认证来源
+const (
+	UserAuthSourceLocal   = "local"   // 本地账号，使用本地密码登录
+	UserAuthSourceLDAP    = "ldap"    // LDAP / AD 账号，密码由目录服务校验
+	UserAuthSourceOIDC    = "oidc"    // OIDC 单点登录账号，由身份提供者认证
+	UserAuthSourceService = "service" // 服务账号，只能通过 API 令牌访问，不能交互式登录
+)
+
 // User 用户模型
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Username  string         `gorm:"unique;not null;size:50" json:"username"`
-	Password  string         `gorm:"not null;size:255" json:"-"` // 不在JSON中返回密码
-	Email     string         `gorm:"unique;size:100" json:"email"`
-	FullName  string         `gorm:"size:100" json:"full_name"`
-	Status    UserStatus     `gorm:"type:varchar(20);default:'active'" json:"status"`
-	LastLogin *time.Time     `json:"last_login"`
-	Roles     []Role         `gorm:"many2many:user_roles;" json:"roles"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	OrganizationID uint           `gorm:"not null;default:1;index;<-:create" json:"organization_id"` // 所属组织
+	Username       string         `gorm:"unique;not null;size:50" json:"username"`
+	Password       string         `gorm:"not null;size:255" json:"-"` // 不在JSON中返回密码
+	Email          string         `gorm:"unique;size:100" json:"email"`
+	FullName       string         `gorm:"size:100" json:"full_name"`
+	Status         UserStatus     `gorm:"type:varchar(20);default:'active'" json:"status"`
+	AuthSource     string         `gorm:"type:varchar(20);default:'local'" json:"auth_source"`
+	ExternalID     string         `gorm:"size:255;index" json:"external_id,omitempty"` // 外部身份标识，如 LDAP 条目 DN
+	LastLogin      *time.Time     `json:"last_login"`
+	Roles          []Role         `gorm:"many2many:user_roles;" json:"roles"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -37,16 +48,17 @@ func (User) TableName() string {
 
 // Role 角色模型
 type Role struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	Name        string         `gorm:"unique;not null;size:50" json:"name"`
-	DisplayName string         `gorm:"size:100" json:"display_name"`
-	Description string         `gorm:"size:255" json:"description"`
-	IsSystem    bool           `gorm:"default:false" json:"is_system"` // 系统角色不可删除
-	Users       []User         `gorm:"many2many:user_roles;" json:"-"`
-	Permissions []Permission   `gorm:"many2many:role_permissions;" json:"permissions"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"unique;not null;size:50" json:"name"`
+	DisplayName      string         `gorm:"size:100" json:"display_name"`
+	Description      string         `gorm:"size:255" json:"description"`
+	IsSystem         bool           `gorm:"default:false" json:"is_system"`          // 系统角色不可删除
+	RequireTwoFactor bool           `gorm:"default:false" json:"require_two_factor"` // 拥有该角色的用户必须启用两步验证
+	Users            []User         `gorm:"many2many:user_roles;" json:"-"`
+	Permissions      []Permission   `gorm:"many2many:role_permissions;" json:"permissions"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName 指定表名
@@ -59,7 +71,7 @@ type Permission struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
 	Resource    string         `gorm:"not null;size:50" json:"resource"`    // 资源类型，如 device, user, plugin
 	Action      string         `gorm:"not null;size:50" json:"action"`      // 操作类型，如 create, read, update, delete
-	Scope       string         `gorm:"size:50" json:"scope"`                // 权限范围，如 own, group, all
+	Scope       string         `gorm:"size:50" json:"scope"`                // 权限范围：global, group, self
 	Description string         `gorm:"size:255" json:"description"`
 	Roles       []Role         `gorm:"many2many:role_permissions;" json:"-"`
 	CreatedAt   time.Time      `json:"created_at"`
@@ -72,6 +84,28 @@ func (Permission) TableName() string {
 	return "permissions"
 }
 
+// 权限范围
+const (
+	PermissionScopeGlobal = "global" // 全部资源
+	PermissionScopeGroup  = "group"  // 用户所属设备分组中的资源
+	PermissionScopeSelf   = "self"   // 用户本人拥有的资源
+)
+
+// NormalizePermissionScope 规范化权限范围，兼容旧的 all、own 写法，空值视为 global
+// 无法识别的范围返回 false
+func NormalizePermissionScope(scope string) (string, bool) {
+	switch scope {
+	case "", "all", PermissionScopeGlobal:
+		return PermissionScopeGlobal, true
+	case PermissionScopeGroup:
+		return PermissionScopeGroup, true
+	case "own", PermissionScopeSelf:
+		return PermissionScopeSelf, true
+	default:
+		return "", false
+	}
+}
+
 // UserRole 用户角色关联表
 type UserRole struct {
 	UserID    uint      `gorm:"primaryKey" json:"user_id"`
@@ -94,4 +128,107 @@ type RolePermission struct {
 // TableName 指定表名
 func (RolePermission) TableName() string {
 	return "role_permissions"
-}
\ No newline at end of file
+}
+
+// 会话撤销原因
+const (
+	SessionRevokeLogout       = "logout"        // 用户登出
+	SessionRevokeLogoutAll    = "logout_all"    // 用户登出所有会话
+	SessionRevokeAdmin        = "admin"         // 管理员强制下线
+	SessionRevokeUserDisabled = "user_disabled" // 用户被停用或禁用
+	SessionRevokeTokenReuse   = "token_reuse"   // 已轮换的刷新令牌被重复使用
+)
+
+// UserSession 用户登录会话
+// 每次登录创建一个会话，访问令牌通过会话ID关联会话，刷新令牌每次使用后轮换；
+// 会话撤销后其访问令牌和刷新令牌立即失效
+type UserSession struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	SessionID           string     `gorm:"uniqueIndex;size:64;not null" json:"session_id"`
+	UserID              uint       `gorm:"index;not null" json:"user_id"`
+	RefreshTokenHash    string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	PreviousRefreshHash string     `gorm:"index;size:64" json:"-"` // 上一个刷新令牌，用于识别重复使用
+	RotatedAt           *time.Time `json:"rotated_at"`
+	IPAddress           string     `gorm:"size:64" json:"ip_address"`
+	UserAgent           string     `gorm:"size:255" json:"user_agent"`
+	ExpiresAt           time.Time  `gorm:"index" json:"expires_at"`
+	LastUsedAt          time.Time  `json:"last_used_at"`
+	RevokedAt           *time.Time `json:"revoked_at"`
+	RevokeReason        string     `gorm:"size:32" json:"revoke_reason,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
+
+// IsActive 会话是否有效（未撤销且未过期）
+func (s *UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// UserTwoFactor 用户两步验证（TOTP）配置
+// 开始绑定时创建，验证首个验证码后启用
+type UserTwoFactor struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	Secret    string     `gorm:"size:64;not null" json:"-"`
+	Enabled   bool       `gorm:"default:false" json:"enabled"`
+	EnabledAt *time.Time `json:"enabled_at"`
+	// LastUsedStep 最近一次使用的验证码时间步，防止验证码被重放
+	LastUsedStep int64     `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserTwoFactor) TableName() string {
+	return "user_two_factors"
+}
+
+// UserRecoveryCode 两步验证恢复码，每个恢复码只能使用一次
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"size:64;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_codes"
+}
+
+// APIToken 个人 API 令牌，供脚本和 CI 调用接口
+// 令牌明文只在创建时返回一次，数据库只保存 SHA-256 哈希；
+// 令牌权限是所属用户权限的子集，可进一步限定到指定设备或设备分组
+type APIToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"user_id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	TokenHash   string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	TokenPrefix string     `gorm:"size:16" json:"token_prefix"`  // 令牌明文开头几位，便于识别
+	Permissions string     `gorm:"type:text" json:"permissions"` // 允许的权限（JSON，resource:action）
+	DeviceIDs   string     `gorm:"type:text" json:"device_ids"`  // 限定的设备（JSON），与分组均为空时不限定
+	GroupIDs    string     `gorm:"type:text" json:"group_ids"`   // 限定的设备分组（JSON）
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at"`      // 为空时永不过期
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:64" json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedBy   uint       `json:"created_by"` // 创建人，管理员为服务账号创建令牌时与 UserID 不同
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (APIToken) TableName() string {
+	return "api_tokens"
+}
+
+// IsActive 令牌是否有效（未撤销且未过期）
+func (t *APIToken) IsActive(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
@@ -0,0 +1,108 @@
+package netflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+)
+
+// maxPacketSize UDP 报文最大长度
+const maxPacketSize = 65535
+
+// Handler 流记录处理函数，exporter 为导出器地址
+type Handler func(exporter net.IP, flows []Flow)
+
+// Collector NetFlow/IPFIX UDP 采集器
+type Collector struct {
+	address string
+	handler Handler
+	decoder *Decoder
+
+	conn    net.PacketConn
+	wg      sync.WaitGroup
+	running bool
+	mu      sync.Mutex
+}
+
+// NewCollector 创建采集器
+func NewCollector(address string, handler Handler) *Collector {
+	return &Collector{
+		address: address,
+		handler: handler,
+		decoder: NewDecoder(),
+	}
+}
+
+// Start 开始监听
+func (c *Collector) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", c.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", c.address, err)
+	}
+	c.conn = conn
+	c.running = true
+
+	c.wg.Add(1)
+	go c.serve(conn)
+	return nil
+}
+
+// Stop 停止监听
+func (c *Collector) Stop() {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return
+	}
+	c.running = false
+	c.conn.Close()
+	c.mu.Unlock()
+
+	c.wg.Wait()
+}
+
+// Addr 返回实际监听地址
+func (c *Collector) Addr() net.Addr {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.LocalAddr()
+}
+
+// serve 接收循环
+func (c *Collector) serve(conn net.PacketConn) {
+	defer c.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("NetFlow read error: %v", err)
+			continue
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+
+		packet, err := c.decoder.Decode(buf[:n], udpAddr.IP)
+		if err != nil {
+			log.Printf("NetFlow packet from %s dropped: %v", udpAddr.IP, err)
+			continue
+		}
+		if len(packet.Flows) > 0 {
+			c.handler(udpAddr.IP, packet.Flows)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// 报文版本
+const (
+	VersionV5    = 5
+	VersionV9    = 9
+	VersionIPFIX = 10
+)
+
+// 字段类型（NetFlow v9 与 IPFIX 信息元素编号一致）
+const (
+	fieldOctetDeltaCount        = 1
+	fieldPacketDeltaCount       = 2
+	fieldProtocol               = 4
+	fieldTCPFlags               = 6
+	fieldSrcPort                = 7
+	fieldSrcIPv4                = 8
+	fieldIngressInterface       = 10
+	fieldDstPort                = 11
+	fieldDstIPv4                = 12
+	fieldEgressInterface        = 14
+	fieldSrcIPv6                = 27
+	fieldDstIPv6                = 28
+	fieldSamplingInterval       = 34
+	fieldOctetTotalCount        = 85
+	fieldPacketTotalCount       = 86
+	fieldSamplingPacketInterval = 305
+)
+
+// variableLength IPFIX 变长字段标记
+const variableLength = 65535
+
+var errShortPacket = errors.New("packet too short")
+
+// Flow 单条流记录
+// Bytes/Packets 已按采样率放大
+type Flow struct {
+	SrcAddr          net.IP `json:"src_addr"`
+	DstAddr          net.IP `json:"dst_addr"`
+	SrcPort          uint16 `json:"src_port"`
+	DstPort          uint16 `json:"dst_port"`
+	Protocol         uint8  `json:"protocol"`
+	TCPFlags         uint8  `json:"tcp_flags"`
+	Bytes            uint64 `json:"bytes"`
+	Packets          uint64 `json:"packets"`
+	InputIf          uint32 `json:"input_if"`  // 入接口 ifIndex
+	OutputIf         uint32 `json:"output_if"` // 出接口 ifIndex
+	SamplingInterval uint32 `json:"sampling_interval"`
+}
+
+// Packet 解码后的报文
+type Packet struct {
+	Version    int
+	ExportTime time.Time
+	SourceID   uint32 // v9 source ID / IPFIX observation domain ID
+	Flows      []Flow
+	// 因模板尚未收到而无法解码的数据记录集数量
+	MissingTemplates int
+}
+
+// templateField 模板字段
+type templateField struct {
+	ID         uint16
+	Length     uint16
+	Enterprise uint32
+}
+
+// template 数据模板
+type template struct {
+	Fields  []templateField
+	Options bool // 选项模板（用于采样率等导出器元数据）
+	// 固定长度记录的长度，含变长字段时为 0
+	RecordLength int
+}
+
+// templateKey 模板按导出器、观测域和模板 ID 区分
+type templateKey struct {
+	exporter string
+	sourceID uint32
+	id       uint16
+}
+
+// domainKey 导出器观测域
+type domainKey struct {
+	exporter string
+	sourceID uint32
+}
+
+// Decoder NetFlow v5/v9 与 IPFIX 解码器
+// 模板和采样率按导出器缓存，可被多个 goroutine 并发使用
+type Decoder struct {
+	mu        sync.Mutex
+	templates map[templateKey]*template
+	sampling  map[domainKey]uint32
+}
+
+// NewDecoder 创建解码器
+func NewDecoder() *Decoder {
+	return &Decoder{
+		templates: make(map[templateKey]*template),
+		sampling:  make(map[domainKey]uint32),
+	}
+}
+
+// Decode 解码一个 UDP 报文，exporter 为导出器地址
+func (d *Decoder) Decode(data []byte, exporter net.IP) (*Packet, error) {
+	if len(data) < 2 {
+		return nil, errShortPacket
+	}
+
+	switch version := binary.BigEndian.Uint16(data); version {
+	case VersionV5:
+		return decodeV5(data)
+	case VersionV9:
+		return d.decodeV9(data, exporter.String())
+	case VersionIPFIX:
+		return d.decodeIPFIX(data, exporter.String())
+	default:
+		return nil, fmt.Errorf("unsupported netflow version %d", version)
+	}
+}
+
+// decodeV5 解码 NetFlow v5（固定格式，采样率在报文头中）
+func decodeV5(data []byte) (*Packet, error) {
+	const headerLength, recordLength = 24, 48
+	if len(data) < headerLength {
+		return nil, errShortPacket
+	}
+
+	count := int(binary.BigEndian.Uint16(data[2:]))
+	if len(data) < headerLength+count*recordLength {
+		return nil, errShortPacket
+	}
+	packet := &Packet{
+		Version:    VersionV5,
+		ExportTime: time.Unix(int64(binary.BigEndian.Uint32(data[8:])), int64(binary.BigEndian.Uint32(data[12:]))),
+		SourceID:   uint32(data[20])<<8 | uint32(data[21]),
+		Flows:      make([]Flow, 0, count),
+	}
+
+	// 高 2 位为采样模式，低 14 位为采样间隔
+	sampling := uint32(binary.BigEndian.Uint16(data[22:]) & 0x3fff)
+	if sampling == 0 {
+		sampling = 1
+	}
+
+	for i := 0; i < count; i++ {
+		r := data[headerLength+i*recordLength:]
+		flow := Flow{
+			SrcAddr:          net.IP(append([]byte(nil), r[0:4]...)),
+			DstAddr:          net.IP(append([]byte(nil), r[4:8]...)),
+			InputIf:          uint32(binary.BigEndian.Uint16(r[12:])),
+			OutputIf:         uint32(binary.BigEndian.Uint16(r[14:])),
+			Packets:          uint64(binary.BigEndian.Uint32(r[16:])) * uint64(sampling),
+			Bytes:            uint64(binary.BigEndian.Uint32(r[20:])) * uint64(sampling),
+			SrcPort:          binary.BigEndian.Uint16(r[32:]),
+			DstPort:          binary.BigEndian.Uint16(r[34:]),
+			TCPFlags:         r[37],
+			Protocol:         r[38],
+			SamplingInterval: sampling,
+		}
+		packet.Flows = append(packet.Flows, flow)
+	}
+	return packet, nil
+}
+
+// decodeV9 解码 NetFlow v9
+func (d *Decoder) decodeV9(data []byte, exporter string) (*Packet, error) {
+	const headerLength = 20
+	if len(data) < headerLength {
+		return nil, errShortPacket
+	}
+	packet := &Packet{
+		Version:    VersionV9,
+		ExportTime: time.Unix(int64(binary.BigEndian.Uint32(data[8:])), 0),
+		SourceID:   binary.BigEndian.Uint32(data[16:]),
+	}
+
+	err := d.walkSets(data[headerLength:], exporter, packet, func(setID uint16, body []byte) error {
+		switch setID {
+		case 0:
+			return d.parseTemplates(body, exporter, packet.SourceID, false)
+		case 1:
+			return d.parseV9OptionsTemplates(body, exporter, packet.SourceID)
+		}
+		return nil
+	})
+	return packet, err
+}
+
+// decodeIPFIX 解码 IPFIX
+func (d *Decoder) decodeIPFIX(data []byte, exporter string) (*Packet, error) {
+	const headerLength = 16
+	if len(data) < headerLength {
+		return nil, errShortPacket
+	}
+	length := int(binary.BigEndian.Uint16(data[2:]))
+	if length < headerLength || length > len(data) {
+		return nil, fmt.Errorf("invalid ipfix message length %d", length)
+	}
+	packet := &Packet{
+		Version:    VersionIPFIX,
+		ExportTime: time.Unix(int64(binary.BigEndian.Uint32(data[4:])), 0),
+		SourceID:   binary.BigEndian.Uint32(data[12:]),
+	}
+
+	err := d.walkSets(data[headerLength:length], exporter, packet, func(setID uint16, body []byte) error {
+		switch setID {
+		case 2:
+			return d.parseTemplates(body, exporter, packet.SourceID, true)
+		case 3:
+			return d.parseIPFIXOptionsTemplates(body, exporter, packet.SourceID)
+		}
+		return nil
+	})
+	return packet, err
+}
+
+// walkSets 遍历 FlowSet/Set，模板集交给 onTemplate，数据集按模板解码
+func (d *Decoder) walkSets(data []byte, exporter string, packet *Packet, onTemplate func(setID uint16, body []byte) error) error {
+	ipfix := packet.Version == VersionIPFIX
+	for len(data) >= 4 {
+		setID := binary.BigEndian.Uint16(data)
+		setLength := int(binary.BigEndian.Uint16(data[2:]))
+		if setLength < 4 || setLength > len(data) {
+			return fmt.Errorf("invalid set length %d", setLength)
+		}
+		body := data[4:setLength]
+		data = data[setLength:]
+
+		if setID < 256 {
+			if err := onTemplate(setID, body); err != nil {
+				return err
+			}
+			continue
+		}
+
+		d.mu.Lock()
+		tmpl := d.templates[templateKey{exporter, packet.SourceID, setID}]
+		d.mu.Unlock()
+		if tmpl == nil {
+			packet.MissingTemplates++
+			continue
+		}
+		if err := d.decodeDataSet(body, tmpl, ipfix, exporter, packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseTemplates 解析模板集（v9 FlowSet 0 / IPFIX Set 2）
+func (d *Decoder) parseTemplates(body []byte, exporter string, sourceID uint32, ipfix bool) error {
+	for len(body) >= 4 {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		if id < 256 {
+			// 剩余为填充
+			return nil
+		}
+
+		if count == 0 {
+			// IPFIX 模板撤销
+			d.mu.Lock()
+			delete(d.templates, templateKey{exporter, sourceID, id})
+			d.mu.Unlock()
+			continue
+		}
+
+		fields, rest, err := parseFields(body, count, ipfix)
+		if err != nil {
+			return err
+		}
+		body = rest
+		d.storeTemplate(templateKey{exporter, sourceID, id}, fields, false)
+	}
+	return nil
+}
+
+// parseV9OptionsTemplates 解析 v9 选项模板（FlowSet 1）
+func (d *Decoder) parseV9OptionsTemplates(body []byte, exporter string, sourceID uint32) error {
+	for len(body) >= 6 {
+		id := binary.BigEndian.Uint16(body)
+		scopeLength := int(binary.BigEndian.Uint16(body[2:]))
+		optionLength := int(binary.BigEndian.Uint16(body[4:]))
+		body = body[6:]
+		if id < 256 || scopeLength%4 != 0 || optionLength%4 != 0 {
+			return nil
+		}
+
+		fields, rest, err := parseFields(body, (scopeLength+optionLength)/4, false)
+		if err != nil {
+			return err
+		}
+		body = rest
+		d.storeTemplate(templateKey{exporter, sourceID, id}, fields, true)
+	}
+	return nil
+}
+
+// parseIPFIXOptionsTemplates 解析 IPFIX 选项模板（Set 3）
+func (d *Decoder) parseIPFIXOptionsTemplates(body []byte, exporter string, sourceID uint32) error {
+	for len(body) >= 6 {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[6:]
+		if id < 256 {
+			return nil
+		}
+
+		fields, rest, err := parseFields(body, count, true)
+		if err != nil {
+			return err
+		}
+		body = rest
+		d.storeTemplate(templateKey{exporter, sourceID, id}, fields, true)
+	}
+	return nil
+}
+
+// parseFields 解析模板字段列表，IPFIX 企业字段带 4 字节企业号
+func parseFields(body []byte, count int, ipfix bool) ([]templateField, []byte, error) {
+	fields := make([]templateField, 0, count)
+	for i := 0; i < count; i++ {
+		if len(body) < 4 {
+			return nil, nil, errShortPacket
+		}
+		field := templateField{
+			ID:     binary.BigEndian.Uint16(body),
+			Length: binary.BigEndian.Uint16(body[2:]),
+		}
+		body = body[4:]
+		if ipfix && field.ID&0x8000 != 0 {
+			if len(body) < 4 {
+				return nil, nil, errShortPacket
+			}
+			field.ID &= 0x7fff
+			field.Enterprise = binary.BigEndian.Uint32(body)
+			body = body[4:]
+		}
+		fields = append(fields, field)
+	}
+	return fields, body, nil
+}
+
+// storeTemplate 缓存模板
+func (d *Decoder) storeTemplate(key templateKey, fields []templateField, options bool) {
+	tmpl := &template{Fields: fields, Options: options}
+	for _, f := range fields {
+		if f.Length == variableLength {
+			tmpl.RecordLength = 0
+			break
+		}
+		tmpl.RecordLength += int(f.Length)
+	}
+
+	d.mu.Lock()
+	d.templates[key] = tmpl
+	d.mu.Unlock()
+}
+
+// decodeDataSet 按模板解码数据集
+func (d *Decoder) decodeDataSet(body []byte, tmpl *template, ipfix bool, exporter string, packet *Packet) error {
+	domain := domainKey{exporter, packet.SourceID}
+	for len(body) > 0 {
+		// 剩余不足一条定长记录时为填充
+		if tmpl.RecordLength > 0 && len(body) < tmpl.RecordLength {
+			return nil
+		}
+
+		values := make(map[uint16][]byte, len(tmpl.Fields))
+		offset := 0
+		for _, f := range tmpl.Fields {
+			length := int(f.Length)
+			if ipfix && f.Length == variableLength {
+				if offset >= len(body) {
+					return errShortPacket
+				}
+				length = int(body[offset])
+				offset++
+				if length == 255 {
+					if offset+2 > len(body) {
+						return errShortPacket
+					}
+					length = int(binary.BigEndian.Uint16(body[offset:]))
+					offset += 2
+				}
+			}
+			if offset+length > len(body) {
+				if tmpl.RecordLength == 0 && offset == 0 {
+					return nil
+				}
+				return errShortPacket
+			}
+			if f.Enterprise == 0 {
+				values[f.ID] = body[offset : offset+length]
+			}
+			offset += length
+		}
+		if offset == 0 {
+			return nil
+		}
+		body = body[offset:]
+
+		if tmpl.Options {
+			// 选项数据中携带导出器采样率
+			if interval := firstUint(values, fieldSamplingInterval, fieldSamplingPacketInterval); interval > 0 {
+				d.mu.Lock()
+				d.sampling[domain] = uint32(interval)
+				d.mu.Unlock()
+			}
+			continue
+		}
+
+		flow := flowFromValues(values)
+		if flow.SamplingInterval == 0 {
+			d.mu.Lock()
+			flow.SamplingInterval = d.sampling[domain]
+			d.mu.Unlock()
+		}
+		if flow.SamplingInterval > 1 {
+			flow.Bytes *= uint64(flow.SamplingInterval)
+			flow.Packets *= uint64(flow.SamplingInterval)
+		}
+		if flow.SamplingInterval == 0 {
+			flow.SamplingInterval = 1
+		}
+		packet.Flows = append(packet.Flows, flow)
+	}
+	return nil
+}
+
+// flowFromValues 由字段值构造流记录
+func flowFromValues(values map[uint16][]byte) Flow {
+	flow := Flow{
+		Bytes:            firstUint(values, fieldOctetDeltaCount, fieldOctetTotalCount),
+		Packets:          firstUint(values, fieldPacketDeltaCount, fieldPacketTotalCount),
+		Protocol:         uint8(firstUint(values, fieldProtocol)),
+		TCPFlags:         uint8(firstUint(values, fieldTCPFlags)),
+		SrcPort:          uint16(firstUint(values, fieldSrcPort)),
+		DstPort:          uint16(firstUint(values, fieldDstPort)),
+		InputIf:          uint32(firstUint(values, fieldIngressInterface)),
+		OutputIf:         uint32(firstUint(values, fieldEgressInterface)),
+		SamplingInterval: uint32(firstUint(values, fieldSamplingInterval, fieldSamplingPacketInterval)),
+	}
+	flow.SrcAddr = firstIP(values, fieldSrcIPv4, fieldSrcIPv6)
+	flow.DstAddr = firstIP(values, fieldDstIPv4, fieldDstIPv6)
+	return flow
+}
+
+// firstUint 按顺序取第一个存在的无符号整数字段（支持 1-8 字节的精简编码）
+func firstUint(values map[uint16][]byte, ids ...uint16) uint64 {
+	for _, id := range ids {
+		b, ok := values[id]
+		if !ok || len(b) == 0 || len(b) > 8 {
+			continue
+		}
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v
+	}
+	return 0
+}
+
+// firstIP 按顺序取第一个存在的地址字段
+func firstIP(values map[uint16][]byte, ids ...uint16) net.IP {
+	for _, id := range ids {
+		if b, ok := values[id]; ok && (len(b) == net.IPv4len || len(b) == net.IPv6len) {
+			return net.IP(append([]byte(nil), b...))
+		}
+	}
+	return nil
+}
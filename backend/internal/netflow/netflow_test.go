@@ -0,0 +1,200 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exporter = net.ParseIP("10.0.0.1")
+
+// be 拼接大端整数和字节切片
+func be(parts ...interface{}) []byte {
+	var out []byte
+	for _, p := range parts {
+		switch v := p.(type) {
+		case uint8:
+			out = append(out, v)
+		case uint16:
+			out = binary.BigEndian.AppendUint16(out, v)
+		case uint32:
+			out = binary.BigEndian.AppendUint32(out, v)
+		case uint64:
+			out = binary.BigEndian.AppendUint64(out, v)
+		case []byte:
+			out = append(out, v...)
+		case net.IP:
+			if v4 := v.To4(); v4 != nil {
+				out = append(out, v4...)
+			} else {
+				out = append(out, v...)
+			}
+		}
+	}
+	return out
+}
+
+// set 生成 FlowSet/Set
+func set(id uint16, body []byte) []byte {
+	return be(id, uint16(len(body)+4), body)
+}
+
+func v5Packet(sampling uint16, records ...[]byte) []byte {
+	header := be(uint16(5), uint16(len(records)), uint32(1000), uint32(1700000000), uint32(0), uint32(1), uint8(0), uint8(3), sampling)
+	for _, r := range records {
+		header = append(header, r...)
+	}
+	return header
+}
+
+func v5Record(src, dst string, in, out uint16, packets, bytes uint32, srcPort, dstPort uint16, proto uint8) []byte {
+	return be(net.ParseIP(src), net.ParseIP(dst), net.ParseIP("0.0.0.0"), in, out, packets, bytes, uint32(0), uint32(0),
+		srcPort, dstPort, uint8(0), uint8(0x18), proto, uint8(0), uint16(0), uint16(0), uint8(24), uint8(24), uint16(0))
+}
+
+func TestDecodeV5(t *testing.T) {
+	d := NewDecoder()
+	data := v5Packet(0x4000|10,
+		v5Record("192.168.1.10", "8.8.8.8", 2, 1, 3, 1500, 40000, 443, 6),
+		v5Record("192.168.1.11", "1.1.1.1", 2, 1, 1, 80, 53000, 53, 17),
+	)
+
+	packet, err := d.Decode(data, exporter)
+	require.NoError(t, err)
+	assert.Equal(t, VersionV5, packet.Version)
+	require.Len(t, packet.Flows, 2)
+
+	flow := packet.Flows[0]
+	assert.Equal(t, "192.168.1.10", flow.SrcAddr.String())
+	assert.Equal(t, "8.8.8.8", flow.DstAddr.String())
+	assert.Equal(t, uint32(2), flow.InputIf)
+	assert.Equal(t, uint32(1), flow.OutputIf)
+	assert.Equal(t, uint16(443), flow.DstPort)
+	assert.Equal(t, uint8(6), flow.Protocol)
+	// 采样率 1:10
+	assert.Equal(t, uint64(15000), flow.Bytes)
+	assert.Equal(t, uint64(30), flow.Packets)
+	assert.Equal(t, uint32(10), flow.SamplingInterval)
+
+	_, err = d.Decode(data[:40], exporter)
+	assert.Error(t, err)
+}
+
+func TestDecodeV9(t *testing.T) {
+	d := NewDecoder()
+	header := func(count uint16) []byte {
+		return be(uint16(9), count, uint32(1000), uint32(1700000000), uint32(1), uint32(7))
+	}
+
+	// 模板 256：src, dst, in, out, bytes(8), packets(4), proto, srcport, dstport
+	tmpl := set(0, be(uint16(256), uint16(9),
+		uint16(fieldSrcIPv4), uint16(4), uint16(fieldDstIPv4), uint16(4),
+		uint16(fieldIngressInterface), uint16(4), uint16(fieldEgressInterface), uint16(4),
+		uint16(fieldOctetDeltaCount), uint16(8), uint16(fieldPacketDeltaCount), uint16(4),
+		uint16(fieldProtocol), uint16(1), uint16(fieldSrcPort), uint16(2), uint16(fieldDstPort), uint16(2)))
+	// 选项模板 257：scope system(4) + sampling interval(4)
+	options := set(1, be(uint16(257), uint16(4), uint16(4), uint16(1), uint16(4), uint16(fieldSamplingInterval), uint16(4)))
+
+	record := func(src, dst string, bytes uint64) []byte {
+		return be(net.ParseIP(src), net.ParseIP(dst), uint32(5), uint32(6), bytes, uint32(2), uint8(6), uint16(50000), uint16(22))
+	}
+
+	// 模板未到达前的数据无法解码
+	early := append(header(1), set(256, record("10.1.1.1", "10.2.2.2", 100))...)
+	packet, err := d.Decode(early, exporter)
+	require.NoError(t, err)
+	assert.Empty(t, packet.Flows)
+	assert.Equal(t, 1, packet.MissingTemplates)
+
+	// 模板、采样率和数据（含 2 字节填充）
+	data := header(4)
+	data = append(data, tmpl...)
+	data = append(data, options...)
+	data = append(data, set(257, be(uint32(1), uint32(100)))...)
+	data = append(data, set(256, be(record("10.1.1.1", "10.2.2.2", 100), record("10.1.1.2", "10.2.2.2", 200), uint16(0)))...)
+
+	packet, err = d.Decode(data, exporter)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(7), packet.SourceID)
+	require.Len(t, packet.Flows, 2)
+	assert.Equal(t, "10.1.1.2", packet.Flows[1].SrcAddr.String())
+	assert.Equal(t, uint32(5), packet.Flows[1].InputIf)
+	assert.Equal(t, uint32(6), packet.Flows[1].OutputIf)
+	assert.Equal(t, uint16(22), packet.Flows[1].DstPort)
+	assert.Equal(t, uint64(20000), packet.Flows[1].Bytes)
+	assert.Equal(t, uint64(200), packet.Flows[1].Packets)
+
+	// 模板按导出器隔离
+	packet, err = d.Decode(early, net.ParseIP("10.0.0.2"))
+	require.NoError(t, err)
+	assert.Empty(t, packet.Flows)
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	d := NewDecoder()
+	message := func(sets ...[]byte) []byte {
+		var body []byte
+		for _, s := range sets {
+			body = append(body, s...)
+		}
+		return append(be(uint16(10), uint16(16+len(body)), uint32(1700000000), uint32(1), uint32(9)), body...)
+	}
+
+	// 模板 300：IPv6 src/dst、企业字段（跳过）、变长字段（跳过）、bytes(4)、packets(2)、proto、端口
+	tmpl := set(2, be(uint16(300), uint16(8),
+		uint16(fieldSrcIPv6), uint16(16), uint16(fieldDstIPv6), uint16(16),
+		uint16(0x8000|fieldOctetDeltaCount), uint16(4), uint32(14988),
+		uint16(82), uint16(variableLength),
+		uint16(fieldOctetDeltaCount), uint16(4), uint16(fieldPacketDeltaCount), uint16(2),
+		uint16(fieldProtocol), uint16(1), uint16(fieldDstPort), uint16(2)))
+
+	record := be(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), uint32(999999),
+		uint8(5), []byte("ether"), uint32(4096), uint16(4), uint8(17), uint16(53))
+
+	packet, err := d.Decode(message(tmpl, set(300, record)), exporter)
+	require.NoError(t, err)
+	assert.Equal(t, VersionIPFIX, packet.Version)
+	assert.Equal(t, uint32(9), packet.SourceID)
+	require.Len(t, packet.Flows, 1)
+	flow := packet.Flows[0]
+	assert.Equal(t, "2001:db8::1", flow.SrcAddr.String())
+	assert.Equal(t, uint64(4096), flow.Bytes)
+	assert.Equal(t, uint64(4), flow.Packets)
+	assert.Equal(t, uint16(53), flow.DstPort)
+	assert.Equal(t, uint32(1), flow.SamplingInterval)
+
+	// 模板撤销
+	packet, err = d.Decode(message(set(2, be(uint16(300), uint16(0))), set(300, record)), exporter)
+	require.NoError(t, err)
+	assert.Empty(t, packet.Flows)
+	assert.Equal(t, 1, packet.MissingTemplates)
+}
+
+func TestCollector(t *testing.T) {
+	received := make(chan []Flow, 1)
+	collector := NewCollector("127.0.0.1:0", func(exporter net.IP, flows []Flow) {
+		assert.Equal(t, "127.0.0.1", exporter.String())
+		received <- flows
+	})
+	require.NoError(t, collector.Start())
+	defer collector.Stop()
+
+	conn, err := net.Dial("udp", collector.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write(v5Packet(0, v5Record("192.168.1.10", "8.8.8.8", 2, 1, 3, 1500, 40000, 443, 6)))
+	require.NoError(t, err)
+
+	select {
+	case flows := <-received:
+		require.Len(t, flows, 1)
+		assert.Equal(t, uint64(1500), flows[0].Bytes)
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for flows")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"nmp-platform/internal/auth"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -30,12 +32,13 @@ type Plugin interface {
 
 // Route 定义插件路由
 type Route struct {
-	Method      string              // HTTP方法 (GET, POST, PUT, DELETE等)
-	Path        string              // 路由路径
-	Handler     gin.HandlerFunc     // 处理函数
-	Middlewares []gin.HandlerFunc   // 中间件列表
-	Permission  string              // 所需权限
-	Description string              // 路由描述
+	Method      string                 // HTTP方法 (GET, POST, PUT, DELETE等)
+	Path        string                 // 路由路径
+	Handler     gin.HandlerFunc        // 处理函数
+	Middlewares []gin.HandlerFunc      // 中间件列表
+	Permission  string                 // 所需权限
+	Owner       auth.ResourceOwnerFunc // 资源归属解析，用于 group、self 范围的权限检查（可选）
+	Description string                 // 路由描述
 }
 
 // MenuItem 定义插件菜单项
@@ -203,7 +203,7 @@ func (m *Manager) integratePluginRoute(p Plugin) error {
 		
 		// 添加权限检查中间件（如果需要）
 		if route.Permission != "" {
-			handlers = append(handlers, m.createPermissionMiddleware(route.Permission))
+			handlers = append(handlers, m.createPermissionMiddleware(route.Permission, route.Owner))
 		}
 		
 		// 添加处理函数
@@ -237,7 +237,9 @@ func (m *Manager) integratePluginRoute(p Plugin) error {
 }
 
 // createPermissionMiddleware 创建权限检查中间件
-func (m *Manager) createPermissionMiddleware(permission string) gin.HandlerFunc {
+// 权限范围为 group 或 self 时，由 owner 解析请求访问的资源归属；未提供 owner 时只检查是否拥有权限，
+// 授予的最大范围设置到上下文 permission_scope，由插件处理函数自行过滤
+func (m *Manager) createPermissionMiddleware(permission string, owner auth.ResourceOwnerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取用户ID
 		userIDValue, exists := c.Get("user_id")
@@ -263,9 +265,20 @@ func (m *Manager) createPermissionMiddleware(permission string) gin.HandlerFunc
 
 		// 解析权限字符串 (格式: resource:action 或 plugin.name.resource:action)
 		resource, action := parsePluginPermission(permission)
+
+		// 解析资源归属
+		var resourceOwner *auth.ResourceOwner
+		if owner != nil {
+			var err error
+			if resourceOwner, err = owner(c); err != nil {
+				c.JSON(400, gin.H{"error": "Bad Request", "message": "无效的资源"})
+				c.Abort()
+				return
+			}
+		}
 		
 		// 检查权限
-		allowed, err := m.rbacService.CheckPermission(userID, resource, action)
+		scope, allowed, err := m.rbacService.ResolvePermission(userID, resource, action, resourceOwner)
 		if err != nil {
 			m.logger.Printf("Permission check error for user %d, permission %s: %v", userID, permission, err)
 			c.JSON(500, gin.H{"error": "Internal Server Error", "message": "权限检查失败"})
@@ -280,6 +293,7 @@ func (m *Manager) createPermissionMiddleware(permission string) gin.HandlerFunc
 			return
 		}
 
+		c.Set("permission_scope", scope)
 		c.Next()
 	}
 }
@@ -0,0 +1,110 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// protocolICMP IPv4 ICMP 协议号
+const protocolICMP = 1
+
+// runICMP ICMP Echo 探测
+// 优先使用非特权 ICMP 套接字（需要 net.ipv4.ping_group_range 允许），失败时回退到原始套接字
+func runICMP(ctx context.Context, cfg Config, result *Result) error {
+	ipAddr, err := net.DefaultResolver.LookupIPAddr(ctx, cfg.Target)
+	if err != nil {
+		return fmt.Errorf("解析目标失败: %w", err)
+	}
+	var dst net.IP
+	for _, addr := range ipAddr {
+		if v4 := addr.IP.To4(); v4 != nil {
+			dst = v4
+			break
+		}
+	}
+	if dst == nil {
+		return errors.New("ICMP 探测仅支持 IPv4 目标")
+	}
+
+	privileged := false
+	conn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+	if err != nil {
+		conn, err = icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+		if err != nil {
+			return fmt.Errorf("创建 ICMP 套接字失败: %w", err)
+		}
+		privileged = true
+	}
+	defer conn.Close()
+
+	var target net.Addr = &net.UDPAddr{IP: dst}
+	if privileged {
+		target = &net.IPAddr{IP: dst}
+	}
+
+	// 非特权模式下内核会改写 ID，只能依靠序列号匹配
+	id := os.Getpid() & 0xffff
+	seq := rand.Intn(0xffff)
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Code: 0,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("NMP-PROBE")},
+	}
+	payload, err := msg.Marshal(nil)
+	if err != nil {
+		return fmt.Errorf("构造 ICMP 报文失败: %w", err)
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(payload, target); err != nil {
+		return fmt.Errorf("发送 ICMP 报文失败: %w", err)
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			result.Latency = time.Since(start)
+			return fmt.Errorf("等待响应超时: %w", err)
+		}
+
+		reply, err := icmp.ParseMessage(protocolICMP, buf[:n])
+		if err != nil || reply.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+			continue
+		}
+		if !peerIP(peer).Equal(dst) {
+			continue
+		}
+
+		result.Latency = time.Since(start)
+		return nil
+	}
+}
+
+// peerIP 提取响应来源 IP
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	}
+	return nil
+}
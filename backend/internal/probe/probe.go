@@ -0,0 +1,244 @@
+// Package probe 实现服务端主动探测：ICMP、TCP 端口、HTTP(S) 和 DNS 解析
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/proxy"
+)
+
+// Type 探测类型
+type Type string
+
+const (
+	TypeICMP Type = "icmp"
+	TypeTCP  Type = "tcp"
+	TypeHTTP Type = "http" // 同时支持 http:// 和 https://
+	TypeDNS  Type = "dns"
+)
+
+// DefaultTimeout 默认探测超时时间
+const DefaultTimeout = 5 * time.Second
+
+// Config 探测配置
+type Config struct {
+	Type           Type
+	Target         string        // ICMP/TCP 为主机名或 IP，HTTP 为 URL，DNS 为 DNS 服务器地址（为空使用系统解析器）
+	Port           int           // TCP 端口
+	DNSQuery       string        // DNS 探测要解析的域名
+	HTTPMethod     string        // HTTP 方法，默认 GET
+	ExpectedStatus int           // 期望的 HTTP 状态码，0 表示 2xx/3xx 均视为成功
+	TLSSkipVerify  bool          // 是否跳过 TLS 证书校验（仍会读取证书到期时间）
+	Timeout        time.Duration // 超时时间，0 使用 DefaultTimeout
+}
+
+// Result 探测结果
+type Result struct {
+	Success       bool          `json:"success"`
+	Latency       time.Duration `json:"latency"`                  // 总耗时
+	ConnectTime   time.Duration `json:"connect_time,omitempty"`   // TCP 建连耗时（TCP/HTTP）
+	StatusCode    int           `json:"status_code,omitempty"`    // HTTP 状态码
+	TLSExpiry     *time.Time    `json:"tls_expiry,omitempty"`     // TLS 证书到期时间（HTTPS）
+	ResolvedAddrs []string      `json:"resolved_addrs,omitempty"` // DNS 解析结果
+	Error         string        `json:"error,omitempty"`
+	CheckedAt     time.Time     `json:"checked_at"`
+}
+
+// TLSDaysLeft 返回证书剩余天数，非 HTTPS 探测返回 false
+func (r *Result) TLSDaysLeft() (float64, bool) {
+	if r.TLSExpiry == nil {
+		return 0, false
+	}
+	return time.Until(*r.TLSExpiry).Hours() / 24, true
+}
+
+// Run 执行一次探测
+// dialer 不为空时通过代理发起连接（ICMP 不支持代理）
+func Run(ctx context.Context, cfg Config, dialer proxy.Dialer) *Result {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := &Result{CheckedAt: time.Now()}
+	var err error
+	switch cfg.Type {
+	case TypeICMP:
+		if dialer != nil {
+			err = errors.New("ICMP 探测不支持通过代理执行")
+		} else {
+			err = runICMP(ctx, cfg, result)
+		}
+	case TypeTCP:
+		err = runTCP(ctx, cfg, dialer, result)
+	case TypeHTTP:
+		err = runHTTP(ctx, cfg, dialer, result)
+	case TypeDNS:
+		err = runDNS(ctx, cfg, dialer, result)
+	default:
+		err = fmt.Errorf("不支持的探测类型: %s", cfg.Type)
+	}
+
+	if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+	return result
+}
+
+// dialContext 通过代理或直接建立连接，并遵守 ctx 的超时
+func dialContext(ctx context.Context, dialer proxy.Dialer, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+
+	// proxy.Dialer 不支持 context，在独立 goroutine 中拨号
+	type dialResult struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan dialResult, 1)
+	go func() {
+		conn, err := dialer.Dial(network, addr)
+		ch <- dialResult{conn, err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-ctx.Done():
+		// 超时后到达的连接需要关闭，避免泄漏
+		go func() {
+			if r := <-ch; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// runTCP TCP 端口探测，测量建连耗时
+func runTCP(ctx context.Context, cfg Config, dialer proxy.Dialer, result *Result) error {
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		return fmt.Errorf("无效的端口: %d", cfg.Port)
+	}
+	addr := net.JoinHostPort(cfg.Target, strconv.Itoa(cfg.Port))
+
+	start := time.Now()
+	conn, err := dialContext(ctx, dialer, "tcp", addr)
+	result.Latency = time.Since(start)
+	if err != nil {
+		return fmt.Errorf("连接失败: %w", err)
+	}
+	conn.Close()
+	result.ConnectTime = result.Latency
+	return nil
+}
+
+// runHTTP HTTP(S) 探测，记录状态码、耗时和证书到期时间
+func runHTTP(ctx context.Context, cfg Config, dialer proxy.Dialer, result *Result) error {
+	method := cfg.HTTPMethod
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cfg.Target, nil)
+	if err != nil {
+		return fmt.Errorf("无效的 URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "NMP-Probe/1.0")
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			start := time.Now()
+			conn, err := dialContext(ctx, dialer, network, addr)
+			result.ConnectTime = time.Since(start)
+			return conn, err
+		},
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: cfg.TLSSkipVerify},
+		DisableKeepAlives: true,
+	}
+	defer transport.CloseIdleConnections()
+
+	client := &http.Client{
+		Transport: transport,
+		// 不跟随重定向，直接报告 3xx 状态码
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		result.Latency = time.Since(start)
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	result.Latency = time.Since(start)
+	result.StatusCode = resp.StatusCode
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expiry := resp.TLS.PeerCertificates[0].NotAfter
+		result.TLSExpiry = &expiry
+	}
+
+	if cfg.ExpectedStatus > 0 {
+		if resp.StatusCode != cfg.ExpectedStatus {
+			return fmt.Errorf("状态码 %d 与期望值 %d 不符", resp.StatusCode, cfg.ExpectedStatus)
+		}
+	} else if resp.StatusCode >= 400 {
+		return fmt.Errorf("状态码异常: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runDNS DNS 解析探测，测量解析耗时
+// 通过代理时使用 TCP 向指定 DNS 服务器查询
+func runDNS(ctx context.Context, cfg Config, dialer proxy.Dialer, result *Result) error {
+	if cfg.DNSQuery == "" {
+		return errors.New("缺少要解析的域名")
+	}
+
+	resolver := net.DefaultResolver
+	if cfg.Target != "" {
+		server := cfg.Target
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				if dialer != nil {
+					network = "tcp"
+				}
+				return dialContext(ctx, dialer, network, server)
+			},
+		}
+	} else if dialer != nil {
+		return errors.New("通过代理执行 DNS 探测时必须指定 DNS 服务器")
+	}
+
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, cfg.DNSQuery)
+	result.Latency = time.Since(start)
+	if err != nil {
+		return fmt.Errorf("解析失败: %w", err)
+	}
+	result.ResolvedAddrs = addrs
+	return nil
+}
@@ -0,0 +1,182 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"nmp-platform/internal/proxy"
+)
+
+func TestRunTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	result := Run(context.Background(), Config{Type: TypeTCP, Target: "127.0.0.1", Port: port}, nil)
+	assert.True(t, result.Success, result.Error)
+	assert.Greater(t, result.ConnectTime, time.Duration(0))
+
+	// 通过代理拨号器执行
+	result = Run(context.Background(), Config{Type: TypeTCP, Target: "127.0.0.1", Port: port}, proxy.NewDirectDialer())
+	assert.True(t, result.Success, result.Error)
+
+	// 端口关闭
+	ln2, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := ln2.Addr().(*net.TCPAddr).Port
+	ln2.Close()
+	result = Run(context.Background(), Config{Type: TypeTCP, Target: "127.0.0.1", Port: closedPort, Timeout: time.Second}, nil)
+	assert.False(t, result.Success)
+	assert.NotEmpty(t, result.Error)
+}
+
+func TestRunHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	result := Run(context.Background(), Config{Type: TypeHTTP, Target: srv.URL}, nil)
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, http.StatusOK, result.StatusCode)
+	assert.Nil(t, result.TLSExpiry)
+
+	result = Run(context.Background(), Config{Type: TypeHTTP, Target: srv.URL + "/missing"}, nil)
+	assert.False(t, result.Success)
+	assert.Equal(t, http.StatusNotFound, result.StatusCode)
+
+	// 期望状态码匹配时视为成功
+	result = Run(context.Background(), Config{Type: TypeHTTP, Target: srv.URL + "/missing", ExpectedStatus: http.StatusNotFound}, nil)
+	assert.True(t, result.Success, result.Error)
+
+	// 不跟随重定向
+	result = Run(context.Background(), Config{Type: TypeHTTP, Target: srv.URL + "/redirect"}, nil)
+	assert.True(t, result.Success, result.Error)
+	assert.Equal(t, http.StatusFound, result.StatusCode)
+}
+
+func TestRunHTTPS_TLSExpiry(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	// 自签名证书校验失败
+	result := Run(context.Background(), Config{Type: TypeHTTP, Target: srv.URL}, nil)
+	assert.False(t, result.Success)
+
+	result = Run(context.Background(), Config{Type: TypeHTTP, Target: srv.URL, TLSSkipVerify: true}, proxy.NewDirectDialer())
+	require.True(t, result.Success, result.Error)
+	require.NotNil(t, result.TLSExpiry)
+	assert.True(t, result.TLSExpiry.Equal(srv.Certificate().NotAfter))
+	days, ok := result.TLSDaysLeft()
+	assert.True(t, ok)
+	assert.Greater(t, days, 0.0)
+}
+
+// startDNSServer 启动仅响应 A 记录查询的测试 DNS 服务器（TCP）
+func startDNSServer(t *testing.T, answer [4]byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				for {
+					var lenBuf [2]byte
+					if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+						return
+					}
+					buf := make([]byte, int(lenBuf[0])<<8|int(lenBuf[1]))
+					if _, err := io.ReadFull(conn, buf); err != nil {
+						return
+					}
+
+					var req dnsmessage.Message
+					if err := req.Unpack(buf); err != nil || len(req.Questions) == 0 {
+						return
+					}
+					q := req.Questions[0]
+					resp := dnsmessage.Message{
+						Header:    dnsmessage.Header{ID: req.ID, Response: true, Authoritative: true},
+						Questions: req.Questions,
+					}
+					if q.Type == dnsmessage.TypeA {
+						resp.Answers = []dnsmessage.Resource{{
+							Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+							Body:   &dnsmessage.AResource{A: answer},
+						}}
+					}
+					out, err := resp.Pack()
+					if err != nil {
+						return
+					}
+					conn.Write(append([]byte{byte(len(out) >> 8), byte(len(out))}, out...))
+				}
+			}(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestRunDNS(t *testing.T) {
+	server := startDNSServer(t, [4]byte{192, 0, 2, 10})
+
+	// 通过代理拨号器时强制使用 TCP
+	result := Run(context.Background(), Config{Type: TypeDNS, Target: server, DNSQuery: "example.test"}, proxy.NewDirectDialer())
+	require.True(t, result.Success, result.Error)
+	assert.Contains(t, result.ResolvedAddrs, "192.0.2.10")
+
+	result = Run(context.Background(), Config{Type: TypeDNS, Target: server}, nil)
+	assert.False(t, result.Success)
+
+	result = Run(context.Background(), Config{Type: TypeDNS, DNSQuery: "example.test"}, proxy.NewDirectDialer())
+	assert.False(t, result.Success)
+}
+
+func TestRunUnsupported(t *testing.T) {
+	result := Run(context.Background(), Config{Type: "smtp", Target: "127.0.0.1"}, nil)
+	assert.False(t, result.Success)
+
+	result = Run(context.Background(), Config{Type: TypeICMP, Target: "127.0.0.1"}, proxy.NewDirectDialer())
+	assert.False(t, result.Success)
+
+	result = Run(context.Background(), Config{Type: TypeTCP, Target: "127.0.0.1", Port: 0}, nil)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, strconv.Itoa(0))
+}
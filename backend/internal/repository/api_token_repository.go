@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// APITokenRepository API 令牌仓库接口
+type APITokenRepository interface {
+	Create(token *models.APIToken) error
+	GetByID(id uint) (*models.APIToken, error)
+	GetByHash(hash string) (*models.APIToken, error)
+	ListByUser(userID uint) ([]*models.APIToken, error)
+	Revoke(id uint, now time.Time) error
+	TouchLastUsed(id uint, ipAddress string, now time.Time) error
+}
+
+// apiTokenRepository API 令牌仓库实现
+type apiTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAPITokenRepository 创建新的 API 令牌仓库
+func NewAPITokenRepository(db *gorm.DB) APITokenRepository {
+	return &apiTokenRepository{db: db}
+}
+
+// Create 创建令牌
+func (r *apiTokenRepository) Create(token *models.APIToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByID 根据ID获取令牌
+func (r *apiTokenRepository) GetByID(id uint) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := r.db.First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// GetByHash 根据令牌哈希获取令牌
+func (r *apiTokenRepository) GetByHash(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ListByUser 获取用户的令牌列表（包括已撤销和已过期的令牌），按创建时间倒序
+func (r *apiTokenRepository) ListByUser(userID uint) ([]*models.APIToken, error) {
+	var tokens []*models.APIToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke 撤销令牌，令牌不存在或已撤销时返回 gorm.ErrRecordNotFound
+func (r *apiTokenRepository) Revoke(id uint, now time.Time) error {
+	result := r.db.Model(&models.APIToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed 记录令牌最近使用时间和来源地址
+func (r *apiTokenRepository) TouchLastUsed(id uint, ipAddress string, now time.Time) error {
+	return r.db.Model(&models.APIToken{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ipAddress,
+	}).Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestAPITokenRepository_LifeCycle(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.APIToken{}))
+
+	repo := NewAPITokenRepository(db)
+	now := time.Now()
+
+	first := &models.APIToken{UserID: 1, Name: "ci", TokenHash: "hash-1", Permissions: `["device:read"]`}
+	second := &models.APIToken{UserID: 1, Name: "backup", TokenHash: "hash-2", Permissions: `["device:read"]`}
+	require.NoError(t, repo.Create(first))
+	require.NoError(t, repo.Create(second))
+	require.NoError(t, repo.Create(&models.APIToken{UserID: 2, Name: "other", TokenHash: "hash-3"}))
+
+	// 令牌哈希唯一
+	assert.Error(t, repo.Create(&models.APIToken{UserID: 2, Name: "dup", TokenHash: "hash-1"}))
+
+	found, err := repo.GetByHash("hash-2")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, found.ID)
+	_, err = repo.GetByHash("missing")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	tokens, err := repo.ListByUser(1)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, second.ID, tokens[0].ID)
+
+	// 记录最近使用
+	require.NoError(t, repo.TouchLastUsed(first.ID, "10.0.0.5", now))
+	found, err = repo.GetByID(first.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.LastUsedAt)
+	assert.Equal(t, "10.0.0.5", found.LastUsedIP)
+	assert.True(t, found.IsActive(now))
+
+	// 撤销后失效，重复撤销返回未找到
+	require.NoError(t, repo.Revoke(first.ID, now))
+	assert.ErrorIs(t, repo.Revoke(first.ID, now), gorm.ErrRecordNotFound)
+	found, err = repo.GetByID(first.ID)
+	require.NoError(t, err)
+	assert.False(t, found.IsActive(now))
+
+	// 过期令牌失效
+	expired := now.Add(-time.Minute)
+	assert.False(t, (&models.APIToken{ExpiresAt: &expired}).IsActive(now))
+}
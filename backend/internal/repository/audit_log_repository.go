@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// auditExportBatchSize 导出审计日志时每批读取的条数
+const auditExportBatchSize = 500
+
+// AuditLogFilter 审计日志查询条件
+type AuditLogFilter struct {
+	ActorID      *uint
+	ActorName    string // 操作者名称（模糊匹配）
+	ActorType    string
+	Action       string // 前缀匹配，如 collector 匹配 collector.deploy
+	ResourceType string
+	ResourceID   string
+	Status       string
+	RequestID    string
+	SourceIP     string
+	Search       string // 详情和路由关键字
+	StartTime    time.Time
+	EndTime      time.Time
+	Offset       int
+	Limit        int
+}
+
+// AuditLogRepository 审计日志仓库接口
+// 只提供追加、查询和按保留期批量清理，不提供修改和逐条删除
+type AuditLogRepository interface {
+	Create(entry *models.AuditLog) error
+	GetByID(id uint) (*models.AuditLog, error)
+	Query(filter AuditLogFilter) ([]*models.AuditLog, int64, error)
+	Each(filter AuditLogFilter, fn func(entries []*models.AuditLog) error) error
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// auditLogRepository 审计日志仓库实现
+type auditLogRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditLogRepository 创建新的审计日志仓库
+func NewAuditLogRepository(db *gorm.DB) AuditLogRepository {
+	return &auditLogRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定审计日志的查询范围
+func (r *auditLogRepository) WithContext(ctx context.Context) AuditLogRepository {
+	return &auditLogRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 追加审计日志
+func (r *auditLogRepository) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+// GetByID 根据ID获取审计日志
+func (r *auditLogRepository) GetByID(id uint) (*models.AuditLog, error) {
+	var entry models.AuditLog
+	if err := r.db.First(&entry, id).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// filtered 按条件构建查询
+func (r *auditLogRepository) filtered(filter AuditLogFilter) *gorm.DB {
+	query := r.db.Model(&models.AuditLog{})
+
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.ActorName != "" {
+		query = query.Where("LOWER(actor_name) LIKE ?", "%"+strings.ToLower(filter.ActorName)+"%")
+	}
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ? OR action LIKE ?", filter.Action, filter.Action+".%")
+	}
+	if filter.ResourceType != "" {
+		query = query.Where("resource_type = ?", filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		query = query.Where("resource_id = ?", filter.ResourceID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.SourceIP != "" {
+		query = query.Where("source_ip = ?", filter.SourceIP)
+	}
+	if filter.Search != "" {
+		keyword := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("LOWER(detail) LIKE ? OR LOWER(route) LIKE ?", keyword, keyword)
+	}
+	if !filter.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query = query.Where("created_at <= ?", filter.EndTime)
+	}
+	return query
+}
+
+// Query 按条件查询审计日志，按时间倒序
+func (r *auditLogRepository) Query(filter AuditLogFilter) ([]*models.AuditLog, int64, error) {
+	query := r.filtered(filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []*models.AuditLog
+	query = query.Order("created_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return entries, total, nil
+}
+
+// Each 按 ID 倒序（即写入顺序倒序）分批遍历全部匹配的审计日志，用于导出（忽略 Offset 和 Limit）
+func (r *auditLogRepository) Each(filter AuditLogFilter, fn func(entries []*models.AuditLog) error) error {
+	lastID := uint(0)
+	for {
+		query := r.filtered(filter)
+		if lastID > 0 {
+			query = query.Where("id < ?", lastID)
+		}
+
+		var entries []*models.AuditLog
+		if err := query.Order("id DESC").Limit(auditExportBatchSize).Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if err := fn(entries); err != nil {
+			return err
+		}
+		if len(entries) < auditExportBatchSize {
+			return nil
+		}
+		lastID = entries[len(entries)-1].ID
+	}
+}
+
+// DeleteBefore 删除指定时间之前的审计日志（保留策略），跳过禁止删除的模型钩子
+func (r *auditLogRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Session(&gorm.Session{SkipHooks: true}).
+		Where("created_at < ?", before).
+		Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
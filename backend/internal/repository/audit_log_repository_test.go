@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupAuditLogTestDB 创建审计日志测试用的内存数据库
+func setupAuditLogTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.AuditLog{}))
+	return db
+}
+
+func TestAuditLogRepository_QueryAndRetention(t *testing.T) {
+	db := setupAuditLogTestDB(t)
+	repo := NewAuditLogRepository(db)
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	entries := []*models.AuditLog{
+		{CreatedAt: base.Add(1 * time.Minute), ActorType: models.AuditActorUser, ActorID: 1, ActorName: "admin", Action: "devices.create", ResourceType: "devices", ResourceID: "10", Route: "/api/v1/devices", Status: models.AuditStatusSuccess, SourceIP: "10.0.0.1", RequestID: "req-1"},
+		{CreatedAt: base.Add(2 * time.Minute), ActorType: models.AuditActorUser, ActorID: 1, ActorName: "admin", Action: "devices.update", ResourceType: "devices", ResourceID: "10", Route: "/api/v1/devices/:id", Status: models.AuditStatusFailure, Detail: "name already exists", SourceIP: "10.0.0.1"},
+		{CreatedAt: base.Add(3 * time.Minute), ActorType: models.AuditActorAPIToken, ActorID: 2, ActorName: "ci-bot", APITokenID: 5, Action: "devices.ping", ResourceType: "devices", ResourceID: "11", Status: models.AuditStatusSuccess, SourceIP: "10.0.0.2"},
+		{CreatedAt: base.Add(4 * time.Minute), ActorType: models.AuditActorSystem, ActorName: "system", Action: "command-jobs.run", ResourceType: "command-jobs", ResourceID: "3", Status: models.AuditStatusSuccess},
+		{CreatedAt: base.Add(-400 * 24 * time.Hour), ActorType: models.AuditActorUser, ActorID: 1, ActorName: "admin", Action: "users.delete", ResourceType: "users", ResourceID: "9", Status: models.AuditStatusSuccess},
+	}
+	for _, entry := range entries {
+		require.NoError(t, repo.Create(entry))
+	}
+
+	// 按时间倒序返回，分页
+	result, total, err := repo.Query(AuditLogFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, result, 2)
+	assert.Equal(t, "command-jobs.run", result[0].Action)
+	assert.Equal(t, "devices.ping", result[1].Action)
+
+	actorID := uint(1)
+	tests := []struct {
+		name   string
+		filter AuditLogFilter
+		want   int64
+	}{
+		{"操作者ID", AuditLogFilter{ActorID: &actorID}, 3},
+		{"操作者名称模糊匹配", AuditLogFilter{ActorName: "BOT"}, 1},
+		{"操作者类型", AuditLogFilter{ActorType: models.AuditActorSystem}, 1},
+		{"操作前缀匹配", AuditLogFilter{Action: "devices"}, 3},
+		{"操作精确匹配", AuditLogFilter{Action: "devices.update"}, 1},
+		{"操作不匹配部分名称", AuditLogFilter{Action: "device"}, 0},
+		{"资源", AuditLogFilter{ResourceType: "devices", ResourceID: "10"}, 2},
+		{"结果", AuditLogFilter{Status: models.AuditStatusFailure}, 1},
+		{"请求ID", AuditLogFilter{RequestID: "req-1"}, 1},
+		{"来源地址", AuditLogFilter{SourceIP: "10.0.0.1"}, 2},
+		{"关键字", AuditLogFilter{Search: "ALREADY"}, 1},
+		{"时间范围", AuditLogFilter{StartTime: base, EndTime: base.Add(2 * time.Minute)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, total, err := repo.Query(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, total)
+		})
+	}
+
+	// 审计日志不能修改或逐条删除
+	entry, err := repo.GetByID(entries[0].ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, db.Model(entry).Update("action", "devices.delete").Error, models.ErrAuditLogImmutable)
+	assert.ErrorIs(t, db.Delete(entry).Error, models.ErrAuditLogImmutable)
+	entry, err = repo.GetByID(entries[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "devices.create", entry.Action)
+
+	// 保留策略按时间批量清理
+	deleted, err := repo.DeleteBefore(base)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, total, err = repo.Query(AuditLogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+}
+
+func TestAuditLogRepository_Each(t *testing.T) {
+	db := setupAuditLogTestDB(t)
+	repo := NewAuditLogRepository(db)
+
+	count := auditExportBatchSize + 20
+	for i := 0; i < count; i++ {
+		status := models.AuditStatusSuccess
+		if i%2 == 1 {
+			status = models.AuditStatusFailure
+		}
+		require.NoError(t, repo.Create(&models.AuditLog{
+			ActorType: models.AuditActorSystem,
+			Action:    "probes.run",
+			Status:    status,
+		}))
+	}
+
+	// 分批遍历全部记录，ID 严格递减，忽略分页参数
+	var batches int
+	var seen int
+	lastID := uint(0)
+	err := repo.Each(AuditLogFilter{Limit: 10, Offset: 5}, func(entries []*models.AuditLog) error {
+		batches++
+		for _, entry := range entries {
+			if lastID > 0 {
+				assert.Less(t, entry.ID, lastID)
+			}
+			lastID = entry.ID
+			seen++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, batches)
+	assert.Equal(t, count, seen)
+
+	seen = 0
+	require.NoError(t, repo.Each(AuditLogFilter{Status: models.AuditStatusFailure}, func(entries []*models.AuditLog) error {
+		seen += len(entries)
+		return nil
+	}))
+	assert.Equal(t, count/2, seen)
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CommandJobFilter 批量命令任务查询条件
+type CommandJobFilter struct {
+	CreatedBy *uint // 指定执行人
+	Status    models.CommandJobStatus
+	Offset    int
+	Limit     int
+}
+
+// CommandJobRepository 批量命令任务仓库接口
+type CommandJobRepository interface {
+	Create(job *models.CommandJob) error
+	GetByID(id uint) (*models.CommandJob, error)
+	List(filter CommandJobFilter) ([]*models.CommandJob, int64, error)
+	UpdateJob(job *models.CommandJob) error
+	UpdateResult(result *models.CommandJobResult) error
+	MarkInterrupted() (int64, error)
+}
+
+// commandJobRepository 批量命令任务仓库实现
+type commandJobRepository struct {
+	db *gorm.DB
+}
+
+// NewCommandJobRepository 创建新的批量命令任务仓库
+func NewCommandJobRepository(db *gorm.DB) CommandJobRepository {
+	return &commandJobRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定命令任务的查询范围
+func (r *commandJobRepository) WithContext(ctx context.Context) CommandJobRepository {
+	return &commandJobRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 创建任务及其设备结果记录
+func (r *commandJobRepository) Create(job *models.CommandJob) error {
+	return r.db.Create(job).Error
+}
+
+// GetByID 获取任务及全部设备结果
+func (r *commandJobRepository) GetByID(id uint) (*models.CommandJob, error) {
+	var job models.CommandJob
+	err := r.db.Preload("Results", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// List 按条件查询任务（不含设备结果），按创建时间倒序
+func (r *commandJobRepository) List(filter CommandJobFilter) ([]*models.CommandJob, int64, error) {
+	query := r.db.Model(&models.CommandJob{})
+
+	if filter.CreatedBy != nil {
+		query = query.Where("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []*models.CommandJob
+	query = query.Order("created_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// UpdateJob 更新任务状态和统计（不更新设备结果）
+func (r *commandJobRepository) UpdateJob(job *models.CommandJob) error {
+	return r.db.Model(job).Select("status", "succeeded", "failed", "skipped", "started_at", "finished_at").Updates(job).Error
+}
+
+// UpdateResult 更新单台设备的执行结果
+func (r *commandJobRepository) UpdateResult(result *models.CommandJobResult) error {
+	return r.db.Model(result).
+		Select("status", "stdout", "stderr", "exit_status", "error", "started_at", "finished_at").
+		Updates(result).Error
+}
+
+// MarkInterrupted 将服务重启前未完成的任务标记为中断，未完成的设备结果标记为跳过
+func (r *commandJobRepository) MarkInterrupted() (int64, error) {
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		running := tx.Model(&models.CommandJob{}).Select("id").Where("status = ?", models.CommandJobStatusRunning)
+		if err := tx.Model(&models.CommandJobResult{}).
+			Where("job_id IN (?) AND status IN ?", running,
+				[]models.CommandResultStatus{models.CommandResultStatusPending, models.CommandResultStatusRunning}).
+			Updates(map[string]interface{}{
+				"status": models.CommandResultStatusSkipped,
+				"error":  "任务因服务重启中断",
+			}).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.CommandJob{}).
+			Where("status = ?", models.CommandJobStatusRunning).
+			Update("status", models.CommandJobStatusInterrupted)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
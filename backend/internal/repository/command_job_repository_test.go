@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"testing"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCommandJobRepository_Lifecycle(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.CommandJob{}, &models.CommandJobResult{}))
+
+	repo := NewCommandJobRepository(db)
+
+	job := &models.CommandJob{
+		Template:  "/system ntp client set enabled=yes servers={{.Vars.ntp}}",
+		OSType:    models.DeviceOSTypeMikroTik,
+		Status:    models.CommandJobStatusRunning,
+		Total:     2,
+		CreatedBy: 7,
+		Results: []models.CommandJobResult{
+			{DeviceID: 1, DeviceName: "r1", Status: models.CommandResultStatusPending},
+			{DeviceID: 2, DeviceName: "r2", Status: models.CommandResultStatusPending},
+		},
+	}
+	require.NoError(t, repo.Create(job))
+	require.NotZero(t, job.Results[0].ID)
+	require.NoError(t, repo.Create(&models.CommandJob{Template: "uptime", OSType: models.DeviceOSTypeLinux, Status: models.CommandJobStatusCompleted, CreatedBy: 8}))
+
+	exitStatus := 0
+	job.Results[0].Status = models.CommandResultStatusSuccess
+	job.Results[0].Stdout = "ok"
+	job.Results[0].ExitStatus = &exitStatus
+	require.NoError(t, repo.UpdateResult(&job.Results[0]))
+
+	job.Succeeded = 1
+	require.NoError(t, repo.UpdateJob(job))
+
+	loaded, err := repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loaded.Succeeded)
+	require.Len(t, loaded.Results, 2)
+	assert.Equal(t, "ok", loaded.Results[0].Stdout)
+	require.NotNil(t, loaded.Results[0].ExitStatus)
+	assert.Equal(t, 0, *loaded.Results[0].ExitStatus)
+
+	// 按执行人过滤，列表不含设备结果
+	createdBy := uint(7)
+	list, total, err := repo.List(CommandJobFilter{CreatedBy: &createdBy})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, list, 1)
+	assert.Empty(t, list[0].Results)
+
+	_, total, err = repo.List(CommandJobFilter{Status: models.CommandJobStatusCompleted})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// 重启后未完成的任务标记为中断，未执行的设备标记为跳过
+	affected, err := repo.MarkInterrupted()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	loaded, err = repo.GetByID(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CommandJobStatusInterrupted, loaded.Status)
+	assert.Equal(t, models.CommandResultStatusSuccess, loaded.Results[0].Status)
+	assert.Equal(t, models.CommandResultStatusSkipped, loaded.Results[1].Status)
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ConfigBackupFilter 配置备份查询条件
+type ConfigBackupFilter struct {
+	DeviceID    *uint  // 指定设备
+	GroupID     *uint  // 指定设备分组
+	DeviceIDs   []uint // 限定的设备范围，为 nil 时不限定
+	ChangesOnly bool   // 只返回配置变更（排除每台设备的首个版本）
+	StartTime   time.Time
+	EndTime     time.Time
+	Offset      int
+	Limit       int
+}
+
+// ConfigBackupRepository 配置备份仓库接口
+type ConfigBackupRepository interface {
+	Create(backup *models.ConfigBackup) error
+	GetLatest(deviceID uint) (*models.ConfigBackup, error)
+	GetByVersion(deviceID uint, version int) (*models.ConfigBackup, error)
+	List(filter ConfigBackupFilter) ([]*models.ConfigBackup, int64, error)
+	DeleteOldVersions(deviceID uint, keep int) (int64, error)
+}
+
+// configBackupRepository 配置备份仓库实现
+type configBackupRepository struct {
+	db *gorm.DB
+}
+
+// NewConfigBackupRepository 创建新的配置备份仓库
+func NewConfigBackupRepository(db *gorm.DB) ConfigBackupRepository {
+	return &configBackupRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定配置备份的查询范围
+func (r *configBackupRepository) WithContext(ctx context.Context) ConfigBackupRepository {
+	return &configBackupRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 保存配置版本
+func (r *configBackupRepository) Create(backup *models.ConfigBackup) error {
+	return r.db.Create(backup).Error
+}
+
+// GetLatest 获取设备最新的配置版本（含内容）
+func (r *configBackupRepository) GetLatest(deviceID uint) (*models.ConfigBackup, error) {
+	var backup models.ConfigBackup
+	if err := r.db.Where("device_id = ?", deviceID).Order("version DESC").First(&backup).Error; err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// GetByVersion 获取设备指定版本（含内容）
+func (r *configBackupRepository) GetByVersion(deviceID uint, version int) (*models.ConfigBackup, error) {
+	var backup models.ConfigBackup
+	if err := r.db.Where("device_id = ? AND version = ?", deviceID, version).First(&backup).Error; err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// List 按条件查询配置版本（不含内容和差异），按时间倒序
+func (r *configBackupRepository) List(filter ConfigBackupFilter) ([]*models.ConfigBackup, int64, error) {
+	query := r.db.Model(&models.ConfigBackup{})
+
+	if filter.DeviceID != nil {
+		query = query.Where("device_id = ?", *filter.DeviceID)
+	}
+	if filter.GroupID != nil {
+		query = query.Where("device_id IN (?)",
+			r.db.Model(&models.DeviceGroupMember{}).Select("device_id").Where("device_group_id = ?", *filter.GroupID))
+	}
+	if filter.DeviceIDs != nil {
+		query = query.Where("device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.ChangesOnly {
+		query = query.Where("version > 1")
+	}
+	if !filter.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query = query.Where("created_at <= ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var backups []*models.ConfigBackup
+	query = query.Omit("content", "diff").Order("created_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&backups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return backups, total, nil
+}
+
+// DeleteOldVersions 只保留设备最新的 keep 个版本
+func (r *configBackupRepository) DeleteOldVersions(deviceID uint, keep int) (int64, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+
+	var cutoff models.ConfigBackup
+	err := r.db.Select("version").Where("device_id = ?", deviceID).
+		Order("version DESC").Offset(keep - 1).Limit(1).Take(&cutoff).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	result := r.db.Where("device_id = ? AND version < ?", deviceID, cutoff.Version).Delete(&models.ConfigBackup{})
+	return result.RowsAffected, result.Error
+}
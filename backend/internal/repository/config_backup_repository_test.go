@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestConfigBackupRepository_VersionsAndRetention(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DeviceGroupMember{}))
+	require.NoError(t, db.AutoMigrate(&models.ConfigBackup{}))
+
+	repo := NewConfigBackupRepository(db)
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Create(&models.DeviceGroupMember{DeviceID: 2, DeviceGroupID: 3}).Error)
+
+	for version := 1; version <= 4; version++ {
+		require.NoError(t, repo.Create(&models.ConfigBackup{
+			DeviceID:  1,
+			Version:   version,
+			Hash:      "hash",
+			Content:   "/system identity set name=r1",
+			Diff:      "@@ -1 +1 @@",
+			CreatedAt: base.Add(time.Duration(version) * time.Hour),
+		}))
+	}
+	require.NoError(t, repo.Create(&models.ConfigBackup{DeviceID: 2, Version: 1, Hash: "hash", Content: "x", CreatedAt: base}))
+
+	// 同一设备的版本号唯一
+	assert.Error(t, repo.Create(&models.ConfigBackup{DeviceID: 1, Version: 4, Hash: "hash"}))
+
+	latest, err := repo.GetLatest(1)
+	require.NoError(t, err)
+	assert.Equal(t, 4, latest.Version)
+	assert.NotEmpty(t, latest.Content)
+
+	// 列表不返回内容，按时间倒序
+	deviceID := uint(1)
+	list, total, err := repo.List(ConfigBackupFilter{DeviceID: &deviceID, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	require.Len(t, list, 2)
+	assert.Equal(t, 4, list[0].Version)
+	assert.Empty(t, list[0].Content)
+	assert.Empty(t, list[0].Diff)
+
+	// 变更事件排除首个版本
+	_, total, err = repo.List(ConfigBackupFilter{ChangesOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+
+	groupID := uint(3)
+	list, _, err = repo.List(ConfigBackupFilter{GroupID: &groupID})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, uint(2), list[0].DeviceID)
+
+	// 只保留最新两个版本
+	deleted, err := repo.DeleteOldVersions(1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	_, err = repo.GetByVersion(1, 2)
+	assert.Error(t, err)
+	backup, err := repo.GetByVersion(1, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, backup.Version)
+
+	deleted, err = repo.DeleteOldVersions(2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+}
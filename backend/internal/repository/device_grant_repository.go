@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"errors"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DeviceGrantFilter 设备授权查询条件
+type DeviceGrantFilter struct {
+	UserID        *uint
+	RoleID        *uint
+	DeviceGroupID *uint
+	TagID         *uint
+	DeviceID      *uint
+}
+
+// DeviceGrantRepository 设备授权仓库接口
+type DeviceGrantRepository interface {
+	Create(grant *models.DeviceGrant) error
+	GetByID(id uint) (*models.DeviceGrant, error)
+	Update(grant *models.DeviceGrant) error
+	Delete(id uint) error
+	List(filter DeviceGrantFilter) ([]*models.DeviceGrant, error)
+	ListForSubject(userID uint, roleIDs []uint) ([]*models.DeviceGrant, error)
+	GroupChildren() (map[uint][]uint, error)
+	GroupMembers(groupIDs []uint) (map[uint][]uint, error)
+	TagMembers(tagIDs []uint) (map[uint][]uint, error)
+	OrganizationDevices(organizationID uint) ([]uint, error)
+}
+
+// deviceGrantRepository 设备授权仓库实现
+type deviceGrantRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceGrantRepository 创建新的设备授权仓库
+func NewDeviceGrantRepository(db *gorm.DB) DeviceGrantRepository {
+	return &deviceGrantRepository{db: db}
+}
+
+// Create 创建设备授权
+func (r *deviceGrantRepository) Create(grant *models.DeviceGrant) error {
+	if err := r.checkReferences(grant); err != nil {
+		return err
+	}
+	return r.db.Create(grant).Error
+}
+
+// GetByID 根据ID获取设备授权
+func (r *deviceGrantRepository) GetByID(id uint) (*models.DeviceGrant, error) {
+	var grant models.DeviceGrant
+	if err := r.db.First(&grant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("device grant not found")
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// Update 更新设备授权
+func (r *deviceGrantRepository) Update(grant *models.DeviceGrant) error {
+	if err := r.checkReferences(grant); err != nil {
+		return err
+	}
+	return r.db.Save(grant).Error
+}
+
+// Delete 删除设备授权
+func (r *deviceGrantRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.DeviceGrant{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("device grant not found")
+	}
+	return nil
+}
+
+// List 按条件查询设备授权
+func (r *deviceGrantRepository) List(filter DeviceGrantFilter) ([]*models.DeviceGrant, error) {
+	query := r.db.Model(&models.DeviceGrant{})
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.RoleID != nil {
+		query = query.Where("role_id = ?", *filter.RoleID)
+	}
+	if filter.DeviceGroupID != nil {
+		query = query.Where("device_group_id = ?", *filter.DeviceGroupID)
+	}
+	if filter.TagID != nil {
+		query = query.Where("tag_id = ?", *filter.TagID)
+	}
+	if filter.DeviceID != nil {
+		query = query.Where("device_id = ?", *filter.DeviceID)
+	}
+
+	var grants []*models.DeviceGrant
+	err := query.Order("id").Find(&grants).Error
+	return grants, err
+}
+
+// ListForSubject 获取授权给用户本人或其任一角色的设备授权
+func (r *deviceGrantRepository) ListForSubject(userID uint, roleIDs []uint) ([]*models.DeviceGrant, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if len(roleIDs) > 0 {
+		query = query.Or("role_id IN ?", roleIDs)
+	}
+
+	var grants []*models.DeviceGrant
+	err := query.Order("id").Find(&grants).Error
+	return grants, err
+}
+
+// GroupChildren 获取全部分组的下级分组，键为上级分组ID
+func (r *deviceGrantRepository) GroupChildren() (map[uint][]uint, error) {
+	var groups []struct {
+		ID       uint
+		ParentID *uint
+	}
+	if err := r.db.Model(&models.DeviceGroup{}).Select("id, parent_id").Where("parent_id IS NOT NULL").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, group := range groups {
+		children[*group.ParentID] = append(children[*group.ParentID], group.ID)
+	}
+	return children, nil
+}
+
+// GroupMembers 获取分组直属的设备（不含下级分组），键为分组ID
+func (r *deviceGrantRepository) GroupMembers(groupIDs []uint) (map[uint][]uint, error) {
+	members := make(map[uint][]uint)
+	if len(groupIDs) == 0 {
+		return members, nil
+	}
+
+	var rows []struct {
+		DeviceGroupID uint
+		DeviceID      uint
+	}
+	err := r.db.Table("device_group_members").
+		Select("device_group_members.device_group_id, device_group_members.device_id").
+		Joins("JOIN devices ON devices.id = device_group_members.device_id AND devices.deleted_at IS NULL").
+		Where("device_group_members.device_group_id IN ?", groupIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		members[row.DeviceGroupID] = append(members[row.DeviceGroupID], row.DeviceID)
+	}
+	return members, nil
+}
+
+// TagMembers 获取带有标签的设备，键为标签ID
+func (r *deviceGrantRepository) TagMembers(tagIDs []uint) (map[uint][]uint, error) {
+	members := make(map[uint][]uint)
+	if len(tagIDs) == 0 {
+		return members, nil
+	}
+
+	var rows []struct {
+		TagID    uint
+		DeviceID uint
+	}
+	err := r.db.Table("device_tags").
+		Select("device_tags.tag_id, device_tags.device_id").
+		Joins("JOIN devices ON devices.id = device_tags.device_id AND devices.deleted_at IS NULL").
+		Where("device_tags.tag_id IN ?", tagIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		members[row.TagID] = append(members[row.TagID], row.DeviceID)
+	}
+	return members, nil
+}
+
+// OrganizationDevices 组织内全部设备的ID
+func (r *deviceGrantRepository) OrganizationDevices(organizationID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Device{}).Where("organization_id = ?", organizationID).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// checkReferences 检查授权对象和授权范围是否存在
+func (r *deviceGrantRepository) checkReferences(grant *models.DeviceGrant) error {
+	if grant == nil {
+		return errors.New("device grant cannot be nil")
+	}
+
+	references := []struct {
+		id      *uint
+		model   interface{}
+		message string
+	}{
+		{grant.UserID, &models.User{}, "user not found"},
+		{grant.RoleID, &models.Role{}, "role not found"},
+		{grant.DeviceGroupID, &models.DeviceGroup{}, "device group not found"},
+		{grant.TagID, &models.Tag{}, "tag not found"},
+		{grant.DeviceID, &models.Device{}, "device not found"},
+	}
+	for _, ref := range references {
+		if ref.id == nil {
+			continue
+		}
+		var count int64
+		if err := r.db.Model(ref.model).Where("id = ?", *ref.id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New(ref.message)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"testing"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupDeviceGrantTestDB 创建设备授权测试用的内存数据库
+// 分组 core(1) 下有 core-east(2)，core-east 下有 core-east-a(3)；设备 1 属于 core，设备 2 属于 core-east-a，
+// 设备 3 已删除且属于 core；标签 pop(1) 标记设备 2 和 3
+func setupDeviceGrantTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Role{}, &models.Device{}, &models.Tag{},
+		&models.DeviceGroup{}, &models.DeviceGroupMember{}, &models.DeviceTag{}, &models.DeviceGrant{}))
+
+	require.NoError(t, db.Create(&models.User{Username: "alice", Email: "alice@example.com"}).Error)
+	require.NoError(t, db.Create(&models.Role{Name: "operator", DisplayName: "operator"}).Error)
+	for _, name := range []string{"r1", "r2", "r3"} {
+		require.NoError(t, db.Create(&models.Device{Name: name, Type: models.DeviceTypeRouter, Host: name}).Error)
+	}
+	require.NoError(t, db.Delete(&models.Device{}, 3).Error)
+
+	core := &models.DeviceGroup{Name: "core"}
+	require.NoError(t, db.Create(core).Error)
+	east := &models.DeviceGroup{Name: "core-east", ParentID: &core.ID}
+	require.NoError(t, db.Create(east).Error)
+	require.NoError(t, db.Create(&models.DeviceGroup{Name: "core-east-a", ParentID: &east.ID}).Error)
+	require.NoError(t, db.Create(&models.Tag{Name: "pop"}).Error)
+
+	require.NoError(t, db.Create(&[]models.DeviceGroupMember{
+		{DeviceID: 1, DeviceGroupID: 1},
+		{DeviceID: 2, DeviceGroupID: 3},
+		{DeviceID: 3, DeviceGroupID: 1},
+	}).Error)
+	require.NoError(t, db.Create(&[]models.DeviceTag{{DeviceID: 2, TagID: 1}, {DeviceID: 3, TagID: 1}}).Error)
+	return db
+}
+
+func TestDeviceGrantRepository_CRUD(t *testing.T) {
+	db := setupDeviceGrantTestDB(t)
+	repo := NewDeviceGrantRepository(db)
+	userID, roleID, groupID, tagID, missing := uint(1), uint(1), uint(1), uint(1), uint(99)
+
+	userGrant := &models.DeviceGrant{UserID: &userID, TagID: &tagID, CanDeploy: true}
+	roleGrant := &models.DeviceGrant{RoleID: &roleID, DeviceGroupID: &groupID, CanRead: true, CanUpdate: true}
+	require.NoError(t, repo.Create(userGrant))
+	require.NoError(t, repo.Create(roleGrant))
+
+	// 授权对象和范围必须存在
+	assert.EqualError(t, repo.Create(&models.DeviceGrant{UserID: &missing, CanRead: true}), "user not found")
+	assert.EqualError(t, repo.Create(&models.DeviceGrant{RoleID: &missing, CanRead: true}), "role not found")
+	assert.EqualError(t, repo.Create(&models.DeviceGrant{UserID: &userID, DeviceGroupID: &missing}), "device group not found")
+	assert.EqualError(t, repo.Create(&models.DeviceGrant{UserID: &userID, TagID: &missing}), "tag not found")
+
+	grants, err := repo.List(DeviceGrantFilter{DeviceGroupID: &groupID})
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+	assert.Equal(t, roleGrant.ID, grants[0].ID)
+
+	// 授权给用户本人或其角色
+	grants, err = repo.ListForSubject(userID, []uint{roleID})
+	require.NoError(t, err)
+	assert.Len(t, grants, 2)
+	grants, err = repo.ListForSubject(userID, nil)
+	require.NoError(t, err)
+	assert.Len(t, grants, 1)
+
+	roleGrant.CanUpdate = false
+	require.NoError(t, repo.Update(roleGrant))
+	updated, err := repo.GetByID(roleGrant.ID)
+	require.NoError(t, err)
+	assert.False(t, updated.Allows(models.DeviceActionUpdate))
+	assert.True(t, updated.Allows(models.DeviceActionRead))
+
+	require.NoError(t, repo.Delete(roleGrant.ID))
+	assert.EqualError(t, repo.Delete(roleGrant.ID), "device grant not found")
+	_, err = repo.GetByID(roleGrant.ID)
+	assert.EqualError(t, err, "device grant not found")
+}
+
+func TestDeviceGrantRepository_Members(t *testing.T) {
+	db := setupDeviceGrantTestDB(t)
+	repo := NewDeviceGrantRepository(db)
+
+	children, err := repo.GroupChildren()
+	require.NoError(t, err)
+	assert.Equal(t, map[uint][]uint{1: {2}, 2: {3}}, children)
+
+	// 只返回直属设备，不含已删除的设备
+	members, err := repo.GroupMembers([]uint{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint][]uint{1: {1}, 3: {2}}, members)
+
+	tagged, err := repo.TagMembers([]uint{1})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint][]uint{1: {2}}, tagged)
+
+	members, err = repo.GroupMembers(nil)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"nmp-platform/internal/models"
@@ -33,6 +34,11 @@ func NewDeviceGroupRepository(db *gorm.DB) DeviceGroupRepository {
 	return &deviceGroupRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定设备分组的查询范围
+func (r *deviceGroupRepository) WithContext(ctx context.Context) DeviceGroupRepository {
+	return &deviceGroupRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create 创建设备分组
 func (r *deviceGroupRepository) Create(group *models.DeviceGroup) error {
 	if group == nil {
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// DeviceInventoryFilter 设备清单查询条件
+type DeviceInventoryFilter struct {
+	OSType           models.DeviceOSType
+	GroupID          *uint
+	DeviceIDs        []uint // 限定的设备范围，为 nil 时不限定
+	Model            string // 型号模糊匹配
+	Architecture     string
+	Package          string // 安装了指定软件包
+	VersionBelow     string // 版本排序键，只返回低于该版本的设备
+	VersionAtLeast   string // 版本排序键，只返回不低于该版本的设备
+	FirmwareOutdated bool   // 只返回 RouterBOOT 固件可升级的设备
+	Offset           int
+	Limit            int
+}
+
+// DeviceInventoryRecord 设备清单及设备基本信息
+type DeviceInventoryRecord struct {
+	models.DeviceInventory
+	DeviceName   string              `json:"device_name"`
+	DeviceHost   string              `json:"device_host"`
+	DeviceOSType models.DeviceOSType `json:"device_os_type"`
+}
+
+// DeviceInventoryRepository 设备清单仓库接口
+type DeviceInventoryRepository interface {
+	GetByDeviceID(deviceID uint) (*models.DeviceInventory, error)
+	Save(inventory *models.DeviceInventory, changes []models.DeviceInventoryChange) error
+	List(filter DeviceInventoryFilter) ([]*DeviceInventoryRecord, int64, error)
+	ListChanges(deviceID uint, offset, limit int) ([]*models.DeviceInventoryChange, int64, error)
+}
+
+// deviceInventoryRepository 设备清单仓库实现
+type deviceInventoryRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceInventoryRepository 创建新的设备清单仓库
+func NewDeviceInventoryRepository(db *gorm.DB) DeviceInventoryRepository {
+	return &deviceInventoryRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定设备清单的查询范围
+func (r *deviceInventoryRepository) WithContext(ctx context.Context) DeviceInventoryRepository {
+	return &deviceInventoryRepository{db: r.db.WithContext(ctx)}
+}
+
+// GetByDeviceID 获取设备当前清单
+func (r *deviceInventoryRepository) GetByDeviceID(deviceID uint) (*models.DeviceInventory, error) {
+	var inventory models.DeviceInventory
+	if err := r.db.Where("device_id = ?", deviceID).First(&inventory).Error; err != nil {
+		return nil, err
+	}
+	return &inventory, nil
+}
+
+// Save 在同一事务中保存清单和变更历史
+func (r *deviceInventoryRepository) Save(inventory *models.DeviceInventory, changes []models.DeviceInventoryChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Device").Save(inventory).Error; err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			return tx.Create(&changes).Error
+		}
+		return nil
+	})
+}
+
+// List 按条件查询设备清单，按设备名称排序
+func (r *deviceInventoryRepository) List(filter DeviceInventoryFilter) ([]*DeviceInventoryRecord, int64, error) {
+	query := r.db.Model(&models.DeviceInventory{}).
+		Joins("JOIN devices ON devices.id = device_inventories.device_id AND devices.deleted_at IS NULL")
+
+	if filter.OSType != "" {
+		query = query.Where("devices.os_type = ?", filter.OSType)
+	}
+	if filter.GroupID != nil {
+		query = query.Where("device_inventories.device_id IN (?)",
+			r.db.Model(&models.DeviceGroupMember{}).Select("device_id").Where("device_group_id = ?", *filter.GroupID))
+	}
+	if filter.DeviceIDs != nil {
+		query = query.Where("device_inventories.device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.Model != "" {
+		query = query.Where("device_inventories.model LIKE ?", "%"+filter.Model+"%")
+	}
+	if filter.Architecture != "" {
+		query = query.Where("device_inventories.architecture = ?", filter.Architecture)
+	}
+	if filter.Package != "" {
+		query = query.Where("device_inventories.packages LIKE ?", `%"name":"`+filter.Package+`"%`)
+	}
+	if filter.VersionBelow != "" {
+		query = query.Where("device_inventories.os_version_key <> '' AND device_inventories.os_version_key < ?", filter.VersionBelow)
+	}
+	if filter.VersionAtLeast != "" {
+		query = query.Where("device_inventories.os_version_key >= ?", filter.VersionAtLeast)
+	}
+	if filter.FirmwareOutdated {
+		query = query.Where("device_inventories.upgrade_firmware <> '' AND device_inventories.upgrade_firmware <> device_inventories.firmware_version")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []*DeviceInventoryRecord
+	query = query.Select("device_inventories.*, devices.name AS device_name, devices.host AS device_host, devices.os_type AS device_os_type").
+		Order("devices.name ASC, device_inventories.id ASC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Scan(&records).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, nil
+}
+
+// ListChanges 查询设备清单变更历史，按时间倒序
+func (r *deviceInventoryRepository) ListChanges(deviceID uint, offset, limit int) ([]*models.DeviceInventoryChange, int64, error) {
+	query := r.db.Model(&models.DeviceInventoryChange{}).Where("device_id = ?", deviceID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var changes []*models.DeviceInventoryChange
+	query = query.Order("changed_at DESC, id DESC").Offset(offset)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Find(&changes).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return changes, total, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestDeviceInventoryRepository_FleetQuery(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DeviceGroupMember{}))
+	require.NoError(t, db.AutoMigrate(&models.Device{}, &models.DeviceInventory{}, &models.DeviceInventoryChange{}))
+
+	repo := NewDeviceInventoryRepository(db)
+	now := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	devices := []models.Device{
+		{ID: 1, Name: "r1", Type: models.DeviceTypeRouter, OSType: models.DeviceOSTypeMikroTik, Host: "10.0.0.1"},
+		{ID: 2, Name: "r2", Type: models.DeviceTypeRouter, OSType: models.DeviceOSTypeMikroTik, Host: "10.0.0.2"},
+		{ID: 3, Name: "srv", Type: models.DeviceTypeServer, OSType: models.DeviceOSTypeLinux, Host: "10.0.0.3"},
+	}
+	require.NoError(t, db.Create(&devices).Error)
+	require.NoError(t, db.Create(&models.DeviceGroupMember{DeviceID: 2, DeviceGroupID: 5}).Error)
+
+	inventories := []*models.DeviceInventory{
+		{DeviceID: 1, Model: "CCR2004-1G-12S+2XS", OSVersion: "7.11.2 (stable)", FirmwareVersion: "7.11.2", UpgradeFirmware: "7.11.2",
+			Packages: `[{"name":"routeros","version":"7.11.2"}]`, CollectedAt: now},
+		{DeviceID: 2, Model: "RB4011iGS+", OSVersion: "7.12.1 (stable)", FirmwareVersion: "7.11.2", UpgradeFirmware: "7.12.1",
+			Packages: `[{"name":"routeros","version":"7.12.1"},{"name":"wireless","version":"7.12.1"}]`, CollectedAt: now},
+		{DeviceID: 3, Model: "PowerEdge R640", OSVersion: "22.04", Kernel: "5.15.0-91-generic", CollectedAt: now},
+	}
+	for _, inventory := range inventories {
+		require.NoError(t, repo.Save(inventory, nil))
+	}
+
+	// RouterOS < 7.12
+	records, total, err := repo.List(DeviceInventoryFilter{
+		OSType:       models.DeviceOSTypeMikroTik,
+		VersionBelow: models.VersionSortKey("7.12"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, records, 1)
+	assert.Equal(t, "r1", records[0].DeviceName)
+	assert.Equal(t, "CCR2004-1G-12S+2XS", records[0].Model)
+
+	_, total, err = repo.List(DeviceInventoryFilter{OSType: models.DeviceOSTypeMikroTik, VersionAtLeast: models.VersionSortKey("7.12")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	records, _, err = repo.List(DeviceInventoryFilter{FirmwareOutdated: true})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, uint(2), records[0].DeviceID)
+
+	_, total, err = repo.List(DeviceInventoryFilter{Package: "wireless"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	groupID := uint(5)
+	_, total, err = repo.List(DeviceInventoryFilter{GroupID: &groupID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	_, total, err = repo.List(DeviceInventoryFilter{Model: "RB4011"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// 更新清单并记录变更
+	current, err := repo.GetByDeviceID(1)
+	require.NoError(t, err)
+	current.OSVersion = "7.12.1 (stable)"
+	require.NoError(t, repo.Save(current, []models.DeviceInventoryChange{
+		{DeviceID: 1, Field: "os_version", OldValue: "7.11.2 (stable)", NewValue: "7.12.1 (stable)", ChangedAt: now.Add(time.Hour)},
+	}))
+
+	_, total, err = repo.List(DeviceInventoryFilter{VersionBelow: models.VersionSortKey("7.12")})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	changes, total, err := repo.ListChanges(1, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "os_version", changes[0].Field)
+}
+
+func TestVersionSortKey(t *testing.T) {
+	assert.Equal(t, "00007.00012.00001.00000", models.VersionSortKey("7.12.1 (stable)"))
+	assert.Equal(t, "00006.00049.00010.00000", models.VersionSortKey("6.49.10"))
+	assert.Equal(t, "00007.00013.00000.00000", models.VersionSortKey("7.13beta2"))
+	assert.Equal(t, "", models.VersionSortKey("unknown"))
+
+	assert.Less(t, models.VersionSortKey("6.49.10"), models.VersionSortKey("7.1"))
+	assert.Less(t, models.VersionSortKey("7.9"), models.VersionSortKey("7.12"))
+	assert.Equal(t, models.VersionSortKey("7.12"), models.VersionSortKey("7.12.0"))
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -41,6 +42,11 @@ func NewDeviceRepository(db *gorm.DB) DeviceRepository {
 	return &deviceRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定设备的查询范围
+func (r *deviceRepository) WithContext(ctx context.Context) DeviceRepository {
+	return &deviceRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create 创建设备
 func (r *deviceRepository) Create(device *models.Device) error {
 	if device == nil {
@@ -123,6 +129,9 @@ func (r *deviceRepository) List(offset, limit int, filters map[string]interface{
 			searchStr := "%" + search.(string) + "%"
 			query = query.Where("name ILIKE ? OR host ILIKE ? OR description ILIKE ?", searchStr, searchStr, searchStr)
 		}
+		if deviceIDs, ok := filters["device_ids"]; ok {
+			query = query.Where("devices.id IN ?", deviceIDs)
+		}
 	}
 
 	// 获取总数
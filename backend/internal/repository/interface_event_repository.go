@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// InterfaceEventRepository 接口状态事件仓库接口
+type InterfaceEventRepository interface {
+	Create(event *models.InterfaceStateEvent) error
+	List(deviceID uint, interfaceName string, start, end time.Time, limit int) ([]*models.InterfaceStateEvent, error)
+	CountFlaps(deviceID uint, start, end time.Time) ([]models.InterfaceFlapCount, error)
+	DeleteByDeviceID(deviceID uint) error
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// interfaceEventRepository 接口状态事件仓库实现
+type interfaceEventRepository struct {
+	db *gorm.DB
+}
+
+// NewInterfaceEventRepository 创建新的接口状态事件仓库
+func NewInterfaceEventRepository(db *gorm.DB) InterfaceEventRepository {
+	return &interfaceEventRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定接口事件的查询范围
+func (r *interfaceEventRepository) WithContext(ctx context.Context) InterfaceEventRepository {
+	return &interfaceEventRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 创建接口状态事件
+func (r *interfaceEventRepository) Create(event *models.InterfaceStateEvent) error {
+	if event == nil {
+		return errors.New("interface event cannot be nil")
+	}
+
+	if event.InterfaceName == "" {
+		return errors.New("interface name is required")
+	}
+
+	return r.db.Create(event).Error
+}
+
+// List 获取时间范围内的接口状态事件（按时间倒序）
+// interfaceName 为空时返回设备所有接口的事件
+func (r *interfaceEventRepository) List(deviceID uint, interfaceName string, start, end time.Time, limit int) ([]*models.InterfaceStateEvent, error) {
+	query := r.db.Where("device_id = ? AND occurred_at >= ? AND occurred_at <= ?", deviceID, start, end)
+	if interfaceName != "" {
+		query = query.Where("interface_name = ?", interfaceName)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var events []*models.InterfaceStateEvent
+	err := query.Order("occurred_at DESC, id DESC").Find(&events).Error
+	return events, err
+}
+
+// CountFlaps 统计时间范围内各接口的抖动次数
+func (r *interfaceEventRepository) CountFlaps(deviceID uint, start, end time.Time) ([]models.InterfaceFlapCount, error) {
+	var events []*models.InterfaceStateEvent
+	err := r.db.Select("interface_name", "old_status", "new_status", "occurred_at").
+		Where("device_id = ? AND occurred_at >= ? AND occurred_at <= ?", deviceID, start, end).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]*models.InterfaceFlapCount)
+	for _, event := range events {
+		count, ok := counts[event.InterfaceName]
+		if !ok {
+			count = &models.InterfaceFlapCount{InterfaceName: event.InterfaceName}
+			counts[event.InterfaceName] = count
+		}
+		count.Transitions++
+		if event.OldStatus == models.InterfaceStatusUp && event.NewStatus == models.InterfaceStatusDown {
+			count.Flaps++
+		}
+		if event.OccurredAt.After(count.LastChange) {
+			count.LastChange = event.OccurredAt
+		}
+	}
+
+	result := make([]models.InterfaceFlapCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Flaps != result[j].Flaps {
+			return result[i].Flaps > result[j].Flaps
+		}
+		return result[i].InterfaceName < result[j].InterfaceName
+	})
+	return result, nil
+}
+
+// DeleteByDeviceID 删除设备的所有接口状态事件
+func (r *interfaceEventRepository) DeleteByDeviceID(deviceID uint) error {
+	return r.db.Where("device_id = ?", deviceID).Delete(&models.InterfaceStateEvent{}).Error
+}
+
+// DeleteBefore 删除指定时间之前的接口状态事件
+func (r *interfaceEventRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("occurred_at < ?", before).Delete(&models.InterfaceStateEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupInterfaceEventTestDB 创建接口事件测试用的内存数据库
+func setupInterfaceEventTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.InterfaceStateEvent{}))
+	return db
+}
+
+func TestInterfaceEventRepository_ListAndCountFlaps(t *testing.T) {
+	repo := NewInterfaceEventRepository(setupInterfaceEventTestDB(t))
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// ether3 抖动两次，ether1 恢复一次
+	events := []*models.InterfaceStateEvent{
+		{DeviceID: 1, InterfaceName: "ether3", OldStatus: models.InterfaceStatusUp, NewStatus: models.InterfaceStatusDown, OccurredAt: base.Add(1 * time.Minute)},
+		{DeviceID: 1, InterfaceName: "ether3", OldStatus: models.InterfaceStatusDown, NewStatus: models.InterfaceStatusUp, OccurredAt: base.Add(2 * time.Minute)},
+		{DeviceID: 1, InterfaceName: "ether3", OldStatus: models.InterfaceStatusUp, NewStatus: models.InterfaceStatusDown, OccurredAt: base.Add(3 * time.Minute)},
+		{DeviceID: 1, InterfaceName: "ether3", OldStatus: models.InterfaceStatusDown, NewStatus: models.InterfaceStatusUp, OccurredAt: base.Add(4 * time.Minute)},
+		{DeviceID: 1, InterfaceName: "ether1", OldStatus: models.InterfaceStatusDown, NewStatus: models.InterfaceStatusUp, OccurredAt: base.Add(5 * time.Minute)},
+		{DeviceID: 2, InterfaceName: "ether3", OldStatus: models.InterfaceStatusUp, NewStatus: models.InterfaceStatusDown, OccurredAt: base.Add(1 * time.Minute)},
+	}
+	for _, event := range events {
+		require.NoError(t, repo.Create(event))
+	}
+
+	// 时间线按时间倒序
+	timeline, err := repo.List(1, "ether3", base, base.Add(time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, timeline, 4)
+	assert.True(t, timeline[0].OccurredAt.Equal(base.Add(4*time.Minute)))
+	assert.Equal(t, models.InterfaceStatusUp, timeline[0].NewStatus)
+
+	limited, err := repo.List(1, "", base, base.Add(time.Hour), 2)
+	require.NoError(t, err)
+	assert.Len(t, limited, 2)
+
+	counts, err := repo.CountFlaps(1, base, base.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, counts, 2)
+	assert.Equal(t, "ether3", counts[0].InterfaceName)
+	assert.Equal(t, int64(2), counts[0].Flaps)
+	assert.Equal(t, int64(4), counts[0].Transitions)
+	assert.True(t, counts[0].LastChange.Equal(base.Add(4*time.Minute)))
+	assert.Equal(t, "ether1", counts[1].InterfaceName)
+	assert.Equal(t, int64(0), counts[1].Flaps)
+
+	// 时间范围之外的事件不计入
+	counts, err = repo.CountFlaps(1, base.Add(150*time.Second), base.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counts[0].Flaps)
+}
+
+func TestInterfaceEventRepository_CreateValidation(t *testing.T) {
+	repo := NewInterfaceEventRepository(setupInterfaceEventTestDB(t))
+
+	assert.Error(t, repo.Create(nil))
+	assert.Error(t, repo.Create(&models.InterfaceStateEvent{DeviceID: 1, NewStatus: models.InterfaceStatusDown}))
+}
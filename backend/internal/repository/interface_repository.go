@@ -24,6 +24,7 @@ type InterfaceRepository interface {
 	// 批量设置监控接口
 	SetMonitoredInterfaces(deviceID uint, interfaceNames []string) error
 	GetByDeviceIDAndName(deviceID uint, name string) (*models.Interface, error)
+	GetByDeviceIDAndIfIndex(deviceID uint, ifIndex int) (*models.Interface, error)
 }
 
 // interfaceRepository 接口仓库实现
@@ -121,7 +122,7 @@ func (r *interfaceRepository) UpdateMonitorStatus(id uint, monitored bool) error
 	return r.db.Model(&models.Interface{}).Where("id = ?", id).Update("monitored", monitored).Error
 }
 
-// SyncInterfaces 同步设备接口（只同步名称和状态）
+// SyncInterfaces 同步设备接口（只同步名称、状态和 ifIndex）
 func (r *interfaceRepository) SyncInterfaces(deviceID uint, interfaces []*models.Interface) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		// 获取现有接口
@@ -142,6 +143,9 @@ func (r *interfaceRepository) SyncInterfaces(deviceID uint, interfaces []*models
 			if existing, exists := existingMap[newIface.Name]; exists {
 				// 更新现有接口（只更新状态，保留监控设置）
 				existing.Status = newIface.Status
+				if newIface.IfIndex > 0 {
+					existing.IfIndex = newIface.IfIndex
+				}
 				if err := tx.Save(existing).Error; err != nil {
 					return err
 				}
@@ -203,4 +207,17 @@ func (r *interfaceRepository) GetByDeviceIDAndName(deviceID uint, name string) (
 		return nil, err
 	}
 	return &iface, nil
-}
\ No newline at end of file
+}
+
+// GetByDeviceIDAndIfIndex 根据设备ID和 SNMP ifIndex 获取接口
+func (r *interfaceRepository) GetByDeviceIDAndIfIndex(deviceID uint, ifIndex int) (*models.Interface, error) {
+	var iface models.Interface
+	err := r.db.Where("device_id = ? AND if_index = ?", deviceID, ifIndex).First(&iface).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("interface not found")
+		}
+		return nil, err
+	}
+	return &iface, nil
+}
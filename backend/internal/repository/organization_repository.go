@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"errors"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// OrganizationUsage 组织的资源用量
+type OrganizationUsage struct {
+	Devices int64 `json:"devices"`
+	Users   int64 `json:"users"`
+}
+
+// OrganizationRepository 组织仓库接口
+type OrganizationRepository interface {
+	Create(organization *models.Organization) error
+	GetByID(id uint) (*models.Organization, error)
+	Update(organization *models.Organization) error
+	Delete(id uint) error
+	List() ([]*models.Organization, error)
+	Usage(id uint) (*OrganizationUsage, error)
+}
+
+// organizationRepository 组织仓库实现
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository 创建新的组织仓库
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create 创建组织
+func (r *organizationRepository) Create(organization *models.Organization) error {
+	if organization == nil {
+		return errors.New("organization cannot be nil")
+	}
+
+	var count int64
+	if err := r.db.Model(&models.Organization{}).Where("name = ?", organization.Name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("organization with this name already exists")
+	}
+
+	return r.db.Create(organization).Error
+}
+
+// GetByID 根据ID获取组织
+func (r *organizationRepository) GetByID(id uint) (*models.Organization, error) {
+	var organization models.Organization
+	if err := r.db.First(&organization, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &organization, nil
+}
+
+// Update 更新组织
+func (r *organizationRepository) Update(organization *models.Organization) error {
+	if organization == nil {
+		return errors.New("organization cannot be nil")
+	}
+	return r.db.Save(organization).Error
+}
+
+// Delete 删除组织，组织内仍有设备或用户时不能删除
+func (r *organizationRepository) Delete(id uint) error {
+	usage, err := r.Usage(id)
+	if err != nil {
+		return err
+	}
+	if usage.Devices > 0 || usage.Users > 0 {
+		return errors.New("organization still has devices or users")
+	}
+
+	result := r.db.Delete(&models.Organization{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("organization not found")
+	}
+	return r.db.Where("organization_id = ?", id).Delete(&models.OrganizationSetting{}).Error
+}
+
+// List 获取全部组织
+func (r *organizationRepository) List() ([]*models.Organization, error) {
+	var organizations []*models.Organization
+	err := r.db.Order("id").Find(&organizations).Error
+	return organizations, err
+}
+
+// Usage 获取组织的设备数和用户数
+func (r *organizationRepository) Usage(id uint) (*OrganizationUsage, error) {
+	usage := &OrganizationUsage{}
+	if err := r.db.Model(&models.Device{}).Where("organization_id = ?", id).Count(&usage.Devices).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&models.User{}).Where("organization_id = ?", id).Count(&usage.Users).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
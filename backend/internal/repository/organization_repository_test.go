@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/tenant"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupOrganizationTestDB 创建组织测试用的内存数据库，已注册租户隔离回调
+// 默认组织 default(1) 和组织 acme(2)
+func setupOrganizationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, tenant.Register(db))
+	require.NoError(t, db.AutoMigrate(&models.Organization{}, &models.OrganizationSetting{},
+		&models.SystemSetting{}, &models.Device{}, &models.User{}))
+
+	require.NoError(t, db.Create(&models.Organization{Name: "default", Enabled: true}).Error)
+	require.NoError(t, db.Create(&models.Organization{Name: "acme", Enabled: true}).Error)
+	return db
+}
+
+func TestOrganizationRepository_CRUD(t *testing.T) {
+	db := setupOrganizationTestDB(t)
+	repo := NewOrganizationRepository(db)
+
+	assert.EqualError(t, repo.Create(&models.Organization{Name: "acme"}), "organization with this name already exists")
+
+	globex := &models.Organization{Name: "globex", MaxDevices: 10, RetentionDays: 3, Enabled: true}
+	require.NoError(t, repo.Create(globex))
+
+	loaded, err := repo.GetByID(globex.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 10, loaded.MaxDevices)
+	assert.Equal(t, 3, loaded.RetentionDays)
+
+	loaded.Enabled = false
+	require.NoError(t, repo.Update(loaded))
+	loaded, err = repo.GetByID(globex.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.Enabled)
+
+	organizations, err := repo.List()
+	require.NoError(t, err)
+	require.Len(t, organizations, 3)
+	assert.Equal(t, []string{"default", "acme", "globex"},
+		[]string{organizations[0].Name, organizations[1].Name, organizations[2].Name})
+
+	require.NoError(t, repo.Delete(globex.ID))
+	_, err = repo.GetByID(globex.ID)
+	assert.EqualError(t, err, "organization not found")
+	assert.EqualError(t, repo.Delete(globex.ID), "organization not found")
+}
+
+func TestOrganizationRepository_UsageAndDelete(t *testing.T) {
+	db := setupOrganizationTestDB(t)
+	repo := NewOrganizationRepository(db)
+	ctx := tenant.WithOrganization(context.Background(), 2)
+
+	// 在组织上下文中创建的设备和用户归属该组织
+	require.NoError(t, db.WithContext(ctx).Create(&models.Device{Name: "r1", Type: models.DeviceTypeRouter, Host: "r1"}).Error)
+	require.NoError(t, db.WithContext(ctx).Create(&models.User{Username: "bob", Email: "bob@example.com"}).Error)
+	require.NoError(t, db.Create(&models.Device{Name: "r2", Type: models.DeviceTypeRouter, Host: "r2"}).Error)
+	require.NoError(t, db.WithContext(ctx).Create(&models.OrganizationSetting{OrganizationID: 2, Key: "k", Value: "v"}).Error)
+
+	usage, err := repo.Usage(2)
+	require.NoError(t, err)
+	assert.Equal(t, &OrganizationUsage{Devices: 1, Users: 1}, usage)
+
+	usage, err = repo.Usage(models.DefaultOrganizationID)
+	require.NoError(t, err)
+	assert.Equal(t, &OrganizationUsage{Devices: 1, Users: 0}, usage)
+
+	// 仍有设备或用户时不能删除
+	assert.EqualError(t, repo.Delete(2), "organization still has devices or users")
+
+	require.NoError(t, db.Unscoped().Where("organization_id = ?", 2).Delete(&models.Device{}).Error)
+	require.NoError(t, db.Unscoped().Where("organization_id = ?", 2).Delete(&models.User{}).Error)
+	require.NoError(t, repo.Delete(2))
+
+	// 组织设置随组织一起删除
+	var count int64
+	require.NoError(t, db.Model(&models.OrganizationSetting{}).Where("organization_id = ?", 2).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestSettingsRepository_OrganizationOverride(t *testing.T) {
+	db := setupOrganizationTestDB(t)
+	repo := NewSettingsRepository(db)
+	require.NoError(t, repo.SetDataRetentionDays(30))
+
+	acme := tenant.Bind(tenant.WithOrganization(context.Background(), 2), repo)
+	defaults := tenant.Bind(tenant.WithOrganization(context.Background(), models.DefaultOrganizationID), repo)
+
+	// 未覆盖时使用系统设置
+	assert.Equal(t, 30, acme.GetDataRetentionDays())
+
+	// 组织覆盖只影响本组织
+	require.NoError(t, acme.SetDataRetentionDays(7))
+	assert.Equal(t, 7, acme.GetDataRetentionDays())
+	assert.Equal(t, 30, repo.GetDataRetentionDays())
+	assert.Equal(t, 30, defaults.GetDataRetentionDays())
+
+	all, err := acme.GetAll()
+	require.NoError(t, err)
+	assert.Equal(t, "7", all[SettingKeyDataRetentionDays])
+	all, err = repo.GetAll()
+	require.NoError(t, err)
+	assert.Equal(t, "30", all[SettingKeyDataRetentionDays])
+
+	// 删除覆盖后恢复为系统设置，系统设置不受影响
+	require.NoError(t, acme.Delete(SettingKeyDataRetentionDays))
+	assert.Equal(t, 30, acme.GetDataRetentionDays())
+	assert.Equal(t, 30, repo.GetDataRetentionDays())
+}
+
+func TestProbeRepository_OrganizationScope(t *testing.T) {
+	db := setupOrganizationTestDB(t)
+	require.NoError(t, db.AutoMigrate(&models.Probe{}))
+	repo := NewProbeRepository(db)
+
+	acme := tenant.Bind(tenant.WithOrganization(context.Background(), 2), repo)
+	defaults := tenant.Bind(tenant.WithOrganization(context.Background(), models.DefaultOrganizationID), repo)
+
+	probe := &models.Probe{Name: "web", Type: models.ProbeTypeHTTP, Target: "http://10.0.0.1", Enabled: true}
+	require.NoError(t, acme.Create(probe))
+	assert.Equal(t, uint(2), probe.OrganizationID)
+
+	// 其他组织看不到也改不了本组织的探测
+	_, err := defaults.GetByID(probe.ID)
+	assert.Error(t, err)
+	probes, err := defaults.List()
+	require.NoError(t, err)
+	assert.Empty(t, probes)
+
+	probes, err = acme.List()
+	require.NoError(t, err)
+	assert.Len(t, probes, 1)
+
+	// 调度器不带组织，执行全部组织的探测
+	probes, err = repo.ListEnabled()
+	require.NoError(t, err)
+	assert.Len(t, probes, 1)
+}
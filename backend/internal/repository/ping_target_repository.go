@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"nmp-platform/internal/models"
@@ -31,6 +32,11 @@ func NewPingTargetRepository(db *gorm.DB) PingTargetRepository {
 	return &pingTargetRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定Ping 目标的查询范围
+func (r *pingTargetRepository) WithContext(ctx context.Context) PingTargetRepository {
+	return &pingTargetRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create 创建 Ping 目标
 func (r *pingTargetRepository) Create(target *models.PingTarget) error {
 	if target == nil {
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ProbeRepository 服务端探测仓库接口
+type ProbeRepository interface {
+	Create(probe *models.Probe) error
+	GetByID(id uint) (*models.Probe, error)
+	List() ([]*models.Probe, error)
+	ListEnabled() ([]*models.Probe, error)
+	Update(probe *models.Probe) error
+	Delete(id uint) error
+	UpdateLastResult(id uint, status string, lastError string, checkedAt time.Time) error
+}
+
+// probeRepository 服务端探测仓库实现
+type probeRepository struct {
+	db *gorm.DB
+}
+
+// NewProbeRepository 创建新的服务端探测仓库
+func NewProbeRepository(db *gorm.DB) ProbeRepository {
+	return &probeRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定探测的查询范围
+func (r *probeRepository) WithContext(ctx context.Context) ProbeRepository {
+	return &probeRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 创建探测
+func (r *probeRepository) Create(probe *models.Probe) error {
+	if probe == nil {
+		return errors.New("probe cannot be nil")
+	}
+
+	return r.db.Create(probe).Error
+}
+
+// GetByID 根据 ID 获取探测
+func (r *probeRepository) GetByID(id uint) (*models.Probe, error) {
+	var probe models.Probe
+	err := r.db.First(&probe, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("probe not found")
+		}
+		return nil, err
+	}
+	return &probe, nil
+}
+
+// List 获取所有探测
+func (r *probeRepository) List() ([]*models.Probe, error) {
+	var probes []*models.Probe
+	err := r.db.Order("id").Find(&probes).Error
+	return probes, err
+}
+
+// ListEnabled 获取所有启用的探测
+func (r *probeRepository) ListEnabled() ([]*models.Probe, error) {
+	var probes []*models.Probe
+	err := r.db.Where("enabled = ?", true).Order("id").Find(&probes).Error
+	return probes, err
+}
+
+// Update 更新探测
+func (r *probeRepository) Update(probe *models.Probe) error {
+	if probe == nil {
+		return errors.New("probe cannot be nil")
+	}
+
+	return r.db.Save(probe).Error
+}
+
+// Delete 删除探测
+func (r *probeRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Probe{}, id).Error
+}
+
+// UpdateLastResult 更新最近一次探测结果
+func (r *probeRepository) UpdateLastResult(id uint, status string, lastError string, checkedAt time.Time) error {
+	return r.db.Model(&models.Probe{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_status":     status,
+		"last_error":      lastError,
+		"last_checked_at": checkedAt,
+	}).Error
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"nmp-platform/internal/models"
@@ -32,6 +33,11 @@ func NewProxyRepository(db *gorm.DB) ProxyRepository {
 	return &proxyRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定代理的查询范围
+func (r *proxyRepository) WithContext(ctx context.Context) ProxyRepository {
+	return &proxyRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create 创建代理
 func (r *proxyRepository) Create(proxy *models.Proxy) error {
 	if proxy == nil {
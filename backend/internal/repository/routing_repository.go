@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// RoutingEventFilter 路由邻居事件查询条件
+type RoutingEventFilter struct {
+	DeviceID      *uint
+	DeviceIDs     []uint // 限定的设备范围，为 nil 时不限定
+	Protocol      models.RoutingProtocol
+	Neighbor      string
+	AdjacencyLost bool // 只返回邻接丢失事件
+	Start         *time.Time
+	End           *time.Time
+	Offset        int
+	Limit         int
+}
+
+// RoutingEventRecord 路由邻居事件及设备名称
+type RoutingEventRecord struct {
+	models.RoutingNeighborEvent
+	DeviceName string `json:"device_name"`
+}
+
+// RoutingRepository 路由邻居仓库接口
+type RoutingRepository interface {
+	ListNeighbors(deviceID uint, protocol models.RoutingProtocol) ([]*models.RoutingNeighbor, error)
+	GetNeighbor(id uint) (*models.RoutingNeighbor, error)
+	SaveNeighbors(neighbors []*models.RoutingNeighbor, events []models.RoutingNeighborEvent) error
+	DeleteNeighbor(id uint) error
+	ListEvents(filter RoutingEventFilter) ([]*RoutingEventRecord, int64, error)
+}
+
+// routingRepository 路由邻居仓库实现
+type routingRepository struct {
+	db *gorm.DB
+}
+
+// NewRoutingRepository 创建新的路由邻居仓库
+func NewRoutingRepository(db *gorm.DB) RoutingRepository {
+	return &routingRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定路由邻居和事件的查询范围
+func (r *routingRepository) WithContext(ctx context.Context) RoutingRepository {
+	return &routingRepository{db: r.db.WithContext(ctx)}
+}
+
+// ListNeighbors 获取设备的路由邻居，protocol 为空时返回所有协议
+func (r *routingRepository) ListNeighbors(deviceID uint, protocol models.RoutingProtocol) ([]*models.RoutingNeighbor, error) {
+	query := r.db.Where("device_id = ?", deviceID)
+	if protocol != "" {
+		query = query.Where("protocol = ?", protocol)
+	}
+
+	var neighbors []*models.RoutingNeighbor
+	err := query.Order("protocol ASC, instance ASC, neighbor ASC").Find(&neighbors).Error
+	return neighbors, err
+}
+
+// GetNeighbor 根据 ID 获取路由邻居
+func (r *routingRepository) GetNeighbor(id uint) (*models.RoutingNeighbor, error) {
+	var neighbor models.RoutingNeighbor
+	if err := r.db.First(&neighbor, id).Error; err != nil {
+		return nil, err
+	}
+	return &neighbor, nil
+}
+
+// SaveNeighbors 在同一事务中保存邻居状态和状态变化事件
+func (r *routingRepository) SaveNeighbors(neighbors []*models.RoutingNeighbor, events []models.RoutingNeighborEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, neighbor := range neighbors {
+			if err := tx.Omit("Device").Save(neighbor).Error; err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			return tx.Omit("Device").Create(&events).Error
+		}
+		return nil
+	})
+}
+
+// DeleteNeighbor 删除路由邻居（邻居已移除配置时使用，事件保留）
+func (r *routingRepository) DeleteNeighbor(id uint) error {
+	return r.db.Delete(&models.RoutingNeighbor{}, id).Error
+}
+
+// ListEvents 按条件查询路由邻居事件，按时间倒序
+func (r *routingRepository) ListEvents(filter RoutingEventFilter) ([]*RoutingEventRecord, int64, error) {
+	query := r.db.Model(&models.RoutingNeighborEvent{}).
+		Joins("LEFT JOIN devices ON devices.id = routing_neighbor_events.device_id")
+
+	if filter.DeviceID != nil {
+		query = query.Where("routing_neighbor_events.device_id = ?", *filter.DeviceID)
+	}
+	if filter.DeviceIDs != nil {
+		query = query.Where("routing_neighbor_events.device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.Protocol != "" {
+		query = query.Where("routing_neighbor_events.protocol = ?", filter.Protocol)
+	}
+	if filter.Neighbor != "" {
+		query = query.Where("routing_neighbor_events.neighbor = ?", filter.Neighbor)
+	}
+	if filter.AdjacencyLost {
+		query = query.Where("routing_neighbor_events.adjacency_lost = ?", true)
+	}
+	if filter.Start != nil {
+		query = query.Where("routing_neighbor_events.occurred_at >= ?", *filter.Start)
+	}
+	if filter.End != nil {
+		query = query.Where("routing_neighbor_events.occurred_at <= ?", *filter.End)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []*RoutingEventRecord
+	query = query.Select("routing_neighbor_events.*, devices.name AS device_name").
+		Order("routing_neighbor_events.occurred_at DESC, routing_neighbor_events.id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Scan(&records).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestRoutingRepository_NeighborsAndEvents(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.DeviceGroupMember{}))
+	require.NoError(t, db.AutoMigrate(&models.Device{}, &models.RoutingNeighbor{}, &models.RoutingNeighborEvent{}))
+
+	devices := []models.Device{
+		{ID: 1, Name: "edge1", Type: models.DeviceTypeRouter, OSType: models.DeviceOSTypeMikroTik, Host: "10.0.0.1"},
+		{ID: 2, Name: "rr1", Type: models.DeviceTypeServer, OSType: models.DeviceOSTypeLinux, Host: "10.0.0.2"},
+	}
+	require.NoError(t, db.Create(&devices).Error)
+
+	repo := NewRoutingRepository(db)
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	neighbors := []*models.RoutingNeighbor{
+		{DeviceID: 1, Protocol: models.RoutingProtocolOSPF, Instance: "default/backbone", Neighbor: "10.255.0.2", State: "full", Established: true, LastChange: base, CollectedAt: base},
+		{DeviceID: 1, Protocol: models.RoutingProtocolBGP, Instance: "default", Neighbor: "192.0.2.1", RemoteAS: 64500, State: "established", Established: true, PrefixCount: 900000, LastChange: base, CollectedAt: base},
+		{DeviceID: 2, Protocol: models.RoutingProtocolBGP, Instance: "ipv4Unicast", Neighbor: "10.0.0.1", RemoteAS: 65000, State: "established", Established: true, LastChange: base, CollectedAt: base},
+	}
+	require.NoError(t, repo.SaveNeighbors(neighbors, nil))
+
+	list, err := repo.ListNeighbors(1, "")
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, models.RoutingProtocolBGP, list[0].Protocol)
+	assert.Equal(t, int64(900000), list[0].PrefixCount)
+
+	bgp, err := repo.ListNeighbors(1, models.RoutingProtocolBGP)
+	require.NoError(t, err)
+	require.Len(t, bgp, 1)
+
+	// 更新已有邻居并记录事件
+	peer := bgp[0]
+	peer.State = "active"
+	peer.Established = false
+	peer.LastChange = base.Add(time.Minute)
+	events := []models.RoutingNeighborEvent{
+		{DeviceID: 1, Protocol: models.RoutingProtocolBGP, Instance: "default", Neighbor: "192.0.2.1", OldState: "established", NewState: "active", AdjacencyLost: true, OccurredAt: base.Add(time.Minute)},
+		{DeviceID: 2, Protocol: models.RoutingProtocolBGP, Instance: "ipv4Unicast", Neighbor: "10.0.0.1", OldState: "idle", NewState: "established", OccurredAt: base.Add(2 * time.Minute)},
+	}
+	require.NoError(t, repo.SaveNeighbors([]*models.RoutingNeighbor{peer}, events))
+
+	updated, err := repo.GetNeighbor(peer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", updated.State)
+	assert.False(t, updated.Established)
+
+	// 唯一索引防止重复插入同一邻居
+	duplicate := &models.RoutingNeighbor{DeviceID: 1, Protocol: models.RoutingProtocolBGP, Instance: "default", Neighbor: "192.0.2.1", State: "idle"}
+	assert.Error(t, repo.SaveNeighbors([]*models.RoutingNeighbor{duplicate}, nil))
+
+	records, total, err := repo.ListEvents(RoutingEventFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, records, 2)
+	assert.Equal(t, "rr1", records[0].DeviceName)
+
+	records, total, err = repo.ListEvents(RoutingEventFilter{AdjacencyLost: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, records, 1)
+	assert.Equal(t, "edge1", records[0].DeviceName)
+	assert.Equal(t, "192.0.2.1", records[0].Neighbor)
+
+	deviceID := uint(1)
+	end := base.Add(30 * time.Second)
+	_, total, err = repo.ListEvents(RoutingEventFilter{DeviceID: &deviceID, End: &end})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	require.NoError(t, repo.DeleteNeighbor(peer.ID))
+	list, err = repo.ListNeighbors(1, "")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
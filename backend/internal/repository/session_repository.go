@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionRepository 用户会话仓库接口
+type SessionRepository interface {
+	Create(session *models.UserSession) error
+	GetBySessionID(sessionID string) (*models.UserSession, error)
+	GetByRefreshTokenHash(hash string) (*models.UserSession, error)
+	GetByPreviousRefreshHash(hash string) (*models.UserSession, error)
+	Rotate(sessionID, oldHash, newHash, ipAddress, userAgent string, now time.Time) error
+	ListByUser(userID uint, activeOnly bool) ([]*models.UserSession, error)
+	Revoke(sessionID, reason string) error
+	RevokeByUser(userID uint, reason string) ([]string, error)
+	DeleteExpired(before time.Time) (int64, error)
+}
+
+// sessionRepository 用户会话仓库实现
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository 创建新的用户会话仓库
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create 创建会话
+func (r *sessionRepository) Create(session *models.UserSession) error {
+	return r.db.Create(session).Error
+}
+
+// GetBySessionID 根据会话ID获取会话
+func (r *sessionRepository) GetBySessionID(sessionID string) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := r.db.Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByRefreshTokenHash 根据当前刷新令牌哈希获取会话
+func (r *sessionRepository) GetByRefreshTokenHash(hash string) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := r.db.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByPreviousRefreshHash 根据上一个（已轮换的）刷新令牌哈希获取会话
+func (r *sessionRepository) GetByPreviousRefreshHash(hash string) (*models.UserSession, error) {
+	var session models.UserSession
+	if err := r.db.Where("previous_refresh_hash = ?", hash).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Rotate 轮换刷新令牌
+// 仅当当前刷新令牌仍为 oldHash 且会话未撤销时更新，并发刷新时只有一个请求成功，其余返回 gorm.ErrRecordNotFound
+func (r *sessionRepository) Rotate(sessionID, oldHash, newHash, ipAddress, userAgent string, now time.Time) error {
+	result := r.db.Model(&models.UserSession{}).
+		Where("session_id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", sessionID, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":    newHash,
+			"previous_refresh_hash": oldHash,
+			"rotated_at":            now,
+			"last_used_at":          now,
+			"ip_address":            ipAddress,
+			"user_agent":            userAgent,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListByUser 获取用户的会话，activeOnly 为 true 时只返回未撤销且未过期的会话
+func (r *sessionRepository) ListByUser(userID uint, activeOnly bool) ([]*models.UserSession, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if activeOnly {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	var sessions []*models.UserSession
+	err := query.Order("last_used_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// Revoke 撤销会话，已撤销的会话保持原撤销原因
+func (r *sessionRepository) Revoke(sessionID, reason string) error {
+	return r.db.Model(&models.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":    time.Now(),
+			"revoke_reason": reason,
+		}).Error
+}
+
+// RevokeByUser 撤销用户所有有效会话，返回被撤销的会话ID
+func (r *sessionRepository) RevokeByUser(userID uint, reason string) ([]string, error) {
+	var sessionIDs []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserSession{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Pluck("session_id", &sessionIDs).Error; err != nil {
+			return err
+		}
+		if len(sessionIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.UserSession{}).
+			Where("session_id IN ?", sessionIDs).
+			Updates(map[string]interface{}{
+				"revoked_at":    time.Now(),
+				"revoke_reason": reason,
+			}).Error
+	})
+	return sessionIDs, err
+}
+
+// DeleteExpired 删除指定时间之前过期的会话
+func (r *sessionRepository) DeleteExpired(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&models.UserSession{})
+	return result.RowsAffected, result.Error
+}
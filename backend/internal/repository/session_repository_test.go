@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSessionRepository_RotateAndRevoke(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.UserSession{}))
+
+	repo := NewSessionRepository(db)
+	now := time.Now()
+
+	sessions := []*models.UserSession{
+		{SessionID: "s1", UserID: 1, RefreshTokenHash: "h1", ExpiresAt: now.Add(time.Hour), LastUsedAt: now},
+		{SessionID: "s2", UserID: 1, RefreshTokenHash: "h2", ExpiresAt: now.Add(time.Hour), LastUsedAt: now.Add(-time.Minute)},
+		{SessionID: "s3", UserID: 2, RefreshTokenHash: "h3", ExpiresAt: now.Add(time.Hour), LastUsedAt: now},
+		{SessionID: "old", UserID: 1, RefreshTokenHash: "h4", ExpiresAt: now.Add(-30 * 24 * time.Hour), LastUsedAt: now.Add(-37 * 24 * time.Hour)},
+	}
+	for _, session := range sessions {
+		require.NoError(t, repo.Create(session))
+	}
+
+	// 轮换刷新令牌，旧哈希可通过 previous_refresh_hash 找到
+	require.NoError(t, repo.Rotate("s1", "h1", "h1-new", "10.0.0.9", "curl", now))
+	session, err := repo.GetByRefreshTokenHash("h1-new")
+	require.NoError(t, err)
+	assert.Equal(t, "s1", session.SessionID)
+	assert.Equal(t, "10.0.0.9", session.IPAddress)
+	require.NotNil(t, session.RotatedAt)
+
+	_, err = repo.GetByRefreshTokenHash("h1")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	previous, err := repo.GetByPreviousRefreshHash("h1")
+	require.NoError(t, err)
+	assert.Equal(t, "s1", previous.SessionID)
+
+	// 并发刷新时只有第一个请求能完成轮换
+	assert.ErrorIs(t, repo.Rotate("s1", "h1", "h1-other", "", "", now), gorm.ErrRecordNotFound)
+
+	active, err := repo.ListByUser(1, true)
+	require.NoError(t, err)
+	require.Len(t, active, 2)
+	assert.Equal(t, "s1", active[0].SessionID)
+
+	all, err := repo.ListByUser(1, false)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	// 撤销单个会话后不能再轮换
+	require.NoError(t, repo.Revoke("s2", models.SessionRevokeLogout))
+	assert.ErrorIs(t, repo.Rotate("s2", "h2", "h2-new", "", "", now), gorm.ErrRecordNotFound)
+	revoked, err := repo.GetBySessionID("s2")
+	require.NoError(t, err)
+	assert.False(t, revoked.IsActive(now))
+	assert.Equal(t, models.SessionRevokeLogout, revoked.RevokeReason)
+
+	// 撤销用户所有会话只返回此前有效的会话，已撤销会话保留原因
+	ids, err := repo.RevokeByUser(1, models.SessionRevokeUserDisabled)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"s1", "old"}, ids)
+	revoked, err = repo.GetBySessionID("s2")
+	require.NoError(t, err)
+	assert.Equal(t, models.SessionRevokeLogout, revoked.RevokeReason)
+
+	other, err := repo.GetBySessionID("s3")
+	require.NoError(t, err)
+	assert.True(t, other.IsActive(now))
+
+	deleted, err := repo.DeleteExpired(now.Add(-7 * 24 * time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
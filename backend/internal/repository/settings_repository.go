@@ -1,11 +1,13 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"time"
 
 	"nmp-platform/internal/models"
+	"nmp-platform/internal/tenant"
 
 	"gorm.io/gorm"
 )
@@ -56,8 +58,10 @@ type SettingsRepository interface {
 }
 
 // settingsRepository 系统设置仓库实现
+// 绑定非默认组织时读取组织级设置，未覆盖的设置使用系统设置；写入只影响组织级设置
 type settingsRepository struct {
-	db *gorm.DB
+	db             *gorm.DB
+	organizationID uint
 }
 
 // NewSettingsRepository 创建新的系统设置仓库
@@ -65,8 +69,28 @@ func NewSettingsRepository(db *gorm.DB) SettingsRepository {
 	return &settingsRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织决定读写的设置范围
+func (r *settingsRepository) WithContext(ctx context.Context) SettingsRepository {
+	scoped := &settingsRepository{db: r.db.WithContext(ctx)}
+	if organizationID, ok := tenant.FromContext(ctx); ok && organizationID != models.DefaultOrganizationID {
+		scoped.organizationID = organizationID
+	}
+	return scoped
+}
+
 // Get 获取设置值
 func (r *settingsRepository) Get(key string) (string, error) {
+	if r.organizationID != 0 {
+		var override models.OrganizationSetting
+		err := r.db.Where("organization_id = ? AND key = ?", r.organizationID, key).First(&override).Error
+		if err == nil {
+			return override.Value, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", err
+		}
+	}
+
 	var setting models.SystemSetting
 	err := r.db.Where("key = ?", key).First(&setting).Error
 	if err != nil {
@@ -80,6 +104,15 @@ func (r *settingsRepository) Get(key string) (string, error) {
 
 // Set 设置值
 func (r *settingsRepository) Set(key, value, description string) error {
+	if r.organizationID != 0 {
+		return r.db.Save(&models.OrganizationSetting{
+			OrganizationID: r.organizationID,
+			Key:            key,
+			Value:          value,
+			UpdatedAt:      time.Now(),
+		}).Error
+	}
+
 	setting := models.SystemSetting{
 		Key:         key,
 		Value:       value,
@@ -142,12 +175,25 @@ func (r *settingsRepository) GetAll() (map[string]string, error) {
 	for _, s := range settings {
 		result[s.Key] = s.Value
 	}
+
+	if r.organizationID != 0 {
+		var overrides []models.OrganizationSetting
+		if err := r.db.Where("organization_id = ?", r.organizationID).Find(&overrides).Error; err != nil {
+			return nil, err
+		}
+		for _, s := range overrides {
+			result[s.Key] = s.Value
+		}
+	}
 	
 	return result, nil
 }
 
-// Delete 删除设置
+// Delete 删除设置，绑定组织时只删除组织级设置，恢复使用系统设置
 func (r *settingsRepository) Delete(key string) error {
+	if r.organizationID != 0 {
+		return r.db.Where("organization_id = ? AND key = ?", r.organizationID, key).Delete(&models.OrganizationSetting{}).Error
+	}
 	return r.db.Where("key = ?", key).Delete(&models.SystemSetting{}).Error
 }
 
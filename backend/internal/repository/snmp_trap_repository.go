@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SNMPTrapFilter SNMP trap 查询条件
+type SNMPTrapFilter struct {
+	DeviceID    *uint  // 指定设备
+	GroupID     *uint  // 指定设备分组
+	DeviceIDs   []uint // 限定的设备范围，为 nil 时不限定
+	InterfaceID *uint  // 指定接口
+	TrapOID     string // trap OID 前缀（如 1.3.6.1.4.1.14988 匹配 MikroTik 私有 trap）
+	Search      string // trap 名称、接口名称或变量绑定关键字
+	StartTime   time.Time
+	EndTime     time.Time
+	Offset      int
+	Limit       int
+}
+
+// SNMPTrapRepository SNMP trap 仓库接口
+type SNMPTrapRepository interface {
+	Create(trap *models.SNMPTrap) error
+	Query(filter SNMPTrapFilter) ([]*models.SNMPTrap, int64, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// snmpTrapRepository SNMP trap 仓库实现
+type snmpTrapRepository struct {
+	db *gorm.DB
+}
+
+// NewSNMPTrapRepository 创建新的 SNMP trap 仓库
+func NewSNMPTrapRepository(db *gorm.DB) SNMPTrapRepository {
+	return &snmpTrapRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定trap的查询范围
+func (r *snmpTrapRepository) WithContext(ctx context.Context) SNMPTrapRepository {
+	return &snmpTrapRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create 保存 trap
+func (r *snmpTrapRepository) Create(trap *models.SNMPTrap) error {
+	return r.db.Create(trap).Error
+}
+
+// Query 按条件查询 trap，按接收时间倒序
+func (r *snmpTrapRepository) Query(filter SNMPTrapFilter) ([]*models.SNMPTrap, int64, error) {
+	query := r.db.Model(&models.SNMPTrap{})
+
+	if filter.DeviceID != nil {
+		query = query.Where("device_id = ?", *filter.DeviceID)
+	}
+	if filter.GroupID != nil {
+		query = query.Where("device_id IN (?)",
+			r.db.Model(&models.DeviceGroupMember{}).Select("device_id").Where("device_group_id = ?", *filter.GroupID))
+	}
+	if filter.DeviceIDs != nil {
+		query = query.Where("device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.InterfaceID != nil {
+		query = query.Where("interface_id = ?", *filter.InterfaceID)
+	}
+	if filter.TrapOID != "" {
+		oid := strings.TrimPrefix(filter.TrapOID, ".")
+		query = query.Where("trap_oid = ? OR trap_oid LIKE ?", oid, oid+".%")
+	}
+	if filter.Search != "" {
+		keyword := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("LOWER(trap_name) LIKE ? OR LOWER(interface_name) LIKE ? OR LOWER(varbinds) LIKE ?", keyword, keyword, keyword)
+	}
+	if !filter.StartTime.IsZero() {
+		query = query.Where("received_at >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query = query.Where("received_at <= ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var traps []*models.SNMPTrap
+	query = query.Order("received_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&traps).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return traps, total, nil
+}
+
+// DeleteBefore 删除指定时间之前接收的 trap
+func (r *snmpTrapRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("received_at < ?", before).Delete(&models.SNMPTrap{})
+	return result.RowsAffected, result.Error
+}
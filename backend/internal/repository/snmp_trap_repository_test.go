@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSNMPTrapRepository_QueryAndRetention(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.SNMPTrap{}, &models.DeviceGroupMember{}))
+
+	repo := NewSNMPTrapRepository(db)
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	device1, device2, iface := uint(1), uint(2), uint(11)
+
+	require.NoError(t, db.Create(&models.DeviceGroupMember{DeviceID: device2, DeviceGroupID: 3}).Error)
+
+	traps := []*models.SNMPTrap{
+		{DeviceID: &device1, InterfaceID: &iface, InterfaceName: "ether3", SourceIP: "10.0.0.1", TrapOID: "1.3.6.1.6.3.1.1.5.3", TrapName: "linkDown", ReceivedAt: base.Add(1 * time.Minute)},
+		{DeviceID: &device1, InterfaceID: &iface, InterfaceName: "ether3", SourceIP: "10.0.0.1", TrapOID: "1.3.6.1.6.3.1.1.5.4", TrapName: "linkUp", ReceivedAt: base.Add(2 * time.Minute)},
+		{DeviceID: &device2, SourceIP: "10.0.0.2", TrapOID: "1.3.6.1.4.1.14988.1.0.1", TrapName: "1.3.6.1.4.1.14988.1.0.1", VarBinds: `[{"value":"Temperature High"}]`, ReceivedAt: base.Add(3 * time.Minute)},
+		{SourceIP: "192.0.2.1", TrapOID: "1.3.6.1.6.3.1.1.5.1", TrapName: "coldStart", ReceivedAt: base.Add(-48 * time.Hour)},
+	}
+	for _, trap := range traps {
+		require.NoError(t, repo.Create(trap))
+	}
+
+	// 按设备过滤，接收时间倒序
+	result, total, err := repo.Query(SNMPTrapFilter{DeviceID: &device1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, "linkUp", result[0].TrapName)
+
+	// 按接口和分组过滤
+	_, total, err = repo.Query(SNMPTrapFilter{InterfaceID: &iface})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	groupID := uint(3)
+	result, _, err = repo.Query(SNMPTrapFilter{GroupID: &groupID})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, device2, *result[0].DeviceID)
+
+	// OID 前缀匹配不应误匹配 1.3.6.1.4.1.149880
+	_, total, err = repo.Query(SNMPTrapFilter{TrapOID: ".1.3.6.1.4.1.14988"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	// 关键字匹配变量绑定内容
+	result, _, err = repo.Query(SNMPTrapFilter{Search: "temperature"})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+
+	// 时间范围与保留期清理
+	_, total, err = repo.Query(SNMPTrapFilter{StartTime: base})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	deleted, err := repo.DeleteBefore(base)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SyslogFilter syslog 查询条件
+type SyslogFilter struct {
+	DeviceID    *uint                  // 指定设备
+	GroupID     *uint                  // 指定设备分组
+	DeviceIDs   []uint                 // 限定的设备范围，为 nil 时不限定
+	MaxSeverity *models.SyslogSeverity // 仅返回该级别及更严重的日志
+	Search      string                 // 消息内容、应用名或主机名关键字
+	StartTime   time.Time
+	EndTime     time.Time
+	Offset      int
+	Limit       int
+}
+
+// SyslogRepository syslog 日志仓库接口
+type SyslogRepository interface {
+	CreateBatch(messages []*models.SyslogMessage) error
+	Query(filter SyslogFilter) ([]*models.SyslogMessage, int64, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// syslogRepository syslog 日志仓库实现
+type syslogRepository struct {
+	db *gorm.DB
+}
+
+// NewSyslogRepository 创建新的 syslog 日志仓库
+func NewSyslogRepository(db *gorm.DB) SyslogRepository {
+	return &syslogRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定日志的查询范围
+func (r *syslogRepository) WithContext(ctx context.Context) SyslogRepository {
+	return &syslogRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateBatch 批量写入日志
+func (r *syslogRepository) CreateBatch(messages []*models.SyslogMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(messages, 200).Error
+}
+
+// Query 按条件查询日志，按接收时间倒序
+func (r *syslogRepository) Query(filter SyslogFilter) ([]*models.SyslogMessage, int64, error) {
+	query := r.db.Model(&models.SyslogMessage{})
+
+	if filter.DeviceID != nil {
+		query = query.Where("device_id = ?", *filter.DeviceID)
+	}
+	if filter.GroupID != nil {
+		query = query.Where("device_id IN (?)",
+			r.db.Model(&models.DeviceGroupMember{}).Select("device_id").Where("device_group_id = ?", *filter.GroupID))
+	}
+	if filter.DeviceIDs != nil {
+		query = query.Where("device_id IN ?", filter.DeviceIDs)
+	}
+	if filter.MaxSeverity != nil {
+		query = query.Where("severity <= ?", *filter.MaxSeverity)
+	}
+	if filter.Search != "" {
+		keyword := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("LOWER(message) LIKE ? OR LOWER(app_name) LIKE ? OR LOWER(hostname) LIKE ?", keyword, keyword, keyword)
+	}
+	if !filter.StartTime.IsZero() {
+		query = query.Where("received_at >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query = query.Where("received_at <= ?", filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []*models.SyslogMessage
+	query = query.Order("received_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+// DeleteBefore 删除指定时间之前接收的日志
+func (r *syslogRepository) DeleteBefore(before time.Time) (int64, error) {
+	result := r.db.Where("received_at < ?", before).Delete(&models.SyslogMessage{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupSyslogTestDB 创建 syslog 测试用的内存数据库
+func setupSyslogTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.SyslogMessage{}, &models.DeviceGroupMember{}))
+	return db
+}
+
+func TestSyslogRepository_QueryAndRetention(t *testing.T) {
+	db := setupSyslogTestDB(t)
+	repo := NewSyslogRepository(db)
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	device1, device2 := uint(1), uint(2)
+
+	require.NoError(t, db.Create(&models.DeviceGroupMember{DeviceID: device2, DeviceGroupID: 7}).Error)
+
+	messages := []*models.SyslogMessage{
+		{DeviceID: &device1, SourceIP: "10.0.0.1", Severity: models.SyslogSeverityInfo, AppName: "system", Message: "user admin logged in", ReceivedAt: base.Add(1 * time.Minute)},
+		{DeviceID: &device1, SourceIP: "10.0.0.1", Severity: models.SyslogSeverityError, Message: "ether1 link down", ReceivedAt: base.Add(2 * time.Minute)},
+		{DeviceID: &device2, SourceIP: "10.0.0.2", Severity: models.SyslogSeverityWarning, Message: "OSPF neighbor Down", ReceivedAt: base.Add(3 * time.Minute)},
+		{SourceIP: "192.0.2.9", Severity: models.SyslogSeverityCritical, Message: "unknown sender", ReceivedAt: base.Add(4 * time.Minute)},
+		{DeviceID: &device1, SourceIP: "10.0.0.1", Severity: models.SyslogSeverityDebug, Message: "old debug", ReceivedAt: base.Add(-48 * time.Hour)},
+	}
+	require.NoError(t, repo.CreateBatch(messages))
+
+	// 按设备过滤，接收时间倒序
+	result, total, err := repo.Query(SyslogFilter{DeviceID: &device1, StartTime: base})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, result, 2)
+	assert.Equal(t, "ether1 link down", result[0].Message)
+	assert.Equal(t, "error", result[0].SeverityName)
+
+	// 按分组过滤
+	groupID := uint(7)
+	result, _, err = repo.Query(SyslogFilter{GroupID: &groupID})
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, device2, *result[0].DeviceID)
+
+	// 限定设备范围：不含未匹配设备的日志，空范围不返回任何日志
+	_, total, err = repo.Query(SyslogFilter{DeviceIDs: []uint{device2}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	_, total, err = repo.Query(SyslogFilter{DeviceIDs: []uint{}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	// 严重级别：warning 及以上
+	maxSeverity := models.SyslogSeverityWarning
+	_, total, err = repo.Query(SyslogFilter{MaxSeverity: &maxSeverity})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+
+	// 关键字搜索不区分大小写
+	result, _, err = repo.Query(SyslogFilter{Search: "DOWN"})
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+
+	// 分页
+	result, total, err = repo.Query(SyslogFilter{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, result, 2)
+	assert.Equal(t, "OSPF neighbor Down", result[0].Message)
+
+	// 保留期清理
+	deleted, err := repo.DeleteBefore(base)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, total, err = repo.Query(SyslogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 
 	"nmp-platform/internal/models"
@@ -30,6 +31,11 @@ func NewTagRepository(db *gorm.DB) TagRepository {
 	return &tagRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定标签的查询范围
+func (r *tagRepository) WithContext(ctx context.Context) TagRepository {
+	return &tagRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create 创建标签
 func (r *tagRepository) Create(tag *models.Tag) error {
 	if tag == nil {
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"time"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TwoFactorRepository 两步验证仓库接口
+type TwoFactorRepository interface {
+	Get(userID uint) (*models.UserTwoFactor, error)
+	Save(twoFactor *models.UserTwoFactor) error
+	Delete(userID uint) error
+	MarkStepUsed(userID uint, step int64) (bool, error)
+	Enable(userID uint, step int64, recoveryCodeHashes []string) error
+	ReplaceRecoveryCodes(userID uint, hashes []string) error
+	UseRecoveryCode(userID uint, hash string) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+}
+
+// twoFactorRepository 两步验证仓库实现
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+// NewTwoFactorRepository 创建新的两步验证仓库
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// Get 获取用户的两步验证配置
+func (r *twoFactorRepository) Get(userID uint) (*models.UserTwoFactor, error) {
+	var twoFactor models.UserTwoFactor
+	if err := r.db.Where("user_id = ?", userID).First(&twoFactor).Error; err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+// Save 保存两步验证配置
+func (r *twoFactorRepository) Save(twoFactor *models.UserTwoFactor) error {
+	return r.db.Save(twoFactor).Error
+}
+
+// Delete 删除用户的两步验证配置和恢复码
+func (r *twoFactorRepository) Delete(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserTwoFactor{}).Error
+	})
+}
+
+// MarkStepUsed 记录已使用的验证码时间步，时间步不大于上次使用的时间步时返回 false（验证码重放）
+func (r *twoFactorRepository) MarkStepUsed(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.UserTwoFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Enable 启用两步验证并生成恢复码
+func (r *twoFactorRepository) Enable(userID uint, step int64, recoveryCodeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.UserTwoFactor{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]interface{}{
+				"enabled":        true,
+				"enabled_at":     time.Now(),
+				"last_used_step": step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码，原有恢复码全部失效
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, hashes)
+	})
+}
+
+// replaceRecoveryCodes 在事务中替换用户的恢复码
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	codes := make([]models.UserRecoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = models.UserRecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 使用恢复码，恢复码不存在或已使用时返回 false
+func (r *twoFactorRepository) UseRecoveryCode(userID uint, hash string) (bool, error) {
+	result := r.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountRecoveryCodes 统计用户未使用的恢复码数量
+func (r *twoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
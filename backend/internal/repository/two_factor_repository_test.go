@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"testing"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTwoFactorRepository_EnableAndRecoveryCodes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.UserTwoFactor{}, &models.UserRecoveryCode{}))
+
+	repo := NewTwoFactorRepository(db)
+
+	// 未登记的用户无法启用
+	assert.ErrorIs(t, repo.Enable(1, 100, []string{"c1"}), gorm.ErrRecordNotFound)
+
+	require.NoError(t, repo.Save(&models.UserTwoFactor{UserID: 1, Secret: "SECRET"}))
+	require.NoError(t, repo.Enable(1, 100, []string{"c1", "c2"}))
+
+	twoFactor, err := repo.Get(1)
+	require.NoError(t, err)
+	assert.True(t, twoFactor.Enabled)
+	require.NotNil(t, twoFactor.EnabledAt)
+	assert.Equal(t, int64(100), twoFactor.LastUsedStep)
+
+	// 同一时间步或更早的验证码视为重放
+	ok, err := repo.MarkStepUsed(1, 100)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.MarkStepUsed(1, 101)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 恢复码只能使用一次
+	ok, err = repo.UseRecoveryCode(1, "c1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.UseRecoveryCode(1, "c1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = repo.UseRecoveryCode(2, "c2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	count, err := repo.CountRecoveryCodes(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// 重新生成后原恢复码失效
+	require.NoError(t, repo.ReplaceRecoveryCodes(1, []string{"c3", "c4", "c5"}))
+	ok, err = repo.UseRecoveryCode(1, "c2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	count, err = repo.CountRecoveryCodes(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// 删除配置同时删除恢复码
+	require.NoError(t, repo.Delete(1))
+	_, err = repo.Get(1)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	count, err = repo.CountRecoveryCodes(1)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+
+	"nmp-platform/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UpgradeJobFilter 升级任务查询条件
+type UpgradeJobFilter struct {
+	CreatedBy *uint
+	Status    models.UpgradeJobStatus
+	Offset    int
+	Limit     int
+}
+
+// activeUpgradeJobStatuses 执行中的升级任务状态
+var activeUpgradeJobStatuses = []models.UpgradeJobStatus{
+	models.UpgradeJobStatusUploading,
+	models.UpgradeJobStatusRebooting,
+}
+
+// UpgradeRepository 升级包和升级任务仓库接口
+type UpgradeRepository interface {
+	CreatePackage(pkg *models.UpgradePackage) error
+	GetPackage(id uint) (*models.UpgradePackage, error)
+	GetPackageByFileName(fileName string) (*models.UpgradePackage, error)
+	ListPackages() ([]*models.UpgradePackage, error)
+	DeletePackage(id uint) error
+
+	CreateJob(job *models.UpgradeJob) error
+	GetJob(id uint) (*models.UpgradeJob, error)
+	ListJobs(filter UpgradeJobFilter) ([]*models.UpgradeJob, int64, error)
+	UpdateJob(job *models.UpgradeJob) error
+	UpdateDevice(device *models.UpgradeJobDevice) error
+	ActiveDeviceIDs() ([]uint, error)
+	MarkInterrupted() (int64, error)
+}
+
+// upgradeRepository 升级包和升级任务仓库实现
+type upgradeRepository struct {
+	db *gorm.DB
+}
+
+// NewUpgradeRepository 创建新的升级仓库
+func NewUpgradeRepository(db *gorm.DB) UpgradeRepository {
+	return &upgradeRepository{db: db}
+}
+
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定升级任务的查询范围
+func (r *upgradeRepository) WithContext(ctx context.Context) UpgradeRepository {
+	return &upgradeRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreatePackage 保存升级包信息
+func (r *upgradeRepository) CreatePackage(pkg *models.UpgradePackage) error {
+	return r.db.Create(pkg).Error
+}
+
+// GetPackage 根据ID获取升级包
+func (r *upgradeRepository) GetPackage(id uint) (*models.UpgradePackage, error) {
+	var pkg models.UpgradePackage
+	if err := r.db.First(&pkg, id).Error; err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// GetPackageByFileName 根据文件名获取升级包
+func (r *upgradeRepository) GetPackageByFileName(fileName string) (*models.UpgradePackage, error) {
+	var pkg models.UpgradePackage
+	if err := r.db.Where("file_name = ?", fileName).First(&pkg).Error; err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
+
+// ListPackages 获取全部升级包，按版本和包名排序
+func (r *upgradeRepository) ListPackages() ([]*models.UpgradePackage, error) {
+	var packages []*models.UpgradePackage
+	err := r.db.Order("version DESC, name ASC, architecture ASC").Find(&packages).Error
+	return packages, err
+}
+
+// DeletePackage 删除升级包信息
+func (r *upgradeRepository) DeletePackage(id uint) error {
+	return r.db.Delete(&models.UpgradePackage{}, id).Error
+}
+
+// CreateJob 创建升级任务及设备记录
+func (r *upgradeRepository) CreateJob(job *models.UpgradeJob) error {
+	return r.db.Create(job).Error
+}
+
+// GetJob 获取升级任务及全部设备进度
+func (r *upgradeRepository) GetJob(id uint) (*models.UpgradeJob, error) {
+	var job models.UpgradeJob
+	err := r.db.Preload("Devices", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListJobs 按条件查询升级任务（不含设备进度），按创建时间倒序
+func (r *upgradeRepository) ListJobs(filter UpgradeJobFilter) ([]*models.UpgradeJob, int64, error) {
+	query := r.db.Model(&models.UpgradeJob{})
+	if filter.CreatedBy != nil {
+		query = query.Where("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []*models.UpgradeJob
+	query = query.Order("created_at DESC, id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return jobs, total, nil
+}
+
+// UpdateJob 更新任务状态和统计（不更新设备进度）
+func (r *upgradeRepository) UpdateJob(job *models.UpgradeJob) error {
+	return r.db.Model(job).
+		Select("status", "succeeded", "failed", "error", "started_at", "finished_at").
+		Updates(job).Error
+}
+
+// UpdateDevice 更新单台设备的升级进度和日志
+func (r *upgradeRepository) UpdateDevice(device *models.UpgradeJobDevice) error {
+	return r.db.Model(device).
+		Select("status", "from_version", "to_version", "log", "error", "started_at", "finished_at").
+		Updates(device).Error
+}
+
+// ActiveDeviceIDs 获取执行中的升级任务涉及的设备，避免同一设备被并发升级
+func (r *upgradeRepository) ActiveDeviceIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.UpgradeJobDevice{}).
+		Where("job_id IN (?)", r.db.Model(&models.UpgradeJob{}).Select("id").Where("status IN ?", activeUpgradeJobStatuses)).
+		Distinct().Pluck("device_id", &ids).Error
+	return ids, err
+}
+
+// MarkInterrupted 将服务重启前未完成的任务标记为中断，未完成的设备标记为跳过
+func (r *upgradeRepository) MarkInterrupted() (int64, error) {
+	var affected int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		active := tx.Model(&models.UpgradeJob{}).Select("id").Where("status IN ?", activeUpgradeJobStatuses)
+		if err := tx.Model(&models.UpgradeJobDevice{}).
+			Where("job_id IN (?) AND status NOT IN ?", active,
+				[]models.UpgradeDeviceStatus{models.UpgradeDeviceStatusSuccess, models.UpgradeDeviceStatusFailed}).
+			Updates(map[string]interface{}{
+				"status": models.UpgradeDeviceStatusSkipped,
+				"error":  "任务因服务重启中断",
+			}).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.UpgradeJob{}).
+			Where("status IN ?", activeUpgradeJobStatuses).
+			Update("status", models.UpgradeJobStatusInterrupted)
+		affected = result.RowsAffected
+		return result.Error
+	})
+	return affected, err
+}
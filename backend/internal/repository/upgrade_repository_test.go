@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"testing"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUpgradeRepository_PackagesAndJobs(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.UpgradePackage{}, &models.UpgradeJob{}, &models.UpgradeJobDevice{}))
+
+	repo := NewUpgradeRepository(db)
+
+	pkg := &models.UpgradePackage{FileName: "routeros-7.12.1-arm64.npk", Name: "routeros", Version: "7.12.1", Architecture: "arm64", Size: 12}
+	require.NoError(t, repo.CreatePackage(pkg))
+	assert.Error(t, repo.CreatePackage(&models.UpgradePackage{FileName: "routeros-7.12.1-arm64.npk", Name: "routeros", Version: "7.12.1"}))
+
+	found, err := repo.GetPackageByFileName("routeros-7.12.1-arm64.npk")
+	require.NoError(t, err)
+	assert.Equal(t, pkg.ID, found.ID)
+
+	job := &models.UpgradeJob{
+		TargetVersion: "7.12.1",
+		BatchSize:     1,
+		Status:        models.UpgradeJobStatusRebooting,
+		Total:         2,
+		Devices: []models.UpgradeJobDevice{
+			{DeviceID: 2, Position: 1, Status: models.UpgradeDeviceStatusSuccess},
+			{DeviceID: 1, Position: 0, Status: models.UpgradeDeviceStatusUploaded},
+		},
+	}
+	require.NoError(t, repo.CreateJob(job))
+	require.NoError(t, repo.CreateJob(&models.UpgradeJob{Status: models.UpgradeJobStatusCompleted,
+		Devices: []models.UpgradeJobDevice{{DeviceID: 3, Status: models.UpgradeDeviceStatusSuccess}}}))
+
+	// 只返回执行中任务的设备
+	ids, err := repo.ActiveDeviceIDs()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{1, 2}, ids)
+
+	// 设备按重启顺序返回
+	loaded, err := repo.GetJob(job.ID)
+	require.NoError(t, err)
+	require.Len(t, loaded.Devices, 2)
+	assert.Equal(t, uint(1), loaded.Devices[0].DeviceID)
+
+	loaded.Devices[0].Log = "uploaded routeros-7.12.1-arm64.npk"
+	require.NoError(t, repo.UpdateDevice(&loaded.Devices[0]))
+
+	_, total, err := repo.ListJobs(UpgradeJobFilter{Status: models.UpgradeJobStatusCompleted})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	affected, err := repo.MarkInterrupted()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	loaded, err = repo.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.UpgradeJobStatusInterrupted, loaded.Status)
+	assert.Equal(t, models.UpgradeDeviceStatusSkipped, loaded.Devices[0].Status)
+	assert.Equal(t, "uploaded routeros-7.12.1-arm64.npk", loaded.Devices[0].Log)
+	assert.Equal(t, models.UpgradeDeviceStatusSuccess, loaded.Devices[1].Status)
+
+	ids, err = repo.ActiveDeviceIDs()
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -32,6 +33,11 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// WithContext 返回绑定上下文的仓库，上下文中的组织限定用户的查询范围
+func (r *userRepository) WithContext(ctx context.Context) UserRepository {
+	return &userRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create 创建用户
 func (r *userRepository) Create(user *models.User) error {
 	if user == nil {
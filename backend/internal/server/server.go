@@ -2,13 +2,18 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"nmp-platform/internal/api"
+	"nmp-platform/internal/audit"
 	"nmp-platform/internal/auth"
 	"nmp-platform/internal/backup"
 	"nmp-platform/internal/config"
@@ -20,6 +25,7 @@ import (
 	"nmp-platform/internal/redis"
 	"nmp-platform/internal/repository"
 	"nmp-platform/internal/service"
+	"nmp-platform/internal/snmptrap"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -55,6 +61,10 @@ type Server struct {
 	// 数据接收相关
 	dataReceiverService *service.DataReceiverService
 	dataReceiverHandler *api.DataReceiverHandler
+	linuxMetricsPoller  *service.LinuxMetricsPoller
+	
+	// 接口链路状态事件相关
+	interfaceEventHandler *api.InterfaceEventHandler
 	
 	// 数据存储管理相关
 	dataCompressionService *service.DataCompressionService
@@ -81,6 +91,42 @@ type Server struct {
 	// 代理管理相关
 	proxyHandler *api.ProxyHandler
 	
+	// 服务端探测相关
+	probeScheduler *service.ProbeScheduler
+	probeHandler   *api.ProbeHandler
+	
+	// syslog 接收相关
+	syslogService *service.SyslogService
+	syslogHandler *api.SyslogHandler
+	
+	// SNMP trap 接收相关
+	snmpTrapService *service.SNMPTrapService
+	snmpTrapHandler *api.SNMPTrapHandler
+	
+	// 流量采集相关
+	flowService *service.FlowService
+	flowHandler *api.FlowHandler
+	
+	// 设备配置备份相关
+	configBackupService *service.ConfigBackupService
+	configBackupHandler *api.ConfigBackupHandler
+	
+	// 批量命令任务相关
+	commandJobService *service.CommandJobService
+	commandJobHandler *api.CommandJobHandler
+	
+	// 设备清单相关
+	inventoryService *service.InventoryService
+	inventoryHandler *api.InventoryHandler
+	
+	// RouterOS 升级相关
+	upgradeService *service.UpgradeService
+	upgradeHandler *api.UpgradeHandler
+	
+	// 路由邻居监控相关
+	routingService *service.RoutingService
+	routingHandler *api.RoutingHandler
+	
 	// 系统备份相关
 	backupService *backup.Service
 	backupHandler *api.SystemBackupHandler
@@ -88,6 +134,16 @@ type Server struct {
 	// 插件市场相关
 	mp                 *marketplace.Marketplace
 	marketplaceHandler *api.MarketplaceHandler
+	
+	// 审计日志相关
+	auditRecorder   *audit.Recorder
+	auditLogHandler *api.AuditLogHandler
+	
+	// 设备授权相关
+	deviceGrantHandler *api.DeviceGrantHandler
+	
+	// 组织管理相关
+	organizationHandler *api.OrganizationHandler
 }
 
 // New 创建新的服务器实例
@@ -140,6 +196,44 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 	// 创建认证服务
 	authService := auth.NewAuthService(userRepo, cfg.Auth.JWTSecret, cfg.Auth.TokenExpiry)
 
+	// 创建组织仓库（校验组织是否启用，允许全局超级管理员切换组织）
+	organizationRepo := repository.NewOrganizationRepository(database.DB)
+	authService.SetOrganizationRepository(organizationRepo)
+	organizationHandler := api.NewOrganizationHandler(organizationRepo)
+
+	// 创建审计日志记录器（记录所有变更操作和后台任务，按保留期清理）
+	auditLogRepo := repository.NewAuditLogRepository(database.DB)
+	auditRecorder := audit.NewRecorder(auditLogRepo, cfg.Audit.RetentionDays)
+	authService.SetAuditRecorder(auditRecorder)
+
+	// 创建会话存储（Redis 缓存撤销列表，数据库持久化会话）
+	sessionRepo := repository.NewSessionRepository(database.DB)
+	authService.SetSessionStore(auth.NewSessionStore(sessionRepo, redisClient, cfg.Auth.RefreshExpiry))
+
+	// 启用登录防暴力破解（Redis 记录失败次数）
+	if cfg.Auth.LoginProtection.Enabled {
+		authService.SetLoginProtector(auth.NewLoginProtector(redisClient, loginProtectionConfig(cfg.Auth.LoginProtection)))
+	}
+
+	// 创建两步验证服务
+	authService.SetTwoFactorService(auth.NewTwoFactorService(repository.NewTwoFactorRepository(database.DB)))
+
+	// 启用 LDAP / AD 认证，本地账号仍使用本地密码登录
+	if cfg.Auth.LDAP.Enabled {
+		authService.SetLDAPProvider(auth.NewLDAPProvider(ldapProviderConfig(cfg.Auth.LDAP), roleRepo))
+		logger.Info("LDAP authentication enabled", zap.String("url", cfg.Auth.LDAP.URL))
+	}
+
+	// 配置 OIDC 单点登录身份提供者
+	if len(cfg.Auth.OIDC.Providers) > 0 {
+		oidcProviders := make([]*auth.OIDCProvider, 0, len(cfg.Auth.OIDC.Providers))
+		for _, providerConfig := range cfg.Auth.OIDC.Providers {
+			oidcProviders = append(oidcProviders, auth.NewOIDCProvider(oidcProviderConfig(providerConfig), roleRepo))
+			logger.Info("OIDC provider configured", zap.String("name", providerConfig.Name), zap.String("issuer", providerConfig.IssuerURL))
+		}
+		authService.SetOIDCProviders(oidcProviders, cfg.Auth.OIDC.FrontendURL)
+	}
+
 	// 创建RBAC服务
 	rbacService, err := auth.NewRBACService(database.DB, userRepo, roleRepo, permRepo)
 	if err != nil {
@@ -169,11 +263,19 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 	tagRepo := repository.NewTagRepository(database.DB)
 	deviceGroupRepo := repository.NewDeviceGroupRepository(database.DB)
 
+	// 创建 API 令牌服务（令牌权限不能超过所属用户的 RBAC 权限，可限定到设备或分组）
+	apiTokenRepo := repository.NewAPITokenRepository(database.DB)
+	authService.SetAPITokenService(auth.NewAPITokenService(apiTokenRepo, userRepo, deviceRepo, rbacService))
+
 	// 创建设备管理相关服务
 	deviceService := service.NewDeviceService(deviceRepo, interfaceRepo, tagRepo, deviceGroupRepo)
 	tagService := service.NewTagService(tagRepo)
 	deviceGroupService := service.NewDeviceGroupService(deviceGroupRepo)
 
+	// 时序数据点和后台写入的记录按设备所属组织归属，查询和清理按组织区分
+	deviceOrganizations := service.NewDeviceOrganizationCache(deviceRepo, 5*time.Minute)
+	influxdbClient.SetOrganizationResolver(deviceOrganizations)
+
 	// 创建采集器仓库（提前创建，供数据接收服务使用）
 	collectorRepo := repository.NewCollectorRepository(database.DB)
 
@@ -181,6 +283,15 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 	influxAdapter := influxdb.NewServiceAdapter(influxdbClient)
 	dataReceiverService := service.NewDataReceiverServiceWithCollector(influxAdapter, redisClient, deviceRepo, collectorRepo)
 	dataReceiverHandler := api.NewDataReceiverHandler(dataReceiverService)
+	linuxMetricsPoller := service.NewLinuxMetricsPoller(deviceRepo, interfaceRepo, dataReceiverService)
+	linuxMetricsPoller.SetPollInterval(cfg.LinuxPoller.Interval)
+
+	// 创建接口链路状态跟踪服务
+	interfaceEventRepo := repository.NewInterfaceEventRepository(database.DB)
+	linkStateService := service.NewLinkStateService(interfaceEventRepo, interfaceRepo)
+	linkStateService.SetDeviceOrganizations(deviceOrganizations)
+	dataReceiverService.SetLinkStateService(linkStateService)
+	interfaceEventHandler := api.NewInterfaceEventHandler(linkStateService)
 
 	// 创建数据压缩服务和处理器
 	dataCompressionService := service.NewDataCompressionService(influxAdapter, redisClient)
@@ -217,6 +328,10 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 			Org:    cfg.InfluxDB.Org,
 		},
 	)
+	// 按组织的数据保留天数额外清理
+	dataCleanupService.SetOrganizationRepository(organizationRepo)
+	// 删除设备或清空设备数据时一并删除接口状态事件
+	dataCleanupService.SetInterfaceEventRepository(interfaceEventRepo)
 	
 	// 构建服务器 URL（用于采集器脚本）
 	// 优先使用配置的 public_url，否则尝试自动检测
@@ -246,6 +361,8 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 		pingTargetRepo,
 		serverURL,
 	)
+	// 创建设备时检查组织的设备数量上限
+	deviceHandler.SetOrganizationRepository(organizationRepo)
 	tagHandler := api.NewTagHandler(tagService)
 	deviceGroupHandler := api.NewDeviceGroupHandler(deviceGroupService, deviceService)
 	
@@ -273,8 +390,81 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 	// 创建代理管理相关
 	proxyRepo := repository.NewProxyRepository(database.DB)
 	proxyManager := proxy.NewManager(proxyRepo)
+	linuxMetricsPoller.SetDialerProvider(proxyManager) // Linux 设备轮询经设备配置的代理连接
 	proxyHandler := api.NewProxyHandler(proxyRepo, proxyManager)
 
+	// 创建服务端探测相关（可通过代理从客户网络发起探测）
+	probeRepo := repository.NewProbeRepository(database.DB)
+	probeService := service.NewProbeService(probeRepo, influxAdapter, proxyManager)
+	probeScheduler := service.NewProbeScheduler(probeService)
+	probeService.SetChangeCallback(probeScheduler.Reload)
+	probeHandler := api.NewProbeHandler(probeService)
+	probeHandler.SetProxyRepository(proxyRepo)
+
+	// 创建 syslog 接收服务和处理器（按来源 IP 归属到设备）
+	syslogRepo := repository.NewSyslogRepository(database.DB)
+	syslogUDPAddr, syslogTCPAddr := "", ""
+	if cfg.Syslog.Enabled {
+		syslogUDPAddr, syslogTCPAddr = cfg.Syslog.UDPAddress, cfg.Syslog.TCPAddress
+	}
+	syslogService := service.NewSyslogService(syslogRepo, dataReceiverService, syslogUDPAddr, syslogTCPAddr,
+		time.Duration(cfg.Syslog.RetentionDays)*24*time.Hour)
+	syslogHost, syslogPort := forwardTarget(serverURL, cfg.Syslog.UDPAddress, 514)
+	syslogHandler := api.NewSyslogHandler(syslogService, deviceRepo, serverURL, syslogHost, syslogPort)
+
+	// 创建 SNMP trap 接收服务和处理器（linkDown/linkUp 按 ifIndex 更新接口状态）
+	snmpTrapConfig, err := snmpTrapServerConfig(cfg.SNMPTrap)
+	if err != nil {
+		logger.Error("Invalid SNMP trap config", zap.Error(err))
+		return nil, err
+	}
+	snmpTrapRepo := repository.NewSNMPTrapRepository(database.DB)
+	snmpTrapService, err := service.NewSNMPTrapService(snmpTrapRepo, interfaceRepo, linkStateService, dataReceiverService,
+		snmpTrapConfig, time.Duration(cfg.SNMPTrap.RetentionDays)*24*time.Hour)
+	if err != nil {
+		logger.Error("Failed to create SNMP trap service", zap.Error(err))
+		return nil, err
+	}
+	snmpTrapHandler := api.NewSNMPTrapHandler(snmpTrapService)
+
+	// 创建流量采集服务和处理器（按导出器地址归属设备、按 ifIndex 归属接口）
+	flowService := service.NewFlowService(influxAdapter, interfaceRepo, dataReceiverService, cfg.Flow.Address, cfg.Flow.TopN)
+	flowHost, flowPort := forwardTarget(serverURL, cfg.Flow.Address, 2055)
+	flowHandler := api.NewFlowHandler(dataQueryService, deviceRepo, serverURL, flowHost, flowPort)
+
+	// 创建设备配置备份服务和处理器（配置变化时保存新版本，经设备代理连接）
+	configBackupRepo := repository.NewConfigBackupRepository(database.DB)
+	configBackupService := service.NewConfigBackupService(configBackupRepo, deviceRepo, cfg.ConfigBackup.Interval,
+		cfg.ConfigBackup.LinuxFiles, cfg.ConfigBackup.MaxVersions)
+	configBackupService.SetDialerProvider(proxyManager)
+	configBackupService.SetAuditRecorder(auditRecorder)
+	configBackupHandler := api.NewConfigBackupHandler(configBackupService, deviceRepo)
+
+	// 创建批量命令任务服务（通过设备代理执行）
+	commandJobRepo := repository.NewCommandJobRepository(database.DB)
+	commandJobService := service.NewCommandJobService(commandJobRepo, deviceRepo, proxyManager)
+	commandJobService.SetAuditRecorder(auditRecorder)
+
+	// 创建设备清单服务和处理器（定时刷新硬件/软件清单，经设备代理连接）
+	inventoryRepo := repository.NewDeviceInventoryRepository(database.DB)
+	inventoryService := service.NewInventoryService(inventoryRepo, deviceRepo, cfg.Inventory.Interval)
+	inventoryService.SetDialerProvider(proxyManager)
+	inventoryHandler := api.NewInventoryHandler(inventoryService, deviceRepo)
+
+	// 创建 RouterOS 升级服务（重启后通过设备状态检查器确认设备恢复在线并重新推送数据）
+	deviceStatusChecker := service.NewDeviceStatusCheckerWithSettings(deviceRepo, collectorRepo, settingsRepo, redisClient)
+	upgradeRepo := repository.NewUpgradeRepository(database.DB)
+	upgradeService := service.NewUpgradeService(upgradeRepo, deviceRepo, inventoryRepo, inventoryService,
+		deviceStatusChecker, cfg.Upgrade.PackageDir, serverURL)
+	upgradeService.SetDialerProvider(proxyManager)
+	upgradeService.SetAuditRecorder(auditRecorder)
+
+	// 创建路由邻居监控服务和处理器（BGP/OSPF 邻居状态变化记录为事件，经设备代理连接）
+	routingRepo := repository.NewRoutingRepository(database.DB)
+	routingService := service.NewRoutingService(routingRepo, deviceRepo, cfg.Routing.Interval)
+	routingService.SetDialerProvider(proxyManager)
+	routingHandler := api.NewRoutingHandler(routingService, deviceRepo)
+
 	// 创建系统备份服务和处理器
 	backupConfig := &backup.BackupConfig{
 		BackupDir:    "/opt/nmp/backups",
@@ -303,15 +493,38 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 	mp := marketplace.NewMarketplace(marketplaceConfig, logger)
 	marketplaceHandler := api.NewMarketplaceHandler(mp, logger)
 
-	// 创建设备权限检查器
-	devicePermChecker := auth.NewDevicePermissionChecker(rbacService, permRepo, userRepo)
+	// 创建设备权限检查器（按分组、标签或设备授权解析用户的有效设备权限并缓存）
+	deviceGrantRepo := repository.NewDeviceGrantRepository(database.DB)
+	devicePermChecker := auth.NewDevicePermissionChecker(rbacService, permRepo, userRepo, deviceGrantRepo)
+	// group 范围的权限只作用于用户被授权的设备分组中的资源
+	rbacService.SetDeviceGroupResolver(devicePermChecker)
+	deviceGrantHandler := api.NewDeviceGrantHandler(deviceGrantRepo, devicePermChecker)
+	// 有效设备权限限定在组织的设备内，设备和组织变化后立即清除缓存
+	deviceHandler.SetDeviceAccessResolver(devicePermChecker)
+	organizationHandler.SetDeviceAccessResolver(devicePermChecker)
+
+	// 创建批量命令任务处理器（逐台检查目标设备的 update 权限）
+	commandJobHandler := api.NewCommandJobHandler(commandJobService, devicePermChecker)
+
+	// 创建 RouterOS 升级处理器（逐台检查目标设备的 update 权限）
+	upgradeHandler := api.NewUpgradeHandler(upgradeService, devicePermChecker)
+
+	// 注册审计快照加载函数，变更前后的快照用于计算字段差异
+	registerAuditLoaders(auditRecorder, deviceRepo, deviceGroupRepo, tagRepo, proxyRepo, probeRepo, userRepo, roleRepo, deviceGrantRepo)
+	auditLogHandler := api.NewAuditLogHandler(auditLogRepo)
 
 	// 创建路由器
 	router := gin.New()
+	// 客户端地址只采信可信反向代理转发的请求头，避免伪造 X-Forwarded-For 绕过或滥用按来源地址的登录锁定
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
 	// 添加中间件
 	router.Use(RecoveryMiddleware(logger))
 	router.Use(LoggerMiddleware(logger))
+	// 审计所有变更请求（设备数据推送除外），依赖 LoggerMiddleware 生成的请求ID
+	router.Use(auditRecorder.Middleware("/api/push", "/api/v1/push", "/api/v1/data/push"))
 	router.Use(ErrorHandlerMiddleware(logger))
 	router.Use(CORSMiddleware())
 	router.Use(SecurityMiddleware())
@@ -352,6 +565,10 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 		// 数据接收相关
 		dataReceiverService: dataReceiverService,
 		dataReceiverHandler: dataReceiverHandler,
+		linuxMetricsPoller:  linuxMetricsPoller,
+		
+		// 接口链路状态事件相关
+		interfaceEventHandler: interfaceEventHandler,
 		
 		// 数据存储管理相关
 		dataCompressionService: dataCompressionService,
@@ -378,6 +595,42 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 		// 代理管理相关
 		proxyHandler: proxyHandler,
 		
+		// 服务端探测相关
+		probeScheduler: probeScheduler,
+		probeHandler:   probeHandler,
+		
+		// syslog 接收相关
+		syslogService: syslogService,
+		syslogHandler: syslogHandler,
+		
+		// SNMP trap 接收相关
+		snmpTrapService: snmpTrapService,
+		snmpTrapHandler: snmpTrapHandler,
+		
+		// 流量采集相关
+		flowService: flowService,
+		flowHandler: flowHandler,
+		
+		// 设备配置备份相关
+		configBackupService: configBackupService,
+		configBackupHandler: configBackupHandler,
+		
+		// 批量命令任务相关
+		commandJobService: commandJobService,
+		commandJobHandler: commandJobHandler,
+		
+		// 设备清单相关
+		inventoryService: inventoryService,
+		inventoryHandler: inventoryHandler,
+		
+		// RouterOS 升级相关
+		upgradeService: upgradeService,
+		upgradeHandler: upgradeHandler,
+		
+		// 路由邻居监控相关
+		routingService: routingService,
+		routingHandler: routingHandler,
+		
 		// 系统备份相关
 		backupService: backupService,
 		backupHandler: backupHandler,
@@ -385,6 +638,16 @@ func New(cfg *config.Config, logger *zap.Logger) (*Server, error) {
 		// 插件市场相关
 		mp:                 mp,
 		marketplaceHandler: marketplaceHandler,
+		
+		// 审计日志相关
+		auditRecorder:   auditRecorder,
+		auditLogHandler: auditLogHandler,
+		
+		// 设备授权相关
+		deviceGrantHandler: deviceGrantHandler,
+		
+		// 组织管理相关
+		organizationHandler: organizationHandler,
 	}
 
 	// 设置路由
@@ -414,6 +677,40 @@ func (s *Server) Start() error {
 		zap.String("mode", s.config.Server.Mode),
 	)
 	
+	// 启动后台采集任务
+	s.auditRecorder.Start(context.Background())
+	s.linuxMetricsPoller.Start(context.Background())
+	s.probeScheduler.Start(context.Background())
+	if s.config.Syslog.Enabled {
+		if err := s.syslogService.Start(context.Background()); err != nil {
+			// syslog 监听失败（如端口被占用或权限不足）不影响 HTTP 服务
+			s.logger.Error("Failed to start syslog receiver", zap.Error(err))
+		}
+	}
+	if s.config.SNMPTrap.Enabled {
+		if err := s.snmpTrapService.Start(context.Background()); err != nil {
+			// trap 监听失败（如 162 端口被占用或权限不足）不影响 HTTP 服务
+			s.logger.Error("Failed to start SNMP trap receiver", zap.Error(err))
+		}
+	}
+	if s.config.Flow.Enabled {
+		if err := s.flowService.Start(context.Background()); err != nil {
+			// 流量采集监听失败不影响 HTTP 服务
+			s.logger.Error("Failed to start flow collector", zap.Error(err))
+		}
+	}
+	if s.config.ConfigBackup.Enabled {
+		s.configBackupService.Start(context.Background())
+	}
+	s.commandJobService.Start(context.Background())
+	if s.config.Inventory.Enabled {
+		s.inventoryService.Start(context.Background())
+	}
+	s.upgradeService.Start(context.Background())
+	if s.config.Routing.Enabled {
+		s.routingService.Start(context.Background())
+	}
+	
 	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		s.logger.Error("Failed to start HTTP server", zap.Error(err))
 		return err
@@ -426,6 +723,18 @@ func (s *Server) Start() error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server")
 	
+	s.linuxMetricsPoller.Stop()
+	s.probeScheduler.Stop()
+	s.syslogService.Stop()
+	s.snmpTrapService.Stop()
+	s.flowService.Stop()
+	s.configBackupService.Stop()
+	s.commandJobService.Stop()
+	s.inventoryService.Stop()
+	s.upgradeService.Stop()
+	s.routingService.Stop()
+	s.auditRecorder.Stop()
+	
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		s.logger.Error("Failed to shutdown HTTP server", zap.Error(err))
 		return err
@@ -498,7 +807,8 @@ func (s *Server) setupRoutes() {
 		
 		// 注册设备管理路由
 		authenticated := api.Group("")
-		authenticated.Use(auth.AuthMiddleware(s.authService))
+		// 解析用户可查看的设备范围，供设备列表、查询和导出接口过滤（结果缓存）
+		authenticated.Use(auth.AuthMiddleware(s.authService), auth.DeviceListFilterMiddleware(s.devicePermChecker))
 		{
 			// 使用带权限检查的设备路由注册
 			readMiddleware := auth.DevicePermissionMiddleware(s.devicePermChecker, "read")
@@ -510,16 +820,37 @@ func (s *Server) setupRoutes() {
 			s.deviceGroupHandler.RegisterRoutes(authenticated)
 			s.connectionTestHandler.RegisterRoutes(authenticated) // 添加连接测试路由
 			s.pingTargetHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware) // 添加 Ping 目标管理路由（带权限检查）
+			s.interfaceEventHandler.RegisterRoutesWithPermission(authenticated, readMiddleware)              // 添加接口状态事件路由（带权限检查）
+			s.dataReceiverHandler.RegisterRoutesWithPermission(authenticated,
+				auth.DeviceParamPermissionMiddleware(s.devicePermChecker, "device_id", "read")) // 添加设备时钟偏差查询路由（带权限检查）
 			s.settingsHandler.RegisterRoutes(authenticated)       // 添加系统设置路由
 			s.collectorHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware) // 添加采集器管理路由（带权限检查）
 			s.proxyHandler.RegisterRoutes(authenticated)          // 添加代理管理路由
+			// 服务端探测不针对单台设备，按设备资源的 RBAC 权限检查（不能使用按路由 id 检查设备的中间件）
+			s.probeHandler.RegisterRoutesWithPermission(authenticated,
+				auth.RequirePermission(s.rbacService, "device", "read"),
+				auth.RequirePermission(s.rbacService, "device", "update")) // 添加服务端探测路由（带权限检查）
+			s.syslogHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware) // 添加 syslog 日志路由（带权限检查）
+			s.snmpTrapHandler.RegisterRoutesWithPermission(authenticated, readMiddleware)                  // 添加 SNMP trap 路由（带权限检查）
+			s.flowHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware)    // 添加流量分析路由（带权限检查）
+			s.configBackupHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware) // 添加配置备份路由（带权限检查）
+			s.commandJobHandler.RegisterRoutes(authenticated)     // 添加批量命令任务路由（处理器内逐台检查设备权限）
+			s.inventoryHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware) // 添加设备清单路由（带权限检查）
+			s.upgradeHandler.RegisterRoutes(authenticated)        // 添加 RouterOS 升级路由（处理器内逐台检查设备权限）
+			s.routingHandler.RegisterRoutesWithPermission(authenticated, readMiddleware, updateMiddleware) // 添加路由邻居监控路由（带权限检查）
 			s.backupHandler.RegisterRoutes(authenticated)         // 添加系统备份路由
 			s.marketplaceHandler.RegisterRoutes(authenticated)    // 添加插件市场路由
+			s.auditLogHandler.RegisterRoutesWithPermission(authenticated, auth.RequireGlobalAdmin()) // 添加审计日志路由（仅全局超级管理员）
+			s.deviceGrantHandler.RegisterRoutesWithPermission(authenticated, auth.RequireGlobalAdmin()) // 添加设备授权路由（仅全局超级管理员）
+			s.organizationHandler.RegisterRoutesWithPermission(authenticated, auth.RequireGlobalAdmin()) // 添加组织管理路由（仅全局超级管理员）
 		}
 		
 		// 注册数据接收路由（不需要认证，供设备推送数据使用）
 		s.dataReceiverHandler.RegisterRoutes(api)
 		
+		// 注册升级包下载路由（不需要认证，设备通过限时签名地址下载）
+		s.upgradeHandler.RegisterPublicRoutes(api)
+		
 		// 注册数据存储管理路由（需要认证）
 		s.dataStorageHandler.RegisterRoutes(authenticated)
 		
@@ -688,4 +1019,135 @@ func getLocalIP() string {
 		}
 	}
 	return ""
-}
\ No newline at end of file
+}
+
+// forwardTarget 根据平台地址和接收器监听地址计算设备转发目标（syslog、流量导出等）
+func forwardTarget(serverURL, listenAddr string, defaultPort int) (string, int) {
+	host := ""
+	if u, err := url.Parse(serverURL); err == nil {
+		host = u.Hostname()
+	}
+
+	port := defaultPort
+	if _, portStr, err := net.SplitHostPort(listenAddr); err == nil {
+		if p, err := strconv.Atoi(portStr); err == nil && p > 0 {
+			port = p
+		}
+	}
+	return host, port
+}
+
+// loginProtectionConfig 将登录保护配置转换为认证服务配置
+func loginProtectionConfig(cfg config.LoginProtectionConfig) auth.LoginProtectionConfig {
+	return auth.LoginProtectionConfig{
+		MaxAttempts:     cfg.MaxAttempts,
+		IPMaxAttempts:   cfg.IPMaxAttempts,
+		FreeAttempts:    cfg.FreeAttempts,
+		BaseDelay:       cfg.BaseDelay,
+		MaxDelay:        cfg.MaxDelay,
+		LockoutDuration: cfg.LockoutDuration,
+		Window:          cfg.Window,
+	}
+}
+
+// ldapProviderConfig 将 LDAP 配置转换为认证提供者配置
+func ldapProviderConfig(cfg config.LDAPConfig) auth.LDAPConfig {
+	providerConfig := auth.LDAPConfig{
+		URL:                cfg.URL,
+		StartTLS:           cfg.StartTLS,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		Timeout:            cfg.Timeout,
+		BindDN:             cfg.BindDN,
+		BindPassword:       cfg.BindPassword,
+		BaseDN:             cfg.BaseDN,
+		UserFilter:         cfg.UserFilter,
+		UsernameAttribute:  cfg.UsernameAttribute,
+		EmailAttribute:     cfg.EmailAttribute,
+		NameAttribute:      cfg.NameAttribute,
+		GroupAttribute:     cfg.GroupAttribute,
+		GroupBaseDN:        cfg.GroupBaseDN,
+		GroupFilter:        cfg.GroupFilter,
+		AutoCreateUsers:    cfg.AutoCreateUsers,
+		DefaultRole:        cfg.DefaultRole,
+	}
+	for _, mapping := range cfg.GroupMappings {
+		providerConfig.GroupMappings = append(providerConfig.GroupMappings, auth.LDAPGroupMapping{
+			Group: mapping.Group,
+			Role:  mapping.Role,
+		})
+	}
+	return providerConfig
+}
+
+// oidcProviderConfig 将 OIDC 提供者配置转换为身份提供者配置
+func oidcProviderConfig(cfg config.OIDCProviderConfig) auth.OIDCProviderConfig {
+	providerConfig := auth.OIDCProviderConfig{
+		Name:            cfg.Name,
+		DisplayName:     cfg.DisplayName,
+		IssuerURL:       cfg.IssuerURL,
+		ClientID:        cfg.ClientID,
+		ClientSecret:    cfg.ClientSecret,
+		RedirectURL:     cfg.RedirectURL,
+		Scopes:          cfg.Scopes,
+		UsernameClaim:   cfg.UsernameClaim,
+		EmailClaim:      cfg.EmailClaim,
+		NameClaim:       cfg.NameClaim,
+		RolesClaim:      cfg.RolesClaim,
+		AutoCreateUsers: cfg.AutoCreateUsers,
+		DefaultRole:     cfg.DefaultRole,
+	}
+	for _, mapping := range cfg.RoleMappings {
+		providerConfig.RoleMappings = append(providerConfig.RoleMappings, auth.OIDCRoleMapping{
+			Value: mapping.Value,
+			Role:  mapping.Role,
+		})
+	}
+	return providerConfig
+}
+
+// snmpTrapServerConfig 将配置文件中的 SNMP trap 配置转换为监听配置
+func snmpTrapServerConfig(cfg config.SNMPTrapConfig) (snmptrap.Config, error) {
+	serverConfig := snmptrap.Config{
+		Address:     cfg.Address,
+		Communities: cfg.Communities,
+	}
+	if cfg.EngineID != "" {
+		engineID, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(cfg.EngineID), "0x"))
+		if err != nil {
+			return serverConfig, fmt.Errorf("invalid snmptrap.engine_id: %w", err)
+		}
+		serverConfig.EngineID = engineID
+	}
+	for _, user := range cfg.Users {
+		serverConfig.Users = append(serverConfig.Users, snmptrap.USMUser{
+			Name:         user.Name,
+			AuthProtocol: user.AuthProtocol,
+			AuthPassword: user.AuthPassword,
+			PrivProtocol: user.PrivProtocol,
+			PrivPassword: user.PrivPassword,
+		})
+	}
+	return serverConfig, nil
+}
+
+// registerAuditLoaders 注册审计日志使用的资源快照加载函数，资源类型与路由第一段一致
+func registerAuditLoaders(
+	recorder *audit.Recorder,
+	deviceRepo repository.DeviceRepository,
+	deviceGroupRepo repository.DeviceGroupRepository,
+	tagRepo repository.TagRepository,
+	proxyRepo repository.ProxyRepository,
+	probeRepo repository.ProbeRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	deviceGrantRepo repository.DeviceGrantRepository,
+) {
+	recorder.RegisterLoader("devices", func(id uint) (interface{}, error) { return deviceRepo.GetByID(id) })
+	recorder.RegisterLoader("device-groups", func(id uint) (interface{}, error) { return deviceGroupRepo.GetByID(id) })
+	recorder.RegisterLoader("tags", func(id uint) (interface{}, error) { return tagRepo.GetByID(id) })
+	recorder.RegisterLoader("proxies", func(id uint) (interface{}, error) { return proxyRepo.GetByID(id) })
+	recorder.RegisterLoader("probes", func(id uint) (interface{}, error) { return probeRepo.GetByID(id) })
+	recorder.RegisterLoader("users", func(id uint) (interface{}, error) { return userRepo.GetByID(id) })
+	recorder.RegisterLoader("roles", func(id uint) (interface{}, error) { return roleRepo.GetByID(id) })
+	recorder.RegisterLoader("device-grants", func(id uint) (interface{}, error) { return deviceGrantRepo.GetByID(id) })
+}
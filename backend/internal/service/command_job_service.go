@@ -0,0 +1,541 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"nmp-platform/internal/audit"
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// 批量命令任务相关常量
+const (
+	defaultCommandJobConcurrency = 10
+	maxCommandJobConcurrency     = 50
+	defaultCommandJobTimeout     = 60  // 秒
+	maxCommandJobTimeout         = 600 // 秒
+	commandJobConnectTimeout     = 15 * time.Second
+)
+
+// 批量命令任务错误
+var (
+	ErrCommandJobNoTargets    = errors.New("no devices selected")
+	ErrCommandJobNotRunning   = errors.New("command job is not running")
+	ErrCommandJobInvalidInput = errors.New("invalid command job")
+)
+
+// CommandTargetSelection 批量命令的目标选择，三类条件取并集
+type CommandTargetSelection struct {
+	DeviceIDs []uint `json:"device_ids"`
+	GroupIDs  []uint `json:"group_ids"`
+	TagIDs    []uint `json:"tag_ids"`
+}
+
+// CommandJobRequest 批量命令任务请求
+type CommandJobRequest struct {
+	Name          string                 `json:"name"`
+	Template      string                 `json:"template" binding:"required"`
+	OSType        models.DeviceOSType    `json:"os_type" binding:"required"` // mikrotik 或 linux，类型不符的设备被跳过
+	Targets       CommandTargetSelection `json:"targets"`
+	Variables     map[string]string      `json:"variables"` // 模板中通过 {{.Vars.xxx}} 引用
+	Concurrency   int                    `json:"concurrency"`
+	Timeout       int                    `json:"timeout"` // 单台设备超时（秒）
+	StopOnFailure bool                   `json:"stop_on_failure"`
+}
+
+// CommandJobPreviewItem 预览中单台设备的渲染结果
+type CommandJobPreviewItem struct {
+	DeviceID   uint                `json:"device_id"`
+	DeviceName string              `json:"device_name"`
+	Host       string              `json:"host"`
+	OSType     models.DeviceOSType `json:"os_type"`
+	Command    string              `json:"command,omitempty"`
+	SkipReason string              `json:"skip_reason,omitempty"`
+	Error      string              `json:"error,omitempty"` // 模板渲染错误
+}
+
+// CommandJobPreview 批量命令预览（dry-run）结果
+type CommandJobPreview struct {
+	Total    int                     `json:"total"`
+	Runnable int                     `json:"runnable"`
+	Skipped  int                     `json:"skipped"`
+	Errors   int                     `json:"errors"`
+	Items    []CommandJobPreviewItem `json:"items"`
+}
+
+// commandTemplateData 命令模板可引用的字段
+type commandTemplateData struct {
+	ID          uint
+	Name        string
+	Host        string
+	Port        int
+	OSType      models.DeviceOSType
+	Version     string
+	Description string
+	Vars        map[string]string
+}
+
+// runningCommandJob 执行中任务的控制信息
+type runningCommandJob struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// CommandJobService 批量命令任务服务
+// 在选定设备上通过 SSH（经设备配置的代理）并发执行命令模板，记录每台设备的输出和退出码
+type CommandJobService struct {
+	repo         repository.CommandJobRepository
+	deviceRepo   repository.DeviceRepository
+	dialers      ProxyDialerProvider
+	sshCollector *collector.SSHCollector
+	audit        *audit.Recorder
+
+	jobs map[uint]*runningCommandJob
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+// NewCommandJobService 创建批量命令任务服务
+func NewCommandJobService(
+	repo repository.CommandJobRepository,
+	deviceRepo repository.DeviceRepository,
+	dialers ProxyDialerProvider,
+) *CommandJobService {
+	return &CommandJobService{
+		repo:         repo,
+		deviceRepo:   deviceRepo,
+		dialers:      dialers,
+		sshCollector: collector.NewSSHCollector(commandJobConnectTimeout),
+		jobs:         make(map[uint]*runningCommandJob),
+	}
+}
+
+// SetAuditRecorder 设置审计记录器，任务结束时记录执行结果
+func (s *CommandJobService) SetAuditRecorder(recorder *audit.Recorder) {
+	s.audit = recorder
+}
+
+// Start 将服务重启前未完成的任务标记为中断
+func (s *CommandJobService) Start(ctx context.Context) {
+	count, err := s.repo.MarkInterrupted()
+	if err != nil {
+		log.Printf("Failed to mark interrupted command jobs: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Marked %d unfinished command jobs as interrupted", count)
+	}
+}
+
+// Stop 停止派发新设备并等待执行中的命令结束
+func (s *CommandJobService) Stop() {
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		job.cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Command job service stopped")
+}
+
+// ResolveTargets 解析目标选择为设备列表（去重，按 ID 排序），上下文中的组织限定设备范围
+func (s *CommandJobService) ResolveTargets(ctx context.Context, selection CommandTargetSelection) ([]*models.Device, error) {
+	deviceRepo := tenant.Bind(ctx, s.deviceRepo)
+	devices := make(map[uint]*models.Device)
+	add := func(list []*models.Device) {
+		for _, device := range list {
+			devices[device.ID] = device
+		}
+	}
+
+	for _, id := range selection.DeviceIDs {
+		device, err := deviceRepo.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("device %d not found: %w", id, err)
+		}
+		add([]*models.Device{device})
+	}
+	for _, id := range selection.GroupIDs {
+		list, err := deviceRepo.GetByGroupID(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get devices of group %d: %w", id, err)
+		}
+		add(list)
+	}
+	for _, id := range selection.TagIDs {
+		list, err := deviceRepo.GetByTagID(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get devices of tag %d: %w", id, err)
+		}
+		add(list)
+	}
+
+	if len(devices) == 0 {
+		return nil, ErrCommandJobNoTargets
+	}
+
+	result := make([]*models.Device, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, device)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// Preview 渲染每台设备将要执行的命令，不连接设备
+func (s *CommandJobService) Preview(req *CommandJobRequest, devices []*models.Device) (*CommandJobPreview, error) {
+	if req.OSType != models.DeviceOSTypeMikroTik && req.OSType != models.DeviceOSTypeLinux {
+		return nil, fmt.Errorf("%w: os_type must be mikrotik or linux", ErrCommandJobInvalidInput)
+	}
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(req.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%w: template parse error: %v", ErrCommandJobInvalidInput, err)
+	}
+
+	preview := &CommandJobPreview{Total: len(devices), Items: make([]CommandJobPreviewItem, 0, len(devices))}
+	for _, device := range devices {
+		item := CommandJobPreviewItem{
+			DeviceID:   device.ID,
+			DeviceName: device.Name,
+			Host:       device.Host,
+			OSType:     device.OSType,
+		}
+		if device.OSType != req.OSType {
+			item.SkipReason = fmt.Sprintf("系统类型 %s 与任务类型 %s 不符", device.OSType, req.OSType)
+			preview.Skipped++
+		} else if item.Command, err = renderCommandTemplate(tmpl, device, req.Variables); err != nil {
+			item.Error = err.Error()
+			preview.Errors++
+		} else {
+			preview.Runnable++
+		}
+		preview.Items = append(preview.Items, item)
+	}
+	return preview, nil
+}
+
+// renderCommandTemplate 用设备信息和变量渲染命令模板
+func renderCommandTemplate(tmpl *template.Template, device *models.Device, vars map[string]string) (string, error) {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, commandTemplateData{
+		ID:          device.ID,
+		Name:        device.Name,
+		Host:        device.Host,
+		Port:        device.Port,
+		OSType:      device.OSType,
+		Version:     device.Version,
+		Description: device.Description,
+		Vars:        vars,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(buf.Bytes())) == 0 {
+		return "", fmt.Errorf("rendered command is empty")
+	}
+	return buf.String(), nil
+}
+
+// Submit 创建任务并在后台执行，任务归属上下文中的组织
+// 任何设备模板渲染失败时拒绝执行；系统类型不符的设备记录为跳过
+func (s *CommandJobService) Submit(ctx context.Context, req *CommandJobRequest, devices []*models.Device, userID uint, username string) (*models.CommandJob, *CommandJobPreview, error) {
+	preview, err := s.Preview(req, devices)
+	if err != nil {
+		return nil, nil, err
+	}
+	if preview.Errors > 0 {
+		return nil, preview, fmt.Errorf("%w: template rendering failed on %d devices", ErrCommandJobInvalidInput, preview.Errors)
+	}
+	if preview.Runnable == 0 {
+		return nil, preview, ErrCommandJobNoTargets
+	}
+
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultCommandJobConcurrency
+	}
+	if concurrency > maxCommandJobConcurrency {
+		concurrency = maxCommandJobConcurrency
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultCommandJobTimeout
+	}
+	if timeout > maxCommandJobTimeout {
+		timeout = maxCommandJobTimeout
+	}
+
+	targets, _ := json.Marshal(req.Targets)
+	now := time.Now()
+	job := &models.CommandJob{
+		Name:          req.Name,
+		Template:      req.Template,
+		OSType:        req.OSType,
+		Targets:       string(targets),
+		Concurrency:   concurrency,
+		Timeout:       timeout,
+		StopOnFailure: req.StopOnFailure,
+		Status:        models.CommandJobStatusRunning,
+		Total:         preview.Total,
+		Skipped:       preview.Skipped,
+		CreatedBy:     userID,
+		CreatedByName: username,
+		StartedAt:     &now,
+		Results:       make([]models.CommandJobResult, 0, len(preview.Items)),
+	}
+	for _, item := range preview.Items {
+		result := models.CommandJobResult{
+			DeviceID:   item.DeviceID,
+			DeviceName: item.DeviceName,
+			Host:       item.Host,
+			Command:    item.Command,
+			Status:     models.CommandResultStatusPending,
+		}
+		if item.SkipReason != "" {
+			result.Status = models.CommandResultStatusSkipped
+			result.Error = item.SkipReason
+		}
+		job.Results = append(job.Results, result)
+	}
+
+	if err := tenant.Bind(ctx, s.repo).Create(job); err != nil {
+		return nil, preview, fmt.Errorf("failed to create command job: %w", err)
+	}
+
+	deviceByID := make(map[uint]*models.Device, len(devices))
+	for _, device := range devices {
+		deviceByID[device.ID] = device
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.jobs[job.ID] = &runningCommandJob{cancel: cancel}
+	s.mu.Unlock()
+
+	log.Printf("Command job %d started by %s (user %d): %d devices, concurrency %d",
+		job.ID, username, userID, preview.Runnable, concurrency)
+
+	s.wg.Add(1)
+	go s.run(runCtx, job, deviceByID)
+
+	return job, preview, nil
+}
+
+// Cancel 取消执行中的任务：不再派发新设备，已在执行的命令继续完成
+func (s *CommandJobService) Cancel(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrCommandJobNotRunning
+	}
+	job.cancelled = true
+	job.cancel()
+	return nil
+}
+
+// Get 获取任务及设备结果，上下文中的组织限定查询范围
+func (s *CommandJobService) Get(ctx context.Context, id uint) (*models.CommandJob, error) {
+	return tenant.Bind(ctx, s.repo).GetByID(id)
+}
+
+// List 查询任务列表，上下文中的组织限定查询范围
+func (s *CommandJobService) List(ctx context.Context, filter repository.CommandJobFilter) ([]*models.CommandJob, int64, error) {
+	return tenant.Bind(ctx, s.repo).List(filter)
+}
+
+// run 以有限并发执行任务
+// ctx 取消（停止、取消或遇到失败）后不再派发新设备，已在执行的命令不中断，以免设备上留下执行一半的配置
+func (s *CommandJobService) run(ctx context.Context, job *models.CommandJob, devices map[uint]*models.Device) {
+	defer s.wg.Done()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		stopped bool
+	)
+	sem := make(chan struct{}, job.Concurrency)
+
+	for i := range job.Results {
+		result := &job.Results[i]
+		if result.Status != models.CommandResultStatusPending {
+			continue
+		}
+
+		if !acquireCommandSlot(ctx, sem) {
+			s.skipResult(job, result, &mu, "任务已停止，未执行")
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *models.CommandJobResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			success := s.execute(job, result, devices[result.DeviceID])
+
+			mu.Lock()
+			if success {
+				job.Succeeded++
+			} else {
+				job.Failed++
+				if job.StopOnFailure && !stopped {
+					stopped = true
+					s.stopJob(job.ID)
+				}
+			}
+			mu.Unlock()
+		}(result)
+	}
+	wg.Wait()
+
+	interrupted := ctx.Err() != nil
+	s.mu.Lock()
+	running := s.jobs[job.ID]
+	delete(s.jobs, job.ID)
+	s.mu.Unlock()
+	running.cancel()
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	switch {
+	case running.cancelled:
+		job.Status = models.CommandJobStatusCancelled
+	case stopped:
+		job.Status = models.CommandJobStatusStopped
+	case job.Failed > 0:
+		job.Status = models.CommandJobStatusFailed
+	case interrupted:
+		job.Status = models.CommandJobStatusInterrupted
+	default:
+		job.Status = models.CommandJobStatusCompleted
+	}
+	if err := s.repo.UpdateJob(job); err != nil {
+		log.Printf("Failed to update command job %d: %v", job.ID, err)
+	}
+
+	log.Printf("Command job %d %s: %d succeeded, %d failed, %d skipped",
+		job.ID, job.Status, job.Succeeded, job.Failed, job.Skipped)
+
+	var jobErr error
+	if job.Status != models.CommandJobStatusCompleted {
+		jobErr = fmt.Errorf("command job %s", job.Status)
+	}
+	s.audit.RecordJob(job.OrganizationID, "command-jobs.finish", "command-jobs", fmt.Sprint(job.ID),
+		fmt.Sprintf("succeeded=%d | failed=%d | skipped=%d", job.Succeeded, job.Failed, job.Skipped), jobErr)
+}
+
+// acquireCommandSlot 获取并发槽位，ctx 已取消时返回 false
+func acquireCommandSlot(ctx context.Context, sem chan struct{}) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case sem <- struct{}{}:
+		// 等待槽位期间任务可能已被停止
+		if ctx.Err() != nil {
+			<-sem
+			return false
+		}
+		return true
+	}
+}
+
+// stopJob 停止派发任务的剩余设备
+func (s *CommandJobService) stopJob(id uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.cancel()
+	}
+}
+
+// skipResult 将未执行的设备标记为跳过
+func (s *CommandJobService) skipResult(job *models.CommandJob, result *models.CommandJobResult, mu *sync.Mutex, reason string) {
+	result.Status = models.CommandResultStatusSkipped
+	result.Error = reason
+	if err := s.repo.UpdateResult(result); err != nil {
+		log.Printf("Failed to update result of command job %d device %d: %v", job.ID, result.DeviceID, err)
+	}
+	mu.Lock()
+	job.Skipped++
+	mu.Unlock()
+}
+
+// execute 在单台设备上执行命令并保存结果，返回是否成功
+func (s *CommandJobService) execute(job *models.CommandJob, result *models.CommandJobResult, device *models.Device) bool {
+	started := time.Now()
+	result.Status = models.CommandResultStatusRunning
+	result.StartedAt = &started
+	if err := s.repo.UpdateResult(result); err != nil {
+		log.Printf("Failed to update result of command job %d device %d: %v", job.ID, result.DeviceID, err)
+	}
+
+	err := s.runOnDevice(job, result, device)
+
+	finished := time.Now()
+	result.FinishedAt = &finished
+	if err != nil {
+		result.Status = models.CommandResultStatusFailed
+		result.Error = err.Error()
+	} else {
+		result.Status = models.CommandResultStatusSuccess
+	}
+	if err := s.repo.UpdateResult(result); err != nil {
+		log.Printf("Failed to update result of command job %d device %d: %v", job.ID, result.DeviceID, err)
+	}
+	return result.Status == models.CommandResultStatusSuccess
+}
+
+// runOnDevice 通过设备代理连接设备并执行命令，退出码非零或 RouterOS 输出错误时返回错误
+func (s *CommandJobService) runOnDevice(job *models.CommandJob, result *models.CommandJobResult, device *models.Device) error {
+	if device == nil {
+		return fmt.Errorf("设备不存在")
+	}
+
+	client, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		return fmt.Errorf("连接设备失败: %w", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(job.Timeout)*time.Second)
+	defer cancel()
+
+	output, err := s.sshCollector.RunCommand(ctx, client, result.Command)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("命令执行超时（%d 秒）", job.Timeout)
+		}
+		return err
+	}
+
+	exitStatus := output.ExitStatus
+	result.Stdout = output.Stdout
+	result.Stderr = output.Stderr
+	result.ExitStatus = &exitStatus
+
+	if exitStatus != 0 {
+		return fmt.Errorf("命令退出码 %d", exitStatus)
+	}
+	if device.OSType == models.DeviceOSTypeMikroTik {
+		if message := collector.RouterOSCommandError(output.Stdout + "\n" + output.Stderr); message != "" {
+			return fmt.Errorf("RouterOS 命令出错: %s", message)
+		}
+	}
+	return nil
+}
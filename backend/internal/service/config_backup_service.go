@@ -0,0 +1,360 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"nmp-platform/internal/audit"
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+)
+
+// 配置备份相关常量
+const (
+	// configBackupStartDelay 启动后首次备份的延迟，避开服务启动时的连接高峰
+	configBackupStartDelay = time.Minute
+	configBackupTimeout    = 30 * time.Second
+	configDiffContext      = 3
+)
+
+// ErrConfigBackupUnsupported 设备类型不支持配置备份
+var ErrConfigBackupUnsupported = errors.New("device type does not support config backup")
+
+// ConfigChangeEvent 设备配置变更事件，供告警等模块订阅
+type ConfigChangeEvent struct {
+	DeviceID        uint                       `json:"device_id"`
+	DeviceName      string                     `json:"device_name"`
+	Version         int                        `json:"version"`
+	PreviousVersion int                        `json:"previous_version"`
+	LinesAdded      int                        `json:"lines_added"`
+	LinesRemoved    int                        `json:"lines_removed"`
+	Trigger         models.ConfigBackupTrigger `json:"trigger"`
+	OccurredAt      time.Time                  `json:"occurred_at"`
+}
+
+// ConfigBackupResult 单次备份结果
+type ConfigBackupResult struct {
+	Changed bool                 `json:"changed"` // 配置是否有变化（无变化时不保存新版本）
+	Backup  *models.ConfigBackup `json:"backup"`  // 最新版本
+}
+
+// ConfigBackupService 设备配置备份服务
+// 定时通过 SSH 导出 RouterOS 配置或读取 Linux 配置文件，内容变化时保存新版本和相对上一版本的差异
+type ConfigBackupService struct {
+	repo         repository.ConfigBackupRepository
+	deviceRepo   repository.DeviceRepository
+	sshCollector *collector.SSHCollector
+	dialers      ProxyDialerProvider
+	interval     time.Duration
+	linuxFiles   []string
+	maxVersions  int
+	concurrency  int
+	onChange     func(ConfigChangeEvent)
+	audit        *audit.Recorder
+
+	deviceLocks sync.Map // 设备 ID -> *sync.Mutex，避免同一设备并发备份产生重复版本号
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewConfigBackupService 创建配置备份服务
+func NewConfigBackupService(
+	repo repository.ConfigBackupRepository,
+	deviceRepo repository.DeviceRepository,
+	interval time.Duration,
+	linuxFiles []string,
+	maxVersions int,
+) *ConfigBackupService {
+	return &ConfigBackupService{
+		repo:         repo,
+		deviceRepo:   deviceRepo,
+		sshCollector: collector.NewSSHCollector(configBackupTimeout),
+		interval:     interval,
+		linuxFiles:   linuxFiles,
+		maxVersions:  maxVersions,
+		concurrency:  4,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// SetChangeCallback 设置配置变更回调
+func (s *ConfigBackupService) SetChangeCallback(fn func(ConfigChangeEvent)) {
+	s.onChange = fn
+}
+
+// SetAuditRecorder 设置审计记录器，每轮定时备份结束时记录汇总结果
+func (s *ConfigBackupService) SetAuditRecorder(recorder *audit.Recorder) {
+	s.audit = recorder
+}
+
+// SetDialerProvider 设置代理拨号器提供者，设备配置了代理时经代理连接
+func (s *ConfigBackupService) SetDialerProvider(dialers ProxyDialerProvider) {
+	s.dialers = dialers
+}
+
+// Start 启动定时备份
+func (s *ConfigBackupService) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running || s.interval <= 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	log.Printf("Config backup scheduler started with interval %v", s.interval)
+}
+
+// Stop 停止定时备份，等待进行中的备份完成
+func (s *ConfigBackupService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Config backup scheduler stopped")
+}
+
+// run 调度循环：启动后延迟执行首次备份，之后按间隔执行
+func (s *ConfigBackupService) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(configBackupStartDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-timer.C:
+			s.BackupAll(ctx)
+			timer.Reset(s.interval)
+		}
+	}
+}
+
+// BackupAll 备份所有 RouterOS 和 Linux 设备
+func (s *ConfigBackupService) BackupAll(ctx context.Context) {
+	var devices []*models.Device
+	for _, osType := range []models.DeviceOSType{models.DeviceOSTypeMikroTik, models.DeviceOSTypeLinux} {
+		list, err := s.deviceRepo.GetByOSType(osType)
+		if err != nil {
+			log.Printf("Failed to get %s devices for config backup: %v", osType, err)
+			continue
+		}
+		devices = append(devices, list...)
+	}
+
+	sem := make(chan struct{}, s.concurrency)
+	var (
+		wg                         sync.WaitGroup
+		mu                         sync.Mutex
+		changed, unchanged, failed int
+	)
+	for _, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(device *models.Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, err := s.BackupDevice(ctx, device, models.ConfigBackupTriggerSchedule)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				failed++
+				log.Printf("Failed to back up config of device %d: %v", device.ID, err)
+			case result.Changed:
+				changed++
+			default:
+				unchanged++
+			}
+		}(device)
+	}
+	wg.Wait()
+
+	var backupErr error
+	if failed > 0 {
+		backupErr = fmt.Errorf("%d devices failed", failed)
+	}
+	s.audit.RecordJob(models.DefaultOrganizationID, "config-backups.schedule", "config-backups", "",
+		fmt.Sprintf("changed=%d | unchanged=%d | failed=%d", changed, unchanged, failed), backupErr)
+}
+
+// BackupDevice 采集单台设备的配置，与最新版本不同时保存新版本
+func (s *ConfigBackupService) BackupDevice(ctx context.Context, device *models.Device, trigger models.ConfigBackupTrigger) (*ConfigBackupResult, error) {
+	lockValue, _ := s.deviceLocks.LoadOrStore(device.ID, &sync.Mutex{})
+	lock := lockValue.(*sync.Mutex)
+	lock.Lock()
+	defer lock.Unlock()
+
+	content, err := s.fetchConfig(device)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	latest, err := s.repo.GetLatest(device.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get latest config version: %w", err)
+	}
+	if latest != nil && latest.Hash == hash {
+		return &ConfigBackupResult{Changed: false, Backup: latest}, nil
+	}
+
+	backup := &models.ConfigBackup{
+		OrganizationID: device.OrganizationID,
+		DeviceID:       device.ID,
+		Version:        1,
+		Hash:           hash,
+		Size:           len(content),
+		Content:        content,
+		Method:         "ssh",
+		Trigger:        trigger,
+	}
+	previousVersion := 0
+	if latest != nil {
+		previousVersion = latest.Version
+		backup.Version = latest.Version + 1
+		backup.Diff, err = unifiedConfigDiff(latest, backup)
+		if err != nil {
+			return nil, err
+		}
+		backup.LinesAdded, backup.LinesRemoved = CountDiffLines(backup.Diff)
+	}
+
+	if err := s.repo.Create(backup); err != nil {
+		return nil, fmt.Errorf("failed to save config version: %w", err)
+	}
+
+	if s.maxVersions > 0 {
+		if _, err := s.repo.DeleteOldVersions(device.ID, s.maxVersions); err != nil {
+			log.Printf("Failed to prune config versions of device %d: %v", device.ID, err)
+		}
+	}
+
+	if latest != nil {
+		log.Printf("Config of device %d (%s) changed: version %d, +%d/-%d lines",
+			device.ID, device.Name, backup.Version, backup.LinesAdded, backup.LinesRemoved)
+		if s.onChange != nil {
+			s.onChange(ConfigChangeEvent{
+				DeviceID:        device.ID,
+				DeviceName:      device.Name,
+				Version:         backup.Version,
+				PreviousVersion: previousVersion,
+				LinesAdded:      backup.LinesAdded,
+				LinesRemoved:    backup.LinesRemoved,
+				Trigger:         trigger,
+				OccurredAt:      backup.CreatedAt,
+			})
+		}
+	}
+
+	return &ConfigBackupResult{Changed: true, Backup: backup}, nil
+}
+
+// fetchConfig 通过 SSH 采集设备配置
+func (s *ConfigBackupService) fetchConfig(device *models.Device) (string, error) {
+	if device.OSType != models.DeviceOSTypeMikroTik && device.OSType != models.DeviceOSTypeLinux {
+		return "", ErrConfigBackupUnsupported
+	}
+
+	client, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	if device.OSType == models.DeviceOSTypeLinux {
+		return s.sshCollector.CollectLinuxFiles(client, s.linuxFiles)
+	}
+	return s.sshCollector.ExportMikroTikConfig(client)
+}
+
+// ListVersions 查询配置版本列表（不含内容），上下文中的组织限定查询范围
+func (s *ConfigBackupService) ListVersions(ctx context.Context, filter repository.ConfigBackupFilter) ([]*models.ConfigBackup, int64, error) {
+	return tenant.Bind(ctx, s.repo).List(filter)
+}
+
+// GetVersion 获取设备指定版本，version 为 0 时返回最新版本，上下文中的组织限定查询范围
+func (s *ConfigBackupService) GetVersion(ctx context.Context, deviceID uint, version int) (*models.ConfigBackup, error) {
+	repo := tenant.Bind(ctx, s.repo)
+	if version == 0 {
+		return repo.GetLatest(deviceID)
+	}
+	return repo.GetByVersion(deviceID, version)
+}
+
+// DiffVersions 比较设备的两个配置版本，返回统一差异格式，上下文中的组织限定查询范围
+func (s *ConfigBackupService) DiffVersions(ctx context.Context, deviceID uint, fromVersion, toVersion int) (string, error) {
+	repo := tenant.Bind(ctx, s.repo)
+	from, err := repo.GetByVersion(deviceID, fromVersion)
+	if err != nil {
+		return "", err
+	}
+	to, err := repo.GetByVersion(deviceID, toVersion)
+	if err != nil {
+		return "", err
+	}
+	return unifiedConfigDiff(from, to)
+}
+
+// unifiedConfigDiff 生成两个版本之间的统一差异
+func unifiedConfigDiff(from, to *models.ConfigBackup) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from.Content),
+		B:        difflib.SplitLines(to.Content),
+		FromFile: fmt.Sprintf("version %d", from.Version),
+		ToFile:   fmt.Sprintf("version %d", to.Version),
+		Context:  configDiffContext,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to generate config diff: %w", err)
+	}
+	return diff, nil
+}
+
+// CountDiffLines 统计统一差异中新增和删除的行数
+func CountDiffLines(diff string) (added, removed int) {
+	lines := strings.Split(diff, "\n")
+	// 跳过 ---/+++ 文件头，避免把内容中以 -- 开头的行误判为文件头
+	if len(lines) >= 2 && strings.HasPrefix(lines[0], "---") && strings.HasPrefix(lines[1], "+++") {
+		lines = lines[2:]
+	}
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
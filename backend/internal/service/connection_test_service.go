@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -108,7 +109,7 @@ func (s *ConnectionTestService) testSSHConnection(ctx context.Context, req *Test
 	}
 
 	// 连接地址
-	addr := fmt.Sprintf("%s:%d", req.Host, req.Port)
+	addr := net.JoinHostPort(req.Host, strconv.Itoa(req.Port))
 
 	// 尝试连接
 	conn, err := ssh.Dial("tcp", addr, config)
@@ -147,7 +148,7 @@ func (s *ConnectionTestService) testSSHConnection(ctx context.Context, req *Test
 // testMikroTikAPIConnection 测试 MikroTik API 连接
 func (s *ConnectionTestService) testMikroTikAPIConnection(ctx context.Context, req *TestConnectionRequest) (*TestConnectionResponse, error) {
 	// 连接地址
-	addr := fmt.Sprintf("%s:%d", req.Host, req.Port)
+	addr := net.JoinHostPort(req.Host, strconv.Itoa(req.Port))
 
 	// 尝试建立 TCP 连接
 	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
@@ -5,11 +5,21 @@ import (
 	"fmt"
 	"log"
 	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
 	"strconv"
 	"sync"
 	"time"
 )
 
+// deviceMeasurements 按设备写入的 measurement（带 device_id 标签），删除设备时全部清理
+var deviceMeasurements = []string{
+	"bandwidth", "ping", "device_metrics", "flow_total", "flow_top",
+	"ppp", "dhcp", "wireless", "queue", "health",
+}
+
+// retentionMeasurements 按数据保留天数清理的 measurement：设备数据和服务端探测数据
+var retentionMeasurements = append(append([]string{}, deviceMeasurements...), "probe")
+
 // DataCleanupService 数据清理服务
 // 负责清理 InfluxDB 中的过期监控数据
 type DataCleanupService struct {
@@ -17,6 +27,8 @@ type DataCleanupService struct {
 	redisClient    RedisClient
 	settingsRepo   repository.SettingsRepository
 	deviceRepo     repository.DeviceRepository
+	organizationRepo repository.OrganizationRepository
+	interfaceEventRepo repository.InterfaceEventRepository
 	
 	// 清理任务控制
 	stopChan       chan struct{}
@@ -158,6 +170,54 @@ func (s *DataCleanupService) executeCleanup(ctx context.Context) {
 	} else {
 		log.Printf("Successfully cleaned up data older than %d days", retentionDays)
 	}
+	
+	// 执行组织级清理
+	s.cleanupOrganizationData(ctx, retentionDays)
+}
+
+// SetOrganizationRepository 设置组织仓库
+// 设置后保留天数短于系统设置的组织会额外清理其过期数据
+func (s *DataCleanupService) SetOrganizationRepository(organizationRepo repository.OrganizationRepository) {
+	s.organizationRepo = organizationRepo
+}
+
+// SetInterfaceEventRepository 设置接口状态事件仓库，清理设备数据时一并删除其接口状态事件
+func (s *DataCleanupService) SetInterfaceEventRepository(interfaceEventRepo repository.InterfaceEventRepository) {
+	s.interfaceEventRepo = interfaceEventRepo
+}
+
+// cleanupOrganizationData 按组织保留天数清理数据
+// 组织保留天数取组织设置与组织上限中较短者，只有短于全局保留天数时才需要额外清理
+func (s *DataCleanupService) cleanupOrganizationData(ctx context.Context, globalRetentionDays int) {
+	if s.organizationRepo == nil {
+		return
+	}
+	
+	organizations, err := s.organizationRepo.List()
+	if err != nil {
+		log.Printf("Failed to list organizations for data cleanup: %v", err)
+		return
+	}
+	
+	for _, organization := range organizations {
+		orgCtx := tenant.WithOrganization(ctx, organization.ID)
+		retentionDays := tenant.Bind(orgCtx, s.settingsRepo).GetDataRetentionDays()
+		if organization.RetentionDays > 0 && (retentionDays <= 0 || organization.RetentionDays < retentionDays) {
+			retentionDays = organization.RetentionDays
+		}
+		if retentionDays <= 0 || retentionDays >= globalRetentionDays {
+			continue
+		}
+		
+		cutoffTime := time.Now().AddDate(0, 0, -retentionDays)
+		tags := map[string]string{tenant.InfluxTag: strconv.FormatUint(uint64(organization.ID), 10)}
+		for _, measurement := range retentionMeasurements {
+			if err := s.deleteDataBefore(ctx, measurement, cutoffTime, tags); err != nil {
+				log.Printf("Failed to cleanup %s data for organization %d: %v", measurement, organization.ID, err)
+			}
+		}
+		log.Printf("Organization %d data cleanup completed for data before %v (retention: %d days)", organization.ID, cutoffTime, retentionDays)
+	}
 }
 
 // CleanupExpiredData 清理过期数据（全局清理）
@@ -173,20 +233,11 @@ func (s *DataCleanupService) CleanupExpiredData(ctx context.Context, retentionDa
 	
 	log.Printf("Cleaning up data older than %v (retention: %d days)", cutoffTime, retentionDays)
 	
-	// 清理带宽数据
-	if err := s.deleteDataBefore(ctx, "bandwidth", cutoffTime, nil); err != nil {
-		log.Printf("Failed to cleanup bandwidth data: %v", err)
-		// 继续清理其他数据
-	}
-	
-	// 清理 Ping 数据
-	if err := s.deleteDataBefore(ctx, "ping", cutoffTime, nil); err != nil {
-		log.Printf("Failed to cleanup ping data: %v", err)
-	}
-	
-	// 清理设备指标数据
-	if err := s.deleteDataBefore(ctx, "device_metrics", cutoffTime, nil); err != nil {
-		log.Printf("Failed to cleanup device_metrics data: %v", err)
+	// 逐个清理，单个 measurement 失败不影响其他数据
+	for _, measurement := range retentionMeasurements {
+		if err := s.deleteDataBefore(ctx, measurement, cutoffTime, nil); err != nil {
+			log.Printf("Failed to cleanup %s data: %v", measurement, err)
+		}
 	}
 	
 	log.Printf("Global data cleanup completed for data before %v", cutoffTime)
@@ -206,19 +257,18 @@ func (s *DataCleanupService) CleanupDeviceData(ctx context.Context, deviceID uin
 	
 	log.Printf("Cleaning up all data for device %d", deviceID)
 	
-	// 清理 InfluxDB 带宽数据
-	if err := s.deleteDataWithPredicate(ctx, "bandwidth", predicate); err != nil {
-		log.Printf("Failed to cleanup bandwidth data for device %d: %v", deviceID, err)
-	}
-	
-	// 清理 InfluxDB Ping 数据
-	if err := s.deleteDataWithPredicate(ctx, "ping", predicate); err != nil {
-		log.Printf("Failed to cleanup ping data for device %d: %v", deviceID, err)
+	// 清理 InfluxDB 中该设备的全部时序数据
+	for _, measurement := range deviceMeasurements {
+		if err := s.deleteDataWithPredicate(ctx, measurement, predicate); err != nil {
+			log.Printf("Failed to cleanup %s data for device %d: %v", measurement, deviceID, err)
+		}
 	}
 	
-	// 清理 InfluxDB 设备指标数据
-	if err := s.deleteDataWithPredicate(ctx, "device_metrics", predicate); err != nil {
-		log.Printf("Failed to cleanup device_metrics data for device %d: %v", deviceID, err)
+	// 清理接口状态事件
+	if s.interfaceEventRepo != nil {
+		if err := s.interfaceEventRepo.DeleteByDeviceID(deviceID); err != nil {
+			log.Printf("Failed to cleanup interface events for device %d: %v", deviceID, err)
+		}
 	}
 	
 	// 清理 Redis 缓存数据
@@ -271,19 +321,10 @@ func (s *DataCleanupService) CleanupDeviceDataBefore(ctx context.Context, device
 	
 	log.Printf("Cleaning up data for device %d before %v", deviceID, before)
 	
-	// 清理带宽数据
-	if err := s.deleteDataBefore(ctx, "bandwidth", before, tags); err != nil {
-		log.Printf("Failed to cleanup bandwidth data for device %d: %v", deviceID, err)
-	}
-	
-	// 清理 Ping 数据
-	if err := s.deleteDataBefore(ctx, "ping", before, tags); err != nil {
-		log.Printf("Failed to cleanup ping data for device %d: %v", deviceID, err)
-	}
-	
-	// 清理设备指标数据
-	if err := s.deleteDataBefore(ctx, "device_metrics", before, tags); err != nil {
-		log.Printf("Failed to cleanup device_metrics data for device %d: %v", deviceID, err)
+	for _, measurement := range deviceMeasurements {
+		if err := s.deleteDataBefore(ctx, measurement, before, tags); err != nil {
+			log.Printf("Failed to cleanup %s data for device %d: %v", measurement, deviceID, err)
+		}
 	}
 	
 	log.Printf("Device data cleanup completed for device %d before %v", deviceID, before)
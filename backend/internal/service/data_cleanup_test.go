@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -168,6 +169,10 @@ func (m *CleanupMockRecord) Value() interface{} {
 	return m.value
 }
 
+func (m *CleanupMockRecord) ValueByKey(key string) interface{} {
+	return nil
+}
+
 // CleanupMockInfluxClient 清理测试专用的 InfluxDB 客户端模拟
 type CleanupMockInfluxClient struct {
 	mock.Mock
@@ -215,6 +220,33 @@ func (m *CleanupMockInfluxClient) Query(query string) (QueryResult, error) {
 	return args.Get(0).(QueryResult), args.Error(1)
 }
 
+// Delete 记录删除请求，谓词中的 measurement 和 device_id 用于校验清理范围
+func (m *CleanupMockInfluxClient) Delete(start, stop time.Time, predicate string) error {
+	m.deletedData = append(m.deletedData, DeletedData{
+		Measurement: extractMeasurement(predicate),
+		DeviceID:    predicateValue(predicate, "device_id"),
+		StartTime:   start,
+		StopTime:    stop,
+	})
+	return nil
+}
+
+func (m *CleanupMockInfluxClient) Flush() {}
+
+// predicateValue 从删除谓词中取出 key="value" 的值
+func predicateValue(predicate, key string) string {
+	prefix := key + `="`
+	start := strings.Index(predicate, prefix)
+	if start < 0 {
+		return ""
+	}
+	value := predicate[start+len(prefix):]
+	if end := strings.IndexByte(value, '"'); end >= 0 {
+		return value[:end]
+	}
+	return value
+}
+
 func (m *CleanupMockInfluxClient) Health() error {
 	args := m.Called()
 	return args.Error(0)
@@ -238,6 +270,10 @@ func (m *CleanupMockInfluxClient) GetWrittenPointsForDevice(deviceID string) []W
 	return result
 }
 
+func (m *CleanupMockInfluxClient) GetDeletedData() []DeletedData {
+	return m.deletedData
+}
+
 func (m *CleanupMockInfluxClient) ClearWrittenPoints() {
 	m.writtenPoints = make([]WrittenPoint, 0)
 }
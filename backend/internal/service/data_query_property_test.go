@@ -30,6 +30,7 @@ type MockRecord struct {
 func (r *MockRecord) Time() time.Time      { return r.time }
 func (r *MockRecord) Field() string       { return r.field }
 func (r *MockRecord) Value() interface{}  { return r.value }
+func (r *MockRecord) ValueByKey(key string) interface{} { return nil }
 
 func (m *MockQueryResult) Next() bool {
 	if m.index < len(m.records) {
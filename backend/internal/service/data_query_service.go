@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
+	"nmp-platform/internal/collector"
 	"nmp-platform/internal/models"
+	"nmp-platform/internal/tenant"
 	"sort"
 	"strconv"
 	"strings"
@@ -25,6 +28,54 @@ func NewDataQueryService(influxClient InfluxClient, redisClient RedisClient) *Da
 	}
 }
 
+// queryDevices 执行设备（及服务端探测）时序数据查询，上下文携带组织时只返回该组织的数据
+func (s *DataQueryService) queryDevices(ctx context.Context, query string) (QueryResult, error) {
+	if organizationID, ok := tenant.FromContext(ctx); ok {
+		scoped, err := withOrganizationFilter(query, organizationID)
+		if err != nil {
+			return nil, err
+		}
+		query = scoped
+	}
+	return s.influxClient.Query(query)
+}
+
+// withOrganizationFilter 在查询的每个 range 之后添加组织过滤条件
+// 升级前写入的数据没有组织标签，归属默认组织；没有 range 的查询无法限定组织，直接拒绝
+func withOrganizationFilter(query string, organizationID uint) (string, error) {
+	predicate := fmt.Sprintf(`r.%s == "%d"`, tenant.InfluxTag, organizationID)
+	if organizationID == models.DefaultOrganizationID {
+		predicate = fmt.Sprintf(`not exists r.%s or %s`, tenant.InfluxTag, predicate)
+	}
+	filter := fmt.Sprintf("\n\t\t|> filter(fn: (r) => %s)", predicate)
+
+	const rangeCall = "|> range("
+	if !strings.Contains(query, rangeCall) {
+		return "", fmt.Errorf("query without range cannot be scoped to organization %d", organizationID)
+	}
+
+	var sb strings.Builder
+	rest := query
+	for {
+		start := strings.Index(rest, rangeCall)
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '\n')
+		if end < 0 {
+			sb.WriteString(rest)
+			sb.WriteString(filter)
+			break
+		}
+		end += start
+		sb.WriteString(rest[:end])
+		sb.WriteString(filter)
+		rest = rest[end:]
+	}
+	return sb.String(), nil
+}
+
 // QueryRequest 数据查询请求
 type QueryRequest struct {
 	DeviceID    string            `json:"device_id" binding:"required"`
@@ -135,7 +186,7 @@ func (s *DataQueryService) QueryHistoricalData(ctx context.Context, req *QueryRe
 	}
 
 	// 执行查询
-	result, err := s.influxClient.Query(query)
+	result, err := s.queryDevices(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -445,7 +496,7 @@ func (s *DataQueryService) QueryMetricSummary(ctx context.Context, deviceID stri
 		metric,
 	)
 
-	result, err := s.influxClient.Query(query)
+	result, err := s.queryDevices(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute summary query: %w", err)
 	}
@@ -567,8 +618,14 @@ type BandwidthQueryResponse struct {
 // BandwidthPoint 带宽数据点
 type BandwidthPoint struct {
 	Timestamp time.Time `json:"timestamp"`
-	RxRate    float64   `json:"rx_rate"` // 接收速率 bps
-	TxRate    float64   `json:"tx_rate"` // 发送速率 bps
+	RxRate    float64   `json:"rx_rate"`    // 接收速率 bps
+	TxRate    float64   `json:"tx_rate"`    // 发送速率 bps
+	RxPackets float64   `json:"rx_packets"` // 接收包速率 pps
+	TxPackets float64   `json:"tx_packets"` // 发送包速率 pps
+	RxErrors  float64   `json:"rx_errors"`  // 接收错误速率 个/秒
+	TxErrors  float64   `json:"tx_errors"`  // 发送错误速率 个/秒
+	RxDrops   float64   `json:"rx_drops"`   // 接收丢包速率 个/秒
+	TxDrops   float64   `json:"tx_drops"`   // 发送丢包速率 个/秒
 }
 
 // PingQueryResponse Ping 查询响应
@@ -582,20 +639,28 @@ type PingQueryResponse struct {
 
 // PingPoint Ping 数据点
 type PingPoint struct {
-	Timestamp time.Time `json:"timestamp"`
-	Latency   float64   `json:"latency"`   // 延迟 ms
-	Status    string    `json:"status"`    // up/down
-	IsLoss    bool      `json:"is_loss"`   // 是否丢包
+	Timestamp  time.Time `json:"timestamp"`
+	Latency    float64   `json:"latency"`               // 平均延迟 us
+	Status     string    `json:"status"`                // up/down
+	IsLoss     bool      `json:"is_loss"`               // 是否整组丢包
+	MinLatency float64   `json:"min_latency,omitempty"` // 最小延迟 us
+	MaxLatency float64   `json:"max_latency,omitempty"` // 最大延迟 us
+	Jitter     float64   `json:"jitter"`                // 抖动 us
+	Loss       float64   `json:"loss"`                  // 丢包率 (%)
+	MOS        float64   `json:"mos,omitempty"`         // MOS 值（仅 VoIP 目标）
 }
 
 // PingStats Ping 统计信息
 type PingStats struct {
-	TotalCount int     `json:"total_count"` // 总包数
-	LossCount  int     `json:"loss_count"`  // 丢包数
-	LossRate   float64 `json:"loss_rate"`   // 丢包率 (%)
-	AvgLatency float64 `json:"avg_latency"` // 平均延迟 ms
-	MinLatency float64 `json:"min_latency"` // 最小延迟 ms
-	MaxLatency float64 `json:"max_latency"` // 最大延迟 ms
+	TotalCount int     `json:"total_count"`   // 总发包数
+	LossCount  int     `json:"loss_count"`    // 丢包数
+	LossRate   float64 `json:"loss_rate"`     // 丢包率 (%)
+	AvgLatency float64 `json:"avg_latency"`   // 平均延迟 us
+	MinLatency float64 `json:"min_latency"`   // 最小延迟 us
+	MaxLatency float64 `json:"max_latency"`   // 最大延迟 us
+	AvgJitter  float64 `json:"avg_jitter"`    // 平均抖动 us
+	MaxJitter  float64 `json:"max_jitter"`    // 最大抖动 us
+	MOS        float64 `json:"mos,omitempty"` // 平均 MOS 值（仅 VoIP 目标）
 }
 
 // QueryBandwidthData 查询带宽数据
@@ -629,7 +694,7 @@ func (s *DataQueryService) QueryBandwidthData(ctx context.Context, deviceID stri
 	query += `|> sort(columns: ["_time"])`
 
 	// 执行查询
-	result, err := s.influxClient.Query(query)
+	result, err := s.queryDevices(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute bandwidth query: %w", err)
 	}
@@ -642,12 +707,8 @@ func (s *DataQueryService) QueryBandwidthData(ctx context.Context, deviceID stri
 		Interfaces: make(map[string][]BandwidthPoint),
 	}
 
-	// 临时存储，用于合并同一时间点的 rx_rate 和 tx_rate
-	type tempPoint struct {
-		RxRate *float64
-		TxRate *float64
-	}
-	interfaceData := make(map[string]map[time.Time]*tempPoint)
+	// 临时存储，用于合并同一时间点的各字段
+	interfaceData := make(map[string]map[time.Time]*BandwidthPoint)
 
 	for result.Next() {
 		record := result.Record()
@@ -664,14 +725,6 @@ func (s *DataQueryService) QueryBandwidthData(ctx context.Context, deviceID stri
 			continue
 		}
 
-		// 初始化接口数据映射
-		if interfaceData[ifaceName] == nil {
-			interfaceData[ifaceName] = make(map[time.Time]*tempPoint)
-		}
-		if interfaceData[ifaceName][timestamp] == nil {
-			interfaceData[ifaceName][timestamp] = &tempPoint{}
-		}
-
 		// 转换值为 float64
 		var floatValue float64
 		switch v := value.(type) {
@@ -683,12 +736,34 @@ func (s *DataQueryService) QueryBandwidthData(ctx context.Context, deviceID stri
 			continue
 		}
 
+		// 初始化接口数据映射
+		if interfaceData[ifaceName] == nil {
+			interfaceData[ifaceName] = make(map[time.Time]*BandwidthPoint)
+		}
+		point := interfaceData[ifaceName][timestamp]
+		if point == nil {
+			point = &BandwidthPoint{Timestamp: timestamp}
+			interfaceData[ifaceName][timestamp] = point
+		}
+
 		// 根据字段名设置值
 		switch field {
 		case "rx_rate":
-			interfaceData[ifaceName][timestamp].RxRate = &floatValue
+			point.RxRate = floatValue
 		case "tx_rate":
-			interfaceData[ifaceName][timestamp].TxRate = &floatValue
+			point.TxRate = floatValue
+		case "rx_packets":
+			point.RxPackets = floatValue
+		case "tx_packets":
+			point.TxPackets = floatValue
+		case "rx_errors":
+			point.RxErrors = floatValue
+		case "tx_errors":
+			point.TxErrors = floatValue
+		case "rx_drops":
+			point.RxDrops = floatValue
+		case "tx_drops":
+			point.TxDrops = floatValue
 		}
 	}
 
@@ -699,17 +774,8 @@ func (s *DataQueryService) QueryBandwidthData(ctx context.Context, deviceID stri
 	// 转换为响应格式
 	for ifaceName, timePoints := range interfaceData {
 		points := make([]BandwidthPoint, 0, len(timePoints))
-		for ts, tp := range timePoints {
-			point := BandwidthPoint{
-				Timestamp: ts,
-			}
-			if tp.RxRate != nil {
-				point.RxRate = *tp.RxRate
-			}
-			if tp.TxRate != nil {
-				point.TxRate = *tp.TxRate
-			}
-			points = append(points, point)
+		for _, point := range timePoints {
+			points = append(points, *point)
 		}
 		// 按时间排序
 		sort.Slice(points, func(i, j int) bool {
@@ -757,7 +823,7 @@ func (s *DataQueryService) QueryTotalTraffic(ctx context.Context, startTime, end
 	)
 
 	// 执行查询
-	result, err := s.influxClient.Query(query)
+	result, err := s.queryDevices(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute total traffic query: %w", err)
 	}
@@ -849,6 +915,34 @@ func (s *DataQueryService) getTrafficAggregateWindow(duration time.Duration) str
 	}
 }
 
+// rawPingPoint Ping 原始数据点（同一时间戳的各字段合并）
+type rawPingPoint struct {
+	Latency  *float64
+	Min      *float64
+	Max      *float64
+	Jitter   *float64
+	Sent     *float64
+	Received *float64
+	MOS      *float64
+	Status   string
+}
+
+// counts 返回该次探测的发包数和收包数
+// 旧版单包数据没有 sent/received 字段，根据 status 和 latency 推断
+func (rp *rawPingPoint) counts() (int, int) {
+	if rp.Sent != nil && *rp.Sent > 0 {
+		received := 0
+		if rp.Received != nil {
+			received = int(*rp.Received)
+		}
+		return int(*rp.Sent), received
+	}
+	if rp.Status == "down" || (rp.Latency != nil && *rp.Latency == 0) {
+		return 1, 0
+	}
+	return 1, 1
+}
+
 // QueryPingData 查询 Ping 数据
 // 新逻辑：延迟数据可以聚合，但丢包点必须保留精确时间，统计基于原始数据
 func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, targetAddress string, startTime, endTime time.Time) (*PingQueryResponse, error) {
@@ -884,16 +978,12 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 	// 第一步：查询所有原始数据（用于统计和丢包点）
 	rawQuery := baseFilter + `|> sort(columns: ["_time"])`
 	
-	rawResult, err := s.influxClient.Query(rawQuery)
+	rawResult, err := s.queryDevices(ctx, rawQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute raw ping query: %w", err)
 	}
 
 	// 解析原始数据
-	type rawPingPoint struct {
-		Latency *float64
-		Status  string
-	}
 	rawData := make(map[string]map[time.Time]*rawPingPoint)
 
 	for rawResult.Next() {
@@ -928,20 +1018,39 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 			rawData[target][timestamp] = &rawPingPoint{}
 		}
 
-		switch field {
-		case "latency":
-			var floatValue float64
-			switch v := value.(type) {
-			case float64:
-				floatValue = v
-			case int64:
-				floatValue = float64(v)
-			}
-			rawData[target][timestamp].Latency = &floatValue
-		case "status":
+		if field == "status" {
 			if strValue, ok := value.(string); ok {
 				rawData[target][timestamp].Status = strValue
 			}
+			continue
+		}
+
+		var floatValue float64
+		switch v := value.(type) {
+		case float64:
+			floatValue = v
+		case int64:
+			floatValue = float64(v)
+		default:
+			continue
+		}
+
+		rp := rawData[target][timestamp]
+		switch field {
+		case "latency":
+			rp.Latency = &floatValue
+		case "min_latency":
+			rp.Min = &floatValue
+		case "max_latency":
+			rp.Max = &floatValue
+		case "jitter":
+			rp.Jitter = &floatValue
+		case "sent":
+			rp.Sent = &floatValue
+		case "received":
+			rp.Received = &floatValue
+		case "mos":
+			rp.MOS = &floatValue
 		}
 	}
 
@@ -956,41 +1065,71 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 		stats := &PingStats{
 			MinLatency: -1, // 用 -1 表示未初始化
 		}
+		var latencySum, jitterSum, mosSum float64
+		var receivedTotal, jitterCount, mosCount int
 		
 		for ts, rp := range timePoints {
-			stats.TotalCount++
+			sent, received := rp.counts()
+			stats.TotalCount += sent
+			stats.LossCount += sent - received
 			
-			isLoss := rp.Status == "down" || (rp.Latency != nil && *rp.Latency == 0)
-			
-			if isLoss {
-				stats.LossCount++
+			if received == 0 {
 				// 保存丢包点
 				lossPoints[target] = append(lossPoints[target], PingPoint{
 					Timestamp: ts,
 					Latency:   0,
 					Status:    "down",
 					IsLoss:    true,
+					Loss:      100,
 				})
-			} else if rp.Latency != nil {
+				continue
+			}
+			
+			if rp.Latency != nil {
 				latency := *rp.Latency
-				stats.AvgLatency += latency
-				if stats.MinLatency < 0 || latency < stats.MinLatency {
-					stats.MinLatency = latency
+				latencySum += latency * float64(received)
+				receivedTotal += received
+				
+				minLatency, maxLatency := latency, latency
+				if rp.Min != nil && *rp.Min > 0 {
+					minLatency = *rp.Min
+				}
+				if rp.Max != nil && *rp.Max > 0 {
+					maxLatency = *rp.Max
+				}
+				if stats.MinLatency < 0 || minLatency < stats.MinLatency {
+					stats.MinLatency = minLatency
 				}
-				if latency > stats.MaxLatency {
-					stats.MaxLatency = latency
+				if maxLatency > stats.MaxLatency {
+					stats.MaxLatency = maxLatency
 				}
 			}
+			if rp.Jitter != nil {
+				jitterSum += *rp.Jitter
+				jitterCount++
+				if *rp.Jitter > stats.MaxJitter {
+					stats.MaxJitter = *rp.Jitter
+				}
+			}
+			if rp.MOS != nil {
+				mosSum += *rp.MOS
+				mosCount++
+			}
 		}
 		
-		// 计算平均延迟和丢包率
-		successCount := stats.TotalCount - stats.LossCount
-		if successCount > 0 {
-			stats.AvgLatency = stats.AvgLatency / float64(successCount)
+		// 计算平均值和丢包率
+		if receivedTotal > 0 {
+			stats.AvgLatency = latencySum / float64(receivedTotal)
 		}
 		if stats.MinLatency < 0 {
 			stats.MinLatency = 0
 		}
+		if jitterCount > 0 {
+			stats.AvgJitter = jitterSum / float64(jitterCount)
+		}
+		if mosCount > 0 {
+			stats.MOS = math.Round(mosSum/float64(mosCount)*100) / 100
+		}
 		if stats.TotalCount > 0 {
 			stats.LossRate = float64(stats.LossCount) / float64(stats.TotalCount) * 100
 		}
@@ -1000,22 +1139,21 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 
 	// 第二步：如果需要聚合，查询聚合后的延迟数据
 	if aggregateWindow != "" {
-		// 只聚合延迟数据（成功的 ping）
+		// 延迟、抖动和 MOS 只聚合成功的探测，丢包率聚合所有探测
 		aggQuery := fmt.Sprintf(`%s
-			|> filter(fn: (r) => r._field == "latency")
-			|> filter(fn: (r) => r._value > 0)
+			|> filter(fn: (r) => ((r._field == "latency" or r._field == "jitter" or r._field == "mos") and r._value > 0) or r._field == "loss")
 			|> aggregateWindow(every: %s, fn: mean, createEmpty: false)
 			|> sort(columns: ["_time"])`,
 			baseFilter, aggregateWindow,
 		)
 		
-		aggResult, err := s.influxClient.Query(aggQuery)
+		aggResult, err := s.queryDevices(ctx, aggQuery)
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute aggregated ping query: %w", err)
 		}
 
 		// 解析聚合数据
-		aggData := make(map[string]map[time.Time]float64)
+		aggData := make(map[string]map[time.Time]map[string]float64)
 
 		for aggResult.Next() {
 			record := aggResult.Record()
@@ -1041,7 +1179,10 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 			}
 
 			if aggData[target] == nil {
-				aggData[target] = make(map[time.Time]float64)
+				aggData[target] = make(map[time.Time]map[string]float64)
+			}
+			if aggData[target][timestamp] == nil {
+				aggData[target][timestamp] = make(map[string]float64)
 			}
 
 			var floatValue float64
@@ -1051,7 +1192,7 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 			case int64:
 				floatValue = float64(v)
 			}
-			aggData[target][timestamp] = floatValue
+			aggData[target][timestamp][record.Field()] = floatValue
 		}
 
 		if aggResult.Err() != nil {
@@ -1059,16 +1200,23 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 		}
 
 		// 合并聚合数据和丢包点
-		for target, latencies := range aggData {
+		for target, windows := range aggData {
 			points := make([]PingPoint, 0)
 			
-			// 添加聚合后的延迟点
-			for ts, latency := range latencies {
+			// 添加聚合后的延迟点（窗口内全部丢包时没有延迟，由丢包点表示）
+			for ts, values := range windows {
+				latency, ok := values["latency"]
+				if !ok {
+					continue
+				}
 				points = append(points, PingPoint{
 					Timestamp: ts,
 					Latency:   latency,
 					Status:    "up",
 					IsLoss:    false,
+					Jitter:    values["jitter"],
+					Loss:      values["loss"],
+					MOS:       values["mos"],
 				})
 			}
 			
@@ -1106,8 +1254,22 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 				if rp.Latency != nil {
 					point.Latency = *rp.Latency
 				}
-				// 标记丢包：latency 为 0 或 status 为 "down" 表示丢包
-				point.IsLoss = (rp.Latency != nil && *rp.Latency == 0) || rp.Status == "down"
+				if rp.Min != nil {
+					point.MinLatency = *rp.Min
+				}
+				if rp.Max != nil {
+					point.MaxLatency = *rp.Max
+				}
+				if rp.Jitter != nil {
+					point.Jitter = *rp.Jitter
+				}
+				if rp.MOS != nil {
+					point.MOS = *rp.MOS
+				}
+				sent, received := rp.counts()
+				point.Loss = float64(sent-received) / float64(sent) * 100
+				// 标记丢包：整组探测都没有响应
+				point.IsLoss = received == 0
 				points = append(points, point)
 			}
 			// 按时间排序
@@ -1119,4 +1281,748 @@ func (s *DataQueryService) QueryPingData(ctx context.Context, deviceID string, t
 	}
 
 	return response, nil
-}
\ No newline at end of file
+}
+
+// ProbeQueryResponse 服务端探测查询响应
+type ProbeQueryResponse struct {
+	ProbeID   string       `json:"probe_id"`
+	StartTime time.Time    `json:"start_time"`
+	EndTime   time.Time    `json:"end_time"`
+	Points    []ProbePoint `json:"points"`
+	Stats     *ProbeStats  `json:"stats"`
+}
+
+// ProbePoint 服务端探测数据点
+type ProbePoint struct {
+	Timestamp    time.Time `json:"timestamp"`
+	Latency      float64   `json:"latency"`                 // 总耗时 ms
+	ConnectTime  float64   `json:"connect_time,omitempty"`  // 建连耗时 ms
+	Status       string    `json:"status"`                  // up/down
+	IsFailure    bool      `json:"is_failure"`              // 是否探测失败
+	Availability float64   `json:"availability"`            // 可用率 (%)，聚合窗口内成功次数占比
+	StatusCode   int       `json:"status_code,omitempty"`   // HTTP 状态码
+	TLSDaysLeft  float64   `json:"tls_days_left,omitempty"` // 证书剩余天数
+	Error        string    `json:"error,omitempty"`         // 失败原因（仅原始失败点）
+}
+
+// ProbeStats 服务端探测统计信息
+type ProbeStats struct {
+	ProbeType     string     `json:"probe_type"`
+	Target        string     `json:"target"`
+	TotalCount    int        `json:"total_count"`             // 探测次数
+	FailureCount  int        `json:"failure_count"`           // 失败次数
+	Availability  float64    `json:"availability"`            // 可用率 (%)
+	AvgLatency    float64    `json:"avg_latency"`             // 平均耗时 ms（仅成功探测）
+	MinLatency    float64    `json:"min_latency"`             // 最小耗时 ms
+	MaxLatency    float64    `json:"max_latency"`             // 最大耗时 ms
+	TLSDaysLeft   *float64   `json:"tls_days_left,omitempty"` // 最近一次证书剩余天数
+	LastStatus    string     `json:"last_status"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+}
+
+// ProbeSummaryResponse 服务端探测汇总响应
+type ProbeSummaryResponse struct {
+	StartTime time.Time              `json:"start_time"`
+	EndTime   time.Time              `json:"end_time"`
+	Probes    map[string]*ProbeStats `json:"probes"` // key 为 probe_id
+}
+
+// rawProbePoint 服务端探测原始数据点（同一时间戳的各字段合并）
+type rawProbePoint struct {
+	Success     bool
+	Latency     float64
+	ConnectTime float64
+	StatusCode  float64
+	TLSDaysLeft *float64
+	Error       string
+}
+
+// probeSeries 单个探测的原始数据
+type probeSeries struct {
+	ProbeType string
+	Target    string
+	Points    map[time.Time]*rawProbePoint
+}
+
+// queryRawProbeData 查询服务端探测原始数据，按 probe_id 分组
+func (s *DataQueryService) queryRawProbeData(ctx context.Context, probeID string, startTime, endTime time.Time) (map[string]*probeSeries, error) {
+	query := fmt.Sprintf(`
+		from(bucket: "monitoring")
+		|> range(start: %s, stop: %s)
+		|> filter(fn: (r) => r._measurement == "probe")`,
+		startTime.Format(time.RFC3339),
+		endTime.Format(time.RFC3339),
+	)
+	if probeID != "" {
+		query += fmt.Sprintf(`|> filter(fn: (r) => r.probe_id == "%s")`, probeID)
+	}
+	query += `|> sort(columns: ["_time"])`
+
+	result, err := s.queryDevices(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute probe query: %w", err)
+	}
+
+	data := make(map[string]*probeSeries)
+	for result.Next() {
+		record := result.Record()
+		id := ""
+		if v := record.ValueByKey("probe_id"); v != nil {
+			id = fmt.Sprintf("%v", v)
+		}
+		if id == "" {
+			continue
+		}
+
+		series := data[id]
+		if series == nil {
+			series = &probeSeries{Points: make(map[time.Time]*rawProbePoint)}
+			if v := record.ValueByKey("probe_type"); v != nil {
+				series.ProbeType = fmt.Sprintf("%v", v)
+			}
+			if v := record.ValueByKey("target"); v != nil {
+				series.Target = fmt.Sprintf("%v", v)
+			}
+			data[id] = series
+		}
+
+		rp := series.Points[record.Time()]
+		if rp == nil {
+			rp = &rawProbePoint{}
+			series.Points[record.Time()] = rp
+		}
+
+		field := record.Field()
+		if field == "error" {
+			if strValue, ok := record.Value().(string); ok {
+				rp.Error = strValue
+			}
+			continue
+		}
+
+		var floatValue float64
+		switch v := record.Value().(type) {
+		case float64:
+			floatValue = v
+		case int64:
+			floatValue = float64(v)
+		default:
+			continue
+		}
+
+		switch field {
+		case "success":
+			rp.Success = floatValue > 0
+		case "latency":
+			rp.Latency = floatValue
+		case "connect_time":
+			rp.ConnectTime = floatValue
+		case "status_code":
+			rp.StatusCode = floatValue
+		case "tls_days_left":
+			rp.TLSDaysLeft = &floatValue
+		}
+	}
+
+	if result.Err() != nil {
+		return nil, fmt.Errorf("probe query execution error: %w", result.Err())
+	}
+
+	return data, nil
+}
+
+// calculateProbeStats 根据原始数据计算服务端探测统计
+func calculateProbeStats(series *probeSeries) *ProbeStats {
+	stats := &ProbeStats{
+		ProbeType:  series.ProbeType,
+		Target:     series.Target,
+		LastStatus: "unknown",
+		MinLatency: -1, // 用 -1 表示未初始化
+	}
+
+	var latencySum float64
+	var successCount int
+	var last time.Time
+	for ts, rp := range series.Points {
+		stats.TotalCount++
+		if ts.After(last) {
+			last = ts
+			stats.LastStatus = "down"
+			if rp.Success {
+				stats.LastStatus = "up"
+			}
+			stats.TLSDaysLeft = rp.TLSDaysLeft
+		}
+
+		if !rp.Success {
+			stats.FailureCount++
+			continue
+		}
+		successCount++
+		latencySum += rp.Latency
+		if stats.MinLatency < 0 || rp.Latency < stats.MinLatency {
+			stats.MinLatency = rp.Latency
+		}
+		if rp.Latency > stats.MaxLatency {
+			stats.MaxLatency = rp.Latency
+		}
+	}
+
+	if successCount > 0 {
+		stats.AvgLatency = latencySum / float64(successCount)
+	}
+	if stats.MinLatency < 0 {
+		stats.MinLatency = 0
+	}
+	if stats.TotalCount > 0 {
+		stats.Availability = float64(successCount) / float64(stats.TotalCount) * 100
+	}
+	if !last.IsZero() {
+		stats.LastCheckedAt = &last
+	}
+
+	return stats
+}
+
+// QueryProbeData 查询服务端探测数据
+// 与 Ping 查询一致：耗时和可用率可以聚合，但失败点保留精确时间和失败原因，统计基于原始数据
+func (s *DataQueryService) QueryProbeData(ctx context.Context, probeID string, startTime, endTime time.Time) (*ProbeQueryResponse, error) {
+	response := &ProbeQueryResponse{
+		ProbeID:   probeID,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Points:    make([]ProbePoint, 0),
+		Stats:     &ProbeStats{LastStatus: "unknown"},
+	}
+
+	// 第一步：查询原始数据（用于统计和失败点）
+	rawData, err := s.queryRawProbeData(ctx, probeID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	series, ok := rawData[probeID]
+	if !ok {
+		return response, nil
+	}
+	response.Stats = calculateProbeStats(series)
+
+	aggregateWindow := s.getAutoAggregateWindow(endTime.Sub(startTime))
+	if aggregateWindow == "" {
+		// 不需要聚合，直接使用原始数据
+		for ts, rp := range series.Points {
+			response.Points = append(response.Points, newProbePoint(ts, rp))
+		}
+	} else {
+		// 第二步：查询聚合后的耗时和可用率
+		aggQuery := fmt.Sprintf(`
+			from(bucket: "monitoring")
+			|> range(start: %s, stop: %s)
+			|> filter(fn: (r) => r._measurement == "probe")
+			|> filter(fn: (r) => r.probe_id == "%s")
+			|> filter(fn: (r) => r._field == "success" or r._field == "latency" or r._field == "connect_time" or r._field == "tls_days_left")
+			|> aggregateWindow(every: %s, fn: mean, createEmpty: false)
+			|> sort(columns: ["_time"])`,
+			startTime.Format(time.RFC3339),
+			endTime.Format(time.RFC3339),
+			probeID,
+			aggregateWindow,
+		)
+
+		aggResult, err := s.queryDevices(ctx, aggQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute aggregated probe query: %w", err)
+		}
+
+		windows := make(map[time.Time]map[string]float64)
+		for aggResult.Next() {
+			record := aggResult.Record()
+			var floatValue float64
+			switch v := record.Value().(type) {
+			case float64:
+				floatValue = v
+			case int64:
+				floatValue = float64(v)
+			default:
+				continue
+			}
+			if windows[record.Time()] == nil {
+				windows[record.Time()] = make(map[string]float64)
+			}
+			windows[record.Time()][record.Field()] = floatValue
+		}
+
+		if aggResult.Err() != nil {
+			return nil, fmt.Errorf("aggregated probe query execution error: %w", aggResult.Err())
+		}
+
+		// 聚合窗口内全部失败时由失败点表示
+		for ts, values := range windows {
+			availability := values["success"] * 100
+			if availability == 0 {
+				continue
+			}
+			response.Points = append(response.Points, ProbePoint{
+				Timestamp:    ts,
+				Latency:      values["latency"],
+				ConnectTime:  values["connect_time"],
+				Status:       "up",
+				Availability: availability,
+				TLSDaysLeft:  values["tls_days_left"],
+			})
+		}
+
+		// 添加所有失败点（保持原始时间戳）
+		for ts, rp := range series.Points {
+			if !rp.Success {
+				response.Points = append(response.Points, newProbePoint(ts, rp))
+			}
+		}
+	}
+
+	// 按时间排序
+	sort.Slice(response.Points, func(i, j int) bool {
+		return response.Points[i].Timestamp.Before(response.Points[j].Timestamp)
+	})
+
+	return response, nil
+}
+
+// newProbePoint 由原始数据构造服务端探测数据点
+func newProbePoint(ts time.Time, rp *rawProbePoint) ProbePoint {
+	point := ProbePoint{
+		Timestamp:   ts,
+		Latency:     rp.Latency,
+		ConnectTime: rp.ConnectTime,
+		Status:      "down",
+		IsFailure:   !rp.Success,
+		StatusCode:  int(rp.StatusCode),
+		Error:       rp.Error,
+	}
+	if rp.Success {
+		point.Status = "up"
+		point.Availability = 100
+	}
+	if rp.TLSDaysLeft != nil {
+		point.TLSDaysLeft = *rp.TLSDaysLeft
+	}
+	return point
+}
+
+// QueryProbeSummary 查询所有服务端探测的汇总统计
+func (s *DataQueryService) QueryProbeSummary(ctx context.Context, startTime, endTime time.Time) (*ProbeSummaryResponse, error) {
+	rawData, err := s.queryRawProbeData(ctx, "", startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ProbeSummaryResponse{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Probes:    make(map[string]*ProbeStats, len(rawData)),
+	}
+	for id, series := range rawData {
+		response.Probes[id] = calculateProbeStats(series)
+	}
+
+	return response, nil
+}
+
+// TopTalkersRequest 流量 Top N 查询请求
+type TopTalkersRequest struct {
+	DeviceID  string
+	IfIndex   int    // 0 表示设备整体
+	Interface string // 接口名称，优先于 IfIndex
+	Direction string // all/in/out
+	Dimension string // src/dst/port/protocol
+	StartTime time.Time
+	EndTime   time.Time
+	Limit     int
+}
+
+// TopTalkersResponse 流量 Top N 查询响应
+type TopTalkersResponse struct {
+	DeviceID     string      `json:"device_id"`
+	Interface    string      `json:"interface,omitempty"`
+	IfIndex      int         `json:"if_index,omitempty"`
+	Direction    string      `json:"direction"`
+	Dimension    string      `json:"dimension"`
+	StartTime    time.Time   `json:"start_time"`
+	EndTime      time.Time   `json:"end_time"`
+	TotalBytes   float64     `json:"total_bytes"`
+	TotalPackets float64     `json:"total_packets"`
+	TotalFlows   float64     `json:"total_flows"`
+	Items        []TopTalker `json:"items"`
+	Other        *TopTalker  `json:"other,omitempty"` // 未进入每分钟 Top N 的流量
+}
+
+// TopTalker 流量 Top N 条目
+type TopTalker struct {
+	Key     string  `json:"key"` // IP 地址、协议/端口或协议名称
+	Bytes   float64 `json:"bytes"`
+	Packets float64 `json:"packets"`
+	Flows   float64 `json:"flows"`
+	Share   float64 `json:"share"` // 占总字节数百分比
+}
+
+// QueryTopTalkers 查询设备或接口在时间范围内的流量 Top N
+// 每分钟只保存 Top N，时间范围内的排名由各分钟 Top N 汇总得出，总量取自 flow_total
+func (s *DataQueryService) QueryTopTalkers(ctx context.Context, req *TopTalkersRequest) (*TopTalkersResponse, error) {
+	response := &TopTalkersResponse{
+		DeviceID:  req.DeviceID,
+		Interface: req.Interface,
+		IfIndex:   req.IfIndex,
+		Direction: req.Direction,
+		Dimension: req.Dimension,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Items:     make([]TopTalker, 0),
+	}
+
+	filter := fmt.Sprintf(`r.device_id == "%s" and r.direction == "%s"`, req.DeviceID, req.Direction)
+	if req.Interface != "" {
+		filter += fmt.Sprintf(` and r.interface == %s`, strconv.Quote(req.Interface))
+	} else {
+		filter += fmt.Sprintf(` and r.if_index == "%d"`, req.IfIndex)
+	}
+
+	totalQuery := fmt.Sprintf(`
+		from(bucket: "monitoring")
+		|> range(start: %s, stop: %s)
+		|> filter(fn: (r) => r._measurement == "flow_total")
+		|> filter(fn: (r) => %s)
+		|> group(columns: ["_field"])
+		|> sum()`,
+		req.StartTime.Format(time.RFC3339),
+		req.EndTime.Format(time.RFC3339),
+		filter,
+	)
+
+	totalResult, err := s.queryDevices(ctx, totalQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute flow total query: %w", err)
+	}
+	for totalResult.Next() {
+		record := totalResult.Record()
+		value, ok := flowValue(record.Value())
+		if !ok {
+			continue
+		}
+		switch record.Field() {
+		case "bytes":
+			response.TotalBytes = value
+		case "packets":
+			response.TotalPackets = value
+		case "flows":
+			response.TotalFlows = value
+		}
+	}
+	if totalResult.Err() != nil {
+		return nil, fmt.Errorf("flow total query execution error: %w", totalResult.Err())
+	}
+
+	topQuery := fmt.Sprintf(`
+		from(bucket: "monitoring")
+		|> range(start: %s, stop: %s)
+		|> filter(fn: (r) => r._measurement == "flow_top")
+		|> filter(fn: (r) => %s and r.dimension == "%s")
+		|> group(columns: ["key", "_field"])
+		|> sum()`,
+		req.StartTime.Format(time.RFC3339),
+		req.EndTime.Format(time.RFC3339),
+		filter,
+		req.Dimension,
+	)
+
+	topResult, err := s.queryDevices(ctx, topQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute top talkers query: %w", err)
+	}
+
+	entries := make(map[string]*TopTalker)
+	for topResult.Next() {
+		record := topResult.Record()
+		key := ""
+		if v := record.ValueByKey("key"); v != nil {
+			key = fmt.Sprintf("%v", v)
+		}
+		value, ok := flowValue(record.Value())
+		if key == "" || !ok {
+			continue
+		}
+
+		entry := entries[key]
+		if entry == nil {
+			entry = &TopTalker{Key: key}
+			entries[key] = entry
+		}
+		switch record.Field() {
+		case "bytes":
+			entry.Bytes = value
+		case "packets":
+			entry.Packets = value
+		case "flows":
+			entry.Flows = value
+		}
+	}
+	if topResult.Err() != nil {
+		return nil, fmt.Errorf("top talkers query execution error: %w", topResult.Err())
+	}
+
+	for key, entry := range entries {
+		if response.TotalBytes > 0 {
+			entry.Share = entry.Bytes / response.TotalBytes * 100
+		}
+		if key == flowOtherKey {
+			response.Other = entry
+			continue
+		}
+		response.Items = append(response.Items, *entry)
+	}
+
+	sort.Slice(response.Items, func(i, j int) bool {
+		if response.Items[i].Bytes != response.Items[j].Bytes {
+			return response.Items[i].Bytes > response.Items[j].Bytes
+		}
+		return response.Items[i].Key < response.Items[j].Key
+	})
+
+	// 超出 limit 的条目并入 other
+	if req.Limit > 0 && len(response.Items) > req.Limit {
+		if response.Other == nil {
+			response.Other = &TopTalker{Key: flowOtherKey}
+		}
+		for _, entry := range response.Items[req.Limit:] {
+			response.Other.Bytes += entry.Bytes
+			response.Other.Packets += entry.Packets
+			response.Other.Flows += entry.Flows
+			response.Other.Share += entry.Share
+		}
+		response.Items = response.Items[:req.Limit]
+	}
+
+	return response, nil
+}
+
+// flowValue 将查询值转换为 float64
+func flowValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// ServiceMetricMeasurements 服务指标 measurement 及区分序列的 tag（为空表示每台设备一条序列）
+var ServiceMetricMeasurements = map[string]string{
+	"ppp":      "",
+	"dhcp":     "server",
+	"wireless": "interface",
+	"queue":    "queue",
+}
+
+// ServiceMetricsQueryResponse 服务指标查询响应
+type ServiceMetricsQueryResponse struct {
+	DeviceID    string                           `json:"device_id"`
+	Measurement string                           `json:"measurement"`
+	StartTime   time.Time                        `json:"start_time"`
+	EndTime     time.Time                        `json:"end_time"`
+	Series      map[string][]ServiceMetricPoint `json:"series"` // 按 DHCP 服务器/无线接口/队列名分组，PPP 只有一条序列 "ppp"
+}
+
+// ServiceMetricPoint 服务指标数据点
+type ServiceMetricPoint struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Values    map[string]float64 `json:"values"` // 字段名 -> 值，如 bound/total、clients/avg_signal
+}
+
+// QueryServiceMetrics 查询服务指标（PPP 会话、DHCP 租约、无线客户端、队列速率）
+// name 非空时只查询对应的 DHCP 服务器、无线接口或队列
+func (s *DataQueryService) QueryServiceMetrics(ctx context.Context, deviceID, measurement, name string, startTime, endTime time.Time) (*ServiceMetricsQueryResponse, error) {
+	seriesTag, ok := ServiceMetricMeasurements[measurement]
+	if !ok {
+		return nil, fmt.Errorf("unknown service measurement: %s", measurement)
+	}
+
+	// 根据时间范围自动选择聚合粒度
+	aggregateWindow := s.getAutoAggregateWindow(endTime.Sub(startTime))
+
+	query := fmt.Sprintf(`
+		from(bucket: "monitoring")
+		|> range(start: %s, stop: %s)
+		|> filter(fn: (r) => r._measurement == "%s")
+		|> filter(fn: (r) => r.device_id == %s)`,
+		startTime.Format(time.RFC3339),
+		endTime.Format(time.RFC3339),
+		measurement,
+		strconv.Quote(deviceID),
+	)
+	if name != "" && seriesTag != "" {
+		query += fmt.Sprintf(`|> filter(fn: (r) => r.%s == %s)`, seriesTag, strconv.Quote(name))
+	}
+	if aggregateWindow != "" {
+		query += fmt.Sprintf(`|> aggregateWindow(every: %s, fn: mean, createEmpty: false)`, aggregateWindow)
+	}
+	query += `|> sort(columns: ["_time"])`
+
+	result, err := s.queryDevices(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s query: %w", measurement, err)
+	}
+
+	// 临时存储，用于合并同一时间点的各字段
+	seriesData := make(map[string]map[time.Time]*ServiceMetricPoint)
+	for result.Next() {
+		record := result.Record()
+		value, ok := flowValue(record.Value())
+		if !ok {
+			continue
+		}
+
+		key := measurement
+		if seriesTag != "" {
+			v := record.ValueByKey(seriesTag)
+			if v == nil {
+				continue
+			}
+			key = fmt.Sprintf("%v", v)
+		}
+
+		if seriesData[key] == nil {
+			seriesData[key] = make(map[time.Time]*ServiceMetricPoint)
+		}
+		point := seriesData[key][record.Time()]
+		if point == nil {
+			point = &ServiceMetricPoint{Timestamp: record.Time(), Values: make(map[string]float64)}
+			seriesData[key][record.Time()] = point
+		}
+		point.Values[record.Field()] = value
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("%s query execution error: %w", measurement, result.Err())
+	}
+
+	response := &ServiceMetricsQueryResponse{
+		DeviceID:    deviceID,
+		Measurement: measurement,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Series:      make(map[string][]ServiceMetricPoint, len(seriesData)),
+	}
+	for key, timePoints := range seriesData {
+		points := make([]ServiceMetricPoint, 0, len(timePoints))
+		for _, point := range timePoints {
+			points = append(points, *point)
+		}
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Timestamp.Before(points[j].Timestamp)
+		})
+		response.Series[key] = points
+	}
+
+	return response, nil
+}
+
+// HealthQueryResponse 硬件健康传感器查询响应
+type HealthQueryResponse struct {
+	DeviceID  string               `json:"device_id"`
+	StartTime time.Time            `json:"start_time"`
+	EndTime   time.Time            `json:"end_time"`
+	Sensors   []HealthSensorSeries `json:"sensors"`
+}
+
+// HealthSensorSeries 单个传感器的时间序列
+type HealthSensorSeries struct {
+	Sensor string                   `json:"sensor"` // 归一化名称，如 cpu、board、psu1、fan1
+	Type   string                   `json:"type"`   // temperature、voltage、fan、power、current、state
+	Unit   string                   `json:"unit"`   // C、V、RPM、W、A，state 为空（1 正常，0 故障）
+	Latest float64                  `json:"latest"` // 时间范围内最后一个值
+	Points []models.TimeSeriesPoint `json:"points"`
+}
+
+// QueryHealthMetrics 查询硬件健康传感器历史数据
+// sensor、sensorType 非空时只查询对应的传感器或类型
+func (s *DataQueryService) QueryHealthMetrics(ctx context.Context, deviceID, sensor, sensorType string, startTime, endTime time.Time) (*HealthQueryResponse, error) {
+	// 根据时间范围自动选择聚合粒度
+	aggregateWindow := s.getAutoAggregateWindow(endTime.Sub(startTime))
+
+	query := fmt.Sprintf(`
+		from(bucket: "monitoring")
+		|> range(start: %s, stop: %s)
+		|> filter(fn: (r) => r._measurement == "health")
+		|> filter(fn: (r) => r.device_id == %s)
+		|> filter(fn: (r) => r._field == "value")`,
+		startTime.Format(time.RFC3339),
+		endTime.Format(time.RFC3339),
+		strconv.Quote(deviceID),
+	)
+	if sensor != "" {
+		query += fmt.Sprintf(`|> filter(fn: (r) => r.sensor == %s)`, strconv.Quote(sensor))
+	}
+	if sensorType != "" {
+		query += fmt.Sprintf(`|> filter(fn: (r) => r.type == %s)`, strconv.Quote(sensorType))
+	}
+	if aggregateWindow != "" {
+		query += fmt.Sprintf(`|> aggregateWindow(every: %s, fn: mean, createEmpty: false)`, aggregateWindow)
+	}
+	query += `|> sort(columns: ["_time"])`
+
+	result, err := s.queryDevices(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute health query: %w", err)
+	}
+
+	seriesMap := make(map[string]*HealthSensorSeries)
+	for result.Next() {
+		record := result.Record()
+		value, ok := flowValue(record.Value())
+		if !ok {
+			continue
+		}
+		name, _ := record.ValueByKey("sensor").(string)
+		typ, _ := record.ValueByKey("type").(string)
+		if name == "" {
+			continue
+		}
+
+		key := typ + "|" + name
+		series := seriesMap[key]
+		if series == nil {
+			series = &HealthSensorSeries{
+				Sensor: name,
+				Type:   typ,
+				Unit:   collector.HealthSensorUnit(typ),
+				Points: []models.TimeSeriesPoint{},
+			}
+			seriesMap[key] = series
+		}
+		series.Points = append(series.Points, models.TimeSeriesPoint{Timestamp: record.Time(), Value: value})
+	}
+	if result.Err() != nil {
+		return nil, fmt.Errorf("health query execution error: %w", result.Err())
+	}
+
+	response := &HealthQueryResponse{
+		DeviceID:  deviceID,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Sensors:   make([]HealthSensorSeries, 0, len(seriesMap)),
+	}
+	for _, series := range seriesMap {
+		sort.Slice(series.Points, func(i, j int) bool {
+			return series.Points[i].Timestamp.Before(series.Points[j].Timestamp)
+		})
+		series.Latest = series.Points[len(series.Points)-1].Value
+		response.Sensors = append(response.Sensors, *series)
+	}
+	sort.Slice(response.Sensors, func(i, j int) bool {
+		if response.Sensors[i].Type != response.Sensors[j].Type {
+			return response.Sensors[i].Type < response.Sensors[j].Type
+		}
+		return response.Sensors[i].Sensor < response.Sensors[j].Sensor
+	})
+
+	return response, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithOrganizationFilter(t *testing.T) {
+	query := `from(bucket: "nmp")
+		|> range(start: -1h)
+		|> filter(fn: (r) => r._measurement == "bandwidth")`
+
+	scoped, err := withOrganizationFilter(query, 2)
+	require.NoError(t, err)
+	assert.Contains(t, scoped, "|> range(start: -1h)\n\t\t|> filter(fn: (r) => r.organization_id == \"2\")")
+
+	// 默认组织同时包含没有组织标签的历史数据
+	scoped, err = withOrganizationFilter(query, models.DefaultOrganizationID)
+	require.NoError(t, err)
+	assert.Contains(t, scoped, `not exists r.organization_id or r.organization_id == "1"`)
+
+	// 多个数据流的每个 range 都要加过滤
+	union := `a = from(bucket: "nmp")
+		|> range(start: -1h)
+b = from(bucket: "nmp")
+		|> range(start: -2h)
+union(tables: [a, b])`
+	scoped, err = withOrganizationFilter(union, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(scoped, `r.organization_id == "2"`))
+
+	// 无法限定组织的查询被拒绝
+	_, err = withOrganizationFilter(`from(bucket: "nmp")`, 2)
+	assert.Error(t, err)
+}
+
+func TestDataQueryService_QueryDevicesRejectsUnscopedQuery(t *testing.T) {
+	mockInflux := &MockInfluxClient{}
+	service := NewDataQueryService(mockInflux, &MockRedisClient{})
+
+	ctx := tenant.WithOrganization(context.Background(), 2)
+	_, err := service.queryDevices(ctx, `from(bucket: "nmp") |> filter(fn: (r) => true)`)
+	assert.Error(t, err)
+	mockInflux.AssertNotCalled(t, "Query", mock.Anything)
+}
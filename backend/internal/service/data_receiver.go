@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
+	"nmp-platform/internal/collector"
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -57,6 +60,24 @@ type DataReceiverService struct {
 	cacheExpiry     time.Duration
 	batchSize       int
 	offlineTimeout  time.Duration // 离线超时时间
+
+	// 接口错误/丢包累计计数的上一次采样，用于换算速率
+	counterMu        sync.Mutex
+	lastCounters     map[string]interfaceCounterSample
+	lastCounterPrune time.Time
+
+	linkStateService *LinkStateService // 可选，记录接口链路状态变化
+}
+
+// counterSampleTTL 接口累计计数基线的保留时长，超过该时长未更新的基线
+// （设备或接口已删除、停止上报）被清理
+const counterSampleTTL = 30 * time.Minute
+
+// interfaceCounterSample 接口累计计数采样
+type interfaceCounterSample struct {
+	ts       time.Time // 设备采样时间
+	seen     time.Time // 服务端收到采样的时间，用于清理过期基线
+	counters map[string]int64
 }
 
 // NewDataReceiverService 创建数据接收服务实例
@@ -72,6 +93,7 @@ func NewDataReceiverService(
 		cacheExpiry:    5 * time.Minute,  // 实时数据缓存5分钟
 		batchSize:      100,               // 批处理大小
 		offlineTimeout: 10 * time.Minute,  // 10分钟无数据视为离线
+		lastCounters:   make(map[string]interfaceCounterSample),
 	}
 }
 
@@ -90,9 +112,15 @@ func NewDataReceiverServiceWithCollector(
 		cacheExpiry:    5 * time.Minute,
 		batchSize:      100,
 		offlineTimeout: 10 * time.Minute,
+		lastCounters:   make(map[string]interfaceCounterSample),
 	}
 }
 
+// SetLinkStateService 设置接口链路状态跟踪服务
+func (s *DataReceiverService) SetLinkStateService(linkStateService *LinkStateService) {
+	s.linkStateService = linkStateService
+}
+
 // SetOfflineTimeout 设置离线超时时间
 func (s *DataReceiverService) SetOfflineTimeout(timeout time.Duration) {
 	s.offlineTimeout = timeout
@@ -123,6 +151,100 @@ func (s *DataReceiverService) ValidateDeviceByIP(ctx context.Context, ip string)
 	return device, nil
 }
 
+// 时间戳与时钟偏差相关常量
+const (
+	// defaultCollectIntervalMs 设备未配置采集间隔时使用的默认值（毫秒）
+	defaultCollectIntervalMs = int64(1000)
+	// clockSkewThresholdMs 偏差低于该阈值视为网络传输延迟，不做校正
+	clockSkewThresholdMs = int64(2000)
+	// clockSkewResetMs 新测量值与平滑值相差超过该值时视为设备时钟跳变（如 NTP 同步），直接重置
+	clockSkewResetMs = int64(30000)
+	// clockSkewSmoothing 偏差指数平滑系数
+	clockSkewSmoothing = 0.2
+)
+
+// ResolvePointTimestamps 计算推送批次中每个数据点的时间戳（Unix 毫秒）
+// 数据点带有设备时间戳时，按测得的设备时钟偏差校正；
+// 未带时间戳时（旧版脚本），按设备配置的采集间隔从当前时间倒推
+func (s *DataReceiverService) ResolvePointTimestamps(ctx context.Context, deviceID uint, sentAt int64, points []models.PushMetricPoint) []int64 {
+	now := time.Now().UnixMilli()
+
+	// 测量时钟偏差：推送时刻的服务器时间减去设备时间
+	var skew int64
+	if sentAt > 0 {
+		if info := s.updateClockSkew(ctx, deviceID, now-sentAt); info.Corrected {
+			skew = info.SkewMs
+		}
+	} else if info, err := s.GetClockSkew(ctx, deviceID); err == nil && info.Corrected {
+		skew = info.SkewMs
+	}
+
+	intervalMs := s.getCollectIntervalMs(deviceID)
+	timestamps := make([]int64, len(points))
+	for i, point := range points {
+		if point.Timestamp > 0 {
+			timestamps[i] = point.Timestamp + skew
+		} else {
+			// 最后一个点是当前时间，往前每个点减去一个采集间隔
+			timestamps[i] = now - int64(len(points)-1-i)*intervalMs
+		}
+	}
+
+	return timestamps
+}
+
+// GetClockSkew 获取设备最近测得的时钟偏差
+func (s *DataReceiverService) GetClockSkew(ctx context.Context, deviceID uint) (*models.ClockSkew, error) {
+	var info models.ClockSkew
+	if err := s.redisClient.GetJSON(ctx, fmt.Sprintf("device:clock_skew:%d", deviceID), &info); err != nil {
+		return nil, fmt.Errorf("clock skew not measured for device %d: %w", deviceID, err)
+	}
+	return &info, nil
+}
+
+// updateClockSkew 记录一次时钟偏差测量并返回平滑后的结果
+func (s *DataReceiverService) updateClockSkew(ctx context.Context, deviceID uint, sampleMs int64) *models.ClockSkew {
+	info, err := s.GetClockSkew(ctx, deviceID)
+	if err != nil {
+		info = &models.ClockSkew{DeviceID: deviceID, SkewMs: sampleMs}
+	} else if absInt64(sampleMs-info.SkewMs) > clockSkewResetMs {
+		info.SkewMs = sampleMs
+	} else {
+		info.SkewMs += int64(float64(sampleMs-info.SkewMs) * clockSkewSmoothing)
+	}
+
+	info.LastSample = sampleMs
+	info.Samples++
+	info.Corrected = absInt64(info.SkewMs) >= clockSkewThresholdMs
+	info.MeasuredAt = time.Now()
+
+	if err := s.redisClient.SetJSON(ctx, fmt.Sprintf("device:clock_skew:%d", deviceID), info, 24*time.Hour); err != nil {
+		log.Printf("Failed to cache clock skew for device %d: %v", deviceID, err)
+	}
+
+	return info
+}
+
+// getCollectIntervalMs 获取设备配置的采集间隔（毫秒）
+func (s *DataReceiverService) getCollectIntervalMs(deviceID uint) int64 {
+	if s.collectorRepo == nil {
+		return defaultCollectIntervalMs
+	}
+	collector, err := s.collectorRepo.GetByDeviceID(deviceID)
+	if err != nil || collector == nil || collector.IntervalMs <= 0 {
+		return defaultCollectIntervalMs
+	}
+	return int64(collector.IntervalMs)
+}
+
+// absInt64 返回 int64 的绝对值
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
 // ProcessBandwidthData 处理带宽数据并写入 InfluxDB
 func (s *DataReceiverService) ProcessBandwidthData(ctx context.Context, deviceID uint, timestamp int64, interfaces map[string]models.InterfaceMetrics) error {
 	// 确定时间戳
@@ -146,6 +268,16 @@ func (s *DataReceiverService) ProcessBandwidthData(ctx context.Context, deviceID
 			"rx_rate": float64(metrics.RxRate),
 			"tx_rate": float64(metrics.TxRate),
 		}
+		// 包速率为 0 也写入，空闲接口显示为 0 而不是断点
+		if metrics.RxPackets != nil {
+			fields["rx_packets"] = float64(*metrics.RxPackets)
+		}
+		if metrics.TxPackets != nil {
+			fields["tx_packets"] = float64(*metrics.TxPackets)
+		}
+		for field, rate := range s.counterRates(deviceID, ifaceName, ts, metrics) {
+			fields[field] = rate
+		}
 		
 		if err := s.influxClient.WritePoint("bandwidth", tags, fields, ts); err != nil {
 			log.Printf("Failed to write bandwidth data for device %d interface %s: %v", deviceID, ifaceName, err)
@@ -172,6 +304,105 @@ func (s *DataReceiverService) ProcessBandwidthData(ctx context.Context, deviceID
 	return nil
 }
 
+// counterRates 将接口错误/丢包累计计数换算为每秒速率
+// 首次采样、时间倒退或计数器缺失时不输出对应字段；计数器回绕（设备重启）时速率记为 0
+func (s *DataReceiverService) counterRates(deviceID uint, ifaceName string, ts time.Time, metrics models.InterfaceMetrics) map[string]float64 {
+	current := make(map[string]int64, 4)
+	for field, value := range map[string]*int64{
+		"rx_errors": metrics.RxErrors,
+		"tx_errors": metrics.TxErrors,
+		"rx_drops":  metrics.RxDrops,
+		"tx_drops":  metrics.TxDrops,
+	} {
+		if value != nil {
+			current[field] = *value
+		}
+	}
+	if len(current) == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("%d/%s", deviceID, ifaceName)
+
+	s.counterMu.Lock()
+	defer s.counterMu.Unlock()
+
+	if s.lastCounters == nil {
+		s.lastCounters = make(map[string]interfaceCounterSample)
+	}
+	now := time.Now()
+	s.pruneCounters(now)
+
+	last, ok := s.lastCounters[key]
+	if ok && !ts.After(last.ts) {
+		// 乱序或重复的采样点，不更新基线
+		return nil
+	}
+	s.lastCounters[key] = interfaceCounterSample{ts: ts, seen: now, counters: current}
+	if !ok {
+		return nil
+	}
+
+	elapsed := ts.Sub(last.ts).Seconds()
+	rates := make(map[string]float64, len(current))
+	for field, value := range current {
+		prev, exists := last.counters[field]
+		if !exists {
+			continue
+		}
+		delta := value - prev
+		if delta < 0 {
+			delta = 0
+		}
+		rates[field] = float64(delta) / elapsed
+	}
+	return rates
+}
+
+// pruneCounters 清理长时间未更新的接口计数基线，每个保留周期最多执行一次，调用方需持有 counterMu
+func (s *DataReceiverService) pruneCounters(now time.Time) {
+	if now.Sub(s.lastCounterPrune) < counterSampleTTL {
+		return
+	}
+	s.lastCounterPrune = now
+	for key, sample := range s.lastCounters {
+		if now.Sub(sample.seen) >= counterSampleTTL {
+			delete(s.lastCounters, key)
+		}
+	}
+}
+
+// ProcessLinkStates 处理接口运行状态，记录链路状态变化事件
+// 未上报 running 字段的接口（旧版脚本）不参与比对
+func (s *DataReceiverService) ProcessLinkStates(ctx context.Context, deviceID uint, timestamp int64, interfaces map[string]models.InterfaceMetrics, source models.InterfaceEventSource) error {
+	if s.linkStateService == nil {
+		return nil
+	}
+
+	states := make(map[string]models.InterfaceStatus)
+	for ifaceName, metrics := range interfaces {
+		if metrics.Running == nil {
+			continue
+		}
+		if *metrics.Running {
+			states[ifaceName] = models.InterfaceStatusUp
+		} else {
+			states[ifaceName] = models.InterfaceStatusDown
+		}
+	}
+	if len(states) == 0 {
+		return nil
+	}
+
+	var ts time.Time
+	if timestamp > 0 {
+		ts = time.UnixMilli(timestamp)
+	} else {
+		ts = time.Now()
+	}
+	return s.linkStateService.RecordStates(deviceID, ts, states, source)
+}
+
 // ProcessPingData 处理 Ping 数据并写入 InfluxDB
 func (s *DataReceiverService) ProcessPingData(ctx context.Context, deviceID uint, timestamp int64, pings []models.PingMetric) error {
 	// 确定时间戳
@@ -203,9 +434,31 @@ func (s *DataReceiverService) ProcessPingData(ctx context.Context, deviceID uint
 			}
 		}
 		
+		// 旧版单包脚本不上报收发包数，按一个包处理
+		sent, received := ping.Sent, ping.Received
+		if sent <= 0 {
+			sent = 1
+			received = 0
+			if status == "up" {
+				received = 1
+			}
+		}
+		loss := float64(sent-received) / float64(sent) * 100
+		
 		fields := map[string]interface{}{
-			"latency": latency,
-			"status":  status,
+			"latency":  latency,
+			"status":   status,
+			"sent":     float64(sent),
+			"received": float64(received),
+			"loss":     loss,
+		}
+		if ping.Sent > 0 {
+			fields["min_latency"] = float64(ping.MinLatency)
+			fields["max_latency"] = float64(ping.MaxLatency)
+			fields["jitter"] = float64(ping.Jitter)
+		}
+		if ping.VoIP {
+			fields["mos"] = CalculateMOS(latency/1000, float64(ping.Jitter)/1000, loss)
 		}
 		
 		if err := s.influxClient.WritePoint("ping", tags, fields, ts); err != nil {
@@ -233,6 +486,165 @@ func (s *DataReceiverService) ProcessPingData(ctx context.Context, deviceID uint
 	return nil
 }
 
+// ProcessServiceMetrics 处理服务指标并写入 InfluxDB
+// 写入 ppp、dhcp（按 server）、wireless（按 interface）、queue（按 queue）四个 measurement
+func (s *DataReceiverService) ProcessServiceMetrics(ctx context.Context, deviceID uint, timestamp int64, services *models.ServiceMetrics) error {
+	if services == nil {
+		return nil
+	}
+
+	// 确定时间戳
+	var ts time.Time
+	if timestamp > 0 {
+		ts = time.UnixMilli(timestamp)
+	} else {
+		ts = time.Now()
+	}
+	deviceTag := strconv.FormatUint(uint64(deviceID), 10)
+
+	writeCount := 0
+	write := func(measurement string, tags map[string]string, fields map[string]interface{}) {
+		tags["device_id"] = deviceTag
+		if err := s.influxClient.WritePoint(measurement, tags, fields, ts); err != nil {
+			log.Printf("Failed to write %s data for device %d: %v", measurement, deviceID, err)
+			return
+		}
+		writeCount++
+	}
+
+	// 确保数值类型为 float64，避免 InfluxDB 类型冲突
+	if services.PPP != nil {
+		write("ppp", map[string]string{}, map[string]interface{}{
+			"active": float64(services.PPP.Active),
+			"pppoe":  float64(services.PPP.PPPoE),
+		})
+	}
+	for _, dhcp := range services.DHCP {
+		write("dhcp", map[string]string{"server": dhcp.Server}, map[string]interface{}{
+			"bound": float64(dhcp.Bound),
+			"total": float64(dhcp.Total),
+		})
+	}
+	for _, wireless := range services.Wireless {
+		fields := map[string]interface{}{
+			"clients": float64(wireless.Clients),
+		}
+		// 没有客户端时信号强度无意义
+		if wireless.Clients > 0 {
+			fields["avg_signal"] = float64(wireless.AvgSignal)
+			fields["min_signal"] = float64(wireless.MinSignal)
+		}
+		write("wireless", map[string]string{"interface": wireless.Interface}, fields)
+	}
+	for _, queue := range services.Queues {
+		write("queue", map[string]string{"queue": queue.Name}, map[string]interface{}{
+			"upload_rate":   float64(queue.UploadRate),
+			"download_rate": float64(queue.DownloadRate),
+		})
+	}
+
+	// 刷新写入缓冲区确保数据被写入
+	s.influxClient.Flush()
+	log.Printf("Wrote %d service points for device %d", writeCount, deviceID)
+
+	if len(services.Health) > 0 {
+		sensors := make([]collector.HealthSensor, 0, len(services.Health))
+		for _, reading := range services.Health {
+			if sensor, ok := collector.NormalizeRouterOSHealth(reading.Name, reading.Value, reading.Type); ok {
+				sensors = append(sensors, sensor)
+			}
+		}
+		if err := s.ProcessHealthSensors(ctx, deviceID, timestamp, sensors); err != nil {
+			log.Printf("Failed to process health sensors for device %d: %v", deviceID, err)
+		}
+	}
+
+	// 更新 Redis 中的最新服务指标
+	latestKey := fmt.Sprintf("device:services:%d", deviceID)
+	serviceData := map[string]interface{}{
+		"timestamp": ts,
+		"services":  services,
+	}
+	if err := s.redisClient.SetJSON(ctx, latestKey, serviceData, s.cacheExpiry); err != nil {
+		log.Printf("Failed to cache service data for device %d: %v", deviceID, err)
+	}
+
+	return nil
+}
+
+// ProcessHealthSensors 处理硬件健康传感器数据（RouterOS 推送或 Linux SSH 采集，名称已归一化）
+// 写入 health measurement（tag: sensor、type），并缓存最新读数
+func (s *DataReceiverService) ProcessHealthSensors(ctx context.Context, deviceID uint, timestamp int64, sensors []collector.HealthSensor) error {
+	if len(sensors) == 0 {
+		return nil
+	}
+
+	// 确定时间戳
+	var ts time.Time
+	if timestamp > 0 {
+		ts = time.UnixMilli(timestamp)
+	} else {
+		ts = time.Now()
+	}
+	deviceTag := strconv.FormatUint(uint64(deviceID), 10)
+
+	writeCount := 0
+	for _, sensor := range sensors {
+		tags := map[string]string{
+			"device_id": deviceTag,
+			"sensor":    sensor.Name,
+			"type":      sensor.Type,
+		}
+		fields := map[string]interface{}{
+			"value": sensor.Value,
+		}
+		if err := s.influxClient.WritePoint("health", tags, fields, ts); err != nil {
+			log.Printf("Failed to write health data for device %d sensor %s: %v", deviceID, sensor.Name, err)
+			continue
+		}
+		writeCount++
+	}
+
+	// 刷新写入缓冲区确保数据被写入
+	s.influxClient.Flush()
+	log.Printf("Wrote %d health points for device %d", writeCount, deviceID)
+
+	// 更新 Redis 中的最新传感器读数
+	latestKey := fmt.Sprintf("device:health:%d", deviceID)
+	healthData := map[string]interface{}{
+		"timestamp": ts,
+		"sensors":   sensors,
+	}
+	if err := s.redisClient.SetJSON(ctx, latestKey, healthData, s.cacheExpiry); err != nil {
+		log.Printf("Failed to cache health data for device %d: %v", deviceID, err)
+	}
+
+	return nil
+}
+
+// CalculateMOS 根据延迟、抖动（毫秒）和丢包率（%）估算 MOS 值
+// 使用简化的 ITU-T G.107 E-model：有效延迟 = 延迟 + 2*抖动 + 10ms 编解码延迟
+func CalculateMOS(latencyMs, jitterMs, lossPercent float64) float64 {
+	effectiveLatency := latencyMs + 2*jitterMs + 10
+
+	var r float64
+	if effectiveLatency < 160 {
+		r = 93.2 - effectiveLatency/40
+	} else {
+		r = 93.2 - (effectiveLatency-120)/10
+	}
+	r -= 2.5 * lossPercent
+
+	if r <= 0 {
+		return 1
+	}
+	if r >= 100 {
+		return 4.5
+	}
+	mos := 1 + 0.035*r + 0.000007*r*(r-60)*(100-r)
+	return math.Round(mos*100) / 100
+}
+
 // UpdateDeviceOnlineStatus 更新设备在线状态
 func (s *DataReceiverService) UpdateDeviceOnlineStatus(ctx context.Context, deviceID uint) error {
 	now := time.Now()
@@ -304,6 +716,11 @@ func (s *DataReceiverService) GetAllDevicesStatus(ctx context.Context) ([]models
 			}
 		}
 		
+		// 获取时钟偏差
+		if skew, err := s.GetClockSkew(ctx, device.ID); err == nil {
+			status.ClockSkew = skew
+		}
+		
 		statuses = append(statuses, status)
 	}
 	
@@ -540,6 +957,7 @@ func (s *DataReceiverService) CleanupExpiredData(ctx context.Context) error {
 				key,
 				fmt.Sprintf("device:latest:%s", deviceID),
 				fmt.Sprintf("device:exists:%s", deviceID),
+				fmt.Sprintf("device:clock_skew:%s", deviceID),
 			}
 			
 			// 删除所有指标缓存
@@ -26,6 +26,12 @@ func (m *MockInfluxClient) WritePoint(measurement string, tags map[string]string
 	return args.Error(0)
 }
 
+func (m *MockInfluxClient) Delete(start, stop time.Time, predicate string) error {
+	return nil
+}
+
+func (m *MockInfluxClient) Flush() {}
+
 func (m *MockInfluxClient) Health() error {
 	args := m.Called()
 	return args.Error(0)
@@ -661,7 +667,7 @@ func TestBandwidthDataProcessing(t *testing.T) {
 		mockInflux.On("WritePoint", "bandwidth", mock.MatchedBy(func(tags map[string]string) bool {
 			return tags["device_id"] == "123" && tags["interface"] == "ether1"
 		}), mock.MatchedBy(func(fields map[string]interface{}) bool {
-			return fields["rx_rate"] == int64(1000000) && fields["tx_rate"] == int64(500000)
+			return fields["rx_rate"] == float64(1000000) && fields["tx_rate"] == float64(500000)
 		}), mock.Anything).Return(nil)
 		mockRedis.On("SetJSON", mock.Anything, "device:bandwidth:123", mock.Anything, mock.Anything).Return(nil)
 
@@ -698,6 +704,47 @@ func TestBandwidthDataProcessing(t *testing.T) {
 		// 验证 WritePoint 被调用了3次（每个接口一次）
 		mockInflux.AssertNumberOfCalls(t, "WritePoint", 3)
 	})
+
+	t.Run("should write zero packet rates for idle interfaces", func(t *testing.T) {
+		mockInflux := &MockInfluxClient{}
+		mockRedis := &MockRedisClient{}
+		mockDeviceRepo := &MockDeviceRepository{}
+
+		mockInflux.On("WritePoint", "bandwidth", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
+			return fields["rx_packets"] == float64(0) && fields["tx_packets"] == float64(0)
+		}), mock.Anything).Return(nil)
+		mockRedis.On("SetJSON", mock.Anything, mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
+
+		service := NewDataReceiverService(mockInflux, mockRedis, mockDeviceRepo)
+
+		var zero int64
+		interfaces := map[string]models.InterfaceMetrics{
+			"ether1": {RxPackets: &zero, TxPackets: &zero},
+		}
+		err := service.ProcessBandwidthData(context.Background(), 123, time.Now().UnixMilli(), interfaces)
+
+		assert.NoError(t, err)
+		mockInflux.AssertExpectations(t)
+	})
+
+	t.Run("should prune stale counter baselines", func(t *testing.T) {
+		service := NewDataReceiverService(&MockInfluxClient{}, &MockRedisClient{}, &MockDeviceRepository{})
+
+		var errors int64 = 5
+		now := time.Now()
+		service.counterRates(1, "ether1", now, models.InterfaceMetrics{RxErrors: &errors})
+		assert.Len(t, service.lastCounters, 1)
+
+		// 模拟设备已停止上报：基线和上次清理时间都已过期
+		sample := service.lastCounters["1/ether1"]
+		sample.seen = now.Add(-counterSampleTTL)
+		service.lastCounters["1/ether1"] = sample
+		service.lastCounterPrune = now.Add(-counterSampleTTL)
+
+		service.counterRates(2, "ether1", now, models.InterfaceMetrics{RxErrors: &errors})
+		assert.NotContains(t, service.lastCounters, "1/ether1")
+		assert.Contains(t, service.lastCounters, "2/ether1")
+	})
 }
 
 // 单元测试：Ping 数据处理
@@ -711,7 +758,7 @@ func TestPingDataProcessing(t *testing.T) {
 		mockInflux.On("WritePoint", "ping", mock.MatchedBy(func(tags map[string]string) bool {
 			return tags["device_id"] == "123" && tags["target_address"] == "8.8.8.8"
 		}), mock.MatchedBy(func(fields map[string]interface{}) bool {
-			return fields["latency"] == int64(10) && fields["status"] == "up"
+			return fields["latency"] == float64(10) && fields["status"] == "up"
 		}), mock.Anything).Return(nil)
 		mockRedis.On("SetJSON", mock.Anything, "device:ping:123", mock.Anything, mock.Anything).Return(nil)
 
@@ -733,7 +780,7 @@ func TestPingDataProcessing(t *testing.T) {
 
 		// 设置模拟行为
 		mockInflux.On("WritePoint", "ping", mock.Anything, mock.MatchedBy(func(fields map[string]interface{}) bool {
-			return fields["latency"] == int64(0) && fields["status"] == "down"
+			return fields["latency"] == float64(0) && fields["status"] == "down"
 		}), mock.Anything).Return(nil)
 		mockRedis.On("SetJSON", mock.Anything, mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(nil)
 
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/proxy"
+
+	"github.com/go-routeros/routeros/v3"
+	"golang.org/x/crypto/ssh"
+)
+
+// deviceDialer 获取设备配置的代理拨号器，设备未配置代理或未设置提供者时返回 nil（直接连接）
+func deviceDialer(dialers ProxyDialerProvider, device *models.Device) (proxy.Dialer, error) {
+	if device.ProxyID == nil || *device.ProxyID == 0 || dialers == nil {
+		return nil, nil
+	}
+	dialer, err := dialers.GetDialer(*device.ProxyID)
+	if err != nil {
+		return nil, fmt.Errorf("获取代理失败: %w", err)
+	}
+	return dialer, nil
+}
+
+// connectDeviceSSH 建立设备 SSH 会话，设备配置了代理时经代理连接
+func connectDeviceSSH(dialers ProxyDialerProvider, sshCollector *collector.SSHCollector, device *models.Device) (*ssh.Client, error) {
+	dialer, err := deviceDialer(dialers, device)
+	if err != nil {
+		return nil, err
+	}
+	return sshCollector.ConnectWithDialer(dialer, device.Host, device.Port, device.Username, device.Password)
+}
+
+// connectDeviceAPI 建立设备 RouterOS API 会话，设备配置了代理时经代理连接
+func connectDeviceAPI(dialers ProxyDialerProvider, rosCollector *collector.RouterOSCollector, device *models.Device) (*routeros.Client, error) {
+	dialer, err := deviceDialer(dialers, device)
+	if err != nil {
+		return nil, err
+	}
+	return rosCollector.ConnectWithDialer(dialer, device.Host, device.APIPort, device.Username, device.Password)
+}
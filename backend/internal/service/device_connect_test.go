@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/proxy"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDialer 记录拨号地址的代理拨号器
+type recordingDialer struct {
+	addresses []string
+}
+
+func (d *recordingDialer) Dial(network, addr string) (net.Conn, error) {
+	d.addresses = append(d.addresses, addr)
+	return nil, errors.New("proxy unreachable")
+}
+
+// staticDialerProvider 按代理 ID 返回固定拨号器
+type staticDialerProvider map[uint]proxy.Dialer
+
+func (p staticDialerProvider) GetDialer(proxyID uint) (proxy.Dialer, error) {
+	if dialer, ok := p[proxyID]; ok {
+		return dialer, nil
+	}
+	return nil, errors.New("proxy not found")
+}
+
+func TestDeviceDialer(t *testing.T) {
+	dialer := &recordingDialer{}
+	dialers := staticDialerProvider{7: dialer}
+	proxyID, missingID, zero := uint(7), uint(8), uint(0)
+
+	// 未配置代理时直接连接
+	got, err := deviceDialer(dialers, &models.Device{})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+	got, err = deviceDialer(dialers, &models.Device{ProxyID: &zero})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	got, err = deviceDialer(dialers, &models.Device{ProxyID: &proxyID})
+	require.NoError(t, err)
+	assert.Same(t, dialer, got)
+
+	// 代理不可用时不退回直接连接
+	_, err = deviceDialer(dialers, &models.Device{ProxyID: &missingID})
+	assert.ErrorContains(t, err, "获取代理失败")
+}
+
+func TestConnectDevice_UsesDeviceProxy(t *testing.T) {
+	dialer := &recordingDialer{}
+	dialers := staticDialerProvider{7: dialer}
+	proxyID := uint(7)
+	device := &models.Device{Host: "10.0.0.1", Port: 2222, APIPort: 8728, Username: "admin", ProxyID: &proxyID}
+
+	_, err := connectDeviceSSH(dialers, collector.NewSSHCollector(time.Second), device)
+	assert.Error(t, err)
+	_, err = connectDeviceAPI(dialers, collector.NewRouterOSCollector(time.Second), device)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"10.0.0.1:2222", "10.0.0.1:8728"}, dialer.addresses)
+}
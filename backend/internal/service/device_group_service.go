@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
 )
 
 // DeviceGroupService 设备分组服务接口
@@ -34,6 +36,11 @@ func NewDeviceGroupService(groupRepo repository.DeviceGroupRepository) DeviceGro
 	}
 }
 
+// WithContext 返回绑定上下文的设备分组服务，上下文中的组织限定分组的范围
+func (s *deviceGroupService) WithContext(ctx context.Context) DeviceGroupService {
+	return &deviceGroupService{groupRepo: tenant.Bind(ctx, s.groupRepo)}
+}
+
 // CreateGroup 创建设备分组
 func (s *deviceGroupService) CreateGroup(group *models.DeviceGroup) error {
 	if group == nil {
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"sync"
+	"time"
+)
+
+// deviceOrganization 设备所属组织（设备不存在时也缓存，避免每个数据点都查库）
+type deviceOrganization struct {
+	organizationID uint
+	found          bool
+	expiresAt      time.Time
+}
+
+// DeviceOrganizationCache 设备到所属组织的缓存，写入时序数据时为数据点附加组织标签
+type DeviceOrganizationCache struct {
+	deviceRepo repository.DeviceRepository
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	entries map[uint]deviceOrganization
+}
+
+// NewDeviceOrganizationCache 创建设备组织缓存
+func NewDeviceOrganizationCache(deviceRepo repository.DeviceRepository, ttl time.Duration) *DeviceOrganizationCache {
+	return &DeviceOrganizationCache{
+		deviceRepo: deviceRepo,
+		ttl:        ttl,
+		entries:    make(map[uint]deviceOrganization),
+	}
+}
+
+// DeviceOrganization 获取设备所属组织，结果缓存一段时间
+func (c *DeviceOrganizationCache) DeviceOrganization(deviceID uint) (uint, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.entries[deviceID]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.organizationID, cached.found
+	}
+
+	entry := deviceOrganization{expiresAt: now.Add(c.ttl)}
+	if device, err := c.deviceRepo.GetByID(deviceID); err == nil && device != nil {
+		entry.organizationID = device.OrganizationID
+		if entry.organizationID == 0 {
+			entry.organizationID = models.DefaultOrganizationID
+		}
+		entry.found = true
+	}
+
+	c.mu.Lock()
+	c.entries[deviceID] = entry
+	c.mu.Unlock()
+	return entry.organizationID, entry.found
+}
+
+// OrganizationOf 获取设备所属组织，缓存未设置或设备不存在时为默认组织
+func (c *DeviceOrganizationCache) OrganizationOf(deviceID uint) uint {
+	if c == nil {
+		return models.DefaultOrganizationID
+	}
+	if organizationID, ok := c.DeviceOrganization(deviceID); ok {
+		return organizationID
+	}
+	return models.DefaultOrganizationID
+}
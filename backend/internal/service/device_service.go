@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"nmp-platform/internal/collector"
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
 )
 
 // DeviceService 设备服务接口
@@ -93,6 +95,15 @@ func NewDeviceService(
 	}
 }
 
+// WithContext 返回绑定上下文的设备服务，上下文中的组织限定设备、标签和分组的范围
+func (s *deviceService) WithContext(ctx context.Context) DeviceService {
+	scoped := *s
+	scoped.deviceRepo = tenant.Bind(ctx, s.deviceRepo)
+	scoped.tagRepo = tenant.Bind(ctx, s.tagRepo)
+	scoped.groupRepo = tenant.Bind(ctx, s.groupRepo)
+	return &scoped
+}
+
 // CreateDevice 创建设备
 func (s *deviceService) CreateDevice(device *models.Device) error {
 	if device == nil {
@@ -573,6 +584,7 @@ func (s *deviceService) syncAndReturnInterfaces(deviceID uint, collectorInterfac
 			DeviceID: deviceID,
 			Name:     ci.Name,
 			Status:   status,
+			IfIndex:  ci.IfIndex,
 		})
 	}
 
@@ -404,6 +404,15 @@ func (m *mockInterfaceRepository) GetByDeviceIDAndName(deviceID uint, name strin
 	return nil, errors.New("interface not found")
 }
 
+func (m *mockInterfaceRepository) GetByDeviceIDAndIfIndex(deviceID uint, ifIndex int) (*models.Interface, error) {
+	for _, iface := range m.interfaces {
+		if iface.DeviceID == deviceID && iface.IfIndex == ifIndex {
+			return iface, nil
+		}
+	}
+	return nil, errors.New("interface not found")
+}
+
 func (m *mockInterfaceRepository) SyncInterfaces(deviceID uint, interfaces []*models.Interface) error {
 	// 删除现有接口
 	m.DeleteByDeviceID(deviceID)
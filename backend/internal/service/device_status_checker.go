@@ -179,40 +179,52 @@ func (c *DeviceStatusChecker) CheckAllDevices(ctx context.Context) {
 
 // checkDeviceStatus 检查单个设备的状态
 func (c *DeviceStatusChecker) checkDeviceStatus(ctx context.Context, device *models.Device) models.DeviceStatus {
-	// 首先检查 Redis 中的最后在线时间
+	lastSeen, ok := c.lastSeenOf(ctx, device)
+	if !ok {
+		// 如果没有任何在线记录，返回未知状态
+		return models.DeviceStatusUnknown
+	}
+	if time.Since(lastSeen) < c.offlineTimeout {
+		return models.DeviceStatusOnline
+	}
+	return models.DeviceStatusOffline
+}
+
+// lastSeenOf 获取设备最后一次推送数据的时间
+// 依次检查 Redis 中的最后在线时间、采集器的最后推送时间和数据库中的最后在线时间
+func (c *DeviceStatusChecker) lastSeenOf(ctx context.Context, device *models.Device) (time.Time, bool) {
 	lastSeenKey := fmt.Sprintf("device:last_seen:%d", device.ID)
 	lastSeenStr, err := c.redisClient.Get(ctx, lastSeenKey)
 	if err == nil && lastSeenStr != "" {
 		lastSeen, err := time.Parse(time.RFC3339, lastSeenStr)
 		if err == nil {
-			if time.Since(lastSeen) < c.offlineTimeout {
-				return models.DeviceStatusOnline
-			}
-			return models.DeviceStatusOffline
+			return lastSeen, true
 		}
 	}
 
-	// 如果 Redis 中没有数据，检查采集器的最后推送时间
 	if c.collectorRepo != nil {
 		collector, err := c.collectorRepo.GetByDeviceID(device.ID)
 		if err == nil && collector != nil && collector.LastPushAt != nil {
-			if time.Since(*collector.LastPushAt) < c.offlineTimeout {
-				return models.DeviceStatusOnline
-			}
-			return models.DeviceStatusOffline
+			return *collector.LastPushAt, true
 		}
 	}
 
-	// 检查数据库中的最后在线时间
 	if device.LastSeen != nil {
-		if time.Since(*device.LastSeen) < c.offlineTimeout {
-			return models.DeviceStatusOnline
-		}
-		return models.DeviceStatusOffline
+		return *device.LastSeen, true
+	}
+
+	return time.Time{}, false
+}
+
+// GetLastSeen 获取设备最后一次推送数据的时间，没有任何记录时返回 false
+func (c *DeviceStatusChecker) GetLastSeen(ctx context.Context, deviceID uint) (time.Time, bool, error) {
+	device, err := c.deviceRepo.GetByID(deviceID)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("device not found: %w", err)
 	}
 
-	// 如果没有任何在线记录，返回未知状态
-	return models.DeviceStatusUnknown
+	lastSeen, ok := c.lastSeenOf(ctx, device)
+	return lastSeen, ok, nil
 }
 
 // updateDeviceStatus 更新设备状态
@@ -0,0 +1,408 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net"
+	"nmp-platform/internal/netflow"
+	"nmp-platform/internal/repository"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 流量采集相关常量
+const (
+	flowSourceTTL     = 5 * time.Minute
+	flowFlushInterval = 10 * time.Second
+	// flowMaxTableSize 单个聚合表的最大条目数，超出部分计入 other，防止扫描流量撑爆内存
+	flowMaxTableSize = 10000
+	// flowOtherKey 未进入 Top N 或超出表容量的流量汇总键
+	flowOtherKey = "other"
+	// defaultFlowTopN 默认每分钟每个维度保存的条目数
+	defaultFlowTopN = 20
+)
+
+// 流量方向：all 为设备整体，in/out 为接口入/出方向
+const (
+	FlowDirectionAll = "all"
+	FlowDirectionIn  = "in"
+	FlowDirectionOut = "out"
+)
+
+// 流量聚合维度
+const (
+	FlowDimensionSrc      = "src"
+	FlowDimensionDst      = "dst"
+	FlowDimensionPort     = "port"
+	FlowDimensionProtocol = "protocol"
+)
+
+// FlowDimensions 支持的聚合维度
+var FlowDimensions = []string{FlowDimensionSrc, FlowDimensionDst, FlowDimensionPort, FlowDimensionProtocol}
+
+// flowProtocolNames 常见 IP 协议号名称
+var flowProtocolNames = map[uint8]string{
+	1:   "icmp",
+	2:   "igmp",
+	6:   "tcp",
+	17:  "udp",
+	47:  "gre",
+	50:  "esp",
+	51:  "ah",
+	58:  "icmpv6",
+	89:  "ospf",
+	112: "vrrp",
+	132: "sctp",
+}
+
+// flowCounter 流量计数
+type flowCounter struct {
+	bytes   uint64
+	packets uint64
+	flows   uint64
+}
+
+func (c *flowCounter) add(flow *netflow.Flow) {
+	c.bytes += flow.Bytes
+	c.packets += flow.Packets
+	c.flows++
+}
+
+func (c *flowCounter) merge(other *flowCounter) {
+	c.bytes += other.bytes
+	c.packets += other.packets
+	c.flows += other.flows
+}
+
+// flowBucketKey 聚合桶：设备 + 接口 + 方向 + 分钟
+type flowBucketKey struct {
+	minute    int64
+	deviceID  uint
+	ifIndex   uint32
+	direction string
+}
+
+// flowBucket 一分钟内的聚合数据
+type flowBucket struct {
+	total  flowCounter
+	tables map[string]map[string]*flowCounter // 维度 -> 键 -> 计数
+}
+
+func newFlowBucket() *flowBucket {
+	tables := make(map[string]map[string]*flowCounter, len(FlowDimensions))
+	for _, dimension := range FlowDimensions {
+		tables[dimension] = make(map[string]*flowCounter)
+	}
+	return &flowBucket{tables: tables}
+}
+
+// add 累加一条流记录，keys 为各维度的键（空表示该维度不适用）
+func (b *flowBucket) add(flow *netflow.Flow, keys map[string]string) {
+	b.total.add(flow)
+	for dimension, key := range keys {
+		if key == "" {
+			continue
+		}
+		table := b.tables[dimension]
+		counter := table[key]
+		if counter == nil {
+			if len(table) >= flowMaxTableSize {
+				key = flowOtherKey
+				counter = table[key]
+			}
+			if counter == nil {
+				counter = &flowCounter{}
+				table[key] = counter
+			}
+		}
+		counter.add(flow)
+	}
+}
+
+// FlowService NetFlow/IPFIX 接收与聚合服务
+// 流记录按导出器地址归属到设备、按 ifIndex 归属到接口，每分钟按源/目的地址、端口、协议聚合 Top N 写入 InfluxDB
+type FlowService struct {
+	influxClient  InfluxClient
+	interfaceRepo repository.InterfaceRepository
+	sources       *sourceDeviceCache
+	topN          int
+	collector     *netflow.Collector
+
+	buckets    map[flowBucketKey]*flowBucket
+	bucketsMu  sync.Mutex
+	unknown    int64
+	interfaces map[flowInterfaceKey]string
+
+	runner receiverRunner
+}
+
+// flowInterfaceKey 接口名称缓存键
+type flowInterfaceKey struct {
+	deviceID uint
+	ifIndex  uint32
+}
+
+// NewFlowService 创建流量采集服务
+func NewFlowService(
+	influxClient InfluxClient,
+	interfaceRepo repository.InterfaceRepository,
+	resolver DeviceResolver,
+	address string,
+	topN int,
+) *FlowService {
+	if topN <= 0 {
+		topN = defaultFlowTopN
+	}
+	s := &FlowService{
+		influxClient:  influxClient,
+		interfaceRepo: interfaceRepo,
+		sources:       newSourceDeviceCache(resolver, flowSourceTTL),
+		topN:          topN,
+		buckets:       make(map[flowBucketKey]*flowBucket),
+		interfaces:    make(map[flowInterfaceKey]string),
+	}
+	s.collector = netflow.NewCollector(address, s.HandleFlows)
+	return s
+}
+
+// Start 启动流量监听和定时写入
+func (s *FlowService) Start(ctx context.Context) error {
+	started, err := s.runner.start(s.collector.Start, s.flushLoop)
+	if started {
+		log.Printf("Flow collector started (udp=%v, top_n=%d)", s.collector.Addr(), s.topN)
+	}
+	return err
+}
+
+// Stop 停止监听，写入所有未完成的聚合数据
+func (s *FlowService) Stop() {
+	if !s.runner.stop(s.collector.Stop) {
+		return
+	}
+	s.flush(time.Time{})
+	log.Println("Flow collector stopped")
+}
+
+// HandleFlows 接收一个导出报文中的流记录并计入当前分钟的聚合
+// 按接收时间归入分钟，未注册设备的导出器直接丢弃
+func (s *FlowService) HandleFlows(exporter net.IP, flows []netflow.Flow) {
+	sourceIP := normalizeSourceIP(exporter)
+	deviceID, _ := s.sources.resolve(sourceIP)
+	if deviceID == nil {
+		s.bucketsMu.Lock()
+		s.unknown++
+		if s.unknown%1000 == 1 {
+			log.Printf("Flow export from unknown device %s ignored (%d packets so far)", sourceIP, s.unknown)
+		}
+		s.bucketsMu.Unlock()
+		return
+	}
+
+	minute := time.Now().Truncate(time.Minute).Unix()
+
+	s.bucketsMu.Lock()
+	defer s.bucketsMu.Unlock()
+	for i := range flows {
+		flow := &flows[i]
+		keys := flowDimensionKeys(flow)
+
+		s.bucket(flowBucketKey{minute: minute, deviceID: *deviceID, direction: FlowDirectionAll}).add(flow, keys)
+		if flow.InputIf > 0 {
+			s.bucket(flowBucketKey{minute: minute, deviceID: *deviceID, ifIndex: flow.InputIf, direction: FlowDirectionIn}).add(flow, keys)
+		}
+		if flow.OutputIf > 0 {
+			s.bucket(flowBucketKey{minute: minute, deviceID: *deviceID, ifIndex: flow.OutputIf, direction: FlowDirectionOut}).add(flow, keys)
+		}
+	}
+}
+
+// bucket 获取或创建聚合桶，调用方需持有 bucketsMu
+func (s *FlowService) bucket(key flowBucketKey) *flowBucket {
+	b := s.buckets[key]
+	if b == nil {
+		b = newFlowBucket()
+		s.buckets[key] = b
+	}
+	return b
+}
+
+// flushLoop 定时写入已结束分钟的聚合数据
+func (s *FlowService) flushLoop(stop <-chan struct{}) {
+	ticker := time.NewTicker(flowFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.flush(now.Truncate(time.Minute))
+		case <-stop:
+			return
+		}
+	}
+}
+
+// flush 写入 before 之前的聚合桶，before 为零值时写入全部
+func (s *FlowService) flush(before time.Time) {
+	s.bucketsMu.Lock()
+	ready := make(map[flowBucketKey]*flowBucket)
+	for key, b := range s.buckets {
+		if before.IsZero() || key.minute < before.Unix() {
+			ready[key] = b
+			delete(s.buckets, key)
+		}
+	}
+	s.bucketsMu.Unlock()
+
+	for key, b := range ready {
+		s.writeBucket(key, b)
+	}
+}
+
+// writeBucket 写入一个聚合桶：flow_total 为总量，flow_top 为各维度 Top N 及 other
+func (s *FlowService) writeBucket(key flowBucketKey, b *flowBucket) {
+	if s.influxClient == nil {
+		return
+	}
+
+	ts := time.Unix(key.minute, 0)
+	baseTags := map[string]string{
+		"device_id": strconv.FormatUint(uint64(key.deviceID), 10),
+		"if_index":  strconv.FormatUint(uint64(key.ifIndex), 10),
+		"direction": key.direction,
+	}
+	if key.ifIndex > 0 {
+		baseTags["interface"] = s.interfaceName(key.deviceID, key.ifIndex)
+	}
+
+	if err := s.influxClient.WritePoint("flow_total", baseTags, flowFields(&b.total), ts); err != nil {
+		log.Printf("Failed to write flow totals for device %d: %v", key.deviceID, err)
+		return
+	}
+
+	for dimension, table := range b.tables {
+		for entryKey, counter := range topFlowEntries(table, s.topN) {
+			tags := make(map[string]string, len(baseTags)+2)
+			for k, v := range baseTags {
+				tags[k] = v
+			}
+			tags["dimension"] = dimension
+			tags["key"] = entryKey
+			if err := s.influxClient.WritePoint("flow_top", tags, flowFields(counter), ts); err != nil {
+				log.Printf("Failed to write flow %s top talkers for device %d: %v", dimension, key.deviceID, err)
+				return
+			}
+		}
+	}
+}
+
+// interfaceName 根据 ifIndex 解析接口名称，未同步的接口返回 ifIndex 字符串
+func (s *FlowService) interfaceName(deviceID uint, ifIndex uint32) string {
+	key := flowInterfaceKey{deviceID: deviceID, ifIndex: ifIndex}
+	s.bucketsMu.Lock()
+	name, ok := s.interfaces[key]
+	s.bucketsMu.Unlock()
+	if ok {
+		return name
+	}
+
+	name = strconv.FormatUint(uint64(ifIndex), 10)
+	if s.interfaceRepo != nil {
+		if iface, err := s.interfaceRepo.GetByDeviceIDAndIfIndex(deviceID, int(ifIndex)); err == nil {
+			name = iface.Name
+		}
+	}
+
+	s.bucketsMu.Lock()
+	s.interfaces[key] = name
+	s.bucketsMu.Unlock()
+	return name
+}
+
+// topFlowEntries 取字节数最多的 n 个条目，其余合并为 other
+func topFlowEntries(table map[string]*flowCounter, n int) map[string]*flowCounter {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		if key != flowOtherKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if table[keys[i]].bytes != table[keys[j]].bytes {
+			return table[keys[i]].bytes > table[keys[j]].bytes
+		}
+		return keys[i] < keys[j]
+	})
+
+	result := make(map[string]*flowCounter, n+1)
+	other := &flowCounter{}
+	if counter, ok := table[flowOtherKey]; ok {
+		other.merge(counter)
+	}
+	for i, key := range keys {
+		if i < n {
+			result[key] = table[key]
+		} else {
+			other.merge(table[key])
+		}
+	}
+	if other.flows > 0 {
+		result[flowOtherKey] = other
+	}
+	return result
+}
+
+// flowFields 计数转换为 InfluxDB 字段
+func flowFields(c *flowCounter) map[string]interface{} {
+	return map[string]interface{}{
+		"bytes":   float64(c.bytes),
+		"packets": float64(c.packets),
+		"flows":   float64(c.flows),
+	}
+}
+
+// flowDimensionKeys 计算流记录在各维度的键
+func flowDimensionKeys(flow *netflow.Flow) map[string]string {
+	keys := map[string]string{
+		FlowDimensionProtocol: flowProtocolName(flow.Protocol),
+	}
+	if flow.SrcAddr != nil {
+		keys[FlowDimensionSrc] = normalizeSourceIP(flow.SrcAddr)
+	}
+	if flow.DstAddr != nil {
+		keys[FlowDimensionDst] = normalizeSourceIP(flow.DstAddr)
+	}
+	if port := flowServicePort(flow); port > 0 {
+		keys[FlowDimensionPort] = flowProtocolName(flow.Protocol) + "/" + strconv.Itoa(int(port))
+	}
+	return keys
+}
+
+// flowServicePort 取服务端口：TCP/UDP/SCTP 两端端口中较小的非零端口（通常为服务端）
+func flowServicePort(flow *netflow.Flow) uint16 {
+	switch flow.Protocol {
+	case 6, 17, 132:
+	default:
+		return 0
+	}
+	src, dst := flow.SrcPort, flow.DstPort
+	switch {
+	case src == 0:
+		return dst
+	case dst == 0:
+		return src
+	case src < dst:
+		return src
+	default:
+		return dst
+	}
+}
+
+// flowProtocolName 协议号转换为名称，未知协议使用协议号
+func flowProtocolName(protocol uint8) string {
+	if name, ok := flowProtocolNames[protocol]; ok {
+		return name
+	}
+	return strconv.Itoa(int(protocol))
+}
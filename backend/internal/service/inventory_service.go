@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 设备清单相关常量
+const (
+	// inventoryStartDelay 启动后首次刷新的延迟，避开服务启动时的连接高峰
+	inventoryStartDelay       = 2 * time.Minute
+	inventoryCollectTimeout   = 15 * time.Second
+	inventoryPackageFieldName = "package:"
+)
+
+// ErrInventoryUnsupported 设备类型不支持清单采集
+var ErrInventoryUnsupported = errors.New("device type does not support inventory collection")
+
+// InventoryService 设备硬件/软件清单服务
+// 定时通过采集器刷新每台设备的型号、序列号、版本、软件包和磁盘等信息，字段变化时记录变更历史
+type InventoryService struct {
+	repo         repository.DeviceInventoryRepository
+	deviceRepo   repository.DeviceRepository
+	rosCollector *collector.RouterOSCollector
+	sshCollector *collector.SSHCollector
+	dialers      ProxyDialerProvider
+	interval     time.Duration
+	concurrency  int
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewInventoryService 创建设备清单服务
+func NewInventoryService(
+	repo repository.DeviceInventoryRepository,
+	deviceRepo repository.DeviceRepository,
+	interval time.Duration,
+) *InventoryService {
+	return &InventoryService{
+		repo:         repo,
+		deviceRepo:   deviceRepo,
+		rosCollector: collector.NewRouterOSCollector(inventoryCollectTimeout),
+		sshCollector: collector.NewSSHCollector(inventoryCollectTimeout),
+		interval:     interval,
+		concurrency:  8,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// SetDialerProvider 设置代理拨号器提供者，设备配置了代理时经代理连接
+func (s *InventoryService) SetDialerProvider(dialers ProxyDialerProvider) {
+	s.dialers = dialers
+}
+
+// Start 启动定时刷新
+func (s *InventoryService) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running || s.interval <= 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	log.Printf("Inventory scheduler started with interval %v", s.interval)
+}
+
+// Stop 停止定时刷新，等待进行中的采集完成
+func (s *InventoryService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Inventory scheduler stopped")
+}
+
+// run 调度循环：启动后延迟执行首次刷新，之后按间隔执行
+func (s *InventoryService) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(inventoryStartDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-timer.C:
+			s.RefreshAll()
+			timer.Reset(s.interval)
+		}
+	}
+}
+
+// RefreshAll 刷新所有 RouterOS 和 Linux 设备的清单
+func (s *InventoryService) RefreshAll() {
+	var devices []*models.Device
+	for _, osType := range []models.DeviceOSType{models.DeviceOSTypeMikroTik, models.DeviceOSTypeLinux} {
+		list, err := s.deviceRepo.GetByOSType(osType)
+		if err != nil {
+			log.Printf("Failed to get %s devices for inventory: %v", osType, err)
+			continue
+		}
+		devices = append(devices, list...)
+	}
+
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(device *models.Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if _, err := s.RefreshDevice(device); err != nil {
+				log.Printf("Failed to refresh inventory of device %d: %v", device.ID, err)
+			}
+		}(device)
+	}
+	wg.Wait()
+}
+
+// RefreshDevice 采集单台设备清单并保存，字段变化时记录变更历史
+func (s *InventoryService) RefreshDevice(device *models.Device) (*models.DeviceInventory, error) {
+	info, err := s.collect(device)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.GetByDeviceID(device.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get current inventory: %w", err)
+	}
+
+	now := time.Now()
+	updated := buildDeviceInventory(device.ID, info, now)
+	var changes []models.DeviceInventoryChange
+	if current != nil {
+		updated.ID = current.ID
+		updated.CreatedAt = current.CreatedAt
+		changes = DiffInventory(current, updated, now)
+	}
+	updated.OrganizationID = device.OrganizationID
+	for i := range changes {
+		changes[i].OrganizationID = device.OrganizationID
+	}
+
+	if err := s.repo.Save(updated, changes); err != nil {
+		return nil, fmt.Errorf("failed to save inventory: %w", err)
+	}
+	if len(changes) > 0 {
+		log.Printf("Inventory of device %d (%s) changed: %d fields", device.ID, device.Name, len(changes))
+	}
+	return updated, nil
+}
+
+// collect 通过采集器获取设备清单，MikroTik 优先使用 API，失败则使用 SSH
+func (s *InventoryService) collect(device *models.Device) (*collector.InventoryInfo, error) {
+	switch device.OSType {
+	case models.DeviceOSTypeMikroTik:
+		client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+		if err == nil {
+			defer client.Close()
+			if info, err := s.rosCollector.GetInventory(client); err == nil {
+				return info, nil
+			}
+		}
+
+		sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+		if err != nil {
+			return nil, fmt.Errorf("无法连接到设备: %w", err)
+		}
+		defer sshClient.Close()
+		return s.sshCollector.GetMikroTikInventory(sshClient)
+
+	case models.DeviceOSTypeLinux:
+		sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+		if err != nil {
+			return nil, fmt.Errorf("无法连接到设备: %w", err)
+		}
+		defer sshClient.Close()
+		return s.sshCollector.GetLinuxInventory(sshClient)
+
+	default:
+		return nil, ErrInventoryUnsupported
+	}
+}
+
+// GetInventory 获取设备当前清单，上下文中的组织限定查询范围
+func (s *InventoryService) GetInventory(ctx context.Context, deviceID uint) (*models.DeviceInventory, error) {
+	return tenant.Bind(ctx, s.repo).GetByDeviceID(deviceID)
+}
+
+// ListInventory 按条件查询设备清单，上下文中的组织限定查询范围
+func (s *InventoryService) ListInventory(ctx context.Context, filter repository.DeviceInventoryFilter) ([]*repository.DeviceInventoryRecord, int64, error) {
+	return tenant.Bind(ctx, s.repo).List(filter)
+}
+
+// ListChanges 查询设备清单变更历史，上下文中的组织限定查询范围
+func (s *InventoryService) ListChanges(ctx context.Context, deviceID uint, offset, limit int) ([]*models.DeviceInventoryChange, int64, error) {
+	return tenant.Bind(ctx, s.repo).ListChanges(deviceID, offset, limit)
+}
+
+// buildDeviceInventory 将采集结果转换为清单记录
+func buildDeviceInventory(deviceID uint, info *collector.InventoryInfo, collectedAt time.Time) *models.DeviceInventory {
+	packages := info.Packages
+	if packages == nil {
+		packages = []collector.PackageInfo{}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	disks := info.Disks
+	if disks == nil {
+		disks = []collector.DiskInfo{}
+	}
+
+	packagesJSON, _ := json.Marshal(packages)
+	disksJSON, _ := json.Marshal(disks)
+	var diskTotal int64
+	for _, disk := range disks {
+		diskTotal += disk.Size
+	}
+
+	return &models.DeviceInventory{
+		DeviceID:        deviceID,
+		Vendor:          info.Vendor,
+		Model:           info.Model,
+		BoardName:       info.BoardName,
+		SerialNumber:    info.SerialNumber,
+		Architecture:    info.Architecture,
+		CPUModel:        info.CPUModel,
+		CPUCount:        info.CPUCount,
+		MemoryTotal:     info.MemoryTotal,
+		OSVersion:       info.OSVersion,
+		FirmwareVersion: info.FirmwareVersion,
+		UpgradeFirmware: info.UpgradeFirmware,
+		Kernel:          info.Kernel,
+		Distro:          info.Distro,
+		Packages:        string(packagesJSON),
+		Disks:           string(disksJSON),
+		DiskTotal:       diskTotal,
+		CollectedAt:     collectedAt,
+	}
+}
+
+// DiffInventory 比较两次清单，返回变化的字段；软件包按包名逐个记录（如 "package:wireless"）
+func DiffInventory(old, updated *models.DeviceInventory, changedAt time.Time) []models.DeviceInventoryChange {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"vendor", old.Vendor, updated.Vendor},
+		{"model", old.Model, updated.Model},
+		{"board_name", old.BoardName, updated.BoardName},
+		{"serial_number", old.SerialNumber, updated.SerialNumber},
+		{"architecture", old.Architecture, updated.Architecture},
+		{"cpu_model", old.CPUModel, updated.CPUModel},
+		{"cpu_count", strconv.Itoa(old.CPUCount), strconv.Itoa(updated.CPUCount)},
+		{"memory_total", strconv.FormatInt(old.MemoryTotal, 10), strconv.FormatInt(updated.MemoryTotal, 10)},
+		{"os_version", old.OSVersion, updated.OSVersion},
+		{"firmware_version", old.FirmwareVersion, updated.FirmwareVersion},
+		{"kernel", old.Kernel, updated.Kernel},
+		{"distro", old.Distro, updated.Distro},
+		{"disks", old.Disks, updated.Disks},
+	}
+
+	var changes []models.DeviceInventoryChange
+	for _, field := range fields {
+		if field.old != field.new {
+			changes = append(changes, models.DeviceInventoryChange{
+				DeviceID:  updated.DeviceID,
+				Field:     field.name,
+				OldValue:  field.old,
+				NewValue:  field.new,
+				ChangedAt: changedAt,
+			})
+		}
+	}
+
+	oldPackages := InventoryPackageVersions(old.Packages)
+	newPackages := InventoryPackageVersions(updated.Packages)
+	names := make([]string, 0, len(oldPackages)+len(newPackages))
+	for name := range oldPackages {
+		names = append(names, name)
+	}
+	for name := range newPackages {
+		if _, ok := oldPackages[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if oldPackages[name] != newPackages[name] {
+			changes = append(changes, models.DeviceInventoryChange{
+				DeviceID:  updated.DeviceID,
+				Field:     inventoryPackageFieldName + name,
+				OldValue:  oldPackages[name],
+				NewValue:  newPackages[name],
+				ChangedAt: changedAt,
+			})
+		}
+	}
+
+	return changes
+}
+
+// InventoryPackageVersions 解析软件包 JSON 为 包名 -> 版本（已禁用的包标记为 disabled）
+func InventoryPackageVersions(packagesJSON string) map[string]string {
+	var packages []collector.PackageInfo
+	versions := make(map[string]string)
+	if packagesJSON == "" || json.Unmarshal([]byte(packagesJSON), &packages) != nil {
+		return versions
+	}
+	for _, pkg := range packages {
+		version := pkg.Version
+		if pkg.Disabled {
+			version += " (disabled)"
+		}
+		versions[pkg.Name] = version
+	}
+	return versions
+}
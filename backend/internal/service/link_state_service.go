@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"sync"
+	"time"
+)
+
+// LinkStateService 接口链路状态跟踪服务
+// 每个采集周期比对接口运行状态，发生变化时记录状态转换事件并更新 Interface.Status
+type LinkStateService struct {
+	eventRepo     repository.InterfaceEventRepository
+	interfaceRepo repository.InterfaceRepository
+	organizations *DeviceOrganizationCache
+
+	mu        sync.Mutex
+	lastState map[string]linkStateSample
+}
+
+// linkStateSample 接口最近一次观测到的状态
+type linkStateSample struct {
+	status models.InterfaceStatus
+	ts     time.Time
+}
+
+// NewLinkStateService 创建接口链路状态跟踪服务
+func NewLinkStateService(
+	eventRepo repository.InterfaceEventRepository,
+	interfaceRepo repository.InterfaceRepository,
+) *LinkStateService {
+	return &LinkStateService{
+		eventRepo:     eventRepo,
+		interfaceRepo: interfaceRepo,
+		lastState:     make(map[string]linkStateSample),
+	}
+}
+
+// SetDeviceOrganizations 设置设备组织缓存，事件记录归属到设备所属组织
+func (s *LinkStateService) SetDeviceOrganizations(organizations *DeviceOrganizationCache) {
+	s.organizations = organizations
+}
+
+// RecordStates 记录一次采集得到的接口状态
+// 首次观测以数据库中的 Interface.Status 作为基线；早于上次观测的乱序数据被忽略
+func (s *LinkStateService) RecordStates(deviceID uint, ts time.Time, states map[string]models.InterfaceStatus, source models.InterfaceEventSource) error {
+	var firstErr error
+	for ifaceName, status := range states {
+		if err := s.recordState(deviceID, ifaceName, ts, status, source); err != nil {
+			log.Printf("Failed to record link state for device %d interface %s: %v", deviceID, ifaceName, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// recordState 比对单个接口状态，变化时写入事件
+func (s *LinkStateService) recordState(deviceID uint, ifaceName string, ts time.Time, status models.InterfaceStatus, source models.InterfaceEventSource) error {
+	key := fmt.Sprintf("%d/%s", deviceID, ifaceName)
+
+	s.mu.Lock()
+	last, ok := s.lastState[key]
+	if ok && ts.Before(last.ts) {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastState[key] = linkStateSample{status: status, ts: ts}
+	s.mu.Unlock()
+
+	// 内存中没有基线时（服务重启后），以数据库记录的状态为准
+	var iface *models.Interface
+	if !ok {
+		var err error
+		iface, err = s.interfaceRepo.GetByDeviceIDAndName(deviceID, ifaceName)
+		if err != nil {
+			// 接口尚未同步到数据库，只建立基线
+			return nil
+		}
+		last.status = iface.Status
+	}
+
+	if last.status == status {
+		return nil
+	}
+
+	// 从未知状态变为已知状态不算作一次转换
+	if last.status != "" && last.status != models.InterfaceStatusUnknown {
+		event := &models.InterfaceStateEvent{
+			OrganizationID: s.organizations.OrganizationOf(deviceID),
+			DeviceID:       deviceID,
+			InterfaceName:  ifaceName,
+			OldStatus:      last.status,
+			NewStatus:      status,
+			Source:         source,
+			OccurredAt:     ts,
+		}
+		if err := s.eventRepo.Create(event); err != nil {
+			return fmt.Errorf("failed to create interface event: %w", err)
+		}
+	}
+
+	if iface == nil {
+		var err error
+		iface, err = s.interfaceRepo.GetByDeviceIDAndName(deviceID, ifaceName)
+		if err != nil {
+			return nil
+		}
+	}
+	iface.Status = status
+	if err := s.interfaceRepo.Update(iface); err != nil {
+		return fmt.Errorf("failed to update interface status: %w", err)
+	}
+	return nil
+}
+
+// GetTimeline 获取接口状态事件时间线，上下文中的组织限定查询范围
+func (s *LinkStateService) GetTimeline(ctx context.Context, deviceID uint, interfaceName string, start, end time.Time, limit int) ([]*models.InterfaceStateEvent, error) {
+	events, err := tenant.Bind(ctx, s.eventRepo).List(deviceID, interfaceName, start, end, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface events: %w", err)
+	}
+	return events, nil
+}
+
+// GetFlapCounts 获取设备各接口的抖动次数，上下文中的组织限定查询范围
+func (s *LinkStateService) GetFlapCounts(ctx context.Context, deviceID uint, start, end time.Time) ([]models.InterfaceFlapCount, error) {
+	counts, err := tenant.Bind(ctx, s.eventRepo).CountFlaps(deviceID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count interface flaps: %w", err)
+	}
+	return counts, nil
+}
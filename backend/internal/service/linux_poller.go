@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"log"
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"sync"
+	"time"
+)
+
+// LinuxMetricsPoller Linux 设备接口指标轮询器
+// Linux 设备不运行推送脚本，由服务端通过 SSH（设备配置了代理时经代理）定时读取 /proc/net/dev，并按较长间隔读取硬件传感器
+type LinuxMetricsPoller struct {
+	deviceRepo     repository.DeviceRepository
+	interfaceRepo  repository.InterfaceRepository
+	dataReceiver   *DataReceiverService
+	sshCollector   *collector.SSHCollector
+	dialers        ProxyDialerProvider
+	pollInterval   time.Duration
+	healthInterval time.Duration
+	concurrency    int
+	stopChan       chan struct{}
+	wg             sync.WaitGroup
+	running        bool
+	mu             sync.Mutex
+
+	// lastHealth 记录每台设备最近一次采集传感器的时间
+	lastHealth map[uint]time.Time
+	healthMu   sync.Mutex
+}
+
+// NewLinuxMetricsPoller 创建 Linux 设备接口指标轮询器
+func NewLinuxMetricsPoller(
+	deviceRepo repository.DeviceRepository,
+	interfaceRepo repository.InterfaceRepository,
+	dataReceiver *DataReceiverService,
+) *LinuxMetricsPoller {
+	return &LinuxMetricsPoller{
+		deviceRepo:     deviceRepo,
+		interfaceRepo:  interfaceRepo,
+		dataReceiver:   dataReceiver,
+		sshCollector:   collector.NewSSHCollector(10 * time.Second),
+		pollInterval:   10 * time.Second, // 默认每10秒采集一次
+		healthInterval: time.Minute,      // 传感器读数变化较慢，每分钟采集一次
+		concurrency:    8,                // 同时轮询的设备数
+		stopChan:       make(chan struct{}),
+		lastHealth:     make(map[uint]time.Time),
+	}
+}
+
+// SetPollInterval 设置轮询间隔，非正数时保持默认间隔
+func (p *LinuxMetricsPoller) SetPollInterval(interval time.Duration) {
+	if interval > 0 {
+		p.pollInterval = interval
+	}
+}
+
+// SetDialerProvider 设置代理拨号器提供者，设备配置了代理时经代理连接
+func (p *LinuxMetricsPoller) SetDialerProvider(dialers ProxyDialerProvider) {
+	p.dialers = dialers
+}
+
+// Start 启动定时轮询
+func (p *LinuxMetricsPoller) Start(ctx context.Context) {
+	p.mu.Lock()
+	if p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = true
+	p.stopChan = make(chan struct{})
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.runPoller(ctx)
+	}()
+
+	log.Printf("Linux metrics poller started with interval %v", p.pollInterval)
+}
+
+// Stop 停止定时轮询
+func (p *LinuxMetricsPoller) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = false
+	close(p.stopChan)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	log.Println("Linux metrics poller stopped")
+}
+
+// runPoller 运行轮询循环
+func (p *LinuxMetricsPoller) runPoller(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+			p.PollAllDevices(ctx)
+		}
+	}
+}
+
+// PollAllDevices 轮询所有配置了监控接口的 Linux 设备，并按传感器采集间隔轮询所有 Linux 设备的硬件传感器
+func (p *LinuxMetricsPoller) PollAllDevices(ctx context.Context) {
+	devices, err := p.deviceRepo.GetByOSType(models.DeviceOSTypeLinux)
+	if err != nil {
+		log.Printf("Failed to get linux devices for polling: %v", err)
+		return
+	}
+
+	now := time.Now()
+	sem := make(chan struct{}, p.concurrency)
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		monitored, err := p.interfaceRepo.GetMonitoredByDeviceID(device.ID)
+		if err != nil {
+			continue
+		}
+		collectHealth := p.healthDue(device.ID, now)
+		if len(monitored) == 0 && !collectHealth {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(device *models.Device, monitored []*models.Interface, collectHealth bool) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := p.pollDevice(ctx, device, monitored, collectHealth); err != nil {
+				log.Printf("Failed to poll linux device %d: %v", device.ID, err)
+			}
+		}(device, monitored, collectHealth)
+	}
+	wg.Wait()
+}
+
+// healthDue 判断设备是否到了传感器采集时间，到期时记录本次采集时间
+func (p *LinuxMetricsPoller) healthDue(deviceID uint, now time.Time) bool {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+	if last, ok := p.lastHealth[deviceID]; ok && now.Sub(last) < p.healthInterval {
+		return false
+	}
+	p.lastHealth[deviceID] = now
+	return true
+}
+
+// pollDevice 采集单台设备的接口指标，collectHealth 为 true 时同时采集硬件传感器
+func (p *LinuxMetricsPoller) pollDevice(ctx context.Context, device *models.Device, monitored []*models.Interface, collectHealth bool) error {
+	client, err := connectDeviceSSH(p.dialers, p.sshCollector, device)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// 传感器采集失败不影响流量采集
+	if collectHealth {
+		if sensors, err := p.sshCollector.GetLinuxHealthSensors(client); err != nil {
+			log.Printf("Failed to collect health sensors for device %d: %v", device.ID, err)
+		} else if err := p.dataReceiver.ProcessHealthSensors(ctx, device.ID, time.Now().UnixMilli(), sensors); err != nil {
+			log.Printf("Failed to process health sensors for device %d: %v", device.ID, err)
+		}
+	}
+	if len(monitored) == 0 {
+		return nil
+	}
+
+	traffic, err := p.sshCollector.GetLinuxInterfaceTraffic(client)
+	if err != nil {
+		return err
+	}
+
+	// 链路状态获取失败不影响流量采集
+	linkStatus := make(map[string]string)
+	if ifaces, err := p.sshCollector.GetLinuxInterfaces(client); err == nil {
+		for _, iface := range ifaces {
+			linkStatus[iface.Name] = iface.Status
+		}
+	}
+
+	interfaces := make(map[string]models.InterfaceMetrics, len(monitored))
+	for _, iface := range monitored {
+		t, ok := traffic[iface.Name]
+		if !ok {
+			continue
+		}
+		rxPackets, txPackets := t.RxPackets, t.TxPackets
+		rxErrors, txErrors, rxDrops, txDrops := t.RxErrors, t.TxErrors, t.RxDrops, t.TxDrops
+		metrics := models.InterfaceMetrics{
+			RxRate:    t.RxRate,
+			TxRate:    t.TxRate,
+			RxPackets: &rxPackets,
+			TxPackets: &txPackets,
+			RxErrors:  &rxErrors,
+			TxErrors:  &txErrors,
+			RxDrops:   &rxDrops,
+			TxDrops:   &txDrops,
+		}
+		if status, ok := linkStatus[iface.Name]; ok {
+			running := status == "up"
+			metrics.Running = &running
+		}
+		interfaces[iface.Name] = metrics
+	}
+	if len(interfaces) == 0 {
+		return nil
+	}
+
+	timestamp := time.Now().UnixMilli()
+	if err := p.dataReceiver.ProcessBandwidthData(ctx, device.ID, timestamp, interfaces); err != nil {
+		return err
+	}
+	if err := p.dataReceiver.ProcessLinkStates(ctx, device.ID, timestamp, interfaces, models.InterfaceEventSourcePoll); err != nil {
+		log.Printf("Failed to process link states for device %d: %v", device.ID, err)
+	}
+	return p.dataReceiver.UpdateDeviceOnlineStatus(ctx, device.ID)
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"log"
+	"nmp-platform/internal/models"
+	"sync"
+	"time"
+)
+
+// ProbeScheduler 服务端探测调度器
+// 按每个探测各自的间隔执行探测，同一探测不会并发执行
+type ProbeScheduler struct {
+	probeService   *ProbeService
+	tickInterval   time.Duration
+	reloadInterval time.Duration
+	concurrency    int
+
+	probes     []*models.Probe
+	nextRun    map[uint]time.Time
+	inFlight   map[uint]bool
+	lastReload time.Time
+	reloadReq  chan struct{}
+	sem        chan struct{}
+	stateMu    sync.Mutex
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewProbeScheduler 创建服务端探测调度器
+func NewProbeScheduler(probeService *ProbeService) *ProbeScheduler {
+	return &ProbeScheduler{
+		probeService:   probeService,
+		tickInterval:   time.Second,
+		reloadInterval: time.Minute, // 兜底定期从数据库重新加载
+		concurrency:    32,
+		nextRun:        make(map[uint]time.Time),
+		inFlight:       make(map[uint]bool),
+		reloadReq:      make(chan struct{}, 1),
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// Reload 请求重新加载探测配置
+func (s *ProbeScheduler) Reload() {
+	select {
+	case s.reloadReq <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动调度器
+func (s *ProbeScheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+	s.sem = make(chan struct{}, s.concurrency)
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx)
+	}()
+
+	log.Println("Probe scheduler started")
+}
+
+// Stop 停止调度器，等待正在执行的探测完成
+func (s *ProbeScheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Probe scheduler stopped")
+}
+
+// run 调度循环
+func (s *ProbeScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.tickInterval)
+	defer ticker.Stop()
+
+	s.reload()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-s.reloadReq:
+			s.reload()
+		case now := <-ticker.C:
+			if now.Sub(s.lastReload) >= s.reloadInterval {
+				s.reload()
+			}
+			s.dispatchDue(ctx, now)
+		}
+	}
+}
+
+// reload 从数据库加载启用的探测
+func (s *ProbeScheduler) reload() {
+	probes, err := s.probeService.probeRepo.ListEnabled()
+	if err != nil {
+		log.Printf("Failed to load probes: %v", err)
+		return
+	}
+	s.lastReload = time.Now()
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	active := make(map[uint]bool, len(probes))
+	for _, p := range probes {
+		active[p.ID] = true
+		if _, ok := s.nextRun[p.ID]; !ok {
+			// 新探测按 ID 错开首次执行时间，避免同时启动
+			s.nextRun[p.ID] = time.Now().Add(time.Duration(p.ID%10) * time.Second)
+		}
+	}
+	for id := range s.nextRun {
+		if !active[id] {
+			delete(s.nextRun, id)
+		}
+	}
+	s.probes = probes
+}
+
+// dispatchDue 执行到期的探测
+func (s *ProbeScheduler) dispatchDue(ctx context.Context, now time.Time) {
+	s.stateMu.Lock()
+	var due []*models.Probe
+	for _, p := range s.probes {
+		if s.inFlight[p.ID] || now.Before(s.nextRun[p.ID]) {
+			continue
+		}
+		s.inFlight[p.ID] = true
+		s.nextRun[p.ID] = now.Add(time.Duration(p.IntervalSec) * time.Second)
+		due = append(due, p)
+	}
+	s.stateMu.Unlock()
+
+	for _, p := range due {
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			s.finish(p.ID)
+			continue
+		case <-s.stopChan:
+			s.finish(p.ID)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(p *models.Probe) {
+			defer s.wg.Done()
+			defer func() { <-s.sem }()
+			defer s.finish(p.ID)
+			if _, err := s.probeService.RunProbe(ctx, p); err != nil {
+				log.Printf("Failed to run probe %d: %v", p.ID, err)
+			}
+		}(p)
+	}
+}
+
+// finish 标记探测执行完成
+func (s *ProbeScheduler) finish(id uint) {
+	s.stateMu.Lock()
+	delete(s.inFlight, id)
+	s.stateMu.Unlock()
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/probe"
+	"nmp-platform/internal/proxy"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"strconv"
+	"time"
+)
+
+// ProxyDialerProvider 代理拨号器提供者（由 proxy.Manager 实现）
+type ProxyDialerProvider interface {
+	GetDialer(proxyID uint) (proxy.Dialer, error)
+}
+
+// ProbeService 服务端探测服务
+type ProbeService struct {
+	probeRepo    repository.ProbeRepository
+	influxClient InfluxClient
+	dialers      ProxyDialerProvider
+	onChange     func() // 探测配置变化时回调（通知调度器重新加载）
+}
+
+// NewProbeService 创建服务端探测服务
+func NewProbeService(
+	probeRepo repository.ProbeRepository,
+	influxClient InfluxClient,
+	dialers ProxyDialerProvider,
+) *ProbeService {
+	return &ProbeService{
+		probeRepo:    probeRepo,
+		influxClient: influxClient,
+		dialers:      dialers,
+	}
+}
+
+// WithContext 返回绑定上下文的探测服务，上下文中的组织限定探测的范围
+func (s *ProbeService) WithContext(ctx context.Context) *ProbeService {
+	scoped := *s
+	scoped.probeRepo = tenant.Bind(ctx, s.probeRepo)
+	return &scoped
+}
+
+// SetChangeCallback 设置探测配置变化回调
+func (s *ProbeService) SetChangeCallback(fn func()) {
+	s.onChange = fn
+}
+
+// notifyChange 通知探测配置变化
+func (s *ProbeService) notifyChange() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// ListProbes 获取所有探测
+func (s *ProbeService) ListProbes() ([]*models.Probe, error) {
+	return s.probeRepo.List()
+}
+
+// GetProbe 获取探测
+func (s *ProbeService) GetProbe(id uint) (*models.Probe, error) {
+	return s.probeRepo.GetByID(id)
+}
+
+// CreateProbe 创建探测
+func (s *ProbeService) CreateProbe(p *models.Probe) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := s.probeRepo.Create(p); err != nil {
+		return fmt.Errorf("failed to create probe: %w", err)
+	}
+	s.notifyChange()
+	return nil
+}
+
+// UpdateProbe 更新探测
+func (s *ProbeService) UpdateProbe(p *models.Probe) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := s.probeRepo.Update(p); err != nil {
+		return fmt.Errorf("failed to update probe: %w", err)
+	}
+	s.notifyChange()
+	return nil
+}
+
+// DeleteProbe 删除探测（历史探测数据保留到过期清理）
+func (s *ProbeService) DeleteProbe(id uint) error {
+	if _, err := s.probeRepo.GetByID(id); err != nil {
+		return err
+	}
+	if err := s.probeRepo.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete probe: %w", err)
+	}
+	s.notifyChange()
+	return nil
+}
+
+// RunProbe 立即执行一次探测并记录结果
+func (s *ProbeService) RunProbe(ctx context.Context, p *models.Probe) (*probe.Result, error) {
+	var dialer proxy.Dialer
+	if p.ProxyID != nil && *p.ProxyID > 0 {
+		if s.dialers == nil {
+			return nil, fmt.Errorf("proxy support is not configured")
+		}
+		d, err := s.dialers.GetDialer(*p.ProxyID)
+		if err != nil {
+			result := &probe.Result{CheckedAt: time.Now(), Error: fmt.Sprintf("获取代理失败: %v", err)}
+			s.recordResult(p, result)
+			return result, nil
+		}
+		dialer = d
+	}
+
+	cfg := probe.Config{
+		Type:           probe.Type(p.Type),
+		Target:         p.Target,
+		Port:           p.Port,
+		DNSQuery:       p.DNSQuery,
+		HTTPMethod:     p.HTTPMethod,
+		ExpectedStatus: p.ExpectedStatus,
+		TLSSkipVerify:  p.TLSSkipVerify,
+		Timeout:        time.Duration(p.TimeoutMs) * time.Millisecond,
+	}
+	result := probe.Run(ctx, cfg, dialer)
+	s.recordResult(p, result)
+	return result, nil
+}
+
+// recordResult 将探测结果写入 InfluxDB 并更新探测的最近状态
+func (s *ProbeService) recordResult(p *models.Probe, result *probe.Result) {
+	status := "down"
+	success := 0.0
+	if result.Success {
+		status = "up"
+		success = 1
+	}
+
+	tags := map[string]string{
+		"probe_id":   strconv.FormatUint(uint64(p.ID), 10),
+		"probe_type": string(p.Type),
+		"target":     p.Target,
+	}
+	// 探测结果不关联设备，直接按探测所属组织打标签
+	if p.OrganizationID > 0 {
+		tags[tenant.InfluxTag] = strconv.FormatUint(uint64(p.OrganizationID), 10)
+	}
+
+	// 数值统一使用 float64，耗时单位为毫秒
+	fields := map[string]interface{}{
+		"status":  status,
+		"success": success,
+		"latency": float64(result.Latency.Microseconds()) / 1000,
+	}
+	if result.ConnectTime > 0 {
+		fields["connect_time"] = float64(result.ConnectTime.Microseconds()) / 1000
+	}
+	if result.StatusCode > 0 {
+		fields["status_code"] = float64(result.StatusCode)
+	}
+	if days, ok := result.TLSDaysLeft(); ok {
+		fields["tls_days_left"] = days
+	}
+	if result.Error != "" {
+		fields["error"] = result.Error
+	}
+
+	if s.influxClient != nil {
+		if err := s.influxClient.WritePoint("probe", tags, fields, result.CheckedAt); err != nil {
+			log.Printf("Failed to write probe result for probe %d: %v", p.ID, err)
+		}
+	}
+
+	lastError := result.Error
+	if len(lastError) > 500 {
+		lastError = lastError[:500]
+	}
+	if err := s.probeRepo.UpdateLastResult(p.ID, status, lastError, result.CheckedAt); err != nil {
+		log.Printf("Failed to update last result for probe %d: %v", p.ID, err)
+	}
+}
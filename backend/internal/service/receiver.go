@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// receiverRunner 管理接收服务（syslog、SNMP trap、流量）后台协程的启动和停止
+type receiverRunner struct {
+	mu       sync.Mutex
+	running  bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// start 调用 open 打开监听后启动 loops，loops 在 stop 通道关闭后退出
+// 已在运行或 open 失败时不启动协程，返回值表示本次是否启动
+func (r *receiverRunner) start(open func() error, loops ...func(stop <-chan struct{})) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return false, nil
+	}
+
+	if err := open(); err != nil {
+		return false, err
+	}
+	r.running = true
+	stop := make(chan struct{})
+	r.stopChan = stop
+
+	r.wg.Add(len(loops))
+	for _, loop := range loops {
+		go func(loop func(stop <-chan struct{})) {
+			defer r.wg.Done()
+			loop(stop)
+		}(loop)
+	}
+	return true, nil
+}
+
+// stop 调用 shutdown 关闭监听，通知后台协程退出并等待其结束
+// 未在运行时直接返回 false
+func (r *receiverRunner) stop(shutdown func()) bool {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return false
+	}
+	r.running = false
+	r.mu.Unlock()
+
+	shutdown()
+	close(r.stopChan)
+	r.wg.Wait()
+	return true
+}
+
+// receiveQueue 接收监听与写入之间的有界队列
+// 队列已满时丢弃新条目，避免消息风暴拖垮服务
+type receiveQueue[T any] struct {
+	name    string // 日志中的条目名称，如 "syslog messages"
+	items   chan T
+	dropped atomic.Int64
+}
+
+// newReceiveQueue 创建容量为 size 的接收队列
+func newReceiveQueue[T any](name string, size int) *receiveQueue[T] {
+	return &receiveQueue[T]{name: name, items: make(chan T, size)}
+}
+
+// push 放入队列，队列已满时丢弃并每 1000 条记录一次日志
+func (q *receiveQueue[T]) push(item T) {
+	select {
+	case q.items <- item:
+	default:
+		if dropped := q.dropped.Add(1); dropped%1000 == 1 {
+			log.Printf("Receive queue full, %d %s dropped", dropped, q.name)
+		}
+	}
+}
+
+// consume 按接收顺序逐条处理，stop 关闭后处理完队列中剩余的条目再返回
+func (q *receiveQueue[T]) consume(stop <-chan struct{}, handle func(T)) {
+	for {
+		select {
+		case item := <-q.items:
+			handle(item)
+		case <-stop:
+			for {
+				select {
+				case item := <-q.items:
+					handle(item)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// consumeBatches 攒批处理：达到 size 条或每隔 every 写入一次，stop 关闭后写入队列中剩余的条目再返回
+func (q *receiveQueue[T]) consumeBatches(stop <-chan struct{}, size int, every time.Duration, write func([]T)) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, size)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		write(batch)
+		batch = make([]T, 0, size)
+	}
+
+	for {
+		select {
+		case item := <-q.items:
+			batch = append(batch, item)
+			if len(batch) >= size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-stop:
+			for {
+				select {
+				case item := <-q.items:
+					batch = append(batch, item)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// retentionCleaner 按保留期定期删除过期记录，保留期不大于 0 时不清理
+type retentionCleaner struct {
+	name         string // 日志中的记录名称，如 "syslog messages"
+	retention    time.Duration
+	every        time.Duration
+	deleteBefore func(before time.Time) (int64, error)
+}
+
+// run 启动时立即清理一次，之后每隔 every 清理一次，直到 ctx 取消或 stop 关闭
+func (c *retentionCleaner) run(ctx context.Context, stop <-chan struct{}) {
+	if c.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.every)
+	defer ticker.Stop()
+
+	c.cleanup()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.cleanup()
+		}
+	}
+}
+
+// cleanup 删除超过保留期的记录
+func (c *retentionCleaner) cleanup() {
+	deleted, err := c.deleteBefore(time.Now().Add(-c.retention))
+	if err != nil {
+		log.Printf("Failed to clean up %s: %v", c.name, err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Cleaned up %d expired %s", deleted, c.name)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiveQueue_DropsWhenFull(t *testing.T) {
+	queue := newReceiveQueue[int]("items", 2)
+	for i := 1; i <= 3; i++ {
+		queue.push(i)
+	}
+	assert.Equal(t, int64(1), queue.dropped.Load())
+
+	// stop 已关闭时处理完剩余条目再返回
+	stop := make(chan struct{})
+	close(stop)
+	var handled []int
+	queue.consume(stop, func(item int) { handled = append(handled, item) })
+	assert.Equal(t, []int{1, 2}, handled)
+}
+
+func TestReceiverRunner_StartStop(t *testing.T) {
+	var runner receiverRunner
+
+	started, err := runner.start(func() error { return errors.New("address in use") })
+	assert.Error(t, err)
+	assert.False(t, started)
+	assert.False(t, runner.stop(func() {}))
+
+	exited := false
+	started, err = runner.start(func() error { return nil }, func(stop <-chan struct{}) {
+		<-stop
+		exited = true
+	})
+	require.NoError(t, err)
+	assert.True(t, started)
+
+	// 重复启动不会再启动协程
+	started, err = runner.start(func() error { return nil })
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	assert.True(t, runner.stop(func() {}))
+	assert.True(t, exited)
+}
+
+func TestRetentionCleaner(t *testing.T) {
+	var cutoff time.Time
+	cleaner := &retentionCleaner{
+		name:      "records",
+		retention: time.Hour,
+		every:     time.Hour,
+		deleteBefore: func(before time.Time) (int64, error) {
+			cutoff = before
+			return 1, nil
+		},
+	}
+
+	// 启动时立即清理一次，stop 关闭后退出
+	stop := make(chan struct{})
+	close(stop)
+	cleaner.run(context.Background(), stop)
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), cutoff, time.Minute)
+
+	// 保留期为 0 时不清理
+	cutoff = time.Time{}
+	cleaner.retention = 0
+	cleaner.run(context.Background(), stop)
+	assert.True(t, cutoff.IsZero())
+}
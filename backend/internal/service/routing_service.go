@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"sync"
+	"time"
+)
+
+// 路由邻居监控相关常量
+const (
+	routingCollectTimeout = 15 * time.Second
+	// routingFRRRetryAfter Linux 设备未安装 FRR 时，在此时间内不再尝试采集
+	routingFRRRetryAfter = time.Hour
+)
+
+// ErrRoutingUnsupported 设备类型不支持路由邻居采集
+var ErrRoutingUnsupported = errors.New("device type does not support routing neighbor collection")
+
+// RoutingService BGP/OSPF 邻居监控服务
+// 定时采集 RouterOS（API）和 Linux FRR（SSH + vtysh）的路由邻居，状态变化时记录事件，
+// 邻居离开 Established（BGP）或 Full（OSPF）状态时记录邻接丢失事件
+type RoutingService struct {
+	repo         repository.RoutingRepository
+	deviceRepo   repository.DeviceRepository
+	rosCollector *collector.RouterOSCollector
+	sshCollector *collector.SSHCollector
+	dialers      ProxyDialerProvider
+	interval     time.Duration
+	concurrency  int
+
+	// frrUnavailable 记录未安装 FRR 的 Linux 设备及下次重试时间
+	frrUnavailable map[uint]time.Time
+	frrMu          sync.Mutex
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+	mu       sync.Mutex
+}
+
+// NewRoutingService 创建路由邻居监控服务
+func NewRoutingService(
+	repo repository.RoutingRepository,
+	deviceRepo repository.DeviceRepository,
+	interval time.Duration,
+) *RoutingService {
+	return &RoutingService{
+		repo:           repo,
+		deviceRepo:     deviceRepo,
+		rosCollector:   collector.NewRouterOSCollector(routingCollectTimeout),
+		sshCollector:   collector.NewSSHCollector(routingCollectTimeout),
+		interval:       interval,
+		concurrency:    16,
+		frrUnavailable: make(map[uint]time.Time),
+		stopChan:       make(chan struct{}),
+	}
+}
+
+// SetDialerProvider 设置代理拨号器提供者，设备配置了代理时经代理连接
+func (s *RoutingService) SetDialerProvider(dialers ProxyDialerProvider) {
+	s.dialers = dialers
+}
+
+// Start 启动定时采集
+func (s *RoutingService) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.running || s.interval <= 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.stopChan = make(chan struct{})
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	log.Printf("Routing neighbor monitor started with interval %v", s.interval)
+}
+
+// Stop 停止定时采集，等待进行中的采集完成
+func (s *RoutingService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Routing neighbor monitor stopped")
+}
+
+// run 调度循环：启动后立即采集一次，之后按间隔执行
+func (s *RoutingService) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.RefreshAll()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.RefreshAll()
+		}
+	}
+}
+
+// RefreshAll 采集所有 RouterOS 和 Linux 设备的路由邻居
+// 最近确认未安装 FRR 的 Linux 设备会被跳过
+func (s *RoutingService) RefreshAll() {
+	var devices []*models.Device
+	for _, osType := range []models.DeviceOSType{models.DeviceOSTypeMikroTik, models.DeviceOSTypeLinux} {
+		list, err := s.deviceRepo.GetByOSType(osType)
+		if err != nil {
+			log.Printf("Failed to get %s devices for routing monitor: %v", osType, err)
+			continue
+		}
+		devices = append(devices, list...)
+	}
+
+	now := time.Now()
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for _, device := range devices {
+		if device.OSType == models.DeviceOSTypeLinux && s.frrSkipped(device.ID, now) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(device *models.Device) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if _, err := s.RefreshDevice(device); err != nil && !errors.Is(err, collector.ErrFRRUnavailable) {
+				log.Printf("Failed to refresh routing neighbors of device %d: %v", device.ID, err)
+			}
+		}(device)
+	}
+	wg.Wait()
+}
+
+// RefreshDevice 采集单台设备的路由邻居并保存状态变化
+// 采集失败时保留上次的邻居状态，不产生事件
+func (s *RoutingService) RefreshDevice(device *models.Device) ([]*models.RoutingNeighbor, error) {
+	observed, err := s.collect(device)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.repo.ListNeighbors(device.ID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current routing neighbors: %w", err)
+	}
+
+	neighbors, events := ReconcileRoutingNeighbors(device.ID, current, observed, time.Now())
+	for _, neighbor := range neighbors {
+		neighbor.OrganizationID = device.OrganizationID
+	}
+	for i := range events {
+		events[i].OrganizationID = device.OrganizationID
+	}
+	if err := s.repo.SaveNeighbors(neighbors, events); err != nil {
+		return nil, fmt.Errorf("failed to save routing neighbors: %w", err)
+	}
+	for _, event := range events {
+		if event.AdjacencyLost {
+			log.Printf("Routing adjacency lost on device %d (%s): %s neighbor %s %s -> %s",
+				device.ID, device.Name, event.Protocol, event.Neighbor, event.OldState, event.NewState)
+		}
+	}
+
+	return s.repo.ListNeighbors(device.ID, "")
+}
+
+// collect 通过采集器获取设备路由邻居
+func (s *RoutingService) collect(device *models.Device) ([]collector.RoutingNeighborInfo, error) {
+	switch device.OSType {
+	case models.DeviceOSTypeMikroTik:
+		client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+		if err != nil {
+			return nil, fmt.Errorf("无法连接到设备: %w", err)
+		}
+		defer client.Close()
+		return s.rosCollector.GetRoutingNeighbors(client)
+
+	case models.DeviceOSTypeLinux:
+		sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+		if err != nil {
+			return nil, fmt.Errorf("无法连接到设备: %w", err)
+		}
+		defer sshClient.Close()
+		neighbors, err := s.sshCollector.GetFRRRoutingNeighbors(sshClient)
+		s.frrMu.Lock()
+		if errors.Is(err, collector.ErrFRRUnavailable) {
+			s.frrUnavailable[device.ID] = time.Now().Add(routingFRRRetryAfter)
+		} else {
+			delete(s.frrUnavailable, device.ID)
+		}
+		s.frrMu.Unlock()
+		return neighbors, err
+
+	default:
+		return nil, ErrRoutingUnsupported
+	}
+}
+
+// frrSkipped 判断 Linux 设备是否处于 FRR 不可用的重试等待期
+func (s *RoutingService) frrSkipped(deviceID uint, now time.Time) bool {
+	s.frrMu.Lock()
+	defer s.frrMu.Unlock()
+	retryAt, ok := s.frrUnavailable[deviceID]
+	return ok && now.Before(retryAt)
+}
+
+// ListNeighbors 获取设备的路由邻居表，上下文中的组织限定查询范围
+func (s *RoutingService) ListNeighbors(ctx context.Context, deviceID uint, protocol models.RoutingProtocol) ([]*models.RoutingNeighbor, error) {
+	return tenant.Bind(ctx, s.repo).ListNeighbors(deviceID, protocol)
+}
+
+// GetNeighbor 获取路由邻居，上下文中的组织限定查询范围
+func (s *RoutingService) GetNeighbor(ctx context.Context, id uint) (*models.RoutingNeighbor, error) {
+	return tenant.Bind(ctx, s.repo).GetNeighbor(id)
+}
+
+// DeleteNeighbor 删除路由邻居，用于清理已从设备配置中移除的邻居，上下文中的组织限定删除范围
+func (s *RoutingService) DeleteNeighbor(ctx context.Context, id uint) error {
+	return tenant.Bind(ctx, s.repo).DeleteNeighbor(id)
+}
+
+// ListEvents 按条件查询路由邻居事件，上下文中的组织限定查询范围
+func (s *RoutingService) ListEvents(ctx context.Context, filter repository.RoutingEventFilter) ([]*repository.RoutingEventRecord, int64, error) {
+	return tenant.Bind(ctx, s.repo).ListEvents(filter)
+}
+
+// routingNeighborKey 路由邻居的唯一键
+func routingNeighborKey(protocol models.RoutingProtocol, instance, neighbor, iface string) string {
+	return fmt.Sprintf("%s|%s|%s|%s", protocol, instance, neighbor, iface)
+}
+
+// ReconcileRoutingNeighbors 将采集结果与已保存的邻居状态对比，返回需要保存的邻居和状态变化事件
+// 首次发现的邻居只记录基线状态，不产生事件；已保存但本次未出现的邻居标记为 down
+func ReconcileRoutingNeighbors(deviceID uint, current []*models.RoutingNeighbor, observed []collector.RoutingNeighborInfo, now time.Time) ([]*models.RoutingNeighbor, []models.RoutingNeighborEvent) {
+	existing := make(map[string]*models.RoutingNeighbor, len(current))
+	for _, neighbor := range current {
+		existing[routingNeighborKey(neighbor.Protocol, neighbor.Instance, neighbor.Neighbor, neighbor.Interface)] = neighbor
+	}
+
+	var neighbors []*models.RoutingNeighbor
+	var events []models.RoutingNeighborEvent
+	seen := make(map[string]bool, len(observed))
+
+	for _, info := range observed {
+		protocol := models.RoutingProtocol(info.Protocol)
+		key := routingNeighborKey(protocol, info.Instance, info.Neighbor, info.Interface)
+		if info.Neighbor == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		neighbor, ok := existing[key]
+		if !ok {
+			neighbor = &models.RoutingNeighbor{
+				DeviceID:   deviceID,
+				Protocol:   protocol,
+				Instance:   info.Instance,
+				Neighbor:   info.Neighbor,
+				Interface:  info.Interface,
+				LastChange: now,
+			}
+		} else if neighbor.State != info.State {
+			events = append(events, routingNeighborEvent(neighbor, info.State, info.Established, now))
+			neighbor.LastChange = now
+		}
+
+		neighbor.Name = info.Name
+		neighbor.Address = info.Address
+		neighbor.RemoteAS = info.RemoteAS
+		neighbor.LocalAS = info.LocalAS
+		neighbor.State = info.State
+		neighbor.Established = info.Established
+		neighbor.Uptime = info.Uptime
+		neighbor.PrefixCount = info.PrefixCount
+		neighbor.CollectedAt = now
+		neighbors = append(neighbors, neighbor)
+	}
+
+	for key, neighbor := range existing {
+		if seen[key] || neighbor.State == models.RoutingNeighborStateDown {
+			continue
+		}
+		events = append(events, routingNeighborEvent(neighbor, models.RoutingNeighborStateDown, false, now))
+		neighbor.State = models.RoutingNeighborStateDown
+		neighbor.Established = false
+		neighbor.Uptime = 0
+		neighbor.PrefixCount = 0
+		neighbor.LastChange = now
+		neighbor.CollectedAt = now
+		neighbors = append(neighbors, neighbor)
+	}
+
+	return neighbors, events
+}
+
+// routingNeighborEvent 构造邻居状态变化事件
+func routingNeighborEvent(neighbor *models.RoutingNeighbor, newState string, established bool, now time.Time) models.RoutingNeighborEvent {
+	return models.RoutingNeighborEvent{
+		DeviceID:      neighbor.DeviceID,
+		Protocol:      neighbor.Protocol,
+		Instance:      neighbor.Instance,
+		Neighbor:      neighbor.Neighbor,
+		Name:          neighbor.Name,
+		OldState:      neighbor.State,
+		NewState:      newState,
+		AdjacencyLost: neighbor.Established && !established,
+		OccurredAt:    now,
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/snmptrap"
+	"nmp-platform/internal/tenant"
+	"time"
+)
+
+// SNMP trap 接收相关常量
+const (
+	snmpTrapQueueSize    = 5000
+	snmpTrapSourceTTL    = 5 * time.Minute
+	snmpTrapCleanupEvery = time.Hour
+)
+
+// receivedTrap 待处理的 trap
+type receivedTrap struct {
+	trap       *snmptrap.Trap
+	source     net.IP
+	receivedAt time.Time
+}
+
+// SNMPTrapService SNMP trap/inform 接收与查询服务
+// trap 按来源 IP 归属到设备、按 ifIndex 关联接口；linkDown/linkUp 立即更新接口状态
+type SNMPTrapService struct {
+	repo          repository.SNMPTrapRepository
+	interfaceRepo repository.InterfaceRepository
+	linkState     *LinkStateService
+	sources       *sourceDeviceCache
+	server        *snmptrap.Server
+
+	queue   *receiveQueue[*receivedTrap]
+	cleaner *retentionCleaner
+	runner  receiverRunner
+}
+
+// NewSNMPTrapService 创建 SNMP trap 服务，cfg 中的 v3 用户配置无效时返回错误
+func NewSNMPTrapService(
+	repo repository.SNMPTrapRepository,
+	interfaceRepo repository.InterfaceRepository,
+	linkState *LinkStateService,
+	resolver DeviceResolver,
+	cfg snmptrap.Config,
+	retention time.Duration,
+) (*SNMPTrapService, error) {
+	s := &SNMPTrapService{
+		repo:          repo,
+		interfaceRepo: interfaceRepo,
+		linkState:     linkState,
+		sources:       newSourceDeviceCache(resolver, snmpTrapSourceTTL),
+		queue:         newReceiveQueue[*receivedTrap]("SNMP traps", snmpTrapQueueSize),
+		cleaner: &retentionCleaner{
+			name:         "SNMP traps",
+			retention:    retention,
+			every:        snmpTrapCleanupEvery,
+			deleteBefore: repo.DeleteBefore,
+		},
+	}
+
+	server, err := snmptrap.NewServer(cfg, s.HandleTrap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snmp trap config: %w", err)
+	}
+	s.server = server
+	return s, nil
+}
+
+// Start 启动 trap 监听、处理和过期清理
+// 按接收顺序逐条处理 trap，保证同一接口的状态变化有序
+func (s *SNMPTrapService) Start(ctx context.Context) error {
+	started, err := s.runner.start(s.server.Start,
+		func(stop <-chan struct{}) { s.queue.consume(stop, s.process) },
+		func(stop <-chan struct{}) { s.cleaner.run(ctx, stop) },
+	)
+	if started {
+		log.Printf("SNMP trap receiver started (udp=%v, engine_id=%x)", s.server.Addr(), s.server.EngineID())
+	}
+	return err
+}
+
+// Stop 停止监听，处理队列中剩余的 trap
+func (s *SNMPTrapService) Stop() {
+	if s.runner.stop(s.server.Stop) {
+		log.Println("SNMP trap receiver stopped")
+	}
+}
+
+// HandleTrap 接收 trap 并放入处理队列
+func (s *SNMPTrapService) HandleTrap(trap *snmptrap.Trap, source net.IP) {
+	s.queue.push(&receivedTrap{trap: trap, source: source, receivedAt: time.Now()})
+}
+
+// process 归属设备和接口、更新链路状态并保存 trap
+func (s *SNMPTrapService) process(item *receivedTrap) {
+	trap := item.trap
+	sourceIP := normalizeSourceIP(item.source)
+
+	varBinds, err := json.Marshal(trap.VarBinds)
+	if err != nil {
+		log.Printf("Failed to encode SNMP trap varbinds from %s: %v", sourceIP, err)
+		varBinds = []byte("[]")
+	}
+
+	deviceID, organizationID := s.sources.resolve(sourceIP)
+	record := &models.SNMPTrap{
+		OrganizationID: organizationID,
+		DeviceID:       deviceID,
+		SourceIP:       sourceIP,
+		Version:        trap.Version,
+		SecurityName:   truncateString(trap.User, 64),
+		TrapOID:        truncateString(trap.TrapOID, 255),
+		TrapName:       truncateString(trap.Name(), 100),
+		Inform:         trap.Inform,
+		Uptime:         int64(trap.Uptime),
+		VarBinds:       string(varBinds),
+		ReceivedAt:     item.receivedAt,
+	}
+	if index, ok := trap.IfIndex(); ok {
+		record.IfIndex = index
+	}
+	record.InterfaceName = truncateString(trap.IfName(), 100)
+
+	if record.DeviceID != nil {
+		if iface := s.resolveInterface(*record.DeviceID, trap); iface != nil {
+			id := iface.ID
+			record.InterfaceID = &id
+			record.InterfaceName = iface.Name
+			s.updateLinkState(*record.DeviceID, iface, trap, item.receivedAt)
+		}
+	}
+
+	if err := s.repo.Create(record); err != nil {
+		log.Printf("Failed to store SNMP trap from %s: %v", sourceIP, err)
+	}
+}
+
+// resolveInterface 按 ifIndex 关联接口，未同步 ifIndex 时退回到 ifName/ifDescr
+func (s *SNMPTrapService) resolveInterface(deviceID uint, trap *snmptrap.Trap) *models.Interface {
+	if index, ok := trap.IfIndex(); ok {
+		if iface, err := s.interfaceRepo.GetByDeviceIDAndIfIndex(deviceID, index); err == nil {
+			return iface
+		}
+	}
+	if name := trap.IfName(); name != "" {
+		if iface, err := s.interfaceRepo.GetByDeviceIDAndName(deviceID, name); err == nil {
+			return iface
+		}
+	}
+	return nil
+}
+
+// updateLinkState linkDown/linkUp 通知立即更新接口状态并记录状态变化事件
+func (s *SNMPTrapService) updateLinkState(deviceID uint, iface *models.Interface, trap *snmptrap.Trap, ts time.Time) {
+	var status models.InterfaceStatus
+	switch {
+	case trap.IsLinkDown():
+		status = models.InterfaceStatusDown
+	case trap.IsLinkUp():
+		status = models.InterfaceStatusUp
+	default:
+		return
+	}
+
+	states := map[string]models.InterfaceStatus{iface.Name: status}
+	if s.linkState != nil {
+		if err := s.linkState.RecordStates(deviceID, ts, states, models.InterfaceEventSourceTrap); err != nil {
+			log.Printf("Failed to record link state from trap for device %d: %v", deviceID, err)
+		}
+		return
+	}
+
+	iface.Status = status
+	if err := s.interfaceRepo.Update(iface); err != nil {
+		log.Printf("Failed to update interface %s status from trap: %v", iface.Name, err)
+	}
+}
+
+// Query 查询 trap，上下文中的组织限定查询范围
+func (s *SNMPTrapService) Query(ctx context.Context, filter repository.SNMPTrapFilter) ([]*models.SNMPTrap, int64, error) {
+	return tenant.Bind(ctx, s.repo).Query(filter)
+}
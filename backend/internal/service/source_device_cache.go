@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"net"
+	"nmp-platform/internal/models"
+	"sync"
+	"time"
+)
+
+// DeviceResolver 根据来源 IP 解析设备（由 DataReceiverService 实现）
+type DeviceResolver interface {
+	ValidateDeviceByIP(ctx context.Context, ip string) (*models.Device, error)
+}
+
+// sourceDevice 来源 IP 对应的设备（未匹配到设备时也缓存，避免每条消息都查库）
+type sourceDevice struct {
+	deviceID       *uint
+	organizationID uint
+	expiresAt      time.Time
+}
+
+// sourceDeviceCache syslog/trap 等被动接收数据的来源 IP 到设备的缓存
+type sourceDeviceCache struct {
+	resolver DeviceResolver
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	entries map[string]sourceDevice
+}
+
+// newSourceDeviceCache 创建来源设备缓存
+func newSourceDeviceCache(resolver DeviceResolver, ttl time.Duration) *sourceDeviceCache {
+	return &sourceDeviceCache{
+		resolver: resolver,
+		ttl:      ttl,
+		entries:  make(map[string]sourceDevice),
+	}
+}
+
+// resolve 根据来源 IP 解析设备 ID 和所属组织，结果缓存一段时间
+// 未匹配到设备时设备 ID 为空，组织为默认组织
+func (c *sourceDeviceCache) resolve(ip string) (*uint, uint) {
+	now := time.Now()
+	c.mu.Lock()
+	cached, ok := c.entries[ip]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.deviceID, cached.organizationID
+	}
+
+	entry := sourceDevice{organizationID: models.DefaultOrganizationID, expiresAt: now.Add(c.ttl)}
+	if c.resolver != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		if device, err := c.resolver.ValidateDeviceByIP(ctx, ip); err == nil && device != nil {
+			id := device.ID
+			entry.deviceID = &id
+			if device.OrganizationID != 0 {
+				entry.organizationID = device.OrganizationID
+			}
+		}
+		cancel()
+	}
+
+	c.mu.Lock()
+	c.entries[ip] = entry
+	c.mu.Unlock()
+	return entry.deviceID, entry.organizationID
+}
+
+// normalizeSourceIP IPv4 映射地址统一为点分格式
+func normalizeSourceIP(source net.IP) string {
+	if v4 := source.To4(); v4 != nil {
+		return v4.String()
+	}
+	return source.String()
+}
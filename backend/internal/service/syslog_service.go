@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"log"
+	"net"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/syslog"
+	"nmp-platform/internal/tenant"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// syslog 接收相关常量
+const (
+	syslogQueueSize      = 10000
+	syslogBatchSize      = 200
+	syslogFlushInterval  = time.Second
+	syslogSourceCacheTTL = 5 * time.Minute
+	syslogCleanupEvery   = time.Hour
+)
+
+// SyslogService syslog 接收与查询服务
+type SyslogService struct {
+	repo    repository.SyslogRepository
+	sources *sourceDeviceCache
+	server  *syslog.Server
+
+	queue   *receiveQueue[*models.SyslogMessage]
+	cleaner *retentionCleaner
+	runner  receiverRunner
+}
+
+// NewSyslogService 创建 syslog 服务
+// udpAddr/tcpAddr 为空时不监听对应协议，retention 为日志保留时长
+func NewSyslogService(repo repository.SyslogRepository, resolver DeviceResolver, udpAddr, tcpAddr string, retention time.Duration) *SyslogService {
+	s := &SyslogService{
+		repo:    repo,
+		sources: newSourceDeviceCache(resolver, syslogSourceCacheTTL),
+		queue:   newReceiveQueue[*models.SyslogMessage]("syslog messages", syslogQueueSize),
+		cleaner: &retentionCleaner{
+			name:         "syslog messages",
+			retention:    retention,
+			every:        syslogCleanupEvery,
+			deleteBefore: repo.DeleteBefore,
+		},
+	}
+	s.server = syslog.NewServer(udpAddr, tcpAddr, s.HandleMessage)
+	return s
+}
+
+// Start 启动 syslog 监听、批量写入和过期清理
+func (s *SyslogService) Start(ctx context.Context) error {
+	started, err := s.runner.start(s.server.Start,
+		func(stop <-chan struct{}) {
+			s.queue.consumeBatches(stop, syslogBatchSize, syslogFlushInterval, s.writeBatch)
+		},
+		func(stop <-chan struct{}) { s.cleaner.run(ctx, stop) },
+	)
+	if started {
+		log.Printf("Syslog receiver started (udp=%v, tcp=%v)", s.server.UDPAddr(), s.server.TCPAddr())
+	}
+	return err
+}
+
+// Stop 停止监听，写入队列中剩余的日志
+func (s *SyslogService) Stop() {
+	if s.runner.stop(s.server.Stop) {
+		log.Println("Syslog receiver stopped")
+	}
+}
+
+// HandleMessage 处理接收到的 syslog 消息，将其归属到设备后放入写入队列
+func (s *SyslogService) HandleMessage(msg *syslog.Message, source net.IP) {
+	sourceIP := normalizeSourceIP(source)
+
+	deviceID, organizationID := s.sources.resolve(sourceIP)
+	s.queue.push(&models.SyslogMessage{
+		OrganizationID: organizationID,
+		DeviceID:       deviceID,
+		SourceIP:       sourceIP,
+		Facility:       msg.Facility,
+		Severity:       models.SyslogSeverity(msg.Severity),
+		Hostname:       truncateString(msg.Hostname, 255),
+		AppName:        truncateString(msg.AppName, 100),
+		ProcID:         truncateString(msg.ProcID, 128),
+		MsgID:          truncateString(msg.MsgID, 32),
+		StructuredData: sanitizeString(msg.StructuredData),
+		Message:        sanitizeString(msg.Content),
+		Timestamp:      msg.Timestamp,
+		ReceivedAt:     time.Now(),
+	})
+}
+
+// writeBatch 批量写入日志，失败时逐条重试，单条无法写入的日志不影响同批其他日志
+func (s *SyslogService) writeBatch(batch []*models.SyslogMessage) {
+	err := s.repo.CreateBatch(batch)
+	if err == nil {
+		return
+	}
+	failed := 0
+	for i := range batch {
+		if err := s.repo.CreateBatch(batch[i : i+1]); err != nil {
+			failed++
+		}
+	}
+	log.Printf("Failed to store syslog batch of %d messages (%v), %d failed after retrying one by one", len(batch), err, failed)
+}
+
+// Query 查询 syslog 日志，上下文中的组织限定查询范围
+func (s *SyslogService) Query(ctx context.Context, filter repository.SyslogFilter) ([]*models.SyslogMessage, int64, error) {
+	return tenant.Bind(ctx, s.repo).Query(filter)
+}
+
+// sanitizeString 替换无效的 UTF-8 序列并去掉 NUL 字符，数据库文本字段不接受这两类内容
+func sanitizeString(value string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(value, "\uFFFD"), "\x00", "")
+}
+
+// truncateString 清理字符串后在字符边界截断到最多 max 字节，避免超出字段长度
+func truncateString(value string, max int) string {
+	value = sanitizeString(value)
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
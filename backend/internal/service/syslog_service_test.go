@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/syslog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySyslogRepository 含有坏消息的批次整体写入失败，记录成功写入的消息
+type flakySyslogRepository struct {
+	mu     sync.Mutex
+	stored []string
+}
+
+func (r *flakySyslogRepository) CreateBatch(messages []*models.SyslogMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range messages {
+		if strings.Contains(msg.Message, "bad") {
+			return errors.New("invalid row")
+		}
+	}
+	for _, msg := range messages {
+		r.stored = append(r.stored, msg.Message)
+	}
+	return nil
+}
+
+func (r *flakySyslogRepository) Query(filter repository.SyslogFilter) ([]*models.SyslogMessage, int64, error) {
+	return nil, 0, nil
+}
+
+func (r *flakySyslogRepository) DeleteBefore(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestTruncateString(t *testing.T) {
+	// 截断点落在多字节字符中间时退回到字符边界
+	assert.Equal(t, "ab", truncateString("ab设备", 4))
+	assert.Equal(t, "ab设", truncateString("ab设备", 5))
+	assert.Equal(t, "ab设备", truncateString("ab设备", 8))
+
+	// 无效 UTF-8 被替换，NUL 被去掉
+	got := truncateString("ab\xff\x00cd", 100)
+	assert.True(t, utf8.ValidString(got))
+	assert.Equal(t, "ab�cd", got)
+	assert.Equal(t, "ab", truncateString("ab\xffcd", 4))
+}
+
+func TestSyslogService_RetriesFailedBatchOneByOne(t *testing.T) {
+	repo := &flakySyslogRepository{}
+	s := NewSyslogService(repo, nil, "", "", 0)
+	require.NoError(t, s.Start(context.Background()))
+
+	source := net.ParseIP("10.0.0.1")
+	for _, content := range []string{"first", "bad", "third\xff"} {
+		s.HandleMessage(&syslog.Message{Content: content, Timestamp: time.Now()}, source)
+	}
+	s.Stop()
+
+	// 坏消息不影响同批其他消息写入
+	assert.Equal(t, []string{"first", "third�"}, repo.stored)
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"errors"
 
 	"nmp-platform/internal/models"
 	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
 )
 
 // TagService 标签服务接口
@@ -31,6 +33,11 @@ func NewTagService(tagRepo repository.TagRepository) TagService {
 	}
 }
 
+// WithContext 返回绑定上下文的标签服务，上下文中的组织限定标签的范围
+func (s *tagService) WithContext(ctx context.Context) TagService {
+	return &tagService{tagRepo: tenant.Bind(ctx, s.tagRepo)}
+}
+
 // CreateTag 创建标签
 func (s *tagService) CreateTag(tag *models.Tag) error {
 	if tag == nil {
@@ -0,0 +1,959 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"nmp-platform/internal/audit"
+	"nmp-platform/internal/collector"
+	"nmp-platform/internal/models"
+	"nmp-platform/internal/repository"
+	"nmp-platform/internal/tenant"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 升级相关常量
+const (
+	defaultUpgradeBatchSize     = 1
+	defaultUpgradeHealthTimeout = 600 // 秒
+	maxUpgradeHealthTimeout     = 3600
+	upgradeUploadConcurrency    = 4
+	upgradeCollectTimeout       = 15 * time.Second
+	upgradePollInterval         = 10 * time.Second
+	upgradeFetchURLTTL          = 30 * time.Minute
+)
+
+// 升级相关错误
+var (
+	ErrUpgradePackageExists  = errors.New("upgrade package already exists")
+	ErrUpgradePackageMissing = errors.New("upgrade package file is missing or corrupted")
+	ErrUpgradePackageInUse   = errors.New("upgrade package is used by a running job")
+	ErrUpgradePlanInvalid    = errors.New("upgrade plan has unresolved issues")
+	ErrUpgradeDeviceBusy     = errors.New("device is already being upgraded")
+	ErrUpgradeJobNotRunning  = errors.New("upgrade job is not running")
+)
+
+// DeviceHealthChecker 设备健康检查（由 DeviceStatusChecker 实现）
+type DeviceHealthChecker interface {
+	CheckSingleDevice(ctx context.Context, deviceID uint) (models.DeviceStatus, error)
+	GetLastSeen(ctx context.Context, deviceID uint) (time.Time, bool, error)
+}
+
+// UpgradeRequest 升级任务请求
+type UpgradeRequest struct {
+	Name            string `json:"name"`
+	PackageIDs      []uint `json:"package_ids" binding:"required"`
+	DeviceIDs       []uint `json:"device_ids" binding:"required"` // 按数组顺序滚动重启
+	BatchSize       int    `json:"batch_size"`                    // 每批同时重启的设备数，默认 1
+	HealthTimeout   int    `json:"health_timeout"`                // 重启后等待恢复的超时（秒），默认 600
+	RequirePush     *bool  `json:"require_push"`                  // 是否要求设备重启后重新推送数据，默认 true
+	UpgradeFirmware bool   `json:"upgrade_firmware"`              // 升级后同时升级 RouterBOOT 固件（需再次重启）
+}
+
+// UpgradePlanItem 升级计划中的单台设备
+type UpgradePlanItem struct {
+	DeviceID       uint     `json:"device_id"`
+	DeviceName     string   `json:"device_name"`
+	Position       int      `json:"position"`
+	CurrentVersion string   `json:"current_version"`
+	Architecture   string   `json:"architecture"`
+	Packages       []string `json:"packages"`
+	Issue          string   `json:"issue,omitempty"`
+}
+
+// UpgradePlan 升级计划（预检结果）
+type UpgradePlan struct {
+	TargetVersion string            `json:"target_version"`
+	Ready         bool              `json:"ready"`
+	Issues        int               `json:"issues"`
+	Items         []UpgradePlanItem `json:"items"`
+}
+
+// runningUpgradeJob 执行中任务的控制信息
+type runningUpgradeJob struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// UpgradeService RouterOS 升级编排服务
+// 管理平台本地保存的升级包，将升级包上传到设备后按顺序分批重启，每批恢复健康后再继续，遇到失败立即停止
+type UpgradeService struct {
+	repo          repository.UpgradeRepository
+	deviceRepo    repository.DeviceRepository
+	inventoryRepo repository.DeviceInventoryRepository
+	inventory     *InventoryService
+	health        DeviceHealthChecker
+	rosCollector  *collector.RouterOSCollector
+	sshCollector  *collector.SSHCollector
+	dialers       ProxyDialerProvider
+	packageDir    string
+	serverURL     string
+	fetchSecret   []byte // 设备通过 /tool fetch 下载升级包时的签名密钥
+	audit         *audit.Recorder
+
+	jobs map[uint]*runningUpgradeJob
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+// NewUpgradeService 创建 RouterOS 升级编排服务
+func NewUpgradeService(
+	repo repository.UpgradeRepository,
+	deviceRepo repository.DeviceRepository,
+	inventoryRepo repository.DeviceInventoryRepository,
+	inventory *InventoryService,
+	health DeviceHealthChecker,
+	packageDir string,
+	serverURL string,
+) *UpgradeService {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("Failed to generate upgrade fetch secret: %v", err)
+	}
+	return &UpgradeService{
+		repo:          repo,
+		deviceRepo:    deviceRepo,
+		inventoryRepo: inventoryRepo,
+		inventory:     inventory,
+		health:        health,
+		rosCollector:  collector.NewRouterOSCollector(upgradeCollectTimeout),
+		sshCollector:  collector.NewSSHCollector(upgradeCollectTimeout),
+		packageDir:    packageDir,
+		serverURL:     strings.TrimRight(serverURL, "/"),
+		fetchSecret:   secret,
+		jobs:          make(map[uint]*runningUpgradeJob),
+	}
+}
+
+// SetAuditRecorder 设置审计记录器，任务结束时记录执行结果
+func (s *UpgradeService) SetAuditRecorder(recorder *audit.Recorder) {
+	s.audit = recorder
+}
+
+// SetDialerProvider 设置代理拨号器提供者，设备配置了代理时经代理连接
+func (s *UpgradeService) SetDialerProvider(dialers ProxyDialerProvider) {
+	s.dialers = dialers
+}
+
+// Start 创建升级包目录，并将服务重启前未完成的任务标记为中断
+func (s *UpgradeService) Start(ctx context.Context) {
+	if err := os.MkdirAll(s.packageDir, 0755); err != nil {
+		log.Printf("Failed to create upgrade package directory %s: %v", s.packageDir, err)
+	}
+
+	count, err := s.repo.MarkInterrupted()
+	if err != nil {
+		log.Printf("Failed to mark interrupted upgrade jobs: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("Marked %d unfinished upgrade jobs as interrupted", count)
+	}
+}
+
+// Stop 停止所有任务并等待结束
+func (s *UpgradeService) Stop() {
+	s.mu.Lock()
+	for _, job := range s.jobs {
+		job.cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	log.Println("Upgrade service stopped")
+}
+
+// SavePackage 保存上传的升级包，文件名需符合 RouterOS 包命名规则
+func (s *UpgradeService) SavePackage(fileName string, content io.Reader, uploadedBy string) (*models.UpgradePackage, error) {
+	fileName = filepath.Base(fileName)
+	name, version, arch, err := collector.ParseRouterOSPackageName(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetPackageByFileName(fileName); err == nil {
+		return nil, ErrUpgradePackageExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check package: %w", err)
+	}
+
+	if err := os.MkdirAll(s.packageDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create package directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.packageDir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create package file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write package file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.packagePath(fileName)); err != nil {
+		return nil, fmt.Errorf("failed to save package file: %w", err)
+	}
+
+	pkg := &models.UpgradePackage{
+		FileName:     fileName,
+		Name:         name,
+		Version:      version,
+		Architecture: arch,
+		Size:         size,
+		SHA256:       hex.EncodeToString(hash.Sum(nil)),
+		UploadedBy:   uploadedBy,
+	}
+	if err := s.repo.CreatePackage(pkg); err != nil {
+		os.Remove(s.packagePath(fileName))
+		return nil, fmt.Errorf("failed to save package: %w", err)
+	}
+	return pkg, nil
+}
+
+// ListPackages 获取全部升级包
+func (s *UpgradeService) ListPackages() ([]*models.UpgradePackage, error) {
+	return s.repo.ListPackages()
+}
+
+// GetPackage 获取升级包
+func (s *UpgradeService) GetPackage(id uint) (*models.UpgradePackage, error) {
+	return s.repo.GetPackage(id)
+}
+
+// GetPackageByFileName 按文件名获取升级包
+func (s *UpgradeService) GetPackageByFileName(fileName string) (*models.UpgradePackage, error) {
+	return s.repo.GetPackageByFileName(fileName)
+}
+
+// DeletePackage 删除升级包及文件，执行中的任务使用的包不能删除
+func (s *UpgradeService) DeletePackage(id uint) error {
+	pkg, err := s.repo.GetPackage(id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	running := len(s.jobs)
+	s.mu.Unlock()
+	if running > 0 {
+		active, _, err := s.repo.ListJobs(repository.UpgradeJobFilter{})
+		if err != nil {
+			return err
+		}
+		for _, job := range active {
+			if job.Status != models.UpgradeJobStatusUploading && job.Status != models.UpgradeJobStatusRebooting {
+				continue
+			}
+			var ids []uint
+			json.Unmarshal([]byte(job.PackageIDs), &ids)
+			for _, pkgID := range ids {
+				if pkgID == id {
+					return ErrUpgradePackageInUse
+				}
+			}
+		}
+	}
+
+	if err := s.repo.DeletePackage(id); err != nil {
+		return err
+	}
+	if err := os.Remove(s.packagePath(pkg.FileName)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove upgrade package file %s: %v", pkg.FileName, err)
+	}
+	return nil
+}
+
+// OpenPackage 打开升级包文件
+func (s *UpgradeService) OpenPackage(pkg *models.UpgradePackage) (*os.File, error) {
+	return os.Open(s.packagePath(pkg.FileName))
+}
+
+// VerifyPackage 检查升级包文件存在且大小、SHA-256 与上传时一致
+func (s *UpgradeService) VerifyPackage(pkg *models.UpgradePackage) error {
+	file, err := s.OpenPackage(pkg)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUpgradePackageMissing, pkg.FileName)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil || size != pkg.Size || hex.EncodeToString(hash.Sum(nil)) != pkg.SHA256 {
+		return fmt.Errorf("%w: %s", ErrUpgradePackageMissing, pkg.FileName)
+	}
+	return nil
+}
+
+// packagePath 升级包在平台上的路径
+func (s *UpgradeService) packagePath(fileName string) string {
+	return filepath.Join(s.packageDir, filepath.Base(fileName))
+}
+
+// FetchURL 生成设备下载升级包的限时签名地址
+func (s *UpgradeService) FetchURL(pkg *models.UpgradePackage) string {
+	expires := time.Now().Add(upgradeFetchURLTTL).Unix()
+	return fmt.Sprintf("%s/api/v1/upgrade-packages/fetch/%s?expires=%d&signature=%s",
+		s.serverURL, url.PathEscape(pkg.FileName), expires, s.fetchSignature(pkg.FileName, expires))
+}
+
+// VerifyFetchSignature 校验升级包下载地址的签名和有效期
+func (s *UpgradeService) VerifyFetchSignature(fileName, expires, signature string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	expected := s.fetchSignature(fileName, expiresAt)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// fetchSignature 计算下载地址签名
+func (s *UpgradeService) fetchSignature(fileName string, expires int64) string {
+	mac := hmac.New(sha256.New, s.fetchSecret)
+	fmt.Fprintf(mac, "%s|%d", fileName, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Preview 预检升级计划：校验升级包文件、设备类型、架构和版本，为每台设备选择对应架构的包
+// 上下文中的组织限定可选设备范围
+func (s *UpgradeService) Preview(ctx context.Context, req *UpgradeRequest) (*UpgradePlan, error) {
+	if len(req.PackageIDs) == 0 || len(req.DeviceIDs) == 0 {
+		return nil, fmt.Errorf("package_ids and device_ids are required")
+	}
+
+	var packages []*models.UpgradePackage
+	for _, id := range req.PackageIDs {
+		pkg, err := s.repo.GetPackage(id)
+		if err != nil {
+			return nil, fmt.Errorf("upgrade package %d not found: %w", id, err)
+		}
+		if err := s.VerifyPackage(pkg); err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg)
+	}
+	// RouterOS 要求所有包版本一致
+	targetVersion := packages[0].Version
+	for _, pkg := range packages[1:] {
+		if pkg.Version != targetVersion {
+			return nil, fmt.Errorf("all packages must have the same version: %s vs %s", targetVersion, pkg.Version)
+		}
+	}
+
+	plan := &UpgradePlan{TargetVersion: targetVersion, Items: make([]UpgradePlanItem, 0, len(req.DeviceIDs))}
+	seen := make(map[uint]bool)
+	for position, deviceID := range req.DeviceIDs {
+		item := UpgradePlanItem{DeviceID: deviceID, Position: position}
+		plan.Items = append(plan.Items, item)
+		current := &plan.Items[len(plan.Items)-1]
+
+		if seen[deviceID] {
+			current.Issue = "设备重复"
+			continue
+		}
+		seen[deviceID] = true
+
+		device, err := tenant.Bind(ctx, s.deviceRepo).GetByID(deviceID)
+		if err != nil {
+			current.Issue = "设备不存在"
+			continue
+		}
+		current.DeviceName = device.Name
+		if device.OSType != models.DeviceOSTypeMikroTik {
+			current.Issue = "仅支持 MikroTik RouterOS 设备"
+			continue
+		}
+
+		inventory, err := tenant.Bind(ctx, s.inventoryRepo).GetByDeviceID(deviceID)
+		if err != nil || inventory.Architecture == "" {
+			current.Issue = "尚未采集设备清单，无法确定架构，请先刷新清单"
+			continue
+		}
+		current.CurrentVersion = inventory.OSVersion
+		current.Architecture = collector.RouterOSPackageArchitecture(inventory.Architecture)
+
+		hasRouterOS := false
+		for _, pkg := range packages {
+			if pkg.Architecture == current.Architecture {
+				current.Packages = append(current.Packages, pkg.FileName)
+				hasRouterOS = hasRouterOS || pkg.Name == "routeros"
+			}
+		}
+		switch {
+		case len(current.Packages) == 0:
+			current.Issue = fmt.Sprintf("没有 %s 架构的升级包", current.Architecture)
+		case !hasRouterOS:
+			current.Issue = fmt.Sprintf("缺少 %s 架构的 routeros 主包", current.Architecture)
+		case models.VersionSortKey(inventory.OSVersion) == models.VersionSortKey(targetVersion):
+			current.Issue = "设备已是目标版本"
+		case models.VersionSortKey(inventory.OSVersion) > models.VersionSortKey(targetVersion):
+			current.Issue = "目标版本低于当前版本，不支持降级"
+		}
+	}
+
+	for _, item := range plan.Items {
+		if item.Issue != "" {
+			plan.Issues++
+		}
+	}
+	plan.Ready = plan.Issues == 0
+	return plan, nil
+}
+
+// Submit 预检通过后创建升级任务并在后台执行，任务归属上下文中的组织
+func (s *UpgradeService) Submit(ctx context.Context, req *UpgradeRequest, userID uint, username string) (*models.UpgradeJob, *UpgradePlan, error) {
+	plan, err := s.Preview(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !plan.Ready {
+		return nil, plan, ErrUpgradePlanInvalid
+	}
+
+	busy, err := s.repo.ActiveDeviceIDs()
+	if err != nil {
+		return nil, plan, fmt.Errorf("failed to check running upgrades: %w", err)
+	}
+	for _, id := range busy {
+		for _, deviceID := range req.DeviceIDs {
+			if id == deviceID {
+				return nil, plan, fmt.Errorf("%w: device %d", ErrUpgradeDeviceBusy, deviceID)
+			}
+		}
+	}
+
+	batchSize := req.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultUpgradeBatchSize
+	}
+	healthTimeout := req.HealthTimeout
+	if healthTimeout <= 0 {
+		healthTimeout = defaultUpgradeHealthTimeout
+	}
+	if healthTimeout > maxUpgradeHealthTimeout {
+		healthTimeout = maxUpgradeHealthTimeout
+	}
+	requirePush := true
+	if req.RequirePush != nil {
+		requirePush = *req.RequirePush
+	}
+
+	packageIDs, _ := json.Marshal(req.PackageIDs)
+	now := time.Now()
+	job := &models.UpgradeJob{
+		Name:            req.Name,
+		PackageIDs:      string(packageIDs),
+		TargetVersion:   plan.TargetVersion,
+		BatchSize:       batchSize,
+		HealthTimeout:   healthTimeout,
+		RequirePush:     requirePush,
+		UpgradeFirmware: req.UpgradeFirmware,
+		Status:          models.UpgradeJobStatusUploading,
+		Total:           len(plan.Items),
+		CreatedBy:       userID,
+		CreatedByName:   username,
+		StartedAt:       &now,
+	}
+	for _, item := range plan.Items {
+		packages, _ := json.Marshal(item.Packages)
+		job.Devices = append(job.Devices, models.UpgradeJobDevice{
+			DeviceID:    item.DeviceID,
+			DeviceName:  item.DeviceName,
+			Position:    item.Position,
+			Packages:    string(packages),
+			Status:      models.UpgradeDeviceStatusPending,
+			FromVersion: item.CurrentVersion,
+		})
+	}
+	if err := tenant.Bind(ctx, s.repo).CreateJob(job); err != nil {
+		return nil, plan, fmt.Errorf("failed to create upgrade job: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.jobs[job.ID] = &runningUpgradeJob{cancel: cancel}
+	s.mu.Unlock()
+
+	log.Printf("Upgrade job %d started by %s (user %d): %d devices to RouterOS %s",
+		job.ID, username, userID, job.Total, job.TargetVersion)
+
+	s.wg.Add(1)
+	go s.run(runCtx, job)
+
+	return job, plan, nil
+}
+
+// Cancel 取消升级任务：不再重启后续设备，已重启的设备停止等待恢复
+func (s *UpgradeService) Cancel(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrUpgradeJobNotRunning
+	}
+	job.cancelled = true
+	job.cancel()
+	return nil
+}
+
+// GetJob 获取升级任务及设备进度和日志，上下文中的组织限定查询范围
+func (s *UpgradeService) GetJob(ctx context.Context, id uint) (*models.UpgradeJob, error) {
+	return tenant.Bind(ctx, s.repo).GetJob(id)
+}
+
+// ListJobs 查询升级任务，上下文中的组织限定查询范围
+func (s *UpgradeService) ListJobs(ctx context.Context, filter repository.UpgradeJobFilter) ([]*models.UpgradeJob, int64, error) {
+	return tenant.Bind(ctx, s.repo).ListJobs(filter)
+}
+
+// run 执行升级任务：上传阶段全部成功后才开始滚动重启，任一设备失败立即停止
+func (s *UpgradeService) run(ctx context.Context, job *models.UpgradeJob) {
+	defer s.wg.Done()
+
+	failure := s.uploadAll(ctx, job)
+	if failure == "" && ctx.Err() == nil {
+		job.Status = models.UpgradeJobStatusRebooting
+		s.saveJob(job)
+		failure = s.rollingReboot(ctx, job)
+	}
+
+	// 停止后清理已上传但未重启设备上的升级包，避免设备下次重启时意外升级
+	for i := range job.Devices {
+		device := &job.Devices[i]
+		switch device.Status {
+		case models.UpgradeDeviceStatusUploaded:
+			s.removeUploadedPackages(device)
+			s.finishDevice(device, models.UpgradeDeviceStatusSkipped, "任务已停止，未重启")
+		case models.UpgradeDeviceStatusPending:
+			s.finishDevice(device, models.UpgradeDeviceStatusSkipped, "任务已停止，未执行")
+		}
+	}
+
+	s.mu.Lock()
+	running := s.jobs[job.ID]
+	delete(s.jobs, job.ID)
+	s.mu.Unlock()
+	interrupted := ctx.Err() != nil
+	running.cancel()
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Error = failure
+	switch {
+	case failure != "":
+		job.Status = models.UpgradeJobStatusFailed
+	case running.cancelled:
+		job.Status = models.UpgradeJobStatusCancelled
+	case interrupted:
+		job.Status = models.UpgradeJobStatusInterrupted
+	default:
+		job.Status = models.UpgradeJobStatusCompleted
+	}
+	s.saveJob(job)
+
+	log.Printf("Upgrade job %d %s: %d succeeded, %d failed", job.ID, job.Status, job.Succeeded, job.Failed)
+
+	var jobErr error
+	if job.Status != models.UpgradeJobStatusCompleted {
+		jobErr = fmt.Errorf("upgrade job %s", job.Status)
+		if failure != "" {
+			jobErr = fmt.Errorf("upgrade job %s: %s", job.Status, failure)
+		}
+	}
+	s.audit.RecordJob(job.OrganizationID, "upgrade-jobs.finish", "upgrade-jobs", strconv.FormatUint(uint64(job.ID), 10),
+		fmt.Sprintf("succeeded=%d | failed=%d", job.Succeeded, job.Failed), jobErr)
+}
+
+// uploadAll 以有限并发将升级包上传到全部设备，返回首个失败原因
+func (s *UpgradeService) uploadAll(ctx context.Context, job *models.UpgradeJob) string {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		failure string
+	)
+	sem := make(chan struct{}, upgradeUploadConcurrency)
+	for i := range job.Devices {
+		device := &job.Devices[i]
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failure != "" || ctx.Err() != nil
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := s.uploadDevice(device); err != nil {
+				s.failDevice(job, device, err, &mu)
+				mu.Lock()
+				if failure == "" {
+					failure = fmt.Sprintf("设备 %s 上传升级包失败: %v", device.DeviceName, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failure
+}
+
+// uploadDevice 上传升级包到单台设备：优先 SSH（SCP），失败时通过 API 让设备从平台下载
+func (s *UpgradeService) uploadDevice(jobDevice *models.UpgradeJobDevice) error {
+	started := time.Now()
+	jobDevice.StartedAt = &started
+	jobDevice.Status = models.UpgradeDeviceStatusUploading
+	s.appendLog(jobDevice, "开始上传升级包")
+
+	device, err := s.deviceRepo.GetByID(jobDevice.DeviceID)
+	if err != nil {
+		return fmt.Errorf("设备不存在: %w", err)
+	}
+	var fileNames []string
+	json.Unmarshal([]byte(jobDevice.Packages), &fileNames)
+
+	for _, fileName := range fileNames {
+		pkg, err := s.repo.GetPackageByFileName(fileName)
+		if err != nil {
+			return fmt.Errorf("升级包 %s 不存在: %w", fileName, err)
+		}
+
+		sshErr := s.uploadViaSSH(device, pkg)
+		if sshErr == nil {
+			s.appendLog(jobDevice, "通过 SSH 上传 %s 成功（%d 字节）", pkg.FileName, pkg.Size)
+			continue
+		}
+		s.appendLog(jobDevice, "通过 SSH 上传 %s 失败: %v，尝试通过 API 下载", pkg.FileName, sshErr)
+
+		if apiErr := s.uploadViaAPI(device, pkg); apiErr != nil {
+			return fmt.Errorf("上传 %s 失败: SSH 错误: %v; API 错误: %v", pkg.FileName, sshErr, apiErr)
+		}
+		s.appendLog(jobDevice, "通过 API 下载 %s 成功", pkg.FileName)
+	}
+
+	jobDevice.Status = models.UpgradeDeviceStatusUploaded
+	s.appendLog(jobDevice, "升级包上传完成，等待重启")
+	return nil
+}
+
+// uploadViaSSH 通过 SCP 上传升级包
+func (s *UpgradeService) uploadViaSSH(device *models.Device, pkg *models.UpgradePackage) error {
+	file, err := s.OpenPackage(pkg)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	client, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return s.sshCollector.UploadFile(client, pkg.FileName, file, pkg.Size)
+}
+
+// uploadViaAPI 通过 API 让设备使用限时签名地址从平台下载升级包
+func (s *UpgradeService) uploadViaAPI(device *models.Device, pkg *models.UpgradePackage) error {
+	client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return s.rosCollector.FetchFile(client, s.FetchURL(pkg), pkg.FileName)
+}
+
+// removeUploadedPackages 删除设备上已上传但不再安装的升级包
+func (s *UpgradeService) removeUploadedPackages(jobDevice *models.UpgradeJobDevice) {
+	device, err := s.deviceRepo.GetByID(jobDevice.DeviceID)
+	if err != nil {
+		return
+	}
+	var fileNames []string
+	json.Unmarshal([]byte(jobDevice.Packages), &fileNames)
+
+	client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+	if err == nil {
+		defer client.Close()
+		for _, fileName := range fileNames {
+			client.Run("/file/remove", "=numbers="+fileName)
+		}
+		s.appendLog(jobDevice, "已删除设备上的升级包")
+		return
+	}
+
+	sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		s.appendLog(jobDevice, "删除设备上的升级包失败，请手动删除以免下次重启时升级: %v", err)
+		return
+	}
+	defer sshClient.Close()
+	for _, fileName := range fileNames {
+		s.sshCollector.RunCommand(context.Background(), sshClient, fmt.Sprintf(`/file remove [find name="%s"]`, fileName))
+	}
+	s.appendLog(jobDevice, "已删除设备上的升级包")
+}
+
+// rollingReboot 按顺序分批重启设备并等待恢复，返回首个失败原因
+func (s *UpgradeService) rollingReboot(ctx context.Context, job *models.UpgradeJob) string {
+	for start := 0; start < len(job.Devices); start += job.BatchSize {
+		if ctx.Err() != nil {
+			return ""
+		}
+		end := start + job.BatchSize
+		if end > len(job.Devices) {
+			end = len(job.Devices)
+		}
+
+		var (
+			mu      sync.Mutex
+			wg      sync.WaitGroup
+			failure string
+		)
+		for i := start; i < end; i++ {
+			device := &job.Devices[i]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := s.upgradeDevice(ctx, job, device); err != nil {
+					if errors.Is(err, context.Canceled) {
+						s.appendLog(device, "任务已停止，未确认设备恢复")
+						s.finishDevice(device, models.UpgradeDeviceStatusSkipped, "任务已停止，未确认设备恢复")
+						return
+					}
+					s.failDevice(job, device, err, &mu)
+					mu.Lock()
+					if failure == "" {
+						failure = fmt.Sprintf("设备 %s 升级失败: %v", device.DeviceName, err)
+					}
+					mu.Unlock()
+					return
+				}
+				mu.Lock()
+				job.Succeeded++
+				mu.Unlock()
+				s.finishDevice(device, models.UpgradeDeviceStatusSuccess, "")
+			}()
+		}
+		wg.Wait()
+		s.saveJob(job)
+
+		if failure != "" {
+			return failure
+		}
+	}
+	return ""
+}
+
+// upgradeDevice 重启单台设备安装升级包，等待恢复健康并校验版本，按需升级固件
+func (s *UpgradeService) upgradeDevice(ctx context.Context, job *models.UpgradeJob, jobDevice *models.UpgradeJobDevice) error {
+	device, err := s.deviceRepo.GetByID(jobDevice.DeviceID)
+	if err != nil {
+		return fmt.Errorf("设备不存在: %w", err)
+	}
+
+	jobDevice.Status = models.UpgradeDeviceStatusRebooting
+	version, err := s.rebootAndWait(ctx, job, jobDevice, device)
+	if err != nil {
+		return err
+	}
+	jobDevice.ToVersion = version
+	if models.VersionSortKey(version) != models.VersionSortKey(job.TargetVersion) {
+		return fmt.Errorf("重启后版本为 %s，预期 %s，升级包可能未安装", version, job.TargetVersion)
+	}
+	s.appendLog(jobDevice, "RouterOS 已升级到 %s", version)
+
+	if job.UpgradeFirmware {
+		upgraded, err := s.upgradeFirmware(device)
+		if err != nil {
+			return err
+		}
+		if upgraded {
+			s.appendLog(jobDevice, "RouterBOOT 固件已升级，重启使其生效")
+			if _, err := s.rebootAndWait(ctx, job, jobDevice, device); err != nil {
+				return err
+			}
+			s.appendLog(jobDevice, "RouterBOOT 固件升级完成")
+		} else {
+			s.appendLog(jobDevice, "RouterBOOT 固件无需升级")
+		}
+	}
+
+	if s.inventory != nil {
+		if _, err := s.inventory.RefreshDevice(device); err != nil {
+			s.appendLog(jobDevice, "刷新设备清单失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// upgradeFirmware 升级 RouterBOOT 固件，返回是否需要重启
+func (s *UpgradeService) upgradeFirmware(device *models.Device) (bool, error) {
+	client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+	if err == nil {
+		defer client.Close()
+		return s.rosCollector.UpgradeRouterboardFirmware(client)
+	}
+
+	sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		return false, fmt.Errorf("无法连接到设备: %w", err)
+	}
+	defer sshClient.Close()
+	inventory, err := s.sshCollector.GetMikroTikInventory(sshClient)
+	if err != nil || inventory.FirmwareVersion == "" || inventory.FirmwareVersion == inventory.UpgradeFirmware {
+		return false, nil
+	}
+	if err := s.sshCollector.UpgradeMikroTikFirmware(sshClient); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// rebootAndWait 重启设备并等待恢复：设备运行时间表明已重启，且（按需）重新推送数据后返回当前版本
+func (s *UpgradeService) rebootAndWait(ctx context.Context, job *models.UpgradeJob, jobDevice *models.UpgradeJobDevice, device *models.Device) (string, error) {
+	rebootAt := time.Now()
+	if err := s.reboot(device); err != nil {
+		return "", err
+	}
+	s.appendLog(jobDevice, "已发送重启命令，等待设备恢复（超时 %d 秒）", job.HealthTimeout)
+
+	deadline := rebootAt.Add(time.Duration(job.HealthTimeout) * time.Second)
+	waitingPush := false
+	lastState := "设备未响应"
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(upgradePollInterval):
+		}
+
+		info, err := s.systemInfo(device)
+		if err == nil && time.Duration(info.Uptime)*time.Second < time.Since(rebootAt) {
+			if !job.RequirePush {
+				s.appendLog(jobDevice, "设备已恢复在线，当前版本 %s", info.Version)
+				return info.Version, nil
+			}
+			if !waitingPush {
+				waitingPush = true
+				s.appendLog(jobDevice, "设备已重启（版本 %s），等待重新推送数据", info.Version)
+			}
+			if s.pushedSince(device.ID, rebootAt) {
+				s.appendLog(jobDevice, "设备已恢复在线并重新推送数据")
+				return info.Version, nil
+			}
+			lastState = "设备已重启但未重新推送数据"
+		} else if err == nil {
+			lastState = "设备尚未重启"
+		}
+
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("等待设备恢复超时（%d 秒）: %s", job.HealthTimeout, lastState)
+		}
+	}
+}
+
+// pushedSince 通过设备状态检查器判断设备在线且在指定时间后推送过数据
+func (s *UpgradeService) pushedSince(deviceID uint, since time.Time) bool {
+	if s.health == nil {
+		return false
+	}
+	ctx := context.Background()
+	status, err := s.health.CheckSingleDevice(ctx, deviceID)
+	if err != nil || status != models.DeviceStatusOnline {
+		return false
+	}
+	lastSeen, ok, err := s.health.GetLastSeen(ctx, deviceID)
+	return err == nil && ok && lastSeen.After(since)
+}
+
+// reboot 重启设备，优先使用 API，失败则使用 SSH
+func (s *UpgradeService) reboot(device *models.Device) error {
+	client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+	if err == nil {
+		defer client.Close()
+		return s.rosCollector.Reboot(client)
+	}
+
+	sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		return fmt.Errorf("无法连接到设备: %w", err)
+	}
+	defer sshClient.Close()
+	return s.sshCollector.RebootMikroTik(sshClient)
+}
+
+// systemInfo 获取设备系统信息，优先使用 API，失败则使用 SSH
+func (s *UpgradeService) systemInfo(device *models.Device) (*collector.SystemInfo, error) {
+	client, err := connectDeviceAPI(s.dialers, s.rosCollector, device)
+	if err == nil {
+		defer client.Close()
+		if info, err := s.rosCollector.GetSystemInfo(client); err == nil {
+			return info, nil
+		}
+	}
+
+	sshClient, err := connectDeviceSSH(s.dialers, s.sshCollector, device)
+	if err != nil {
+		return nil, err
+	}
+	defer sshClient.Close()
+	return s.sshCollector.GetMikroTikSystemInfo(sshClient)
+}
+
+// failDevice 记录设备失败
+func (s *UpgradeService) failDevice(job *models.UpgradeJob, device *models.UpgradeJobDevice, err error, mu *sync.Mutex) {
+	mu.Lock()
+	job.Failed++
+	mu.Unlock()
+	s.appendLog(device, "失败: %v", err)
+	s.finishDevice(device, models.UpgradeDeviceStatusFailed, err.Error())
+}
+
+// finishDevice 设置设备最终状态并保存
+func (s *UpgradeService) finishDevice(device *models.UpgradeJobDevice, status models.UpgradeDeviceStatus, message string) {
+	finished := time.Now()
+	device.Status = status
+	device.Error = message
+	device.FinishedAt = &finished
+	if err := s.repo.UpdateDevice(device); err != nil {
+		log.Printf("Failed to update upgrade job %d device %d: %v", device.JobID, device.DeviceID, err)
+	}
+}
+
+// appendLog 追加设备升级日志并保存
+func (s *UpgradeService) appendLog(device *models.UpgradeJobDevice, format string, args ...interface{}) {
+	device.Log += fmt.Sprintf("%s %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+	if err := s.repo.UpdateDevice(device); err != nil {
+		log.Printf("Failed to update upgrade job %d device %d: %v", device.JobID, device.DeviceID, err)
+	}
+}
+
+// saveJob 保存任务状态
+func (s *UpgradeService) saveJob(job *models.UpgradeJob) {
+	if err := s.repo.UpdateJob(job); err != nil {
+		log.Printf("Failed to update upgrade job %d: %v", job.ID, err)
+	}
+}
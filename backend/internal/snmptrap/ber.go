@@ -0,0 +1,284 @@
+package snmptrap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BER 标签
+const (
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagNull        = 0x05
+	tagOID         = 0x06
+	tagSequence    = 0x30
+
+	tagIPAddress       = 0x40
+	tagCounter32       = 0x41
+	tagGauge32         = 0x42
+	tagTimeTicks       = 0x43
+	tagOpaque          = 0x44
+	tagCounter64       = 0x46
+	tagNoSuchObject    = 0x80
+	tagNoSuchInstance  = 0x81
+	tagEndOfMibView    = 0x82
+	maxBERLengthOctets = 4
+)
+
+var errTruncated = errors.New("truncated BER data")
+
+// berReader 顺序读取 BER 编码数据
+// 偏移量均为相对原始报文的绝对位置，便于定位 USM 认证参数
+type berReader struct {
+	data []byte
+	pos  int
+	end  int
+}
+
+// newBERReader 创建读取整个缓冲区的读取器
+func newBERReader(data []byte) *berReader {
+	return &berReader{data: data, end: len(data)}
+}
+
+// more 是否还有未读取的数据
+func (r *berReader) more() bool {
+	return r.pos < r.end
+}
+
+// readTLV 读取一个 TLV，返回标签以及值在原始数据中的起止位置
+func (r *berReader) readTLV() (byte, int, int, error) {
+	if r.pos+2 > r.end {
+		return 0, 0, 0, errTruncated
+	}
+	tag := r.data[r.pos]
+	r.pos++
+
+	length := int(r.data[r.pos])
+	r.pos++
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > maxBERLengthOctets || r.pos+n > r.end {
+			return 0, 0, 0, fmt.Errorf("invalid BER length")
+		}
+		length = 0
+		for i := 0; i < n; i++ {
+			length = length<<8 | int(r.data[r.pos])
+			r.pos++
+		}
+	}
+	if length < 0 || r.pos+length > r.end {
+		return 0, 0, 0, errTruncated
+	}
+
+	start := r.pos
+	r.pos += length
+	return tag, start, r.pos, nil
+}
+
+// expect 读取指定标签的 TLV
+func (r *berReader) expect(tag byte) (int, int, error) {
+	t, start, end, err := r.readTLV()
+	if err != nil {
+		return 0, 0, err
+	}
+	if t != tag {
+		return 0, 0, fmt.Errorf("unexpected BER tag 0x%02x, want 0x%02x", t, tag)
+	}
+	return start, end, nil
+}
+
+// sub 返回读取指定区间的子读取器
+func (r *berReader) sub(start, end int) *berReader {
+	return &berReader{data: r.data, pos: start, end: end}
+}
+
+// sequence 读取 SEQUENCE（或指定的构造类型）并返回其内容读取器
+func (r *berReader) sequence(tag byte) (*berReader, error) {
+	start, end, err := r.expect(tag)
+	if err != nil {
+		return nil, err
+	}
+	return r.sub(start, end), nil
+}
+
+// readInt 读取 INTEGER
+func (r *berReader) readInt() (int64, error) {
+	start, end, err := r.expect(tagInteger)
+	if err != nil {
+		return 0, err
+	}
+	return decodeInt(r.data[start:end])
+}
+
+// readOctets 读取 OCTET STRING
+func (r *berReader) readOctets() ([]byte, error) {
+	start, end, err := r.expect(tagOctetString)
+	if err != nil {
+		return nil, err
+	}
+	return r.data[start:end], nil
+}
+
+// decodeInt 解码有符号整数
+func decodeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, fmt.Errorf("invalid integer length %d", len(b))
+	}
+	v := int64(int8(b[0]))
+	for _, c := range b[1:] {
+		v = v<<8 | int64(c)
+	}
+	return v, nil
+}
+
+// decodeUint 解码无符号整数（Counter/Gauge/TimeTicks 可能带前导 0）
+func decodeUint(b []byte) (uint64, error) {
+	if len(b) == 0 || len(b) > 9 || (len(b) == 9 && b[0] != 0) {
+		return 0, fmt.Errorf("invalid unsigned length %d", len(b))
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// decodeOID 解码 OBJECT IDENTIFIER 为点分字符串
+func decodeOID(b []byte) (string, error) {
+	if len(b) == 0 {
+		return "", errors.New("empty oid")
+	}
+	var arcs []uint64
+	var v uint64
+	for i, c := range b {
+		if v > 1<<56 {
+			return "", errors.New("oid arc overflow")
+		}
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			if i == len(b)-1 {
+				return "", errors.New("truncated oid")
+			}
+			continue
+		}
+		if len(arcs) == 0 {
+			// 第一个子标识符编码了前两个弧
+			switch {
+			case v < 40:
+				arcs = append(arcs, 0, v)
+			case v < 80:
+				arcs = append(arcs, 1, v-40)
+			default:
+				arcs = append(arcs, 2, v-80)
+			}
+		} else {
+			arcs = append(arcs, v)
+		}
+		v = 0
+	}
+
+	parts := make([]string, len(arcs))
+	for i, arc := range arcs {
+		parts[i] = strconv.FormatUint(arc, 10)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// encodeLength 编码长度
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var buf []byte
+	for v := n; v > 0; v >>= 8 {
+		buf = append([]byte{byte(v)}, buf...)
+	}
+	return append([]byte{0x80 | byte(len(buf))}, buf...)
+}
+
+// encodeTLV 编码 TLV
+func encodeTLV(tag byte, value []byte) []byte {
+	out := append([]byte{tag}, encodeLength(len(value))...)
+	return append(out, value...)
+}
+
+// encodeSequence 编码构造类型
+func encodeSequence(tag byte, items ...[]byte) []byte {
+	var value []byte
+	for _, item := range items {
+		value = append(value, item...)
+	}
+	return encodeTLV(tag, value)
+}
+
+// encodeInt 编码有符号整数
+func encodeInt(v int64) []byte {
+	return encodeTLV(tagInteger, intBytes(v))
+}
+
+// intBytes 最短的二进制补码表示
+func intBytes(v int64) []byte {
+	buf := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		buf = append([]byte{byte(v)}, buf...)
+	}
+	return buf
+}
+
+// encodeUint 编码无符号整数类型（Counter32/Gauge32/TimeTicks/Counter64）
+func encodeUint(tag byte, v uint64) []byte {
+	var buf []byte
+	for {
+		buf = append([]byte{byte(v)}, buf...)
+		v >>= 8
+		if v == 0 {
+			break
+		}
+	}
+	if buf[0]&0x80 != 0 {
+		buf = append([]byte{0}, buf...)
+	}
+	return encodeTLV(tag, buf)
+}
+
+// encodeOctets 编码 OCTET STRING
+func encodeOctets(b []byte) []byte {
+	return encodeTLV(tagOctetString, b)
+}
+
+// encodeOID 编码 OBJECT IDENTIFIER
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid oid %q", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid oid %q", oid)
+		}
+		arcs[i] = v
+	}
+	if arcs[0] > 2 || (arcs[0] < 2 && arcs[1] >= 40) {
+		return nil, fmt.Errorf("invalid oid %q", oid)
+	}
+
+	value := encodeBase128(arcs[0]*40 + arcs[1])
+	for _, arc := range arcs[2:] {
+		value = append(value, encodeBase128(arc)...)
+	}
+	return encodeTLV(tagOID, value), nil
+}
+
+// encodeBase128 编码 OID 子标识符
+func encodeBase128(v uint64) []byte {
+	buf := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		buf = append([]byte{byte(v&0x7f) | 0x80}, buf...)
+	}
+	return buf
+}
@@ -0,0 +1,532 @@
+package snmptrap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+)
+
+// SNMP 版本
+const (
+	versionV1  = 0
+	versionV2c = 1
+	versionV3  = 3
+)
+
+// PDU 类型
+const (
+	pduGetRequest = 0xa0
+	pduResponse   = 0xa2
+	pduTrapV1     = 0xa4
+	pduInform     = 0xa6
+	pduTrapV2     = 0xa7
+	pduReport     = 0xa8
+)
+
+// SNMPv3 消息标志
+const (
+	flagAuth       = 0x01
+	flagPriv       = 0x02
+	flagReportable = 0x04
+)
+
+// usmSecurityModel USM 安全模型编号
+const usmSecurityModel = 3
+
+// 常用 OID
+const (
+	OIDSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	OIDSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+	OIDColdStart   = "1.3.6.1.6.3.1.1.5.1"
+	OIDWarmStart   = "1.3.6.1.6.3.1.1.5.2"
+	OIDLinkDown    = "1.3.6.1.6.3.1.1.5.3"
+	OIDLinkUp      = "1.3.6.1.6.3.1.1.5.4"
+	OIDAuthFailure = "1.3.6.1.6.3.1.1.5.5"
+
+	// 接口表列（后缀为 ifIndex）
+	OIDIfIndex       = "1.3.6.1.2.1.2.2.1.1"
+	OIDIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	OIDIfAdminStatus = "1.3.6.1.2.1.2.2.1.7"
+	OIDIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+	OIDIfName        = "1.3.6.1.2.1.31.1.1.1.1"
+
+	oidUsmStatsNotInTime   = "1.3.6.1.6.3.15.1.1.2.0"
+	oidUsmStatsUnknownUser = "1.3.6.1.6.3.15.1.1.3.0"
+	oidUsmStatsUnknownEng  = "1.3.6.1.6.3.15.1.1.4.0"
+	oidUsmStatsWrongDigest = "1.3.6.1.6.3.15.1.1.5.0"
+)
+
+// wellKnownTraps 标准 trap 名称
+var wellKnownTraps = map[string]string{
+	OIDColdStart:   "coldStart",
+	OIDWarmStart:   "warmStart",
+	OIDLinkDown:    "linkDown",
+	OIDLinkUp:      "linkUp",
+	OIDAuthFailure: "authenticationFailure",
+}
+
+// TrapName 返回标准 trap 的名称，未知 trap 返回空字符串
+func TrapName(oid string) string {
+	return wellKnownTraps[oid]
+}
+
+// VarBind 变量绑定
+// Type 取值：Integer、OctetString、Null、OID、IpAddress、Counter32、Gauge32、
+// TimeTicks、Opaque、Counter64、NoSuchObject、NoSuchInstance、EndOfMibView
+type VarBind struct {
+	OID   string      `json:"oid"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// pdu 协议数据单元
+type pdu struct {
+	Type        byte
+	RequestID   int64
+	ErrorStatus int64
+	ErrorIndex  int64
+	VarBinds    []VarBind
+
+	// SNMPv1 Trap-PDU 字段
+	Enterprise   string
+	AgentAddress string
+	GenericTrap  int64
+	SpecificTrap int64
+	Timestamp    uint64
+}
+
+// usmParams USM 安全参数
+type usmParams struct {
+	EngineID    []byte
+	EngineBoots int64
+	EngineTime  int64
+	UserName    string
+	AuthParams  []byte
+	PrivParams  []byte
+}
+
+// packet SNMP 报文
+type packet struct {
+	Version   int64
+	Community string
+
+	// SNMPv3 字段
+	MsgID           int64
+	MaxSize         int64
+	Flags           byte
+	Security        usmParams
+	ContextEngineID []byte
+	ContextName     string
+	EncryptedPDU    []byte
+
+	PDU pdu
+
+	// 认证参数在原始报文中的起止位置，用于校验 HMAC
+	authStart, authEnd int
+}
+
+// decodePacket 解码 SNMP 报文
+// SNMPv3 加密报文只解码到 EncryptedPDU，解密后再调用 decodeScopedPDU
+func decodePacket(data []byte) (*packet, error) {
+	msg, err := newBERReader(data).sequence(tagSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &packet{}
+	if p.Version, err = msg.readInt(); err != nil {
+		return nil, fmt.Errorf("read version: %w", err)
+	}
+
+	switch p.Version {
+	case versionV1, versionV2c:
+		community, err := msg.readOctets()
+		if err != nil {
+			return nil, fmt.Errorf("read community: %w", err)
+		}
+		p.Community = string(community)
+		if err := decodePDU(msg, &p.PDU); err != nil {
+			return nil, err
+		}
+	case versionV3:
+		if err := decodeV3Header(msg, p); err != nil {
+			return nil, err
+		}
+		if p.Flags&flagPriv != 0 {
+			if p.EncryptedPDU, err = msg.readOctets(); err != nil {
+				return nil, fmt.Errorf("read encrypted pdu: %w", err)
+			}
+		} else if err := decodeScopedPDU(msg, p); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported snmp version %d", p.Version)
+	}
+
+	return p, nil
+}
+
+// decodeV3Header 解码 SNMPv3 全局头和 USM 安全参数
+func decodeV3Header(msg *berReader, p *packet) error {
+	global, err := msg.sequence(tagSequence)
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	if p.MsgID, err = global.readInt(); err != nil {
+		return fmt.Errorf("read msg id: %w", err)
+	}
+	if p.MaxSize, err = global.readInt(); err != nil {
+		return fmt.Errorf("read max size: %w", err)
+	}
+	flags, err := global.readOctets()
+	if err != nil || len(flags) != 1 {
+		return fmt.Errorf("invalid msg flags")
+	}
+	p.Flags = flags[0]
+	model, err := global.readInt()
+	if err != nil {
+		return fmt.Errorf("read security model: %w", err)
+	}
+	if model != usmSecurityModel {
+		return fmt.Errorf("unsupported security model %d", model)
+	}
+
+	start, end, err := msg.expect(tagOctetString)
+	if err != nil {
+		return fmt.Errorf("read security parameters: %w", err)
+	}
+	usm, err := msg.sub(start, end).sequence(tagSequence)
+	if err != nil {
+		return fmt.Errorf("read usm parameters: %w", err)
+	}
+	sec := &p.Security
+	if sec.EngineID, err = usm.readOctets(); err != nil {
+		return err
+	}
+	if sec.EngineBoots, err = usm.readInt(); err != nil {
+		return err
+	}
+	if sec.EngineTime, err = usm.readInt(); err != nil {
+		return err
+	}
+	userName, err := usm.readOctets()
+	if err != nil {
+		return err
+	}
+	sec.UserName = string(userName)
+	if p.authStart, p.authEnd, err = usm.expect(tagOctetString); err != nil {
+		return err
+	}
+	sec.AuthParams = usm.data[p.authStart:p.authEnd]
+	if sec.PrivParams, err = usm.readOctets(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// decodeScopedPDU 解码 ScopedPDU（解密后的数据末尾可能带填充，忽略多余字节）
+func decodeScopedPDU(r *berReader, p *packet) error {
+	scoped, err := r.sequence(tagSequence)
+	if err != nil {
+		return fmt.Errorf("read scoped pdu: %w", err)
+	}
+	if p.ContextEngineID, err = scoped.readOctets(); err != nil {
+		return err
+	}
+	contextName, err := scoped.readOctets()
+	if err != nil {
+		return err
+	}
+	p.ContextName = string(contextName)
+	return decodePDU(scoped, &p.PDU)
+}
+
+// decodePDU 解码 PDU
+func decodePDU(r *berReader, out *pdu) error {
+	tag, start, end, err := r.readTLV()
+	if err != nil {
+		return fmt.Errorf("read pdu: %w", err)
+	}
+	body := r.sub(start, end)
+	out.Type = tag
+
+	if tag == pduTrapV1 {
+		return decodeTrapV1PDU(body, out)
+	}
+
+	if out.RequestID, err = body.readInt(); err != nil {
+		return err
+	}
+	if out.ErrorStatus, err = body.readInt(); err != nil {
+		return err
+	}
+	if out.ErrorIndex, err = body.readInt(); err != nil {
+		return err
+	}
+	out.VarBinds, err = decodeVarBinds(body)
+	return err
+}
+
+// decodeTrapV1PDU 解码 SNMPv1 Trap-PDU
+func decodeTrapV1PDU(body *berReader, out *pdu) error {
+	start, end, err := body.expect(tagOID)
+	if err != nil {
+		return err
+	}
+	if out.Enterprise, err = decodeOID(body.data[start:end]); err != nil {
+		return err
+	}
+	if start, end, err = body.expect(tagIPAddress); err != nil {
+		return err
+	}
+	if end-start == 4 {
+		out.AgentAddress = net.IP(body.data[start:end]).String()
+	}
+	if out.GenericTrap, err = body.readInt(); err != nil {
+		return err
+	}
+	if out.SpecificTrap, err = body.readInt(); err != nil {
+		return err
+	}
+	if start, end, err = body.expect(tagTimeTicks); err != nil {
+		return err
+	}
+	if out.Timestamp, err = decodeUint(body.data[start:end]); err != nil {
+		return err
+	}
+	out.VarBinds, err = decodeVarBinds(body)
+	return err
+}
+
+// decodeVarBinds 解码变量绑定列表
+func decodeVarBinds(r *berReader) ([]VarBind, error) {
+	list, err := r.sequence(tagSequence)
+	if err != nil {
+		return nil, fmt.Errorf("read varbind list: %w", err)
+	}
+
+	var varBinds []VarBind
+	for list.more() {
+		item, err := list.sequence(tagSequence)
+		if err != nil {
+			return nil, fmt.Errorf("read varbind: %w", err)
+		}
+		start, end, err := item.expect(tagOID)
+		if err != nil {
+			return nil, err
+		}
+		oid, err := decodeOID(item.data[start:end])
+		if err != nil {
+			return nil, err
+		}
+		tag, vStart, vEnd, err := item.readTLV()
+		if err != nil {
+			return nil, err
+		}
+		vb, err := decodeValue(tag, item.data[vStart:vEnd])
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", oid, err)
+		}
+		vb.OID = oid
+		varBinds = append(varBinds, vb)
+	}
+	return varBinds, nil
+}
+
+// decodeValue 解码变量值
+func decodeValue(tag byte, b []byte) (VarBind, error) {
+	switch tag {
+	case tagInteger:
+		v, err := decodeInt(b)
+		return VarBind{Type: "Integer", Value: v}, err
+	case tagOctetString:
+		return VarBind{Type: "OctetString", Value: formatOctets(b)}, nil
+	case tagNull:
+		return VarBind{Type: "Null"}, nil
+	case tagOID:
+		v, err := decodeOID(b)
+		return VarBind{Type: "OID", Value: v}, err
+	case tagIPAddress:
+		if len(b) != 4 {
+			return VarBind{}, fmt.Errorf("invalid ip address length %d", len(b))
+		}
+		return VarBind{Type: "IpAddress", Value: net.IP(b).String()}, nil
+	case tagCounter32, tagGauge32, tagTimeTicks, tagCounter64:
+		v, err := decodeUint(b)
+		return VarBind{Type: uintTypeNames[tag], Value: v}, err
+	case tagOpaque:
+		return VarBind{Type: "Opaque", Value: hex.EncodeToString(b)}, nil
+	case tagNoSuchObject:
+		return VarBind{Type: "NoSuchObject"}, nil
+	case tagNoSuchInstance:
+		return VarBind{Type: "NoSuchInstance"}, nil
+	case tagEndOfMibView:
+		return VarBind{Type: "EndOfMibView"}, nil
+	default:
+		return VarBind{}, fmt.Errorf("unsupported value type 0x%02x", tag)
+	}
+}
+
+var uintTypeNames = map[byte]string{
+	tagCounter32: "Counter32",
+	tagGauge32:   "Gauge32",
+	tagTimeTicks: "TimeTicks",
+	tagCounter64: "Counter64",
+}
+
+// formatOctets 可打印文本按字符串返回，二进制数据（如 MAC 地址）按冒号分隔的十六进制返回
+func formatOctets(b []byte) string {
+	if utf8.Valid(b) {
+		printable := true
+		for _, r := range string(b) {
+			if r < 0x20 && r != '\t' && r != '\r' && r != '\n' {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return string(b)
+		}
+	}
+
+	parts := make([]string, len(b))
+	for i, c := range b {
+		parts[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(parts, ":")
+}
+
+// encodePDU 编码 PDU（不支持 SNMPv1 Trap-PDU）
+func encodePDU(p *pdu) ([]byte, error) {
+	var list []byte
+	for _, vb := range p.VarBinds {
+		encoded, err := encodeVarBind(vb)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, encoded...)
+	}
+	return encodeSequence(p.Type,
+		encodeInt(p.RequestID),
+		encodeInt(p.ErrorStatus),
+		encodeInt(p.ErrorIndex),
+		encodeTLV(tagSequence, list),
+	), nil
+}
+
+// encodeVarBind 编码变量绑定
+func encodeVarBind(vb VarBind) ([]byte, error) {
+	name, err := encodeOID(vb.OID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := encodeValue(vb)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s: %w", vb.OID, err)
+	}
+	return encodeSequence(tagSequence, name, value), nil
+}
+
+// encodeValue 编码变量值
+func encodeValue(vb VarBind) ([]byte, error) {
+	switch vb.Type {
+	case "Integer":
+		v, ok := toInt64(vb.Value)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer value %v", vb.Value)
+		}
+		return encodeInt(v), nil
+	case "OctetString":
+		switch v := vb.Value.(type) {
+		case string:
+			return encodeOctets([]byte(v)), nil
+		case []byte:
+			return encodeOctets(v), nil
+		}
+		return nil, fmt.Errorf("invalid octet string value %v", vb.Value)
+	case "Null", "":
+		return encodeTLV(tagNull, nil), nil
+	case "OID":
+		v, ok := vb.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid oid value %v", vb.Value)
+		}
+		return encodeOID(v)
+	case "IpAddress":
+		v, ok := vb.Value.(string)
+		ip := net.ParseIP(v).To4()
+		if !ok || ip == nil {
+			return nil, fmt.Errorf("invalid ip address %v", vb.Value)
+		}
+		return encodeTLV(tagIPAddress, ip), nil
+	case "Counter32", "Gauge32", "TimeTicks", "Counter64":
+		v, ok := toInt64(vb.Value)
+		if !ok || v < 0 {
+			return nil, fmt.Errorf("invalid unsigned value %v", vb.Value)
+		}
+		for tag, name := range uintTypeNames {
+			if name == vb.Type {
+				return encodeUint(tag, uint64(v)), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unsupported value type %q", vb.Type)
+}
+
+// toInt64 转换整数类型的值
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// encodeCommunityMessage 编码 SNMPv1/v2c 报文
+func encodeCommunityMessage(version int64, community string, p *pdu) ([]byte, error) {
+	encoded, err := encodePDU(p)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSequence(tagSequence, encodeInt(version), encodeOctets([]byte(community)), encoded), nil
+}
+
+// encodeScopedPDU 编码 ScopedPDU
+func encodeScopedPDU(contextEngineID []byte, contextName string, p *pdu) ([]byte, error) {
+	encoded, err := encodePDU(p)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSequence(tagSequence, encodeOctets(contextEngineID), encodeOctets([]byte(contextName)), encoded), nil
+}
+
+// encodeV3Message 编码 SNMPv3 报文，msgData 为 ScopedPDU 或已加密的 OCTET STRING
+func encodeV3Message(msgID, maxSize int64, flags byte, sec *usmParams, msgData []byte) []byte {
+	global := encodeSequence(tagSequence,
+		encodeInt(msgID),
+		encodeInt(maxSize),
+		encodeOctets([]byte{flags}),
+		encodeInt(usmSecurityModel),
+	)
+	usm := encodeSequence(tagSequence,
+		encodeOctets(sec.EngineID),
+		encodeInt(sec.EngineBoots),
+		encodeInt(sec.EngineTime),
+		encodeOctets([]byte(sec.UserName)),
+		encodeOctets(sec.AuthParams),
+		encodeOctets(sec.PrivParams),
+	)
+	return encodeSequence(tagSequence, encodeInt(versionV3), global, encodeOctets(usm), msgData)
+}
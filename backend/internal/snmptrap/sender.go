@@ -0,0 +1,331 @@
+package snmptrap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// SenderConfig 通知发送配置
+type SenderConfig struct {
+	Version   string        // v1、v2c（默认）、v3
+	Community string        // v1/v2c 团体名，默认 public
+	User      *USMUser      // v3 用户
+	EngineID  []byte        // v3 trap 使用的本地引擎 ID，为空自动生成
+	Timeout   time.Duration // inform 等待应答超时，默认 2 秒
+}
+
+// Sender SNMP trap/inform 发送端
+// 用于测试和自检，向指定地址发送通知
+type Sender struct {
+	addr      string
+	cfg       SenderConfig
+	startedAt time.Time
+	requestID int64
+	salt      uint64
+}
+
+// NewSender 创建通知发送端
+func NewSender(addr string, cfg SenderConfig) *Sender {
+	if cfg.Version == "" {
+		cfg.Version = "v2c"
+	}
+	if cfg.Community == "" {
+		cfg.Community = "public"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if len(cfg.EngineID) == 0 {
+		random := make([]byte, 8)
+		rand.Read(random)
+		cfg.EngineID = append([]byte{0x80, 0x00, 0x00, 0x00, 0x05}, random...)
+	}
+
+	var seed [8]byte
+	rand.Read(seed[:])
+	return &Sender{
+		addr:      addr,
+		cfg:       cfg,
+		startedAt: time.Now(),
+		requestID: int64(binary.BigEndian.Uint32(seed[:4]) >> 1),
+		salt:      binary.BigEndian.Uint64(seed[:]),
+	}
+}
+
+// notificationPDU 构造 SNMPv2 通知 PDU
+func (s *Sender) notificationPDU(pduType byte, trapOID string, uptime uint32, varBinds []VarBind) *pdu {
+	list := []VarBind{
+		{OID: OIDSysUpTime, Type: "TimeTicks", Value: uptime},
+		{OID: OIDSnmpTrapOID, Type: "OID", Value: trapOID},
+	}
+	return &pdu{
+		Type:      pduType,
+		RequestID: atomic.AddInt64(&s.requestID, 1) & 0x7fffffff,
+		VarBinds:  append(list, varBinds...),
+	}
+}
+
+// Trap 发送 trap（不等待应答）
+func (s *Sender) Trap(trapOID string, uptime uint32, varBinds ...VarBind) error {
+	conn, err := net.Dial("udp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var msg []byte
+	switch s.cfg.Version {
+	case "v1":
+		msg, err = s.encodeTrapV1(trapOID, uptime, varBinds)
+	case "v2c":
+		msg, err = encodeCommunityMessage(versionV2c, s.cfg.Community, s.notificationPDU(pduTrapV2, trapOID, uptime, varBinds))
+	case "v3":
+		msg, err = s.encodeV3Trap(trapOID, uptime, varBinds)
+	default:
+		err = fmt.Errorf("unsupported version %s", s.cfg.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(msg)
+	return err
+}
+
+// encodeTrapV1 编码 SNMPv1 trap，标准 trap OID 转为 generic-trap，其余按 enterprise.0.specific 拆分
+func (s *Sender) encodeTrapV1(trapOID string, uptime uint32, varBinds []VarBind) ([]byte, error) {
+	enterprise := "1.3.6.1.4.1"
+	generic, specific := int64(6), int64(0)
+	for i := 1; i <= 6; i++ {
+		if trapOID == fmt.Sprintf("1.3.6.1.6.3.1.1.5.%d", i) {
+			generic = int64(i - 1)
+		}
+	}
+	if generic == 6 {
+		var suffix int64
+		if _, err := fmt.Sscanf(trapOID[lastDot(trapOID)+1:], "%d", &suffix); err != nil {
+			return nil, fmt.Errorf("invalid trap oid %q", trapOID)
+		}
+		enterprise = trapOID[:lastDot(trapOID)]
+		if len(enterprise) > 2 && enterprise[len(enterprise)-2:] == ".0" {
+			enterprise = enterprise[:len(enterprise)-2]
+		}
+		specific = suffix
+	}
+
+	enterpriseOID, err := encodeOID(enterprise)
+	if err != nil {
+		return nil, err
+	}
+	var list []byte
+	for _, vb := range varBinds {
+		encoded, err := encodeVarBind(vb)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, encoded...)
+	}
+	trapPDU := encodeSequence(pduTrapV1,
+		enterpriseOID,
+		encodeTLV(tagIPAddress, []byte{127, 0, 0, 1}),
+		encodeInt(generic),
+		encodeInt(specific),
+		encodeUint(tagTimeTicks, uint64(uptime)),
+		encodeTLV(tagSequence, list),
+	)
+	return encodeSequence(tagSequence, encodeInt(versionV1), encodeOctets([]byte(s.cfg.Community)), trapPDU), nil
+}
+
+// lastDot 最后一个点的位置
+func lastDot(oid string) int {
+	for i := len(oid) - 1; i >= 0; i-- {
+		if oid[i] == '.' {
+			return i
+		}
+	}
+	return -1
+}
+
+// encodeV3Trap 编码 SNMPv3 trap，本地引擎为权威引擎
+func (s *Sender) encodeV3Trap(trapOID string, uptime uint32, varBinds []VarBind) ([]byte, error) {
+	if s.cfg.User == nil {
+		return nil, fmt.Errorf("v3 requires a usm user")
+	}
+	keys, err := s.cfg.User.localizeKeys(s.cfg.EngineID)
+	if err != nil {
+		return nil, err
+	}
+	notification := s.notificationPDU(pduTrapV2, trapOID, uptime, varBinds)
+	scoped, err := encodeScopedPDU(s.cfg.EngineID, "", notification)
+	if err != nil {
+		return nil, err
+	}
+	sec := usmParams{
+		EngineID:    s.cfg.EngineID,
+		EngineBoots: 1,
+		EngineTime:  int64(time.Since(s.startedAt).Seconds()),
+		UserName:    s.cfg.User.Name,
+		PrivParams:  s.nextSalt(),
+	}
+	return sealV3Message(notification.RequestID, s.cfg.User.securityFlags(), sec, keys, scoped)
+}
+
+// Inform 发送 inform 并等待应答
+func (s *Sender) Inform(trapOID string, uptime uint32, varBinds ...VarBind) error {
+	conn, err := net.Dial("udp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	notification := s.notificationPDU(pduInform, trapOID, uptime, varBinds)
+	switch s.cfg.Version {
+	case "v2c":
+		msg, err := encodeCommunityMessage(versionV2c, s.cfg.Community, notification)
+		if err != nil {
+			return err
+		}
+		reply, err := s.exchange(conn, msg)
+		if err != nil {
+			return err
+		}
+		p, err := decodePacket(reply)
+		if err != nil {
+			return err
+		}
+		return checkResponse(p, notification.RequestID)
+	case "v3":
+		return s.informV3(conn, notification)
+	}
+	return fmt.Errorf("inform is not supported for version %s", s.cfg.Version)
+}
+
+// informV3 发送 v3 inform：先发现接收方引擎，时间窗口不一致时同步后重试一次
+func (s *Sender) informV3(conn net.Conn, notification *pdu) error {
+	user := s.cfg.User
+	if user == nil {
+		return fmt.Errorf("v3 requires a usm user")
+	}
+
+	// 引擎发现
+	discovery, err := encodeScopedPDU(nil, "", &pdu{Type: pduGetRequest, RequestID: notification.RequestID})
+	if err != nil {
+		return err
+	}
+	msgID := notification.RequestID
+	reply, err := s.exchange(conn, encodeV3Message(msgID, maxMessageSize, flagReportable, &usmParams{}, discovery))
+	if err != nil {
+		return fmt.Errorf("engine discovery: %w", err)
+	}
+	report, err := decodePacket(reply)
+	if err != nil {
+		return fmt.Errorf("engine discovery: %w", err)
+	}
+	remote := report.Security
+	if len(remote.EngineID) == 0 {
+		return fmt.Errorf("engine discovery: empty engine id")
+	}
+
+	keys, err := user.localizeKeys(remote.EngineID)
+	if err != nil {
+		return err
+	}
+	scoped, err := encodeScopedPDU(remote.EngineID, "", notification)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		msgID++
+		sec := usmParams{
+			EngineID:    remote.EngineID,
+			EngineBoots: remote.EngineBoots,
+			EngineTime:  remote.EngineTime,
+			UserName:    user.Name,
+			PrivParams:  s.nextSalt(),
+		}
+		msg, err := sealV3Message(msgID, user.securityFlags()|flagReportable, sec, keys, scoped)
+		if err != nil {
+			return err
+		}
+		reply, err := s.exchange(conn, msg)
+		if err != nil {
+			return err
+		}
+		p, err := decodePacket(reply)
+		if err != nil {
+			return err
+		}
+		if p.Flags&flagAuth != 0 {
+			if err := keys.verify(reply, p.authStart, p.authEnd); err != nil {
+				return err
+			}
+		}
+		if p.Flags&flagPriv != 0 {
+			plain, err := keys.decrypt(p.EncryptedPDU, &p.Security)
+			if err != nil {
+				return err
+			}
+			if err := decodeScopedPDU(newBERReader(plain), p); err != nil {
+				return err
+			}
+		}
+
+		if p.PDU.Type == pduReport && len(p.PDU.VarBinds) > 0 && p.PDU.VarBinds[0].OID == oidUsmStatsNotInTime {
+			remote.EngineBoots = p.Security.EngineBoots
+			remote.EngineTime = p.Security.EngineTime
+			continue
+		}
+		if !bytes.Equal(p.Security.EngineID, remote.EngineID) {
+			return fmt.Errorf("response from unexpected engine")
+		}
+		return checkResponse(p, notification.RequestID)
+	}
+	return fmt.Errorf("not in time window")
+}
+
+// exchange 发送报文并等待一个应答
+func (s *Sender) exchange(conn net.Conn, msg []byte) ([]byte, error) {
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(s.cfg.Timeout))
+	buf := make([]byte, maxMessageSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// checkResponse 校验 inform 应答
+func checkResponse(p *packet, requestID int64) error {
+	if p.PDU.Type == pduReport {
+		if len(p.PDU.VarBinds) > 0 {
+			return fmt.Errorf("report received: %s", p.PDU.VarBinds[0].OID)
+		}
+		return fmt.Errorf("report received")
+	}
+	if p.PDU.Type != pduResponse {
+		return fmt.Errorf("unexpected pdu 0x%02x", p.PDU.Type)
+	}
+	if p.PDU.RequestID != requestID {
+		return fmt.Errorf("request id mismatch")
+	}
+	if p.PDU.ErrorStatus != 0 {
+		return fmt.Errorf("inform error status %d", p.PDU.ErrorStatus)
+	}
+	return nil
+}
+
+// nextSalt 生成隐私参数 salt
+func (s *Sender) nextSalt() []byte {
+	salt := make([]byte, 8)
+	binary.BigEndian.PutUint64(salt, atomic.AddUint64(&s.salt, 1))
+	return salt
+}
@@ -0,0 +1,401 @@
+package snmptrap
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxMessageSize 最大报文长度
+const maxMessageSize = 65507
+
+// timeWindow SNMPv3 时间窗口（秒，RFC 3414 3.2.7）
+const timeWindow = 150
+
+// Handler 通知处理函数，source 为发送方地址
+type Handler func(trap *Trap, source net.IP)
+
+// Config 监听配置
+type Config struct {
+	Address     string    // 监听地址，如 ":162"
+	Communities []string  // 允许的 v1/v2c 团体名，为空时接受任意团体名
+	EngineID    []byte    // 本地 SNMPv3 引擎 ID（接收 inform 时为权威引擎），为空自动生成
+	Users       []USMUser // SNMPv3 用户
+}
+
+// Server SNMP trap/inform 监听服务
+type Server struct {
+	address     string
+	communities map[string]struct{}
+	users       map[string]USMUser
+	engineID    []byte
+	engineBoots int64
+	startedAt   time.Time
+	handler     Handler
+
+	keys   map[string]*localizedKeys
+	keysMu sync.Mutex
+	salt   uint64
+
+	// USM 统计计数，随 Report 返回给发送方
+	unknownEngineIDs uint32
+	unknownUserNames uint32
+	notInTimeWindows uint32
+	wrongDigests     uint32
+
+	conn    net.PacketConn
+	wg      sync.WaitGroup
+	running bool
+	mu      sync.Mutex
+}
+
+// NewServer 创建 trap 监听服务
+func NewServer(cfg Config, handler Handler) (*Server, error) {
+	s := &Server{
+		address:     cfg.Address,
+		communities: make(map[string]struct{}),
+		users:       make(map[string]USMUser),
+		engineID:    cfg.EngineID,
+		engineBoots: 1,
+		handler:     handler,
+		keys:        make(map[string]*localizedKeys),
+	}
+	for _, community := range cfg.Communities {
+		if community != "" {
+			s.communities[community] = struct{}{}
+		}
+	}
+	for _, user := range cfg.Users {
+		if err := user.Validate(); err != nil {
+			return nil, err
+		}
+		s.users[user.Name] = user
+	}
+	if len(s.engineID) == 0 {
+		s.engineID = DefaultEngineID()
+	}
+	if len(s.engineID) < 5 || len(s.engineID) > 32 {
+		return nil, fmt.Errorf("engine id must be 5 to 32 bytes")
+	}
+
+	var seed [8]byte
+	rand.Read(seed[:])
+	s.salt = binary.BigEndian.Uint64(seed[:])
+	return s, nil
+}
+
+// DefaultEngineID 生成基于主机名的引擎 ID（RFC 3411 文本格式）
+func DefaultEngineID() []byte {
+	hostname, _ := os.Hostname()
+	text := "nmp-" + hostname
+	if len(text) > 27 {
+		text = text[:27]
+	}
+	return append([]byte{0x80, 0x00, 0x00, 0x00, 0x04}, text...)
+}
+
+// EngineID 返回本地引擎 ID
+func (s *Server) EngineID() []byte {
+	return s.engineID
+}
+
+// Start 开始监听
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", s.address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", s.address, err)
+	}
+	s.conn = conn
+	s.startedAt = time.Now()
+	s.running = true
+
+	s.wg.Add(1)
+	go s.serve(conn)
+	return nil
+}
+
+// Stop 停止监听
+func (s *Server) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.conn.Close()
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Addr 返回实际监听地址
+func (s *Server) Addr() net.Addr {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// serve 接收循环
+func (s *Server) serve(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("SNMP trap read error: %v", err)
+			continue
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		data := append([]byte(nil), buf[:n]...)
+		if err := s.handlePacket(conn, data, udpAddr); err != nil {
+			log.Printf("SNMP trap from %s dropped: %v", udpAddr.IP, err)
+		}
+	}
+}
+
+// handlePacket 处理单个报文
+func (s *Server) handlePacket(conn net.PacketConn, data []byte, addr *net.UDPAddr) error {
+	p, err := decodePacket(data)
+	if err != nil {
+		return err
+	}
+
+	switch p.Version {
+	case versionV1, versionV2c:
+		if !s.allowCommunity(p.Community) {
+			return fmt.Errorf("unknown community")
+		}
+		var trap *Trap
+		switch p.PDU.Type {
+		case pduTrapV1:
+			trap = trapFromV1PDU(&p.PDU)
+			trap.Version = "v1"
+		case pduTrapV2, pduInform:
+			if p.Version == versionV1 {
+				return fmt.Errorf("unexpected pdu 0x%02x in v1 message", p.PDU.Type)
+			}
+			if p.PDU.Type == pduInform {
+				if err := s.respondCommunity(conn, addr, p); err != nil {
+					return err
+				}
+			}
+			trap = trapFromPDU(&p.PDU)
+			trap.Version = "v2c"
+		default:
+			return fmt.Errorf("unexpected pdu 0x%02x", p.PDU.Type)
+		}
+		trap.Community = p.Community
+		s.handler(trap, addr.IP)
+		return nil
+	case versionV3:
+		return s.handleV3(conn, data, p, addr)
+	}
+	return nil
+}
+
+// allowCommunity 校验团体名
+func (s *Server) allowCommunity(community string) bool {
+	if len(s.communities) == 0 {
+		return true
+	}
+	_, ok := s.communities[community]
+	return ok
+}
+
+// respondCommunity 应答 v2c inform
+func (s *Server) respondCommunity(conn net.PacketConn, addr *net.UDPAddr, p *packet) error {
+	response := p.PDU
+	response.Type = pduResponse
+	response.ErrorStatus = 0
+	response.ErrorIndex = 0
+	msg, err := encodeCommunityMessage(p.Version, p.Community, &response)
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(msg, addr)
+	return err
+}
+
+// handleV3 处理 SNMPv3 报文
+// 对 trap 而言发送方是权威引擎，密钥按其引擎 ID 本地化；
+// 对 inform 而言本服务是权威引擎，需要支持引擎发现和时间窗口校验
+func (s *Server) handleV3(conn net.PacketConn, data []byte, p *packet, addr *net.UDPAddr) error {
+	sec := &p.Security
+	level := p.Flags & (flagAuth | flagPriv)
+	if level == flagPriv {
+		return errUnsupportedSec
+	}
+
+	// 引擎发现
+	if len(sec.EngineID) == 0 {
+		if p.Flags&flagReportable == 0 {
+			return fmt.Errorf("missing engine id")
+		}
+		return s.sendReport(conn, addr, p, oidUsmStatsUnknownEng, atomic.AddUint32(&s.unknownEngineIDs, 1), 0, nil)
+	}
+
+	user, ok := s.users[sec.UserName]
+	if !ok {
+		if p.Flags&flagReportable != 0 && bytes.Equal(sec.EngineID, s.engineID) {
+			s.sendReport(conn, addr, p, oidUsmStatsUnknownUser, atomic.AddUint32(&s.unknownUserNames, 1), 0, nil)
+		}
+		return errUnknownUser
+	}
+	// 安全级别必须与用户配置一致，避免降级绕过认证
+	if level != user.securityFlags() {
+		return errUnsupportedSec
+	}
+
+	keys, err := s.keysFor(&user, sec.EngineID)
+	if err != nil {
+		return err
+	}
+	if level&flagAuth != 0 {
+		if err := keys.verify(data, p.authStart, p.authEnd); err != nil {
+			counter := atomic.AddUint32(&s.wrongDigests, 1)
+			if p.Flags&flagReportable != 0 && bytes.Equal(sec.EngineID, s.engineID) {
+				s.sendReport(conn, addr, p, oidUsmStatsWrongDigest, counter, 0, nil)
+			}
+			return err
+		}
+	}
+	if level&flagPriv != 0 {
+		plain, err := keys.decrypt(p.EncryptedPDU, sec)
+		if err != nil {
+			return err
+		}
+		if err := decodeScopedPDU(newBERReader(plain), p); err != nil {
+			return fmt.Errorf("decrypt scoped pdu: %w", err)
+		}
+	}
+
+	switch p.PDU.Type {
+	case pduTrapV2:
+	case pduInform:
+		if !bytes.Equal(sec.EngineID, s.engineID) {
+			return fmt.Errorf("inform for unknown engine %s", hex.EncodeToString(sec.EngineID))
+		}
+		if level&flagAuth != 0 && !s.inTimeWindow(sec) {
+			return s.sendReport(conn, addr, p, oidUsmStatsNotInTime, atomic.AddUint32(&s.notInTimeWindows, 1), flagAuth, keys)
+		}
+		if err := s.respondV3(conn, addr, p, keys); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unexpected pdu 0x%02x", p.PDU.Type)
+	}
+
+	trap := trapFromPDU(&p.PDU)
+	trap.Version = "v3"
+	trap.User = sec.UserName
+	s.handler(trap, addr.IP)
+	return nil
+}
+
+// keysFor 获取用户在指定引擎上的本地化密钥（口令转换开销较大，结果缓存）
+func (s *Server) keysFor(user *USMUser, engineID []byte) (*localizedKeys, error) {
+	cacheKey := user.Name + "/" + hex.EncodeToString(engineID)
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	if keys, ok := s.keys[cacheKey]; ok {
+		return keys, nil
+	}
+	keys, err := user.localizeKeys(engineID)
+	if err != nil {
+		return nil, err
+	}
+	s.keys[cacheKey] = keys
+	return keys, nil
+}
+
+// engineTime 本地引擎运行秒数
+func (s *Server) engineTime() int64 {
+	return int64(time.Since(s.startedAt).Seconds())
+}
+
+// inTimeWindow 校验发送方携带的引擎启动次数和时间
+func (s *Server) inTimeWindow(sec *usmParams) bool {
+	if sec.EngineBoots != s.engineBoots {
+		return false
+	}
+	diff := sec.EngineTime - s.engineTime()
+	return diff <= timeWindow && diff >= -timeWindow
+}
+
+// nextSalt 生成隐私参数 salt
+func (s *Server) nextSalt() []byte {
+	salt := make([]byte, 8)
+	binary.BigEndian.PutUint64(salt, atomic.AddUint64(&s.salt, 1))
+	return salt
+}
+
+// localSecurity 以本地引擎为权威引擎的安全参数
+func (s *Server) localSecurity(userName string) usmParams {
+	return usmParams{
+		EngineID:    s.engineID,
+		EngineBoots: s.engineBoots,
+		EngineTime:  s.engineTime(),
+		UserName:    userName,
+		PrivParams:  s.nextSalt(),
+	}
+}
+
+// respondV3 应答 v3 inform
+func (s *Server) respondV3(conn net.PacketConn, addr *net.UDPAddr, p *packet, keys *localizedKeys) error {
+	response := p.PDU
+	response.Type = pduResponse
+	response.ErrorStatus = 0
+	response.ErrorIndex = 0
+	scoped, err := encodeScopedPDU(p.ContextEngineID, p.ContextName, &response)
+	if err != nil {
+		return err
+	}
+	msg, err := sealV3Message(p.MsgID, p.Flags&(flagAuth|flagPriv), s.localSecurity(p.Security.UserName), keys, scoped)
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(msg, addr)
+	return err
+}
+
+// sendReport 发送 Report PDU（引擎发现、未知用户、时间窗口同步）
+func (s *Server) sendReport(conn net.PacketConn, addr *net.UDPAddr, p *packet, oid string, counter uint32, flags byte, keys *localizedKeys) error {
+	report := &pdu{
+		Type:      pduReport,
+		RequestID: p.PDU.RequestID,
+		VarBinds:  []VarBind{{OID: oid, Type: "Counter32", Value: counter}},
+	}
+	scoped, err := encodeScopedPDU(s.engineID, "", report)
+	if err != nil {
+		return err
+	}
+	msg, err := sealV3Message(p.MsgID, flags, s.localSecurity(p.Security.UserName), keys, scoped)
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(msg, addr)
+	return err
+}
@@ -0,0 +1,203 @@
+package snmptrap
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedTrap 测试中接收到的通知
+type receivedTrap struct {
+	trap   *Trap
+	source net.IP
+}
+
+// startTestServer 在随机端口启动监听服务
+func startTestServer(t *testing.T, cfg Config) (*Server, chan receivedTrap) {
+	received := make(chan receivedTrap, 10)
+	cfg.Address = "127.0.0.1:0"
+	server, err := NewServer(cfg, func(trap *Trap, source net.IP) {
+		received <- receivedTrap{trap: trap, source: source}
+	})
+	require.NoError(t, err)
+	require.NoError(t, server.Start())
+	t.Cleanup(server.Stop)
+	return server, received
+}
+
+// waitTrap 等待一条通知
+func waitTrap(t *testing.T, received chan receivedTrap) receivedTrap {
+	select {
+	case r := <-received:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for trap")
+		return receivedTrap{}
+	}
+}
+
+// linkDownVarBinds ifIndex/ifAdminStatus/ifOperStatus 以及接口名称
+func linkDownVarBinds(index int) []VarBind {
+	return []VarBind{
+		{OID: "1.3.6.1.2.1.2.2.1.1.3", Type: "Integer", Value: index},
+		{OID: "1.3.6.1.2.1.2.2.1.7.3", Type: "Integer", Value: 1},
+		{OID: "1.3.6.1.2.1.2.2.1.8.3", Type: "Integer", Value: 2},
+		{OID: "1.3.6.1.2.1.31.1.1.1.1.3", Type: "OctetString", Value: "ether3"},
+	}
+}
+
+func TestBERRoundTrip(t *testing.T) {
+	for _, oid := range []string{"1.3.6.1.2.1.1.3.0", "1.3.6.1.4.1.14988.1.1.1.2.1.3", "2.999.4294967295", "0.0"} {
+		encoded, err := encodeOID(oid)
+		require.NoError(t, err)
+		decoded, err := decodeOID(encoded[2:])
+		require.NoError(t, err)
+		assert.Equal(t, oid, decoded)
+	}
+
+	for _, v := range []int64{0, 1, -1, 127, 128, -128, -129, 65535, 1 << 40} {
+		encoded := encodeInt(v)
+		decoded, err := decodeInt(encoded[2:])
+		require.NoError(t, err)
+		assert.Equal(t, v, decoded)
+	}
+
+	encoded := encodeUint(tagCounter32, 4294967295)
+	decoded, err := decodeUint(encoded[2:])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4294967295), decoded)
+
+	// 长格式长度
+	long := encodeOctets(make([]byte, 300))
+	_, start, end, err := newBERReader(long).readTLV()
+	require.NoError(t, err)
+	assert.Equal(t, 300, end-start)
+
+	_, err = decodePacket([]byte{0x30, 0x05, 0x02, 0x01})
+	assert.Error(t, err)
+}
+
+func TestServer_V2cTrapAndInform(t *testing.T) {
+	server, received := startTestServer(t, Config{Communities: []string{"netops"}})
+	addr := server.Addr().String()
+
+	sender := NewSender(addr, SenderConfig{Community: "netops"})
+	require.NoError(t, sender.Trap(OIDLinkDown, 12345, linkDownVarBinds(3)...))
+
+	r := waitTrap(t, received)
+	assert.Equal(t, "127.0.0.1", r.source.String())
+	assert.Equal(t, "v2c", r.trap.Version)
+	assert.Equal(t, "netops", r.trap.Community)
+	assert.Equal(t, "linkDown", r.trap.Name())
+	assert.True(t, r.trap.IsLinkDown())
+	assert.False(t, r.trap.Inform)
+	assert.Equal(t, uint64(12345), r.trap.Uptime)
+	index, ok := r.trap.IfIndex()
+	assert.True(t, ok)
+	assert.Equal(t, 3, index)
+	assert.Equal(t, "ether3", r.trap.IfName())
+	require.Len(t, r.trap.VarBinds, 4)
+
+	// inform 需要应答
+	require.NoError(t, sender.Inform(OIDLinkUp, 12400, linkDownVarBinds(3)[:1]...))
+	r = waitTrap(t, received)
+	assert.True(t, r.trap.Inform)
+	assert.True(t, r.trap.IsLinkUp())
+
+	// 团体名不匹配时丢弃，inform 得不到应答
+	wrong := NewSender(addr, SenderConfig{Community: "public", Timeout: 200 * time.Millisecond})
+	assert.Error(t, wrong.Inform(OIDLinkUp, 1))
+	assert.Empty(t, received)
+}
+
+func TestServer_V1Trap(t *testing.T) {
+	server, received := startTestServer(t, Config{})
+
+	sender := NewSender(server.Addr().String(), SenderConfig{Version: "v1"})
+	require.NoError(t, sender.Trap(OIDLinkUp, 500, linkDownVarBinds(7)[:1]...))
+	r := waitTrap(t, received)
+	assert.Equal(t, "v1", r.trap.Version)
+	assert.Equal(t, OIDLinkUp, r.trap.TrapOID)
+	assert.Equal(t, uint64(500), r.trap.Uptime)
+
+	// 企业私有 trap：enterprise.0.specific
+	require.NoError(t, sender.Trap("1.3.6.1.4.1.14988.0.42", 600))
+	r = waitTrap(t, received)
+	assert.Equal(t, "1.3.6.1.4.1.14988.0.42", r.trap.TrapOID)
+}
+
+func TestServer_V3(t *testing.T) {
+	users := []USMUser{
+		{Name: "authpriv", AuthProtocol: "SHA", AuthPassword: "authpass123", PrivProtocol: "AES", PrivPassword: "privpass123"},
+		{Name: "authdes", AuthProtocol: "MD5", AuthPassword: "authpass123", PrivProtocol: "DES", PrivPassword: "privpass123"},
+		{Name: "authonly", AuthProtocol: "SHA256", AuthPassword: "authpass123"},
+	}
+	server, received := startTestServer(t, Config{Users: users})
+	addr := server.Addr().String()
+
+	for i := range users {
+		user := users[i]
+		t.Run(user.Name, func(t *testing.T) {
+			sender := NewSender(addr, SenderConfig{Version: "v3", User: &user})
+			require.NoError(t, sender.Trap(OIDLinkDown, 100, linkDownVarBinds(5)...))
+			r := waitTrap(t, received)
+			assert.Equal(t, "v3", r.trap.Version)
+			assert.Equal(t, user.Name, r.trap.User)
+			index, ok := r.trap.IfIndex()
+			assert.True(t, ok)
+			assert.Equal(t, 5, index)
+
+			require.NoError(t, sender.Inform(OIDLinkUp, 200, linkDownVarBinds(5)...))
+			r = waitTrap(t, received)
+			assert.True(t, r.trap.Inform)
+			assert.True(t, r.trap.IsLinkUp())
+		})
+	}
+
+	// 口令错误：认证失败，不产生通知
+	bad := USMUser{Name: "authpriv", AuthProtocol: "SHA", AuthPassword: "wrongpass", PrivProtocol: "AES", PrivPassword: "privpass123"}
+	sender := NewSender(addr, SenderConfig{Version: "v3", User: &bad, Timeout: 200 * time.Millisecond})
+	require.NoError(t, sender.Trap(OIDLinkDown, 100))
+	assert.Error(t, sender.Inform(OIDLinkDown, 100))
+
+	// 降级为 noAuth 的报文被拒绝
+	downgraded := USMUser{Name: "authonly"}
+	sender = NewSender(addr, SenderConfig{Version: "v3", User: &downgraded})
+	require.NoError(t, sender.Trap(OIDLinkDown, 100))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, received)
+}
+
+func TestServer_V3InformTimeWindow(t *testing.T) {
+	user := USMUser{Name: "ops", AuthProtocol: "SHA", AuthPassword: "authpass123"}
+	server, received := startTestServer(t, Config{Users: []USMUser{user}})
+
+	// 模拟接收方重启：引擎启动次数变化后，发送方应通过 Report 同步并重试成功
+	server.engineBoots = 2
+	sender := NewSender(server.Addr().String(), SenderConfig{Version: "v3", User: &user})
+	require.NoError(t, sender.Inform(OIDColdStart, 1))
+	r := waitTrap(t, received)
+	assert.Equal(t, "coldStart", r.trap.Name())
+}
+
+func TestUSMUser_Validate(t *testing.T) {
+	assert.NoError(t, (&USMUser{Name: "noauth"}).Validate())
+	assert.Error(t, (&USMUser{}).Validate())
+	assert.Error(t, (&USMUser{Name: "u", AuthProtocol: "SHA1024", AuthPassword: "authpass123"}).Validate())
+	assert.Error(t, (&USMUser{Name: "u", AuthProtocol: "SHA", AuthPassword: "short"}).Validate())
+	assert.Error(t, (&USMUser{Name: "u", PrivProtocol: "AES", PrivPassword: "privpass123"}).Validate())
+}
+
+func TestPasswordToKey(t *testing.T) {
+	// RFC 3414 A.3.1 / A.3.2 测试向量
+	engineID := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}
+	md5Key := passwordToKey(authProtocols["MD5"].newHash, "maplesyrup", engineID)
+	assert.Equal(t, "526f5eed9fcce26f8964c2930787d82b", hex.EncodeToString(md5Key))
+	shaKey := passwordToKey(authProtocols["SHA"].newHash, "maplesyrup", engineID)
+	assert.Equal(t, "6695febc9288e36282235fc7151f128497b38f3f", hex.EncodeToString(shaKey))
+}
@@ -0,0 +1,130 @@
+package snmptrap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Trap 解码后的 trap/inform 通知
+type Trap struct {
+	Version      string    `json:"version"` // v1、v2c、v3
+	Community    string    `json:"community,omitempty"`
+	User         string    `json:"user,omitempty"`
+	Inform       bool      `json:"inform"`
+	TrapOID      string    `json:"trap_oid"`
+	Uptime       uint64    `json:"uptime"` // sysUpTime，单位 1/100 秒
+	AgentAddress string    `json:"agent_address,omitempty"`
+	VarBinds     []VarBind `json:"varbinds"`
+}
+
+// Name 标准 trap 名称，未知 trap 返回 OID
+func (t *Trap) Name() string {
+	if name := TrapName(t.TrapOID); name != "" {
+		return name
+	}
+	return t.TrapOID
+}
+
+// IsLinkDown 是否为 linkDown 通知
+func (t *Trap) IsLinkDown() bool {
+	return t.TrapOID == OIDLinkDown
+}
+
+// IsLinkUp 是否为 linkUp 通知
+func (t *Trap) IsLinkUp() bool {
+	return t.TrapOID == OIDLinkUp
+}
+
+// IfIndex 从变量绑定中提取接口索引
+// 优先使用 ifIndex 的值，其次使用 ifTable/ifXTable 列 OID 的索引后缀
+func (t *Trap) IfIndex() (int, bool) {
+	for _, vb := range t.VarBinds {
+		if strings.HasPrefix(vb.OID, OIDIfIndex+".") {
+			if v, ok := toInt64(vb.Value); ok && v > 0 {
+				return int(v), true
+			}
+		}
+	}
+	for _, vb := range t.VarBinds {
+		if index, ok := interfaceIndexSuffix(vb.OID); ok {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+// IfName 从变量绑定中提取接口名称（ifName 优先，其次 ifDescr）
+func (t *Trap) IfName() string {
+	var descr string
+	for _, vb := range t.VarBinds {
+		value, ok := vb.Value.(string)
+		if !ok || value == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(vb.OID, OIDIfName+"."):
+			return value
+		case strings.HasPrefix(vb.OID, OIDIfDescr+".") && descr == "":
+			descr = value
+		}
+	}
+	return descr
+}
+
+// interfaceIndexSuffix 解析 ifTable（1.3.6.1.2.1.2.2.1.X.N）或 ifXTable（1.3.6.1.2.1.31.1.1.1.X.N）列的索引
+func interfaceIndexSuffix(oid string) (int, bool) {
+	for _, prefix := range []string{"1.3.6.1.2.1.2.2.1.", "1.3.6.1.2.1.31.1.1.1."} {
+		if !strings.HasPrefix(oid, prefix) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(oid, prefix), ".")
+		if len(parts) != 2 {
+			return 0, false
+		}
+		index, err := strconv.Atoi(parts[1])
+		if err != nil || index <= 0 {
+			return 0, false
+		}
+		return index, true
+	}
+	return 0, false
+}
+
+// trapFromPDU 由 SNMPv2-Trap/InformRequest PDU 构造通知
+func trapFromPDU(p *pdu) *Trap {
+	trap := &Trap{Inform: p.Type == pduInform, VarBinds: []VarBind{}}
+	for _, vb := range p.VarBinds {
+		switch vb.OID {
+		case OIDSysUpTime:
+			if v, ok := vb.Value.(uint64); ok {
+				trap.Uptime = v
+			}
+		case OIDSnmpTrapOID:
+			if v, ok := vb.Value.(string); ok {
+				trap.TrapOID = v
+			}
+		default:
+			trap.VarBinds = append(trap.VarBinds, vb)
+		}
+	}
+	return trap
+}
+
+// trapFromV1PDU 由 SNMPv1 Trap-PDU 构造通知，trap OID 按 RFC 3584 转换
+func trapFromV1PDU(p *pdu) *Trap {
+	trap := &Trap{
+		Uptime:       p.Timestamp,
+		AgentAddress: p.AgentAddress,
+		VarBinds:     p.VarBinds,
+	}
+	if trap.VarBinds == nil {
+		trap.VarBinds = []VarBind{}
+	}
+	if p.GenericTrap >= 0 && p.GenericTrap < 6 {
+		trap.TrapOID = fmt.Sprintf("1.3.6.1.6.3.1.1.5.%d", p.GenericTrap+1)
+	} else {
+		trap.TrapOID = fmt.Sprintf("%s.0.%d", p.Enterprise, p.SpecificTrap)
+	}
+	return trap
+}
@@ -0,0 +1,269 @@
+package snmptrap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// USMUser SNMPv3 USM 用户
+// AuthProtocol 取值：空（noAuth）、MD5、SHA、SHA224、SHA256、SHA384、SHA512
+// PrivProtocol 取值：空（noPriv）、DES、AES（AES-128）
+type USMUser struct {
+	Name         string `json:"name" yaml:"name" mapstructure:"name"`
+	AuthProtocol string `json:"auth_protocol" yaml:"auth_protocol" mapstructure:"auth_protocol"`
+	AuthPassword string `json:"-" yaml:"auth_password" mapstructure:"auth_password"`
+	PrivProtocol string `json:"priv_protocol" yaml:"priv_protocol" mapstructure:"priv_protocol"`
+	PrivPassword string `json:"-" yaml:"priv_password" mapstructure:"priv_password"`
+}
+
+// authProtocol 认证协议参数
+type authProtocol struct {
+	newHash   func() hash.Hash
+	macLength int
+}
+
+var authProtocols = map[string]authProtocol{
+	"MD5":    {md5.New, 12},
+	"SHA":    {sha1.New, 12},
+	"SHA224": {sha256.New224, 16},
+	"SHA256": {sha256.New, 24},
+	"SHA384": {sha512.New384, 32},
+	"SHA512": {sha512.New, 48},
+}
+
+var (
+	errUnknownUser    = errors.New("unknown usm user")
+	errWrongDigest    = errors.New("wrong digest")
+	errUnsupportedSec = errors.New("unsupported security level")
+)
+
+// Validate 校验用户配置
+func (u *USMUser) Validate() error {
+	if u.Name == "" {
+		return fmt.Errorf("usm user name is required")
+	}
+	if u.AuthProtocol != "" {
+		if _, ok := authProtocols[strings.ToUpper(u.AuthProtocol)]; !ok {
+			return fmt.Errorf("usm user %s: unsupported auth protocol %s", u.Name, u.AuthProtocol)
+		}
+		if len(u.AuthPassword) < 8 {
+			return fmt.Errorf("usm user %s: auth password must be at least 8 characters", u.Name)
+		}
+	}
+	if u.PrivProtocol != "" {
+		if u.AuthProtocol == "" {
+			return fmt.Errorf("usm user %s: privacy requires authentication", u.Name)
+		}
+		switch strings.ToUpper(u.PrivProtocol) {
+		case "DES", "AES":
+		default:
+			return fmt.Errorf("usm user %s: unsupported privacy protocol %s", u.Name, u.PrivProtocol)
+		}
+		if len(u.PrivPassword) < 8 {
+			return fmt.Errorf("usm user %s: privacy password must be at least 8 characters", u.Name)
+		}
+	}
+	return nil
+}
+
+// securityFlags 用户支持的最高安全级别
+func (u *USMUser) securityFlags() byte {
+	var flags byte
+	if u.AuthProtocol != "" {
+		flags |= flagAuth
+	}
+	if u.PrivProtocol != "" {
+		flags |= flagPriv
+	}
+	return flags
+}
+
+// localizedKeys 按引擎 ID 本地化后的密钥
+type localizedKeys struct {
+	auth authProtocol
+	authKey,
+	privKey []byte
+	privProto string
+}
+
+// localizeKeys 计算用户在指定引擎上的本地化密钥（RFC 3414 A.2）
+func (u *USMUser) localizeKeys(engineID []byte) (*localizedKeys, error) {
+	keys := &localizedKeys{}
+	if u.AuthProtocol == "" {
+		return keys, nil
+	}
+	proto, ok := authProtocols[strings.ToUpper(u.AuthProtocol)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported auth protocol %s", u.AuthProtocol)
+	}
+	keys.auth = proto
+	keys.authKey = passwordToKey(proto.newHash, u.AuthPassword, engineID)
+	if u.PrivProtocol != "" {
+		keys.privProto = strings.ToUpper(u.PrivProtocol)
+		keys.privKey = passwordToKey(proto.newHash, u.PrivPassword, engineID)
+	}
+	return keys, nil
+}
+
+// passwordToKey 口令转换为本地化密钥：对重复口令的前 1MB 做摘要，再与引擎 ID 组合
+func passwordToKey(newHash func() hash.Hash, password string, engineID []byte) []byte {
+	h := newHash()
+	pw := []byte(password)
+	buf := make([]byte, 64)
+	index := 0
+	for count := 0; count < 1048576; count += 64 {
+		for i := range buf {
+			buf[i] = pw[index%len(pw)]
+			index++
+		}
+		h.Write(buf)
+	}
+	ku := h.Sum(nil)
+
+	h.Reset()
+	h.Write(ku)
+	h.Write(engineID)
+	h.Write(ku)
+	return h.Sum(nil)
+}
+
+// mac 计算整个报文的 HMAC（认证参数位置需为全 0）
+func (k *localizedKeys) mac(msg []byte) []byte {
+	m := hmac.New(k.auth.newHash, k.authKey)
+	m.Write(msg)
+	return m.Sum(nil)[:k.auth.macLength]
+}
+
+// verify 校验报文摘要
+func (k *localizedKeys) verify(raw []byte, authStart, authEnd int) error {
+	if k.auth.newHash == nil {
+		return errUnsupportedSec
+	}
+	if authEnd-authStart != k.auth.macLength {
+		return errWrongDigest
+	}
+	received := append([]byte(nil), raw[authStart:authEnd]...)
+	msg := append([]byte(nil), raw...)
+	for i := authStart; i < authEnd; i++ {
+		msg[i] = 0
+	}
+	if !hmac.Equal(received, k.mac(msg)) {
+		return errWrongDigest
+	}
+	return nil
+}
+
+// decrypt 解密 ScopedPDU
+func (k *localizedKeys) decrypt(data []byte, sec *usmParams) ([]byte, error) {
+	if len(sec.PrivParams) != 8 {
+		return nil, fmt.Errorf("invalid privacy parameters")
+	}
+	switch k.privProto {
+	case "DES":
+		if len(data) == 0 || len(data)%des.BlockSize != 0 {
+			return nil, fmt.Errorf("invalid DES ciphertext length %d", len(data))
+		}
+		block, err := des.NewCipher(k.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCDecrypter(block, k.desIV(sec.PrivParams)).CryptBlocks(out, data)
+		return out, nil
+	case "AES":
+		block, err := aes.NewCipher(k.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBDecrypter(block, aesIV(sec)).XORKeyStream(out, data)
+		return out, nil
+	}
+	return nil, errUnsupportedSec
+}
+
+// encrypt 加密 ScopedPDU，salt 为 8 字节的隐私参数
+func (k *localizedKeys) encrypt(data []byte, sec *usmParams) ([]byte, error) {
+	switch k.privProto {
+	case "DES":
+		if pad := len(data) % des.BlockSize; pad != 0 {
+			data = append(data, make([]byte, des.BlockSize-pad)...)
+		}
+		block, err := des.NewCipher(k.privKey[:8])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCBCEncrypter(block, k.desIV(sec.PrivParams)).CryptBlocks(out, data)
+		return out, nil
+	case "AES":
+		block, err := aes.NewCipher(k.privKey[:16])
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(data))
+		cipher.NewCFBEncrypter(block, aesIV(sec)).XORKeyStream(out, data)
+		return out, nil
+	}
+	return nil, errUnsupportedSec
+}
+
+// desIV DES 初始向量：密钥后 8 字节（pre-IV）与 salt 异或
+func (k *localizedKeys) desIV(salt []byte) []byte {
+	iv := make([]byte, 8)
+	for i := range iv {
+		iv[i] = k.privKey[8+i] ^ salt[i]
+	}
+	return iv
+}
+
+// aesIV AES 初始向量：engineBoots || engineTime || salt
+func aesIV(sec *usmParams) []byte {
+	iv := make([]byte, 16)
+	binary.BigEndian.PutUint32(iv[0:4], uint32(sec.EngineBoots))
+	binary.BigEndian.PutUint32(iv[4:8], uint32(sec.EngineTime))
+	copy(iv[8:], sec.PrivParams)
+	return iv
+}
+
+// sealV3Message 编码 SNMPv3 报文，按安全级别加密并签名
+// sec.PrivParams 在加密时需已设置为 8 字节 salt
+func sealV3Message(msgID int64, flags byte, sec usmParams, keys *localizedKeys, scopedPDU []byte) ([]byte, error) {
+	msgData := scopedPDU
+	if flags&flagPriv != 0 {
+		encrypted, err := keys.encrypt(scopedPDU, &sec)
+		if err != nil {
+			return nil, err
+		}
+		msgData = encodeOctets(encrypted)
+	} else {
+		sec.PrivParams = nil
+	}
+
+	if flags&flagAuth == 0 {
+		sec.AuthParams = nil
+		return encodeV3Message(msgID, maxMessageSize, flags, &sec, msgData), nil
+	}
+
+	sec.AuthParams = make([]byte, keys.auth.macLength)
+	msg := encodeV3Message(msgID, maxMessageSize, flags, &sec, msgData)
+
+	// 重新解码定位认证参数，写入 HMAC
+	decoded, err := decodePacket(msg)
+	if err != nil {
+		return nil, err
+	}
+	copy(msg[decoded.authStart:decoded.authEnd], keys.mac(msg))
+	return msg, nil
+}
@@ -0,0 +1,232 @@
+// Package syslog 提供内置 syslog 接收功能（RFC3164 / RFC5424，UDP / TCP）
+package syslog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 日志格式
+const (
+	FormatRFC3164 = "rfc3164"
+	FormatRFC5424 = "rfc5424"
+)
+
+// 未携带 PRI 的报文按 RFC3164 约定视为 user.notice
+const defaultPriority = 13
+
+// maxTagLength RFC3164 TAG 最大长度
+const maxTagLength = 32
+
+// Message 解析后的 syslog 消息
+type Message struct {
+	Format         string
+	Facility       int
+	Severity       int
+	Timestamp      time.Time // 设备上报时间，缺失时使用接收时间
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData string // RFC5424 结构化数据原文
+	Content        string
+}
+
+// ErrEmptyMessage 空消息
+var ErrEmptyMessage = errors.New("empty syslog message")
+
+// Parse 解析 syslog 报文，自动识别 RFC5424 和 RFC3164 格式
+// RFC3164 格式较为宽松，无法识别的部分整体作为消息内容保留
+func Parse(data []byte, received time.Time) (*Message, error) {
+	line := strings.TrimRight(string(data), "\r\n\x00")
+	if strings.TrimSpace(line) == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	pri, rest, ok := parsePriority(line)
+	if !ok {
+		pri = defaultPriority
+		rest = line
+	}
+
+	msg := &Message{
+		Facility:  pri / 8,
+		Severity:  pri % 8,
+		Timestamp: received,
+	}
+
+	// RFC5424 在 PRI 后紧跟版本号（目前为 1）和空格
+	if strings.HasPrefix(rest, "1 ") {
+		if err := parseRFC5424(rest[2:], msg); err == nil {
+			msg.Format = FormatRFC5424
+			return msg, nil
+		}
+		// 按 RFC5424 解析失败时回退为 RFC3164
+		msg.Timestamp = received
+		msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID, msg.StructuredData = "", "", "", "", ""
+	}
+
+	msg.Format = FormatRFC3164
+	parseRFC3164(rest, received, msg)
+	return msg, nil
+}
+
+// parsePriority 解析 <PRI>
+func parsePriority(line string) (int, string, bool) {
+	if len(line) < 3 || line[0] != '<' {
+		return 0, line, false
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, line, false
+	}
+	pri, err := strconv.Atoi(line[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, line, false
+	}
+	return pri, line[end+1:], true
+}
+
+// parseRFC5424 解析 RFC5424 头部（PRI 和 VERSION 之后的部分）
+// TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func parseRFC5424(rest string, msg *Message) error {
+	fields := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		idx := strings.IndexByte(rest, ' ')
+		if idx < 0 {
+			return errors.New("truncated rfc5424 header")
+		}
+		fields = append(fields, rest[:idx])
+		rest = rest[idx+1:]
+	}
+
+	if fields[0] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return err
+		}
+		msg.Timestamp = ts
+	}
+	msg.Hostname = nilValue(fields[1])
+	msg.AppName = nilValue(fields[2])
+	msg.ProcID = nilValue(fields[3])
+	msg.MsgID = nilValue(fields[4])
+
+	sd, content, err := splitStructuredData(rest)
+	if err != nil {
+		return err
+	}
+	msg.StructuredData = sd
+	// MSG 可能以 UTF-8 BOM 开头
+	msg.Content = strings.TrimPrefix(content, "\ufeff")
+	return nil
+}
+
+// splitStructuredData 拆分结构化数据和消息内容
+func splitStructuredData(rest string) (string, string, error) {
+	if strings.HasPrefix(rest, "-") {
+		return "", strings.TrimPrefix(rest[1:], " "), nil
+	}
+	if !strings.HasPrefix(rest, "[") {
+		return "", "", errors.New("invalid structured data")
+	}
+
+	inQuote := false
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case c == '\\' && inQuote:
+			i++ // 跳过转义字符
+		case c == '"':
+			inQuote = !inQuote
+		case c == ']' && !inQuote:
+			// 结构化数据可以包含多个连续的 SD-ELEMENT
+			if i+1 < len(rest) && rest[i+1] == '[' {
+				continue
+			}
+			return rest[:i+1], strings.TrimPrefix(rest[i+1:], " "), nil
+		}
+	}
+	return "", "", errors.New("unterminated structured data")
+}
+
+// nilValue RFC5424 中 "-" 表示空值
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// rfc3164Layouts RFC3164 时间戳格式（日期不足两位时以空格填充）
+var rfc3164Layouts = []string{time.Stamp, "Jan _2 15:04:05.000"}
+
+// parseRFC3164 宽松解析 RFC3164 报文：[TIMESTAMP SP HOSTNAME SP] [TAG[PID]:] CONTENT
+// RouterOS 默认（未启用 bsd-syslog）不发送时间戳和主机名，整体作为内容
+func parseRFC3164(rest string, received time.Time, msg *Message) {
+	rest = strings.TrimLeft(rest, " ")
+
+	if ts, remain, ok := parseRFC3164Timestamp(rest, received); ok {
+		msg.Timestamp = ts
+		rest = remain
+		// 时间戳之后为主机名
+		if idx := strings.IndexByte(rest, ' '); idx > 0 {
+			msg.Hostname = rest[:idx]
+			rest = rest[idx+1:]
+		}
+	}
+
+	msg.AppName, msg.ProcID, rest = parseTag(rest)
+	msg.Content = rest
+}
+
+// parseRFC3164Timestamp 解析 "Mmm dd hh:mm:ss" 或 RFC3339 格式的时间戳
+func parseRFC3164Timestamp(rest string, received time.Time) (time.Time, string, bool) {
+	// 部分设备在 RFC3164 报文中使用 RFC3339 时间戳
+	if idx := strings.IndexByte(rest, ' '); idx > 0 {
+		if ts, err := time.Parse(time.RFC3339Nano, rest[:idx]); err == nil {
+			return ts, rest[idx+1:], true
+		}
+	}
+
+	for _, layout := range rfc3164Layouts {
+		if len(rest) <= len(layout) || rest[len(layout)] != ' ' {
+			continue
+		}
+		ts, err := time.ParseInLocation(layout, rest[:len(layout)], received.Location())
+		if err != nil {
+			continue
+		}
+		// RFC3164 时间戳不含年份，使用接收时间的年份，跨年时回退一年
+		ts = ts.AddDate(received.Year(), 0, 0)
+		if ts.Sub(received) > 24*time.Hour {
+			ts = ts.AddDate(-1, 0, 0)
+		}
+		return ts, rest[len(layout)+1:], true
+	}
+	return time.Time{}, rest, false
+}
+
+// parseTag 解析 TAG[PID]: 前缀，不符合格式时返回原内容
+func parseTag(rest string) (string, string, string) {
+	for i := 0; i < len(rest) && i <= maxTagLength; i++ {
+		c := rest[i]
+		switch {
+		case c == ':':
+			if i == 0 {
+				return "", "", rest
+			}
+			return rest[:i], "", strings.TrimPrefix(rest[i+1:], " ")
+		case c == '[':
+			end := strings.Index(rest[i:], "]:")
+			if i == 0 || end < 0 {
+				return "", "", rest
+			}
+			return rest[:i], rest[i+1 : i+end], strings.TrimPrefix(rest[i+end+2:], " ")
+		case c == ' ' || c == ',':
+			return "", "", rest
+		}
+	}
+	return "", "", rest
+}
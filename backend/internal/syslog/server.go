@@ -0,0 +1,279 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxMessageSize 单条消息最大长度（RFC5424 建议接收端至少支持 2048 字节）
+const maxMessageSize = 64 * 1024
+
+// maxFrameLengthDigits 八位组计数分帧长度前缀的最大位数（maxMessageSize 为 5 位）
+const maxFrameLengthDigits = 5
+
+// tcpIdleTimeout TCP 连接空闲超时
+const tcpIdleTimeout = 10 * time.Minute
+
+// Handler 消息处理函数，source 为发送方地址
+type Handler func(msg *Message, source net.IP)
+
+// Server syslog 监听服务
+type Server struct {
+	udpAddr string
+	tcpAddr string
+	handler Handler
+
+	udpConn     net.PacketConn
+	tcpListener net.Listener
+	conns       map[net.Conn]struct{}
+	connMu      sync.Mutex
+
+	wg      sync.WaitGroup
+	running bool
+	mu      sync.Mutex
+}
+
+// NewServer 创建 syslog 监听服务，地址为空时不监听对应协议
+func NewServer(udpAddr, tcpAddr string, handler Handler) *Server {
+	return &Server{
+		udpAddr: udpAddr,
+		tcpAddr: tcpAddr,
+		handler: handler,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// Start 开始监听，任一地址绑定失败时返回错误
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return nil
+	}
+
+	if s.udpAddr != "" {
+		conn, err := net.ListenPacket("udp", s.udpAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on udp %s: %w", s.udpAddr, err)
+		}
+		s.udpConn = conn
+	}
+	if s.tcpAddr != "" {
+		ln, err := net.Listen("tcp", s.tcpAddr)
+		if err != nil {
+			if s.udpConn != nil {
+				s.udpConn.Close()
+				s.udpConn = nil
+			}
+			return fmt.Errorf("failed to listen on tcp %s: %w", s.tcpAddr, err)
+		}
+		s.tcpListener = ln
+	}
+
+	if s.udpConn != nil {
+		s.wg.Add(1)
+		go s.serveUDP(s.udpConn)
+	}
+	if s.tcpListener != nil {
+		s.wg.Add(1)
+		go s.serveTCP(s.tcpListener)
+	}
+	s.running = true
+	return nil
+}
+
+// Stop 停止监听并关闭所有连接
+func (s *Server) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	if s.udpConn != nil {
+		s.udpConn.Close()
+	}
+	if s.tcpListener != nil {
+		s.tcpListener.Close()
+	}
+	s.mu.Unlock()
+
+	s.connMu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.connMu.Unlock()
+
+	s.wg.Wait()
+}
+
+// UDPAddr 返回实际监听的 UDP 地址
+func (s *Server) UDPAddr() net.Addr {
+	if s.udpConn == nil {
+		return nil
+	}
+	return s.udpConn.LocalAddr()
+}
+
+// TCPAddr 返回实际监听的 TCP 地址
+func (s *Server) TCPAddr() net.Addr {
+	if s.tcpListener == nil {
+		return nil
+	}
+	return s.tcpListener.Addr()
+}
+
+// serveUDP UDP 接收循环，每个数据报为一条消息
+func (s *Server) serveUDP(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Syslog UDP read error: %v", err)
+			continue
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		s.dispatch(buf[:n], udpAddr.IP)
+	}
+}
+
+// serveTCP TCP 接收循环
+func (s *Server) serveTCP(ln net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Syslog TCP accept error: %v", err)
+			continue
+		}
+
+		s.connMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.connMu.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+// handleConn 处理单个 TCP 连接
+// 同时支持 RFC6587 的八位组计数（"长度 空格 消息"）和换行分隔两种分帧方式
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, conn)
+		s.connMu.Unlock()
+		conn.Close()
+	}()
+
+	var source net.IP
+	if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		source = tcpAddr.IP
+	}
+
+	reader := bufio.NewReaderSize(conn, 4096)
+	for {
+		conn.SetReadDeadline(time.Now().Add(tcpIdleTimeout))
+		frame, err := readFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("Syslog TCP read error from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(frame) > 0 {
+			s.dispatch(frame, source)
+		}
+	}
+}
+
+// readFrame 读取一帧 TCP syslog 消息
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		// 八位组计数分帧，长度前缀最多读取 maxFrameLengthDigits 位，
+		// 避免未认证的客户端发送没有分隔符的数字流耗尽内存
+		length, err := readFrameLength(reader)
+		if err != nil {
+			return nil, err
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return nil, err
+		}
+		return frame, nil
+	}
+
+	// 换行分隔（非透明分帧）
+	var frame []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if len(frame) > 0 && errors.Is(err, io.EOF) {
+				return frame, nil
+			}
+			return nil, err
+		}
+		if len(frame)+len(chunk) > maxMessageSize {
+			return nil, errors.New("message too long")
+		}
+		frame = append(frame, chunk...)
+		if !isPrefix {
+			return frame, nil
+		}
+	}
+}
+
+// readFrameLength 读取八位组计数分帧的长度前缀（数字后跟空格）
+func readFrameLength(reader *bufio.Reader) (int, error) {
+	length := 0
+	for digits := 0; ; digits++ {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == ' ' && digits > 0 {
+			break
+		}
+		if b < '0' || b > '9' || digits == maxFrameLengthDigits {
+			return 0, errors.New("invalid frame length prefix")
+		}
+		length = length*10 + int(b-'0')
+	}
+	if length <= 0 || length > maxMessageSize {
+		return 0, fmt.Errorf("invalid frame length %d", length)
+	}
+	return length, nil
+}
+
+// dispatch 解析并交给处理函数
+func (s *Server) dispatch(data []byte, source net.IP) {
+	msg, err := Parse(data, time.Now())
+	if err != nil {
+		return
+	}
+	s.handler(msg, source)
+}
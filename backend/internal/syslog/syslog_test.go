@@ -0,0 +1,157 @@
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRFC5424(t *testing.T) {
+	received := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	raw := `<165>1 2024-01-15T09:59:58.123Z core-sw01 sshd 4123 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"][meta seq="1"] ` + "\ufeff" + `Accepted password for admin`
+
+	msg, err := Parse([]byte(raw), received)
+	require.NoError(t, err)
+	assert.Equal(t, FormatRFC5424, msg.Format)
+	assert.Equal(t, 20, msg.Facility)
+	assert.Equal(t, 5, msg.Severity)
+	assert.True(t, msg.Timestamp.Equal(time.Date(2024, 1, 15, 9, 59, 58, 123000000, time.UTC)))
+	assert.Equal(t, "core-sw01", msg.Hostname)
+	assert.Equal(t, "sshd", msg.AppName)
+	assert.Equal(t, "4123", msg.ProcID)
+	assert.Equal(t, "ID47", msg.MsgID)
+	assert.Equal(t, `[exampleSDID@32473 iut="3" eventSource="App\"lication"][meta seq="1"]`, msg.StructuredData)
+	assert.Equal(t, "Accepted password for admin", msg.Content)
+
+	// 空值字段
+	msg, err = Parse([]byte("<14>1 - - - - - - interface ether1 link down"), received)
+	require.NoError(t, err)
+	assert.Equal(t, FormatRFC5424, msg.Format)
+	assert.True(t, msg.Timestamp.Equal(received))
+	assert.Empty(t, msg.Hostname)
+	assert.Equal(t, "interface ether1 link down", msg.Content)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	received := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+	msg, err := Parse([]byte("<34>Jan  5 22:14:15 mymachine su[230]: 'su root' failed for lonvick\n"), received)
+	require.NoError(t, err)
+	assert.Equal(t, FormatRFC3164, msg.Format)
+	assert.Equal(t, 4, msg.Facility)
+	assert.Equal(t, 2, msg.Severity)
+	assert.True(t, msg.Timestamp.Equal(time.Date(2024, 1, 5, 22, 14, 15, 0, time.UTC)))
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, "su", msg.AppName)
+	assert.Equal(t, "230", msg.ProcID)
+	assert.Equal(t, "'su root' failed for lonvick", msg.Content)
+
+	// 跨年：一月收到的十二月日志属于上一年
+	msg, err = Parse([]byte("<13>Dec 31 23:59:59 host app: bye"), received)
+	require.NoError(t, err)
+	assert.Equal(t, 2023, msg.Timestamp.Year())
+
+	// RouterOS 默认格式：无时间戳和主机名
+	msg, err = Parse([]byte("<30>system,info,account user admin logged in from 10.0.0.5 via ssh"), received)
+	require.NoError(t, err)
+	assert.Equal(t, 3, msg.Facility)
+	assert.Equal(t, 6, msg.Severity)
+	assert.True(t, msg.Timestamp.Equal(received))
+	assert.Empty(t, msg.AppName)
+	assert.Equal(t, "system,info,account user admin logged in from 10.0.0.5 via ssh", msg.Content)
+
+	// 缺少 PRI 时按 user.notice 处理
+	msg, err = Parse([]byte("plain message"), received)
+	require.NoError(t, err)
+	assert.Equal(t, 1, msg.Facility)
+	assert.Equal(t, 5, msg.Severity)
+	assert.Equal(t, "plain message", msg.Content)
+
+	_, err = Parse([]byte("\r\n"), received)
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+}
+
+// startTestServer 启动监听本地随机端口的测试服务
+func startTestServer(t *testing.T) (*Server, chan *Message) {
+	received := make(chan *Message, 10)
+	srv := NewServer("127.0.0.1:0", "127.0.0.1:0", func(msg *Message, source net.IP) {
+		assert.True(t, source.IsLoopback())
+		received <- msg
+	})
+	require.NoError(t, srv.Start())
+	t.Cleanup(srv.Stop)
+	return srv, received
+}
+
+// waitMessage 等待接收一条消息
+func waitMessage(t *testing.T, ch chan *Message) *Message {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for syslog message")
+		return nil
+	}
+}
+
+func TestServerUDP(t *testing.T) {
+	srv, received := startTestServer(t)
+
+	conn, err := net.Dial("udp", srv.UDPAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("<11>Jan 15 09:00:00 router1 kernel: eth0 link down"))
+	require.NoError(t, err)
+
+	msg := waitMessage(t, received)
+	assert.Equal(t, 3, msg.Severity)
+	assert.Equal(t, "router1", msg.Hostname)
+	assert.Equal(t, "eth0 link down", msg.Content)
+}
+
+func TestServerTCPFraming(t *testing.T) {
+	srv, received := startTestServer(t)
+
+	conn, err := net.Dial("tcp", srv.TCPAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// 八位组计数分帧（消息内容允许包含换行）和换行分隔混合发送
+	counted := "<14>1 2024-01-15T10:00:00Z host app - - - line1\nline2"
+	_, err = fmt.Fprintf(conn, "%d %s", len(counted), counted)
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<12>newline framed\n"))
+	require.NoError(t, err)
+
+	msg := waitMessage(t, received)
+	assert.Equal(t, FormatRFC5424, msg.Format)
+	assert.Equal(t, "line1\nline2", msg.Content)
+
+	msg = waitMessage(t, received)
+	assert.Equal(t, 4, msg.Severity)
+	assert.Equal(t, "newline framed", msg.Content)
+}
+
+func TestReadFrameRejectsLongLengthPrefix(t *testing.T) {
+	// 没有分隔符的数字流只读取有限的前缀就被拒绝
+	digits := strings.NewReader(strings.Repeat("9", 1<<20))
+	_, err := readFrame(bufio.NewReaderSize(digits, 16))
+	assert.Error(t, err)
+	assert.Greater(t, digits.Len(), 1<<20-64)
+
+	for _, input := range []string{"99999 x", "100000 x", "12x <14>msg", "1 "} {
+		_, err := readFrame(bufio.NewReader(strings.NewReader(input)))
+		assert.Error(t, err, input)
+	}
+
+	frame, err := readFrame(bufio.NewReader(strings.NewReader("5 hello")))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(frame))
+}
@@ -0,0 +1,125 @@
+// Package tenant 多租户（组织）隔离
+// 请求上下文携带当前组织，数据库查询通过 GORM 回调自动限定到该组织
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// organizationField 归属组织的模型字段名
+const organizationField = "OrganizationID"
+
+// InfluxTag 时序数据点的组织标签
+const InfluxTag = "organization_id"
+
+type contextKey struct{}
+
+// WithOrganization 返回携带当前组织的上下文
+func WithOrganization(ctx context.Context, organizationID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, organizationID)
+}
+
+// FromContext 获取上下文中的当前组织，未设置时返回 false
+func FromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	organizationID, ok := ctx.Value(contextKey{}).(uint)
+	return organizationID, ok && organizationID != 0
+}
+
+// Register 注册租户隔离回调
+// 语句上下文携带组织时，含 OrganizationID 字段的模型的查询、更新和删除自动限定到该组织，
+// 创建时自动写入该组织；未携带组织的语句（后台任务、登录等）不做限定
+func Register(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("tenant:create", assignOrganization); err != nil {
+		return fmt.Errorf("failed to register tenant create callback: %w", err)
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", scopeOrganization); err != nil {
+		return fmt.Errorf("failed to register tenant query callback: %w", err)
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("tenant:row", scopeOrganization); err != nil {
+		return fmt.Errorf("failed to register tenant row callback: %w", err)
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", scopeOrganization); err != nil {
+		return fmt.Errorf("failed to register tenant update callback: %w", err)
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", scopeOrganization); err != nil {
+		return fmt.Errorf("failed to register tenant delete callback: %w", err)
+	}
+	return nil
+}
+
+// statementOrganization 返回语句的当前组织和模型的组织列名，不需要限定时列名为空
+func statementOrganization(db *gorm.DB) (uint, string) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return 0, ""
+	}
+	organizationID, ok := FromContext(db.Statement.Context)
+	if !ok {
+		return 0, ""
+	}
+	field := db.Statement.Schema.LookUpField(organizationField)
+	if field == nil || field.DBName == "" {
+		return 0, ""
+	}
+	return organizationID, field.DBName
+}
+
+// organizationCondition 当前表的组织条件
+func organizationCondition(column string, organizationID uint) clause.Expression {
+	return clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: column}, Value: organizationID}
+}
+
+// scopeOrganization 查询、更新和删除限定到当前组织
+func scopeOrganization(db *gorm.DB) {
+	organizationID, column := statementOrganization(db)
+	if column == "" {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{organizationCondition(column, organizationID)}})
+}
+
+// assignOrganization 新建记录写入当前组织
+func assignOrganization(db *gorm.DB) {
+	organizationID, column := statementOrganization(db)
+	if column == "" {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(organizationField)
+	ctx := db.Statement.Context
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			elem := reflect.Indirect(value.Index(i))
+			if elem.Kind() == reflect.Struct {
+				db.AddError(field.Set(ctx, elem, organizationID))
+			}
+		}
+	case reflect.Struct:
+		db.AddError(field.Set(ctx, value, organizationID))
+	}
+
+	// Save 更新不到记录时会改为 upsert，主键冲突时只允许覆盖本组织的记录
+	if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok {
+		if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+			onConflict.Where.Exprs = append(onConflict.Where.Exprs, organizationCondition(column, organizationID))
+			c.Expression = onConflict
+			db.Statement.Clauses["ON CONFLICT"] = c
+		}
+	}
+}
+
+// Bind 返回绑定上下文的仓库或服务，上下文中的组织限定其数据范围
+// 未实现 WithContext 的实现（如测试替身）原样返回
+func Bind[T any](ctx context.Context, v T) T {
+	if scoped, ok := any(v).(interface{ WithContext(context.Context) T }); ok {
+		return scoped.WithContext(ctx)
+	}
+	return v
+}
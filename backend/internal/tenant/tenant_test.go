@@ -0,0 +1,111 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"nmp-platform/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupTenantTestDB 创建注册了租户回调的内存数据库
+func setupTenantTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, Register(db))
+	require.NoError(t, db.AutoMigrate(&models.Device{}, &models.Tag{}, &models.DeviceGroup{}, &models.SystemSetting{}))
+	return db
+}
+
+func TestTenantIsolation(t *testing.T) {
+	db := setupTenantTestDB(t)
+	acme := WithOrganization(context.Background(), 2)
+	globex := WithOrganization(context.Background(), 3)
+
+	// 未携带组织的数据归属默认组织，创建时写入当前组织（忽略请求中的组织）
+	require.NoError(t, db.Create(&models.Device{Name: "legacy", Host: "10.0.0.1", Type: models.DeviceTypeRouter}).Error)
+	acmeDevice := &models.Device{Name: "acme-r1", Host: "10.0.0.1", Type: models.DeviceTypeRouter, OrganizationID: 3}
+	require.NoError(t, db.WithContext(acme).Create(acmeDevice).Error)
+	assert.Equal(t, uint(2), acmeDevice.OrganizationID)
+	require.NoError(t, db.WithContext(globex).Create([]*models.Device{
+		{Name: "globex-r1", Host: "10.0.0.1", Type: models.DeviceTypeRouter},
+		{Name: "globex-r2", Host: "10.0.0.2", Type: models.DeviceTypeRouter},
+	}).Error)
+
+	var legacy models.Device
+	require.NoError(t, db.Where("name = ?", "legacy").First(&legacy).Error)
+	assert.Equal(t, models.DefaultOrganizationID, legacy.OrganizationID)
+
+	// 查询和计数只包含本组织的数据，未携带组织时不限定
+	var count int64
+	require.NoError(t, db.WithContext(globex).Model(&models.Device{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	require.NoError(t, db.Model(&models.Device{}).Count(&count).Error)
+	assert.Equal(t, int64(4), count)
+	var device models.Device
+	assert.ErrorIs(t, db.WithContext(globex).First(&device, acmeDevice.ID).Error, gorm.ErrRecordNotFound)
+
+	// 不能更新或删除其他组织的数据，更新时也不能修改所属组织
+	result := db.WithContext(globex).Model(&models.Device{}).Where("id = ?", acmeDevice.ID).Update("name", "stolen")
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+	result = db.WithContext(globex).Delete(&models.Device{}, acmeDevice.ID)
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	// Save 找不到本组织的记录时转为 upsert，也不能覆盖其他组织的记录
+	forged := *acmeDevice
+	forged.Name = "stolen"
+	forged.OrganizationID = 3
+	require.NoError(t, db.WithContext(globex).Save(&forged).Error)
+	require.NoError(t, db.First(&device, acmeDevice.ID).Error)
+	assert.Equal(t, "acme-r1", device.Name)
+	assert.Equal(t, uint(2), device.OrganizationID)
+
+	acmeDevice.Name = "acme-core"
+	acmeDevice.OrganizationID = 3
+	require.NoError(t, db.WithContext(acme).Save(acmeDevice).Error)
+	require.NoError(t, db.First(&device, acmeDevice.ID).Error)
+	assert.Equal(t, "acme-core", device.Name)
+	assert.Equal(t, uint(2), device.OrganizationID)
+
+	// 没有组织字段的模型不受影响
+	require.NoError(t, db.WithContext(acme).Create(&models.SystemSetting{Key: "k", Value: "v"}).Error)
+	var setting models.SystemSetting
+	require.NoError(t, db.WithContext(globex).First(&setting, "key = ?", "k").Error)
+}
+
+func TestTenantUniqueNamesPerOrganization(t *testing.T) {
+	db := setupTenantTestDB(t)
+	acme := WithOrganization(context.Background(), 2)
+	globex := WithOrganization(context.Background(), 3)
+
+	// 标签和分组名称在组织内唯一，不同组织可以重名
+	require.NoError(t, db.WithContext(acme).Create(&models.Tag{Name: "core"}).Error)
+	require.NoError(t, db.WithContext(globex).Create(&models.Tag{Name: "core"}).Error)
+	assert.Error(t, db.WithContext(acme).Create(&models.Tag{Name: "core"}).Error)
+
+	require.NoError(t, db.WithContext(acme).Create(&models.DeviceGroup{Name: "sites"}).Error)
+	require.NoError(t, db.WithContext(globex).Create(&models.DeviceGroup{Name: "sites"}).Error)
+
+	var tag models.Tag
+	require.NoError(t, db.WithContext(globex).Where("name = ?", "core").First(&tag).Error)
+	assert.Equal(t, uint(3), tag.OrganizationID)
+}
+
+func TestFromContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+	_, ok = FromContext(WithOrganization(context.Background(), 0))
+	assert.False(t, ok)
+	organizationID, ok := FromContext(WithOrganization(context.Background(), 5))
+	assert.True(t, ok)
+	assert.Equal(t, uint(5), organizationID)
+}
@@ -382,6 +382,7 @@ server:
   read_timeout: "30s"
   write_timeout: "30s"
   public_url: "http://$SERVER_IP:$BACKEND_PORT"
+  trusted_proxies: ["127.0.0.1", "::1"]
 
 database:
   host: "localhost"
@@ -3,16 +3,19 @@
 import { useState, useEffect } from "react";
 import { useRouter } from "next/navigation";
 import { useAuthStore } from "@/stores/auth";
+import { authApi } from "@/lib/api/auth";
+import type { OIDCProviderInfo } from "@/lib/api/types";
 import { ServerStackIcon, EyeIcon, EyeSlashIcon } from "@heroicons/react/24/outline";
 
 export default function LoginPage() {
   const router = useRouter();
-  const { login, isAuthenticated, isLoading, error, clearError } = useAuthStore();
+  const { login, completeSSOLogin, isAuthenticated, isLoading, error, clearError } = useAuthStore();
   
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
   const [showPassword, setShowPassword] = useState(false);
   const [localError, setLocalError] = useState("");
+  const [ssoProviders, setSSOProviders] = useState<OIDCProviderInfo[]>([]);
 
   useEffect(() => {
     if (isAuthenticated) {
@@ -20,6 +23,29 @@ export default function LoginPage() {
     }
   }, [isAuthenticated, router]);
 
+  // 单点登录：加载身份提供者列表，并处理后端回调跳转带回的结果
+  useEffect(() => {
+    authApi
+      .getOIDCProviders()
+      .then((response) => setSSOProviders(response.data || []))
+      .catch(() => setSSOProviders([]));
+
+    const params = new URLSearchParams(window.location.search);
+    const ssoError = params.get("sso_error");
+    if (ssoError) {
+      setLocalError(`单点登录失败：${ssoError}`);
+    } else if (params.get("sso") === "success") {
+      completeSSOLogin().then((success) => {
+        if (success) {
+          router.push("/");
+        }
+      });
+    }
+    if (ssoError || params.has("sso")) {
+      window.history.replaceState(null, "", window.location.pathname);
+    }
+  }, [completeSSOLogin, router]);
+
   useEffect(() => {
     if (error) {
       setLocalError(error);
@@ -140,6 +166,28 @@ export default function LoginPage() {
             </button>
           </form>
 
+          {/* 单点登录 */}
+          {ssoProviders.length > 0 && (
+            <div className="mt-6">
+              <div className="flex items-center gap-3 text-xs text-slate-500 mb-4">
+                <div className="flex-1 h-px bg-white/10" />
+                或使用单点登录
+                <div className="flex-1 h-px bg-white/10" />
+              </div>
+              <div className="space-y-3">
+                {ssoProviders.map((provider) => (
+                  <a
+                    key={provider.name}
+                    href={authApi.oidcLoginUrl(provider.name)}
+                    className="block w-full py-3 text-sm font-medium text-center text-slate-200 bg-white/5 hover:bg-white/10 border border-white/10 rounded-xl transition-all"
+                  >
+                    {provider.display_name || provider.name}
+                  </a>
+                ))}
+              </div>
+            </div>
+          )}
+
           {/* 提示信息 */}
           <div className="mt-6 text-center text-xs text-slate-500">
             默认账号: admin / admin
@@ -2,8 +2,8 @@
  * 认证 API
  */
 
-import { api, setToken, clearToken } from './client';
-import type { LoginParams, LoginResult, UserInfo } from './types';
+import { api, apiUrl, setToken, clearToken } from './client';
+import type { LoginParams, LoginResult, OIDCProviderInfo, UserInfo } from './types';
 
 export const authApi = {
   // 登录
@@ -31,13 +31,23 @@ export const authApi = {
 
   // 刷新 Token
   async refreshToken() {
-    const response = await api.post<{ success: boolean; data: { token: string } }>('/api/v1/auth/refresh-token');
+    const response = await api.post<{ success: boolean; data: LoginResult }>('/api/v1/auth/refresh-token');
     if (response.success && response.data?.token) {
       setToken(response.data.token);
     }
     return response;
   },
 
+  // 获取单点登录身份提供者列表
+  async getOIDCProviders() {
+    return api.get<{ success: boolean; data: OIDCProviderInfo[] }>('/api/v1/auth/oidc/providers');
+  },
+
+  // 单点登录入口地址（浏览器跳转，由后端重定向到身份提供者）
+  oidcLoginUrl(provider: string) {
+    return apiUrl(`/api/v1/auth/oidc/${encodeURIComponent(provider)}/login`);
+  },
+
   // 修改密码
   async changePassword(oldPassword: string, newPassword: string) {
     return api.post<{ success: boolean; message: string }>('/api/v1/auth/change-password', {
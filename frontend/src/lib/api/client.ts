@@ -39,6 +39,11 @@ export function clearToken(): void {
   localStorage.removeItem(TOKEN_KEY);
 }
 
+// 构建完整的后端地址（用于浏览器直接跳转的接口，如单点登录）
+export function apiUrl(path: string): string {
+  return `${API_BASE_URL}${path}`;
+}
+
 // 构建 URL 带查询参数
 function buildUrl(url: string, params?: Record<string, string | number | boolean | undefined>): string {
   const fullUrl = url.startsWith('http') ? url : `${API_BASE_URL}${url}`;
@@ -59,8 +64,34 @@ function buildUrl(url: string, params?: Record<string, string | number | boolean
 // 请求超时时间（毫秒）
 const REQUEST_TIMEOUT = 30000;
 
+// 进行中的刷新请求（并发的 401 请求共用同一次刷新，避免旧刷新令牌被重复使用）
+let refreshPromise: Promise<boolean> | null = null;
+
+// 使用 HttpOnly Cookie 中的刷新令牌换取新的访问令牌
+function refreshAccessToken(): Promise<boolean> {
+  if (!refreshPromise) {
+    refreshPromise = fetch(buildUrl('/api/v1/auth/refresh-token'), {
+      method: 'POST',
+      credentials: 'include',
+    })
+      .then(async (response) => {
+        if (!response.ok) return false;
+        const data = await response.json().catch(() => ({}));
+        if (data?.data?.token) {
+          setToken(data.data.token);
+        }
+        return true;
+      })
+      .catch(() => false)
+      .finally(() => {
+        refreshPromise = null;
+      });
+  }
+  return refreshPromise;
+}
+
 // 基础请求函数
-async function request<T>(url: string, config: RequestConfig = {}): Promise<T> {
+async function request<T>(url: string, config: RequestConfig = {}, retried = false): Promise<T> {
   const { params, ...fetchConfig } = config;
   
   const token = getToken();
@@ -87,8 +118,11 @@ async function request<T>(url: string, config: RequestConfig = {}): Promise<T> {
     
     clearTimeout(timeoutId);
     
-    // 处理 401 未授权
+    // 处理 401 未授权：访问令牌过期时先尝试刷新，成功后重试一次
     if (response.status === 401) {
+      if (!retried && !/\/auth\/(login|refresh|logout)/.test(url) && (await refreshAccessToken())) {
+        return request<T>(url, config, true);
+      }
       clearToken();
       if (typeof window !== 'undefined' && !url.includes('/auth/login')) {
         window.location.href = '/login';
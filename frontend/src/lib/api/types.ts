@@ -25,6 +25,11 @@ export interface LoginResult {
   userInfo?: UserInfo;
 }
 
+export interface OIDCProviderInfo {
+  name: string;
+  display_name: string;
+}
+
 export interface UserInfo {
   id: number;
   username: string;
@@ -16,6 +16,7 @@ interface AuthState {
   
   // Actions
   login: (username: string, password: string) => Promise<boolean>;
+  completeSSOLogin: () => Promise<boolean>;
   logout: () => Promise<void>;
   fetchUser: () => Promise<void>;
   clearError: () => void;
@@ -53,6 +54,30 @@ export const useAuthStore = create<AuthState>()(
         }
       },
 
+      // 单点登录回调后令牌已写入 Cookie，通过刷新接口换取访问令牌
+      completeSSOLogin: async () => {
+        set({ isLoading: true, error: null });
+        try {
+          const response = await authApi.refreshToken();
+          if (response.success && response.data?.token) {
+            const user = response.data.user || response.data.userInfo || null;
+            set({
+              user,
+              isAuthenticated: true,
+              isLoading: false,
+            });
+            return true;
+          }
+          set({ isLoading: false, error: '单点登录失败：服务器响应异常' });
+          return false;
+        } catch (error) {
+          const message = error instanceof Error ? error.message : '单点登录失败';
+          console.error('SSO login error:', error);
+          set({ isLoading: false, error: message });
+          return false;
+        }
+      },
+
       logout: async () => {
         try {
           await authApi.logout();
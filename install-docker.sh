@@ -592,6 +592,7 @@ server:
   read_timeout: "30s"
   write_timeout: "30s"
   public_url: "http://$SERVER_IP"
+  trusted_proxies: ["172.16.0.0/12", "192.168.0.0/16"]  # Docker 网络中的 Nginx
 
 database:
   host: "postgres"